@@ -0,0 +1,62 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/customers/loyalty"
+	"encore.app/customers/store"
+	"encore.app/pkg/database"
+	"encore.app/pkg/events"
+	"encore.app/pkg/outbox"
+)
+
+// Service - the name the customers report their cleanups with
+const Service = "customers"
+
+// the customers database
+var cleanupDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("customers").Stdlib(), "postgres")
+})
+
+// DeleteUser - DeleteUser is a function that removes the profile, addresses and loyalty ledger of a deleted user
+// in one transaction, and saves the event reporting it is done. An event handled before changes nothing.
+//
+// @param ctx - context.Context
+// @param subscription - the name of the subscription handling the event
+// @param event - *events.UserDeleted
+// @return error
+func DeleteUser(ctx context.Context, subscription string, event *events.UserDeleted) error {
+	return database.Transaction(ctx, cleanupDatabase(), func(tx *sqlx.Tx) error {
+		claimed, err := outbox.Claim(ctx, tx, subscription, event.Meta.Id)
+		if err != nil || !claimed {
+			return err
+		}
+
+		removed := make(map[string]int)
+		for _, remove := range []func(context.Context, *sqlx.Tx, string) (map[string]int, error){
+			store.DeleteUser,
+			loyalty.DeleteUser,
+		} {
+			counts, err := remove(ctx, tx, event.UserId)
+			if err != nil {
+				return fmt.Errorf("cleaning up user: %w", err)
+			}
+			for name, count := range counts {
+				removed[name] = count
+			}
+		}
+
+		done := &events.CleanupCompleted{
+			Meta:    events.NewMeta(events.TypeCleanupCompleted, time.Now()),
+			UserId:  event.UserId,
+			Service: Service,
+			Removed: removed,
+		}
+		return outbox.Add(ctx, tx, done.Meta, done)
+	})
+}
@@ -1 +1,292 @@
 package customers
+
+import (
+	"context"
+	"errors"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/customers/store"
+	"encore.app/pkg/middleware"
+)
+
+// =====================================================================================================================
+// PROFILES
+// =====================================================================================================================
+
+// GetMyProfile - Get the profile of the signed in user
+//
+//	@param ctx - context.Context
+//	@return profile
+//	@return error
+//
+// encore:api auth method=GET path=/customers/me
+func GetMyProfile(ctx context.Context) (*store.Profile, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.Profile{}, err
+	}
+
+	// get the profile
+	profile, err := store.FindProfile(ctx, claims.Subject.Id)
+	if err != nil {
+		return &store.Profile{}, err
+	}
+
+	return profile, nil
+}
+
+// UpdateMyProfile - Replace the details and display preferences of the signed in user
+//
+//	@param ctx - context.Context
+//	@param payload - *store.ProfileRequest
+//	@return profile
+//	@return error
+//
+// encore:api auth method=PUT path=/customers/me
+func UpdateMyProfile(ctx context.Context, payload *store.ProfileRequest) (*store.Profile, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.Profile{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &store.Profile{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// save the profile
+	profile, err := store.SaveProfile(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &store.Profile{}, customerError(err)
+	}
+
+	return profile, nil
+}
+
+// UpdateMyConsents - Give or withdraw the marketing consents of the signed in user
+//
+//	@param ctx - context.Context
+//	@param payload - *store.ConsentsRequest
+//	@return profile
+//	@return error
+//
+// encore:api auth method=PUT path=/customers/me/consents
+func UpdateMyConsents(ctx context.Context, payload *store.ConsentsRequest) (*store.Profile, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.Profile{}, err
+	}
+
+	// set the consents
+	profile, err := store.SetConsents(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &store.Profile{}, customerError(err)
+	}
+
+	return profile, nil
+}
+
+// =====================================================================================================================
+// ADDRESSES
+// =====================================================================================================================
+
+// ListMyAddresses - List the shipping and billing addresses of the signed in user
+//
+//	@param ctx - context.Context
+//	@return addresses
+//	@return error
+//
+// encore:api auth method=GET path=/customers/me/addresses
+func ListMyAddresses(ctx context.Context) (*store.AddressesResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.AddressesResponse{}, err
+	}
+
+	// get the addresses
+	addresses, err := store.ListAddresses(ctx, claims.Subject.Id)
+	if err != nil {
+		return &store.AddressesResponse{}, err
+	}
+
+	return &store.AddressesResponse{Addresses: addresses}, nil
+}
+
+// CreateMyAddress - Add a shipping or billing address of the signed in user
+//
+//	@param ctx - context.Context
+//	@param payload - *store.AddressRequest
+//	@return address
+//	@return error
+//
+// encore:api auth method=POST path=/customers/me/addresses
+func CreateMyAddress(ctx context.Context, payload *store.AddressRequest) (*store.Address, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.Address{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &store.Address{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// add the address
+	address, err := store.CreateAddress(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &store.Address{}, customerError(err)
+	}
+
+	return address, nil
+}
+
+// UpdateMyAddress - Replace an address of the signed in user
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *store.AddressRequest
+//	@return address
+//	@return error
+//
+// encore:api auth method=PUT path=/customers/me/addresses/:id
+func UpdateMyAddress(ctx context.Context, id string, payload *store.AddressRequest) (*store.Address, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.Address{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &store.Address{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// replace the address
+	address, err := store.UpdateAddress(ctx, claims.Subject.Id, id, payload)
+	if err != nil {
+		return &store.Address{}, customerError(err)
+	}
+
+	return address, nil
+}
+
+// SetMyDefaultAddress - Make an address of the signed in user the default of its kind
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return address
+//	@return error
+//
+// encore:api auth method=POST path=/customers/me/addresses/:id/default
+func SetMyDefaultAddress(ctx context.Context, id string) (*store.Address, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.Address{}, err
+	}
+
+	// set the default
+	address, err := store.SetDefaultAddress(ctx, claims.Subject.Id, id)
+	if err != nil {
+		return &store.Address{}, customerError(err)
+	}
+
+	return address, nil
+}
+
+// DeleteMyAddress - Remove an address of the signed in user
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/customers/me/addresses/:id
+func DeleteMyAddress(ctx context.Context, id string) error {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// remove the address
+	if err := store.DeleteAddress(ctx, claims.Subject.Id, id); err != nil {
+		return customerError(err)
+	}
+
+	return nil
+}
+
+// =====================================================================================================================
+// ADMIN
+// =====================================================================================================================
+
+// GetCustomer - Get the profile and addresses of a user
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return customer
+//	@return error
+//
+// encore:api auth method=GET path=/customers/:id
+func GetCustomer(ctx context.Context, id string) (*store.CustomerResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.CustomerResponse{}, err
+	}
+
+	// get the profile
+	profile, err := store.FindProfile(ctx, id)
+	if err != nil {
+		return &store.CustomerResponse{}, err
+	}
+
+	// get the addresses
+	addresses, err := store.ListAddresses(ctx, id)
+	if err != nil {
+		return &store.CustomerResponse{}, err
+	}
+
+	return &store.CustomerResponse{Profile: profile, Addresses: addresses}, nil
+}
+
+// GetDateOfBirth - Get the date of birth of a user, for the claims of their token
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return dateOfBirth
+//	@return error
+//
+// encore:api private method=GET path=/customers/:id/date-of-birth
+func GetDateOfBirth(ctx context.Context, id string) (*store.DateOfBirthResponse, error) {
+	// get the profile
+	profile, err := store.FindProfile(ctx, id)
+	if err != nil {
+		return &store.DateOfBirthResponse{}, err
+	}
+
+	return &store.DateOfBirthResponse{DateOfBirth: profile.FormatDateOfBirth()}, nil
+}
+
+// customerError - maps customer store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func customerError(err error) error {
+	switch {
+	case errors.Is(err, store.ErrAddressNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, store.ErrInvalidProfile):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, store.ErrTooManyAddresses):
+		return &errs.Error{Code: errs.ResourceExhausted, Message: err.Error()}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,70 @@
+package customers
+
+import (
+	"context"
+	"time"
+
+	"encore.dev/cron"
+	"encore.dev/pubsub"
+	"encore.dev/rlog"
+
+	"encore.app/customers/cleanup"
+	"encore.app/customers/store"
+	"encore.app/pkg/events"
+	"encore.app/pkg/events/topics"
+	"encore.app/pkg/outbox"
+)
+
+// =====================================================================================================================
+// EVENTS
+// =====================================================================================================================
+
+// publish the events saved with the changes of the customers
+var _ = cron.NewJob("relay-customer-events", cron.JobConfig{
+	Title:    "Publish the events of the customers",
+	Every:    1 * cron.Minute,
+	Endpoint: RelayCustomerEvents,
+})
+
+// RelayCustomerEvents - Publish the events saved in the outbox of the customers
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/customers/events/relay
+func RelayCustomerEvents(ctx context.Context) (*outbox.RelayResponse, error) {
+	// publish the events
+	response, err := store.RelayEvents(ctx, topics.Publish, time.Now())
+	if err != nil {
+		return &outbox.RelayResponse{}, err
+	}
+
+	rlog.Info("customers.RelayCustomerEvents", "sent", response.Sent, "failed", response.Failed, "purged", response.Purged)
+
+	return response, nil
+}
+
+// remove what the customers keep for a deleted user
+var _ = pubsub.NewSubscription(topics.UserDeleted, "customers-user-cleanup", pubsub.SubscriptionConfig[*events.UserDeleted]{
+	Handler: CleanupDeletedUser,
+})
+
+// CleanupDeletedUser - Remove the profile, addresses and loyalty ledger of a deleted user
+//
+//	@param ctx - context.Context
+//	@param event - *events.UserDeleted
+//	@return error
+func CleanupDeletedUser(ctx context.Context, event *events.UserDeleted) error {
+	if !events.Accept(event.Meta, 1) {
+		return nil
+	}
+
+	if err := cleanup.DeleteUser(ctx, "customers-user-cleanup", event); err != nil {
+		return err
+	}
+
+	rlog.Info("customers.CleanupDeletedUser", "id", event.Meta.Id, "user", event.UserId)
+
+	return nil
+}
@@ -0,0 +1,428 @@
+package customers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/customers/loyalty"
+	"encore.app/pkg/middleware"
+	"encore.app/pkg/pagination"
+)
+
+// =====================================================================================================================
+// LOYALTY
+// =====================================================================================================================
+
+// expire the points that were not spent in time
+var _ = cron.NewJob("expire-loyalty-points", cron.JobConfig{
+	Title:    "Expire loyalty points",
+	Every:    1 * cron.Hour,
+	Endpoint: ExpireLoyaltyPoints,
+})
+
+// GetLoyaltyProgram - Get the settings of the points program with its category multipliers and tiers
+//
+//	@param ctx - context.Context
+//	@return settings
+//	@return error
+//
+// encore:api public method=GET path=/loyalty/program
+func GetLoyaltyProgram(ctx context.Context) (*loyalty.ProgramResponse, error) {
+	// get the settings
+	settings, err := loyalty.GetSettings(ctx)
+	if err != nil {
+		return &loyalty.ProgramResponse{}, err
+	}
+
+	return settings, nil
+}
+
+// UpdateLoyaltyProgram - Replace the earning, redemption, expiry and tier settings of the points program
+//
+//	@param ctx - context.Context
+//	@param payload - *loyalty.ProgramRequest
+//	@return program
+//	@return error
+//
+// encore:api auth method=PUT path=/loyalty/program
+func UpdateLoyaltyProgram(ctx context.Context, payload *loyalty.ProgramRequest) (*loyalty.Program, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &loyalty.Program{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &loyalty.Program{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// save the program
+	program, err := loyalty.SaveProgram(ctx, payload)
+	if err != nil {
+		return &loyalty.Program{}, loyaltyError(err)
+	}
+
+	return program, nil
+}
+
+// SetLoyaltyMultiplier - Set the points earned on a category, in basis points of the normal points
+//
+//	@param ctx - context.Context
+//	@param categoryId - string
+//	@param payload - *loyalty.MultiplierRequest
+//	@return multiplier
+//	@return error
+//
+// encore:api auth method=PUT path=/loyalty/multipliers/:categoryId
+func SetLoyaltyMultiplier(ctx context.Context, categoryId string, payload *loyalty.MultiplierRequest) (*loyalty.Multiplier, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &loyalty.Multiplier{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Var(categoryId, "uuid"); err != nil {
+		return &loyalty.Multiplier{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+	if err := validator.New().Struct(payload); err != nil {
+		return &loyalty.Multiplier{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// set the multiplier
+	multiplier, err := loyalty.SetMultiplier(ctx, categoryId, payload)
+	if err != nil {
+		return &loyalty.Multiplier{}, loyaltyError(err)
+	}
+
+	return multiplier, nil
+}
+
+// DeleteLoyaltyMultiplier - Make a category earn the normal points again
+//
+//	@param ctx - context.Context
+//	@param categoryId - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/loyalty/multipliers/:categoryId
+func DeleteLoyaltyMultiplier(ctx context.Context, categoryId string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// delete the multiplier
+	if err := loyalty.DeleteMultiplier(ctx, categoryId); err != nil {
+		return loyaltyError(err)
+	}
+
+	return nil
+}
+
+// SaveLoyaltyTier - Create a tier or replace the spend that reaches it and its multiplier
+//
+//	@param ctx - context.Context
+//	@param payload - *loyalty.TierRequest
+//	@return tier
+//	@return error
+//
+// encore:api auth method=POST path=/loyalty/tiers
+func SaveLoyaltyTier(ctx context.Context, payload *loyalty.TierRequest) (*loyalty.Tier, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &loyalty.Tier{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &loyalty.Tier{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// save the tier
+	tier, err := loyalty.SaveTier(ctx, payload)
+	if err != nil {
+		return &loyalty.Tier{}, loyaltyError(err)
+	}
+
+	return tier, nil
+}
+
+// DeleteLoyaltyTier - Delete a tier
+//
+//	@param ctx - context.Context
+//	@param code - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/loyalty/tiers/:code
+func DeleteLoyaltyTier(ctx context.Context, code string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// delete the tier
+	if err := loyalty.DeleteTier(ctx, code); err != nil {
+		return loyaltyError(err)
+	}
+
+	return nil
+}
+
+// EarnLoyaltyPoints - Give a customer the points of an order, once per order
+//
+//	@param ctx - context.Context
+//	@param payload - *loyalty.EarnRequest
+//	@return entry
+//	@return error
+//
+// encore:api auth method=POST path=/loyalty/earn
+func EarnLoyaltyPoints(ctx context.Context, payload *loyalty.EarnRequest) (*loyalty.Entry, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &loyalty.Entry{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &loyalty.Entry{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// earn the points
+	entry, err := loyalty.Earn(ctx, payload, time.Now())
+	if err != nil {
+		return &loyalty.Entry{}, loyaltyError(err)
+	}
+
+	return entry, nil
+}
+
+// GetMyLoyaltyBalance - Get the points, tier and expiring points of the signed in user
+//
+//	@param ctx - context.Context
+//	@return balance
+//	@return error
+//
+// encore:api auth method=GET path=/loyalty/me
+func GetMyLoyaltyBalance(ctx context.Context) (*loyalty.Balance, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &loyalty.Balance{}, err
+	}
+
+	// get the balance
+	balance, err := loyalty.GetBalance(ctx, claims.Subject.Id, time.Now())
+	if err != nil {
+		return &loyalty.Balance{}, loyaltyError(err)
+	}
+
+	return balance, nil
+}
+
+// ListMyLoyaltyEntries - List the points earned and spent by the signed in user, latest first
+//
+//	@param ctx - context.Context
+//	@param params - *loyalty.EntriesQuery
+//	@return entries
+//	@return error
+//
+// encore:api auth method=GET path=/loyalty/me/entries
+func ListMyLoyaltyEntries(ctx context.Context, params *loyalty.EntriesQuery) (*loyalty.PaginatedEntriesResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &loyalty.PaginatedEntriesResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &loyalty.PaginatedEntriesResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the entries
+	history, err := loyalty.History(ctx, claims.Subject.Id, params)
+	if err != nil {
+		return &loyalty.PaginatedEntriesResponse{}, loyaltyError(err)
+	}
+
+	return history, nil
+}
+
+// RedeemMyLoyaltyPoints - Spend points of the signed in user on an order, once per order
+//
+//	@param ctx - context.Context
+//	@param payload - *loyalty.RedeemRequest
+//	@return entry
+//	@return error
+//
+// encore:api auth method=POST path=/loyalty/me/redeem
+func RedeemMyLoyaltyPoints(ctx context.Context, payload *loyalty.RedeemRequest) (*loyalty.Entry, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &loyalty.Entry{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &loyalty.Entry{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// spend the points
+	entry, err := loyalty.Redeem(ctx, claims.Subject.Id, payload, time.Now())
+	if err != nil {
+		return &loyalty.Entry{}, loyaltyError(err)
+	}
+
+	return entry, nil
+}
+
+// GetLoyaltyBalance - Get the points, tier and expiring points of a customer
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return balance
+//	@return error
+//
+// encore:api auth method=GET path=/loyalty/customers/:id
+func GetLoyaltyBalance(ctx context.Context, id string) (*loyalty.Balance, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &loyalty.Balance{}, err
+	}
+
+	// get the balance
+	balance, err := loyalty.GetBalance(ctx, id, time.Now())
+	if err != nil {
+		return &loyalty.Balance{}, loyaltyError(err)
+	}
+
+	return balance, nil
+}
+
+// ListLoyaltyEntries - List the points earned and spent by a customer, latest first
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param params - *loyalty.EntriesQuery
+//	@return entries
+//	@return error
+//
+// encore:api auth method=GET path=/loyalty/customers/:id/entries
+func ListLoyaltyEntries(ctx context.Context, id string, params *loyalty.EntriesQuery) (*loyalty.PaginatedEntriesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &loyalty.PaginatedEntriesResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &loyalty.PaginatedEntriesResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the entries
+	history, err := loyalty.History(ctx, id, params)
+	if err != nil {
+		return &loyalty.PaginatedEntriesResponse{}, loyaltyError(err)
+	}
+
+	return history, nil
+}
+
+// AdjustLoyaltyPoints - Give or take points of a customer with a note of why
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *loyalty.AdjustRequest
+//	@return entry
+//	@return error
+//
+// encore:api auth method=POST path=/loyalty/customers/:id/adjust
+func AdjustLoyaltyPoints(ctx context.Context, id string, payload *loyalty.AdjustRequest) (*loyalty.Entry, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &loyalty.Entry{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &loyalty.Entry{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// adjust the points
+	entry, err := loyalty.Adjust(ctx, id, payload, time.Now())
+	if err != nil {
+		return &loyalty.Entry{}, loyaltyError(err)
+	}
+
+	return entry, nil
+}
+
+// ReconcileLoyaltyLedger - Check the ledger of a customer adds up
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return response
+//	@return error
+//
+// encore:api auth method=GET path=/loyalty/customers/:id/reconcile
+func ReconcileLoyaltyLedger(ctx context.Context, id string) (*loyalty.ReconcileResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &loyalty.ReconcileResponse{}, err
+	}
+
+	// check the ledger
+	response, err := loyalty.ReconcileUser(ctx, id)
+	if err != nil {
+		return &loyalty.ReconcileResponse{}, err
+	}
+
+	return response, nil
+}
+
+// ExpireLoyaltyPoints - Expire the points that were not spent in time
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/loyalty/expire
+func ExpireLoyaltyPoints(ctx context.Context) (*loyalty.ExpireResponse, error) {
+	// expire the points
+	response, err := loyalty.ExpirePoints(ctx, time.Now())
+	if err != nil {
+		return &loyalty.ExpireResponse{}, err
+	}
+
+	rlog.Info("customers.ExpireLoyaltyPoints", "entries", response.Entries, "points", response.Points)
+
+	return response, nil
+}
+
+// loyaltyError - maps loyalty store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func loyaltyError(err error) error {
+	switch {
+	case errors.Is(err, loyalty.ErrTierNotFound), errors.Is(err, loyalty.ErrMultiplierNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, loyalty.ErrAlreadyRecorded):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, loyalty.ErrInvalidProgram), errors.Is(err, loyalty.ErrInvalidEarn), errors.Is(err, loyalty.ErrInvalidRedemption),
+		pagination.IsQueryError(err):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, loyalty.ErrNoPoints), errors.Is(err, loyalty.ErrInsufficientPoints):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	case errors.Is(err, loyalty.ErrUnbalanced):
+		return &errs.Error{Code: errs.DataLoss, Message: err.Error()}
+	default:
+		return err
+	}
+}
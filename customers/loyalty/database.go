@@ -0,0 +1,795 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/money"
+	"encore.app/pkg/pagination"
+)
+
+// the customers database
+var loyaltyDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("customers").Stdlib(), "postgres")
+})
+
+// the columns of the program
+const programColumns = "currency, points_per_unit, point_value, expiry_days, tier_window_days, updated_at"
+
+// GetProgram - GetProgram is a function that gets the settings of the points program.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @return program
+// @return error
+func GetProgram(ctx context.Context, db sqlx.ExtContext) (*Program, error) {
+	var program Program
+
+	// execute query
+	if err := database.NamedStructQuery(ctx, db, fmt.Sprintf("SELECT %v FROM loyalty_program", programColumns), map[string]interface{}{}, &program); err != nil {
+		return nil, fmt.Errorf("selecting loyalty program: %w", err)
+	}
+
+	return &program, nil
+}
+
+// SaveProgram - SaveProgram is a function that replaces the settings of the points program.
+// Points already earned keep their expiry.
+//
+// @param ctx - context.Context
+// @param payload - *ProgramRequest
+// @return program
+// @return error
+func SaveProgram(ctx context.Context, payload *ProgramRequest) (*Program, error) {
+	program := Program{
+		Currency:       strings.ToUpper(strings.TrimSpace(payload.Currency)),
+		PointsPerUnit:  payload.PointsPerUnit,
+		PointValue:     payload.PointValue,
+		ExpiryDays:     payload.ExpiryDays,
+		TierWindowDays: payload.TierWindowDays,
+		UpdatedAt:      time.Now().UTC(),
+	}
+
+	// check the settings
+	if err := program.Validate(); err != nil {
+		return nil, err
+	}
+
+	// query statement to be executed
+	q := `
+    UPDATE loyalty_program SET
+      currency = :currency, points_per_unit = :points_per_unit, point_value = :point_value,
+      expiry_days = :expiry_days, tier_window_days = :tier_window_days, updated_at = :updated_at
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, loyaltyDatabase(), q, program); err != nil {
+		return nil, fmt.Errorf("updating loyalty program: %w", err)
+	}
+
+	return &program, nil
+}
+
+// GetSettings - GetSettings is a function that gets the program with its multipliers and tiers.
+//
+// @param ctx - context.Context
+// @return settings
+// @return error
+func GetSettings(ctx context.Context) (*ProgramResponse, error) {
+	program, err := GetProgram(ctx, loyaltyDatabase())
+	if err != nil {
+		return nil, err
+	}
+	multipliers, err := ListMultipliers(ctx, loyaltyDatabase())
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := ListTiers(ctx, loyaltyDatabase())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProgramResponse{Program: program, Multipliers: multipliers, Tiers: tiers}, nil
+}
+
+// ListMultipliers - ListMultipliers is a function that gets the multipliers of the categories.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @return multipliers
+// @return error
+func ListMultipliers(ctx context.Context, db sqlx.ExtContext) ([]Multiplier, error) {
+	multipliers := make([]Multiplier, 0)
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, db, "SELECT * FROM loyalty_category_multipliers ORDER BY category_id", map[string]interface{}{}, &multipliers); err != nil {
+		return nil, fmt.Errorf("selecting category multipliers: %w", err)
+	}
+
+	return multipliers, nil
+}
+
+// SetMultiplier - SetMultiplier is a function that sets the points earned on a category.
+//
+// @param ctx - context.Context
+// @param categoryId - string
+// @param payload - *MultiplierRequest
+// @return multiplier
+// @return error
+func SetMultiplier(ctx context.Context, categoryId string, payload *MultiplierRequest) (*Multiplier, error) {
+	multiplier := Multiplier{CategoryId: categoryId, Multiplier: payload.Multiplier, UpdatedAt: time.Now().UTC()}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO loyalty_category_multipliers (category_id, multiplier, updated_at)
+    VALUES (:category_id, :multiplier, :updated_at)
+    ON CONFLICT (category_id) DO UPDATE SET multiplier = EXCLUDED.multiplier, updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, loyaltyDatabase(), q, multiplier); err != nil {
+		return nil, fmt.Errorf("setting category multiplier: %w", err)
+	}
+
+	return &multiplier, nil
+}
+
+// DeleteMultiplier - DeleteMultiplier is a function that makes a category earn the normal points again.
+//
+// @param ctx - context.Context
+// @param categoryId - string
+// @return error
+func DeleteMultiplier(ctx context.Context, categoryId string) error {
+	count, err := database.NamedCountQuery(ctx, loyaltyDatabase(), "SELECT COUNT(*) FROM loyalty_category_multipliers WHERE category_id = :category_id", map[string]interface{}{
+		"category_id": categoryId,
+	})
+	if err != nil {
+		return fmt.Errorf("counting category multipliers: %w", err)
+	}
+	if count < 1 {
+		return ErrMultiplierNotFound
+	}
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, loyaltyDatabase(), "DELETE FROM loyalty_category_multipliers WHERE category_id = :category_id", map[string]interface{}{
+		"category_id": categoryId,
+	}); err != nil {
+		return fmt.Errorf("deleting category multiplier: %w", err)
+	}
+
+	return nil
+}
+
+// ListTiers - ListTiers is a function that gets the tiers, lowest first.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @return tiers
+// @return error
+func ListTiers(ctx context.Context, db sqlx.ExtContext) ([]Tier, error) {
+	tiers := make([]Tier, 0)
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, db, "SELECT * FROM loyalty_tiers ORDER BY (min_spend).amount, code", map[string]interface{}{}, &tiers); err != nil {
+		return nil, fmt.Errorf("selecting loyalty tiers: %w", err)
+	}
+
+	return tiers, nil
+}
+
+// SaveTier - SaveTier is a function that creates a tier or replaces its settings.
+//
+// @param ctx - context.Context
+// @param payload - *TierRequest
+// @return tier
+// @return error
+func SaveTier(ctx context.Context, payload *TierRequest) (*Tier, error) {
+	now := time.Now().UTC()
+	tier := Tier{
+		Code:       strings.ToLower(strings.TrimSpace(payload.Code)),
+		Name:       payload.Name,
+		MinSpend:   payload.MinSpend,
+		Multiplier: payload.Multiplier,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	// the spend is in the currency of the program
+	program, err := GetProgram(ctx, loyaltyDatabase())
+	if err != nil {
+		return nil, err
+	}
+	if tier.MinSpend.Currency != program.Currency || tier.MinSpend.IsNegative() {
+		return nil, fmt.Errorf("%w: the spend of a tier must be a positive amount in %v", ErrInvalidProgram, program.Currency)
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO loyalty_tiers (code, name, min_spend, multiplier, created_at, updated_at)
+    VALUES (:code, :name, :min_spend, :multiplier, :created_at, :updated_at)
+    ON CONFLICT (code) DO UPDATE SET
+      name = EXCLUDED.name, min_spend = EXCLUDED.min_spend, multiplier = EXCLUDED.multiplier, updated_at = EXCLUDED.updated_at
+    RETURNING *
+  `
+
+	// execute query
+	var saved Tier
+	if err := database.NamedStructQuery(ctx, loyaltyDatabase(), q, tier, &saved); err != nil {
+		return nil, fmt.Errorf("saving loyalty tier: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// DeleteTier - DeleteTier is a function that deletes a tier.
+//
+// @param ctx - context.Context
+// @param code - string
+// @return error
+func DeleteTier(ctx context.Context, code string) error {
+	count, err := database.NamedCountQuery(ctx, loyaltyDatabase(), "SELECT COUNT(*) FROM loyalty_tiers WHERE code = :code", map[string]interface{}{
+		"code": code,
+	})
+	if err != nil {
+		return fmt.Errorf("counting loyalty tiers: %w", err)
+	}
+	if count < 1 {
+		return ErrTierNotFound
+	}
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, loyaltyDatabase(), "DELETE FROM loyalty_tiers WHERE code = :code", map[string]interface{}{
+		"code": code,
+	}); err != nil {
+		return fmt.Errorf("deleting loyalty tier: %w", err)
+	}
+
+	return nil
+}
+
+// lockUser - serializes the entries of a customer within a transaction.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @return error
+func lockUser(ctx context.Context, tx *sqlx.Tx, userId string) error {
+	if err := database.NamedExecQuery(ctx, tx, "SELECT pg_advisory_xact_lock(hashtext('loyalty:' || :user_id))", map[string]interface{}{
+		"user_id": userId,
+	}); err != nil {
+		return fmt.Errorf("locking loyalty ledger: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUser - DeleteUser is a function that removes the ledger of a deleted user. Entries are otherwise never
+// removed, so the transaction first allows the append-only trigger to let the removal through.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @return the rows removed, by what they were
+// @return error
+func DeleteUser(ctx context.Context, tx *sqlx.Tx, userId string) (map[string]int, error) {
+	if err := lockUser(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+	if err := database.NamedExecQuery(ctx, tx, "SELECT set_config('loyalty.erase', 'on', true)", map[string]interface{}{}); err != nil {
+		return nil, fmt.Errorf("allowing ledger removal: %w", err)
+	}
+
+	count, err := database.NamedCountQuery(ctx, tx, `
+    WITH removed AS (DELETE FROM loyalty_entries WHERE user_id = :user_id RETURNING id)
+    SELECT COUNT(*) FROM removed
+  `, map[string]interface{}{"user_id": userId})
+	if err != nil {
+		return nil, fmt.Errorf("deleting loyalty entries: %w", err)
+	}
+
+	return map[string]int{"loyaltyEntries": count}, nil
+}
+
+// entries - gets the whole ledger of a customer in order.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @return entries
+// @return error
+func entries(ctx context.Context, db sqlx.ExtContext, userId string) ([]Entry, error) {
+	entries := make([]Entry, 0)
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, db, "SELECT * FROM loyalty_entries WHERE user_id = :user_id ORDER BY sequence", map[string]interface{}{
+		"user_id": userId,
+	}, &entries); err != nil {
+		return nil, fmt.Errorf("selecting loyalty entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// appendEntry - appends an entry to the ledger of a customer, after its latest entry.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param entry - *Entry
+// @return error
+func appendEntry(ctx context.Context, tx *sqlx.Tx, entry *Entry) error {
+	// get the latest entry
+	var latest struct {
+		Sequence     int64 `db:"sequence"`
+		BalanceAfter int64 `db:"balance_after"`
+	}
+	if err := database.NamedStructQuery(ctx, tx, "SELECT sequence, balance_after FROM loyalty_entries WHERE user_id = :user_id ORDER BY sequence DESC LIMIT 1", map[string]interface{}{
+		"user_id": entry.UserId,
+	}, &latest); err != nil && !errors.Is(err, database.ErrNotFound) {
+		return fmt.Errorf("selecting latest loyalty entry: %w", err)
+	}
+
+	entry.Id = uuid.New().String()
+	entry.Sequence = latest.Sequence + 1
+	entry.BalanceAfter = latest.BalanceAfter + entry.Points
+	if entry.BalanceAfter < 0 {
+		return fmt.Errorf("%w: the balance is %v points", ErrInsufficientPoints, latest.BalanceAfter)
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO loyalty_entries (
+      id, user_id, sequence, kind, points, balance_after, spend, value, reference, source_id, expires_at, note, created_at
+    )
+    VALUES (
+      :id, :user_id, :sequence, :kind, :points, :balance_after, :spend, :value, :reference, :source_id, :expires_at, :note, :created_at
+    )
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, tx, q, entry); err != nil {
+		return fmt.Errorf("inserting loyalty entry: %w", err)
+	}
+
+	return nil
+}
+
+// checkReference - checks points were not already earned or redeemed for an order.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @param kind - string
+// @param reference - string
+// @return error
+func checkReference(ctx context.Context, tx *sqlx.Tx, userId, kind, reference string) error {
+	count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM loyalty_entries WHERE user_id = :user_id AND kind = :kind AND reference = :reference", map[string]interface{}{
+		"user_id":   userId,
+		"kind":      kind,
+		"reference": reference,
+	})
+	if err != nil {
+		return fmt.Errorf("counting loyalty entries: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %v", ErrAlreadyRecorded, reference)
+	}
+
+	return nil
+}
+
+// rollingSpend - gets the spend of a customer over the tier window.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @param program - *Program
+// @param now - time.Time
+// @return money.Money
+// @return error
+func rollingSpend(ctx context.Context, db sqlx.ExtContext, userId string, program *Program, now time.Time) (money.Money, error) {
+	// query statement to be executed
+	q := `
+    SELECT COALESCE(SUM((spend).amount), 0) FROM loyalty_entries
+    WHERE user_id = :user_id AND kind = 'earn' AND (spend).currency = :currency AND created_at > :since
+  `
+
+	// execute query
+	spend, err := database.NamedCountQuery(ctx, db, q, map[string]interface{}{
+		"user_id":  userId,
+		"currency": program.Currency,
+		"since":    now.AddDate(0, 0, -program.TierWindowDays),
+	})
+	if err != nil {
+		return money.Money{}, fmt.Errorf("summing loyalty spend: %w", err)
+	}
+
+	return money.Money{Amount: int64(spend), Currency: program.Currency}, nil
+}
+
+// expireUser - appends an expire entry for every lot of a customer that expired, and settles the lapsed lots
+// so they are not picked up again, including the lots spent before they expired.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @param now - time.Time
+// @return expired - the expire entries
+// @return error
+func expireUser(ctx context.Context, tx *sqlx.Tx, userId string, now time.Time) ([]Entry, error) {
+	ledger, err := entries(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	lots, err := Lots(ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := make([]Entry, 0)
+	for _, lot := range Expired(lots, now) {
+		source := lot.EntryId
+		entry := Entry{
+			UserId:    userId,
+			Kind:      KindExpire,
+			Points:    -lot.Remaining,
+			SourceId:  &source,
+			Note:      fmt.Sprintf("expired at %v", lot.ExpiresAt.UTC().Format(time.RFC3339)),
+			CreatedAt: now,
+		}
+		if err := appendEntry(ctx, tx, &entry); err != nil {
+			return nil, err
+		}
+		expired = append(expired, entry)
+	}
+
+	// settle the lapsed lots
+	ids := make([]string, 0)
+	for _, lot := range Lapsed(lots, now) {
+		ids = append(ids, lot.EntryId)
+	}
+	if len(ids) > 0 {
+		if err := database.NamedExecQuery(ctx, tx, `
+      INSERT INTO loyalty_settled_lots (entry_id, user_id, settled_at)
+      SELECT id, user_id, :now FROM loyalty_entries WHERE id = ANY(CAST(:ids AS UUID[]))
+      ON CONFLICT (entry_id) DO NOTHING
+    `, map[string]interface{}{"ids": ids, "now": now}); err != nil {
+			return nil, fmt.Errorf("settling loyalty lots: %w", err)
+		}
+	}
+
+	return expired, nil
+}
+
+// Earn - Earn is a function that gives a customer the points of an order.
+//
+// @param ctx - context.Context
+// @param payload - *EarnRequest
+// @param now - time.Time
+// @return entry
+// @return error
+func Earn(ctx context.Context, payload *EarnRequest, now time.Time) (*Entry, error) {
+	now = now.UTC()
+	entry := Entry{UserId: payload.UserId, Kind: KindEarn, Reference: payload.Reference, CreatedAt: now}
+
+	if err := database.Transaction(ctx, loyaltyDatabase(), func(tx *sqlx.Tx) error {
+		if err := lockUser(ctx, tx, payload.UserId); err != nil {
+			return err
+		}
+		if err := checkReference(ctx, tx, payload.UserId, KindEarn, payload.Reference); err != nil {
+			return err
+		}
+
+		// get the rules
+		program, err := GetProgram(ctx, tx)
+		if err != nil {
+			return err
+		}
+		multipliers, err := ListMultipliers(ctx, tx)
+		if err != nil {
+			return err
+		}
+		byCategory := make(map[string]int64, len(multipliers))
+		for _, multiplier := range multipliers {
+			byCategory[multiplier.CategoryId] = multiplier.Multiplier
+		}
+		tiers, err := ListTiers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		// get the tier of the customer before the order
+		spent, err := rollingSpend(ctx, tx, payload.UserId, program, now)
+		if err != nil {
+			return err
+		}
+		tier, _ := TierFor(tiers, spent)
+
+		// work out the points
+		points, spend, err := program.Earn(tier, byCategory, payload.Lines)
+		if err != nil {
+			return err
+		}
+		if points < 1 {
+			return ErrNoPoints
+		}
+		entry.Points = points
+		entry.Spend = &spend
+		entry.ExpiresAt = program.ExpiresAt(now)
+		if tier != nil {
+			entry.Note = fmt.Sprintf("earned in the %v tier", tier.Name)
+		}
+
+		return appendEntry(ctx, tx, &entry)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// Redeem - Redeem is a function that spends the points of a customer on an order.
+// Expired points are expired first, so they cannot be spent.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param payload - *RedeemRequest
+// @param now - time.Time
+// @return entry
+// @return error
+func Redeem(ctx context.Context, userId string, payload *RedeemRequest, now time.Time) (*Entry, error) {
+	now = now.UTC()
+	entry := Entry{UserId: userId, Kind: KindRedeem, Points: -payload.Points, Reference: payload.Reference, CreatedAt: now}
+
+	if err := database.Transaction(ctx, loyaltyDatabase(), func(tx *sqlx.Tx) error {
+		if err := lockUser(ctx, tx, userId); err != nil {
+			return err
+		}
+		if err := checkReference(ctx, tx, userId, KindRedeem, payload.Reference); err != nil {
+			return err
+		}
+
+		// work out what the points are worth
+		program, err := GetProgram(ctx, tx)
+		if err != nil {
+			return err
+		}
+		value, err := program.PointValue.Mul(payload.Points)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRedemption, err)
+		}
+		if payload.OrderTotal != nil {
+			if cmp, err := value.Cmp(*payload.OrderTotal); err != nil || cmp > 0 {
+				return fmt.Errorf("%w: %v points are worth more than the order", ErrInvalidRedemption, payload.Points)
+			}
+		}
+		entry.Value = &value
+
+		// expire the points that can no longer be spent
+		if _, err := expireUser(ctx, tx, userId, now); err != nil {
+			return err
+		}
+
+		return appendEntry(ctx, tx, &entry)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// Adjust - Adjust is a function that gives or takes points of a customer.
+// Points given expire like earned points, points taken are taken after the expired points are expired.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param payload - *AdjustRequest
+// @param now - time.Time
+// @return entry
+// @return error
+func Adjust(ctx context.Context, userId string, payload *AdjustRequest, now time.Time) (*Entry, error) {
+	now = now.UTC()
+	entry := Entry{UserId: userId, Kind: KindAdjust, Points: payload.Points, Note: payload.Note, CreatedAt: now}
+
+	if err := database.Transaction(ctx, loyaltyDatabase(), func(tx *sqlx.Tx) error {
+		if err := lockUser(ctx, tx, userId); err != nil {
+			return err
+		}
+
+		// points given expire, points taken are taken once the points that can no longer be spent are expired
+		if payload.Points > 0 {
+			program, err := GetProgram(ctx, tx)
+			if err != nil {
+				return err
+			}
+			entry.ExpiresAt = program.ExpiresAt(now)
+		} else if _, err := expireUser(ctx, tx, userId, now); err != nil {
+			return err
+		}
+
+		return appendEntry(ctx, tx, &entry)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// GetBalance - GetBalance is a function that gets the points of a customer, their tier and the points that will expire.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param now - time.Time
+// @return balance
+// @return error
+func GetBalance(ctx context.Context, userId string, now time.Time) (*Balance, error) {
+	program, err := GetProgram(ctx, loyaltyDatabase())
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := ListTiers(ctx, loyaltyDatabase())
+	if err != nil {
+		return nil, err
+	}
+
+	// replay the ledger
+	ledger, err := entries(ctx, loyaltyDatabase(), userId)
+	if err != nil {
+		return nil, err
+	}
+	lots, err := Lots(ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &Balance{UserId: userId, Value: money.Zero(program.Currency), Expiring: Expiring(lots)}
+	if len(ledger) > 0 {
+		balance.Points = ledger[len(ledger)-1].BalanceAfter
+	}
+	if balance.Value, err = program.PointValue.Mul(balance.Points); err != nil {
+		return nil, err
+	}
+
+	// work out the tier
+	if balance.RollingSpend, err = rollingSpend(ctx, loyaltyDatabase(), userId, program, now); err != nil {
+		return nil, err
+	}
+	balance.Tier, balance.NextTier = TierFor(tiers, balance.RollingSpend)
+	if balance.NextTier != nil {
+		missing, err := balance.NextTier.MinSpend.Sub(balance.RollingSpend)
+		if err != nil {
+			return nil, err
+		}
+		balance.SpendToNextTier = &missing
+	}
+
+	return balance, nil
+}
+
+// entriesSchema - the fields of a loyalty entry allowed in list queries
+var entriesSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID},
+		{Name: "sequence", Column: "sequence", Type: pagination.Number, Sortable: true},
+		{Name: "kind", Column: "kind", Type: pagination.String},
+		{Name: "points", Column: "points", Type: pagination.Number, Sortable: true},
+		{Name: "reference", Column: "reference", Type: pagination.String},
+		{Name: "expiresAt", Column: "expires_at", Type: pagination.Time},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-sequence",
+}
+
+// History - History is a function that gets the entries of a customer, latest first.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param params - *EntriesQuery
+// @return entries
+// @return error
+func History(ctx context.Context, userId string, params *EntriesQuery) (*PaginatedEntriesResponse, error) {
+	// parse the filter and sort
+	statement, err := entriesSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	statement.AddCondition("user_id = CAST(:user_id AS UUID)", map[string]interface{}{"user_id": userId})
+
+	// execute query
+	history := make([]Entry, 0)
+	page, err := database.NamedPageQuery(ctx, loyaltyDatabase(), "loyalty_entries", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &history)
+	if err != nil {
+		return nil, fmt.Errorf("selecting loyalty entries: %w", err)
+	}
+
+	return &PaginatedEntriesResponse{
+		Entries:         history,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// ReconcileUser - ReconcileUser is a function that checks the ledger of a customer adds up.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @return response
+// @return error
+func ReconcileUser(ctx context.Context, userId string) (*ReconcileResponse, error) {
+	ledger, err := entries(ctx, loyaltyDatabase(), userId)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := Reconcile(ledger)
+	response := &ReconcileResponse{UserId: userId, Entries: len(ledger), Reconciled: len(problems) < 1, Problems: problems}
+	if len(ledger) > 0 {
+		response.Points = ledger[len(ledger)-1].BalanceAfter
+	}
+
+	return response, nil
+}
+
+// ExpirePoints - ExpirePoints is a function that expires the points of every customer that were not spent in time.
+//
+// @param ctx - context.Context
+// @param now - time.Time
+// @return response
+// @return error
+func ExpirePoints(ctx context.Context, now time.Time) (*ExpireResponse, error) {
+	now = now.UTC()
+
+	// get the customers with lapsed lots that have not been settled yet
+	q := `
+    SELECT DISTINCT e.user_id FROM loyalty_entries e
+    WHERE e.points > 0 AND e.expires_at <= :now
+      AND NOT EXISTS (SELECT 1 FROM loyalty_settled_lots l WHERE l.entry_id = e.id)
+  `
+	var users []struct {
+		UserId string `db:"user_id"`
+	}
+	if err := database.NamedSliceQuery(ctx, loyaltyDatabase(), q, map[string]interface{}{"now": now}, &users); err != nil {
+		return nil, fmt.Errorf("selecting customers with expired points: %w", err)
+	}
+
+	// expire the points of every customer on its own, so one ledger cannot hold up the others
+	response := &ExpireResponse{}
+	for _, user := range users {
+		var expired []Entry
+		if err := database.Transaction(ctx, loyaltyDatabase(), func(tx *sqlx.Tx) error {
+			if err := lockUser(ctx, tx, user.UserId); err != nil {
+				return err
+			}
+			var err error
+			expired, err = expireUser(ctx, tx, user.UserId, now)
+			return err
+		}); err != nil {
+			return response, fmt.Errorf("expiring points of customer %v: %w", user.UserId, err)
+		}
+
+		for _, entry := range expired {
+			response.Entries++
+			response.Points -= entry.Points
+		}
+	}
+
+	return response, nil
+}
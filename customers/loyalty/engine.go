@@ -0,0 +1,277 @@
+package loyalty
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+// Validate - Validate checks the settings of the program.
+//
+// @return error
+func (p *Program) Validate() error {
+	if !money.IsCurrency(p.Currency) {
+		return fmt.Errorf("%w: unknown currency %v", ErrInvalidProgram, p.Currency)
+	}
+	if p.PointValue.Currency != p.Currency || p.PointValue.Amount < 1 {
+		return fmt.Errorf("%w: a point must be worth more than nothing in %v", ErrInvalidProgram, p.Currency)
+	}
+	if p.PointsPerUnit < 0 || p.ExpiryDays < 0 || p.TierWindowDays < 1 {
+		return fmt.Errorf("%w: the points, expiry and tier window cannot be negative", ErrInvalidProgram)
+	}
+
+	return nil
+}
+
+// ExpiresAt - ExpiresAt returns when points earned at a time expire, nil when they never do.
+//
+// @param at - time.Time
+// @return *time.Time
+func (p *Program) ExpiresAt(at time.Time) *time.Time {
+	if p.ExpiryDays < 1 {
+		return nil
+	}
+
+	expiresAt := at.AddDate(0, 0, p.ExpiryDays)
+	return &expiresAt
+}
+
+// Earn - Earn works out the points of an order and the spend they count towards the tier.
+// Every line earns the points of the program times the multiplier of its category, the whole order
+// is then multiplied by the tier. Fractions of points are dropped once, on the whole order.
+//
+// @param tier - the tier of the customer, nil when none
+// @param multipliers - the multipliers by category id
+// @param lines - []EarnLine
+// @return points
+// @return spend
+// @return error
+func (p *Program) Earn(tier *Tier, multipliers map[string]int64, lines []EarnLine) (int64, money.Money, error) {
+	currency, ok := money.LookupCurrency(p.Currency)
+	if !ok {
+		return 0, money.Money{}, fmt.Errorf("%w: unknown currency %v", ErrInvalidProgram, p.Currency)
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currency.Exponent)), nil)
+
+	points := new(big.Rat)
+	spend := money.Zero(currency.Code)
+	for _, line := range lines {
+		if line.Amount.Currency != currency.Code || line.Amount.IsNegative() {
+			return 0, money.Money{}, fmt.Errorf("%w: amounts must be positive amounts in %v", ErrInvalidEarn, currency.Code)
+		}
+		spend.Amount += line.Amount.Amount
+
+		// amount / unit * points per unit * multiplier / basis points
+		multiplier, ok := multipliers[line.CategoryId]
+		if !ok {
+			multiplier = BasisPoints
+		}
+		earned := new(big.Rat).SetFrac(
+			new(big.Int).Mul(big.NewInt(line.Amount.Amount), big.NewInt(p.PointsPerUnit*multiplier)),
+			new(big.Int).Mul(unit, big.NewInt(BasisPoints)),
+		)
+		points.Add(points, earned)
+	}
+
+	// multiply by the tier
+	if tier != nil {
+		points.Mul(points, big.NewRat(tier.Multiplier, BasisPoints))
+	}
+
+	return money.Round(points.Num(), points.Denom(), money.Floor).Int64(), spend, nil
+}
+
+// TierFor - TierFor returns the tier reached by a spend and the tier after it, nil when there is none.
+//
+// @param tiers - []Tier
+// @param spend - money.Money
+// @return tier
+// @return next
+func TierFor(tiers []Tier, spend money.Money) (*Tier, *Tier) {
+	// order the tiers of the currency by the spend that reaches them
+	sorted := make([]Tier, 0, len(tiers))
+	for _, tier := range tiers {
+		if tier.MinSpend.Currency == spend.Currency {
+			sorted = append(sorted, tier)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].MinSpend.Amount < sorted[j].MinSpend.Amount
+	})
+
+	var tier, next *Tier
+	for i := range sorted {
+		if sorted[i].MinSpend.Amount <= spend.Amount {
+			tier = &sorted[i]
+		} else {
+			next = &sorted[i]
+			break
+		}
+	}
+
+	return tier, next
+}
+
+// Lots - Lots replays a ledger in order and returns what is left of the points of every entry that gave points.
+// Expired points are taken from the entry they expire, every other spent point is taken from the oldest points.
+//
+// @param entries - the entries of a customer ordered by sequence
+// @return lots
+// @return error
+func Lots(entries []Entry) ([]Lot, error) {
+	lots := make([]Lot, 0)
+	index := make(map[string]int)
+
+	for _, entry := range entries {
+		// the entry gives points
+		if entry.Points > 0 {
+			index[entry.Id] = len(lots)
+			lots = append(lots, Lot{
+				EntryId:   entry.Id,
+				Points:    entry.Points,
+				Remaining: entry.Points,
+				ExpiresAt: entry.ExpiresAt,
+				CreatedAt: entry.CreatedAt,
+			})
+			continue
+		}
+
+		// the entry expires the points of another entry
+		need := -entry.Points
+		if entry.Kind == KindExpire {
+			i, ok := index[derefString(entry.SourceId)]
+			if !ok || lots[i].Remaining < need {
+				return nil, fmt.Errorf("%w: entry %v expires more points than are left of entry %v", ErrUnbalanced, entry.Sequence, derefString(entry.SourceId))
+			}
+			lots[i].Remaining -= need
+			continue
+		}
+
+		// the entry spends the oldest points
+		for i := range lots {
+			if need < 1 {
+				break
+			}
+			taken := lots[i].Remaining
+			if taken > need {
+				taken = need
+			}
+			lots[i].Remaining -= taken
+			need -= taken
+		}
+		if need > 0 {
+			return nil, fmt.Errorf("%w: entry %v spends %v points more than there are", ErrUnbalanced, entry.Sequence, need)
+		}
+	}
+
+	return lots, nil
+}
+
+// Expired - Expired returns the lots with points left that have expired at a time.
+//
+// @param lots - []Lot
+// @param now - time.Time
+// @return []Lot
+func Expired(lots []Lot, now time.Time) []Lot {
+	expired := make([]Lot, 0)
+	for _, lot := range lots {
+		if lot.Remaining > 0 && lot.ExpiresAt != nil && !now.Before(*lot.ExpiresAt) {
+			expired = append(expired, lot)
+		}
+	}
+
+	return expired
+}
+
+// Lapsed - Lapsed returns the lots that have expired at a time, whether or not points are left of them.
+//
+// @param lots - []Lot
+// @param now - time.Time
+// @return []Lot
+func Lapsed(lots []Lot, now time.Time) []Lot {
+	lapsed := make([]Lot, 0)
+	for _, lot := range lots {
+		if lot.ExpiresAt != nil && !now.Before(*lot.ExpiresAt) {
+			lapsed = append(lapsed, lot)
+		}
+	}
+
+	return lapsed
+}
+
+// Expiring - Expiring returns the lots with points left that will expire, soonest first.
+//
+// @param lots - []Lot
+// @return []Lot
+func Expiring(lots []Lot) []Lot {
+	expiring := make([]Lot, 0)
+	for _, lot := range lots {
+		if lot.Remaining > 0 && lot.ExpiresAt != nil {
+			expiring = append(expiring, lot)
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].ExpiresAt.Before(*expiring[j].ExpiresAt)
+	})
+
+	return expiring
+}
+
+// Reconcile - Reconcile checks a ledger adds up, it returns the problems found.
+// The entries must follow each other, every balance must be the balance before it plus the points of
+// the entry, and the points left of every lot must add up to the last balance.
+//
+// @param entries - the entries of a customer ordered by sequence
+// @return []string
+func Reconcile(entries []Entry) []string {
+	problems := make([]string, 0)
+
+	// check the running balance
+	var balance int64
+	for i, entry := range entries {
+		if entry.Sequence != int64(i+1) {
+			problems = append(problems, fmt.Sprintf("entry %v follows entry %v", entry.Sequence, i))
+		}
+		if entry.Points == 0 {
+			problems = append(problems, fmt.Sprintf("entry %v has no points", entry.Sequence))
+		}
+		balance += entry.Points
+		if entry.BalanceAfter != balance {
+			problems = append(problems, fmt.Sprintf("entry %v has a balance of %v, the entries add up to %v", entry.Sequence, entry.BalanceAfter, balance))
+			balance = entry.BalanceAfter
+		}
+		if balance < 0 {
+			problems = append(problems, fmt.Sprintf("entry %v leaves a negative balance of %v", entry.Sequence, balance))
+		}
+	}
+
+	// check the lots
+	lots, err := Lots(entries)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	var remaining int64
+	for _, lot := range lots {
+		remaining += lot.Remaining
+	}
+	if remaining != balance {
+		problems = append(problems, fmt.Sprintf("%v points are left of the entries, the balance is %v", remaining, balance))
+	}
+
+	return problems
+}
+
+// derefString - returns the value of a string pointer, or an empty string when nil.
+//
+// @param s - *string
+// @return string
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -0,0 +1,195 @@
+package loyalty
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"encore.app/pkg/money"
+	"encore.app/pkg/money/moneytest"
+)
+
+// the time the points are earned at
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// program - a program earning a point per dollar, with points worth a cent for a year
+var program = Program{Currency: "USD", PointsPerUnit: 1, PointValue: money.Money{Amount: 1, Currency: "USD"}, ExpiryDays: 365, TierWindowDays: 365}
+
+// TestEarn - test the points earned on orders
+//
+//	@param t - testing.T
+func TestEarn(t *testing.T) {
+	gold := &Tier{Code: "gold", Multiplier: 15000}
+	multipliers := map[string]int64{"groceries": 20000, "tobacco": 0}
+
+	// create a slice
+	slice := []struct {
+		name   string
+		tier   *Tier
+		lines  []EarnLine
+		points int64
+		spend  int64
+	}{
+		{name: "fractions are dropped", lines: []EarnLine{{Amount: moneytest.USD(1999)}}, points: 19, spend: 1999},
+		{name: "fractions add up over lines", lines: []EarnLine{{Amount: moneytest.USD(1050)}, {Amount: moneytest.USD(1050)}}, points: 21, spend: 2100},
+		{name: "category multipliers", lines: []EarnLine{{CategoryId: "groceries", Amount: moneytest.USD(1000)}, {CategoryId: "tobacco", Amount: moneytest.USD(5000)}, {Amount: moneytest.USD(500)}}, points: 25, spend: 6500},
+		{name: "tier multiplier", tier: gold, lines: []EarnLine{{CategoryId: "groceries", Amount: moneytest.USD(1000)}, {Amount: moneytest.USD(500)}}, points: 37, spend: 1500},
+	}
+
+	// check every order
+	for _, item := range slice {
+		points, spend, err := program.Earn(item.tier, multipliers, item.lines)
+		if err != nil {
+			t.Errorf("%v: should earn, got %v", item.name, err)
+			continue
+		}
+		if points != item.points || spend != moneytest.USD(item.spend) {
+			t.Errorf("%v: should earn %v on %v, got %v on %v", item.name, item.points, item.spend, points, spend.Amount)
+		}
+	}
+
+	// amounts in another currency are rejected
+	if _, _, err := program.Earn(nil, nil, []EarnLine{{Amount: money.Money{Amount: 100, Currency: "EUR"}}}); !errors.Is(err, ErrInvalidEarn) {
+		t.Errorf("should reject other currencies, got %v", err)
+	}
+}
+
+// TestTierFor - test the tier reached by a spend
+//
+//	@param t - testing.T
+func TestTierFor(t *testing.T) {
+	tiers := []Tier{
+		{Code: "gold", MinSpend: moneytest.USD(100000)},
+		{Code: "silver", MinSpend: moneytest.USD(25000)},
+		{Code: "bronze", MinSpend: moneytest.USD(0)},
+	}
+
+	for spend, want := range map[int64][2]string{0: {"bronze", "silver"}, 25000: {"silver", "gold"}, 99999: {"silver", "gold"}, 500000: {"gold", ""}} {
+		tier, next := TierFor(tiers, moneytest.USD(spend))
+		got := [2]string{}
+		if tier != nil {
+			got[0] = tier.Code
+		}
+		if next != nil {
+			got[1] = next.Code
+		}
+		if got != want {
+			t.Errorf("a spend of %v should reach %v, got %v", spend, want, got)
+		}
+	}
+}
+
+// ledger - creates the entries of a customer with their running balance.
+//
+//	@param entries - ...Entry
+//	@return []Entry
+func ledger(entries ...Entry) []Entry {
+	var balance int64
+	for i := range entries {
+		balance += entries[i].Points
+		entries[i].Sequence = int64(i + 1)
+		entries[i].BalanceAfter = balance
+	}
+
+	return entries
+}
+
+// TestLots - test spending and expiring points
+//
+//	@param t - testing.T
+func TestLots(t *testing.T) {
+	first, second := "first", "second"
+	soon, later := now.Add(time.Hour), now.AddDate(0, 6, 0)
+
+	entries := ledger(
+		Entry{Id: first, Kind: KindEarn, Points: 100, ExpiresAt: &soon},
+		Entry{Id: second, Kind: KindEarn, Points: 50, ExpiresAt: &later},
+		Entry{Kind: KindRedeem, Points: -70},
+	)
+	lots, err := Lots(entries)
+	if err != nil {
+		t.Fatalf("should replay, got %v", err)
+	}
+
+	// the oldest points are spent first
+	if lots[0].Remaining != 30 || lots[1].Remaining != 50 {
+		t.Errorf("30 and 50 points should be left, got %+v", lots)
+	}
+
+	// only what is left of the first entry expires
+	expired := Expired(lots, soon)
+	if len(expired) != 1 || expired[0].EntryId != first || expired[0].Remaining != 30 {
+		t.Errorf("30 points of the first entry should expire, got %+v", expired)
+	}
+	if lapsed := Lapsed(lots, later); len(lapsed) != 2 {
+		t.Errorf("both entries should have lapsed, got %+v", lapsed)
+	}
+	if expiring := Expiring(lots); len(expiring) != 2 || expiring[0].EntryId != first {
+		t.Errorf("the first entry should expire first, got %+v", expiring)
+	}
+
+	// once expired the ledger still reconciles
+	entries = ledger(append(entries, Entry{Kind: KindExpire, Points: -30, SourceId: &first})...)
+	if problems := Reconcile(entries); len(problems) > 0 {
+		t.Errorf("the ledger should reconcile, got %v", problems)
+	}
+	if lots, _ := Lots(entries); lots[0].Remaining != 0 || len(Expired(lots, later)) != 1 {
+		t.Errorf("only the second entry should be left to expire, got %+v", lots)
+	}
+
+	// a lot spent before it expired lapses with nothing to expire
+	spent := ledger(
+		Entry{Id: first, Kind: KindEarn, Points: 100, ExpiresAt: &soon},
+		Entry{Kind: KindRedeem, Points: -100},
+	)
+	if lots, _ := Lots(spent); len(Expired(lots, later)) != 0 || len(Lapsed(lots, later)) != 1 {
+		t.Errorf("the spent entry should lapse without expiring, got %+v", lots)
+	}
+
+	// expiring more than is left does not balance
+	if _, err := Lots(ledger(append(entries, Entry{Kind: KindExpire, Points: -1, SourceId: &first})...)); !errors.Is(err, ErrUnbalanced) {
+		t.Errorf("should not balance, got %v", err)
+	}
+}
+
+// TestReconcile - test the problems found in ledgers
+//
+//	@param t - testing.T
+func TestReconcile(t *testing.T) {
+	entries := ledger(
+		Entry{Id: "a", Kind: KindEarn, Points: 100},
+		Entry{Kind: KindRedeem, Points: -40},
+	)
+
+	// a changed balance
+	broken := append([]Entry{}, entries...)
+	broken[1].BalanceAfter = 70
+	if problems := Reconcile(broken); len(problems) != 2 || !strings.Contains(problems[0], "balance of 70") {
+		t.Errorf("the balance should not reconcile, got %v", problems)
+	}
+
+	// a missing entry
+	if problems := Reconcile(entries[1:]); len(problems) < 1 {
+		t.Errorf("a missing entry should not reconcile, got %v", problems)
+	}
+}
+
+// TestValidate - test the settings of a program
+//
+//	@param t - testing.T
+func TestValidate(t *testing.T) {
+	if err := program.Validate(); err != nil {
+		t.Errorf("the program should be valid, got %v", err)
+	}
+
+	invalid := program
+	invalid.PointValue = money.Money{Amount: 1, Currency: "EUR"}
+	if err := invalid.Validate(); !errors.Is(err, ErrInvalidProgram) {
+		t.Errorf("points worth another currency should be rejected, got %v", err)
+	}
+
+	if expiresAt := program.ExpiresAt(now); expiresAt == nil || !expiresAt.Equal(now.AddDate(1, 0, 0)) {
+		t.Errorf("points should expire a year later, got %v", expiresAt)
+	}
+}
@@ -0,0 +1,15 @@
+package loyalty
+
+import "errors"
+
+var (
+	ErrTierNotFound       = errors.New("loyalty tier not found")
+	ErrMultiplierNotFound = errors.New("category multiplier not found")
+	ErrInvalidProgram     = errors.New("invalid loyalty program")
+	ErrInvalidEarn        = errors.New("invalid points earning")
+	ErrNoPoints           = errors.New("the order earns no points")
+	ErrInvalidRedemption  = errors.New("invalid points redemption")
+	ErrInsufficientPoints = errors.New("not enough points")
+	ErrAlreadyRecorded    = errors.New("points already recorded for the order")
+	ErrUnbalanced         = errors.New("the loyalty ledger does not balance")
+)
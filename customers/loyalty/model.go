@@ -0,0 +1,163 @@
+package loyalty
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+const (
+	KindEarn   = "earn"   // points earned on an order
+	KindRedeem = "redeem" // points spent on an order
+	KindExpire = "expire" // points of an earlier entry that were not spent in time
+	KindAdjust = "adjust" // points given or taken by an admin
+
+	// BasisPoints - the multiplier that earns the normal points
+	BasisPoints = 10000
+)
+
+// Program - the settings of the points program
+type Program struct {
+	Currency       string      `json:"currency" db:"currency"`
+	PointsPerUnit  int64       `json:"pointsPerUnit" db:"points_per_unit"` // points for every whole unit spent
+	PointValue     money.Money `json:"pointValue" db:"point_value"`        // what a point is worth when redeemed
+	ExpiryDays     int         `json:"expiryDays" db:"expiry_days"`        // 0 never expires
+	TierWindowDays int         `json:"tierWindowDays" db:"tier_window_days"`
+	UpdatedAt      time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+type ProgramRequest struct {
+	Currency       string      `json:"currency" validate:"required,len=3"`
+	PointsPerUnit  int64       `json:"pointsPerUnit" validate:"min=0"`
+	PointValue     money.Money `json:"pointValue"`
+	ExpiryDays     int         `json:"expiryDays" validate:"min=0"`
+	TierWindowDays int         `json:"tierWindowDays" validate:"required,min=1"`
+}
+
+// Multiplier - the points earned on a category, in basis points of the normal points
+type Multiplier struct {
+	CategoryId string    `json:"categoryId" db:"category_id"`
+	Multiplier int64     `json:"multiplier" db:"multiplier"` // e.g. 20000 earns double points
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type MultiplierRequest struct {
+	Multiplier int64 `json:"multiplier" validate:"min=0"`
+}
+
+// Tier - a level reached by the spend of a customer over the tier window
+type Tier struct {
+	Code       string      `json:"code" db:"code"`
+	Name       string      `json:"name" db:"name"`
+	MinSpend   money.Money `json:"minSpend" db:"min_spend"`
+	Multiplier int64       `json:"multiplier" db:"multiplier"` // the points earned in the tier, in basis points
+	CreatedAt  time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+type TierRequest struct {
+	Code       string      `json:"code" validate:"required,max=50"`
+	Name       string      `json:"name" validate:"required,max=255"`
+	MinSpend   money.Money `json:"minSpend"`
+	Multiplier int64       `json:"multiplier" validate:"required,min=0"`
+}
+
+type ProgramResponse struct {
+	Program     *Program     `json:"program"`
+	Multipliers []Multiplier `json:"multipliers"`
+	Tiers       []Tier       `json:"tiers"`
+}
+
+// Entry - a change of the points of a customer, entries are never changed or removed
+type Entry struct {
+	Id           string       `json:"id" db:"id"`
+	UserId       string       `json:"userId" db:"user_id"`
+	Sequence     int64        `json:"sequence" db:"sequence"`
+	Kind         string       `json:"kind" db:"kind"`
+	Points       int64        `json:"points" db:"points"` // negative when points are spent
+	BalanceAfter int64        `json:"balanceAfter" db:"balance_after"`
+	Spend        *money.Money `json:"spend" db:"spend"`
+	Value        *money.Money `json:"value" db:"value"`
+	Reference    string       `json:"reference" db:"reference"`
+	SourceId     *string      `json:"sourceId" db:"source_id"`
+	ExpiresAt    *time.Time   `json:"expiresAt" db:"expires_at"`
+	Note         string       `json:"note" db:"note"`
+	CreatedAt    time.Time    `json:"createdAt" db:"created_at"`
+}
+
+// Lot - the points of an entry that gave points, and how many of them are left
+type Lot struct {
+	EntryId   string     `json:"entryId"`
+	Points    int64      `json:"points"`
+	Remaining int64      `json:"remaining"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type EarnLine struct {
+	CategoryId string      `json:"categoryId" validate:"omitempty,uuid"`
+	Amount     money.Money `json:"amount"` // the amount paid for the line
+}
+
+type EarnRequest struct {
+	UserId    string     `json:"userId" validate:"required,uuid"`
+	Reference string     `json:"reference" validate:"required,max=255"` // e.g. the order id
+	Lines     []EarnLine `json:"lines" validate:"required,min=1,max=200,dive"`
+}
+
+type RedeemRequest struct {
+	Points     int64        `json:"points" validate:"required,min=1"`
+	Reference  string       `json:"reference" validate:"required,max=255"` // e.g. the order id
+	OrderTotal *money.Money `json:"orderTotal" validate:"omitempty"`       // the points cannot be worth more than the order
+}
+
+type AdjustRequest struct {
+	Points int64  `json:"points" validate:"required"` // negative to take points
+	Note   string `json:"note" validate:"required,max=1000"`
+}
+
+// Balance - the points of a customer and their tier
+type Balance struct {
+	UserId          string       `json:"userId"`
+	Points          int64        `json:"points"`
+	Value           money.Money  `json:"value"`        // what the points are worth
+	RollingSpend    money.Money  `json:"rollingSpend"` // the spend over the tier window
+	Tier            *Tier        `json:"tier"`
+	NextTier        *Tier        `json:"nextTier"`
+	SpendToNextTier *money.Money `json:"spendToNextTier"`
+	Expiring        []Lot        `json:"expiring"` // the points left of every lot that will expire, soonest first
+}
+
+type EntriesQuery struct {
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page   int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. kind=earn|redeem
+	Sort   string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -sequence
+	Cursor string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the entries
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedEntriesResponse struct {
+	Entries         []Entry `json:"data"`
+	Total           int     `json:"total"`
+	TotalPages      int     `json:"totalPages"`
+	CurrentPage     int     `json:"currentPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	HasNextPage     bool    `json:"hasNextPage"`
+	PrevCursor      string  `json:"prevCursor"`
+	NextCursor      string  `json:"nextCursor"`
+}
+
+type ReconcileResponse struct {
+	UserId     string   `json:"userId"`
+	Entries    int      `json:"entries"`
+	Points     int64    `json:"points"`
+	Reconciled bool     `json:"reconciled"`
+	Problems   []string `json:"problems"`
+}
+
+type ExpireResponse struct {
+	Entries int   `json:"entries"` // the expire entries appended
+	Points  int64 `json:"points"`
+}
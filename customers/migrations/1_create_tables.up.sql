@@ -0,0 +1,42 @@
+-- shopper data kept apart from the identities of the users service, a profile is created on first save
+CREATE TABLE profiles (
+  user_id             UUID NOT NULL PRIMARY KEY,
+  display_name        VARCHAR(255) NOT NULL DEFAULT '',
+  date_of_birth       DATE,
+  -- display preferences
+  language            VARCHAR(35) NOT NULL DEFAULT 'en',
+  currency            CHAR(3) NOT NULL DEFAULT 'USD',
+  timezone            VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  -- marketing consents with the time each was last given or withdrawn
+  email_marketing     BOOLEAN NOT NULL DEFAULT FALSE,
+  email_marketing_at  TIMESTAMP,
+  sms_marketing       BOOLEAN NOT NULL DEFAULT FALSE,
+  sms_marketing_at    TIMESTAMP,
+  push_marketing      BOOLEAN NOT NULL DEFAULT FALSE,
+  push_marketing_at   TIMESTAMP,
+  created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE addresses (
+  id                  UUID NOT NULL PRIMARY KEY,
+  user_id             UUID NOT NULL,
+  -- shipping or billing
+  kind                VARCHAR(20) NOT NULL CHECK (kind IN ('shipping', 'billing')),
+  label               VARCHAR(255) NOT NULL DEFAULT '',
+  name                VARCHAR(255) NOT NULL,
+  line1               VARCHAR(255) NOT NULL,
+  line2               VARCHAR(255) NOT NULL DEFAULT '',
+  city                VARCHAR(255) NOT NULL,
+  region              VARCHAR(255) NOT NULL DEFAULT '',
+  postal_code         VARCHAR(32) NOT NULL DEFAULT '',
+  country             CHAR(2) NOT NULL,
+  phone               VARCHAR(255) NOT NULL DEFAULT '',
+  is_default          BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX addresses_user_id_idx ON addresses (user_id, kind);
+-- a user has at most one default address of each kind
+CREATE UNIQUE INDEX addresses_default_idx ON addresses (user_id, kind) WHERE is_default;
@@ -0,0 +1,78 @@
+CREATE TYPE monetary AS (amount BIGINT, currency CHAR(3));
+
+-- the settings of the points program, a single row
+CREATE TABLE loyalty_program (
+  id                  BOOLEAN NOT NULL PRIMARY KEY DEFAULT TRUE CHECK (id),
+  -- the currency points are earned and redeemed in
+  currency            CHAR(3) NOT NULL,
+  -- points earned for every whole unit of the currency spent
+  points_per_unit     BIGINT NOT NULL CHECK (points_per_unit >= 0),
+  -- what a point is worth when redeemed
+  point_value         monetary NOT NULL,
+  -- points expire this many days after they are earned, 0 never expires
+  expiry_days         INTEGER NOT NULL DEFAULT 0 CHECK (expiry_days >= 0),
+  -- tiers are computed from the spend of the last tier_window_days
+  tier_window_days    INTEGER NOT NULL CHECK (tier_window_days > 0),
+  updated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO loyalty_program (currency, points_per_unit, point_value, expiry_days, tier_window_days)
+VALUES ('USD', 1, '(1,USD)', 365, 365);
+
+-- multipliers are in basis points, 10000 earns the normal points
+CREATE TABLE loyalty_category_multipliers (
+  category_id         UUID NOT NULL PRIMARY KEY,
+  multiplier          BIGINT NOT NULL CHECK (multiplier >= 0),
+  updated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE loyalty_tiers (
+  code                VARCHAR(50) NOT NULL PRIMARY KEY,
+  name                VARCHAR(255) NOT NULL,
+  -- the rolling spend that reaches the tier
+  min_spend           monetary NOT NULL,
+  multiplier          BIGINT NOT NULL CHECK (multiplier >= 0),
+  created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the append-only ledger of points, the balance of a customer is the balance_after of their latest entry
+CREATE TABLE loyalty_entries (
+  id                  UUID NOT NULL PRIMARY KEY,
+  user_id             UUID NOT NULL,
+  -- the position of the entry in the ledger of the customer, starting at 1
+  sequence            BIGINT NOT NULL CHECK (sequence > 0),
+  -- earn, redeem, expire or adjust
+  kind                VARCHAR(20) NOT NULL CHECK (kind IN ('earn', 'redeem', 'expire', 'adjust')),
+  points              BIGINT NOT NULL CHECK (points <> 0),
+  balance_after       BIGINT NOT NULL CHECK (balance_after >= 0),
+  -- the spend points were earned on, counted towards the tier
+  spend               monetary,
+  -- what redeemed points are worth
+  value               monetary,
+  -- the order points were earned on or redeemed against
+  reference           VARCHAR(255) NOT NULL DEFAULT '',
+  -- the earn or adjust entry whose points expired
+  source_id           UUID REFERENCES loyalty_entries (id),
+  expires_at          TIMESTAMP,
+  note                TEXT NOT NULL DEFAULT '',
+  created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+  -- two entries appended at once cannot both follow the same entry
+  UNIQUE (user_id, sequence)
+);
+
+-- points are earned and redeemed once per order, and expire once per entry
+CREATE UNIQUE INDEX loyalty_entries_reference_idx ON loyalty_entries (user_id, kind, reference) WHERE kind IN ('earn', 'redeem') AND reference <> '';
+CREATE UNIQUE INDEX loyalty_entries_source_idx ON loyalty_entries (source_id) WHERE source_id IS NOT NULL;
+CREATE INDEX loyalty_entries_expires_at_idx ON loyalty_entries (expires_at) WHERE expires_at IS NOT NULL;
+
+-- the ledger is append-only
+CREATE FUNCTION reject_loyalty_entry_change() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'loyalty entries cannot be changed or removed';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER loyalty_entries_append_only
+BEFORE UPDATE OR DELETE ON loyalty_entries
+FOR EACH ROW EXECUTE FUNCTION reject_loyalty_entry_change();
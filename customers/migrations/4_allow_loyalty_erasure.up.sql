@@ -0,0 +1,9 @@
+-- the ledger of a deleted user is removed, a transaction allows it with SET LOCAL loyalty.erase = 'on'
+CREATE OR REPLACE FUNCTION reject_loyalty_entry_change() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' AND current_setting('loyalty.erase', true) = 'on' THEN
+    RETURN OLD;
+  END IF;
+  RAISE EXCEPTION 'loyalty entries cannot be changed or removed';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,11 @@
+-- the lots whose expiry has been handled, with or without points left to expire,
+-- so the hourly expiry run only picks up customers with lots still to settle
+CREATE TABLE loyalty_settled_lots (
+  entry_id            UUID NOT NULL PRIMARY KEY REFERENCES loyalty_entries (id) ON DELETE CASCADE,
+  user_id             UUID NOT NULL,
+  settled_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the lots already expired are settled
+INSERT INTO loyalty_settled_lots (entry_id, user_id, settled_at)
+SELECT source_id, user_id, created_at FROM loyalty_entries WHERE kind = 'expire' AND source_id IS NOT NULL;
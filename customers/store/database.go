@@ -0,0 +1,515 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/outbox"
+)
+
+// the customers database
+var customersDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("customers").Stdlib(), "postgres")
+})
+
+// lockUser - serializes the changes to the profile and addresses of a user within a transaction.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @return error
+func lockUser(ctx context.Context, tx *sqlx.Tx, userId string) error {
+	if err := database.NamedExecQuery(ctx, tx, "SELECT pg_advisory_xact_lock(hashtext(:user_id))", map[string]interface{}{
+		"user_id": userId,
+	}); err != nil {
+		return fmt.Errorf("locking customer: %w", err)
+	}
+
+	return nil
+}
+
+// GetProfile - GetProfile is a function that gets the profile of a user, the default profile when none was saved.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @return profile
+// @return error
+func GetProfile(ctx context.Context, db sqlx.ExtContext, userId string) (*Profile, error) {
+	var profile Profile
+
+	// execute query
+	if err := database.NamedStructQuery(ctx, db, "SELECT * FROM profiles WHERE user_id = :user_id", map[string]interface{}{
+		"user_id": userId,
+	}, &profile); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			profile = NewProfile(userId, time.Now().UTC())
+			return &profile, nil
+		}
+		return nil, fmt.Errorf("selecting profile by user ID[%v]: %w", userId, err)
+	}
+
+	return &profile, nil
+}
+
+// FindProfile - FindProfile is a function that gets the profile of a user.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @return profile
+// @return error
+func FindProfile(ctx context.Context, userId string) (*Profile, error) {
+	return GetProfile(ctx, customersDatabase(), userId)
+}
+
+// saveProfile - creates or replaces a profile.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param profile - *Profile
+// @return error
+func saveProfile(ctx context.Context, tx *sqlx.Tx, profile *Profile) error {
+	// query statement to be executed
+	q := `
+    INSERT INTO profiles (
+      user_id, display_name, date_of_birth, language, currency, timezone, email_marketing, email_marketing_at,
+      sms_marketing, sms_marketing_at, push_marketing, push_marketing_at, created_at, updated_at
+    )
+    VALUES (
+      :user_id, :display_name, :date_of_birth, :language, :currency, :timezone, :email_marketing, :email_marketing_at,
+      :sms_marketing, :sms_marketing_at, :push_marketing, :push_marketing_at, :created_at, :updated_at
+    )
+    ON CONFLICT (user_id) DO UPDATE SET
+      display_name = EXCLUDED.display_name, date_of_birth = EXCLUDED.date_of_birth, language = EXCLUDED.language,
+      currency = EXCLUDED.currency, timezone = EXCLUDED.timezone, email_marketing = EXCLUDED.email_marketing,
+      email_marketing_at = EXCLUDED.email_marketing_at, sms_marketing = EXCLUDED.sms_marketing,
+      sms_marketing_at = EXCLUDED.sms_marketing_at, push_marketing = EXCLUDED.push_marketing,
+      push_marketing_at = EXCLUDED.push_marketing_at, updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, tx, q, profile); err != nil {
+		return fmt.Errorf("saving profile: %w", err)
+	}
+
+	return nil
+}
+
+// updateProfile - changes the profile of a user within a transaction.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param change - the change to the profile
+// @return profile
+// @return error
+func updateProfile(ctx context.Context, userId string, change func(profile *Profile) error) (*Profile, error) {
+	var profile *Profile
+
+	if err := database.Transaction(ctx, customersDatabase(), func(tx *sqlx.Tx) error {
+		if err := lockUser(ctx, tx, userId); err != nil {
+			return err
+		}
+
+		// get the profile
+		var err error
+		if profile, err = GetProfile(ctx, tx, userId); err != nil {
+			return err
+		}
+
+		// change and save it
+		if err := change(profile); err != nil {
+			return err
+		}
+		return saveProfile(ctx, tx, profile)
+	}); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// SaveProfile - SaveProfile is a function that replaces the details and display preferences of a user.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param payload - *ProfileRequest
+// @return profile
+// @return error
+func SaveProfile(ctx context.Context, userId string, payload *ProfileRequest) (*Profile, error) {
+	return updateProfile(ctx, userId, func(profile *Profile) error {
+		return profile.Apply(payload, time.Now().UTC())
+	})
+}
+
+// SetConsents - SetConsents is a function that changes the marketing consents of a user.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param payload - *ConsentsRequest
+// @return profile
+// @return error
+func SetConsents(ctx context.Context, userId string, payload *ConsentsRequest) (*Profile, error) {
+	return updateProfile(ctx, userId, func(profile *Profile) error {
+		profile.SetConsents(payload, time.Now().UTC())
+		return nil
+	})
+}
+
+// ListAddresses - ListAddresses is a function that gets the addresses of a user, defaults first.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @return addresses
+// @return error
+func ListAddresses(ctx context.Context, userId string) ([]Address, error) {
+	addresses := make([]Address, 0)
+
+	// query statement to be executed
+	q := "SELECT * FROM addresses WHERE user_id = :user_id ORDER BY kind DESC, is_default DESC, created_at DESC"
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, customersDatabase(), q, map[string]interface{}{"user_id": userId}, &addresses); err != nil {
+		return nil, fmt.Errorf("selecting addresses: %w", err)
+	}
+
+	return addresses, nil
+}
+
+// getAddress - gets an address of a user.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @param id - string
+// @return address
+// @return error
+func getAddress(ctx context.Context, db sqlx.ExtContext, userId, id string) (*Address, error) {
+	var address Address
+
+	// execute query
+	if err := database.NamedStructQuery(ctx, db, "SELECT * FROM addresses WHERE id = :id AND user_id = :user_id", map[string]interface{}{
+		"id":      id,
+		"user_id": userId,
+	}, &address); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrAddressNotFound
+		}
+		return nil, fmt.Errorf("selecting address by ID[%v]: %w", id, err)
+	}
+
+	return &address, nil
+}
+
+// clearDefault - removes the default address of a kind, before another becomes the default.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @param kind - string
+// @return error
+func clearDefault(ctx context.Context, tx *sqlx.Tx, userId, kind string) error {
+	if err := database.NamedExecQuery(ctx, tx, "UPDATE addresses SET is_default = FALSE WHERE user_id = :user_id AND kind = :kind AND is_default", map[string]interface{}{
+		"user_id": userId,
+		"kind":    kind,
+	}); err != nil {
+		return fmt.Errorf("clearing default address: %w", err)
+	}
+
+	return nil
+}
+
+// ensureDefault - makes the latest address of a kind the default when the kind has no default.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @param kind - string
+// @return error
+func ensureDefault(ctx context.Context, tx *sqlx.Tx, userId, kind string) error {
+	// query statement to be executed
+	q := `
+    UPDATE addresses SET is_default = TRUE
+    WHERE id = (SELECT id FROM addresses WHERE user_id = :user_id AND kind = :kind ORDER BY created_at DESC LIMIT 1)
+      AND NOT EXISTS (SELECT 1 FROM addresses WHERE user_id = :user_id AND kind = :kind AND is_default)
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, tx, q, map[string]interface{}{"user_id": userId, "kind": kind}); err != nil {
+		return fmt.Errorf("setting default address: %w", err)
+	}
+
+	return nil
+}
+
+// fill - sets the fields of an address from a request.
+//
+// @param address - *Address
+// @param payload - *AddressRequest
+func (address *Address) fill(payload *AddressRequest) {
+	address.Kind = payload.Kind
+	address.Label = strings.TrimSpace(payload.Label)
+	address.Name = strings.TrimSpace(payload.Name)
+	address.Line1 = strings.TrimSpace(payload.Line1)
+	address.Line2 = strings.TrimSpace(payload.Line2)
+	address.City = strings.TrimSpace(payload.City)
+	address.Region = strings.TrimSpace(payload.Region)
+	address.PostalCode = strings.TrimSpace(payload.PostalCode)
+	address.Country = strings.ToUpper(payload.Country)
+	address.Phone = strings.TrimSpace(payload.Phone)
+}
+
+// CreateAddress - CreateAddress is a function that adds an address of a user.
+// The first address of a kind becomes the default.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param payload - *AddressRequest
+// @return address
+// @return error
+func CreateAddress(ctx context.Context, userId string, payload *AddressRequest) (*Address, error) {
+	now := time.Now().UTC()
+	address := Address{Id: uuid.New().String(), UserId: userId, CreatedAt: now, UpdatedAt: now}
+	address.fill(payload)
+
+	if err := database.Transaction(ctx, customersDatabase(), func(tx *sqlx.Tx) error {
+		if err := lockUser(ctx, tx, userId); err != nil {
+			return err
+		}
+
+		// check the number of addresses
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM addresses WHERE user_id = :user_id", map[string]interface{}{
+			"user_id": userId,
+		})
+		if err != nil {
+			return fmt.Errorf("counting addresses: %w", err)
+		}
+		if count >= MaxAddresses {
+			return fmt.Errorf("%w: a customer can keep up to %v addresses", ErrTooManyAddresses, MaxAddresses)
+		}
+
+		// replace the default
+		if payload.IsDefault {
+			if err := clearDefault(ctx, tx, userId, address.Kind); err != nil {
+				return err
+			}
+			address.IsDefault = true
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO addresses (
+        id, user_id, kind, label, name, line1, line2, city, region, postal_code, country, phone, is_default, created_at, updated_at
+      )
+      VALUES (
+        :id, :user_id, :kind, :label, :name, :line1, :line2, :city, :region, :postal_code, :country, :phone, :is_default, :created_at, :updated_at
+      )
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, address); err != nil {
+			return fmt.Errorf("inserting address: %w", err)
+		}
+		if err := ensureDefault(ctx, tx, userId, address.Kind); err != nil {
+			return err
+		}
+
+		// read back whether it became the default
+		saved, err := getAddress(ctx, tx, userId, address.Id)
+		if err != nil {
+			return err
+		}
+		address = *saved
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+// UpdateAddress - UpdateAddress is a function that replaces an address of a user.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @param payload - *AddressRequest
+// @return address
+// @return error
+func UpdateAddress(ctx context.Context, userId, id string, payload *AddressRequest) (*Address, error) {
+	var address *Address
+
+	if err := database.Transaction(ctx, customersDatabase(), func(tx *sqlx.Tx) error {
+		if err := lockUser(ctx, tx, userId); err != nil {
+			return err
+		}
+
+		// get the address
+		current, err := getAddress(ctx, tx, userId, id)
+		if err != nil {
+			return err
+		}
+		kind := current.Kind
+		current.fill(payload)
+		current.UpdatedAt = time.Now().UTC()
+
+		// a default moved to another kind stops being the default of its old kind
+		if kind != current.Kind {
+			current.IsDefault = false
+		}
+		if payload.IsDefault {
+			if err := clearDefault(ctx, tx, userId, current.Kind); err != nil {
+				return err
+			}
+			current.IsDefault = true
+		}
+
+		// query statement to be executed
+		q := `
+      UPDATE addresses SET
+        kind = :kind, label = :label, name = :name, line1 = :line1, line2 = :line2, city = :city, region = :region,
+        postal_code = :postal_code, country = :country, phone = :phone, is_default = :is_default, updated_at = :updated_at
+      WHERE id = :id
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, current); err != nil {
+			return fmt.Errorf("updating address: %w", err)
+		}
+
+		// keep a default of both kinds
+		for _, k := range []string{kind, current.Kind} {
+			if err := ensureDefault(ctx, tx, userId, k); err != nil {
+				return err
+			}
+		}
+
+		address, err = getAddress(ctx, tx, userId, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// SetDefaultAddress - SetDefaultAddress is a function that makes an address the default of its kind.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @return address
+// @return error
+func SetDefaultAddress(ctx context.Context, userId, id string) (*Address, error) {
+	var address *Address
+
+	if err := database.Transaction(ctx, customersDatabase(), func(tx *sqlx.Tx) error {
+		if err := lockUser(ctx, tx, userId); err != nil {
+			return err
+		}
+
+		// get the address
+		current, err := getAddress(ctx, tx, userId, id)
+		if err != nil {
+			return err
+		}
+
+		// replace the default
+		if err := clearDefault(ctx, tx, userId, current.Kind); err != nil {
+			return err
+		}
+		if err := database.NamedExecQuery(ctx, tx, "UPDATE addresses SET is_default = TRUE, updated_at = :updated_at WHERE id = :id", map[string]interface{}{
+			"id":         id,
+			"updated_at": time.Now().UTC(),
+		}); err != nil {
+			return fmt.Errorf("setting default address: %w", err)
+		}
+
+		address, err = getAddress(ctx, tx, userId, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// DeleteAddress - DeleteAddress is a function that removes an address of a user.
+// The latest other address of the kind becomes the default when the default is removed.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @return error
+func DeleteAddress(ctx context.Context, userId, id string) error {
+	return database.Transaction(ctx, customersDatabase(), func(tx *sqlx.Tx) error {
+		if err := lockUser(ctx, tx, userId); err != nil {
+			return err
+		}
+
+		// get the address
+		address, err := getAddress(ctx, tx, userId, id)
+		if err != nil {
+			return err
+		}
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, "DELETE FROM addresses WHERE id = :id", map[string]interface{}{
+			"id": id,
+		}); err != nil {
+			return fmt.Errorf("deleting address: %w", err)
+		}
+
+		return ensureDefault(ctx, tx, userId, address.Kind)
+	})
+}
+
+// DeleteUser - DeleteUser is a function that removes the profile and addresses of a deleted user.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @return the rows removed, by what they were
+// @return error
+func DeleteUser(ctx context.Context, tx *sqlx.Tx, userId string) (map[string]int, error) {
+	if err := lockUser(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{"user_id": userId}
+
+	profiles, err := database.NamedCountQuery(ctx, tx, `
+    WITH removed AS (DELETE FROM profiles WHERE user_id = :user_id RETURNING user_id)
+    SELECT COUNT(*) FROM removed
+  `, data)
+	if err != nil {
+		return nil, fmt.Errorf("deleting profile: %w", err)
+	}
+
+	addresses, err := database.NamedCountQuery(ctx, tx, `
+    WITH removed AS (DELETE FROM addresses WHERE user_id = :user_id RETURNING id)
+    SELECT COUNT(*) FROM removed
+  `, data)
+	if err != nil {
+		return nil, fmt.Errorf("deleting addresses: %w", err)
+	}
+
+	return map[string]int{"profiles": profiles, "addresses": addresses}, nil
+}
+
+// RelayEvents - RelayEvents is a function that publishes the events saved in the outbox of the customers.
+//
+// @param ctx - context.Context
+// @param publish - outbox.Publisher
+// @param now - time.Time
+// @return response
+// @return error
+func RelayEvents(ctx context.Context, publish outbox.Publisher, now time.Time) (*outbox.RelayResponse, error) {
+	return outbox.Relay(ctx, customersDatabase(), publish, now)
+}
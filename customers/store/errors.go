@@ -0,0 +1,9 @@
+package store
+
+import "errors"
+
+var (
+	ErrAddressNotFound  = errors.New("address not found")
+	ErrTooManyAddresses = errors.New("too many addresses")
+	ErrInvalidProfile   = errors.New("invalid profile")
+)
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"time"
+)
+
+const (
+	KindShipping = "shipping" // an address orders are delivered to
+	KindBilling  = "billing"  // an address payments are billed to
+
+	// MaxAddresses - the most addresses a user can keep
+	MaxAddresses = 20
+)
+
+type Profile struct {
+	UserId      string     `json:"userId" db:"user_id"`
+	DisplayName string     `json:"displayName" db:"display_name"`
+	DateOfBirth *time.Time `json:"dateOfBirth" db:"date_of_birth"`
+	Language    string     `json:"language" db:"language"`
+	Currency    string     `json:"currency" db:"currency"`
+	Timezone    string     `json:"timezone" db:"timezone"`
+	// the kinds of marketing the user agreed to, with when each was last given or withdrawn
+	EmailMarketing   bool       `json:"emailMarketing" db:"email_marketing"`
+	EmailMarketingAt *time.Time `json:"emailMarketingAt" db:"email_marketing_at"`
+	SmsMarketing     bool       `json:"smsMarketing" db:"sms_marketing"`
+	SmsMarketingAt   *time.Time `json:"smsMarketingAt" db:"sms_marketing_at"`
+	PushMarketing    bool       `json:"pushMarketing" db:"push_marketing"`
+	PushMarketingAt  *time.Time `json:"pushMarketingAt" db:"push_marketing_at"`
+	CreatedAt        time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+type ProfileRequest struct {
+	DisplayName string `json:"displayName" validate:"omitempty,max=255"`
+	DateOfBirth string `json:"dateOfBirth" validate:"omitempty,datetime=2006-01-02"` // e.g. 1990-04-21, removed when empty
+	Language    string `json:"language" validate:"omitempty,bcp47_language_tag"`     // e.g. en-GB, en when empty
+	Currency    string `json:"currency" validate:"omitempty,len=3"`                  // USD when empty
+	Timezone    string `json:"timezone" validate:"omitempty,timezone"`               // e.g. Africa/Accra, UTC when empty
+}
+
+// ConsentsRequest - the consents to change, the ones left out are kept
+type ConsentsRequest struct {
+	EmailMarketing *bool `json:"emailMarketing" validate:"omitempty"`
+	SmsMarketing   *bool `json:"smsMarketing" validate:"omitempty"`
+	PushMarketing  *bool `json:"pushMarketing" validate:"omitempty"`
+}
+
+type Address struct {
+	Id         string    `json:"id" db:"id"`
+	UserId     string    `json:"userId" db:"user_id"`
+	Kind       string    `json:"kind" db:"kind"`
+	Label      string    `json:"label" db:"label"` // e.g. home or work
+	Name       string    `json:"name" db:"name"`   // the name of the recipient
+	Line1      string    `json:"line1" db:"line1"`
+	Line2      string    `json:"line2" db:"line2"`
+	City       string    `json:"city" db:"city"`
+	Region     string    `json:"region" db:"region"`
+	PostalCode string    `json:"postalCode" db:"postal_code"`
+	Country    string    `json:"country" db:"country"` // the ISO 3166-1 alpha-2 code
+	Phone      string    `json:"phone" db:"phone"`
+	IsDefault  bool      `json:"isDefault" db:"is_default"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type AddressRequest struct {
+	Kind       string `json:"kind" validate:"required,oneof=shipping billing"`
+	Label      string `json:"label" validate:"omitempty,max=255"`
+	Name       string `json:"name" validate:"required,max=255"`
+	Line1      string `json:"line1" validate:"required,max=255"`
+	Line2      string `json:"line2" validate:"omitempty,max=255"`
+	City       string `json:"city" validate:"required,max=255"`
+	Region     string `json:"region" validate:"omitempty,max=255"`
+	PostalCode string `json:"postalCode" validate:"omitempty,max=32"`
+	Country    string `json:"country" validate:"required,iso3166_1_alpha2"`
+	Phone      string `json:"phone" validate:"omitempty,max=255"`
+	IsDefault  bool   `json:"isDefault"` // the first address of a kind is always the default
+}
+
+type AddressesResponse struct {
+	Addresses []Address `json:"data"`
+}
+
+// CustomerResponse - a profile with its addresses
+type CustomerResponse struct {
+	Profile   *Profile  `json:"profile"`
+	Addresses []Address `json:"addresses"`
+}
+
+type DateOfBirthResponse struct {
+	DateOfBirth string `json:"dateOfBirth"` // e.g. 1990-04-21, empty when not given
+}
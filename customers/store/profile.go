@@ -0,0 +1,107 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+// DateLayout - the layout of dates of birth
+const DateLayout = "2006-01-02"
+
+// NewProfile - NewProfile returns the profile of a user who has not saved one yet.
+//
+// @param userId - string
+// @param now - time.Time
+// @return Profile
+func NewProfile(userId string, now time.Time) Profile {
+	return Profile{
+		UserId:    userId,
+		Language:  "en",
+		Currency:  money.DefaultCurrency,
+		Timezone:  "UTC",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Apply - Apply replaces the details and display preferences of the profile, empty preferences are reset.
+//
+// @param payload - *ProfileRequest
+// @param now - time.Time
+// @return error
+func (p *Profile) Apply(payload *ProfileRequest, now time.Time) error {
+	defaults := NewProfile(p.UserId, now)
+
+	// check the date of birth
+	var dateOfBirth *time.Time
+	if len(payload.DateOfBirth) > 0 {
+		date, err := time.Parse(DateLayout, payload.DateOfBirth)
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a date", ErrInvalidProfile, payload.DateOfBirth)
+		}
+		if date.After(now) || date.Year() < 1900 {
+			return fmt.Errorf("%w: %v is not a possible date of birth", ErrInvalidProfile, payload.DateOfBirth)
+		}
+		dateOfBirth = &date
+	}
+
+	// check the currency
+	currency := strings.ToUpper(strings.TrimSpace(payload.Currency))
+	if len(currency) < 1 {
+		currency = defaults.Currency
+	}
+	if !money.IsCurrency(currency) {
+		return fmt.Errorf("%w: unknown currency %v", ErrInvalidProfile, payload.Currency)
+	}
+
+	p.DisplayName = strings.TrimSpace(payload.DisplayName)
+	p.DateOfBirth = dateOfBirth
+	p.Language = payload.Language
+	p.Currency = currency
+	p.Timezone = payload.Timezone
+	if len(p.Language) < 1 {
+		p.Language = defaults.Language
+	}
+	if len(p.Timezone) < 1 {
+		p.Timezone = defaults.Timezone
+	}
+	p.UpdatedAt = now
+
+	return nil
+}
+
+// SetConsents - SetConsents changes the marketing consents of the profile.
+// The time of a consent only moves when it is given or withdrawn, so repeating a consent keeps when it was given.
+//
+// @param payload - *ConsentsRequest
+// @param now - time.Time
+func (p *Profile) SetConsents(payload *ConsentsRequest, now time.Time) {
+	set := func(consent *bool, at **time.Time, value *bool) {
+		if value == nil || (*consent == *value && *at != nil) {
+			return
+		}
+		*consent = *value
+		t := now
+		*at = &t
+	}
+
+	set(&p.EmailMarketing, &p.EmailMarketingAt, payload.EmailMarketing)
+	set(&p.SmsMarketing, &p.SmsMarketingAt, payload.SmsMarketing)
+	set(&p.PushMarketing, &p.PushMarketingAt, payload.PushMarketing)
+	p.UpdatedAt = now
+}
+
+// FormatDateOfBirth - FormatDateOfBirth returns the date of birth of the profile, e.g. 1990-04-21, or an
+// empty string when it was not given.
+//
+// @return string
+func (p *Profile) FormatDateOfBirth() string {
+	if p.DateOfBirth == nil {
+		return ""
+	}
+
+	return p.DateOfBirth.Format(DateLayout)
+}
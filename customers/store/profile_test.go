@@ -0,0 +1,70 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// the time the profiles are saved at
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// TestApply - test replacing the details of a profile
+//
+//	@param t - testing.T
+func TestApply(t *testing.T) {
+	profile := NewProfile("user", now.Add(-time.Hour))
+
+	// save the details
+	if err := profile.Apply(&ProfileRequest{DisplayName: " Ama ", DateOfBirth: "1990-04-21", Currency: "ghs", Timezone: "Africa/Accra"}, now); err != nil {
+		t.Fatalf("should apply, got %v", err)
+	}
+	if profile.DisplayName != "Ama" || profile.FormatDateOfBirth() != "1990-04-21" || profile.Currency != "GHS" || profile.Language != "en" || profile.Timezone != "Africa/Accra" || !profile.UpdatedAt.Equal(now) {
+		t.Errorf("the profile was not applied, got %+v", profile)
+	}
+
+	// empty preferences are reset
+	if err := profile.Apply(&ProfileRequest{}, now); err != nil {
+		t.Fatalf("should apply, got %v", err)
+	}
+	if profile.DateOfBirth != nil || profile.Currency != "USD" || profile.Timezone != "UTC" {
+		t.Errorf("the profile should be reset, got %+v", profile)
+	}
+
+	// impossible details are rejected
+	for _, payload := range []ProfileRequest{{DateOfBirth: "2030-01-01"}, {DateOfBirth: "1850-01-01"}, {DateOfBirth: "21/04/1990"}, {Currency: "XYZ"}} {
+		if err := profile.Apply(&payload, now); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("%+v should be rejected, got %v", payload, err)
+		}
+	}
+}
+
+// TestSetConsents - test the times of the consents only move when they change
+//
+//	@param t - testing.T
+func TestSetConsents(t *testing.T) {
+	yes, no := true, false
+	later := now.Add(time.Hour)
+	profile := NewProfile("user", now)
+
+	// give the email consent and withdraw the sms consent that was never given
+	profile.SetConsents(&ConsentsRequest{EmailMarketing: &yes, SmsMarketing: &no}, now)
+	if !profile.EmailMarketing || profile.EmailMarketingAt == nil || !profile.EmailMarketingAt.Equal(now) {
+		t.Errorf("the email consent should be given at %v, got %+v", now, profile)
+	}
+	if profile.SmsMarketing || profile.SmsMarketingAt == nil || !profile.SmsMarketingAt.Equal(now) {
+		t.Errorf("the sms consent should be withdrawn at %v, got %+v", now, profile)
+	}
+	if profile.PushMarketingAt != nil {
+		t.Errorf("the push consent should be left out, got %+v", profile)
+	}
+
+	// repeating a consent keeps its time, changing it moves it
+	profile.SetConsents(&ConsentsRequest{EmailMarketing: &yes, SmsMarketing: &yes}, later)
+	if !profile.EmailMarketingAt.Equal(now) {
+		t.Errorf("the email consent should be kept from %v, got %v", now, profile.EmailMarketingAt)
+	}
+	if !profile.SmsMarketing || !profile.SmsMarketingAt.Equal(later) {
+		t.Errorf("the sms consent should be given at %v, got %+v", later, profile)
+	}
+}
@@ -0,0 +1,156 @@
+package notifications
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/pubsub"
+	"encore.dev/rlog"
+
+	"encore.app/notifications/store"
+	"encore.app/pkg/events"
+	"encore.app/pkg/events/topics"
+	"encore.app/pkg/outbox"
+)
+
+// =====================================================================================================================
+// EVENTS
+// =====================================================================================================================
+
+// keep where new users are reached and welcome them
+var _ = pubsub.NewSubscription(topics.UserCreated, "notifications-user-created", pubsub.SubscriptionConfig[*events.UserCreated]{
+	Handler: WelcomeUser,
+})
+
+// WelcomeUser - Keep where a new user is reached and send the welcome notification
+//
+//	@param ctx - context.Context
+//	@param event - *events.UserCreated
+//	@return error
+func WelcomeUser(ctx context.Context, event *events.UserCreated) error {
+	if !events.Accept(event.Meta, 1) {
+		return nil
+	}
+
+	if err := store.SaveContact(ctx, event); err != nil {
+		return err
+	}
+
+	// the event id is the reference, so a redelivered event welcomes the user once
+	_, err := SendNotification(ctx, &store.SendRequest{UserId: event.UserId, Event: store.EventWelcome, Reference: event.Meta.Id})
+	return err
+}
+
+// confirm placed orders
+var _ = pubsub.NewSubscription(topics.OrderPlaced, "notifications-order-placed", pubsub.SubscriptionConfig[*events.OrderPlaced]{
+	Handler: ConfirmOrder,
+})
+
+// ConfirmOrder - Send the confirmation of a placed order to the customer
+//
+//	@param ctx - context.Context
+//	@param event - *events.OrderPlaced
+//	@return error
+func ConfirmOrder(ctx context.Context, event *events.OrderPlaced) error {
+	if !events.Accept(event.Meta, 1) {
+		return nil
+	}
+
+	items := 0
+	for _, line := range event.Lines {
+		items += line.Quantity
+	}
+
+	_, err := SendNotification(ctx, &store.SendRequest{
+		UserId:    event.UserId,
+		Event:     store.EventOrderConfirmation,
+		Reference: event.OrderId,
+		Data:      map[string]string{"orderId": event.OrderId, "items": strconv.Itoa(items), "total": event.Total.Format()},
+	})
+	if errs.Code(err) == errs.NotFound {
+		// the customer signed up before contacts were kept, there is nowhere to send it
+		rlog.Warn("notifications.ConfirmOrder", "order", event.OrderId, "user", event.UserId, "error", err)
+		return nil
+	}
+
+	return err
+}
+
+// remove what the notifications keep for a deleted user
+var _ = pubsub.NewSubscription(topics.UserDeleted, "notifications-user-cleanup", pubsub.SubscriptionConfig[*events.UserDeleted]{
+	Handler: CleanupDeletedUser,
+})
+
+// CleanupDeletedUser - Remove the contact details, preferences and notifications of a deleted user
+//
+//	@param ctx - context.Context
+//	@param event - *events.UserDeleted
+//	@return error
+func CleanupDeletedUser(ctx context.Context, event *events.UserDeleted) error {
+	if !events.Accept(event.Meta, 1) {
+		return nil
+	}
+
+	if err := store.DeleteUser(ctx, "notifications-user-cleanup", event); err != nil {
+		return err
+	}
+
+	rlog.Info("notifications.CleanupDeletedUser", "id", event.Meta.Id, "user", event.UserId)
+
+	return nil
+}
+
+// send the notifications due
+var _ = cron.NewJob("dispatch-notifications", cron.JobConfig{
+	Title:    "Send the notifications due",
+	Every:    1 * cron.Minute,
+	Endpoint: DispatchNotifications,
+})
+
+// DispatchNotifications - Send the notifications due, trying failed ones again later until they are dead
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/notifications/dispatch
+func DispatchNotifications(ctx context.Context) (*store.DispatchResponse, error) {
+	// send the notifications
+	response, err := store.Dispatch(ctx, providers(), time.Now())
+	if err != nil {
+		return &store.DispatchResponse{}, err
+	}
+
+	rlog.Info("notifications.DispatchNotifications", "sent", response.Sent, "failed", response.Failed, "dead", response.Dead)
+
+	return response, nil
+}
+
+// publish the events saved with the changes of the notifications
+var _ = cron.NewJob("relay-notification-events", cron.JobConfig{
+	Title:    "Publish the events of the notifications",
+	Every:    1 * cron.Minute,
+	Endpoint: RelayNotificationEvents,
+})
+
+// RelayNotificationEvents - Publish the events saved in the outbox of the notifications
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/notifications/events/relay
+func RelayNotificationEvents(ctx context.Context) (*outbox.RelayResponse, error) {
+	// publish the events
+	response, err := store.RelayEvents(ctx, topics.Publish, time.Now())
+	if err != nil {
+		return &outbox.RelayResponse{}, err
+	}
+
+	rlog.Info("notifications.RelayNotificationEvents", "sent", response.Sent, "failed", response.Failed, "purged", response.Purged)
+
+	return response, nil
+}
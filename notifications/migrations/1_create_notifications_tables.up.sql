@@ -0,0 +1,95 @@
+-- where users are reached, kept from the events of the users service
+CREATE TABLE notification_contacts (
+  user_id         UUID NOT NULL PRIMARY KEY,
+  username        VARCHAR(255) NOT NULL DEFAULT '',
+  email           VARCHAR(255) NOT NULL DEFAULT '',
+  phone           VARCHAR(255) NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the channels a user changed for an event, the others keep their default
+CREATE TABLE notification_preferences (
+  user_id         UUID NOT NULL,
+  event           VARCHAR(50) NOT NULL,
+  channel         VARCHAR(20) NOT NULL CHECK (channel IN ('email', 'sms')),
+  enabled         BOOLEAN NOT NULL,
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, event, channel)
+);
+
+-- the go templates of every event and channel, given the data of the event and the username and email of the user
+CREATE TABLE notification_templates (
+  event           VARCHAR(50) NOT NULL,
+  channel         VARCHAR(20) NOT NULL CHECK (channel IN ('email', 'sms')),
+  subject         TEXT NOT NULL DEFAULT '',
+  body            TEXT NOT NULL,
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (event, channel)
+);
+
+INSERT INTO notification_templates (event, channel, subject, body) VALUES
+  ('welcome', 'email', 'Welcome to Supermark, {{.username}}',
+   E'Hi {{.username}},\n\nThanks for joining Supermark. Your account is ready, happy shopping!\n\nThe Supermark team'),
+  ('welcome', 'sms', '', 'Welcome to Supermark, {{.username}}!'),
+  ('password_reset', 'email', 'Reset your Supermark password',
+   E'Hi {{.username}},\n\nUse this link to choose a new password: {{.resetUrl}}\nThe link expires in {{.expiresIn}}.\n\nIf you did not ask for it, you can ignore this email.'),
+  ('password_reset', 'sms', '', 'Your Supermark password reset link: {{.resetUrl}}'),
+  ('order_confirmation', 'email', 'Your order {{.orderId}} is confirmed',
+   E'Hi {{.username}},\n\nWe received your order {{.orderId}} of {{.items}} items for {{.total}}.\nWe will let you know when it ships.\n\nThanks for shopping with Supermark.'),
+  ('order_confirmation', 'sms', '', 'Supermark: order {{.orderId}} is confirmed, total {{.total}}.'),
+  ('back_in_stock', 'email', '{{.productName}} is back in stock',
+   E'Hi {{.username}},\n\n{{.productName}} from your list is back in stock at {{.price}}.\n\nThe Supermark team'),
+  ('back_in_stock', 'sms', '', 'Supermark: {{.productName}} is back in stock at {{.price}}.');
+
+-- the notifications sent, tried again until they are sent or dead
+CREATE TABLE notifications (
+  id              UUID NOT NULL PRIMARY KEY,
+  user_id         UUID NOT NULL,
+  event           VARCHAR(50) NOT NULL,
+  channel         VARCHAR(20) NOT NULL,
+  recipient       VARCHAR(255) NOT NULL,
+  subject         TEXT NOT NULL DEFAULT '',
+  body            TEXT NOT NULL,
+  reference       VARCHAR(255) NOT NULL DEFAULT '',
+  provider        VARCHAR(50) NOT NULL DEFAULT '',
+  status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+  attempts        INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  last_error      TEXT NOT NULL DEFAULT '',
+  sent_at         TIMESTAMP,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX notifications_due_idx ON notifications (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, created_at DESC);
+-- a user is notified once for a reference, e.g. an order
+CREATE UNIQUE INDEX notifications_reference_idx ON notifications (user_id, event, channel, reference) WHERE reference <> '';
+
+-- events saved with the changes they describe, published by the relay once the change is committed
+CREATE TABLE outbox (
+  -- the id of the event, subscribers dedupe on it
+  id              UUID NOT NULL PRIMARY KEY,
+  type            VARCHAR(100) NOT NULL,
+  version         INTEGER NOT NULL,
+  payload         JSONB NOT NULL,
+  occurred_at     TIMESTAMP NOT NULL,
+  attempts        INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  last_error      TEXT NOT NULL DEFAULT '',
+  sent_at         TIMESTAMP,
+  message_id      VARCHAR(255) NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (next_attempt_at, occurred_at) WHERE sent_at IS NULL;
+CREATE INDEX outbox_sent_at_idx ON outbox (sent_at) WHERE sent_at IS NOT NULL;
+
+-- the events handled by the subscriptions of the service, so an event delivered twice is handled once
+CREATE TABLE processed_events (
+  subscription    VARCHAR(100) NOT NULL,
+  event_id        UUID NOT NULL,
+  processed_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (subscription, event_id)
+);
@@ -0,0 +1,286 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"encore.dev"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/notifications/provider"
+	"encore.app/notifications/store"
+	"encore.app/pkg/middleware"
+	"encore.app/pkg/pagination"
+)
+
+// the smtp server sending email, email is written to the console when the host is empty on a local run
+var secrets struct {
+	SMTPHost     string
+	SMTPPort     string // defaults to 587
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string // e.g. Supermark <no-reply@supermark.com>
+}
+
+var (
+	providersOnce sync.Once
+	senders       store.Providers
+)
+
+// providers - gets the provider of every channel, chosen on first use. Channels without a provider are written to
+// the console on local runs and tests only, elsewhere their notifications fail until a provider is configured.
+//
+//	@return store.Providers
+func providers() store.Providers {
+	providersOnce.Do(func() {
+		senders = store.Providers{}
+		if env := encore.Meta().Environment; env.Type == encore.EnvTest || env.Type == encore.EnvLocal || env.Cloud == encore.CloudLocal {
+			console := provider.NewConsole(os.Stdout)
+			senders[provider.ChannelEmail] = console
+			senders[provider.ChannelSMS] = console
+		}
+
+		if len(secrets.SMTPHost) > 0 {
+			port, err := strconv.Atoi(secrets.SMTPPort)
+			if err != nil || port < 1 {
+				port = 587
+			}
+			senders[provider.ChannelEmail] = &provider.SMTP{
+				Host:     secrets.SMTPHost,
+				Port:     port,
+				Username: secrets.SMTPUsername,
+				Password: secrets.SMTPPassword,
+				From:     secrets.SMTPFrom,
+			}
+		}
+		for _, channel := range []string{provider.ChannelEmail, provider.ChannelSMS} {
+			if sender, ok := senders[channel]; ok {
+				rlog.Info("notifications.providers", "channel", channel, "provider", sender.Name())
+			} else {
+				rlog.Warn("notifications.providers", "channel", channel, "error", provider.ErrUnconfigured)
+			}
+		}
+	})
+
+	return senders
+}
+
+// =====================================================================================================================
+// SENDING
+// =====================================================================================================================
+
+// SendNotification - Notify a user of an event on every channel they get it on, each notification is sent right away
+// and tried again later when it fails
+//
+//	@param ctx - context.Context
+//	@param payload - *store.SendRequest
+//	@return notifications
+//	@return error
+//
+// encore:api private method=POST path=/notifications/send
+func SendNotification(ctx context.Context, payload *store.SendRequest) (*store.SendResponse, error) {
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &store.SendResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// render the notifications
+	notifications, err := store.Queue(ctx, payload)
+	if err != nil {
+		return &store.SendResponse{}, notificationError(err)
+	}
+
+	// send them
+	now := time.Now()
+	for i := range notifications {
+		if err := store.Send(ctx, providers(), &notifications[i], now); err != nil {
+			return &store.SendResponse{}, err
+		}
+	}
+
+	return &store.SendResponse{Notifications: notifications}, nil
+}
+
+// ListNotifications - List the notifications sent, the newest first
+//
+//	@param ctx - context.Context
+//	@param params - *store.NotificationsQuery
+//	@return notifications
+//	@return error
+//
+// encore:api auth method=GET path=/notifications
+func ListNotifications(ctx context.Context, params *store.NotificationsQuery) (*store.PaginatedNotificationsResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.PaginatedNotificationsResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &store.PaginatedNotificationsResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// list the notifications
+	notifications, err := store.List(ctx, params)
+	if err != nil {
+		return &store.PaginatedNotificationsResponse{}, notificationError(err)
+	}
+
+	return notifications, nil
+}
+
+// ResendNotification - Send a notification again now, a dead notification gets every attempt again when this one fails
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return notification
+//	@return error
+//
+// encore:api auth method=POST path=/notifications/:id/resend
+func ResendNotification(ctx context.Context, id string) (*store.Notification, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.Notification{}, err
+	}
+
+	// send the notification
+	notification, err := store.Resend(ctx, providers(), id, time.Now())
+	if err != nil {
+		return &store.Notification{}, notificationError(err)
+	}
+
+	return notification, nil
+}
+
+// =====================================================================================================================
+// PREFERENCES
+// =====================================================================================================================
+
+// GetMyNotificationPreferences - Get whether the signed in user gets every event on every channel
+//
+//	@param ctx - context.Context
+//	@return preferences
+//	@return error
+//
+// encore:api auth method=GET path=/notifications/preferences
+func GetMyNotificationPreferences(ctx context.Context) (*store.PreferencesResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.PreferencesResponse{}, err
+	}
+
+	// get the preferences
+	preferences, err := store.GetPreferences(ctx, claims.Subject.Id)
+	if err != nil {
+		return &store.PreferencesResponse{}, err
+	}
+
+	return preferences, nil
+}
+
+// UpdateMyNotificationPreferences - Turn events on or off on channels for the signed in user
+//
+//	@param ctx - context.Context
+//	@param payload - *store.PreferencesRequest
+//	@return preferences
+//	@return error
+//
+// encore:api auth method=PUT path=/notifications/preferences
+func UpdateMyNotificationPreferences(ctx context.Context, payload *store.PreferencesRequest) (*store.PreferencesResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &store.PreferencesResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &store.PreferencesResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// save the preferences
+	preferences, err := store.SetPreferences(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &store.PreferencesResponse{}, notificationError(err)
+	}
+
+	return preferences, nil
+}
+
+// =====================================================================================================================
+// TEMPLATES
+// =====================================================================================================================
+
+// ListNotificationTemplates - List the template of every event and channel
+//
+//	@param ctx - context.Context
+//	@return templates
+//	@return error
+//
+// encore:api auth method=GET path=/notifications/templates
+func ListNotificationTemplates(ctx context.Context) (*store.TemplatesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.TemplatesResponse{}, err
+	}
+
+	// list the templates
+	templates, err := store.ListTemplates(ctx)
+	if err != nil {
+		return &store.TemplatesResponse{}, err
+	}
+
+	return &store.TemplatesResponse{Templates: templates}, nil
+}
+
+// UpdateNotificationTemplate - Replace the Go template of an event on a channel
+//
+//	@param ctx - context.Context
+//	@param event - string
+//	@param channel - string
+//	@param payload - *store.TemplateRequest
+//	@return template
+//	@return error
+//
+// encore:api auth method=PUT path=/notifications/templates/:event/:channel
+func UpdateNotificationTemplate(ctx context.Context, event, channel string, payload *store.TemplateRequest) (*store.Template, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.Template{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &store.Template{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// save the template
+	template, err := store.SetTemplate(ctx, event, channel, payload)
+	if err != nil {
+		return &store.Template{}, notificationError(err)
+	}
+
+	return template, nil
+}
+
+// notificationError - maps notification store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func notificationError(err error) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrTemplateNotFound), errors.Is(err, store.ErrContactNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, store.ErrInvalidTemplate), errors.Is(err, store.ErrRequired), pagination.IsQueryError(err):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	default:
+		return err
+	}
+}
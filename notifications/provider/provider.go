@@ -0,0 +1,247 @@
+// Package provider sends rendered notifications. A provider is chosen for every channel when the service
+// starts: SMTP for email when it is configured, and the console on local runs so they need no accounts.
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+var (
+	ErrUnsupportedChannel = errors.New("the provider does not send on the channel")
+	ErrUnconfigured       = errors.New("no provider is configured for the channel")
+)
+
+// Message - a rendered notification
+type Message struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`      // an email address or a phone number
+	Subject string `json:"subject"` // empty for sms
+	Body    string `json:"body"`
+}
+
+// Provider - sends messages on one or more channels
+type Provider interface {
+	// Name - the name kept in the send log
+	Name() string
+	// Send - sends a message, an error means it was not sent and can be tried again
+	Send(ctx context.Context, message *Message) error
+}
+
+// =====================================================================================================================
+// CONSOLE
+// =====================================================================================================================
+
+// Console - writes every message to a writer instead of sending it, for local runs
+type Console struct {
+	mu  sync.Mutex
+	Out io.Writer
+}
+
+// NewConsole - NewConsole creates a provider writing to out.
+//
+// @param out - io.Writer
+// @return *Console
+func NewConsole(out io.Writer) *Console {
+	return &Console{Out: out}
+}
+
+func (c *Console) Name() string {
+	return "console"
+}
+
+// Send - Send writes the message.
+//
+// @param ctx - context.Context
+// @param message - *Message
+// @return error
+func (c *Console) Send(_ context.Context, message *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := fmt.Fprintf(c.Out, "--- %v to %v\nSubject: %v\n\n%v\n---\n", message.Channel, message.To, message.Subject, message.Body)
+	return err
+}
+
+// =====================================================================================================================
+// MEMORY
+// =====================================================================================================================
+
+// Memory - keeps every message it is given, for tests
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+	Err      error // returned by Send instead of keeping the message when set
+}
+
+// NewMemory - NewMemory creates an empty provider.
+//
+// @return *Memory
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Name() string {
+	return "memory"
+}
+
+// Send - Send keeps the message, or fails with Err when it is set.
+//
+// @param ctx - context.Context
+// @param message - *Message
+// @return error
+func (m *Memory) Send(_ context.Context, message *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	m.messages = append(m.messages, *message)
+
+	return nil
+}
+
+// Messages - Messages gets the messages kept, the oldest first.
+//
+// @return []Message
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message{}, m.messages...)
+}
+
+// =====================================================================================================================
+// SMTP
+// =====================================================================================================================
+
+// SMTP - sends email through an SMTP server, authenticating when a username is set
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // the sender, e.g. Supermark <no-reply@supermark.com>
+}
+
+func (s *SMTP) Name() string {
+	return "smtp"
+}
+
+// Send - Send sends an email. The server must answer before the context is done.
+//
+// @param ctx - context.Context
+// @param message - *Message
+// @return error
+func (s *SMTP) Send(ctx context.Context, message *Message) error {
+	if message.Channel != ChannelEmail {
+		return fmt.Errorf("%w[%v]", ErrUnsupportedChannel, message.Channel)
+	}
+
+	// connect, stopping when the context is done
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connecting to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("starting smtp session: %w", err)
+	}
+	defer client.Close()
+
+	// use tls when the server offers it, and authenticate
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			return fmt.Errorf("starting tls: %w", err)
+		}
+	}
+	if len(s.Username) > 0 {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	// send the message
+	from, err := mailAddress(s.From)
+	if err != nil {
+		return err
+	}
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("setting sender: %w", err)
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return fmt.Errorf("setting recipient: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("starting message: %w", err)
+	}
+	if _, err := w.Write(Compose(s.From, message, time.Now())); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// mailAddress - gets the address of a sender written as "Name <address>".
+//
+// @param from - string
+// @return address
+// @return error
+func mailAddress(from string) (string, error) {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		if j := strings.LastIndex(from, ">"); j > i {
+			return from[i+1 : j], nil
+		}
+		return "", fmt.Errorf("invalid sender[%v]", from)
+	}
+
+	return strings.TrimSpace(from), nil
+}
+
+// Compose - Compose writes an email as sent over SMTP, a plain text body with its headers.
+//
+// @param from - the sender
+// @param message - *Message
+// @param at - the date of the email
+// @return the email
+func Compose(from string, message *Message, at time.Time) []byte {
+	// keep header injection out of the subject
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(message.Subject)
+	body := strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n")
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + message.To + "\r\n")
+	b.WriteString("Subject: " + subject + "\r\n")
+	b.WriteString("Date: " + at.UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body)
+	b.WriteString("\r\n")
+
+	return []byte(b.String())
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestConsole - test the console writes every message
+//
+//	@param t - testing.T
+func TestConsole(t *testing.T) {
+	var out bytes.Buffer
+	console := NewConsole(&out)
+
+	if err := console.Send(context.Background(), &Message{Channel: ChannelSMS, To: "+15550100", Body: "Your order shipped"}); err != nil {
+		t.Fatalf("console should not fail, got %v", err)
+	}
+	if !strings.Contains(out.String(), "sms to +15550100") || !strings.Contains(out.String(), "Your order shipped") {
+		t.Errorf("console should write the message, got %q", out.String())
+	}
+}
+
+// TestMemory - test the memory keeps messages until it is set to fail
+//
+//	@param t - testing.T
+func TestMemory(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+
+	if err := memory.Send(ctx, &Message{Channel: ChannelEmail, To: "a@example.com", Subject: "Hi"}); err != nil {
+		t.Fatalf("memory should not fail, got %v", err)
+	}
+	memory.Err = errors.New("down")
+	if err := memory.Send(ctx, &Message{Channel: ChannelEmail, To: "b@example.com"}); err == nil {
+		t.Errorf("memory should fail when set to")
+	}
+
+	messages := memory.Messages()
+	if len(messages) != 1 || messages[0].To != "a@example.com" {
+		t.Errorf("memory should keep the message sent, got %v", messages)
+	}
+}
+
+// TestCompose - test an email is written with its headers and crlf line endings
+//
+//	@param t - testing.T
+func TestCompose(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	email := string(Compose("Supermark <no-reply@supermark.com>", &Message{
+		Channel: ChannelEmail,
+		To:      "a@example.com",
+		Subject: "Welcome\r\nBcc: x@example.com",
+		Body:    "Hello\nthere",
+	}, at))
+
+	for _, want := range []string{
+		"From: Supermark <no-reply@supermark.com>\r\n",
+		"To: a@example.com\r\n",
+		"Subject: Welcome  Bcc: x@example.com\r\n",
+		"Date: Fri, 01 Mar 2024 12:00:00 +0000\r\n",
+		"\r\n\r\nHello\r\nthere\r\n",
+	} {
+		if !strings.Contains(email, want) {
+			t.Errorf("email should contain %q, got %q", want, email)
+		}
+	}
+}
+
+// TestSMTPChannels - test smtp only sends email
+//
+//	@param t - testing.T
+func TestSMTPChannels(t *testing.T) {
+	err := (&SMTP{Host: "localhost", Port: 25}).Send(context.Background(), &Message{Channel: ChannelSMS, To: "+15550100"})
+	if !errors.Is(err, ErrUnsupportedChannel) {
+		t.Errorf("smtp should not send sms, got %v", err)
+	}
+}
+
+// TestMailAddress - test the address of a sender is found
+//
+//	@param t - testing.T
+func TestMailAddress(t *testing.T) {
+	for from, want := range map[string]string{
+		"Supermark <no-reply@supermark.com>": "no-reply@supermark.com",
+		" no-reply@supermark.com ":           "no-reply@supermark.com",
+	} {
+		if got, err := mailAddress(from); err != nil || got != want {
+			t.Errorf("%v should be %v, got %v and %v", from, want, got, err)
+		}
+	}
+	if _, err := mailAddress("Supermark <no-reply"); err == nil {
+		t.Errorf("an unclosed address should fail")
+	}
+}
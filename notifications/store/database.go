@@ -0,0 +1,545 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/notifications/provider"
+	"encore.app/pkg/database"
+	"encore.app/pkg/events"
+	"encore.app/pkg/outbox"
+	"encore.app/pkg/pagination"
+	"encore.app/pkg/retry"
+	"encore.app/pkg/slice"
+)
+
+// Service - the name the notifications report their cleanups with
+const Service = "notifications"
+
+// the notifications database
+var notificationsDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("notifications").Stdlib(), "postgres")
+})
+
+// lease - how long a dispatch keeps the notifications it picked before another dispatch can pick them
+const lease = 5 * time.Minute
+
+// SaveContact - SaveContact is a function that keeps where a new user is reached.
+//
+// @param ctx - context.Context
+// @param event - *events.UserCreated
+// @return error
+func SaveContact(ctx context.Context, event *events.UserCreated) error {
+	contact := &Contact{
+		UserId:    event.UserId,
+		Username:  event.Username,
+		Email:     event.Email,
+		Phone:     event.Phone,
+		CreatedAt: event.Meta.OccurredAt,
+		UpdatedAt: event.Meta.OccurredAt,
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO notification_contacts (user_id, username, email, phone, created_at, updated_at)
+    VALUES (:user_id, :username, :email, :phone, :created_at, :updated_at)
+    ON CONFLICT (user_id) DO UPDATE SET username = EXCLUDED.username, email = EXCLUDED.email, phone = EXCLUDED.phone,
+      updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, notificationsDatabase(), q, contact); err != nil {
+		return fmt.Errorf("saving contact: %w", err)
+	}
+
+	return nil
+}
+
+// saved - gets the preferences a user saved.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @return preferences
+// @return error
+func saved(ctx context.Context, db sqlx.ExtContext, userId string) ([]Preference, error) {
+	preferences := make([]Preference, 0)
+	if err := database.NamedSliceQuery(ctx, db, "SELECT event, channel, enabled FROM notification_preferences WHERE user_id = :user_id", map[string]interface{}{
+		"user_id": userId,
+	}, &preferences); err != nil {
+		return nil, fmt.Errorf("selecting preferences: %w", err)
+	}
+
+	return preferences, nil
+}
+
+// GetPreferences - GetPreferences is a function that gets whether a user gets every event on every channel.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @return preferences
+// @return error
+func GetPreferences(ctx context.Context, userId string) (*PreferencesResponse, error) {
+	preferences, err := saved(ctx, notificationsDatabase(), userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreferencesResponse{Preferences: Resolve(preferences)}, nil
+}
+
+// SetPreferences - SetPreferences is a function that turns events on or off on channels for a user.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param payload - *PreferencesRequest
+// @return preferences
+// @return error
+func SetPreferences(ctx context.Context, userId string, payload *PreferencesRequest) (*PreferencesResponse, error) {
+	now := time.Now().UTC()
+	rows := make([]map[string]interface{}, 0, len(payload.Preferences))
+	for _, preference := range payload.Preferences {
+		if Required(preference.Event, preference.Channel) && !preference.Enabled {
+			return nil, fmt.Errorf("%w: %v by %v", ErrRequired, preference.Event, preference.Channel)
+		}
+		rows = append(rows, map[string]interface{}{
+			"user_id":    userId,
+			"event":      preference.Event,
+			"channel":    preference.Channel,
+			"enabled":    preference.Enabled,
+			"updated_at": now,
+		})
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO notification_preferences (user_id, event, channel, enabled, updated_at)
+    VALUES (:user_id, :event, :channel, :enabled, :updated_at)
+    ON CONFLICT (user_id, event, channel) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query, one row at a time so a preference given twice keeps the last
+	if err := database.Transaction(ctx, notificationsDatabase(), func(tx *sqlx.Tx) error {
+		for _, row := range rows {
+			if err := database.NamedExecQuery(ctx, tx, q, row); err != nil {
+				return fmt.Errorf("saving preference: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return GetPreferences(ctx, userId)
+}
+
+// ListTemplates - ListTemplates is a function that lists the template of every event and channel.
+//
+// @param ctx - context.Context
+// @return templates
+// @return error
+func ListTemplates(ctx context.Context) ([]Template, error) {
+	templates := make([]Template, 0)
+	if err := database.NamedSliceQuery(ctx, notificationsDatabase(), "SELECT * FROM notification_templates ORDER BY event, channel", map[string]interface{}{}, &templates); err != nil {
+		return nil, fmt.Errorf("selecting templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// SetTemplate - SetTemplate is a function that replaces the template of an event on a channel.
+//
+// @param ctx - context.Context
+// @param event - string
+// @param channel - string
+// @param payload - *TemplateRequest
+// @return template
+// @return error
+func SetTemplate(ctx context.Context, event, channel string, payload *TemplateRequest) (*Template, error) {
+	if !slice.Contains(Events, event) || !slice.Contains(Channels, channel) {
+		return nil, ErrTemplateNotFound
+	}
+	if err := Check(payload); err != nil {
+		return nil, err
+	}
+
+	tmpl := &Template{Event: event, Channel: channel, Subject: payload.Subject, Body: payload.Body, UpdatedAt: time.Now().UTC()}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO notification_templates (event, channel, subject, body, updated_at)
+    VALUES (:event, :channel, :subject, :body, :updated_at)
+    ON CONFLICT (event, channel) DO UPDATE SET subject = EXCLUDED.subject, body = EXCLUDED.body, updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, notificationsDatabase(), q, tmpl); err != nil {
+		return nil, fmt.Errorf("saving template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// Queue - Queue is a function that renders a notification for every channel the user gets the event on and has
+// an address for. A reference notified before is not notified again.
+//
+// @param ctx - context.Context
+// @param payload - *SendRequest
+// @return the notifications queued
+// @return error
+func Queue(ctx context.Context, payload *SendRequest) ([]Notification, error) {
+	data := map[string]interface{}{"user_id": payload.UserId, "event": payload.Event}
+
+	// get where the user is reached
+	contact := &Contact{}
+	if err := database.NamedStructQuery(ctx, notificationsDatabase(), "SELECT * FROM notification_contacts WHERE user_id = :user_id", data, contact); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, fmt.Errorf("selecting contact: %w", err)
+	}
+
+	// get the channels and their templates
+	preferences, err := saved(ctx, notificationsDatabase(), payload.UserId)
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]Template, 0)
+	if err := database.NamedSliceQuery(ctx, notificationsDatabase(), "SELECT * FROM notification_templates WHERE event = :event", data, &templates); err != nil {
+		return nil, fmt.Errorf("selecting templates: %w", err)
+	}
+
+	// the templates get the data of the event and the user
+	values := map[string]string{}
+	for key, value := range payload.Data {
+		values[key] = value
+	}
+	values["username"] = contact.Username
+	values["email"] = contact.Email
+
+	// render a notification for every channel
+	now := time.Now().UTC()
+	notifications := make([]Notification, 0, len(Channels))
+	for _, channel := range Wanted(preferences, payload.Event) {
+		recipient := Recipient(contact, channel)
+		if len(recipient) < 1 {
+			continue
+		}
+		for i := range templates {
+			if templates[i].Channel != channel {
+				continue
+			}
+			subject, body, err := Render(&templates[i], values)
+			if err != nil {
+				return nil, fmt.Errorf("rendering %v %v: %w", payload.Event, channel, err)
+			}
+			notifications = append(notifications, Notification{
+				Id:            uuid.New().String(),
+				UserId:        payload.UserId,
+				Event:         payload.Event,
+				Channel:       channel,
+				Recipient:     recipient,
+				Subject:       subject,
+				Body:          body,
+				Reference:     payload.Reference,
+				Status:        StatusPending,
+				NextAttemptAt: now.Add(lease), // sent right away by the caller
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			})
+		}
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO notifications (
+      id, user_id, event, channel, recipient, subject, body, reference, status, next_attempt_at, created_at, updated_at
+    )
+    VALUES (
+      :id, :user_id, :event, :channel, :recipient, :subject, :body, :reference, :status, :next_attempt_at, :created_at, :updated_at
+    )
+    ON CONFLICT (user_id, event, channel, reference) WHERE reference <> '' DO NOTHING
+    RETURNING id
+  `
+
+	// execute query, keeping the notifications not sent before
+	queued := make([]Notification, 0, len(notifications))
+	if err := database.Transaction(ctx, notificationsDatabase(), func(tx *sqlx.Tx) error {
+		for i := range notifications {
+			var inserted struct {
+				Id string `db:"id"`
+			}
+			if err := database.NamedStructQuery(ctx, tx, q, &notifications[i], &inserted); err != nil {
+				if errors.Is(err, database.ErrNotFound) {
+					continue
+				}
+				return fmt.Errorf("inserting notification: %w", err)
+			}
+			queued = append(queued, notifications[i])
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return queued, nil
+}
+
+// Send - Send is a function that sends a notification once with the provider of its channel and records the
+// attempt, the notification is dead once it used every attempt.
+//
+// @param ctx - context.Context
+// @param providers - Providers
+// @param notification - *Notification
+// @param now - time.Time
+// @return error
+func Send(ctx context.Context, providers Providers, notification *Notification, now time.Time) error {
+	var err error
+	if sender, ok := providers[notification.Channel]; ok {
+		sendCtx, cancel := context.WithTimeout(ctx, Timeout)
+		err = sender.Send(sendCtx, &provider.Message{
+			Channel: notification.Channel,
+			To:      notification.Recipient,
+			Subject: notification.Subject,
+			Body:    notification.Body,
+		})
+		cancel()
+		notification.Provider = sender.Name()
+	} else {
+		err = fmt.Errorf("%w[%v]", provider.ErrUnconfigured, notification.Channel)
+	}
+
+	notification.Attempts++
+	notification.UpdatedAt = now.UTC()
+	switch {
+	case err == nil:
+		sentAt := now.UTC()
+		notification.Status = StatusSent
+		notification.LastError = ""
+		notification.SentAt = &sentAt
+	case notification.Attempts >= MaxAttempts:
+		notification.Status = StatusDead
+		notification.LastError = err.Error()
+	default:
+		notification.Status = StatusPending
+		notification.LastError = err.Error()
+		notification.NextAttemptAt = now.UTC().Add(retry.Backoff(RetryWait, MaxRetryWait, notification.Attempts))
+	}
+	if err != nil {
+		rlog.Warn("store.Send", "id", notification.Id, "channel", notification.Channel, "attempts", notification.Attempts, "error", err)
+	}
+
+	// query statement to be executed
+	q := `
+    UPDATE notifications SET provider = :provider, status = :status, attempts = :attempts, next_attempt_at = :next_attempt_at,
+      last_error = :last_error, sent_at = :sent_at, updated_at = :updated_at
+    WHERE id = :id
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, notificationsDatabase(), q, notification); err != nil {
+		return fmt.Errorf("updating notification: %w", err)
+	}
+
+	return nil
+}
+
+// Dispatch - Dispatch is a function that sends the notifications due, the oldest first. The notifications are
+// leased before they are sent, so concurrent dispatches skip them and a dispatch that stops sends them again later.
+//
+// @param ctx - context.Context
+// @param providers - Providers
+// @param now - time.Time
+// @return response
+// @return error
+func Dispatch(ctx context.Context, providers Providers, now time.Time) (*DispatchResponse, error) {
+	response := &DispatchResponse{}
+
+	// lease the notifications due
+	notifications := make([]Notification, 0)
+	if err := database.NamedSliceQuery(ctx, notificationsDatabase(), `
+    UPDATE notifications SET next_attempt_at = :lease
+    WHERE id IN (
+      SELECT id FROM notifications
+      WHERE status = 'pending' AND next_attempt_at <= :now
+      ORDER BY next_attempt_at, created_at
+      LIMIT :limit
+      FOR UPDATE SKIP LOCKED
+    )
+    RETURNING *
+  `, map[string]interface{}{"now": now.UTC(), "lease": now.UTC().Add(lease), "limit": BatchSize}, &notifications); err != nil {
+		return nil, fmt.Errorf("leasing notifications: %w", err)
+	}
+
+	// send them
+	for i := range notifications {
+		if err := Send(ctx, providers, &notifications[i], now); err != nil {
+			return nil, err
+		}
+
+		switch notifications[i].Status {
+		case StatusSent:
+			response.Sent++
+		case StatusDead:
+			response.Dead++
+		default:
+			response.Failed++
+		}
+	}
+
+	return response, nil
+}
+
+// Resend - Resend is a function that sends a notification again now, whatever its status. A notification that
+// fails is tried again with every attempt, like a new one.
+//
+// @param ctx - context.Context
+// @param providers - Providers
+// @param id - string
+// @param now - time.Time
+// @return notification
+// @return error
+func Resend(ctx context.Context, providers Providers, id string, now time.Time) (*Notification, error) {
+	notification := &Notification{}
+
+	// reset the notification, leasing it so a dispatch does not send it as well
+	if err := database.NamedStructQuery(ctx, notificationsDatabase(), `
+    UPDATE notifications SET status = 'pending', attempts = 0, next_attempt_at = :lease, updated_at = :now
+    WHERE id = :id
+    RETURNING *
+  `, map[string]interface{}{"id": id, "now": now.UTC(), "lease": now.UTC().Add(lease)}, notification); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("resetting notification: %w", err)
+	}
+
+	if err := Send(ctx, providers, notification, now); err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+// notificationsSchema - the fields of a notification allowed in list queries
+var notificationsSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "userId", Column: "user_id", Type: pagination.UUID},
+		{Name: "event", Column: "event", Type: pagination.String},
+		{Name: "channel", Column: "channel", Type: pagination.String},
+		{Name: "reference", Column: "reference", Type: pagination.String},
+		{Name: "provider", Column: "provider", Type: pagination.String},
+		{Name: "status", Column: "status", Type: pagination.String},
+		{Name: "attempts", Column: "attempts", Type: pagination.Number, Sortable: true},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-createdAt",
+}
+
+// List - List is a function that lists the send log, the newest first unless sorted otherwise.
+//
+// @param ctx - context.Context
+// @param params - *NotificationsQuery
+// @return notifications
+// @return error
+func List(ctx context.Context, params *NotificationsQuery) (*PaginatedNotificationsResponse, error) {
+	// parse the filter and sort
+	statement, err := notificationsSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	if len(params.UserId) > 0 {
+		statement.AddCondition("user_id = CAST(:user_id AS UUID)", map[string]interface{}{"user_id": params.UserId})
+	}
+	if len(params.Event) > 0 {
+		statement.AddCondition("event = :event", map[string]interface{}{"event": params.Event})
+	}
+	if len(params.Status) > 0 {
+		statement.AddCondition("status = :status", map[string]interface{}{"status": params.Status})
+	}
+
+	// execute query
+	notifications := make([]Notification, 0)
+	page, err := database.NamedPageQuery(ctx, notificationsDatabase(), "notifications", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &notifications)
+	if err != nil {
+		return nil, fmt.Errorf("selecting notifications: %w", err)
+	}
+
+	return &PaginatedNotificationsResponse{
+		Notifications:   notifications,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// DeleteUser - DeleteUser is a function that removes the contact details, preferences and sent notifications of
+// a deleted user in one transaction, and saves the event reporting it is done. An event handled before changes
+// nothing.
+//
+// @param ctx - context.Context
+// @param subscription - the name of the subscription handling the event
+// @param event - *events.UserDeleted
+// @return error
+func DeleteUser(ctx context.Context, subscription string, event *events.UserDeleted) error {
+	return database.Transaction(ctx, notificationsDatabase(), func(tx *sqlx.Tx) error {
+		claimed, err := outbox.Claim(ctx, tx, subscription, event.Meta.Id)
+		if err != nil || !claimed {
+			return err
+		}
+
+		removed := make(map[string]int)
+		for name, table := range map[string]string{
+			"contacts":      "notification_contacts",
+			"preferences":   "notification_preferences",
+			"notifications": "notifications",
+		} {
+			count, err := database.NamedCountQuery(ctx, tx, `
+        WITH removed AS (DELETE FROM `+table+` WHERE user_id = :user_id RETURNING user_id)
+        SELECT COUNT(*) FROM removed
+      `, map[string]interface{}{"user_id": event.UserId})
+			if err != nil {
+				return fmt.Errorf("deleting %v: %w", name, err)
+			}
+			removed[name] = count
+		}
+
+		done := &events.CleanupCompleted{
+			Meta:    events.NewMeta(events.TypeCleanupCompleted, time.Now()),
+			UserId:  event.UserId,
+			Service: Service,
+			Removed: removed,
+		}
+		return outbox.Add(ctx, tx, done.Meta, done)
+	})
+}
+
+// RelayEvents - RelayEvents is a function that publishes the events saved in the outbox of the notifications.
+//
+// @param ctx - context.Context
+// @param publish - outbox.Publisher
+// @param now - time.Time
+// @return response
+// @return error
+func RelayEvents(ctx context.Context, publish outbox.Publisher, now time.Time) (*outbox.RelayResponse, error) {
+	return outbox.Relay(ctx, notificationsDatabase(), publish, now)
+}
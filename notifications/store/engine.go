@@ -0,0 +1,129 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"encore.app/notifications/provider"
+)
+
+// parse - parses the text of a template, failing on values the data does not have.
+//
+// @param name - string
+// @param text - string
+// @return *template.Template
+// @return error
+func parse(name, text string) (*template.Template, error) {
+	parsed, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	return parsed, nil
+}
+
+// Check - Check parses the subject and body of a template.
+//
+// @param payload - *TemplateRequest
+// @return error
+func Check(payload *TemplateRequest) error {
+	if _, err := parse("subject", payload.Subject); err != nil {
+		return err
+	}
+	_, err := parse("body", payload.Body)
+	return err
+}
+
+// Render - Render writes the subject and body of a notification from its template.
+//
+// @param tmpl - *Template
+// @param data - the values the template uses
+// @return subject
+// @return body
+// @return error
+func Render(tmpl *Template, data map[string]string) (string, string, error) {
+	rendered := make([]string, 0, 2)
+	for _, text := range []string{tmpl.Subject, tmpl.Body} {
+		parsed, err := parse(tmpl.Event, text)
+		if err != nil {
+			return "", "", err
+		}
+
+		var b strings.Builder
+		if err := parsed.Execute(&b, data); err != nil {
+			return "", "", fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		rendered = append(rendered, strings.TrimSpace(b.String()))
+	}
+
+	return rendered[0], rendered[1], nil
+}
+
+// Required - Required reports whether a user cannot turn a notification off. The password reset email
+// is always sent, so an account can be recovered.
+//
+// @param event - string
+// @param channel - string
+// @return bool
+func Required(event, channel string) bool {
+	return event == EventPasswordReset && channel == provider.ChannelEmail
+}
+
+// Resolve - Resolve gets the preference of every event and channel from the ones a user saved. Email is
+// on and sms is off until the user changes them.
+//
+// @param saved - the preferences the user saved
+// @return []Preference
+func Resolve(saved []Preference) []Preference {
+	preferences := make([]Preference, 0, len(Events)*len(Channels))
+	for _, event := range Events {
+		for _, channel := range Channels {
+			preference := Preference{Event: event, Channel: channel, Enabled: channel == provider.ChannelEmail}
+			for _, s := range saved {
+				if s.Event == event && s.Channel == channel {
+					preference.Enabled = s.Enabled
+				}
+			}
+			if Required(event, channel) {
+				preference.Enabled = true
+				preference.Required = true
+			}
+			preferences = append(preferences, preference)
+		}
+	}
+
+	return preferences
+}
+
+// Wanted - Wanted gets the channels a user gets an event on.
+//
+// @param saved - the preferences the user saved
+// @param event - string
+// @return channels
+func Wanted(saved []Preference, event string) []string {
+	channels := make([]string, 0, len(Channels))
+	for _, preference := range Resolve(saved) {
+		if preference.Event == event && preference.Enabled {
+			channels = append(channels, preference.Channel)
+		}
+	}
+
+	return channels
+}
+
+// Recipient - Recipient gets the address of a user on a channel, empty when the user has none.
+//
+// @param contact - *Contact
+// @param channel - string
+// @return string
+func Recipient(contact *Contact, channel string) string {
+	switch channel {
+	case provider.ChannelEmail:
+		return strings.TrimSpace(contact.Email)
+	case provider.ChannelSMS:
+		return strings.TrimSpace(contact.Phone)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"encore.app/notifications/provider"
+)
+
+// TestRender - test a template is rendered with the data of its event
+//
+//	@param t - testing.T
+func TestRender(t *testing.T) {
+	tmpl := &Template{
+		Event:   EventOrderConfirmation,
+		Channel: provider.ChannelEmail,
+		Subject: "Your order {{.orderId}} is confirmed",
+		Body:    "Hi {{.username}},\n\nThe total is {{.total}}.\n",
+	}
+
+	subject, body, err := Render(tmpl, map[string]string{"orderId": "A-1", "username": "ada", "total": "$10.00"})
+	if err != nil {
+		t.Fatalf("template should render, got %v", err)
+	}
+	if subject != "Your order A-1 is confirmed" {
+		t.Errorf("subject should be rendered, got %q", subject)
+	}
+	if body != "Hi ada,\n\nThe total is $10.00." {
+		t.Errorf("body should be rendered, got %q", body)
+	}
+
+	// a value the data does not have fails instead of sending "<no value>"
+	if _, _, err := Render(tmpl, map[string]string{"orderId": "A-1"}); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("missing values should fail, got %v", err)
+	}
+}
+
+// TestCheck - test templates that do not parse are rejected
+//
+//	@param t - testing.T
+func TestCheck(t *testing.T) {
+	if err := Check(&TemplateRequest{Subject: "Hi {{.username}}", Body: "{{if .resetUrl}}{{.resetUrl}}{{end}}"}); err != nil {
+		t.Errorf("template should parse, got %v", err)
+	}
+	if err := Check(&TemplateRequest{Subject: "Hi {{.username", Body: "x"}); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("subject should not parse, got %v", err)
+	}
+	if err := Check(&TemplateRequest{Body: "{{if .x}}"}); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("body should not parse, got %v", err)
+	}
+}
+
+// TestWanted - test the channels a user gets an event on
+//
+//	@param t - testing.T
+func TestWanted(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		name  string
+		saved []Preference
+		event string
+		want  []string
+	}{
+		{name: "defaults", event: EventWelcome, want: []string{provider.ChannelEmail}},
+		{name: "sms turned on", event: EventOrderConfirmation, saved: []Preference{
+			{Event: EventOrderConfirmation, Channel: provider.ChannelSMS, Enabled: true},
+		}, want: []string{provider.ChannelEmail, provider.ChannelSMS}},
+		{name: "email turned off", event: EventBackInStock, saved: []Preference{
+			{Event: EventBackInStock, Channel: provider.ChannelEmail, Enabled: false},
+		}, want: []string{}},
+		{name: "other event turned off", event: EventWelcome, saved: []Preference{
+			{Event: EventBackInStock, Channel: provider.ChannelEmail, Enabled: false},
+		}, want: []string{provider.ChannelEmail}},
+		{name: "required", event: EventPasswordReset, saved: []Preference{
+			{Event: EventPasswordReset, Channel: provider.ChannelEmail, Enabled: false},
+		}, want: []string{provider.ChannelEmail}},
+	}
+
+	for _, item := range slice {
+		if got := Wanted(item.saved, item.event); !reflect.DeepEqual(got, item.want) {
+			t.Errorf("%v: channels should be %v, got %v", item.name, item.want, got)
+		}
+	}
+}
+
+// TestResolve - test every event and channel gets a preference
+//
+//	@param t - testing.T
+func TestResolve(t *testing.T) {
+	preferences := Resolve(nil)
+	if len(preferences) != len(Events)*len(Channels) {
+		t.Fatalf("every event and channel should have a preference, got %v", len(preferences))
+	}
+	for _, preference := range preferences {
+		if preference.Required != Required(preference.Event, preference.Channel) {
+			t.Errorf("%v %v should be required %v", preference.Event, preference.Channel, !preference.Required)
+		}
+	}
+}
+
+// TestRecipient - test the address of a user on each channel
+//
+//	@param t - testing.T
+func TestRecipient(t *testing.T) {
+	contact := &Contact{Email: " ada@example.com ", Phone: "+15550100"}
+	if got := Recipient(contact, provider.ChannelEmail); got != "ada@example.com" {
+		t.Errorf("email should be ada@example.com, got %v", got)
+	}
+	if got := Recipient(contact, provider.ChannelSMS); got != "+15550100" {
+		t.Errorf("sms should be +15550100, got %v", got)
+	}
+	if got := Recipient(contact, "fax"); got != "" {
+		t.Errorf("unknown channels should have no address, got %v", got)
+	}
+}
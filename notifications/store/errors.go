@@ -0,0 +1,11 @@
+package store
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("notification not found")
+	ErrTemplateNotFound = errors.New("notification template not found")
+	ErrContactNotFound  = errors.New("no contact details for the user")
+	ErrInvalidTemplate  = errors.New("invalid notification template")
+	ErrRequired         = errors.New("the notification cannot be turned off")
+)
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"time"
+
+	"encore.app/notifications/provider"
+)
+
+const (
+	EventWelcome           = "welcome"            // sent when a user signs up
+	EventPasswordReset     = "password_reset"     // sent when a user asks to reset their password
+	EventOrderConfirmation = "order_confirmation" // sent when an order is placed
+	EventBackInStock       = "back_in_stock"      // sent when a product on a wishlist is in stock again
+
+	StatusPending = "pending" // waiting for its next attempt
+	StatusSent    = "sent"    // the provider took it
+	StatusDead    = "dead"    // every attempt failed, only sent again when resent
+
+	// MaxAttempts - the attempts made before a notification is dead
+	MaxAttempts = 5
+	// RetryWait - the wait after the first failed attempt, doubling after every attempt up to MaxRetryWait
+	RetryWait    = time.Minute
+	MaxRetryWait = time.Hour
+	// BatchSize - the most notifications sent by a dispatch
+	BatchSize = 100
+	// Timeout - how long a provider has to take a notification
+	Timeout = 30 * time.Second
+)
+
+// Events - the events users are notified of, each has a template for every channel
+var Events = []string{EventWelcome, EventPasswordReset, EventOrderConfirmation, EventBackInStock}
+
+// Channels - the channels notifications are sent on
+var Channels = []string{provider.ChannelEmail, provider.ChannelSMS}
+
+// Providers - the provider sending each channel
+type Providers map[string]provider.Provider
+
+// Template - the Go template of the subject and body of a notification, given the data of the event and
+// the username and email of the user
+type Template struct {
+	Event     string    `json:"event" db:"event"`
+	Channel   string    `json:"channel" db:"channel"`
+	Subject   string    `json:"subject" db:"subject"` // empty for sms
+	Body      string    `json:"body" db:"body"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type TemplateRequest struct {
+	Subject string `json:"subject" validate:"max=1000"`
+	Body    string `json:"body" validate:"required,max=20000"`
+}
+
+type TemplatesResponse struct {
+	Templates []Template `json:"data"`
+}
+
+// Preference - whether a user gets an event on a channel
+type Preference struct {
+	Event    string `json:"event" db:"event"`
+	Channel  string `json:"channel" db:"channel"`
+	Enabled  bool   `json:"enabled" db:"enabled"`
+	Required bool   `json:"required" db:"-"` // cannot be turned off
+}
+
+type PreferenceRequest struct {
+	Event   string `json:"event" validate:"required,oneof=welcome password_reset order_confirmation back_in_stock"`
+	Channel string `json:"channel" validate:"required,oneof=email sms"`
+	Enabled bool   `json:"enabled"`
+}
+
+type PreferencesRequest struct {
+	Preferences []PreferenceRequest `json:"preferences" validate:"required,min=1,max=50,dive"`
+}
+
+type PreferencesResponse struct {
+	Preferences []Preference `json:"data"`
+}
+
+// Contact - where a user is reached, kept from the events of the users service
+type Contact struct {
+	UserId    string    `json:"userId" db:"user_id"`
+	Username  string    `json:"username" db:"username"`
+	Email     string    `json:"email" db:"email"`
+	Phone     string    `json:"phone" db:"phone"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Notification - a rendered message to a user and the state of its sending
+type Notification struct {
+	Id            string     `json:"id" db:"id"`
+	UserId        string     `json:"userId" db:"user_id"`
+	Event         string     `json:"event" db:"event"`
+	Channel       string     `json:"channel" db:"channel"`
+	Recipient     string     `json:"recipient" db:"recipient"`
+	Subject       string     `json:"subject" db:"subject"`
+	Body          string     `json:"body" db:"body"`
+	Reference     string     `json:"reference" db:"reference"` // e.g. the order id, a user is notified once for a reference
+	Provider      string     `json:"provider" db:"provider"`   // the provider of the last attempt
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastError     string     `json:"lastError" db:"last_error"`
+	SentAt        *time.Time `json:"sentAt" db:"sent_at"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+type SendRequest struct {
+	UserId    string            `json:"userId" validate:"required,uuid"`
+	Event     string            `json:"event" validate:"required,oneof=welcome password_reset order_confirmation back_in_stock"`
+	Data      map[string]string `json:"data"`                         // the values the templates use, e.g. orderId
+	Reference string            `json:"reference" validate:"max=255"` // sent once for a reference, e.g. the order id
+}
+
+type SendResponse struct {
+	Notifications []Notification `json:"data"` // none when the user turned every channel off
+}
+
+type NotificationsQuery struct {
+	UserId string `json:"userId" query:"userId" validate:"omitempty,uuid"`
+	Event  string `json:"event" query:"event" validate:"omitempty,oneof=welcome password_reset order_confirmation back_in_stock"`
+	Status string `json:"status" query:"status" validate:"omitempty,oneof=pending sent dead"`
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page   int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. channel=sms,attempts>1
+	Sort   string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -createdAt
+	Cursor string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the notifications
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedNotificationsResponse struct {
+	Notifications   []Notification `json:"data"`
+	Total           int            `json:"total"`
+	TotalPages      int            `json:"totalPages"`
+	CurrentPage     int            `json:"currentPage"`
+	HasPreviousPage bool           `json:"hasPreviousPage"`
+	HasNextPage     bool           `json:"hasNextPage"`
+	PrevCursor      string         `json:"prevCursor"`
+	NextCursor      string         `json:"nextCursor"`
+}
+
+type DispatchResponse struct {
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+	Dead   int `json:"dead"`
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// entry - a cached value and the time it expires
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache - an in-process cache safe for concurrent use.
+// Values expire after the ttl and the oldest value is evicted once the cache is full.
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[K]entry[V]
+	order   []K
+	now     func() time.Time
+}
+
+// New - creates a new cache.
+//
+//	@param ttl - time.Duration
+//	@param size - int
+//	@return *Cache
+func New[K comparable, V any](ttl time.Duration, size int) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[K]entry[V], size),
+		now:     time.Now,
+	}
+}
+
+// Get - returns the value of the key if it is cached and has not expired.
+//
+//	@param key - K
+//	@return V
+//	@return bool
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// check if the key is cached
+	e, ok := c.entries[key]
+	if !ok || c.now().After(e.expiresAt) {
+		var zero V
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Set - caches the value of the key.
+//
+//	@param key - K
+//	@param value - V
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// only track the order of new keys
+	if _, ok := c.entries[key]; !ok {
+		// evict the oldest keys once the cache is full
+		for len(c.order) > 0 && len(c.order) >= c.size {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+
+	c.entries[key] = entry[V]{value: value, expiresAt: c.now().Add(c.ttl)}
+}
+
+// Delete - removes the key from the cache.
+//
+//	@param key - K
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// check if the key is cached
+	if _, ok := c.entries[key]; !ok {
+		return
+	}
+
+	delete(c.entries, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Clear - removes every key from the cache.
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[K]entry[V], c.size)
+	c.order = nil
+}
+
+// Len - returns the number of cached keys, including expired keys that have not been evicted.
+//
+//	@return int
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGetSet - test the Get and Set functions
+//
+//	@param t - testing.T
+func TestGetSet(t *testing.T) {
+	c := New[string, int](time.Minute, 10)
+
+	// set a value
+	c.Set("a", 1)
+
+	// check the value is cached
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("key a should be cached with 1, got %v %v", v, ok)
+	}
+
+	// check a missing value
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("key b should not be cached")
+	}
+
+	// delete the value
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("key a should not be cached after delete")
+	}
+}
+
+// TestExpiry - test values expire after the ttl
+//
+//	@param t - testing.T
+func TestExpiry(t *testing.T) {
+	now := time.Now()
+	c := New[string, int](time.Minute, 10)
+	c.now = func() time.Time { return now }
+
+	// set a value
+	c.Set("a", 1)
+
+	// move the clock past the ttl
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("key a should have expired")
+	}
+}
+
+// TestEviction - test the oldest values are evicted once the cache is full
+//
+//	@param t - testing.T
+func TestEviction(t *testing.T) {
+	c := New[string, int](time.Minute, 2)
+
+	// fill the cache past its size
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("b", 3)
+	c.Set("c", 4)
+
+	// check the oldest key was evicted
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("key a should have been evicted")
+	}
+	if v, ok := c.Get("b"); !ok || v != 3 {
+		t.Errorf("key b should be cached with 3, got %v %v", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("cache should have 2 keys, got %v", c.Len())
+	}
+}
@@ -8,9 +8,13 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 
 	"encore.dev/rlog"
 	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/condition"
+	"encore.app/pkg/pagination"
 )
 
 // Config is the database configuration
@@ -143,12 +147,12 @@ func queryString(query string, args ...interface{}) string {
 //	@param query - query to execute
 //	@param data - data to bind to the query
 //	@return error - error if any
-func NamedExecQuery(ctx context.Context, db *sqlx.DB, query string, data interface{}) error {
+func NamedExecQuery(ctx context.Context, db sqlx.ExtContext, query string, data interface{}) error {
 	q := queryString(query, data)
 	rlog.Info("database.NamedExecQuery", "query", q)
 
 	// Execute the query.
-	_, err := db.NamedExecContext(ctx, query, data)
+	_, err := sqlx.NamedExecContext(ctx, db, query, data)
 	if err != nil {
 		return err
 	}
@@ -156,6 +160,28 @@ func NamedExecQuery(ctx context.Context, db *sqlx.DB, query string, data interfa
 	return nil
 }
 
+// NamedExecRowsQuery - helper function for executing queries that return no rows, returning the number of rows
+// they changed. Used for conditional UPDATE and DELETE queries whose caller has to know if they matched.
+//
+//	@param ctx - context
+//	@param db - database connection
+//	@param query - query to execute
+//	@param data - data to bind to the query
+//	@return int64 - number of rows affected
+//	@return error - error if any
+func NamedExecRowsQuery(ctx context.Context, db sqlx.ExtContext, query string, data interface{}) (int64, error) {
+	q := queryString(query, data)
+	rlog.Info("database.NamedExecRowsQuery", "query", q)
+
+	// Execute the query.
+	result, err := sqlx.NamedExecContext(ctx, db, query, data)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 // NamedSliceQuery - helper function for executing queries that return a slice of rows.
 // Most of the time, this will be used for SELECT queries.
 //
@@ -165,7 +191,7 @@ func NamedExecQuery(ctx context.Context, db *sqlx.DB, query string, data interfa
 //	@param data - data to bind to the query
 //	@param dest - destination to scan the rows into
 //	@return error - error if any
-func NamedSliceQuery(ctx context.Context, db *sqlx.DB, query string, data interface{}, dest interface{}) error {
+func NamedSliceQuery(ctx context.Context, db sqlx.ExtContext, query string, data interface{}, dest interface{}) error {
 	// get formated query string
 	q := queryString(query, data)
 	// log query info
@@ -179,10 +205,12 @@ func NamedSliceQuery(ctx context.Context, db *sqlx.DB, query string, data interf
 	}
 
 	// Execute the query.
-	rows, err := db.NamedQueryContext(ctx, query, data)
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
 	if err != nil {
 		return err
 	}
+	// release the connection once the rows are read
+	defer rows.Close()
 
 	// get the next row
 	slice := val.Elem()
@@ -197,7 +225,7 @@ func NamedSliceQuery(ctx context.Context, db *sqlx.DB, query string, data interf
 		slice.Set(reflect.Append(slice, v.Elem()))
 	}
 
-	return nil
+	return rows.Err()
 }
 
 // NamedStructQuery - helper function for executing queries that return a single row.
@@ -209,15 +237,17 @@ func NamedSliceQuery(ctx context.Context, db *sqlx.DB, query string, data interf
 //	@param data - data to bind to the query
 //	@param dest - destination to scan the row into
 //	@return error - error if any
-func NamedStructQuery(ctx context.Context, db *sqlx.DB, query string, data interface{}, dest interface{}) error {
+func NamedStructQuery(ctx context.Context, db sqlx.ExtContext, query string, data interface{}, dest interface{}) error {
 	q := queryString(query, data)
 	rlog.Info("database.NamedStructQuery", "query", q)
 
 	// Execute the query.
-	rows, err := db.NamedQueryContext(ctx, query, data)
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
 	if err != nil {
 		return err
 	}
+	// release the connection once the rows are read
+	defer rows.Close()
 
 	// If there are no rows, return an error.
 	if !rows.Next() {
@@ -240,15 +270,17 @@ func NamedStructQuery(ctx context.Context, db *sqlx.DB, query string, data inter
 //	@param data - data to bind to the query
 //	@return int - integer value returned from the query
 //	@return error - error if any
-func NamedCountQuery(ctx context.Context, db *sqlx.DB, query string, data interface{}) (int, error) {
+func NamedCountQuery(ctx context.Context, db sqlx.ExtContext, query string, data interface{}) (int, error) {
 	q := queryString(query, data)
 	rlog.Info("database.NamedQueryCount", "query", q)
 
 	// Execute the query.
-	rows, err := db.NamedQueryContext(ctx, query, data)
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
 	if err != nil {
 		return 0, err
 	}
+	// release the connection once the rows are read
+	defer rows.Close()
 
 	// If there are no rows, return an error.
 	if !rows.Next() {
@@ -264,3 +296,131 @@ func NamedCountQuery(ctx context.Context, db *sqlx.DB, query string, data interf
 
 	return count, nil
 }
+
+// NamedPageQuery - helper function for selecting a page of a list parsed into a statement.
+// The items are counted unless the total is skipped, then read after the cursor or from the offset of the page,
+// fetching one more item than the limit to find the next page. The limit of the options defaults to
+// pagination.DefaultLimit.
+//
+//	@param ctx - context
+//	@param db - database connection
+//	@param from - the table or subquery the items are selected from, with the columns of the schema
+//	@param statement - the parsed filter and sort, with any conditions added
+//	@param options - the limit, page, cursor and whether to skip the total
+//	@param dest - pointer to the slice of items
+//	@return *pagination.PageInfo - where the page is in the list
+//	@return error - error if any
+func NamedPageQuery(ctx context.Context, db sqlx.ExtContext, from string, statement *pagination.Statement, options *pagination.Options, dest interface{}) (*pagination.PageInfo, error) {
+	// get count of the items unless it is skipped
+	count := 0
+	if !options.SkipTotal {
+		var err error
+		count, err = NamedCountQuery(ctx, db, fmt.Sprintf("SELECT COUNT(*) FROM %v %v", from, statement.Where()), statement.Args)
+		if err != nil {
+			return nil, fmt.Errorf("getting count: %w", err)
+		}
+	}
+
+	// set the limit to the default if it is less than 1 or greater than the maximum
+	if options.Limit < 1 || options.Limit > pagination.MaxLimit {
+		options.Limit = pagination.DefaultLimit
+	}
+	pages := condition.Ternary(options.SkipTotal, 0, pagination.New(options.Page, options.Limit, count).Pages())
+
+	// continue from the cursor or from the offset of the page
+	if len(options.Cursor) > 0 {
+		if err := statement.ApplyCursor(options.Cursor); err != nil {
+			return nil, err
+		}
+	} else {
+		statement.Offset = pagination.Offset(options.Page, options.Limit, pages)
+	}
+
+	// execute query
+	statement.Args["limit"] = options.Limit + 1
+	statement.Args["offset"] = statement.Offset
+	q := fmt.Sprintf("SELECT %v FROM %v %v %v LIMIT :limit OFFSET :offset", statement.Select(), from, statement.Where(), statement.OrderBy())
+	if err := NamedSliceQuery(ctx, db, q, statement.Args, dest); err != nil {
+		return nil, err
+	}
+
+	// create the cursors of the previous and next pages
+	window, err := statement.Window(dest, options.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pagination.PageInfo{
+		Total:           count,
+		TotalPages:      pages,
+		CurrentPage:     condition.Ternary(len(options.Cursor) > 0, 0, statement.Offset/options.Limit+1),
+		HasPreviousPage: window.HasPrevious,
+		HasNextPage:     window.HasNext,
+		PrevCursor:      window.PrevCursor,
+		NextCursor:      window.NextCursor,
+	}, nil
+}
+
+// Transaction - helper function for running a set of queries in a single transaction.
+// The transaction is committed when fn returns nil and rolled back otherwise, so the
+// query helpers above can be passed the transaction in place of the database.
+//
+//	@param ctx - context
+//	@param db - database connection
+//	@param fn - function to run within the transaction
+//	@return error - error if any
+func Transaction(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	// begin the transaction
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+
+	// run the queries and roll back on failure
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			rlog.Error("database.Transaction", "rollback", rbErr)
+		}
+		return err
+	}
+
+	// commit the transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Lazy - helper function for connecting to a database on first use.
+// Packages holding their connection in a lazy handle can be imported, e.g. for their models,
+// by code running outside of the database runtime such as unit tests.
+//
+//	@param connect - function opening the connection
+//	@return func() *sqlx.DB - function returning the connection
+func Lazy(connect func() *sqlx.DB) func() *sqlx.DB {
+	var once sync.Once
+	var db *sqlx.DB
+
+	return func() *sqlx.DB {
+		once.Do(func() {
+			db = connect()
+		})
+		return db
+	}
+}
+
+// Available - helper function for tests checking a lazy database can be connected to. Outside of the database
+// runtime, e.g. under go test rather than encore test, connecting panics and tests needing the database skip.
+//
+//	@param db - function returning the connection
+//	@return bool - whether the database can be used
+func Available(db func() *sqlx.DB) (available bool) {
+	defer func() {
+		if recover() != nil {
+			available = false
+		}
+	}()
+
+	return db() != nil
+}
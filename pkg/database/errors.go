@@ -9,3 +9,13 @@ var (
 	ErrAuthenticationFailure = errors.New("authentication failed")
 	ErrForbidden             = errors.New("attempted action is not allowed")
 )
+
+// IsUniqueViolation - checks if an error is postgres refusing a row that breaks a unique index.
+//
+//	@param err - error
+//	@return bool
+func IsUniqueViolation(err error) bool {
+	// the postgres driver errors report their SQLSTATE code
+	var state interface{ SQLState() string }
+	return errors.As(err, &state) && state.SQLState() == "23505"
+}
@@ -1 +1,25 @@
 package events
+
+import (
+	"encore.dev/rlog"
+)
+
+// Accept - Accept reports whether a subscriber written for a schema version can handle an event. Events of
+// a newer version are logged and skipped, so subscribers are upgraded before the publishers of a new version.
+// A subscription handler starts with:
+//
+//	if !events.Accept(event.Meta, 1) {
+//		return nil
+//	}
+//
+// @param meta - the metadata of the event
+// @param known - the newest version the subscriber handles
+// @return bool
+func Accept(meta Meta, known int) bool {
+	if meta.Version > known {
+		rlog.Warn("events.Accept", "id", meta.Id, "type", meta.Type, "version", meta.Version, "known", known)
+		return false
+	}
+
+	return true
+}
@@ -1 +1,131 @@
 package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"encore.app/pkg/money"
+)
+
+// the names of the events, kept in every message so stored or relayed messages can be told apart
+const (
+	TypeUserCreated    = "user.created"
+	TypeUserDeleted    = "user.deleted"
+	TypeRoleChanged    = "user.role_changed"
+	TypeProductCreated = "product.created"
+	TypePriceChanged   = "product.price_changed"
+	TypeStockChanged   = "product.stock_changed"
+	TypeOrderPlaced    = "order.placed"
+
+	TypeCleanupCompleted = "user.cleanup_completed"
+)
+
+// Versions - the schema version published for every event. Adding a field keeps the version,
+// renaming, removing or changing the meaning of a field bumps it.
+var Versions = map[string]int{
+	TypeUserCreated:    1,
+	TypeUserDeleted:    1,
+	TypeRoleChanged:    1,
+	TypeProductCreated: 1,
+	TypePriceChanged:   1,
+	TypeStockChanged:   1,
+	TypeOrderPlaced:    1,
+
+	TypeCleanupCompleted: 1,
+}
+
+// Meta - what every event carries besides its payload
+type Meta struct {
+	Id         string    `json:"id"`         // unique for every event, subscribers can ignore an id they have seen
+	Type       string    `json:"type"`       // e.g. user.created
+	Version    int       `json:"version"`    // the schema version of the payload
+	OccurredAt time.Time `json:"occurredAt"` // when the change was made
+}
+
+// NewMeta - NewMeta creates the metadata of an event with the current schema version of its type.
+//
+// @param eventType - string
+// @param at - when the change was made
+// @return Meta
+func NewMeta(eventType string, at time.Time) Meta {
+	return Meta{Id: uuid.New().String(), Type: eventType, Version: Versions[eventType], OccurredAt: at.UTC()}
+}
+
+// UserCreated - a user signed up or was created by an admin
+type UserCreated struct {
+	Meta     Meta     `json:"meta"`
+	UserId   string   `json:"userId"`
+	Email    string   `json:"email"`
+	Phone    string   `json:"phone"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+}
+
+// UserDeleted - a user was deleted, services remove what they keep for the user
+type UserDeleted struct {
+	Meta   Meta   `json:"meta"`
+	UserId string `json:"userId"`
+}
+
+// CleanupCompleted - a service removed what it kept for a deleted user
+type CleanupCompleted struct {
+	Meta    Meta           `json:"meta"`
+	UserId  string         `json:"userId"`
+	Service string         `json:"service"` // e.g. products
+	Removed map[string]int `json:"removed"` // the rows removed, by what they were
+}
+
+// RoleChanged - the roles of a user changed
+type RoleChanged struct {
+	Meta          Meta     `json:"meta"`
+	UserId        string   `json:"userId"`
+	Roles         []string `json:"roles"`
+	PreviousRoles []string `json:"previousRoles"`
+}
+
+// ProductCreated - a product was added to the catalog
+type ProductCreated struct {
+	Meta          Meta        `json:"meta"`
+	ProductId     string      `json:"productId"`
+	Name          string      `json:"name"`
+	Brand         string      `json:"brand"`
+	CategoryId    string      `json:"categoryId"`
+	Price         money.Money `json:"price"`
+	StockQuantity int         `json:"stockQuantity"`
+}
+
+// PriceChanged - the price of a product changed
+type PriceChanged struct {
+	Meta      Meta        `json:"meta"`
+	ProductId string      `json:"productId"`
+	PriceId   string      `json:"priceId"` // the price in the timeline of the product
+	OldPrice  money.Money `json:"oldPrice"`
+	NewPrice  money.Money `json:"newPrice"`
+}
+
+// StockChanged - the stock of a product changed
+type StockChanged struct {
+	Meta        Meta   `json:"meta"`
+	ProductId   string `json:"productId"`
+	OldQuantity int    `json:"oldQuantity"`
+	NewQuantity int    `json:"newQuantity"`
+	Reason      string `json:"reason"`               // e.g. sale, restock or adjustment
+	LocationId  string `json:"locationId,omitempty"` // the location the stock moved at, the quantities are of every location
+}
+
+// OrderLine - a product of a placed order
+type OrderLine struct {
+	ProductId string      `json:"productId"`
+	Quantity  int         `json:"quantity"`
+	Price     money.Money `json:"price"` // the price of a unit paid
+}
+
+// OrderPlaced - a customer placed an order
+type OrderPlaced struct {
+	Meta    Meta        `json:"meta"`
+	OrderId string      `json:"orderId"`
+	UserId  string      `json:"userId"`
+	Lines   []OrderLine `json:"lines"`
+	Total   money.Money `json:"total"`
+}
@@ -0,0 +1,107 @@
+// Package topics declares the Pub/Sub topics of the domain events. Topics cannot be created outside of the
+// Encore runtime, so only the services relaying their outbox and the subscribers import this package; the
+// stores save their events with the outbox package instead.
+package topics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"encore.dev/pubsub"
+
+	"encore.app/pkg/events"
+	"encore.app/pkg/outbox"
+)
+
+// the topics of the domain events, every topic delivers an event at least once so subscribers must be idempotent
+var (
+	UserCreated = pubsub.NewTopic[*events.UserCreated]("user-created", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	UserDeleted = pubsub.NewTopic[*events.UserDeleted]("user-deleted", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	// published by every service once it removed what it kept for a deleted user
+	CleanupCompleted = pubsub.NewTopic[*events.CleanupCompleted]("user-cleanup-completed", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	RoleChanged = pubsub.NewTopic[*events.RoleChanged]("user-role-changed", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	ProductCreated = pubsub.NewTopic[*events.ProductCreated]("product-created", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	PriceChanged = pubsub.NewTopic[*events.PriceChanged]("product-price-changed", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	// published when the stock of a product moves
+	StockChanged = pubsub.NewTopic[*events.StockChanged]("product-stock-changed", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	// published by the checkout once orders are placed
+	OrderPlaced = pubsub.NewTopic[*events.OrderPlaced]("order-placed", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+)
+
+// Publish - Publish publishes a message of an outbox on the topic of its event.
+//
+//	@param ctx - context.Context
+//	@param message - *outbox.Message
+//	@return message id
+//	@return error
+func Publish(ctx context.Context, message *outbox.Message) (string, error) {
+	switch message.Type {
+	case events.TypeUserCreated:
+		event := &events.UserCreated{}
+		if err := json.Unmarshal(message.Payload, event); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		return UserCreated.Publish(ctx, event)
+	case events.TypeUserDeleted:
+		event := &events.UserDeleted{}
+		if err := json.Unmarshal(message.Payload, event); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		return UserDeleted.Publish(ctx, event)
+	case events.TypeCleanupCompleted:
+		event := &events.CleanupCompleted{}
+		if err := json.Unmarshal(message.Payload, event); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		return CleanupCompleted.Publish(ctx, event)
+	case events.TypeRoleChanged:
+		event := &events.RoleChanged{}
+		if err := json.Unmarshal(message.Payload, event); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		return RoleChanged.Publish(ctx, event)
+	case events.TypeProductCreated:
+		event := &events.ProductCreated{}
+		if err := json.Unmarshal(message.Payload, event); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		return ProductCreated.Publish(ctx, event)
+	case events.TypePriceChanged:
+		event := &events.PriceChanged{}
+		if err := json.Unmarshal(message.Payload, event); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		return PriceChanged.Publish(ctx, event)
+	case events.TypeStockChanged:
+		event := &events.StockChanged{}
+		if err := json.Unmarshal(message.Payload, event); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		return StockChanged.Publish(ctx, event)
+	case events.TypeOrderPlaced:
+		event := &events.OrderPlaced{}
+		if err := json.Unmarshal(message.Payload, event); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		return OrderPlaced.Publish(ctx, event)
+	default:
+		return "", fmt.Errorf("no topic for event type[%v]", message.Type)
+	}
+}
@@ -0,0 +1,27 @@
+// Package lifecycle holds the state machine moving records such as reviews, purchase orders, transfers and
+// stocktakes between their statuses.
+package lifecycle
+
+import "fmt"
+
+// Machine - the status an action moves a record to, by the status it is in
+type Machine struct {
+	Noun        string                       // what the records are called in errors, e.g. transfer
+	Transitions map[string]map[string]string // the next status, by status and action
+	Err         error                        // wrapped when an action is not allowed
+}
+
+// Next - Next returns the status an action moves a record to.
+//
+//	@param status - the status of the record
+//	@param action - the action taken on the record
+//	@return the new status
+//	@return error
+func (m *Machine) Next(status, action string) (string, error) {
+	next, ok := m.Transitions[status][action]
+	if !ok {
+		return "", fmt.Errorf("%w: cannot %v a %v %v", m.Err, action, status, m.Noun)
+	}
+
+	return next, nil
+}
@@ -0,0 +1,57 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+)
+
+// errInvalid - the error of the test machine
+var errInvalid = errors.New("invalid transition")
+
+// order - a machine where drafts are sent, and drafts and sent orders are cancelled
+var order = &Machine{
+	Noun: "order",
+	Transitions: map[string]map[string]string{
+		"draft": {"send": "sent", "cancel": "cancelled"},
+		"sent":  {"cancel": "cancelled"},
+	},
+	Err: errInvalid,
+}
+
+// TestNext - test the status actions move records to
+//
+//	@param t - testing.T
+func TestNext(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		status string
+		action string
+		next   string
+	}{
+		{status: "draft", action: "send", next: "sent"},
+		{status: "draft", action: "cancel", next: "cancelled"},
+		{status: "sent", action: "cancel", next: "cancelled"},
+		{status: "sent", action: "send"},
+		{status: "cancelled", action: "cancel"},
+		{status: "unknown", action: "send"},
+		{status: "draft", action: "unknown"},
+	}
+
+	for _, item := range slice {
+		next, err := order.Next(item.status, item.action)
+		if len(item.next) < 1 {
+			if !errors.Is(err, errInvalid) {
+				t.Errorf("should not %v a %v order, got %v", item.action, item.status, next)
+			}
+			continue
+		}
+		if err != nil || next != item.next {
+			t.Errorf("%v a %v order should make it %v, got %v %v", item.action, item.status, item.next, next, err)
+		}
+	}
+
+	// the error names the record
+	if _, err := order.Next("sent", "send"); err == nil || err.Error() != "invalid transition: cannot send a sent order" {
+		t.Errorf("should explain the transition, got %v", err)
+	}
+}
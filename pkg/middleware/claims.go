@@ -31,3 +31,27 @@ func GetVerifiedClaims(ctx context.Context, role string) (*DataI, error) {
 
 	return claims, nil
 }
+
+// RequireRole - is a function that returns the verified claims when the user has at least one of the roles.
+//
+//	@param ctx - context.Context
+//	@param roles - ...string
+//	@return *DataI
+//	@return error
+func RequireRole(ctx context.Context, roles ...string) (*DataI, error) {
+	// check for claims
+	claims, err := GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &DataI{}, err
+	}
+
+	// check for the roles
+	if !claims.HasRole(roles...) {
+		return &DataI{}, &errs.Error{
+			Code:    errs.PermissionDenied,
+			Message: "unauthorized: you are not authorized to perform this action",
+		}
+	}
+
+	return claims, nil
+}
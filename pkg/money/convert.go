@@ -0,0 +1,48 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ParseRate - parses an exchange rate from a decimal string, e.g. "1.0850".
+// The rate is the number of major units of the quote currency in one major unit of the base currency.
+//
+//	@param s - string
+//	@return *big.Rat
+//	@return error
+func ParseRate(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+
+	// only plain decimals are accepted
+	if len(s) < 1 || strings.ContainsAny(s, "eE/") {
+		return nil, fmt.Errorf("%w: %q is not a rate", ErrInvalidAmount, s)
+	}
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q is not a rate", ErrInvalidAmount, s)
+	}
+
+	return rate, nil
+}
+
+// Convert - converts the amount to another currency with an exchange rate.
+// The converted amount is rounded to the minor units of the currency with the rounding mode.
+//
+//	@param currency - string
+//	@param rate - *big.Rat
+//	@param mode - RoundingMode
+//	@return Money
+//	@return error
+func (m Money) Convert(currency string, rate *big.Rat, mode RoundingMode) (Money, error) {
+	from, ok := LookupCurrency(m.Currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %v", ErrUnknownCurrency, m.Currency)
+	}
+
+	// the amount in major units of the currency converted from
+	major := new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10(from.Exponent))
+
+	return FromMajor(major.Mul(major, rate), currency, mode)
+}
@@ -0,0 +1,52 @@
+package money
+
+import "strings"
+
+// DefaultCurrency - the currency of the catalog when none is given
+const DefaultCurrency = "USD"
+
+// Currency - an ISO 4217 currency
+type Currency struct {
+	Code     string // the alphabetic code, e.g. USD
+	Exponent int    // the number of digits after the decimal separator
+	Symbol   string // the symbol used when formatting
+}
+
+// currencies - the supported ISO 4217 currencies
+var currencies = map[string]Currency{
+	"AUD": {Code: "AUD", Exponent: 2, Symbol: "A$"},
+	"CAD": {Code: "CAD", Exponent: 2, Symbol: "CA$"},
+	"CHF": {Code: "CHF", Exponent: 2, Symbol: "CHF"},
+	"CNY": {Code: "CNY", Exponent: 2, Symbol: "CN¥"},
+	"EUR": {Code: "EUR", Exponent: 2, Symbol: "€"},
+	"GBP": {Code: "GBP", Exponent: 2, Symbol: "£"},
+	"GHS": {Code: "GHS", Exponent: 2, Symbol: "GH₵"},
+	"INR": {Code: "INR", Exponent: 2, Symbol: "₹"},
+	"JPY": {Code: "JPY", Exponent: 0, Symbol: "¥"},
+	"KES": {Code: "KES", Exponent: 2, Symbol: "KSh"},
+	"KRW": {Code: "KRW", Exponent: 0, Symbol: "₩"},
+	"KWD": {Code: "KWD", Exponent: 3, Symbol: "KD"},
+	"NGN": {Code: "NGN", Exponent: 2, Symbol: "₦"},
+	"USD": {Code: "USD", Exponent: 2, Symbol: "$"},
+	"XOF": {Code: "XOF", Exponent: 0, Symbol: "CFA"},
+	"ZAR": {Code: "ZAR", Exponent: 2, Symbol: "R"},
+}
+
+// LookupCurrency - returns the currency with the code.
+//
+//	@param code - string
+//	@return Currency
+//	@return bool
+func LookupCurrency(code string) (Currency, bool) {
+	currency, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	return currency, ok
+}
+
+// IsCurrency - returns true if the code is a supported currency.
+//
+//	@param code - string
+//	@return bool
+func IsCurrency(code string) bool {
+	_, ok := LookupCurrency(code)
+	return ok
+}
@@ -0,0 +1,11 @@
+package money
+
+import "errors"
+
+// Set of error variables for money operations.
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrOverflow         = errors.New("amount overflows")
+)
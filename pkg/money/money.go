@@ -0,0 +1,513 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode - how an amount between two minor units is rounded
+type RoundingMode int
+
+const (
+	HalfEven RoundingMode = iota // to the nearest unit, ties to the even unit (banker's rounding)
+	HalfUp                       // to the nearest unit, ties away from zero
+	HalfDown                     // to the nearest unit, ties towards zero
+	Up                           // away from zero
+	Down                         // towards zero
+	Ceiling                      // towards positive infinity
+	Floor                        // towards negative infinity
+)
+
+// Money - an exact amount of money in the minor units of its currency, e.g. cents
+type Money struct {
+	Amount   int64  `json:"amount"`   // the amount in minor units, e.g. 1234 for 12.34 USD
+	Currency string `json:"currency"` // the ISO 4217 currency code
+}
+
+// New - creates an amount of money from minor units.
+//
+//	@param amount - int64
+//	@param currency - string
+//	@return Money
+//	@return error
+func New(amount int64, currency string) (Money, error) {
+	c, ok := LookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %v", ErrUnknownCurrency, currency)
+	}
+
+	return Money{Amount: amount, Currency: c.Code}, nil
+}
+
+// Zero - creates an amount of zero in the currency.
+//
+//	@param currency - string
+//	@return Money
+func Zero(currency string) Money {
+	return Money{Currency: strings.ToUpper(strings.TrimSpace(currency))}
+}
+
+// Parse - creates an amount of money from a decimal string, e.g. "12.34".
+// Amounts with more decimals than the currency allows are rejected rather than rounded.
+//
+//	@param s - string
+//	@param currency - string
+//	@return Money
+//	@return error
+func Parse(s, currency string) (Money, error) {
+	c, ok := LookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %v", ErrUnknownCurrency, currency)
+	}
+
+	// split the sign, whole and fractional parts
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, fraction, _ := strings.Cut(s, ".")
+	if len(whole) < 1 && len(fraction) < 1 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(fraction) > c.Exponent {
+		return Money{}, fmt.Errorf("%w: %v allows %v decimals", ErrInvalidAmount, c.Code, c.Exponent)
+	}
+
+	// pad the fraction to the exponent and read the digits as minor units
+	digits := whole + fraction + strings.Repeat("0", c.Exponent-len(fraction))
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+	}
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+
+	if negative {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: c.Code}, nil
+}
+
+// FromMajor - creates an amount of money from a number of major units, e.g. 12.34 dollars.
+// The amount is rounded to the minor units of the currency with the rounding mode.
+//
+//	@param major - *big.Rat
+//	@param currency - string
+//	@param mode - RoundingMode
+//	@return Money
+//	@return error
+func FromMajor(major *big.Rat, currency string, mode RoundingMode) (Money, error) {
+	c, ok := LookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %v", ErrUnknownCurrency, currency)
+	}
+
+	// scale to minor units and round
+	scaled := new(big.Rat).Mul(major, new(big.Rat).SetInt(pow10(c.Exponent)))
+	amount := Round(scaled.Num(), scaled.Denom(), mode)
+	if !amount.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{Amount: amount.Int64(), Currency: c.Code}, nil
+}
+
+// Round - divides num by den and rounds the quotient with the rounding mode.
+//
+//	@param num - *big.Int
+//	@param den - *big.Int
+//	@param mode - RoundingMode
+//	@return *big.Int
+func Round(num, den *big.Int, mode RoundingMode) *big.Int {
+	// the quotient truncated towards zero
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	// the sign of the exact result
+	sign := int64(num.Sign() * den.Sign())
+	away := func() *big.Int { return q.Add(q, big.NewInt(sign)) }
+
+	switch mode {
+	case Up:
+		return away()
+	case Down:
+		return q
+	case Ceiling:
+		if sign > 0 {
+			return away()
+		}
+		return q
+	case Floor:
+		if sign < 0 {
+			return away()
+		}
+		return q
+	}
+
+	// compare the remainder with half of the divisor
+	half := new(big.Int).Abs(r)
+	half.Mul(half, big.NewInt(2))
+	switch half.Cmp(new(big.Int).Abs(den)) {
+	case 1:
+		return away()
+	case -1:
+		return q
+	}
+
+	// the remainder is exactly half
+	switch mode {
+	case HalfUp:
+		return away()
+	case HalfDown:
+		return q
+	default:
+		if q.Bit(0) == 1 {
+			return away()
+		}
+		return q
+	}
+}
+
+// pow10 - returns 10 to the power of n.
+//
+//	@param n - int
+//	@return *big.Int
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// sameCurrency - returns an error if the amounts are in different currencies.
+//
+//	@param o - Money
+//	@return error
+func (m Money) sameCurrency(o Money) error {
+	if m.Currency != o.Currency {
+		return fmt.Errorf("%w: %v and %v", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+
+	return nil
+}
+
+// Add - returns the sum of the amounts.
+//
+//	@param o - Money
+//	@return Money
+//	@return error
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+
+	// check for overflow
+	if (o.Amount > 0 && m.Amount > math.MaxInt64-o.Amount) || (o.Amount < 0 && m.Amount < math.MinInt64-o.Amount) {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub - returns the difference of the amounts.
+//
+//	@param o - Money
+//	@return Money
+//	@return error
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+
+	return m.Add(o.Neg())
+}
+
+// Mul - returns the amount multiplied by a whole number, e.g. a quantity.
+//
+//	@param n - int64
+//	@return Money
+//	@return error
+func (m Money) Mul(n int64) (Money, error) {
+	return m.MulRat(n, 1, HalfEven)
+}
+
+// MulRat - returns the amount multiplied by num/den, rounded with the rounding mode.
+//
+//	@param num - int64
+//	@param den - int64
+//	@param mode - RoundingMode
+//	@return Money
+//	@return error
+func (m Money) MulRat(num, den int64, mode RoundingMode) (Money, error) {
+	if den == 0 {
+		return Money{}, fmt.Errorf("%w: division by zero", ErrInvalidAmount)
+	}
+
+	// multiply and round with big integers so nothing overflows on the way
+	product := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num))
+	amount := Round(product, big.NewInt(den), mode)
+	if !amount.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{Amount: amount.Int64(), Currency: m.Currency}, nil
+}
+
+// Percentage - returns a percentage of the amount in basis points (1/100 of a percent), e.g. 1250 for 12.5%.
+//
+//	@param basisPoints - int64
+//	@param mode - RoundingMode
+//	@return Money
+//	@return error
+func (m Money) Percentage(basisPoints int64, mode RoundingMode) (Money, error) {
+	return m.MulRat(basisPoints, 10000, mode)
+}
+
+// Allocate - splits the amount by the ratios without losing minor units.
+// Units left over from rounding down are given to the first parts.
+//
+//	@param ratios - ...int64
+//	@return []Money
+//	@return error
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	// sum the ratios
+	var total int64
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, fmt.Errorf("%w: negative ratio", ErrInvalidAmount)
+		}
+		total += ratio
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: ratios sum to zero", ErrInvalidAmount)
+	}
+
+	// give every part its share, rounded towards zero
+	parts := make([]Money, len(ratios))
+	remainder := m.Amount
+	for i, ratio := range ratios {
+		part, err := m.MulRat(ratio, total, Down)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = part
+		remainder -= part.Amount
+	}
+
+	// hand out the left over units one at a time
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(parts) {
+		if ratios[i] == 0 {
+			continue
+		}
+		parts[i].Amount += step
+		remainder -= step
+	}
+
+	return parts, nil
+}
+
+// Neg - returns the amount with the opposite sign.
+//
+//	@return Money
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Cmp - compares the amounts, returning -1, 0 or 1.
+//
+//	@param o - Money
+//	@return int
+//	@return error
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Min - returns the smaller of the amounts.
+//
+//	@param o - Money
+//	@return Money
+//	@return error
+func (m Money) Min(o Money) (Money, error) {
+	cmp, err := m.Cmp(o)
+	if err != nil {
+		return Money{}, err
+	}
+	if cmp > 0 {
+		return o, nil
+	}
+
+	return m, nil
+}
+
+// IsZero - returns true if the amount is zero.
+//
+//	@return bool
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative - returns true if the amount is less than zero.
+//
+//	@return bool
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Validate - returns an error if the currency is not supported.
+//
+//	@return error
+func (m Money) Validate() error {
+	if !IsCurrency(m.Currency) {
+		return fmt.Errorf("%w: %q", ErrUnknownCurrency, m.Currency)
+	}
+
+	return nil
+}
+
+// Decimal - returns the amount in major units as a decimal string, e.g. "-12.34".
+//
+//	@return string
+func (m Money) Decimal() string {
+	c, ok := LookupCurrency(m.Currency)
+	if !ok || c.Exponent == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	// split the absolute amount into whole and fractional digits
+	digits := strconv.FormatUint(absUint(m.Amount), 10)
+	if len(digits) <= c.Exponent {
+		digits = strings.Repeat("0", c.Exponent-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-c.Exponent], digits[len(digits)-c.Exponent:]
+
+	if m.Amount < 0 {
+		return "-" + whole + "." + fraction
+	}
+
+	return whole + "." + fraction
+}
+
+// absUint - returns the absolute value of n, including math.MinInt64.
+//
+//	@param n - int64
+//	@return uint64
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+
+	return uint64(n)
+}
+
+// Format - returns the amount with the currency symbol, e.g. "$12.34" or "-€5.00".
+//
+//	@return string
+func (m Money) Format() string {
+	c, ok := LookupCurrency(m.Currency)
+	if !ok {
+		return m.String()
+	}
+
+	if m.Amount < 0 {
+		return "-" + c.Symbol + strings.TrimPrefix(m.Decimal(), "-")
+	}
+
+	return c.Symbol + m.Decimal()
+}
+
+// String - returns the amount with the currency code, e.g. "12.34 USD".
+//
+//	@return string
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// UnmarshalJSON - decodes an amount and checks its currency.
+//
+//	@param data - []byte
+//	@return error
+func (m *Money) UnmarshalJSON(data []byte) error {
+	// decode into a type without this method
+	type plain Money
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	// an empty currency is left for validation by the caller
+	p.Currency = strings.ToUpper(strings.TrimSpace(p.Currency))
+	if len(p.Currency) > 0 && !IsCurrency(p.Currency) {
+		return fmt.Errorf("%w: %q", ErrUnknownCurrency, p.Currency)
+	}
+
+	*m = Money(p)
+	return nil
+}
+
+// Value - encodes the amount as a postgres monetary composite, e.g. "(1234,USD)".
+//
+//	@return driver.Value
+//	@return error
+func (m Money) Value() (driver.Value, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return fmt.Sprintf("(%d,%s)", m.Amount, m.Currency), nil
+}
+
+// Scan - decodes a postgres monetary composite, e.g. "(1234,USD)".
+//
+//	@param src - interface{}
+//	@return error
+func (m *Money) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("scanning money: unsupported type %T", src)
+	}
+
+	// split the fields of the composite
+	amount, currency, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "("), ")"), ",")
+	if !ok {
+		return fmt.Errorf("scanning money: %w: %q", ErrInvalidAmount, s)
+	}
+
+	// read the amount
+	value, err := strconv.ParseInt(strings.Trim(amount, `" `), 10, 64)
+	if err != nil {
+		return fmt.Errorf("scanning money: %w: %q", ErrInvalidAmount, s)
+	}
+
+	*m = Money{Amount: value, Currency: strings.ToUpper(strings.Trim(currency, `" `))}
+	return nil
+}
@@ -0,0 +1,294 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+// TestParse - test the Parse function
+//
+//	@param t - testing.T
+func TestParse(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		value    string
+		currency string
+		amount   int64
+		err      error
+	}{
+		{value: "12.34", currency: "USD", amount: 1234},
+		{value: "12.3", currency: "usd", amount: 1230},
+		{value: "-0.05", currency: "EUR", amount: -5},
+		{value: ".5", currency: "GBP", amount: 50},
+		{value: "7", currency: "JPY", amount: 7},
+		{value: "1.234", currency: "KWD", amount: 1234},
+		{value: "1.234", currency: "USD", err: ErrInvalidAmount},
+		{value: "1.5", currency: "JPY", err: ErrInvalidAmount},
+		{value: "1e3", currency: "USD", err: ErrInvalidAmount},
+		{value: "", currency: "USD", err: ErrInvalidAmount},
+		{value: "99999999999999999999", currency: "USD", err: ErrOverflow},
+		{value: "1", currency: "XXX", err: ErrUnknownCurrency},
+	}
+
+	// check the amount of every value
+	for _, item := range slice {
+		m, err := Parse(item.value, item.currency)
+		if item.err != nil {
+			if !errors.Is(err, item.err) {
+				t.Errorf("%q %v should fail with %v, got %v", item.value, item.currency, item.err, err)
+			}
+			continue
+		}
+		if err != nil || m.Amount != item.amount {
+			t.Errorf("%q %v should be %v minor units, got %v %v", item.value, item.currency, item.amount, m.Amount, err)
+		}
+	}
+}
+
+// TestRound - test the Round function with every rounding mode
+//
+//	@param t - testing.T
+func TestRound(t *testing.T) {
+	// the quotients of 25/10, 35/10, 26/10, 24/10 and their negatives
+	values := []int64{25, 35, 26, 24, -25, -35, -26, -24}
+
+	// create a slice
+	slice := []struct {
+		mode    RoundingMode
+		results []int64
+	}{
+		{mode: HalfEven, results: []int64{2, 4, 3, 2, -2, -4, -3, -2}},
+		{mode: HalfUp, results: []int64{3, 4, 3, 2, -3, -4, -3, -2}},
+		{mode: HalfDown, results: []int64{2, 3, 3, 2, -2, -3, -3, -2}},
+		{mode: Up, results: []int64{3, 4, 3, 3, -3, -4, -3, -3}},
+		{mode: Down, results: []int64{2, 3, 2, 2, -2, -3, -2, -2}},
+		{mode: Ceiling, results: []int64{3, 4, 3, 3, -2, -3, -2, -2}},
+		{mode: Floor, results: []int64{2, 3, 2, 2, -3, -4, -3, -3}},
+	}
+
+	// check the result of every mode
+	for _, item := range slice {
+		for i, value := range values {
+			if result := Round(big.NewInt(value), big.NewInt(10), item.mode).Int64(); result != item.results[i] {
+				t.Errorf("mode %v should round %v/10 to %v, got %v", item.mode, value, item.results[i], result)
+			}
+		}
+	}
+}
+
+// TestArithmetic - test the arithmetic functions
+//
+//	@param t - testing.T
+func TestArithmetic(t *testing.T) {
+	a := Money{Amount: 1999, Currency: "USD"}
+	b := Money{Amount: 1, Currency: "USD"}
+
+	// add and subtract
+	if sum, err := a.Add(b); err != nil || sum.Amount != 2000 {
+		t.Errorf("19.99 + 0.01 should be 20.00, got %v %v", sum, err)
+	}
+	if diff, err := b.Sub(a); err != nil || diff.Amount != -1998 {
+		t.Errorf("0.01 - 19.99 should be -19.98, got %v %v", diff, err)
+	}
+
+	// multiply by a quantity
+	if product, err := a.Mul(3); err != nil || product.Amount != 5997 {
+		t.Errorf("19.99 * 3 should be 59.97, got %v %v", product, err)
+	}
+
+	// take a percentage, 12.5% of 19.99 is 2.49875
+	if part, err := a.Percentage(1250, HalfEven); err != nil || part.Amount != 250 {
+		t.Errorf("12.5%% of 19.99 should round to 2.50, got %v %v", part, err)
+	}
+	if part, err := a.Percentage(1250, Down); err != nil || part.Amount != 249 {
+		t.Errorf("12.5%% of 19.99 should round down to 2.49, got %v %v", part, err)
+	}
+
+	// currencies cannot be mixed
+	if _, err := a.Add(Money{Amount: 1, Currency: "EUR"}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("adding USD and EUR should fail, got %v", err)
+	}
+	if _, err := a.Cmp(Money{Amount: 1, Currency: "EUR"}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("comparing USD and EUR should fail, got %v", err)
+	}
+
+	// overflows are reported
+	if _, err := (Money{Amount: math.MaxInt64, Currency: "USD"}).Add(b); !errors.Is(err, ErrOverflow) {
+		t.Errorf("adding to the largest amount should overflow, got %v", err)
+	}
+	if _, err := (Money{Amount: math.MaxInt64, Currency: "USD"}).Mul(2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("doubling the largest amount should overflow, got %v", err)
+	}
+}
+
+// TestAllocate - test the Allocate function keeps every minor unit
+//
+//	@param t - testing.T
+func TestAllocate(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		amount int64
+		ratios []int64
+		parts  []int64
+	}{
+		{amount: 100, ratios: []int64{1, 1, 1}, parts: []int64{34, 33, 33}},
+		{amount: 5, ratios: []int64{3, 7}, parts: []int64{2, 3}},
+		{amount: -100, ratios: []int64{1, 1, 1}, parts: []int64{-34, -33, -33}},
+		{amount: 10, ratios: []int64{0, 1, 1}, parts: []int64{0, 5, 5}},
+	}
+
+	// check the parts of every allocation
+	for _, item := range slice {
+		parts, err := Money{Amount: item.amount, Currency: "USD"}.Allocate(item.ratios...)
+		if err != nil {
+			t.Errorf("allocating %v should succeed, got %v", item.amount, err)
+			continue
+		}
+		for i, part := range parts {
+			if part.Amount != item.parts[i] {
+				t.Errorf("allocating %v by %v should give %v, got %v", item.amount, item.ratios, item.parts, parts)
+				break
+			}
+		}
+	}
+}
+
+// TestFormat - test the formatting functions
+//
+//	@param t - testing.T
+func TestFormat(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		money   Money
+		decimal string
+		format  string
+	}{
+		{money: Money{Amount: 1234, Currency: "USD"}, decimal: "12.34", format: "$12.34"},
+		{money: Money{Amount: -5, Currency: "EUR"}, decimal: "-0.05", format: "-€0.05"},
+		{money: Money{Amount: 1500, Currency: "JPY"}, decimal: "1500", format: "¥1500"},
+		{money: Money{Amount: 1, Currency: "KWD"}, decimal: "0.001", format: "KD0.001"},
+		{money: Money{Amount: math.MinInt64, Currency: "USD"}, decimal: "-92233720368547758.08", format: "-$92233720368547758.08"},
+	}
+
+	// check the format of every amount
+	for _, item := range slice {
+		if item.money.Decimal() != item.decimal || item.money.Format() != item.format {
+			t.Errorf("%v should format as %q and %q, got %q and %q", item.money.Amount, item.decimal, item.format, item.money.Decimal(), item.money.Format())
+		}
+	}
+}
+
+// TestFromMajor - test the FromMajor function rounds to minor units
+//
+//	@param t - testing.T
+func TestFromMajor(t *testing.T) {
+	// 1/3 of a dollar
+	if m, err := FromMajor(big.NewRat(1, 3), "USD", HalfEven); err != nil || m.Amount != 33 {
+		t.Errorf("1/3 USD should be 33 cents, got %v %v", m, err)
+	}
+
+	// 2.5 yen ties to the even yen
+	if m, err := FromMajor(big.NewRat(5, 2), "JPY", HalfEven); err != nil || m.Amount != 2 {
+		t.Errorf("2.5 JPY should be 2 yen, got %v %v", m, err)
+	}
+}
+
+// TestJSON - test the JSON encoding of money
+//
+//	@param t - testing.T
+func TestJSON(t *testing.T) {
+	// encode an amount
+	data, err := json.Marshal(Money{Amount: 1234, Currency: "USD"})
+	if err != nil || string(data) != `{"amount":1234,"currency":"USD"}` {
+		t.Errorf("money should encode to minor units, got %s %v", data, err)
+	}
+
+	// decode an amount with a lower case currency
+	var m Money
+	if err := json.Unmarshal([]byte(`{"amount":-5,"currency":"eur"}`), &m); err != nil || m.Amount != -5 || m.Currency != "EUR" {
+		t.Errorf("money should decode, got %v %v", m, err)
+	}
+
+	// reject unknown currencies
+	if err := json.Unmarshal([]byte(`{"amount":1,"currency":"ABC"}`), &m); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("unknown currency should fail, got %v", err)
+	}
+}
+
+// TestSQL - test the SQL encoding of money
+//
+//	@param t - testing.T
+func TestSQL(t *testing.T) {
+	// encode an amount
+	value, err := Money{Amount: -1234, Currency: "GHS"}.Value()
+	if err != nil || value != "(-1234,GHS)" {
+		t.Errorf("money should encode as a composite, got %v %v", value, err)
+	}
+
+	// decode amounts
+	slice := []struct {
+		src   interface{}
+		money Money
+	}{
+		{src: "(-1234,GHS)", money: Money{Amount: -1234, Currency: "GHS"}},
+		{src: []byte(`(5,"USD")`), money: Money{Amount: 5, Currency: "USD"}},
+		{src: nil, money: Money{}},
+	}
+	for _, item := range slice {
+		var m Money
+		if err := m.Scan(item.src); err != nil || m != item.money {
+			t.Errorf("%v should decode to %v, got %v %v", item.src, item.money, m, err)
+		}
+	}
+
+	// reject invalid amounts
+	var m Money
+	if err := m.Scan("(abc,USD)"); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("invalid amount should fail, got %v", err)
+	}
+	if _, err := (Money{Amount: 1}).Value(); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("money without a currency should not be stored, got %v", err)
+	}
+}
+
+// TestConvert - test the Convert function
+//
+//	@param t - testing.T
+func TestConvert(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		money    Money
+		currency string
+		rate     string
+		amount   int64
+	}{
+		{money: Money{Amount: 1000, Currency: "USD"}, currency: "EUR", rate: "0.92", amount: 920},
+		{money: Money{Amount: 1999, Currency: "USD"}, currency: "GHS", rate: "12.3456", amount: 24679},
+		{money: Money{Amount: 1999, Currency: "USD"}, currency: "JPY", rate: "149.5", amount: 2989},
+		{money: Money{Amount: 150, Currency: "JPY"}, currency: "USD", rate: "0.0066889632", amount: 100},
+		{money: Money{Amount: 1000, Currency: "KWD"}, currency: "USD", rate: "3.25", amount: 325},
+	}
+
+	// check the amount of every conversion
+	for _, item := range slice {
+		rate, err := ParseRate(item.rate)
+		if err != nil {
+			t.Errorf("rate %q should parse, got %v", item.rate, err)
+			continue
+		}
+		m, err := item.money.Convert(item.currency, rate, HalfUp)
+		if err != nil || m.Amount != item.amount || m.Currency != item.currency {
+			t.Errorf("%v at %v should be %v %v, got %v %v", item.money, item.rate, item.amount, item.currency, m, err)
+		}
+	}
+
+	// reject invalid rates
+	for _, s := range []string{"", "0", "-1.5", "1e3", "1/3", "abc"} {
+		if _, err := ParseRate(s); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("rate %q should fail, got %v", s, err)
+		}
+	}
+}
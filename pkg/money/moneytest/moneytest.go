@@ -0,0 +1,21 @@
+// Package moneytest holds the amounts shared by the tests of the packages pricing in the catalog currency.
+package moneytest
+
+import "encore.app/pkg/money"
+
+// USD - creates an amount in dollars.
+//
+//	@param cents - int64
+//	@return money.Money
+func USD(cents int64) money.Money {
+	return money.Money{Amount: cents, Currency: "USD"}
+}
+
+// USDRef - creates a reference to an amount in dollars, for the optional amounts of promotions and alerts.
+//
+//	@param cents - int64
+//	@return *money.Money
+func USDRef(cents int64) *money.Money {
+	amount := USD(cents)
+	return &amount
+}
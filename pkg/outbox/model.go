@@ -0,0 +1,39 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// MaxAttempts - the attempts to publish a message before it is left for someone to look at
+	MaxAttempts = 20
+	// BatchSize - the most messages published by a relay run
+	BatchSize = 500
+	// Retention - how long sent messages are kept
+	Retention = 7 * 24 * time.Hour
+)
+
+// Message - an event saved with the change it describes, waiting to be published
+type Message struct {
+	Id            string     `db:"id"` // the id of the event, subscribers dedupe on it
+	Type          string     `db:"type"`
+	Version       int        `db:"version"`
+	Payload       []byte     `db:"payload"` // the event as JSON
+	OccurredAt    time.Time  `db:"occurred_at"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     string     `db:"last_error"`
+	SentAt        *time.Time `db:"sent_at"`
+	MessageId     string     `db:"message_id"` // the id given by the topic
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+// Publisher - a function that publishes a message on its topic and returns the id given by the topic
+type Publisher func(ctx context.Context, message *Message) (string, error)
+
+type RelayResponse struct {
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"` // the messages that will be tried again
+	Purged int `json:"purged"` // the sent messages removed after the retention
+}
@@ -0,0 +1,159 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/rlog"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/events"
+	"encore.app/pkg/retry"
+)
+
+// Add - Add saves an event to the outbox. It is given the transaction of the change the event describes,
+// so the event is only published when the change is committed.
+//
+//	@param ctx - context.Context
+//	@param db - the transaction of the change
+//	@param meta - the metadata of the event
+//	@param event - the event
+//	@return error
+func Add(ctx context.Context, db sqlx.ExtContext, meta events.Meta, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO outbox (id, type, version, payload, occurred_at, next_attempt_at, created_at)
+    VALUES (:id, :type, :version, :payload, :occurred_at, :occurred_at, :occurred_at)
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, db, q, &Message{
+		Id:         meta.Id,
+		Type:       meta.Type,
+		Version:    meta.Version,
+		Payload:    payload,
+		OccurredAt: meta.OccurredAt,
+	}); err != nil {
+		return fmt.Errorf("inserting outbox message: %w", err)
+	}
+
+	return nil
+}
+
+// Relay - Relay publishes the messages of the outbox in the order they occurred. A message that fails is
+// tried again later, waiting longer after every attempt. Messages are locked while they are published so
+// concurrent relays skip them; a message can still be published twice when the relay stops before it is
+// marked sent, which subscribers handle by deduplicating on the event id.
+//
+//	@param ctx - context.Context
+//	@param db - the database of the service
+//	@param publish - the function publishing a message
+//	@param now - time.Time
+//	@return response
+//	@return error
+func Relay(ctx context.Context, db *sqlx.DB, publish Publisher, now time.Time) (*RelayResponse, error) {
+	response := &RelayResponse{}
+
+	if err := database.Transaction(ctx, db, func(tx *sqlx.Tx) error {
+		// lock the messages due
+		messages := make([]Message, 0)
+		if err := database.NamedSliceQuery(ctx, tx, `
+      SELECT * FROM outbox
+      WHERE sent_at IS NULL AND next_attempt_at <= :now AND attempts < :max_attempts
+      ORDER BY occurred_at, id
+      LIMIT :limit
+      FOR UPDATE SKIP LOCKED
+    `, map[string]interface{}{"now": now.UTC(), "max_attempts": MaxAttempts, "limit": BatchSize}, &messages); err != nil {
+			return fmt.Errorf("selecting outbox messages: %w", err)
+		}
+
+		// publish them
+		for i := range messages {
+			message := &messages[i]
+			messageId, err := publish(ctx, message)
+			if err != nil {
+				message.Attempts++
+				message.NextAttemptAt = now.UTC().Add(retry.Backoff(time.Second, time.Hour, message.Attempts))
+				message.LastError = err.Error()
+				response.Failed++
+				rlog.Warn("outbox.Relay", "id", message.Id, "type", message.Type, "attempts", message.Attempts, "error", err)
+			} else {
+				sentAt := now.UTC()
+				message.SentAt = &sentAt
+				message.MessageId = messageId
+				response.Sent++
+			}
+
+			if err := database.NamedExecQuery(ctx, tx, `
+        UPDATE outbox SET attempts = :attempts, next_attempt_at = :next_attempt_at, last_error = :last_error,
+          sent_at = :sent_at, message_id = :message_id
+        WHERE id = :id
+      `, message); err != nil {
+				return fmt.Errorf("updating outbox message: %w", err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// remove the messages sent long ago
+	purged, err := database.NamedCountQuery(ctx, db, `
+    WITH purged AS (DELETE FROM outbox WHERE sent_at < :before RETURNING id)
+    SELECT COUNT(*) FROM purged
+  `, map[string]interface{}{"before": now.UTC().Add(-Retention)})
+	if err != nil {
+		return nil, fmt.Errorf("purging outbox messages: %w", err)
+	}
+	response.Purged = purged
+
+	return response, nil
+}
+
+// Claim - Claim records that a subscription handled an event and reports whether it is the first time.
+// It is given the transaction of the change the handler makes, so a handler that fails can claim the
+// event again when it is delivered again.
+//
+//	@param ctx - context.Context
+//	@param db - the transaction of the handler
+//	@param subscription - the name of the subscription
+//	@param eventId - the id of the event
+//	@return bool - true the first time the event is claimed
+//	@return error
+func Claim(ctx context.Context, db sqlx.ExtContext, subscription, eventId string) (bool, error) {
+	var claimed struct {
+		EventId string `db:"event_id"`
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO processed_events (subscription, event_id, processed_at)
+    VALUES (:subscription, :event_id, :processed_at)
+    ON CONFLICT (subscription, event_id) DO NOTHING
+    RETURNING event_id
+  `
+
+	// execute query, nothing is returned when the event was claimed before
+	if err := database.NamedStructQuery(ctx, db, q, map[string]interface{}{
+		"subscription": subscription,
+		"event_id":     eventId,
+		"processed_at": time.Now().UTC(),
+	}, &claimed); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claiming event: %w", err)
+	}
+
+	return true, nil
+}
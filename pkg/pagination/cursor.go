@@ -0,0 +1,275 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"encore.app/pkg/condition"
+)
+
+// cursor - the position of an item in a sorted list
+type cursor struct {
+	Sort     string   `json:"s"` // the sort the cursor was created for
+	Values   []string `json:"v"` // the values of the sort columns and the key
+	Backward bool     `json:"b"` // whether the cursor points to the items before it
+}
+
+// Window - the cursors around a page of items
+type Window struct {
+	HasPrevious bool
+	HasNext     bool
+	PrevCursor  string
+	NextCursor  string
+}
+
+// keyColumns - returns the sort columns followed by the key.
+//
+//	@return []Field
+func (st *Statement) keyColumns() []Field {
+	fields := make([]Field, 0, len(st.Sorts)+1)
+	keySorted := false
+
+	for _, sort := range st.Sorts {
+		fields = append(fields, sort.Field)
+		keySorted = keySorted || sort.Field.Column == st.Key
+	}
+
+	// the key makes the position unique
+	if !keySorted {
+		fields = append(fields, Field{Name: st.Key, Column: st.Key, Type: String, Sortable: true})
+	}
+
+	return fields
+}
+
+// signature - returns a description of the sort so cursors cannot be reused with another sort.
+//
+//	@return string
+func (st *Statement) signature() string {
+	parts := make([]string, 0, len(st.Sorts)+1)
+	for _, field := range st.keyColumns() {
+		parts = append(parts, field.Column+condition.Ternary(st.descending(field.Column), " DESC", " ASC"))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// descending - returns whether the column is sorted in descending order.
+//
+//	@param column - string
+//	@return bool
+func (st *Statement) descending(column string) bool {
+	for _, sort := range st.Sorts {
+		if sort.Field.Column == column {
+			return sort.Descending
+		}
+	}
+
+	return false
+}
+
+// ApplyCursor - limits the statement to the items after (or before) the cursor.
+// The condition is added to the statement, so counts should be queried before applying the cursor.
+//
+//	@param encoded - string
+//	@return error
+func (st *Statement) ApplyCursor(encoded string) error {
+	// decode the cursor
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: cannot be decoded", ErrInvalidCursor)
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return fmt.Errorf("%w: cannot be decoded", ErrInvalidCursor)
+	}
+
+	// the cursor must belong to the same sort
+	fields := st.keyColumns()
+	if c.Sort != st.signature() || len(c.Values) != len(fields) {
+		return fmt.Errorf("%w: the sort has changed", ErrInvalidCursor)
+	}
+
+	// bind the values of the cursor
+	names := make([]string, 0, len(fields))
+	for i, field := range fields {
+		value, err := convert(field, "=", c.Values[i])
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		name := fmt.Sprintf("cursor_%v", i)
+		st.Args[name] = value
+		names = append(names, ":"+name)
+	}
+
+	// items after the cursor are greater in ascending columns and smaller in descending columns,
+	// e.g. (a > :a) OR (a = :a AND id > :id)
+	alternatives := make([]string, 0, len(fields))
+	for i, field := range fields {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%v = %v", fields[j].Column, names[j]))
+		}
+
+		op := ">"
+		if st.descending(field.Column) != c.Backward {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%v %v %v", field.Column, op, names[i]))
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	st.Conditions = append(st.Conditions, "("+strings.Join(alternatives, " OR ")+")")
+	st.cursor = &c
+
+	return nil
+}
+
+// Window - trims the extra item fetched to detect more items, restores the order of a backward page
+// and returns the cursors of the previous and next pages.
+// Queries should fetch one item more than the limit.
+//
+//	@param items - pointer to the slice of items
+//	@param limit - int
+//	@return *Window
+//	@return error
+func (st *Statement) Window(items interface{}, limit int) (*Window, error) {
+	// get value of the slice
+	val := reflect.ValueOf(items)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("must provide a pointer to a slice")
+	}
+	list := val.Elem()
+
+	// trim the extra item
+	more := list.Len() > limit
+	if more {
+		list.Set(list.Slice(0, limit))
+	}
+
+	window := &Window{}
+	backward := st.cursor != nil && st.cursor.Backward
+
+	// backward pages are fetched in reverse order
+	if backward {
+		for i, j := 0, list.Len()-1; i < j; i, j = i+1, j-1 {
+			a, b := list.Index(i).Interface(), list.Index(j).Interface()
+			list.Index(i).Set(reflect.ValueOf(b))
+			list.Index(j).Set(reflect.ValueOf(a))
+		}
+		window.HasPrevious = more
+		window.HasNext = true
+	} else {
+		window.HasPrevious = st.cursor != nil || st.Offset > 0
+		window.HasNext = more
+	}
+
+	// no cursors can be created without items
+	if list.Len() < 1 {
+		return window, nil
+	}
+
+	// create the cursors from the first and last items
+	if window.HasPrevious {
+		c, err := st.encode(list.Index(0), true)
+		if err != nil {
+			return nil, err
+		}
+		window.PrevCursor = c
+	}
+	if window.HasNext {
+		c, err := st.encode(list.Index(list.Len()-1), false)
+		if err != nil {
+			return nil, err
+		}
+		window.NextCursor = c
+	}
+
+	return window, nil
+}
+
+// encode - creates the cursor of an item.
+//
+//	@param item - reflect.Value
+//	@param backward - bool
+//	@return string
+//	@return error
+func (st *Statement) encode(item reflect.Value, backward bool) (string, error) {
+	fields := st.keyColumns()
+	c := cursor{Sort: st.signature(), Values: make([]string, 0, len(fields)), Backward: backward}
+
+	// read the value of every sort column
+	for _, field := range fields {
+		value, ok := columnValue(item, field.Column)
+		if !ok {
+			return "", fmt.Errorf("creating cursor: column %v is not selected", field.Column)
+		}
+		c.Values = append(c.Values, value)
+	}
+
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("creating cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// columnValue - returns the value of the struct field tagged with the column, formatted for a cursor.
+//
+//	@param item - reflect.Value
+//	@param column - string
+//	@return string
+//	@return bool
+func columnValue(item reflect.Value, column string) (string, bool) {
+	for item.Kind() == reflect.Ptr {
+		item = item.Elem()
+	}
+	if item.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	// loop through the fields, including embedded structs
+	for i := 0; i < item.NumField(); i++ {
+		field := item.Type().Field(i)
+		value := item.Field(i)
+
+		if field.Anonymous {
+			if v, ok := columnValue(value, column); ok {
+				return v, true
+			}
+			continue
+		}
+
+		if strings.Split(field.Tag.Get("db"), ",")[0] != column {
+			continue
+		}
+
+		// read through pointers, e.g. dates that can be empty
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return "", true
+			}
+			value = value.Elem()
+		}
+
+		switch v := value.Interface().(type) {
+		case time.Time:
+			return v.UTC().Format(time.RFC3339Nano), true
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), true
+		case float32:
+			return strconv.FormatFloat(float64(v), 'g', -1, 32), true
+		default:
+			return fmt.Sprintf("%v", v), true
+		}
+	}
+
+	return "", false
+}
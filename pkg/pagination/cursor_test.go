@@ -0,0 +1,119 @@
+package pagination
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testItem - the item used by the cursor tests
+type testItem struct {
+	Id        string    `db:"id"`
+	Name      string    `db:"name"`
+	Price     float64   `db:"price"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// TestWindow - test the Window function creates cursors that continue the list
+//
+//	@param t - testing.T
+func TestWindow(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+	items := []testItem{
+		{Id: "7a5b0f4e-8c68-4a39-9a4f-1b2f1c3d4e5f", Name: "a", Price: 1.5, CreatedAt: created},
+		{Id: "8a5b0f4e-8c68-4a39-9a4f-1b2f1c3d4e5f", Name: "b", Price: 2.25, CreatedAt: created},
+		{Id: "9a5b0f4e-8c68-4a39-9a4f-1b2f1c3d4e5f", Name: "c", Price: 3, CreatedAt: created},
+	}
+
+	// parse a statement and window the first page
+	statement, err := testSchema.Parse(Query{Sort: "-price"})
+	if err != nil {
+		t.Fatalf("query should parse, got %v", err)
+	}
+	page := append([]testItem{}, items...)
+	window, err := statement.Window(&page, 2)
+	if err != nil {
+		t.Fatalf("window should be created, got %v", err)
+	}
+
+	// check the extra item was trimmed and only the next cursor was created
+	if len(page) != 2 || !window.HasNext || window.HasPrevious || window.PrevCursor != "" || window.NextCursor == "" {
+		t.Fatalf("first page should have 2 items and a next cursor, got %v %+v", len(page), window)
+	}
+
+	// continue from the next cursor
+	next, _ := testSchema.Parse(Query{Sort: "-price"})
+	if err := next.ApplyCursor(window.NextCursor); err != nil {
+		t.Fatalf("cursor should apply, got %v", err)
+	}
+	where := "WHERE ((price < :cursor_0) OR (price = :cursor_0 AND id > :cursor_1))"
+	if next.Where() != where {
+		t.Errorf("next page should have where %q, got %q", where, next.Where())
+	}
+	if next.Args["cursor_0"] != 2.25 || next.Args["cursor_1"] != items[1].Id {
+		t.Errorf("next page should continue after the second item, got %v", next.Args)
+	}
+
+	// go back from a backward cursor
+	previous, _ := testSchema.Parse(Query{Sort: "-price"})
+	backward, err := previous.encode(valueOf(items[2]), true)
+	if err != nil {
+		t.Fatalf("cursor should be encoded, got %v", err)
+	}
+	if err := previous.ApplyCursor(backward); err != nil {
+		t.Fatalf("cursor should apply, got %v", err)
+	}
+	if previous.OrderBy() != "ORDER BY price ASC, id DESC" {
+		t.Errorf("backward page should be read in reverse, got %q", previous.OrderBy())
+	}
+
+	// the backward page is returned in the original order
+	page = []testItem{items[1], items[0]}
+	window, err = previous.Window(&page, 2)
+	if err != nil {
+		t.Fatalf("window should be created, got %v", err)
+	}
+	if page[0].Name != "a" || window.HasPrevious || !window.HasNext {
+		t.Errorf("backward page should be reversed with only a next cursor, got %v %+v", page, window)
+	}
+}
+
+// TestApplyCursorErrors - test the ApplyCursor function rejects invalid cursors
+//
+//	@param t - testing.T
+func TestApplyCursorErrors(t *testing.T) {
+	// create a cursor for one sort
+	statement, _ := testSchema.Parse(Query{Sort: "name"})
+	c, err := statement.encode(valueOf(testItem{Id: "7a5b0f4e-8c68-4a39-9a4f-1b2f1c3d4e5f", Name: "a"}), false)
+	if err != nil {
+		t.Fatalf("cursor should be encoded, got %v", err)
+	}
+
+	// create a slice
+	slice := []struct {
+		sort   string
+		cursor string
+	}{
+		{sort: "name", cursor: "not a cursor"},
+		{sort: "name", cursor: "e30"},
+		{sort: "-name", cursor: c},
+		{sort: "price", cursor: c},
+	}
+
+	// check the error of every cursor
+	for _, item := range slice {
+		statement, _ := testSchema.Parse(Query{Sort: item.sort})
+		if err := statement.ApplyCursor(item.cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q should fail for sort %v, got %v", item.cursor, item.sort, err)
+		}
+	}
+}
+
+// valueOf - returns the reflect value of an item
+//
+//	@param item - testItem
+//	@return reflect.Value
+func valueOf(item testItem) reflect.Value {
+	return reflect.ValueOf(item)
+}
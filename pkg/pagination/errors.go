@@ -0,0 +1,26 @@
+package pagination
+
+import "errors"
+
+// Set of error variables for parsing list queries.
+var (
+	ErrUnknownField    = errors.New("unknown field")
+	ErrInvalidFilter   = errors.New("invalid filter")
+	ErrInvalidOperator = errors.New("invalid operator")
+	ErrInvalidValue    = errors.New("invalid value")
+	ErrInvalidSort     = errors.New("invalid sort")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+)
+
+// IsQueryError - returns true if the error was caused by an invalid list query.
+//
+//	@param err - error
+//	@return bool
+func IsQueryError(err error) bool {
+	return errors.Is(err, ErrUnknownField) ||
+		errors.Is(err, ErrInvalidFilter) ||
+		errors.Is(err, ErrInvalidOperator) ||
+		errors.Is(err, ErrInvalidValue) ||
+		errors.Is(err, ErrInvalidSort) ||
+		errors.Is(err, ErrInvalidCursor)
+}
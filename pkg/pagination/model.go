@@ -10,17 +10,90 @@ type PaginatedResults struct {
 	HasNextPage     bool          `json:"hasNextPage" db:"hasNextPage"`
 }
 
-// Query - query
+// Query - the filter, sort and field selection of a list
+//
+//	filter - comma separated conditions, e.g. price>=2,name~milk,brand=a|b
+//	sort   - comma separated fields, prefixed with "-" for descending, e.g. -createdAt,name
+//	fields - comma separated fields to be selected, e.g. id,name
 type Query struct {
-	Where  string `json:"where" db:"where"`
-	Tasks  string `json:"tasks" db:"tasks"`
+	Filter string `json:"filter" db:"filter"` // the conditions to be matched
+	Sort   string `json:"sort" db:"sort"`     // the order of the items
 	Fields string `json:"fields" db:"fields"` // the fields to be selected
 }
 
 // pagination options
 type Options struct {
-	Limit int `json:"limit" db:"limit" url:"limit"` // the number of items
-	Page  int `json:"page" db:"page" url:"limit"`   // the page
+	Limit  int    `json:"limit" db:"limit" url:"limit" query:"limit"`     // the number of items
+	Page   int    `json:"page" db:"page" url:"page" query:"page"`         // the page
+	Filter string `json:"filter" db:"filter" url:"filter" query:"filter"` // the conditions to be matched
+	Sort   string `json:"sort" db:"sort" url:"sort" query:"sort"`         // the order of the items
+	Fields string `json:"fields" db:"fields" url:"fields" query:"fields"` // the fields to be selected
+	Cursor string `json:"cursor" db:"cursor" url:"cursor" query:"cursor"` // the position to continue from, instead of the page
+	// SkipTotal - skips counting the items, for large lists paged with cursors
+	SkipTotal bool `json:"skipTotal" db:"skipTotal" url:"skipTotal" query:"skipTotal"`
+}
+
+// Query - returns the filter, sort and field selection of the options.
+//
+//	@return Query
+func (o *Options) Query() Query {
+	return Query{Filter: o.Filter, Sort: o.Sort, Fields: o.Fields}
+}
+
+// FieldType - the type of the values of a field
+type FieldType int
+
+const (
+	String FieldType = iota
+	Number
+	Bool
+	Time
+	UUID
+	Opaque // can only be selected, not filtered
+)
+
+// Field - a field of a resource that can be filtered, sorted or selected
+type Field struct {
+	Name     string    // the name used in queries, e.g. createdAt
+	Column   string    // the database column, e.g. created_at
+	Type     FieldType // the type of the values
+	Sortable bool      // whether the field can be sorted on
+}
+
+// Schema - the fields of a resource allowed in list queries
+type Schema struct {
+	Fields      []Field // the allowed fields
+	Key         string  // the unique column appended to every sort so the order is stable
+	DefaultSort string  // the sort used when none is given
+}
+
+// Sort - a field to be sorted on
+type Sort struct {
+	Field      Field
+	Descending bool
+}
+
+// Statement - a parsed list query that can be added to an SQL query
+type Statement struct {
+	Conditions []string               // the conditions to be matched
+	Sorts      []Sort                 // the order of the items
+	Columns    []string               // the columns to be selected
+	Names      []string               // the fields selected, to be kept in the response
+	Args       map[string]interface{} // the named arguments of the conditions
+	Key        string                 // the unique column appended to the order
+	Offset     int                    // the offset of a page, when paging by number
+	cursor     *cursor
+}
+
+// PageInfo - where a page is in a list, with the cursors of the pages around it
+type PageInfo struct {
+	Total           int    // the items matched, 0 when the total is skipped
+	TotalPages      int    // the pages, 0 when the total is skipped
+	CurrentPage     int    // the page, 0 when paging with cursors
+	HasPreviousPage bool   // whether items come before the page
+	HasNextPage     bool   // whether items come after the page
+	PrevCursor      string // the cursor of the previous page
+	NextCursor      string // the cursor of the next page
 }
 
 // PaginationResponse - pagination
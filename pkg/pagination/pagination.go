@@ -4,6 +4,12 @@ import (
 	"math"
 )
 
+// MaxLimit is the largest number of items in a page.
+const MaxLimit = 100
+
+// DefaultLimit is the number of items in a page when none is given.
+const DefaultLimit = 20
+
 // New creates a new pagination.
 // @param page is the current page.
 // @param perPage is the number of items per page.
@@ -85,6 +91,26 @@ func New(page, perPage, total int) *Pagination {
 	return p
 }
 
+// Offset returns the offset of a page without creating a pagination.
+// The page is limited to the total number of pages when it is known (greater than 0).
+// @param page is the current page.
+// @param perPage is the number of items per page.
+// @param pages is the total number of pages, or 0 when it is not known.
+// @return int
+func Offset(page, perPage, pages int) int {
+	// If the page is greater than the pages, use the last page.
+	if pages > 0 && page > pages {
+		page = pages
+	}
+
+	// If the page is less than 2, start from the first item.
+	if page < 2 {
+		return 0
+	}
+
+	return (page - 1) * perPage
+}
+
 // Page returns the page.
 // @return int
 func (p *Pagination) Page() int {
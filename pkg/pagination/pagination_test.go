@@ -0,0 +1,29 @@
+package pagination
+
+import "testing"
+
+// TestOffset - test the Offset function
+//
+//	@param t - testing.T
+func TestOffset(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		page    int
+		perPage int
+		pages   int
+		offset  int
+	}{
+		{page: 0, perPage: 10, pages: 3, offset: 0},
+		{page: 1, perPage: 10, pages: 3, offset: 0},
+		{page: 3, perPage: 10, pages: 3, offset: 20},
+		{page: 9, perPage: 10, pages: 3, offset: 20},
+		{page: 9, perPage: 10, pages: 0, offset: 80},
+	}
+
+	// check the offset of every page
+	for _, item := range slice {
+		if offset := Offset(item.page, item.perPage, item.pages); offset != item.offset {
+			t.Errorf("page %v of %v should have offset %v, got %v", item.page, item.pages, item.offset, offset)
+		}
+	}
+}
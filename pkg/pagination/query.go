@@ -0,0 +1,396 @@
+package pagination
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"encore.app/pkg/condition"
+	"encore.app/pkg/search"
+	"encore.app/pkg/slice"
+)
+
+// operators - the filter operators, longest first so ">=" is matched before ">"
+var operators = []string{">=", "<=", "!=", "=", ">", "<", "~"}
+
+// operatorsByType - the filter operators allowed for each type of field
+var operatorsByType = map[FieldType][]string{
+	String: {"=", "!=", "~"},
+	Number: {"=", "!=", ">", ">=", "<", "<="},
+	Bool:   {"=", "!="},
+	Time:   {"=", "!=", ">", ">=", "<", "<="},
+	UUID:   {"=", "!="},
+}
+
+// Field - returns the field with the name.
+//
+//	@param name - string
+//	@return Field
+//	@return bool
+func (s *Schema) Field(name string) (Field, bool) {
+	for _, field := range s.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+
+	return Field{}, false
+}
+
+// Parse - parses the filter, sort and field selection of a list query.
+// Only fields of the schema are accepted and every value is bound as a named argument,
+// so the statement is safe to add to an SQL query.
+//
+//	@param q - Query
+//	@return *Statement
+//	@return error
+func (s *Schema) Parse(q Query) (*Statement, error) {
+	statement := &Statement{Args: map[string]interface{}{}, Key: s.Key}
+
+	// parse the filter
+	if err := s.parseFilter(statement, q.Filter); err != nil {
+		return nil, err
+	}
+
+	// parse the sort
+	if err := s.parseSort(statement, q.Sort); err != nil {
+		return nil, err
+	}
+
+	// parse the field selection
+	if err := s.parseFields(statement, q.Fields); err != nil {
+		return nil, err
+	}
+
+	return statement, nil
+}
+
+// parseFilter - parses the comma separated conditions of a filter.
+//
+//	@param statement - *Statement
+//	@param filter - string
+//	@return error
+func (s *Schema) parseFilter(statement *Statement, filter string) error {
+	for _, part := range strings.Split(filter, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) < 1 {
+			continue
+		}
+
+		// find the start of the operator
+		i := strings.IndexAny(part, "<>=!~")
+		if i < 1 {
+			return fmt.Errorf("%w: %v", ErrInvalidFilter, part)
+		}
+
+		// find the field
+		name := strings.TrimSpace(part[:i])
+		field, ok := s.Field(name)
+		if !ok {
+			return fmt.Errorf("%w: %v", ErrUnknownField, name)
+		}
+
+		// find the operator
+		op := ""
+		for _, o := range operators {
+			if strings.HasPrefix(part[i:], o) {
+				op = o
+				break
+			}
+		}
+		if len(op) < 1 || !slice.Contains(operatorsByType[field.Type], op) {
+			return fmt.Errorf("%w: %v on %v", ErrInvalidOperator, part[i:], name)
+		}
+
+		// the values to be matched, "|" separates alternatives
+		raw := strings.Split(strings.TrimSpace(part[i+len(op):]), "|")
+		if len(raw) > 1 && op != "=" && op != "!=" {
+			return fmt.Errorf("%w: %v only accepts one value", ErrInvalidOperator, op)
+		}
+
+		// convert the values to the type of the field
+		values := make([]interface{}, 0, len(raw))
+		for _, r := range raw {
+			value, err := convert(field, op, strings.TrimSpace(r))
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+
+		statement.Conditions = append(statement.Conditions, filterCondition(statement, field, op, values))
+	}
+
+	return nil
+}
+
+// filterCondition - creates the SQL condition of a filter and binds its values.
+//
+//	@param statement - *Statement
+//	@param field - Field
+//	@param op - string
+//	@param values - []interface{}
+//	@return string
+func filterCondition(statement *Statement, field Field, op string, values []interface{}) string {
+	names := make([]string, 0, len(values))
+
+	// bind every value to a unique name, uuids cast to the type of the column so its index can be used
+	for _, value := range values {
+		name := fmt.Sprintf("filter_%v", len(statement.Args))
+		statement.Args[name] = value
+		names = append(names, condition.Ternary(field.Type == UUID, "CAST(:"+name+" AS UUID)", ":"+name))
+	}
+
+	// match any of the values
+	if len(names) > 1 {
+		in := fmt.Sprintf("%v IN (%v)", field.Column, strings.Join(names, ", "))
+		if op == "!=" {
+			return "NOT " + in
+		}
+		return in
+	}
+
+	// match part of the value
+	if op == "~" {
+		return fmt.Sprintf("%v ILIKE %v", field.Column, names[0])
+	}
+
+	return fmt.Sprintf("%v %v %v", field.Column, op, names[0])
+}
+
+// convert - converts a filter value to the type of the field.
+//
+//	@param field - Field
+//	@param op - string
+//	@param raw - string
+//	@return interface{}
+//	@return error
+func convert(field Field, op, raw string) (interface{}, error) {
+	switch field.Type {
+	case Number:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v must be a number", ErrInvalidValue, field.Name)
+		}
+		return value, nil
+	case Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v must be true or false", ErrInvalidValue, field.Name)
+		}
+		return value, nil
+	case Time:
+		// accept a full timestamp or a date
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value.UTC(), nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %v must be a date or an RFC3339 time", ErrInvalidValue, field.Name)
+	case UUID:
+		value, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v must be a uuid", ErrInvalidValue, field.Name)
+		}
+		return value.String(), nil
+	default:
+		if op == "~" {
+			return search.LikeContains(raw), nil
+		}
+		return raw, nil
+	}
+}
+
+// parseSort - parses the comma separated fields of a sort.
+//
+//	@param statement - *Statement
+//	@param sort - string
+//	@return error
+func (s *Schema) parseSort(statement *Statement, sort string) error {
+	// use the default sort when none is given
+	if len(strings.TrimSpace(sort)) < 1 {
+		sort = s.DefaultSort
+	}
+
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) < 1 {
+			continue
+		}
+
+		// a "-" prefix sorts in descending order
+		descending := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+
+		// find the field
+		field, ok := s.Field(name)
+		if !ok {
+			return fmt.Errorf("%w: %v", ErrUnknownField, name)
+		}
+		if !field.Sortable {
+			return fmt.Errorf("%w: %v cannot be sorted", ErrInvalidSort, name)
+		}
+
+		statement.Sorts = append(statement.Sorts, Sort{Field: field, Descending: descending})
+	}
+
+	return nil
+}
+
+// parseFields - parses the comma separated fields to be selected.
+//
+//	@param statement - *Statement
+//	@param fields - string
+//	@return error
+func (s *Schema) parseFields(statement *Statement, fields string) error {
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) < 1 {
+			continue
+		}
+
+		// find the field
+		field, ok := s.Field(name)
+		if !ok {
+			return fmt.Errorf("%w: %v", ErrUnknownField, name)
+		}
+
+		if !slice.Contains(statement.Columns, field.Column) {
+			statement.Columns = append(statement.Columns, field.Column)
+			statement.Names = append(statement.Names, field.Name)
+		}
+	}
+
+	// always select the key so the items can be identified
+	if len(statement.Columns) > 0 && len(s.Key) > 0 && !slice.Contains(statement.Columns, s.Key) {
+		statement.Columns = append([]string{s.Key}, statement.Columns...)
+		for _, field := range s.Fields {
+			if field.Column == s.Key {
+				statement.Names = append([]string{field.Name}, statement.Names...)
+			}
+		}
+	}
+
+	// always select the sort columns so cursors can be created
+	for _, sort := range statement.Sorts {
+		if len(statement.Columns) > 0 && !slice.Contains(statement.Columns, sort.Field.Column) {
+			statement.Columns = append(statement.Columns, sort.Field.Column)
+		}
+	}
+
+	return nil
+}
+
+// AddCondition - adds a condition and its named arguments to the statement.
+//
+//	@param condition - string
+//	@param args - map[string]interface{}
+func (st *Statement) AddCondition(condition string, args map[string]interface{}) {
+	st.Conditions = append(st.Conditions, condition)
+	for k, v := range args {
+		st.Args[k] = v
+	}
+}
+
+// Where - returns the WHERE clause of the statement, or an empty string without conditions.
+//
+//	@return string
+func (st *Statement) Where() string {
+	if len(st.Conditions) < 1 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(st.Conditions, " AND ")
+}
+
+// OrderBy - returns the ORDER BY clause of the statement, ending with the key so the order is stable.
+//
+//	@return string
+func (st *Statement) OrderBy() string {
+	orders := make([]string, 0, len(st.Sorts)+1)
+	keySorted := false
+
+	// backward cursors read the items in reverse order
+	backward := st.cursor != nil && st.cursor.Backward
+
+	for _, sort := range st.Sorts {
+		direction := "ASC"
+		if sort.Descending != backward {
+			direction = "DESC"
+		}
+		orders = append(orders, fmt.Sprintf("%v %v", sort.Field.Column, direction))
+		keySorted = keySorted || sort.Field.Column == st.Key
+	}
+
+	// sort on the key last
+	if len(st.Key) > 0 && !keySorted {
+		orders = append(orders, st.Key+condition.Ternary(backward, " DESC", " ASC"))
+	}
+
+	if len(orders) < 1 {
+		return ""
+	}
+
+	return "ORDER BY " + strings.Join(orders, ", ")
+}
+
+// Select - returns the columns to be selected, or "*" when no fields were selected.
+//
+//	@return string
+func (st *Statement) Select() string {
+	if len(st.Columns) < 1 {
+		return "*"
+	}
+
+	return strings.Join(st.Columns, ", ")
+}
+
+// Project - returns the items with only the selected fields, or with every field when none were selected.
+// Sort columns selected only to create cursors are left out.
+//
+//	@param items - slice of items
+//	@return []json.RawMessage
+//	@return error
+func (st *Statement) Project(items interface{}) ([]json.RawMessage, error) {
+	// get value of the slice
+	val := reflect.ValueOf(items)
+	for val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("must provide a slice")
+	}
+
+	projected := make([]json.RawMessage, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		raw, err := json.Marshal(val.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("projecting item: %w", err)
+		}
+		if len(st.Names) < 1 {
+			projected = append(projected, raw)
+			continue
+		}
+
+		// keep the selected keys, in the order they were selected
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("projecting item: %w", err)
+		}
+		kept := make([]string, 0, len(st.Names))
+		for _, name := range st.Names {
+			if value, ok := fields[name]; ok {
+				key, _ := json.Marshal(name)
+				kept = append(kept, string(key)+":"+string(value))
+			}
+		}
+		projected = append(projected, json.RawMessage("{"+strings.Join(kept, ",")+"}"))
+	}
+
+	return projected, nil
+}
@@ -0,0 +1,160 @@
+package pagination
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testSchema - the schema used by the query tests
+var testSchema = &Schema{
+	Fields: []Field{
+		{Name: "id", Column: "id", Type: UUID, Sortable: true},
+		{Name: "name", Column: "name", Type: String, Sortable: true},
+		{Name: "price", Column: "price", Type: Number, Sortable: true},
+		{Name: "active", Column: "active", Type: Bool},
+		{Name: "createdAt", Column: "created_at", Type: Time, Sortable: true},
+		{Name: "tags", Column: "tags", Type: Opaque},
+	},
+	Key:         "id",
+	DefaultSort: "name",
+}
+
+// TestParse - test the Parse function
+//
+//	@param t - testing.T
+func TestParse(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		query   Query
+		where   string
+		orderBy string
+		columns string
+		args    map[string]interface{}
+	}{
+		{
+			query:   Query{},
+			where:   "",
+			orderBy: "ORDER BY name ASC, id ASC",
+			columns: "*",
+			args:    map[string]interface{}{},
+		},
+		{
+			query:   Query{Filter: "price>=2, name~50%", Sort: "-createdAt,name", Fields: "name,price"},
+			where:   "WHERE price >= :filter_0 AND name ILIKE :filter_1",
+			orderBy: "ORDER BY created_at DESC, name ASC, id ASC",
+			columns: "id, name, price, created_at",
+			args:    map[string]interface{}{"filter_0": 2.0, "filter_1": `%50\%%`},
+		},
+		{
+			query:   Query{Filter: "name!=a|b,active=true", Sort: "-id"},
+			where:   "WHERE NOT name IN (:filter_0, :filter_1) AND active = :filter_2",
+			orderBy: "ORDER BY id DESC",
+			columns: "*",
+			args:    map[string]interface{}{"filter_0": "a", "filter_1": "b", "filter_2": true},
+		},
+		{
+			query:   Query{Filter: "id=8d5b6a1e-6f1b-4c3e-9d2a-1f0e3c4b5a69,id!=00000000-0000-0000-0000-000000000001|00000000-0000-0000-0000-000000000002"},
+			where:   "WHERE id = CAST(:filter_0 AS UUID) AND NOT id IN (CAST(:filter_1 AS UUID), CAST(:filter_2 AS UUID))",
+			orderBy: "ORDER BY name ASC, id ASC",
+			columns: "*",
+			args: map[string]interface{}{
+				"filter_0": "8d5b6a1e-6f1b-4c3e-9d2a-1f0e3c4b5a69",
+				"filter_1": "00000000-0000-0000-0000-000000000001",
+				"filter_2": "00000000-0000-0000-0000-000000000002",
+			},
+		},
+		{
+			query:   Query{Filter: "createdAt<2024-01-02"},
+			where:   "WHERE created_at < :filter_0",
+			orderBy: "ORDER BY name ASC, id ASC",
+			columns: "*",
+			args:    map[string]interface{}{"filter_0": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	// check the statement of every query
+	for _, item := range slice {
+		statement, err := testSchema.Parse(item.query)
+		if err != nil {
+			t.Errorf("query %+v should parse, got %v", item.query, err)
+			continue
+		}
+
+		if statement.Where() != item.where {
+			t.Errorf("query %+v should have where %q, got %q", item.query, item.where, statement.Where())
+		}
+		if statement.OrderBy() != item.orderBy {
+			t.Errorf("query %+v should have order %q, got %q", item.query, item.orderBy, statement.OrderBy())
+		}
+		if statement.Select() != item.columns {
+			t.Errorf("query %+v should select %q, got %q", item.query, item.columns, statement.Select())
+		}
+		if !reflect.DeepEqual(statement.Args, item.args) {
+			t.Errorf("query %+v should have args %v, got %v", item.query, item.args, statement.Args)
+		}
+	}
+}
+
+// TestParseErrors - test the Parse function rejects invalid queries
+//
+//	@param t - testing.T
+func TestParseErrors(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		query Query
+		err   error
+	}{
+		{query: Query{Filter: "password=secret"}, err: ErrUnknownField},
+		{query: Query{Filter: "price"}, err: ErrInvalidFilter},
+		{query: Query{Filter: "=2"}, err: ErrInvalidFilter},
+		{query: Query{Filter: "price~2"}, err: ErrInvalidOperator},
+		{query: Query{Filter: "name>a"}, err: ErrInvalidOperator},
+		{query: Query{Filter: "price>1|2"}, err: ErrInvalidOperator},
+		{query: Query{Filter: "price=cheap"}, err: ErrInvalidValue},
+		{query: Query{Filter: "id=1; DROP TABLE users"}, err: ErrInvalidValue},
+		{query: Query{Filter: "createdAt>yesterday"}, err: ErrInvalidValue},
+		{query: Query{Filter: "tags=a"}, err: ErrInvalidOperator},
+		{query: Query{Sort: "-password"}, err: ErrUnknownField},
+		{query: Query{Sort: "active"}, err: ErrInvalidSort},
+		{query: Query{Fields: "id,password"}, err: ErrUnknownField},
+	}
+
+	// check the error of every query
+	for _, item := range slice {
+		if _, err := testSchema.Parse(item.query); !errors.Is(err, item.err) || !IsQueryError(err) {
+			t.Errorf("query %+v should fail with %v, got %v", item.query, item.err, err)
+		}
+	}
+}
+
+// TestProject - test only the selected fields are kept
+//
+//	@param t - testing.T
+func TestProject(t *testing.T) {
+	type item struct {
+		Id        string    `json:"id"`
+		Name      string    `json:"name"`
+		Price     float64   `json:"price"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	items := []item{{Id: "a", Name: "milk", Price: 2, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}}
+
+	// the key is kept and the sort column is left out
+	statement, err := testSchema.Parse(Query{Sort: "-createdAt", Fields: "price,name"})
+	if err != nil {
+		t.Fatalf("query should parse, got %v", err)
+	}
+	projected, err := statement.Project(items)
+	if err != nil || len(projected) != 1 || string(projected[0]) != `{"id":"a","price":2,"name":"milk"}` {
+		t.Errorf("only id, price and name should be kept, got %s %v", projected, err)
+	}
+
+	// every field without a selection
+	statement, _ = testSchema.Parse(Query{})
+	projected, err = statement.Project(&items)
+	if err != nil || string(projected[0]) != `{"id":"a","name":"milk","price":2,"createdAt":"2024-01-02T00:00:00Z"}` {
+		t.Errorf("every field should be kept, got %s %v", projected, err)
+	}
+}
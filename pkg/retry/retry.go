@@ -0,0 +1,27 @@
+// Package retry holds the waits shared by the jobs that try failed work again later, such as the outbox relay.
+package retry
+
+import "time"
+
+// Backoff - Backoff returns how long to wait before trying again, doubling from the base after every attempt
+// up to the max.
+//
+//	@param base - the wait after the first attempt
+//	@param max - the longest wait
+//	@param attempts - the attempts made
+//	@return time.Duration
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	wait := base
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		return max
+	}
+
+	return wait
+}
@@ -0,0 +1,32 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackoff - test the wait between attempts
+//
+//	@param t - testing.T
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		0:  0,
+		1:  time.Second,
+		2:  2 * time.Second,
+		5:  16 * time.Second,
+		12: 2048 * time.Second,
+		13: time.Hour,
+		64: time.Hour,
+	} {
+		if got := Backoff(time.Second, time.Hour, attempts); got != want {
+			t.Errorf("%v attempts should wait %v, got %v", attempts, want, got)
+		}
+	}
+
+	// the max caps a wait that is not a doubling of the base
+	for attempts, want := range map[int]time.Duration{1: 30 * time.Second, 9: 2*time.Hour + 8*time.Minute, 10: 3 * time.Hour, 1000: 3 * time.Hour} {
+		if got := Backoff(30*time.Second, 3*time.Hour, attempts); got != want {
+			t.Errorf("%v attempts should wait %v, got %v", attempts, want, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxTerms - the maximum number of terms taken from a search query
+const MaxTerms = 8
+
+// likeEscaper - escapes the LIKE wildcards so they are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Terms - splits a search query into lower cased, de-duplicated terms.
+// Anything that is not a letter or a digit separates terms, so the terms are safe to use in a tsquery.
+//
+//	@param q - string
+//	@return []string
+func Terms(q string) []string {
+	// split the query on anything that is not a letter or a digit
+	fields := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	// terms that have been seen
+	seen := map[string]bool{}
+	terms := make([]string, 0, len(fields))
+
+	// loop through the fields and drop duplicates
+	for _, field := range fields {
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		terms = append(terms, field)
+
+		// stop once the maximum number of terms is reached
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+
+	return terms
+}
+
+// PrefixQuery - builds a postgres tsquery that matches every term of the query as a prefix.
+// An empty string is returned when the query has no terms.
+//
+//	@param q - string
+//	@return string
+func PrefixQuery(q string) string {
+	terms := Terms(q)
+
+	// append the prefix operator to every term
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// LikePrefix - builds a lower cased LIKE pattern that matches values starting with the query.
+// The LIKE wildcards in the query are escaped so they are matched literally.
+//
+//	@param q - string
+//	@return string
+func LikePrefix(q string) string {
+	return likeEscaper.Replace(strings.ToLower(strings.TrimSpace(q))) + "%"
+}
+
+// LikeContains - builds a LIKE pattern that matches values containing the value.
+// The LIKE wildcards in the value are escaped so they are matched literally.
+//
+//	@param v - string
+//	@return string
+func LikeContains(v string) string {
+	return "%" + likeEscaper.Replace(v) + "%"
+}
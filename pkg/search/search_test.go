@@ -0,0 +1,110 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestTerms - test the Terms function
+//
+//	@param t - testing.T
+func TestTerms(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		query string
+		terms []string
+	}{
+		{
+			query: "Whole Milk",
+			terms: []string{"whole", "milk"},
+		},
+		{
+			query: "  milk & milk | !bread:*  ",
+			terms: []string{"milk", "bread"},
+		},
+		{
+			query: "crème brûlée 2l",
+			terms: []string{"crème", "brûlée", "2l"},
+		},
+		{
+			query: "a b c d e f g h i j",
+			terms: []string{"a", "b", "c", "d", "e", "f", "g", "h"},
+		},
+		{
+			query: " ':* ",
+			terms: []string{},
+		},
+	}
+
+	// check the terms of every query
+	for _, item := range slice {
+		if terms := Terms(item.query); !reflect.DeepEqual(terms, item.terms) {
+			// log the error
+			t.Errorf("query %q should have terms %v, got %v", item.query, item.terms, terms)
+		}
+	}
+}
+
+// TestPrefixQuery - test the PrefixQuery function
+//
+//	@param t - testing.T
+func TestPrefixQuery(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		query  string
+		result string
+	}{
+		{
+			query:  "whole milk",
+			result: "whole:* & milk:*",
+		},
+		{
+			query:  "o'brien's",
+			result: "o:* & brien:* & s:*",
+		},
+		{
+			query:  "",
+			result: "",
+		},
+	}
+
+	// check the tsquery of every query
+	for _, item := range slice {
+		if result := PrefixQuery(item.query); result != item.result {
+			// log the error
+			t.Errorf("query %q should return %q, got %q", item.query, item.result, result)
+		}
+	}
+}
+
+// TestLikePrefix - test the LikePrefix function
+//
+//	@param t - testing.T
+func TestLikePrefix(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		query  string
+		result string
+	}{
+		{
+			query:  " Milk ",
+			result: "milk%",
+		},
+		{
+			query:  "100%_juice",
+			result: `100\%\_juice%`,
+		},
+		{
+			query:  `back\slash`,
+			result: `back\\slash%`,
+		},
+	}
+
+	// check the pattern of every query
+	for _, item := range slice {
+		if result := LikePrefix(item.query); result != item.result {
+			// log the error
+			t.Errorf("query %q should return %q, got %q", item.query, item.result, result)
+		}
+	}
+}
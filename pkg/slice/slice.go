@@ -74,3 +74,19 @@ func Filter[T comparable](slice []T, conditions ...func(T) bool) []T {
 	// return the result
 	return result
 }
+
+// Unique - return the slice without repeated elements, keeping the first of each in order
+//
+//	@param slice - slice to deduplicate
+//	@return []T - slice with every element once
+func Unique[T comparable](slice []T) []T {
+	seen := make(map[T]bool, len(slice))
+	result := make([]T, 0, len(slice))
+	for _, item := range slice {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
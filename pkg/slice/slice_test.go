@@ -104,3 +104,37 @@ func TestFilter(t *testing.T) {
 		}
 	}
 }
+
+// TestUnique - test the Unique function
+//
+//	@param t - testing.T
+func TestUnique(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		slice  []string
+		result []string
+	}{
+		{
+			slice:  []string{"a", "b", "a", "c", "b"},
+			result: []string{"a", "b", "c"},
+		},
+		{
+			slice:  []string{},
+			result: []string{},
+		},
+	}
+
+	// check the repeated elements are removed in order
+	for _, item := range slice {
+		result := Unique(item.slice)
+		if len(result) != len(item.result) {
+			t.Errorf("slice %v should be %v, got %v", item.slice, item.result, result)
+			continue
+		}
+		for i := range result {
+			if result[i] != item.result[i] {
+				t.Errorf("slice %v should be %v, got %v", item.slice, item.result, result)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// CodeAlphabet - the characters of codes read or typed by people, without the easily confused 0, O, 1 and I
+const CodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateSecureCode - generates a random code with a cryptographically secure generator.
+// Every character of the alphabet is equally likely.
+//
+//	@param n - the length of the code
+//	@param alphabet - the characters of the code
+//	@return code - the code
+//	@return error
+func GenerateSecureCode(n int, alphabet string) (string, error) {
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return "", errors.New("alphabet must have between 2 and 256 characters")
+	}
+
+	// bytes at or above the largest multiple of the alphabet size are rejected so no character is favoured
+	limit := 256 - 256%len(alphabet)
+
+	code := make([]byte, 0, n)
+	buf := make([]byte, n+n/4+1)
+	for len(code) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("reading random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			code = append(code, alphabet[int(b)%len(alphabet)])
+			if len(code) == n {
+				break
+			}
+		}
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerateSecureCode - test the GenerateSecureCode function
+//
+//	@param t - testing.T
+func TestGenerateSecureCode(t *testing.T) {
+	// generate codes of every length
+	seen := map[string]bool{}
+	for _, n := range []int{0, 1, 8, 12, 64} {
+		code, err := GenerateSecureCode(n, CodeAlphabet)
+		if err != nil || len(code) != n {
+			t.Errorf("code should have %v characters, got %q %v", n, code, err)
+		}
+		for _, c := range code {
+			if !strings.ContainsRune(CodeAlphabet, c) {
+				t.Errorf("code %q should only use the alphabet", code)
+			}
+		}
+		if n >= 8 && seen[code] {
+			t.Errorf("code %q should not repeat", code)
+		}
+		seen[code] = true
+	}
+
+	// every character should be used
+	code, err := GenerateSecureCode(4096, "ab7")
+	if err != nil {
+		t.Fatalf("code should be generated, got %v", err)
+	}
+	for _, c := range "ab7" {
+		if count := strings.Count(code, string(c)); count < 1000 {
+			t.Errorf("%q should be used about 1365 times, got %v", c, count)
+		}
+	}
+
+	// reject invalid alphabets
+	if _, err := GenerateSecureCode(8, "a"); err == nil {
+		t.Errorf("an alphabet of one character should fail")
+	}
+}
@@ -0,0 +1,193 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/money"
+)
+
+// the products database
+var cartDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// Get - Get is a function that gets the cart of a user with the current prices of its products.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @return cart
+// @return error
+func Get(ctx context.Context, userId string) (*Cart, error) {
+	lines := make([]Line, 0)
+
+	// query statement to be executed
+	q := `
+    SELECT c.*, p.name, p.price, p.stock_quantity
+    FROM cart_items c
+    JOIN products p ON p.id = c.product_id
+    WHERE c.user_id = :user_id
+    ORDER BY c.created_at, c.product_id
+  `
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, cartDatabase(), q, map[string]interface{}{"user_id": userId}, &lines); err != nil {
+		return nil, fmt.Errorf("selecting cart items: %w", err)
+	}
+
+	// total the lines
+	cart := &Cart{Items: lines, Subtotal: money.Zero(money.DefaultCurrency)}
+	if len(lines) > 0 {
+		cart.Subtotal = money.Zero(lines[0].Price.Currency)
+	}
+	for i := range cart.Items {
+		total, err := cart.Items[i].Price.Mul(int64(cart.Items[i].Quantity))
+		if err != nil {
+			return nil, err
+		}
+		cart.Items[i].Total = total
+		if cart.Subtotal, err = cart.Subtotal.Add(total); err != nil {
+			return nil, err
+		}
+	}
+
+	return cart, nil
+}
+
+// SetItem - SetItem is a function that sets the quantity of a product in the cart of a user.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param productId - string
+// @param quantity - the quantity, 0 removes the product
+// @return error
+func SetItem(ctx context.Context, userId, productId string, quantity int) error {
+	data := map[string]interface{}{"user_id": userId, "product_id": productId, "quantity": quantity, "now": time.Now().UTC()}
+
+	// remove the product
+	if quantity < 1 {
+		if err := database.NamedExecQuery(ctx, cartDatabase(), "DELETE FROM cart_items WHERE user_id = :user_id AND product_id = :product_id", data); err != nil {
+			return fmt.Errorf("deleting cart item: %w", err)
+		}
+		return nil
+	}
+
+	// check if the product exists
+	count, err := database.NamedCountQuery(ctx, cartDatabase(), "SELECT COUNT(*) FROM products WHERE id = :product_id", data)
+	if err != nil {
+		return fmt.Errorf("counting products: %w", err)
+	}
+	if count < 1 {
+		return ErrProductNotFound
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO cart_items (user_id, product_id, quantity, created_at, updated_at)
+    VALUES (:user_id, :product_id, :quantity, :now, :now)
+    ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query, removing the product again when the cart is full
+	return database.Transaction(ctx, cartDatabase(), func(tx *sqlx.Tx) error {
+		if err := database.NamedExecQuery(ctx, tx, q, data); err != nil {
+			return fmt.Errorf("setting cart item: %w", err)
+		}
+		return checkSize(ctx, tx, userId)
+	})
+}
+
+// Add - Add is a function that adds products to the cart of a user, on top of the quantities already in it.
+// It is given a transaction so a full cart rolls the products back.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @param items - the products and quantities to add
+// @return error
+func Add(ctx context.Context, db sqlx.ExtContext, userId string, items []Item) error {
+	if len(items) < 1 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	for i := range items {
+		items[i].UserId = userId
+		items[i].CreatedAt = now
+		items[i].UpdatedAt = now
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO cart_items (user_id, product_id, quantity, created_at, updated_at)
+    VALUES (:user_id, :product_id, :quantity, :created_at, :updated_at)
+    ON CONFLICT (user_id, product_id) DO UPDATE SET
+      quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, db, q, items); err != nil {
+		return fmt.Errorf("adding cart items: %w", err)
+	}
+
+	return checkSize(ctx, db, userId)
+}
+
+// Clear - Clear is a function that removes every product from the cart of a user.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @return error
+func Clear(ctx context.Context, userId string) error {
+	if err := database.NamedExecQuery(ctx, cartDatabase(), "DELETE FROM cart_items WHERE user_id = :user_id", map[string]interface{}{
+		"user_id": userId,
+	}); err != nil {
+		return fmt.Errorf("clearing cart: %w", err)
+	}
+
+	return nil
+}
+
+// checkSize - checks the cart of a user holds no more than the most products allowed.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @return error
+func checkSize(ctx context.Context, db sqlx.ExtContext, userId string) error {
+	count, err := database.NamedCountQuery(ctx, db, "SELECT COUNT(*) FROM cart_items WHERE user_id = :user_id", map[string]interface{}{
+		"user_id": userId,
+	})
+	if err != nil {
+		return fmt.Errorf("counting cart items: %w", err)
+	}
+	if count > MaxItems {
+		return fmt.Errorf("%w: a cart holds up to %v products", ErrTooManyItems, MaxItems)
+	}
+
+	return nil
+}
+
+// DeleteUser - DeleteUser is a function that removes the cart of a deleted user.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @return the rows removed, by what they were
+// @return error
+func DeleteUser(ctx context.Context, db sqlx.ExtContext, userId string) (map[string]int, error) {
+	count, err := database.NamedCountQuery(ctx, db, `
+    WITH removed AS (DELETE FROM cart_items WHERE user_id = :user_id RETURNING product_id)
+    SELECT COUNT(*) FROM removed
+  `, map[string]interface{}{"user_id": userId})
+	if err != nil {
+		return nil, fmt.Errorf("deleting cart items: %w", err)
+	}
+
+	return map[string]int{"cartItems": count}, nil
+}
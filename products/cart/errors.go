@@ -0,0 +1,8 @@
+package cart
+
+import "errors"
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrTooManyItems    = errors.New("too many items in the cart")
+)
@@ -0,0 +1,36 @@
+package cart
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+// MaxItems - the most products a cart can hold
+const MaxItems = 200
+
+type Item struct {
+	UserId    string    `json:"userId" db:"user_id"`
+	ProductId string    `json:"productId" db:"product_id"`
+	Quantity  int       `json:"quantity" db:"quantity"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Line - an item of the cart with its product
+type Line struct {
+	Item
+	Name          string      `json:"name" db:"name"`
+	Price         money.Money `json:"price" db:"price"`
+	StockQuantity int         `json:"stockQuantity" db:"stock_quantity"`
+	Total         money.Money `json:"total" db:"-"` // the price times the quantity
+}
+
+type Cart struct {
+	Items    []Line      `json:"items"`
+	Subtotal money.Money `json:"subtotal"` // before promotions and tax
+}
+
+type ItemRequest struct {
+	Quantity int `json:"quantity" validate:"min=0,max=1000"` // removes the product when 0
+}
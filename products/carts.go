@@ -0,0 +1,110 @@
+package products
+
+import (
+	"context"
+	"errors"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/products/cart"
+)
+
+// =====================================================================================================================
+// CART
+// =====================================================================================================================
+
+// GetCart - Get the cart of the signed in user with the current prices of its products
+//
+//	@param ctx - context.Context
+//	@return cart
+//	@return error
+//
+// encore:api auth method=GET path=/cart
+func GetCart(ctx context.Context) (*cart.Cart, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &cart.Cart{}, err
+	}
+
+	// get the cart
+	c, err := cart.Get(ctx, claims.Subject.Id)
+	if err != nil {
+		return &cart.Cart{}, err
+	}
+
+	return c, nil
+}
+
+// SetCartItem - Set the quantity of a product in the cart of the signed in user, 0 removes it
+//
+//	@param ctx - context.Context
+//	@param productId - string
+//	@param payload - *cart.ItemRequest
+//	@return cart
+//	@return error
+//
+// encore:api auth method=PUT path=/cart/items/:productId
+func SetCartItem(ctx context.Context, productId string, payload *cart.ItemRequest) (*cart.Cart, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &cart.Cart{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &cart.Cart{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// set the item
+	if err := cart.SetItem(ctx, claims.Subject.Id, productId, payload.Quantity); err != nil {
+		return &cart.Cart{}, cartError(err)
+	}
+
+	// get the cart
+	c, err := cart.Get(ctx, claims.Subject.Id)
+	if err != nil {
+		return &cart.Cart{}, err
+	}
+
+	return c, nil
+}
+
+// ClearCart - Remove every product from the cart of the signed in user
+//
+//	@param ctx - context.Context
+//	@return error
+//
+// encore:api auth method=DELETE path=/cart
+func ClearCart(ctx context.Context) error {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// clear the cart
+	if err := cart.Clear(ctx, claims.Subject.Id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// cartError - maps cart store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func cartError(err error) error {
+	switch {
+	case errors.Is(err, cart.ErrProductNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, cart.ErrTooManyItems):
+		return &errs.Error{Code: errs.ResourceExhausted, Message: err.Error()}
+	default:
+		return err
+	}
+}
@@ -2,11 +2,15 @@ package products
 
 import (
 	"context"
-	"encore.app/products/cs"
-	"fmt"
+	"errors"
+
+	"encore.dev/beta/errs"
 	"github.com/go-playground/validator/v10"
 
+	"encore.app/pkg/database"
 	"encore.app/pkg/middleware"
+	"encore.app/pkg/pagination"
+	"encore.app/products/cs"
 )
 
 // =====================================================================================================================
@@ -21,15 +25,9 @@ import (
 //
 // encore:api auth method=POST path=/categories/create
 func CreateCategory(ctx context.Context, payload *cs.CategoryRequest) error {
-	// check for claims
-	claims, err := middleware.GetVerifiedClaims(ctx, "")
-	if err != nil {
-		return err
-	}
-
 	// check for the roles
-	if !claims.HasRole(middleware.RoleSuperAdmin, middleware.RoleAdmin) {
-		return fmt.Errorf("unauthorized: you are not authorized to perform this action")
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
 	}
 
 	// validate payload
@@ -39,36 +37,48 @@ func CreateCategory(ctx context.Context, payload *cs.CategoryRequest) error {
 
 	// create category
 	if err := cs.Create(ctx, payload); err != nil {
-		return err
+		return categoryError(err)
 	}
 
 	return nil
 }
 
-// GetCategory - Get a product
+// ListCategories - List categories
 //
 //	@param ctx - context.Context
-//	@param id
-//	@return product
+//	@param params - *cs.CategoriesQuery
+//	@return categories
 //	@return error
 //
-// encore:api auth method=GET path=/categories/get/:id
-func GetCategory(ctx context.Context, id string) (*cs.Category, error) {
-	// check for claims
-	claims, err := middleware.GetVerifiedClaims(ctx, "")
-	if err != nil {
-		return &cs.Category{}, err
+// encore:api public method=GET path=/categories
+func ListCategories(ctx context.Context, params *cs.CategoriesQuery) (*cs.PaginatedCategoriesResponse, error) {
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &cs.PaginatedCategoriesResponse{}, err
 	}
 
-	// check for the roles
-	if !claims.HasRole(middleware.RoleSuperAdmin, middleware.RoleAdmin) {
-		return &cs.Category{}, fmt.Errorf("unauthorized: you are not authorized to perform this action")
+	// query categories
+	categories, err := cs.GetAll(ctx, params)
+	if err != nil {
+		return &cs.PaginatedCategoriesResponse{}, categoryError(err)
 	}
 
+	return categories, nil
+}
+
+// GetCategory - Get a category
+//
+//	@param ctx - context.Context
+//	@param id
+//	@return category
+//	@return error
+//
+// encore:api public method=GET path=/categories/get/:id
+func GetCategory(ctx context.Context, id string) (*cs.Category, error) {
 	// get category
 	category, err := cs.Get(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, categoryError(err)
 	}
 
 	return category, nil
@@ -83,6 +93,11 @@ func GetCategory(ctx context.Context, id string) (*cs.Category, error) {
 //
 // encore:api auth method=PATCH path=/categories/update/:id
 func UpdateCategory(ctx context.Context, id string, payload *cs.UpdateCategoryRequest) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
 	// validate payload
 	if err := validator.New().Struct(payload); err != nil {
 		return err
@@ -90,9 +105,81 @@ func UpdateCategory(ctx context.Context, id string, payload *cs.UpdateCategoryRe
 
 	// update category
 	if err := cs.Update(ctx, id, payload); err != nil {
-		return err
+		return categoryError(err)
 	}
 
 	// return nil if no error
 	return nil
 }
+
+// DeleteCategory - Delete a category
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param params - *cs.DeleteCategoryRequest
+//	@return error
+//
+// encore:api auth method=DELETE path=/categories/delete/:id
+func DeleteCategory(ctx context.Context, id string, params *cs.DeleteCategoryRequest) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return err
+	}
+
+	// delete category
+	if err := cs.Delete(ctx, id, params.ReassignTo); err != nil {
+		return categoryError(err)
+	}
+
+	return nil
+}
+
+// DeleteCategories - Delete many categories
+//
+//	@param ctx - context.Context
+//	@param payload - *cs.DeleteCategoriesRequest
+//	@return error
+//
+// encore:api auth method=POST path=/categories/delete
+func DeleteCategories(ctx context.Context, payload *cs.DeleteCategoriesRequest) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return err
+	}
+
+	// delete categories
+	if err := cs.DeleteMany(ctx, payload.Ids, payload.ReassignTo); err != nil {
+		return categoryError(err)
+	}
+
+	return nil
+}
+
+// categoryError - maps category store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func categoryError(err error) error {
+	switch {
+	case errors.Is(err, cs.ErrNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, cs.ErrAlreadyExists):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, cs.ErrHasProducts):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: "category still has products: reassign them before deleting"}
+	case pagination.IsQueryError(err), errors.Is(err, database.ErrForbidden):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,64 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/events"
+	"encore.app/pkg/outbox"
+	"encore.app/products/cart"
+	"encore.app/products/lists"
+	"encore.app/products/reviews"
+)
+
+// Service - the name the products report their cleanups with
+const Service = "products"
+
+// the products database
+var cleanupDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// DeleteUser - DeleteUser is a function that removes the carts, lists and reviews of a deleted user in one
+// transaction, and saves the event reporting it is done. An event handled before changes nothing.
+//
+// @param ctx - context.Context
+// @param subscription - the name of the subscription handling the event
+// @param event - *events.UserDeleted
+// @return error
+func DeleteUser(ctx context.Context, subscription string, event *events.UserDeleted) error {
+	return database.Transaction(ctx, cleanupDatabase(), func(tx *sqlx.Tx) error {
+		claimed, err := outbox.Claim(ctx, tx, subscription, event.Meta.Id)
+		if err != nil || !claimed {
+			return err
+		}
+
+		removed := make(map[string]int)
+		for _, remove := range []func() (map[string]int, error){
+			func() (map[string]int, error) { return cart.DeleteUser(ctx, tx, event.UserId) },
+			func() (map[string]int, error) { return lists.DeleteUser(ctx, tx, event.UserId) },
+			func() (map[string]int, error) { return reviews.DeleteUser(ctx, tx, event.UserId) },
+		} {
+			counts, err := remove()
+			if err != nil {
+				return fmt.Errorf("cleaning up user: %w", err)
+			}
+			for name, count := range counts {
+				removed[name] = count
+			}
+		}
+
+		done := &events.CleanupCompleted{
+			Meta:    events.NewMeta(events.TypeCleanupCompleted, time.Now()),
+			UserId:  event.UserId,
+			Service: Service,
+			Removed: removed,
+		}
+		return outbox.Add(ctx, tx, done.Meta, done)
+	})
+}
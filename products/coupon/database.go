@@ -0,0 +1,460 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/utils"
+	"encore.app/products/promo"
+)
+
+// the products database
+var couponsDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+const (
+	// the length of the random part of generated codes
+	defaultCodeLength = 10
+	// the coupons inserted per statement when generating codes
+	generateChunkSize = 500
+	// the rounds of generation before giving up on unique codes
+	generateAttempts = 5
+)
+
+// insertCoupon - query statement inserting coupons, codes already taken are skipped
+const insertCoupon = `
+  INSERT INTO coupons (
+    id, code, promotion_id, batch, max_uses, max_uses_per_user, uses, expires_at,
+    product_ids, category_ids, active, created_at, updated_at
+  )
+  VALUES (
+    :id, :code, :promotion_id, :batch, :max_uses, :max_uses_per_user, :uses, :expires_at,
+    :product_ids, :category_ids, :active, :created_at, :updated_at
+  )
+  ON CONFLICT (code) DO NOTHING
+`
+
+// FindOneByField - get coupon by field
+//
+//	@param ctx - context.Context
+//	@param db - sqlx.ExtContext
+//	@param field - string
+//	@param value - interface{}
+//	@param lock - lock the coupon until the end of the transaction
+//	@return coupon
+//	@return error
+func FindOneByField(ctx context.Context, db sqlx.ExtContext, field string, value interface{}, lock bool) (Coupon, error) {
+	// query statement to be executed
+	q := fmt.Sprintf("SELECT * FROM coupons WHERE %v = :%v LIMIT 1", field, field)
+	if lock {
+		q += " FOR UPDATE"
+	}
+
+	// execute query
+	var coupon Coupon
+	if err := database.NamedStructQuery(ctx, db, q, map[string]interface{}{field: value}, &coupon); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return Coupon{}, ErrNotFound
+		}
+		return Coupon{}, fmt.Errorf("selecting coupons by %v[%v]: %w", field, value, err)
+	}
+
+	return coupon, nil
+}
+
+// Get - Get is a function that gets a coupon.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return coupon
+// @return error
+func Get(ctx context.Context, id string) (*Coupon, error) {
+	coupon, err := FindOneByField(ctx, couponsDatabase(), "id", id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &coupon, nil
+}
+
+// Create - Create is a function that creates a coupon, with a generated code when none is given.
+//
+// @param ctx - context.Context
+// @param payload - *CouponRequest
+// @return coupon
+// @return error
+func Create(ctx context.Context, payload *CouponRequest) (*Coupon, error) {
+	// check the promotion exists
+	if _, err := promotion(ctx, payload.PromotionId); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	coupon := Coupon{
+		Id:             uuid.New().String(),
+		Code:           NormalizeCode(payload.Code),
+		PromotionId:    payload.PromotionId,
+		MaxUses:        payload.MaxUses,
+		MaxUsesPerUser: payload.MaxUsesPerUser,
+		ExpiresAt:      payload.ExpiresAt,
+		ProductIds:     nonNil(payload.ProductIds),
+		CategoryIds:    nonNil(payload.CategoryIds),
+		Active:         true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	// generate a code, trying again when it is taken
+	generated := len(coupon.Code) < 1
+	for attempt := 0; attempt < generateAttempts; attempt++ {
+		if generated {
+			code, err := utils.GenerateSecureCode(defaultCodeLength, utils.CodeAlphabet)
+			if err != nil {
+				return nil, err
+			}
+			coupon.Code = code
+		}
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, couponsDatabase(), insertCoupon, coupon); err != nil {
+			return nil, fmt.Errorf("inserting coupon: %w", err)
+		}
+
+		// the coupon is only inserted when the code is free
+		inserted, err := FindOneByField(ctx, couponsDatabase(), "code", coupon.Code, false)
+		if err != nil {
+			return nil, err
+		}
+		if inserted.Id == coupon.Id {
+			return &inserted, nil
+		}
+		if !generated {
+			return nil, ErrAlreadyExists
+		}
+	}
+
+	return nil, ErrExhausted
+}
+
+// Generate - Generate is a function that creates many coupons with random codes in a new batch.
+//
+// @param ctx - context.Context
+// @param payload - *GenerateRequest
+// @return response
+// @return error
+func Generate(ctx context.Context, payload *GenerateRequest) (*GenerateResponse, error) {
+	// check the promotion exists
+	if _, err := promotion(ctx, payload.PromotionId); err != nil {
+		return nil, err
+	}
+
+	length := payload.Length
+	if length < 1 {
+		length = defaultCodeLength
+	}
+	prefix := NormalizeCode(payload.Prefix)
+	batch := uuid.New().String()
+	now := time.Now().UTC()
+
+	// insert the codes, generating more for the ones already taken
+	codes := make([]string, 0, payload.Count)
+	for attempt := 0; attempt < generateAttempts && len(codes) < payload.Count; attempt++ {
+		coupons := make([]Coupon, 0, payload.Count-len(codes))
+		unique := make(map[string]bool, payload.Count-len(codes))
+		for len(coupons) < payload.Count-len(codes) {
+			code, err := utils.GenerateSecureCode(length, utils.CodeAlphabet)
+			if err != nil {
+				return nil, err
+			}
+			if unique[code] {
+				continue
+			}
+			unique[code] = true
+
+			coupons = append(coupons, Coupon{
+				Id:             uuid.New().String(),
+				Code:           prefix + code,
+				PromotionId:    payload.PromotionId,
+				Batch:          batch,
+				MaxUses:        payload.MaxUses,
+				MaxUsesPerUser: payload.MaxUsesPerUser,
+				ExpiresAt:      payload.ExpiresAt,
+				ProductIds:     nonNil(payload.ProductIds),
+				CategoryIds:    nonNil(payload.CategoryIds),
+				Active:         true,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			})
+		}
+
+		// insert the coupons in chunks
+		if err := database.Transaction(ctx, couponsDatabase(), func(tx *sqlx.Tx) error {
+			for start := 0; start < len(coupons); start += generateChunkSize {
+				end := start + generateChunkSize
+				if end > len(coupons) {
+					end = len(coupons)
+				}
+				if err := database.NamedExecQuery(ctx, tx, insertCoupon, coupons[start:end]); err != nil {
+					return fmt.Errorf("inserting coupons: %w", err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		// get the codes inserted so far
+		var inserted []struct {
+			Code string `db:"code"`
+		}
+		if err := database.NamedSliceQuery(ctx, couponsDatabase(), "SELECT code FROM coupons WHERE batch = :batch ORDER BY code", map[string]interface{}{
+			"batch": batch,
+		}, &inserted); err != nil {
+			return nil, fmt.Errorf("selecting coupons: %w", err)
+		}
+		codes = codes[:0]
+		for _, row := range inserted {
+			codes = append(codes, row.Code)
+		}
+	}
+	if len(codes) < payload.Count {
+		return nil, ErrExhausted
+	}
+
+	return &GenerateResponse{Batch: batch, Codes: codes}, nil
+}
+
+// Deactivate - Deactivate is a function that stops a coupon from being used.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return error
+func Deactivate(ctx context.Context, id string) error {
+	// check if the coupon exists
+	if _, err := Get(ctx, id); err != nil {
+		return err
+	}
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, couponsDatabase(), "UPDATE coupons SET active = FALSE, updated_at = NOW() WHERE id = :id", map[string]interface{}{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("deactivating coupon: %w", err)
+	}
+
+	return nil
+}
+
+// Validate - Validate is a function that checks a coupon can be used by a user for a basket.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param payload - *ValidateRequest
+// @param now - time.Time
+// @return response
+// @return error
+func Validate(ctx context.Context, userId string, payload *ValidateRequest, now time.Time) (*ValidateResponse, error) {
+	// get the coupon and the number of times the user used it
+	coupon, err := FindOneByField(ctx, couponsDatabase(), "code", NormalizeCode(payload.Code), false)
+	if err != nil {
+		return nil, err
+	}
+	userUses, err := countUses(ctx, couponsDatabase(), coupon.Id, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply the coupon to the basket
+	basket, err := load(ctx, &coupon, payload.Lines, now)
+	if err != nil {
+		return nil, err
+	}
+	result, discount, reason, err := coupon.Apply(userUses, basket.promotion, basket.lines, basket.running, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValidateResponse{
+		Valid:    len(reason) < 1,
+		Reason:   reason,
+		Discount: discount,
+		Result:   result,
+	}, nil
+}
+
+// Redeem - Redeem is a function that uses a coupon for a basket.
+// The coupon is locked while it is checked and its use recorded, so concurrent redemptions
+// are applied one at a time and the limits of the coupon cannot be exceeded.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param payload - *RedeemRequest
+// @param now - time.Time
+// @return response
+// @return error
+func Redeem(ctx context.Context, userId string, payload *RedeemRequest, now time.Time) (*RedeemResponse, error) {
+	code := NormalizeCode(payload.Code)
+
+	// load the basket before taking the lock
+	coupon, err := FindOneByField(ctx, couponsDatabase(), "code", code, false)
+	if err != nil {
+		return nil, err
+	}
+	basket, err := load(ctx, &coupon, payload.Lines, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *RedeemResponse
+	if err := database.Transaction(ctx, couponsDatabase(), func(tx *sqlx.Tx) error {
+		// lock the coupon, reading the uses after other redemptions have finished
+		coupon, err := FindOneByField(ctx, tx, "code", code, true)
+		if err != nil {
+			return err
+		}
+
+		// a coupon is used once per reference
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id = :coupon_id AND reference = :reference", map[string]interface{}{
+			"coupon_id": coupon.Id,
+			"reference": payload.Reference,
+		})
+		if err != nil {
+			return fmt.Errorf("selecting redemptions: %w", err)
+		}
+		if count > 0 {
+			return ErrAlreadyRedeemed
+		}
+
+		// check the coupon applies
+		userUses, err := countUses(ctx, tx, coupon.Id, userId)
+		if err != nil {
+			return err
+		}
+		result, discount, reason, err := coupon.Apply(userUses, basket.promotion, basket.lines, basket.running, now)
+		if err != nil {
+			return err
+		}
+		if len(reason) > 0 {
+			return fmt.Errorf("%w: %v", ErrNotApplicable, reason)
+		}
+
+		// record the use
+		if err := database.NamedExecQuery(ctx, tx, "UPDATE coupons SET uses = uses + 1, updated_at = :updated_at WHERE id = :id", map[string]interface{}{
+			"id":         coupon.Id,
+			"updated_at": now.UTC(),
+		}); err != nil {
+			return fmt.Errorf("updating coupon: %w", err)
+		}
+		redemption := Redemption{
+			Id:        uuid.New().String(),
+			CouponId:  coupon.Id,
+			UserId:    userId,
+			Reference: payload.Reference,
+			Discount:  discount,
+			CreatedAt: now.UTC(),
+		}
+		if err := database.NamedExecQuery(ctx, tx, `
+      INSERT INTO coupon_redemptions (id, coupon_id, user_id, reference, discount, created_at)
+      VALUES (:id, :coupon_id, :user_id, :reference, :discount, :created_at)
+    `, redemption); err != nil {
+			return fmt.Errorf("inserting redemption: %w", err)
+		}
+
+		response = &RedeemResponse{Redemption: redemption, Result: result}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// basket - what a coupon is applied to
+type basket struct {
+	promotion promo.Promotion
+	lines     []promo.Line
+	running   []promo.Promotion
+}
+
+// load - gets the promotion of the coupon, the products of the basket and the running promotions.
+//
+// @param ctx - context.Context
+// @param coupon - *Coupon
+// @param lines - []promo.BasketLine
+// @param now - time.Time
+// @return basket
+// @return error
+func load(ctx context.Context, coupon *Coupon, lines []promo.BasketLine, now time.Time) (*basket, error) {
+	p, err := promotion(ctx, coupon.PromotionId)
+	if err != nil {
+		return nil, err
+	}
+	products, err := promo.Lines(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+	running, err := promo.List(ctx, true, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &basket{promotion: *p, lines: products, running: running}, nil
+}
+
+// promotion - gets the promotion of a coupon.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return promotion
+// @return error
+func promotion(ctx context.Context, id string) (*promo.Promotion, error) {
+	p, err := promo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, promo.ErrNotFound) {
+			return nil, ErrPromotionNotFound
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// countUses - counts the times a user has used a coupon.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param couponId - string
+// @param userId - string
+// @return int
+// @return error
+func countUses(ctx context.Context, db sqlx.ExtContext, couponId, userId string) (int, error) {
+	count, err := database.NamedCountQuery(ctx, db, "SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id = :coupon_id AND user_id = :user_id", map[string]interface{}{
+		"coupon_id": couponId,
+		"user_id":   userId,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("selecting redemptions: %w", err)
+	}
+
+	return count, nil
+}
+
+// nonNil - returns an empty list in place of nil, the lists of a coupon are never null.
+//
+// @param list - []string
+// @return []string
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+
+	return list
+}
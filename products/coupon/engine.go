@@ -0,0 +1,97 @@
+package coupon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"encore.app/pkg/money"
+	"encore.app/products/promo"
+)
+
+// NormalizeCode - NormalizeCode returns the code as it is stored, coupon codes are not case sensitive.
+//
+// @param code - string
+// @return string
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Check - Check returns why the coupon cannot be used, or an empty string when it can.
+//
+// @param userUses - the number of times the user has used the coupon
+// @param now - time.Time
+// @return string
+func (c *Coupon) Check(userUses int, now time.Time) string {
+	switch {
+	case !c.Active:
+		return "the coupon is not active"
+	case c.ExpiresAt != nil && !now.Before(*c.ExpiresAt):
+		return fmt.Sprintf("the coupon expired at %v", c.ExpiresAt.UTC().Format(time.RFC3339))
+	case c.MaxUses > 0 && c.Uses >= c.MaxUses:
+		return "the coupon has been used up"
+	case c.MaxUsesPerUser > 0 && userUses >= c.MaxUsesPerUser:
+		return "you have already used the coupon the most times allowed"
+	default:
+		return ""
+	}
+}
+
+// Apply - Apply evaluates a basket with the promotion of the coupon and the running promotions.
+// The promotion of the coupon is limited to the products and categories of the coupon when it has any.
+// A reason is returned when the coupon cannot be used, together with the result without the coupon.
+//
+// @param userUses - the number of times the user has used the coupon
+// @param promotion - the promotion of the coupon
+// @param lines - []promo.Line
+// @param running - the running promotions
+// @param now - time.Time
+// @return result
+// @return discount - the discount of the coupon
+// @return reason
+// @return error
+func (c *Coupon) Apply(userUses int, promotion promo.Promotion, lines []promo.Line, running []promo.Promotion, now time.Time) (*promo.Result, money.Money, string, error) {
+	// check the coupon itself
+	if reason := c.Check(userUses, now); len(reason) > 0 {
+		result, err := promo.Evaluate(lines, running, now)
+		if err != nil {
+			return nil, money.Money{}, "", err
+		}
+		return result, money.Zero(result.Subtotal.Currency), reason, nil
+	}
+
+	// limit the promotion to the items of the coupon
+	if len(c.ProductIds) > 0 || len(c.CategoryIds) > 0 {
+		promotion.ProductIds = c.ProductIds
+		promotion.CategoryIds = c.CategoryIds
+	}
+
+	// evaluate the basket with the coupon, in place of the promotion if it is also running
+	promotions := make([]promo.Promotion, 0, len(running)+1)
+	for _, p := range running {
+		if p.Id != promotion.Id {
+			promotions = append(promotions, p)
+		}
+	}
+	promotions = append(promotions, promotion)
+
+	result, err := promo.Evaluate(lines, promotions, now)
+	if err != nil {
+		return nil, money.Money{}, "", err
+	}
+
+	// find the discount of the coupon
+	for _, applied := range result.Applied {
+		if applied.PromotionId == promotion.Id {
+			return result, applied.Discount, "", nil
+		}
+	}
+	reason := "the coupon does not apply to the basket"
+	for _, skipped := range result.Skipped {
+		if skipped.PromotionId == promotion.Id {
+			reason = skipped.Reason
+		}
+	}
+
+	return result, money.Zero(result.Subtotal.Currency), reason, nil
+}
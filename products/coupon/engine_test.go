@@ -0,0 +1,98 @@
+package coupon
+
+import (
+	"testing"
+	"time"
+
+	"encore.app/pkg/money"
+	"encore.app/products/promo"
+	"encore.app/products/ps"
+)
+
+// the time the coupons are used at
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// TestNormalizeCode - test the NormalizeCode function
+//
+//	@param t - testing.T
+func TestNormalizeCode(t *testing.T) {
+	if code := NormalizeCode("  summer24 "); code != "SUMMER24" {
+		t.Errorf("code should be SUMMER24, got %q", code)
+	}
+}
+
+// TestCheck - test the limits of a coupon
+//
+//	@param t - testing.T
+func TestCheck(t *testing.T) {
+	expired := now.Add(-time.Minute)
+	later := now.Add(time.Hour)
+
+	// create a slice
+	slice := []struct {
+		coupon   Coupon
+		userUses int
+		reason   string
+	}{
+		{coupon: Coupon{Active: true}},
+		{coupon: Coupon{Active: true, MaxUses: 5, Uses: 4, MaxUsesPerUser: 2, ExpiresAt: &later}, userUses: 1},
+		{coupon: Coupon{}, reason: "the coupon is not active"},
+		{coupon: Coupon{Active: true, ExpiresAt: &expired}, reason: "the coupon expired at 2024-06-01T11:59:00Z"},
+		{coupon: Coupon{Active: true, MaxUses: 5, Uses: 5}, reason: "the coupon has been used up"},
+		{coupon: Coupon{Active: true, MaxUsesPerUser: 1}, userUses: 1, reason: "you have already used the coupon the most times allowed"},
+	}
+
+	// check every coupon
+	for _, item := range slice {
+		if reason := item.coupon.Check(item.userUses, now); reason != item.reason {
+			t.Errorf("coupon %+v should give %q, got %q", item.coupon, item.reason, reason)
+		}
+	}
+}
+
+// TestApply - test the discount of a coupon on a basket
+//
+//	@param t - testing.T
+func TestApply(t *testing.T) {
+	lines := []promo.Line{
+		{Product: ps.Product{Id: "apple", CategoryId: "fruit", Price: money.Money{Amount: 1000, Currency: "USD"}}, Quantity: 1},
+		{Product: ps.Product{Id: "juice", CategoryId: "drinks", Price: money.Money{Amount: 500, Currency: "USD"}}, Quantity: 2},
+	}
+	promotion := promo.Promotion{Id: "coupon", Name: "20% off", Type: promo.TypePercentage, Percentage: 2000, Stackable: true, CouponOnly: true, Active: true}
+	running := []promo.Promotion{
+		{Id: "sale", Name: "10% off", Type: promo.TypePercentage, Percentage: 1000, Priority: 1, Stackable: true, Active: true},
+	}
+
+	// create a slice
+	slice := []struct {
+		name     string
+		coupon   Coupon
+		running  []promo.Promotion
+		discount int64
+		total    int64
+		reason   string
+	}{
+		{name: "every item", coupon: Coupon{Active: true}, discount: 400, total: 1600},
+		{name: "limited to a category", coupon: Coupon{Active: true, CategoryIds: []string{"drinks"}}, discount: 200, total: 1800},
+		{name: "after the running promotions", coupon: Coupon{Active: true}, running: running, discount: 360, total: 1440},
+		{name: "no eligible items", coupon: Coupon{Active: true, ProductIds: []string{"pear"}}, running: running, total: 1800, reason: "no items in the basket are included in the promotion"},
+		{name: "used up", coupon: Coupon{Active: true, MaxUses: 1, Uses: 1}, running: running, total: 1800, reason: "the coupon has been used up"},
+	}
+
+	// check every coupon
+	for _, item := range slice {
+		result, discount, reason, err := item.coupon.Apply(0, promotion, lines, item.running, now)
+		if err != nil {
+			t.Errorf("%v: should apply, got %v", item.name, err)
+			continue
+		}
+		if reason != item.reason || discount.Amount != item.discount || result.Total.Amount != item.total {
+			t.Errorf("%v: should discount %v to %v with %q, got %v to %v with %q", item.name, item.discount, item.total, item.reason, discount.Amount, result.Total.Amount, reason)
+		}
+	}
+
+	// the promotion of the coupon is not changed
+	if len(promotion.ProductIds) > 0 || len(promotion.CategoryIds) > 0 {
+		t.Errorf("the promotion should not be changed, got %+v", promotion)
+	}
+}
@@ -0,0 +1,12 @@
+package coupon
+
+import "errors"
+
+var (
+	ErrNotFound          = errors.New("coupon not found")
+	ErrAlreadyExists     = errors.New("a coupon with the code already exists")
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrNotApplicable     = errors.New("the coupon cannot be used")
+	ErrAlreadyRedeemed   = errors.New("the coupon was already used for the reference")
+	ErrExhausted         = errors.New("not enough unique codes could be generated, use a longer code")
+)
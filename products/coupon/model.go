@@ -0,0 +1,83 @@
+package coupon
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+	"encore.app/products/promo"
+)
+
+type Coupon struct {
+	Id             string     `json:"id" db:"id"`
+	Code           string     `json:"code" db:"code"`
+	PromotionId    string     `json:"promotionId" db:"promotion_id"`
+	Batch          string     `json:"batch" db:"batch"`                      // the coupons generated together share a batch
+	MaxUses        int        `json:"maxUses" db:"max_uses"`                 // 0 is unlimited
+	MaxUsesPerUser int        `json:"maxUsesPerUser" db:"max_uses_per_user"` // 0 is unlimited
+	Uses           int        `json:"uses" db:"uses"`
+	ExpiresAt      *time.Time `json:"expiresAt" db:"expires_at"`
+	ProductIds     []string   `json:"productIds" db:"product_ids"`   // the products the coupon can be used for
+	CategoryIds    []string   `json:"categoryIds" db:"category_ids"` // the categories the coupon can be used for
+	Active         bool       `json:"active" db:"active"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+type CouponRequest struct {
+	Code           string     `json:"code" validate:"omitempty,min=4,max=64,alphanum"` // generated when empty
+	PromotionId    string     `json:"promotionId" validate:"required,uuid"`
+	MaxUses        int        `json:"maxUses" validate:"omitempty,min=0"`
+	MaxUsesPerUser int        `json:"maxUsesPerUser" validate:"omitempty,min=0"`
+	ExpiresAt      *time.Time `json:"expiresAt" validate:"omitempty"`
+	ProductIds     []string   `json:"productIds" validate:"omitempty,dive,uuid"`
+	CategoryIds    []string   `json:"categoryIds" validate:"omitempty,dive,uuid"`
+}
+
+type GenerateRequest struct {
+	PromotionId    string     `json:"promotionId" validate:"required,uuid"`
+	Count          int        `json:"count" validate:"required,min=1,max=10000"`
+	Prefix         string     `json:"prefix" validate:"omitempty,max=16,alphanum"` // e.g. SUMMER
+	Length         int        `json:"length" validate:"omitempty,min=6,max=32"`    // the random part of the code, 10 when empty
+	MaxUses        int        `json:"maxUses" validate:"omitempty,min=0"`
+	MaxUsesPerUser int        `json:"maxUsesPerUser" validate:"omitempty,min=0"`
+	ExpiresAt      *time.Time `json:"expiresAt" validate:"omitempty"`
+	ProductIds     []string   `json:"productIds" validate:"omitempty,dive,uuid"`
+	CategoryIds    []string   `json:"categoryIds" validate:"omitempty,dive,uuid"`
+}
+
+type GenerateResponse struct {
+	Batch string   `json:"batch"`
+	Codes []string `json:"codes"`
+}
+
+type Redemption struct {
+	Id        string      `json:"id" db:"id"`
+	CouponId  string      `json:"couponId" db:"coupon_id"`
+	UserId    string      `json:"userId" db:"user_id"`
+	Reference string      `json:"reference" db:"reference"`
+	Discount  money.Money `json:"discount" db:"discount"`
+	CreatedAt time.Time   `json:"createdAt" db:"created_at"`
+}
+
+type ValidateRequest struct {
+	Code  string             `json:"code" validate:"required,max=64"`
+	Lines []promo.BasketLine `json:"lines" validate:"required,min=1,max=200,dive"`
+}
+
+type ValidateResponse struct {
+	Valid    bool          `json:"valid"`
+	Reason   string        `json:"reason"`   // why the coupon cannot be used
+	Discount money.Money   `json:"discount"` // the discount of the coupon
+	Result   *promo.Result `json:"result"`   // the basket with the coupon and the running promotions
+}
+
+type RedeemRequest struct {
+	Code      string             `json:"code" validate:"required,max=64"`
+	Reference string             `json:"reference" validate:"required,max=255"` // what the coupon is used for, e.g. an order id
+	Lines     []promo.BasketLine `json:"lines" validate:"required,min=1,max=200,dive"`
+}
+
+type RedeemResponse struct {
+	Redemption Redemption    `json:"redemption"`
+	Result     *promo.Result `json:"result"`
+}
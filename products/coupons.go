@@ -0,0 +1,194 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/products/coupon"
+)
+
+// =====================================================================================================================
+// COUPONS
+// =====================================================================================================================
+
+// CreateCoupon - Create a coupon for a promotion, with a generated code when none is given
+//
+//	@param ctx - context.Context
+//	@param payload - *coupon.CouponRequest
+//	@return coupon
+//	@return error
+//
+// encore:api auth method=POST path=/coupons
+func CreateCoupon(ctx context.Context, payload *coupon.CouponRequest) (*coupon.Coupon, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &coupon.Coupon{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &coupon.Coupon{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the coupon
+	c, err := coupon.Create(ctx, payload)
+	if err != nil {
+		return &coupon.Coupon{}, couponError(err)
+	}
+
+	return c, nil
+}
+
+// GenerateCoupons - Generate a batch of coupons with random codes for a promotion
+//
+//	@param ctx - context.Context
+//	@param payload - *coupon.GenerateRequest
+//	@return codes
+//	@return error
+//
+// encore:api auth method=POST path=/coupons/generate
+func GenerateCoupons(ctx context.Context, payload *coupon.GenerateRequest) (*coupon.GenerateResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &coupon.GenerateResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &coupon.GenerateResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// generate the coupons
+	response, err := coupon.Generate(ctx, payload)
+	if err != nil {
+		return &coupon.GenerateResponse{}, couponError(err)
+	}
+
+	return response, nil
+}
+
+// GetCoupon - Get a coupon
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return coupon
+//	@return error
+//
+// encore:api auth method=GET path=/coupons/:id
+func GetCoupon(ctx context.Context, id string) (*coupon.Coupon, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &coupon.Coupon{}, err
+	}
+
+	// get the coupon
+	c, err := coupon.Get(ctx, id)
+	if err != nil {
+		return &coupon.Coupon{}, couponError(err)
+	}
+
+	return c, nil
+}
+
+// DeactivateCoupon - Stop a coupon from being used
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return error
+//
+// encore:api auth method=POST path=/coupons/:id/deactivate
+func DeactivateCoupon(ctx context.Context, id string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// deactivate the coupon
+	if err := coupon.Deactivate(ctx, id); err != nil {
+		return couponError(err)
+	}
+
+	return nil
+}
+
+// ValidateCoupon - Check a coupon can be used for a cart and preview its discount
+//
+//	@param ctx - context.Context
+//	@param payload - *coupon.ValidateRequest
+//	@return response
+//	@return error
+//
+// encore:api auth method=POST path=/coupons/validate
+func ValidateCoupon(ctx context.Context, payload *coupon.ValidateRequest) (*coupon.ValidateResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &coupon.ValidateResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &coupon.ValidateResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// check the coupon
+	response, err := coupon.Validate(ctx, claims.Subject.Id, payload, time.Now())
+	if err != nil {
+		return &coupon.ValidateResponse{}, couponError(err)
+	}
+
+	return response, nil
+}
+
+// RedeemCoupon - Use a coupon for a cart
+//
+//	@param ctx - context.Context
+//	@param payload - *coupon.RedeemRequest
+//	@return response
+//	@return error
+//
+// encore:api auth method=POST path=/coupons/redeem
+func RedeemCoupon(ctx context.Context, payload *coupon.RedeemRequest) (*coupon.RedeemResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &coupon.RedeemResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &coupon.RedeemResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// use the coupon
+	response, err := coupon.Redeem(ctx, claims.Subject.Id, payload, time.Now())
+	if err != nil {
+		return &coupon.RedeemResponse{}, couponError(err)
+	}
+
+	return response, nil
+}
+
+// couponError - maps coupon store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func couponError(err error) error {
+	switch {
+	case errors.Is(err, coupon.ErrNotFound), errors.Is(err, coupon.ErrPromotionNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, coupon.ErrAlreadyExists), errors.Is(err, coupon.ErrAlreadyRedeemed):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, coupon.ErrNotApplicable):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	case errors.Is(err, coupon.ErrExhausted):
+		return &errs.Error{Code: errs.ResourceExhausted, Message: err.Error()}
+	default:
+		return promotionError(err)
+	}
+}
@@ -8,17 +8,20 @@ import (
 	"strings"
 	"time"
 
-	"encore.app/pkg/pagination"
-
 	"encore.dev/storage/sqldb"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
+	"encore.app/pkg/condition"
 	"encore.app/pkg/database"
+	"encore.app/pkg/pagination"
+	"encore.app/pkg/slice"
 )
 
-// get the service name
-var categoriesDatabase = sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+// the products database
+var categoriesDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
 
 // FindOneByField - get user by field
 //
@@ -42,7 +45,7 @@ func FindOneByField(ctx context.Context, field, ops string, value interface{}) (
 	// declare category
 	var category Category
 	// execute query
-	if err := database.NamedStructQuery(ctx, categoriesDatabase, q, data, &category); err != nil {
+	if err := database.NamedStructQuery(ctx, categoriesDatabase(), q, data, &category); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
 			return Category{}, ErrNotFound
 		}
@@ -74,7 +77,7 @@ func FindManyByField(ctx context.Context, field, ops string, value interface{})
 	// declare categories
 	var categories []Category
 	// execute query
-	if err := database.NamedStructQuery(ctx, categoriesDatabase, q, data, &categories); err != nil {
+	if err := database.NamedStructQuery(ctx, categoriesDatabase(), q, data, &categories); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
 			return []Category{}, ErrNotFound
 		}
@@ -92,7 +95,7 @@ func FindManyByField(ctx context.Context, field, ops string, value interface{})
 func Create(ctx context.Context, payload *CategoryRequest) error {
 	// check if category already exists
 	cat, err := FindOneByField(ctx, "name", "=", strings.ToLower(payload.Name))
-	if err == nil {
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		return err
 	}
 	// check if category ID is empty (if not, category already exists)
@@ -116,7 +119,7 @@ func Create(ctx context.Context, payload *CategoryRequest) error {
   `
 
 	// create category
-	if err := database.NamedExecQuery(ctx, categoriesDatabase, query, category); err != nil {
+	if err := database.NamedExecQuery(ctx, categoriesDatabase(), query, category); err != nil {
 		return fmt.Errorf("creating category: %w", err)
 	}
 
@@ -158,44 +161,92 @@ func GetMany(ctx context.Context, ids []string) ([]Category, error) {
 }
 
 // Delete - Delete is a function that deletes a category.
+// Products still in the category are moved to reassignTo when it is set, otherwise the
+// category is left untouched and ErrHasProducts is returned.
 //
 // @param ctx - context.Context
 // @param id - string
+// @param reassignTo - string
 // @return error
-func Delete(ctx context.Context, id string) error {
-	// query statement to be executed
-	q := "DELETE FROM categories WHERE id = :id"
-
-	// execute query
-	if err := database.NamedExecQuery(ctx, categoriesDatabase, q, map[string]interface{}{"id": id}); err != nil {
-		return fmt.Errorf("deleting task: %w", err)
+func Delete(ctx context.Context, id, reassignTo string) error {
+	// check if category exists
+	if _, err := FindOneByField(ctx, "id", "=", id); err != nil {
+		return fmt.Errorf("selecting category: %w", err)
 	}
 
-	// Delete was successful
-	return nil
+	// Delete the category
+	return DeleteMany(ctx, []string{id}, reassignTo)
 }
 
 // DeleteMany - DeleteMany is a function that deletes many categories.
+// Products still in any of the categories are moved to reassignTo when it is set, otherwise
+// none of the categories are deleted and ErrHasProducts is returned.
 //
 // @param ctx - context.Context
 // @param ids - []string
+// @param reassignTo - string
 // @return error
-func DeleteMany(ctx context.Context, ids []string) error {
-	// query statement to be executed
-	q := `
-    DELETE FROM categories
-    WHERE id IN (:ids)
-  `
+func DeleteMany(ctx context.Context, ids []string, reassignTo string) error {
+	// check the category products are moved into
+	if len(strings.TrimSpace(reassignTo)) > 0 {
+		// the target category cannot be one of the deleted categories
+		if slice.Contains(ids, reassignTo) {
+			return fmt.Errorf("reassigning products: %w", database.ErrForbidden)
+		}
 
-	// execute query
-	if err := database.NamedExecQuery(ctx, categoriesDatabase, q, map[string]interface{}{
-		"ids": ids,
-	}); err != nil {
-		return fmt.Errorf("deleting categories: %w", err)
+		// check if the target category exists
+		if _, err := FindOneByField(ctx, "id", "=", reassignTo); err != nil {
+			return fmt.Errorf("selecting category: %w", err)
+		}
 	}
 
-	// Delete was successful
-	return nil
+	// data to be passed to the queries
+	data := map[string]interface{}{
+		"ids":         ids,
+		"reassign_to": reassignTo,
+	}
+
+	// delete the categories and move their products in one transaction
+	return database.Transaction(ctx, categoriesDatabase(), func(tx *sqlx.Tx) error {
+		// count the products still in the categories
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM products WHERE category_id = ANY(CAST(:ids AS UUID[]))", data)
+		if err != nil {
+			return fmt.Errorf("getting count of products: %w", err)
+		}
+
+		// refuse to delete categories with products unless they can be reassigned
+		if count > 0 {
+			if len(strings.TrimSpace(reassignTo)) < 1 {
+				return ErrHasProducts
+			}
+
+			// query statement to be executed
+			q := `
+        UPDATE products
+        SET category_id = :reassign_to, updated_at = NOW()
+        WHERE category_id = ANY(CAST(:ids AS UUID[]))
+      `
+
+			// execute query
+			if err := database.NamedExecQuery(ctx, tx, q, data); err != nil {
+				return fmt.Errorf("reassigning products: %w", err)
+			}
+		}
+
+		// query statement to be executed
+		q := `
+      DELETE FROM categories
+      WHERE id = ANY(CAST(:ids AS UUID[]))
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, data); err != nil {
+			return fmt.Errorf("deleting categories: %w", err)
+		}
+
+		// Delete was successful
+		return nil
+	})
 }
 
 // Update - Update is a function that updates a category.
@@ -241,11 +292,14 @@ func Update(ctx context.Context, id string, payload *UpdateCategoryRequest) erro
 		ks = append(ks, fmt.Sprintf("%v = :%v", k, k))
 	}
 
+	// the id is bound after the set fields so it is not updated
+	fields["id"] = category.Id
+
 	// query statement to be executed
-	q := fmt.Sprintf("UPDATE categories SET %v WHERE id = :%v RETURNING *", strings.Join(ks, ", "), category.Id)
+	q := fmt.Sprintf("UPDATE categories SET %v WHERE id = :id", strings.Join(ks, ", "))
 
 	// execute query
-	if err := database.NamedExecQuery(ctx, categoriesDatabase, q, fields); err != nil {
+	if err := database.NamedExecQuery(ctx, categoriesDatabase(), q, fields); err != nil {
 		return fmt.Errorf("updating category: %w", err)
 	}
 
@@ -253,59 +307,102 @@ func Update(ctx context.Context, id string, payload *UpdateCategoryRequest) erro
 	return nil
 }
 
-// GetAll - GetAll is a function that gets all users.
+// categoriesSchema - the fields of a category allowed in list queries
+var categoriesSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "name", Column: "name", Type: pagination.String, Sortable: true},
+		{Name: "description", Column: "description", Type: pagination.String},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "name",
+}
+
+// GetAll - GetAll is a function that gets all categories.
 //
 //	@param ctx - context.Context
-//	@return users
+//	@param params - *CategoriesQuery
+//	@return categories
 //	@return error
-func GetAll(ctx context.Context, pag *pagination.Options) (*PaginatedCategoriesResponse, error) {
+func GetAll(ctx context.Context, params *CategoriesQuery) (*PaginatedCategoriesResponse, error) {
 	var categories []Category
 
-	// create query
-	countQuery := `SELECT COUNT(*) FROM categories`
-
-	// get total count of users
-	// get count of categories
-	count, err := database.NamedCountQuery(ctx, categoriesDatabase, countQuery, map[string]interface{}{})
+	// parse the filter, sort and field selection
+	statement, err := categoriesSchema.Parse(pagination.Query{
+		Filter: params.Filter,
+		Sort:   params.Sort,
+		Fields: params.Fields,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("getting count of categories: %w", err)
+		return nil, err
+	}
+
+	// filter by name when searching
+	if search := strings.TrimSpace(params.Search); len(search) > 0 {
+		statement.AddCondition("name ILIKE :search", map[string]interface{}{
+			"search": "%" + strings.ToLower(search) + "%",
+		})
+	}
+
+	// get count of categories unless it is skipped
+	count := 0
+	if !params.SkipTotal {
+		count, err = database.NamedCountQuery(ctx, categoriesDatabase(), fmt.Sprintf("SELECT COUNT(*) FROM categories %v", statement.Where()), statement.Args)
+		if err != nil {
+			return nil, fmt.Errorf("getting count of categories: %w", err)
+		}
 	}
 
-	// set limit to 20 if it is less than 0 or greater than count
-	if pag.Limit < 1 || pag.Limit > count {
-		pag.Limit = 50
+	// set limit to 50 if it is less than 1 or greater than the maximum
+	if params.Limit < 1 || params.Limit > pagination.MaxLimit {
+		params.Limit = 50
 	}
 
-	// calculate for pagination
-	// var paginate pagination.Paginate
 	// initialize pagination
-	paging := pagination.New(pag.Page, pag.Limit, count)
+	paging := pagination.New(params.Page, params.Limit, count)
 
-	// if page is greater than total pages, set page to total pages
-	if pag.Page > paging.Pages() {
-		paging.SetPage(paging.Pages())
+	// continue from the cursor or from the offset of the page
+	if len(params.Cursor) > 0 {
+		if err := statement.ApplyCursor(params.Cursor); err != nil {
+			return nil, err
+		}
+	} else {
+		statement.Offset = pagination.Offset(params.Page, params.Limit, condition.Ternary(params.SkipTotal, 0, paging.Pages()))
 	}
 
-	// query to set offset and limit
-	const query = `SELECT * FROM categories LIMIT :limit OFFSET :offset`
+	// query to set filter, order, offset and limit, fetching one more category to find the next page
+	query := fmt.Sprintf("SELECT %v FROM categories %v %v LIMIT :limit OFFSET :offset", statement.Select(), statement.Where(), statement.OrderBy())
 	// data to be passed to the query
-	p := struct {
-		Limit  int `db:"limit" json:"limit" validate:"omitempty" url:"limit"`
-		Offset int `db:"offset" json:"offset" validate:"omitempty" url:"offset"`
-	}{
-		Limit:  paging.PerPage(),
-		Offset: paging.Offset(),
-	}
+	statement.Args["limit"] = params.Limit + 1
+	statement.Args["offset"] = statement.Offset
 
 	// execute query
-	if err := database.NamedSliceQuery(ctx, categoriesDatabase, query, p, &categories); err != nil {
+	if err := database.NamedSliceQuery(ctx, categoriesDatabase(), query, statement.Args, &categories); err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+
+	// create the cursors of the previous and next pages
+	window, err := statement.Window(&categories, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+
+	// leave out the fields not selected
+	data, err := statement.Project(categories)
+	if err != nil {
 		return nil, fmt.Errorf("getting categories: %w", err)
 	}
 
 	return &PaginatedCategoriesResponse{
-		TotalPages:  paging.Pages(),
-		Total:       paging.Total(),
-		CurrentPage: paging.Page(),
-		Categories:  categories,
+		TotalPages:      condition.Ternary(params.SkipTotal, 0, paging.Pages()),
+		Total:           count,
+		CurrentPage:     condition.Ternary(len(params.Cursor) > 0, 0, statement.Offset/params.Limit+1),
+		HasPreviousPage: window.HasPrevious,
+		HasNextPage:     window.HasNext,
+		PrevCursor:      window.PrevCursor,
+		NextCursor:      window.NextCursor,
+		Categories:      data,
 	}, nil
 }
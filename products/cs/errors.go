@@ -5,4 +5,5 @@ import "errors"
 var (
 	ErrNotFound      = errors.New("category not found")
 	ErrAlreadyExists = errors.New("category already exists")
+	ErrHasProducts   = errors.New("category still has products")
 )
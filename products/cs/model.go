@@ -1,6 +1,9 @@
 package cs
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Category struct {
 	Id          string    `json:"id" db:"id"`
@@ -20,9 +23,34 @@ type UpdateCategoryRequest struct {
 	Description string `json:"description" db:"description" validate:"omitempty"`
 }
 
+type CategoriesQuery struct {
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page   int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Search string `json:"search" query:"search" validate:"omitempty"`     // matches part of the category name
+	Filter string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. createdAt>=2024-01-01
+	Sort   string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -createdAt,name
+	Fields string `json:"fields" query:"fields" validate:"omitempty"`     // e.g. id,name
+	Cursor string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the categories
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type DeleteCategoriesRequest struct {
+	Ids        []string `json:"ids" validate:"required,min=1,dive,uuid"`
+	ReassignTo string   `json:"reassignTo" validate:"omitempty,uuid"` // category to move remaining products into
+}
+
+type DeleteCategoryRequest struct {
+	ReassignTo string `json:"reassignTo" query:"reassignTo" validate:"omitempty,uuid"` // category to move remaining products into
+}
+
 type PaginatedCategoriesResponse struct {
-	Categories  []Category `json:"data"`
-	Total       int        `json:"total" db:"total"`
-	TotalPages  int        `json:"totalPages" db:"total_pages"`
-	CurrentPage int        `json:"currentPage" db:"current_page"`
+	Categories      []json.RawMessage `json:"data"` // categories with the fields selected
+	Total           int               `json:"total" db:"total"`
+	TotalPages      int               `json:"totalPages" db:"total_pages"`
+	CurrentPage     int               `json:"currentPage" db:"current_page"`
+	HasPreviousPage bool              `json:"hasPreviousPage" db:"hasPreviousPage"`
+	HasNextPage     bool              `json:"hasNextPage" db:"hasNextPage"`
+	PrevCursor      string            `json:"prevCursor" db:"prevCursor"`
+	NextCursor      string            `json:"nextCursor" db:"nextCursor"`
 }
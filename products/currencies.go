@@ -0,0 +1,181 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/pkg/money"
+	"encore.app/products/fx"
+)
+
+// =====================================================================================================================
+// CURRENCIES
+// =====================================================================================================================
+
+// SetExchangeRate - Set the exchange rate of a currency pair now or at a later time
+//
+//	@param ctx - context.Context
+//	@param payload - *fx.RateRequest
+//	@return rate
+//	@return error
+//
+// encore:api auth method=POST path=/exchange-rates
+func SetExchangeRate(ctx context.Context, payload *fx.RateRequest) (*fx.Rate, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &fx.Rate{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &fx.Rate{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// set the rate
+	rate, err := fx.SetRate(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &fx.Rate{}, currencyError(err)
+	}
+
+	return rate, nil
+}
+
+// ListExchangeRates - List the current exchange rates
+//
+//	@param ctx - context.Context
+//	@return rates
+//	@return error
+//
+// encore:api public method=GET path=/exchange-rates
+func ListExchangeRates(ctx context.Context) (*fx.RatesResponse, error) {
+	// get the rates
+	rates, err := fx.CurrentRates(ctx, time.Now())
+	if err != nil {
+		return &fx.RatesResponse{}, err
+	}
+
+	return &fx.RatesResponse{Rates: rates}, nil
+}
+
+// GetExchangeRateHistory - Get every rate of a currency pair, including scheduled rates
+//
+//	@param ctx - context.Context
+//	@param params - *fx.RateHistoryQuery
+//	@return rates
+//	@return error
+//
+// encore:api auth method=GET path=/exchange-rates/history
+func GetExchangeRateHistory(ctx context.Context, params *fx.RateHistoryQuery) (*fx.RatesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &fx.RatesResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &fx.RatesResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the rates
+	rates, err := fx.History(ctx, params.Base, params.Quote)
+	if err != nil {
+		return &fx.RatesResponse{}, err
+	}
+
+	return &fx.RatesResponse{Rates: rates}, nil
+}
+
+// SetCurrencyPrice - Set the price of a product in a currency other than its own
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *fx.CurrencyPriceRequest
+//	@return price
+//	@return error
+//
+// encore:api auth method=PUT path=/products/:id/currency-prices
+func SetCurrencyPrice(ctx context.Context, id string, payload *fx.CurrencyPriceRequest) (*fx.CurrencyPrice, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &fx.CurrencyPrice{}, err
+	}
+
+	// set the price
+	price, err := fx.SetPrice(ctx, id, payload.Price)
+	if err != nil {
+		return &fx.CurrencyPrice{}, currencyError(err)
+	}
+
+	return price, nil
+}
+
+// ListCurrencyPrices - List the prices of a product in other currencies
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return prices
+//	@return error
+//
+// encore:api auth method=GET path=/products/:id/currency-prices
+func ListCurrencyPrices(ctx context.Context, id string) (*fx.CurrencyPricesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &fx.CurrencyPricesResponse{}, err
+	}
+
+	// get the prices
+	prices, err := fx.Prices(ctx, id)
+	if err != nil {
+		return &fx.CurrencyPricesResponse{}, err
+	}
+
+	return &fx.CurrencyPricesResponse{
+		ProductId: id,
+		Prices:    prices,
+	}, nil
+}
+
+// DeleteCurrencyPrice - Remove the price of a product in a currency, so it is converted again
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param currency - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/products/:id/currency-prices/:currency
+func DeleteCurrencyPrice(ctx context.Context, id, currency string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// delete the price
+	if err := fx.DeletePrice(ctx, id, currency); err != nil {
+		return currencyError(err)
+	}
+
+	return nil
+}
+
+// currencyError - maps currency store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func currencyError(err error) error {
+	switch {
+	case errors.Is(err, fx.ErrNotFound), errors.Is(err, fx.ErrProductNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, fx.ErrInvalidRate), errors.Is(err, fx.ErrInvalidPrice), errors.Is(err, money.ErrUnknownCurrency):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, fx.ErrSameCurrency), errors.Is(err, fx.ErrNoRate):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,70 @@
+package products
+
+import (
+	"context"
+	"time"
+
+	"encore.dev/cron"
+	"encore.dev/pubsub"
+	"encore.dev/rlog"
+
+	"encore.app/pkg/events"
+	"encore.app/pkg/events/topics"
+	"encore.app/pkg/outbox"
+	"encore.app/products/cleanup"
+	"encore.app/products/ps"
+)
+
+// =====================================================================================================================
+// EVENTS
+// =====================================================================================================================
+
+// publish the events saved with the changes of the products
+var _ = cron.NewJob("relay-product-events", cron.JobConfig{
+	Title:    "Publish the events of the products",
+	Every:    1 * cron.Minute,
+	Endpoint: RelayProductEvents,
+})
+
+// RelayProductEvents - Publish the events saved in the outbox of the products
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/products/events/relay
+func RelayProductEvents(ctx context.Context) (*outbox.RelayResponse, error) {
+	// publish the events
+	response, err := ps.RelayEvents(ctx, topics.Publish, time.Now())
+	if err != nil {
+		return &outbox.RelayResponse{}, err
+	}
+
+	rlog.Info("products.RelayProductEvents", "sent", response.Sent, "failed", response.Failed, "purged", response.Purged)
+
+	return response, nil
+}
+
+// remove what the products keep for a deleted user
+var _ = pubsub.NewSubscription(topics.UserDeleted, "products-user-cleanup", pubsub.SubscriptionConfig[*events.UserDeleted]{
+	Handler: CleanupDeletedUser,
+})
+
+// CleanupDeletedUser - Remove the cart, lists, reviews and purchases of a deleted user
+//
+//	@param ctx - context.Context
+//	@param event - *events.UserDeleted
+//	@return error
+func CleanupDeletedUser(ctx context.Context, event *events.UserDeleted) error {
+	if !events.Accept(event.Meta, 1) {
+		return nil
+	}
+
+	if err := cleanup.DeleteUser(ctx, "products-user-cleanup", event); err != nil {
+		return err
+	}
+
+	rlog.Info("products.CleanupDeletedUser", "id", event.Meta.Id, "user", event.UserId)
+
+	return nil
+}
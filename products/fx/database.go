@@ -0,0 +1,395 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/money"
+)
+
+// the products database
+var fxDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// SetRate - SetRate is a function that sets the exchange rate of a currency pair.
+// Rates are never overwritten, so the history of a pair is kept.
+//
+// @param ctx - context.Context
+// @param createdBy - string
+// @param payload - *RateRequest
+// @return rate
+// @return error
+func SetRate(ctx context.Context, createdBy string, payload *RateRequest) (*Rate, error) {
+	base := strings.ToUpper(strings.TrimSpace(payload.Base))
+	quote := strings.ToUpper(strings.TrimSpace(payload.Quote))
+
+	// check the currencies and the rate
+	if !money.IsCurrency(base) || !money.IsCurrency(quote) || base == quote {
+		return nil, ErrInvalidRate
+	}
+	if _, err := money.ParseRate(payload.Rate); err != nil {
+		return nil, ErrInvalidRate
+	}
+
+	// create the rate
+	now := time.Now().UTC()
+	rate := Rate{
+		Id:            uuid.New().String(),
+		Base:          base,
+		Quote:         quote,
+		Rate:          strings.TrimSpace(payload.Rate),
+		EffectiveFrom: now,
+		CreatedBy:     createdBy,
+		CreatedAt:     now,
+	}
+	if payload.EffectiveFrom != nil {
+		rate.EffectiveFrom = payload.EffectiveFrom.UTC()
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO exchange_rates (id, base, quote, rate, effective_from, created_by, created_at)
+    VALUES (:id, :base, :quote, :rate, :effective_from, :created_by, :created_at)
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, fxDatabase(), q, rate); err != nil {
+		return nil, fmt.Errorf("inserting exchange rate: %w", err)
+	}
+
+	return &rate, nil
+}
+
+// CurrentRates - CurrentRates is a function that gets the latest started rate of every currency pair.
+//
+// @param ctx - context.Context
+// @param at - time.Time
+// @return rates
+// @return error
+func CurrentRates(ctx context.Context, at time.Time) ([]Rate, error) {
+	rates := make([]Rate, 0)
+
+	// query statement to be executed
+	q := `
+    SELECT DISTINCT ON (base, quote) * FROM exchange_rates
+    WHERE effective_from <= :at
+    ORDER BY base, quote, effective_from DESC, created_at DESC
+  `
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, fxDatabase(), q, map[string]interface{}{"at": at.UTC()}, &rates); err != nil {
+		return nil, fmt.Errorf("selecting exchange rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// History - History is a function that gets every rate of a currency pair, latest first.
+//
+// @param ctx - context.Context
+// @param base - string
+// @param quote - string
+// @return rates
+// @return error
+func History(ctx context.Context, base, quote string) ([]Rate, error) {
+	rates := make([]Rate, 0)
+
+	// query statement to be executed
+	q := `
+    SELECT * FROM exchange_rates
+    WHERE base = :base AND quote = :quote
+    ORDER BY effective_from DESC, created_at DESC
+  `
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, fxDatabase(), q, map[string]interface{}{
+		"base":  strings.ToUpper(base),
+		"quote": strings.ToUpper(quote),
+	}, &rates); err != nil {
+		return nil, fmt.Errorf("selecting exchange rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// SetPrice - SetPrice is a function that sets the price of a product in a currency other than its own.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param price - money.Money
+// @return price
+// @return error
+func SetPrice(ctx context.Context, productId string, price money.Money) (*CurrencyPrice, error) {
+	// check the price
+	if err := price.Validate(); err != nil || price.Amount <= 0 {
+		return nil, ErrInvalidPrice
+	}
+
+	// get the price of the product
+	var product struct {
+		Price money.Money `db:"price"`
+	}
+	if err := database.NamedStructQuery(ctx, fxDatabase(), "SELECT price FROM products WHERE id = :id", map[string]interface{}{
+		"id": productId,
+	}, &product); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, fmt.Errorf("selecting product: %w", err)
+	}
+
+	// the price in the currency of the product is changed with the price list
+	if product.Price.Currency == price.Currency {
+		return nil, ErrSameCurrency
+	}
+
+	// create the price
+	now := time.Now().UTC()
+	currencyPrice := CurrencyPrice{
+		ProductId: productId,
+		Currency:  price.Currency,
+		Price:     price,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO product_currency_prices (product_id, currency, price, created_at, updated_at)
+    VALUES (:product_id, :currency, :price, :created_at, :updated_at)
+    ON CONFLICT (product_id, currency) DO UPDATE SET price = EXCLUDED.price, updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, fxDatabase(), q, currencyPrice); err != nil {
+		return nil, fmt.Errorf("setting currency price: %w", err)
+	}
+
+	return &currencyPrice, nil
+}
+
+// DeletePrice - DeletePrice is a function that removes the price of a product in a currency,
+// so the price is converted from the price of the product again.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param currency - string
+// @return error
+func DeletePrice(ctx context.Context, productId, currency string) error {
+	data := map[string]interface{}{
+		"product_id": productId,
+		"currency":   strings.ToUpper(currency),
+	}
+
+	// check if the price exists
+	count, err := database.NamedCountQuery(ctx, fxDatabase(), "SELECT COUNT(*) FROM product_currency_prices WHERE product_id = :product_id AND currency = :currency", data)
+	if err != nil {
+		return fmt.Errorf("selecting currency price: %w", err)
+	}
+	if count < 1 {
+		return ErrNotFound
+	}
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, fxDatabase(), "DELETE FROM product_currency_prices WHERE product_id = :product_id AND currency = :currency", data); err != nil {
+		return fmt.Errorf("deleting currency price: %w", err)
+	}
+
+	return nil
+}
+
+// Prices - Prices is a function that gets the prices of a product in other currencies.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @return prices
+// @return error
+func Prices(ctx context.Context, productId string) ([]CurrencyPrice, error) {
+	prices := make([]CurrencyPrice, 0)
+
+	// query statement to be executed
+	q := "SELECT * FROM product_currency_prices WHERE product_id = :product_id ORDER BY currency"
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, fxDatabase(), q, map[string]interface{}{"product_id": productId}, &prices); err != nil {
+		return nil, fmt.Errorf("selecting currency prices: %w", err)
+	}
+
+	return prices, nil
+}
+
+// Localize - Localize is a function that replaces the prices of products with their prices in a currency.
+// Prices set for the currency are used first, other prices are converted with the current exchange rates.
+//
+// @param ctx - context.Context
+// @param currency - string
+// @param prices - the prices to replace by product id
+// @return error
+func Localize(ctx context.Context, currency string, prices map[string]*money.Money) error {
+	// the prices are kept in the currency of the catalog
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) < 1 || len(prices) < 1 {
+		return nil
+	}
+	if !money.IsCurrency(currency) {
+		return fmt.Errorf("%w: %v", money.ErrUnknownCurrency, currency)
+	}
+
+	// get the prices set for the currency
+	ids := make([]string, 0, len(prices))
+	for id := range prices {
+		ids = append(ids, id)
+	}
+	var overrides []CurrencyPrice
+	q := "SELECT * FROM product_currency_prices WHERE currency = :currency AND product_id = ANY(CAST(:ids AS UUID[]))"
+	if err := database.NamedSliceQuery(ctx, fxDatabase(), q, map[string]interface{}{
+		"currency": currency,
+		"ids":      ids,
+	}, &overrides); err != nil {
+		return fmt.Errorf("selecting currency prices: %w", err)
+	}
+	for _, override := range overrides {
+		*prices[override.ProductId] = override.Price
+	}
+
+	// convert the other prices, loading the rates only when needed
+	var table rateTable
+	for _, price := range prices {
+		if price.Currency == currency {
+			continue
+		}
+
+		if table == nil {
+			rates, err := CurrentRates(ctx, time.Now())
+			if err != nil {
+				return err
+			}
+			if table, err = newRateTable(rates); err != nil {
+				return err
+			}
+		}
+
+		rate, ok := table.lookup(price.Currency, currency)
+		if !ok {
+			return fmt.Errorf("%w: %v to %v", ErrNoRate, price.Currency, currency)
+		}
+		converted, err := price.Convert(currency, rate, money.HalfUp)
+		if err != nil {
+			return fmt.Errorf("converting price: %w", err)
+		}
+		*price = converted
+	}
+
+	return nil
+}
+
+// Convert - Convert is a function that converts an amount into a currency with the exchange rates current at a time.
+//
+// @param ctx - context.Context
+// @param amount - money.Money
+// @param currency - the currency to convert into
+// @param at - time.Time
+// @return the converted amount
+// @return error
+func Convert(ctx context.Context, amount money.Money, currency string, at time.Time) (money.Money, error) {
+	if amount.Currency == currency {
+		return amount, nil
+	}
+
+	// get the rates
+	rates, err := CurrentRates(ctx, at)
+	if err != nil {
+		return money.Money{}, err
+	}
+	table, err := newRateTable(rates)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	rate, ok := table.lookup(amount.Currency, currency)
+	if !ok {
+		return money.Money{}, fmt.Errorf("%w: %v to %v", ErrNoRate, amount.Currency, currency)
+	}
+	converted, err := amount.Convert(currency, rate, money.HalfUp)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("converting amount: %w", err)
+	}
+
+	return converted, nil
+}
+
+// rateTable - the exchange rates by base and quote currency
+type rateTable map[[2]string]*big.Rat
+
+// newRateTable - creates a rate table from rates.
+//
+// @param rates - []Rate
+// @return rateTable
+// @return error
+func newRateTable(rates []Rate) (rateTable, error) {
+	table := make(rateTable, len(rates))
+	for _, rate := range rates {
+		value, err := money.ParseRate(rate.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing exchange rate %v: %w", rate.Id, err)
+		}
+		table[[2]string{rate.Base, rate.Quote}] = value
+	}
+
+	return table, nil
+}
+
+// lookup - returns the rate from base to quote. The rate of the opposite pair is inverted when there is
+// no rate for the pair, and pairs without either are converted through the default currency.
+//
+// @param base - string
+// @param quote - string
+// @return *big.Rat
+// @return bool
+func (table rateTable) lookup(base, quote string) (*big.Rat, bool) {
+	if rate, ok := table.direct(base, quote); ok {
+		return rate, true
+	}
+
+	// cross the rates through the default currency
+	if base == money.DefaultCurrency || quote == money.DefaultCurrency {
+		return nil, false
+	}
+	first, ok := table.direct(base, money.DefaultCurrency)
+	if !ok {
+		return nil, false
+	}
+	second, ok := table.direct(money.DefaultCurrency, quote)
+	if !ok {
+		return nil, false
+	}
+
+	return new(big.Rat).Mul(first, second), true
+}
+
+// direct - returns the rate of the pair, or the inverted rate of the opposite pair.
+//
+// @param base - string
+// @param quote - string
+// @return *big.Rat
+// @return bool
+func (table rateTable) direct(base, quote string) (*big.Rat, bool) {
+	if rate, ok := table[[2]string{base, quote}]; ok {
+		return rate, true
+	}
+	if rate, ok := table[[2]string{quote, base}]; ok {
+		return new(big.Rat).Inv(rate), true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,12 @@
+package fx
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("currency price not found")
+	ErrProductNotFound = errors.New("product not found")
+	ErrNoRate          = errors.New("no exchange rate between the currencies")
+	ErrInvalidRate     = errors.New("rate must be a positive decimal between two different supported currencies")
+	ErrInvalidPrice    = errors.New("price must be greater than zero and in a supported currency")
+	ErrSameCurrency    = errors.New("the price is already in the currency of the product")
+)
@@ -0,0 +1,54 @@
+package fx
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+type Rate struct {
+	Id            string    `json:"id" db:"id"`
+	Base          string    `json:"base" db:"base"`
+	Quote         string    `json:"quote" db:"quote"`
+	Rate          string    `json:"rate" db:"rate"`
+	EffectiveFrom time.Time `json:"effectiveFrom" db:"effective_from"`
+	CreatedBy     string    `json:"createdBy" db:"created_by"`
+	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
+}
+
+type RateRequest struct {
+	Base          string     `json:"base" validate:"required,len=3"`
+	Quote         string     `json:"quote" validate:"required,len=3"`
+	Rate          string     `json:"rate" validate:"required"`           // e.g. 0.92
+	EffectiveFrom *time.Time `json:"effectiveFrom" validate:"omitempty"` // starts immediately when empty
+}
+
+type RateHistoryQuery struct {
+	Base  string `json:"base" query:"base" validate:"required,len=3"`
+	Quote string `json:"quote" query:"quote" validate:"required,len=3"`
+}
+
+type RatesResponse struct {
+	Rates []Rate `json:"data"`
+}
+
+type CurrencyPrice struct {
+	ProductId string      `json:"productId" db:"product_id"`
+	Currency  string      `json:"currency" db:"currency"`
+	Price     money.Money `json:"price" db:"price"`
+	CreatedAt time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+type CurrencyPriceRequest struct {
+	Price money.Money `json:"price"`
+}
+
+type CurrencyPricesResponse struct {
+	ProductId string          `json:"productId"`
+	Prices    []CurrencyPrice `json:"data"`
+}
+
+type CurrencyQuery struct {
+	Currency string `json:"currency" query:"currency" validate:"omitempty,len=3"` // the currency of the prices, the catalog currency when empty
+}
@@ -0,0 +1,277 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/pkg/pagination"
+	"encore.app/products/inventory"
+)
+
+// =====================================================================================================================
+// INVENTORY
+// =====================================================================================================================
+
+// ListStockMovements - List the stock movements of a product, the newest first
+//
+//	@param ctx - context.Context
+//	@param productId - string
+//	@param params - *inventory.MovementsQuery
+//	@return movements
+//	@return error
+//
+// encore:api auth method=GET path=/inventory/:productId/movements
+func ListStockMovements(ctx context.Context, productId string, params *inventory.MovementsQuery) (*inventory.PaginatedMovementsResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &inventory.PaginatedMovementsResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &inventory.PaginatedMovementsResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the movements
+	response, err := inventory.ListMovements(ctx, productId, params)
+	if err != nil {
+		return &inventory.PaginatedMovementsResponse{}, inventoryError(err)
+	}
+
+	return response, nil
+}
+
+// AdjustStock - Correct the stock of a product at a location after counting it or finding damage
+//
+//	@param ctx - context.Context
+//	@param productId - string
+//	@param payload - *inventory.AdjustRequest
+//	@return movement
+//	@return error
+//
+// encore:api auth method=POST path=/inventory/:productId/adjust
+func AdjustStock(ctx context.Context, productId string, payload *inventory.AdjustRequest) (*inventory.Movement, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &inventory.Movement{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &inventory.Movement{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// adjust the stock
+	movement, err := inventory.Adjust(ctx, claims.Subject.Id, productId, payload)
+	if err != nil {
+		return &inventory.Movement{}, inventoryError(err)
+	}
+
+	return movement, nil
+}
+
+// GetStockLevels - Get the stock of a product at every location
+//
+//	@param ctx - context.Context
+//	@param productId - string
+//	@return levels
+//	@return error
+//
+// encore:api auth method=GET path=/inventory/:productId/stock
+func GetStockLevels(ctx context.Context, productId string) (*inventory.LevelsResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &inventory.LevelsResponse{}, err
+	}
+
+	// get the levels
+	response, err := inventory.Levels(ctx, productId)
+	if err != nil {
+		return &inventory.LevelsResponse{}, inventoryError(err)
+	}
+
+	return response, nil
+}
+
+// RecordSale - Take the products of an order out of stock, the lots expiring first, called by the checkout
+//
+//	@param ctx - context.Context
+//	@param payload - *inventory.SaleRequest
+//	@return movements
+//	@return error
+//
+// encore:api private method=POST path=/inventory/sales
+func RecordSale(ctx context.Context, payload *inventory.SaleRequest) (*inventory.SaleResponse, error) {
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &inventory.SaleResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// record the sale
+	response, err := inventory.RecordSale(ctx, payload)
+	if err != nil {
+		return &inventory.SaleResponse{}, inventoryError(err)
+	}
+
+	return response, nil
+}
+
+// ListExpiringLots - List the lots in stock expiring within some days, or already expired, to mark them down
+//
+//	@param ctx - context.Context
+//	@param params - *inventory.ExpiringQuery
+//	@return lots
+//	@return error
+//
+// encore:api auth method=GET path=/inventory/expiring
+func ListExpiringLots(ctx context.Context, params *inventory.ExpiringQuery) (*inventory.PaginatedExpiringResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &inventory.PaginatedExpiringResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &inventory.PaginatedExpiringResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the lots
+	response, err := inventory.Expiring(ctx, params, time.Now())
+	if err != nil {
+		return &inventory.PaginatedExpiringResponse{}, inventoryError(err)
+	}
+
+	return response, nil
+}
+
+// RecallLot - Find the stock left of a lot and every order it was sold in
+//
+//	@param ctx - context.Context
+//	@param params - *inventory.RecallQuery
+//	@return recall
+//	@return error
+//
+// encore:api auth method=GET path=/inventory/recalls
+func RecallLot(ctx context.Context, params *inventory.RecallQuery) (*inventory.RecallResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &inventory.RecallResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &inventory.RecallResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// find the lot
+	response, err := inventory.Recall(ctx, params)
+	if err != nil {
+		return &inventory.RecallResponse{}, inventoryError(err)
+	}
+
+	return response, nil
+}
+
+// =====================================================================================================================
+// BARCODES
+// =====================================================================================================================
+
+// ListProductBarcodes - List the barcodes printed on a product
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return barcodes
+//	@return error
+//
+// encore:api auth method=GET path=/products/:id/barcodes
+func ListProductBarcodes(ctx context.Context, id string) (*inventory.BarcodesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &inventory.BarcodesResponse{}, err
+	}
+
+	// get the barcodes
+	response, err := inventory.ListBarcodes(ctx, id)
+	if err != nil {
+		return &inventory.BarcodesResponse{}, inventoryError(err)
+	}
+
+	return response, nil
+}
+
+// AddProductBarcode - Add a barcode printed on a product, so scanners can find it
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param barcode - string
+//	@return barcode
+//	@return error
+//
+// encore:api auth method=PUT path=/products/:id/barcodes/:barcode
+func AddProductBarcode(ctx context.Context, id, barcode string) (*inventory.Barcode, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &inventory.Barcode{}, err
+	}
+
+	// validate the barcode
+	if err := validator.New().Var(barcode, "required,max=64,printascii"); err != nil {
+		return &inventory.Barcode{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// add the barcode
+	response, err := inventory.AddBarcode(ctx, id, barcode)
+	if err != nil {
+		return &inventory.Barcode{}, inventoryError(err)
+	}
+
+	return response, nil
+}
+
+// RemoveProductBarcode - Remove a barcode from a product
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param barcode - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/products/:id/barcodes/:barcode
+func RemoveProductBarcode(ctx context.Context, id, barcode string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// remove the barcode
+	if err := inventory.RemoveBarcode(ctx, id, barcode); err != nil {
+		return inventoryError(err)
+	}
+
+	return nil
+}
+
+// inventoryError - maps inventory store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func inventoryError(err error) error {
+	switch {
+	case errors.Is(err, inventory.ErrProductNotFound), errors.Is(err, inventory.ErrLocationNotFound),
+		errors.Is(err, inventory.ErrLotNotFound), errors.Is(err, inventory.ErrBarcodeNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, inventory.ErrInvalidMovement), pagination.IsQueryError(err):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, inventory.ErrAlreadyRecorded), errors.Is(err, inventory.ErrBarcodeTaken):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	default:
+		return err
+	}
+}
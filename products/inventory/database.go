@@ -0,0 +1,756 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/events"
+	"encore.app/pkg/outbox"
+	"encore.app/pkg/pagination"
+)
+
+// the products database
+var inventoryDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// location - gets a location, or the default location when the id is empty.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param id - string
+// @return the id of the location
+// @return error
+func location(ctx context.Context, db sqlx.ExtContext, id string) (string, error) {
+	q := "SELECT id FROM locations WHERE is_default"
+	if len(id) > 0 {
+		q = "SELECT id FROM locations WHERE id = :id"
+	}
+
+	var row struct {
+		Id string `db:"id"`
+	}
+	if err := database.NamedStructQuery(ctx, db, q, map[string]interface{}{"id": id}, &row); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return "", ErrLocationNotFound
+		}
+		return "", fmt.Errorf("selecting location: %w", err)
+	}
+
+	return row.Id, nil
+}
+
+// Move - Move is a function that changes the stock of a product at a location and records the movement. It is
+// given the transaction of the change causing it, e.g. receiving a purchase order, and saves a stock changed event
+// with it. The stock of the product is kept as the sum of its locations and the units in transit between them,
+// neither can go below zero, so a transfer only changes the stock at the location and saves no event. Stock going
+// in is put in its lot, and stock going out is taken from the lots expiring first.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param movement - the movement, its id, location when empty, quantity after and time are set
+// @return error
+func Move(ctx context.Context, tx *sqlx.Tx, movement *Movement) error {
+	if movement.Quantity == 0 {
+		return fmt.Errorf("%w: the quantity cannot be zero", ErrInvalidMovement)
+	}
+
+	// lock the product, which serializes the moves of its stock at every location
+	var product struct {
+		StockQuantity int `db:"stock_quantity"`
+	}
+	if err := database.NamedStructQuery(ctx, tx, "SELECT stock_quantity FROM products WHERE id = :id FOR UPDATE", map[string]interface{}{
+		"id": movement.ProductId,
+	}, &product); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrProductNotFound
+		}
+		return fmt.Errorf("selecting product: %w", err)
+	}
+
+	// get the stock at the location
+	var err error
+	if movement.LocationId, err = location(ctx, tx, movement.LocationId); err != nil {
+		return err
+	}
+	var level struct {
+		Quantity int `db:"quantity"`
+	}
+	if err := database.NamedStructQuery(ctx, tx, "SELECT quantity FROM location_stock WHERE location_id = :location_id AND product_id = :product_id", movement, &level); err != nil && !errors.Is(err, database.ErrNotFound) {
+		return fmt.Errorf("selecting location stock: %w", err)
+	}
+
+	after := level.Quantity + movement.Quantity
+	if after < 0 {
+		return fmt.Errorf("%w: %v in stock at the location", ErrInsufficientStock, level.Quantity)
+	}
+	total := product.StockQuantity + movement.Quantity
+
+	movement.Id = uuid.New().String()
+	movement.QuantityAfter = after
+	if movement.CreatedAt.IsZero() {
+		movement.CreatedAt = time.Now().UTC()
+	}
+
+	// change the stock and record the movement
+	if err := database.NamedExecQuery(ctx, tx, `
+    INSERT INTO location_stock (location_id, product_id, quantity, updated_at)
+    VALUES (:location_id, :product_id, :quantity_after, :created_at)
+    ON CONFLICT (location_id, product_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = EXCLUDED.updated_at
+  `, movement); err != nil {
+		return fmt.Errorf("upserting location stock: %w", err)
+	}
+	if movement.Reason != ReasonTransfer {
+		if err := database.NamedExecQuery(ctx, tx, "UPDATE products SET stock_quantity = stock_quantity + :quantity, updated_at = :created_at WHERE id = :product_id", movement); err != nil {
+			return fmt.Errorf("updating stock: %w", err)
+		}
+	}
+	if err := database.NamedExecQuery(ctx, tx, `
+    INSERT INTO stock_movements (id, product_id, location_id, quantity, quantity_after, reason, reason_code, reference, unit_cost, note, created_by, created_at)
+    VALUES (:id, :product_id, :location_id, :quantity, :quantity_after, :reason, :reason_code, :reference, :unit_cost, :note, :created_by, :created_at)
+  `, movement); err != nil {
+		return fmt.Errorf("inserting stock movement: %w", err)
+	}
+	if err := moveLots(ctx, tx, movement); err != nil {
+		return err
+	}
+	if movement.Reason == ReasonTransfer {
+		return nil
+	}
+
+	// let other services know
+	event := &events.StockChanged{
+		Meta:        events.NewMeta(events.TypeStockChanged, movement.CreatedAt),
+		ProductId:   movement.ProductId,
+		OldQuantity: product.StockQuantity,
+		NewQuantity: total,
+		Reason:      movement.Reason,
+		LocationId:  movement.LocationId,
+	}
+	return outbox.Add(ctx, tx, event.Meta, event)
+}
+
+// moveLots - puts the units of a movement in its lots, or takes them out of the lots expiring first, and records
+// the lots the movement went in or came out of.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param movement - the movement, its lots are set
+// @return error
+func moveLots(ctx context.Context, tx *sqlx.Tx, movement *Movement) error {
+	if movement.Quantity > 0 {
+		// put the units in the lots given, or the lot of the movement
+		if len(movement.Lots) < 1 {
+			movement.Lots = []Allocation{{LotNumber: movement.LotNumber, ExpiresAt: movement.ExpiresAt, Quantity: movement.Quantity}}
+		}
+
+		sum := 0
+		for i := range movement.Lots {
+			allocation := &movement.Lots[i]
+			if allocation.Quantity < 1 {
+				return fmt.Errorf("%w: the units of a lot must be positive", ErrInvalidMovement)
+			}
+			sum += allocation.Quantity
+
+			lot := &Lot{
+				Id:         uuid.New().String(),
+				ProductId:  movement.ProductId,
+				LocationId: movement.LocationId,
+				LotNumber:  allocation.LotNumber,
+				ExpiresAt:  Day(allocation.ExpiresAt),
+				Quantity:   allocation.Quantity,
+				CreatedAt:  movement.CreatedAt,
+				UpdatedAt:  movement.CreatedAt,
+			}
+			if err := database.NamedStructQuery(ctx, tx, `
+        INSERT INTO stock_lots (id, product_id, location_id, lot_number, expires_at, quantity, created_at, updated_at)
+        VALUES (:id, :product_id, :location_id, :lot_number, :expires_at, :quantity, :created_at, :updated_at)
+        ON CONFLICT (product_id, location_id, lot_number, COALESCE(expires_at, 'infinity'::DATE)) DO UPDATE
+        SET quantity = stock_lots.quantity + EXCLUDED.quantity, updated_at = EXCLUDED.updated_at
+        RETURNING *
+      `, lot, lot); err != nil {
+				return fmt.Errorf("upserting stock lot: %w", err)
+			}
+			allocation.LotId, allocation.ExpiresAt = lot.Id, lot.ExpiresAt
+		}
+		if sum != movement.Quantity {
+			return fmt.Errorf("%w: the units of the lots must add up to the quantity", ErrInvalidMovement)
+		}
+	} else {
+		// take the units out of the lots expiring first, never selling or sending on expired stock
+		lots := make([]Lot, 0)
+		if err := database.NamedSliceQuery(ctx, tx, `
+      SELECT * FROM stock_lots
+      WHERE product_id = :product_id AND location_id = :location_id AND quantity > 0
+        AND (:lot_number = '' OR lot_number = :lot_number)
+      FOR UPDATE
+    `, map[string]interface{}{
+			"product_id":  movement.ProductId,
+			"location_id": movement.LocationId,
+			"lot_number":  movement.LotNumber,
+		}, &lots); err != nil {
+			return fmt.Errorf("selecting stock lots: %w", err)
+		}
+		if len(movement.LotNumber) > 0 && len(lots) < 1 {
+			return fmt.Errorf("%w[%v]", ErrLotNotFound, movement.LotNumber)
+		}
+
+		sellable := movement.Reason == ReasonSale || movement.Reason == ReasonTransfer
+		var err error
+		if movement.Lots, err = Pick(lots, -movement.Quantity, movement.CreatedAt, sellable); err != nil {
+			return err
+		}
+		for _, allocation := range movement.Lots {
+			if err := database.NamedExecQuery(ctx, tx, "UPDATE stock_lots SET quantity = quantity - :quantity, updated_at = :updated_at WHERE id = :id", map[string]interface{}{
+				"id":         allocation.LotId,
+				"quantity":   allocation.Quantity,
+				"updated_at": movement.CreatedAt,
+			}); err != nil {
+				return fmt.Errorf("updating stock lot: %w", err)
+			}
+		}
+	}
+
+	// record the lots, the units signed like the movement
+	rows := make([]Allocation, 0, len(movement.Lots))
+	for _, allocation := range movement.Lots {
+		allocation.MovementId = movement.Id
+		if movement.Quantity < 0 {
+			allocation.Quantity = -allocation.Quantity
+		}
+		rows = append(rows, allocation)
+	}
+	if err := database.NamedExecQuery(ctx, tx, "INSERT INTO stock_movement_lots (movement_id, lot_id, quantity) VALUES (:movement_id, :lot_id, :quantity)", rows); err != nil {
+		return fmt.Errorf("inserting stock movement lots: %w", err)
+	}
+
+	return nil
+}
+
+// Open - Open is a function that puts the stock a product is created with at the default location, without a lot.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param productId - string
+// @param quantity - the stock of the product
+// @param at - when the product was created
+// @return error
+func Open(ctx context.Context, tx *sqlx.Tx, productId string, quantity int, at time.Time) error {
+	if quantity < 1 {
+		return nil
+	}
+
+	id, err := location(ctx, tx, "")
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{"id": uuid.New().String(), "location_id": id, "product_id": productId, "quantity": quantity, "updated_at": at}
+	if err := database.NamedExecQuery(ctx, tx, `
+    INSERT INTO location_stock (location_id, product_id, quantity, updated_at)
+    VALUES (:location_id, :product_id, :quantity, :updated_at)
+  `, data); err != nil {
+		return fmt.Errorf("inserting location stock: %w", err)
+	}
+	if err := database.NamedExecQuery(ctx, tx, `
+    INSERT INTO stock_lots (id, product_id, location_id, quantity, created_at, updated_at)
+    VALUES (:id, :product_id, :location_id, :quantity, :updated_at, :updated_at)
+  `, data); err != nil {
+		return fmt.Errorf("inserting stock lot: %w", err)
+	}
+
+	return nil
+}
+
+// Levels - Levels is a function that gets the stock of a product at every location holding or having held it, and
+// the units on their way between locations.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @return levels
+// @return error
+func Levels(ctx context.Context, productId string) (*LevelsResponse, error) {
+	data := map[string]interface{}{"product_id": productId}
+
+	// get the stock of the product
+	var product struct {
+		StockQuantity int `db:"stock_quantity"`
+	}
+	if err := database.NamedStructQuery(ctx, inventoryDatabase(), "SELECT stock_quantity FROM products WHERE id = :product_id", data, &product); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, fmt.Errorf("selecting product: %w", err)
+	}
+
+	// get the units shipped but not yet received
+	var transit struct {
+		Quantity int `db:"quantity"`
+	}
+	if err := database.NamedStructQuery(ctx, inventoryDatabase(), `
+    SELECT COALESCE(SUM(l.quantity), 0) AS quantity
+    FROM transfer_lines l
+    JOIN transfers t ON t.id = l.transfer_id
+    WHERE l.product_id = :product_id AND t.status = 'in_transit'
+  `, data, &transit); err != nil {
+		return nil, fmt.Errorf("selecting stock in transit: %w", err)
+	}
+
+	// execute query
+	response := &LevelsResponse{ProductId: productId, Total: product.StockQuantity, InTransit: transit.Quantity, Levels: make([]Level, 0)}
+	if err := database.NamedSliceQuery(ctx, inventoryDatabase(), `
+    SELECT s.location_id, l.code, l.name, l.kind, s.quantity, s.updated_at
+    FROM location_stock s
+    JOIN locations l ON l.id = s.location_id
+    WHERE s.product_id = :product_id
+    ORDER BY l.name, l.id
+  `, data, &response.Levels); err != nil {
+		return nil, fmt.Errorf("selecting location stock: %w", err)
+	}
+
+	return response, nil
+}
+
+// Adjust - Adjust is a function that corrects the stock of a product, e.g. after counting it or finding damage.
+//
+// @param ctx - context.Context
+// @param userId - the admin making the adjustment
+// @param productId - string
+// @param payload - *AdjustRequest
+// @return movement
+// @return error
+func Adjust(ctx context.Context, userId, productId string, payload *AdjustRequest) (*Movement, error) {
+	movement := &Movement{
+		ProductId:  productId,
+		LocationId: payload.LocationId,
+		Quantity:   payload.Quantity,
+		LotNumber:  payload.LotNumber,
+		ExpiresAt:  payload.ExpiresAt,
+		Reason:     ReasonAdjustment,
+		Note:       payload.Note,
+		CreatedBy:  &userId,
+	}
+
+	if err := database.Transaction(ctx, inventoryDatabase(), func(tx *sqlx.Tx) error {
+		return Move(ctx, tx, movement)
+	}); err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// attachLots - gets the lots of movements.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param movements - the movements, their lots are set
+// @return error
+func attachLots(ctx context.Context, db sqlx.ExtContext, movements []Movement) error {
+	if len(movements) < 1 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(movements))
+	for _, movement := range movements {
+		ids = append(ids, movement.Id)
+	}
+
+	// query statement to be executed
+	q := `
+    SELECT ml.movement_id, ml.lot_id, l.lot_number, l.expires_at, ABS(ml.quantity) AS quantity
+    FROM stock_movement_lots ml
+    JOIN stock_lots l ON l.id = ml.lot_id
+    WHERE ml.movement_id = ANY(CAST(:ids AS UUID[]))
+    ORDER BY l.expires_at NULLS LAST, l.created_at
+  `
+
+	// execute query
+	allocations := make([]Allocation, 0)
+	if err := database.NamedSliceQuery(ctx, db, q, map[string]interface{}{"ids": ids}, &allocations); err != nil {
+		return fmt.Errorf("selecting stock movement lots: %w", err)
+	}
+
+	byMovement := make(map[string][]Allocation, len(movements))
+	for _, allocation := range allocations {
+		byMovement[allocation.MovementId] = append(byMovement[allocation.MovementId], allocation)
+	}
+	for i := range movements {
+		movements[i].Lots = byMovement[movements[i].Id]
+		if movements[i].Lots == nil {
+			movements[i].Lots = make([]Allocation, 0)
+		}
+	}
+
+	return nil
+}
+
+// MovementLots - MovementLots is a function that gets the lots a stock movement put units in or took units out of.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param movementId - string
+// @return lots
+// @return error
+func MovementLots(ctx context.Context, db sqlx.ExtContext, movementId string) ([]Allocation, error) {
+	movements := []Movement{{Id: movementId}}
+	if err := attachLots(ctx, db, movements); err != nil {
+		return nil, err
+	}
+
+	return movements[0].Lots, nil
+}
+
+// movementsSchema - the fields of a stock movement allowed in list queries
+var movementsSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "locationId", Column: "location_id", Type: pagination.UUID},
+		{Name: "quantity", Column: "quantity", Type: pagination.Number, Sortable: true},
+		{Name: "reason", Column: "reason", Type: pagination.String},
+		{Name: "reasonCode", Column: "reason_code", Type: pagination.String},
+		{Name: "reference", Column: "reference", Type: pagination.String},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-createdAt",
+}
+
+// ListMovements - ListMovements is a function that lists the stock movements of a product, the newest first unless
+// sorted otherwise, optionally at one location.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param params - *MovementsQuery
+// @return movements
+// @return error
+func ListMovements(ctx context.Context, productId string, params *MovementsQuery) (*PaginatedMovementsResponse, error) {
+	// check if the product exists
+	count, err := database.NamedCountQuery(ctx, inventoryDatabase(), "SELECT COUNT(*) FROM products WHERE id = :product_id", map[string]interface{}{
+		"product_id": productId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("counting products: %w", err)
+	}
+	if count < 1 {
+		return nil, ErrProductNotFound
+	}
+
+	// parse the filter and sort
+	statement, err := movementsSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	statement.AddCondition("product_id = CAST(:product_id AS UUID)", map[string]interface{}{"product_id": productId})
+	if len(params.LocationId) > 0 {
+		statement.AddCondition("location_id = CAST(:location_id AS UUID)", map[string]interface{}{"location_id": params.LocationId})
+	}
+
+	// execute query
+	movements := make([]Movement, 0)
+	page, err := database.NamedPageQuery(ctx, inventoryDatabase(), "stock_movements", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &movements)
+	if err != nil {
+		return nil, fmt.Errorf("selecting stock movements: %w", err)
+	}
+	if err := attachLots(ctx, inventoryDatabase(), movements); err != nil {
+		return nil, err
+	}
+
+	return &PaginatedMovementsResponse{
+		Movements:       movements,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// RecordSale - RecordSale is a function that takes the products of an order out of the stock of a location, from
+// the lots expiring first. The lots they came from are what recalls find the order by. A sale is recorded once per
+// order.
+//
+// @param ctx - context.Context
+// @param payload - *SaleRequest
+// @return movements
+// @return error
+func RecordSale(ctx context.Context, payload *SaleRequest) (*SaleResponse, error) {
+	response := &SaleResponse{Movements: make([]Movement, 0, len(payload.Lines))}
+	err := database.Transaction(ctx, inventoryDatabase(), func(tx *sqlx.Tx) error {
+		// serialize the sales of the order
+		if err := database.NamedExecQuery(ctx, tx, "SELECT pg_advisory_xact_lock(hashtext(:reference))", map[string]interface{}{
+			"reference": "sale:" + payload.OrderId,
+		}); err != nil {
+			return fmt.Errorf("locking order: %w", err)
+		}
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM stock_movements WHERE reason = :reason AND reference = :reference", map[string]interface{}{
+			"reason":    ReasonSale,
+			"reference": payload.OrderId,
+		})
+		if err != nil {
+			return fmt.Errorf("counting sales: %w", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%w[%v]", ErrAlreadyRecorded, payload.OrderId)
+		}
+
+		now := time.Now().UTC()
+		for _, line := range payload.Lines {
+			movement := &Movement{
+				ProductId:  line.ProductId,
+				LocationId: payload.LocationId,
+				Quantity:   -line.Quantity,
+				Reason:     ReasonSale,
+				Reference:  payload.OrderId,
+				CreatedAt:  now,
+			}
+			if err := Move(ctx, tx, movement); err != nil {
+				return fmt.Errorf("selling product %v: %w", line.ProductId, err)
+			}
+			response.Movements = append(response.Movements, *movement)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// expiringSchema - the fields of an expiring lot allowed in list queries
+var expiringSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "productId", Column: "product_id", Type: pagination.UUID},
+		{Name: "lotNumber", Column: "lot_number", Type: pagination.String},
+		{Name: "expiresAt", Column: "expires_at", Type: pagination.Time, Sortable: true},
+		{Name: "quantity", Column: "quantity", Type: pagination.Number, Sortable: true},
+		{Name: "name", Column: "name", Type: pagination.String, Sortable: true},
+		{Name: "daysLeft", Column: "days_left", Type: pagination.Number, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "expiresAt,name",
+}
+
+// Expiring - Expiring is a function that lists the lots in stock expiring within some days, or already expired,
+// the first to expire first unless sorted otherwise.
+//
+// @param ctx - context.Context
+// @param params - *ExpiringQuery
+// @param now - time.Time
+// @return lots
+// @return error
+func Expiring(ctx context.Context, params *ExpiringQuery, now time.Time) (*PaginatedExpiringResponse, error) {
+	days := params.Days
+	if days < 1 {
+		days = 7
+	}
+
+	// parse the filter and sort
+	statement, err := expiringSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	statement.AddCondition("quantity > 0 AND expires_at <= :cutoff", map[string]interface{}{"cutoff": Cutoff(now, days), "today": Cutoff(now, 0)})
+	if len(params.LocationId) > 0 {
+		statement.AddCondition("location_id = CAST(:location_id AS UUID)", map[string]interface{}{"location_id": params.LocationId})
+	}
+
+	// execute query
+	lots := make([]ExpiringLot, 0)
+	page, err := database.NamedPageQuery(ctx, inventoryDatabase(), `(
+    SELECT l.*, p.name, p.price, lo.name AS location_name, l.expires_at - CAST(:today AS DATE) AS days_left
+    FROM stock_lots l
+    JOIN products p ON p.id = l.product_id
+    JOIN locations lo ON lo.id = l.location_id
+  ) AS lots`, statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &lots)
+	if err != nil {
+		return nil, fmt.Errorf("selecting expiring lots: %w", err)
+	}
+
+	return &PaginatedExpiringResponse{
+		Lots:            lots,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// Recall - Recall is a function that finds the lots with a lot number, the units of them left at every location
+// and every order they were sold in.
+//
+// @param ctx - context.Context
+// @param params - *RecallQuery
+// @return recall
+// @return error
+func Recall(ctx context.Context, params *RecallQuery) (*RecallResponse, error) {
+	data := map[string]interface{}{"lot_number": params.LotNumber, "product_id": params.ProductId}
+	where := " WHERE l.lot_number = :lot_number AND (:product_id = '' OR CAST(l.product_id AS TEXT) = :product_id)"
+
+	// get the lots
+	response := &RecallResponse{LotNumber: params.LotNumber, Lots: make([]Lot, 0), Orders: make([]RecallOrder, 0)}
+	if err := database.NamedSliceQuery(ctx, inventoryDatabase(), "SELECT l.* FROM stock_lots l"+where+" ORDER BY l.product_id, l.location_id, l.expires_at", data, &response.Lots); err != nil {
+		return nil, fmt.Errorf("selecting lots: %w", err)
+	}
+	if len(response.Lots) < 1 {
+		return nil, fmt.Errorf("%w[%v]", ErrLotNotFound, params.LotNumber)
+	}
+
+	// get the orders they were sold in
+	if err := database.NamedSliceQuery(ctx, inventoryDatabase(), `
+    SELECT m.reference AS order_id, m.product_id, m.location_id, -SUM(ml.quantity) AS quantity, MIN(m.created_at) AS sold_at
+    FROM stock_lots l
+    JOIN stock_movement_lots ml ON ml.lot_id = l.id
+    JOIN stock_movements m ON m.id = ml.movement_id AND m.reason = 'sale'
+  `+where+`
+    GROUP BY m.reference, m.product_id, m.location_id
+    ORDER BY sold_at DESC, order_id
+  `, data, &response.Orders); err != nil {
+		return nil, fmt.Errorf("selecting recalled orders: %w", err)
+	}
+
+	return response, nil
+}
+
+// =====================================================================================================================
+// BARCODES
+// =====================================================================================================================
+
+// ListBarcodes - ListBarcodes is a function that lists the barcodes of a product.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @return barcodes
+// @return error
+func ListBarcodes(ctx context.Context, productId string) (*BarcodesResponse, error) {
+	data := map[string]interface{}{"product_id": productId}
+
+	// check if the product exists
+	count, err := database.NamedCountQuery(ctx, inventoryDatabase(), "SELECT COUNT(*) FROM products WHERE id = :product_id", data)
+	if err != nil {
+		return nil, fmt.Errorf("counting products: %w", err)
+	}
+	if count < 1 {
+		return nil, ErrProductNotFound
+	}
+
+	// execute query
+	response := &BarcodesResponse{ProductId: productId, Barcodes: make([]Barcode, 0)}
+	if err := database.NamedSliceQuery(ctx, inventoryDatabase(), "SELECT * FROM product_barcodes WHERE product_id = :product_id ORDER BY created_at, barcode", data, &response.Barcodes); err != nil {
+		return nil, fmt.Errorf("selecting barcodes: %w", err)
+	}
+
+	return response, nil
+}
+
+// AddBarcode - AddBarcode is a function that adds a barcode to a product. Adding a barcode the product already has
+// changes nothing.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param code - the barcode
+// @return barcode
+// @return error
+func AddBarcode(ctx context.Context, productId, code string) (*Barcode, error) {
+	barcode := &Barcode{Barcode: code, ProductId: productId, CreatedAt: time.Now().UTC()}
+
+	// check if the product exists
+	count, err := database.NamedCountQuery(ctx, inventoryDatabase(), "SELECT COUNT(*) FROM products WHERE id = :product_id", barcode)
+	if err != nil {
+		return nil, fmt.Errorf("counting products: %w", err)
+	}
+	if count < 1 {
+		return nil, ErrProductNotFound
+	}
+
+	// query statement to be executed, keeping the barcode when the product has it already
+	q := `
+    INSERT INTO product_barcodes (barcode, product_id, created_at) VALUES (:barcode, :product_id, :created_at)
+    ON CONFLICT (barcode) DO UPDATE SET barcode = EXCLUDED.barcode
+    RETURNING *
+  `
+
+	// execute query
+	if err := database.NamedStructQuery(ctx, inventoryDatabase(), q, barcode, barcode); err != nil {
+		return nil, fmt.Errorf("inserting barcode: %w", err)
+	}
+	if barcode.ProductId != productId {
+		return nil, fmt.Errorf("%w[%v]", ErrBarcodeTaken, code)
+	}
+
+	return barcode, nil
+}
+
+// RemoveBarcode - RemoveBarcode is a function that removes a barcode from a product.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param code - the barcode
+// @return error
+func RemoveBarcode(ctx context.Context, productId, code string) error {
+	count, err := database.NamedCountQuery(ctx, inventoryDatabase(), `
+    WITH removed AS (DELETE FROM product_barcodes WHERE barcode = :barcode AND product_id = :product_id RETURNING barcode)
+    SELECT COUNT(*) FROM removed
+  `, map[string]interface{}{"barcode": code, "product_id": productId})
+	if err != nil {
+		return fmt.Errorf("deleting barcode: %w", err)
+	}
+	if count < 1 {
+		return fmt.Errorf("%w[%v]", ErrBarcodeNotFound, code)
+	}
+
+	return nil
+}
+
+// Scan - Scan is a function that gets the products of barcodes, as read by a scanner.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param codes - the barcodes
+// @return the product of every barcode
+// @return error
+func Scan(ctx context.Context, db sqlx.ExtContext, codes []string) (map[string]string, error) {
+	products := make(map[string]string, len(codes))
+	if len(codes) < 1 {
+		return products, nil
+	}
+
+	barcodes := make([]Barcode, 0, len(codes))
+	if err := database.NamedSliceQuery(ctx, db, "SELECT * FROM product_barcodes WHERE barcode = ANY(CAST(:codes AS TEXT[]))", map[string]interface{}{"codes": codes}, &barcodes); err != nil {
+		return nil, fmt.Errorf("selecting barcodes: %w", err)
+	}
+	for _, barcode := range barcodes {
+		products[barcode.Barcode] = barcode.ProductId
+	}
+	for _, code := range codes {
+		if _, ok := products[code]; !ok {
+			return nil, fmt.Errorf("%w[%v]", ErrBarcodeNotFound, code)
+		}
+	}
+
+	return products, nil
+}
@@ -0,0 +1,87 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Pick - Pick chooses the lots to take stock out of, first expired first out. Lots that do not expire go last,
+// and lots expiring the same day go in the order they were received. Lots that expired before the day of the
+// movement are skipped when the stock must be sellable.
+//
+// @param lots - the lots at the location
+// @param quantity - the units to take out
+// @param at - when the stock is taken out
+// @param sellable - whether expired lots are skipped
+// @return the units to take from each lot
+// @return error
+func Pick(lots []Lot, quantity int, at time.Time, sellable bool) ([]Allocation, error) {
+	today := Cutoff(at, 0)
+
+	sorted := append([]Lot{}, lots...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].ExpiresAt, sorted[j].ExpiresAt
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.Before(*b)
+		case a != nil && b == nil:
+			return true
+		case a == nil && b != nil:
+			return false
+		default:
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+	})
+
+	allocations := make([]Allocation, 0)
+	remaining := quantity
+	for _, lot := range sorted {
+		if remaining < 1 {
+			break
+		}
+		if lot.Quantity < 1 || (sellable && lot.ExpiresAt != nil && lot.ExpiresAt.Before(today)) {
+			continue
+		}
+
+		take := lot.Quantity
+		if take > remaining {
+			take = remaining
+		}
+		allocations = append(allocations, Allocation{LotId: lot.Id, LotNumber: lot.LotNumber, ExpiresAt: lot.ExpiresAt, Quantity: take})
+		remaining -= take
+	}
+	if remaining > 0 {
+		if sellable {
+			return nil, fmt.Errorf("%w: %v unexpired in stock at the location", ErrInsufficientStock, quantity-remaining)
+		}
+		return nil, fmt.Errorf("%w: %v in stock at the location", ErrInsufficientStock, quantity-remaining)
+	}
+
+	return allocations, nil
+}
+
+// Cutoff - Cutoff gets the last day a lot can expire on to be expiring within the days, dates being kept without
+// a time.
+//
+// @param now - time.Time
+// @param days - int
+// @return the last day
+func Cutoff(now time.Time, days int) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+}
+
+// Day - Day gets the day of a time, as expiry dates are kept without a time.
+//
+// @param t - *time.Time
+// @return the day, nil when the time is nil
+func Day(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	y, m, d := t.UTC().Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return &day
+}
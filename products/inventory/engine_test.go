@@ -0,0 +1,98 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestPick - test stock is taken from the lots expiring first
+//
+//	@param t - testing.T
+func TestPick(t *testing.T) {
+	day := func(d int) *time.Time {
+		at := time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC)
+		return &at
+	}
+	received := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	lots := []Lot{
+		{Id: "never", Quantity: 10, CreatedAt: received},
+		{Id: "late", ExpiresAt: day(20), Quantity: 5, CreatedAt: received},
+		{Id: "early-second", ExpiresAt: day(10), Quantity: 3, CreatedAt: received.Add(time.Hour)},
+		{Id: "early-first", ExpiresAt: day(10), Quantity: 2, CreatedAt: received},
+		{Id: "empty", ExpiresAt: day(1), Quantity: 0, CreatedAt: received},
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	allocations, err := Pick(lots, 12, now, true)
+	if err != nil {
+		t.Fatalf("12 units should be picked, got %v", err)
+	}
+	want := []Allocation{{LotId: "early-first", Quantity: 2}, {LotId: "early-second", Quantity: 3}, {LotId: "late", Quantity: 5}, {LotId: "never", Quantity: 2}}
+	if len(allocations) != len(want) {
+		t.Fatalf("allocations should be %v, got %v", want, allocations)
+	}
+	for i := range want {
+		if allocations[i].LotId != want[i].LotId || allocations[i].Quantity != want[i].Quantity {
+			t.Errorf("allocation %v should be %v, got %v", i, want[i], allocations[i])
+		}
+	}
+	if lots[0].Id != "never" {
+		t.Errorf("the lots given should not be sorted, got %v", lots)
+	}
+
+	// more than in stock
+	if _, err := Pick(lots, 21, now, true); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("21 units should be insufficient, got %v", err)
+	}
+
+	// a lot expired before the day is skipped for sales, but still written off
+	later := time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)
+	allocations, err = Pick(lots, 3, later, true)
+	if err != nil || len(allocations) != 1 || allocations[0].LotId != "late" {
+		t.Errorf("the expired lots should be skipped, got %v %v", allocations, err)
+	}
+	allocations, err = Pick(lots, 3, later, false)
+	if err != nil || len(allocations) != 2 || allocations[0].LotId != "early-first" {
+		t.Errorf("the expired lots should be written off first, got %v %v", allocations, err)
+	}
+	if _, err := Pick(lots, 16, later, true); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("only 15 units should be sellable, got %v", err)
+	}
+}
+
+// TestCutoff - test the last day a lot can expire on to be expiring
+//
+//	@param t - testing.T
+func TestCutoff(t *testing.T) {
+	now := time.Date(2024, 2, 27, 23, 30, 0, 0, time.FixedZone("", -2*60*60))
+
+	// create a slice
+	slice := []struct {
+		days int
+		want time.Time
+	}{
+		{0, time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{1, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{7, time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, item := range slice {
+		if got := Cutoff(now, item.days); !got.Equal(item.want) {
+			t.Errorf("cutoff in %v days should be %v, got %v", item.days, item.want, got)
+		}
+	}
+}
+
+// TestDay - test times are cut to their day
+//
+//	@param t - testing.T
+func TestDay(t *testing.T) {
+	if Day(nil) != nil {
+		t.Errorf("the day of no time should be nil")
+	}
+
+	at := time.Date(2024, 5, 4, 15, 4, 5, 0, time.UTC)
+	if got := Day(&at); !got.Equal(time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("the day should be 2024-05-04, got %v", got)
+	}
+}
@@ -0,0 +1,14 @@
+package inventory
+
+import "errors"
+
+var (
+	ErrProductNotFound   = errors.New("product not found")
+	ErrInsufficientStock = errors.New("not enough stock")
+	ErrInvalidMovement   = errors.New("invalid stock movement")
+	ErrLocationNotFound  = errors.New("location not found")
+	ErrLotNotFound       = errors.New("no stock of the lot at the location")
+	ErrAlreadyRecorded   = errors.New("the sale of the order is already recorded")
+	ErrBarcodeNotFound   = errors.New("barcode not found")
+	ErrBarcodeTaken      = errors.New("the barcode is on another product")
+)
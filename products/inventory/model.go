@@ -0,0 +1,181 @@
+package inventory
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+const (
+	ReasonReceipt    = "receipt"    // stock bought from a supplier
+	ReasonSale       = "sale"       // stock sold to a customer
+	ReasonReturn     = "return"     // stock a customer sent back
+	ReasonAdjustment = "adjustment" // stock counted, damaged or lost
+	ReasonTransfer   = "transfer"   // stock sent between locations, the stock of the product is unchanged
+)
+
+// Movement - a change of the stock of a product
+type Movement struct {
+	Id            string       `json:"id" db:"id"`
+	ProductId     string       `json:"productId" db:"product_id"`
+	LocationId    string       `json:"locationId" db:"location_id"`       // the default location when empty
+	Quantity      int          `json:"quantity" db:"quantity"`            // negative when stock goes out
+	QuantityAfter int          `json:"quantityAfter" db:"quantity_after"` // the stock at the location after
+	Reason        string       `json:"reason" db:"reason"`
+	ReasonCode    string       `json:"reasonCode" db:"reason_code"` // why an adjustment was made, e.g. damaged or theft
+	Reference     string       `json:"reference" db:"reference"`    // e.g. the purchase order
+	UnitCost      *money.Money `json:"unitCost" db:"unit_cost"`     // what a unit cost when stock was bought
+	Note          string       `json:"note" db:"note"`
+	CreatedBy     *string      `json:"createdBy" db:"created_by"`
+	CreatedAt     time.Time    `json:"createdAt" db:"created_at"`
+	LotNumber     string       `json:"-" db:"-"`    // the lot stock goes in, or comes out of instead of the first to expire
+	ExpiresAt     *time.Time   `json:"-" db:"-"`    // when the units going in expire
+	Lots          []Allocation `json:"lots" db:"-"` // the lots the stock went in or came out of
+}
+
+// Lot - units of a product at a location with the same lot number and expiry date
+type Lot struct {
+	Id         string     `json:"id" db:"id"`
+	ProductId  string     `json:"productId" db:"product_id"`
+	LocationId string     `json:"locationId" db:"location_id"`
+	LotNumber  string     `json:"lotNumber" db:"lot_number"` // empty for stock received without one
+	ExpiresAt  *time.Time `json:"expiresAt" db:"expires_at"` // none for stock that does not expire
+	Quantity   int        `json:"quantity" db:"quantity"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// Allocation - units of a movement in or out of a lot
+type Allocation struct {
+	MovementId string     `json:"-" db:"movement_id"`
+	LotId      string     `json:"lotId" db:"lot_id"`
+	LotNumber  string     `json:"lotNumber" db:"lot_number"`
+	ExpiresAt  *time.Time `json:"expiresAt" db:"expires_at"`
+	Quantity   int        `json:"quantity" db:"quantity"` // the units, whichever way they moved
+}
+
+type AdjustRequest struct {
+	LocationId string     `json:"locationId" validate:"omitempty,uuid"` // the default location when empty
+	Quantity   int        `json:"quantity" validate:"required"`         // negative to take stock out
+	LotNumber  string     `json:"lotNumber" validate:"max=100"`         // the lot, the first to expire when taking stock out without one
+	ExpiresAt  *time.Time `json:"expiresAt"`                            // when the units put in expire
+	Note       string     `json:"note" validate:"required,max=1000"`
+}
+
+type MovementsQuery struct {
+	LocationId string `json:"locationId" query:"locationId" validate:"omitempty,uuid"`
+	Limit      int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page       int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter     string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. reason=sale|transfer
+	Sort       string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -createdAt
+	Cursor     string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the movements
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedMovementsResponse struct {
+	Movements       []Movement `json:"data"`
+	Total           int        `json:"total"`
+	TotalPages      int        `json:"totalPages"`
+	CurrentPage     int        `json:"currentPage"`
+	HasPreviousPage bool       `json:"hasPreviousPage"`
+	HasNextPage     bool       `json:"hasNextPage"`
+	PrevCursor      string     `json:"prevCursor"`
+	NextCursor      string     `json:"nextCursor"`
+}
+
+// Level - the stock of a product at a location
+type Level struct {
+	LocationId string    `json:"locationId" db:"location_id"`
+	Code       string    `json:"code" db:"code"`
+	Name       string    `json:"name" db:"name"`
+	Kind       string    `json:"kind" db:"kind"`
+	Quantity   int       `json:"quantity" db:"quantity"`
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type LevelsResponse struct {
+	ProductId string  `json:"productId"`
+	Total     int     `json:"total"`     // the stock of the product, the sum of its locations and the units in transit
+	InTransit int     `json:"inTransit"` // the units shipped from one location and not yet received at another
+	Levels    []Level `json:"data"`
+}
+
+// Barcode - a barcode printed on a product, e.g. its EAN-13
+type Barcode struct {
+	Barcode   string    `json:"barcode" db:"barcode"`
+	ProductId string    `json:"productId" db:"product_id"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+type BarcodesResponse struct {
+	ProductId string    `json:"productId"`
+	Barcodes  []Barcode `json:"data"`
+}
+
+type SaleLine struct {
+	ProductId string `json:"productId" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"required,min=1"`
+}
+
+type SaleRequest struct {
+	OrderId    string     `json:"orderId" validate:"required,max=255"`
+	LocationId string     `json:"locationId" validate:"omitempty,uuid"` // where the order is picked, the default location when empty
+	Lines      []SaleLine `json:"lines" validate:"required,min=1,max=500,dive"`
+}
+
+type SaleResponse struct {
+	Movements []Movement `json:"data"`
+}
+
+type ExpiringQuery struct {
+	Days       int    `json:"days" query:"days" validate:"omitempty,min=0,max=365"` // lots expiring within the days, 7 when empty
+	LocationId string `json:"locationId" query:"locationId" validate:"omitempty,uuid"`
+	Limit      int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page       int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter     string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. daysLeft<0
+	Sort       string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. expiresAt,name
+	Cursor     string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the lots
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+// ExpiringLot - a lot in stock expiring soon
+type ExpiringLot struct {
+	Lot
+	Name         string      `json:"name" db:"name"`
+	Price        money.Money `json:"price" db:"price"`
+	LocationName string      `json:"locationName" db:"location_name"`
+	DaysLeft     int         `json:"daysLeft" db:"days_left"` // negative once expired
+}
+
+type PaginatedExpiringResponse struct {
+	Lots            []ExpiringLot `json:"data"`
+	Total           int           `json:"total"`
+	TotalPages      int           `json:"totalPages"`
+	CurrentPage     int           `json:"currentPage"`
+	HasPreviousPage bool          `json:"hasPreviousPage"`
+	HasNextPage     bool          `json:"hasNextPage"`
+	PrevCursor      string        `json:"prevCursor"`
+	NextCursor      string        `json:"nextCursor"`
+}
+
+type RecallQuery struct {
+	LotNumber string `json:"lotNumber" query:"lotNumber" validate:"required,max=100"`
+	ProductId string `json:"productId" query:"productId" validate:"omitempty,uuid"` // every product with the lot number when empty
+}
+
+// RecallOrder - units of a recalled lot sold in an order
+type RecallOrder struct {
+	OrderId    string    `json:"orderId" db:"order_id"`
+	ProductId  string    `json:"productId" db:"product_id"`
+	LocationId string    `json:"locationId" db:"location_id"`
+	Quantity   int       `json:"quantity" db:"quantity"`
+	SoldAt     time.Time `json:"soldAt" db:"sold_at"`
+}
+
+type RecallResponse struct {
+	LotNumber string        `json:"lotNumber"`
+	Lots      []Lot         `json:"lots"`   // the lots with the number and the units left of them
+	Orders    []RecallOrder `json:"orders"` // the orders the lots were sold in, the newest first
+}
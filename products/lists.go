@@ -0,0 +1,414 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/notifications"
+	"encore.app/notifications/store"
+	"encore.app/pkg/middleware"
+	"encore.app/products/cart"
+	"encore.app/products/lists"
+	"encore.app/products/ps"
+)
+
+// =====================================================================================================================
+// LISTS
+// =====================================================================================================================
+
+// watch the wishlists for sales and restocks
+var _ = cron.NewJob("check-list-alerts", cron.JobConfig{
+	Title:    "Check wishlists for sales and restocks",
+	Every:    15 * cron.Minute,
+	Endpoint: CheckListAlerts,
+})
+
+// ListLists - List the lists of the signed in user and the lists shared with them
+//
+//	@param ctx - context.Context
+//	@return lists
+//	@return error
+//
+// encore:api auth method=GET path=/lists
+func ListLists(ctx context.Context) (*lists.ListsResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &lists.ListsResponse{}, err
+	}
+
+	// get the lists
+	all, err := lists.ListAll(ctx, claims.Subject.Id)
+	if err != nil {
+		return &lists.ListsResponse{}, err
+	}
+
+	return &lists.ListsResponse{Lists: all}, nil
+}
+
+// CreateList - Create a wishlist or shopping list owned by the signed in user
+//
+//	@param ctx - context.Context
+//	@param payload - *lists.ListRequest
+//	@return list
+//	@return error
+//
+// encore:api auth method=POST path=/lists
+func CreateList(ctx context.Context, payload *lists.ListRequest) (*lists.List, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &lists.List{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &lists.List{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the list
+	list, err := lists.Create(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &lists.List{}, listError(err)
+	}
+
+	return list, nil
+}
+
+// GetList - Get a list with its items
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return list
+//	@return error
+//
+// encore:api auth method=GET path=/lists/:id
+func GetList(ctx context.Context, id string) (*lists.ListResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &lists.ListResponse{}, err
+	}
+
+	// get the list
+	list, err := lists.Get(ctx, claims.Subject.Id, id)
+	if err != nil {
+		return &lists.ListResponse{}, listError(err)
+	}
+
+	return list, nil
+}
+
+// UpdateList - Rename a list or change its kind, only the owner can
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *lists.ListRequest
+//	@return list
+//	@return error
+//
+// encore:api auth method=PUT path=/lists/:id
+func UpdateList(ctx context.Context, id string, payload *lists.ListRequest) (*lists.List, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &lists.List{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &lists.List{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// update the list
+	list, err := lists.Update(ctx, claims.Subject.Id, id, payload)
+	if err != nil {
+		return &lists.List{}, listError(err)
+	}
+
+	return list, nil
+}
+
+// DeleteList - Delete a list, only the owner can
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/lists/:id
+func DeleteList(ctx context.Context, id string) error {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// delete the list
+	if err := lists.Delete(ctx, claims.Subject.Id, id); err != nil {
+		return listError(err)
+	}
+
+	return nil
+}
+
+// SetListItem - Add a product to a list or replace its quantity and note, the owner and editors can
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param productId - string
+//	@param payload - *lists.ItemRequest
+//	@return item
+//	@return error
+//
+// encore:api auth method=PUT path=/lists/:id/items/:productId
+func SetListItem(ctx context.Context, id, productId string, payload *lists.ItemRequest) (*lists.Item, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &lists.Item{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &lists.Item{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// set the item
+	item, err := lists.SetItem(ctx, claims.Subject.Id, id, productId, payload)
+	if err != nil {
+		return &lists.Item{}, listError(err)
+	}
+
+	return item, nil
+}
+
+// RemoveListItem - Remove a product from a list, the owner and editors can
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param productId - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/lists/:id/items/:productId
+func RemoveListItem(ctx context.Context, id, productId string) error {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// remove the item
+	if err := lists.RemoveItem(ctx, claims.Subject.Id, id, productId); err != nil {
+		return listError(err)
+	}
+
+	return nil
+}
+
+// ShareList - Share a list with a user to view or edit it, only the owner can
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param userId - string
+//	@param payload - *lists.ShareRequest
+//	@return share
+//	@return error
+//
+// encore:api auth method=PUT path=/lists/:id/shares/:userId
+func ShareList(ctx context.Context, id, userId string, payload *lists.ShareRequest) (*lists.Share, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &lists.Share{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &lists.Share{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// share the list
+	share, err := lists.SetShare(ctx, claims.Subject.Id, id, userId, payload)
+	if err != nil {
+		return &lists.Share{}, listError(err)
+	}
+
+	return share, nil
+}
+
+// UnshareList - Stop sharing a list with a user, the owner can remove anyone and a user can remove themselves
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param userId - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/lists/:id/shares/:userId
+func UnshareList(ctx context.Context, id, userId string) error {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// remove the share
+	if err := lists.RemoveShare(ctx, claims.Subject.Id, id, userId); err != nil {
+		return listError(err)
+	}
+
+	return nil
+}
+
+// AddListToCart - Add every item of a list to the cart of the signed in user
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return cart
+//	@return error
+//
+// encore:api auth method=POST path=/lists/:id/add-to-cart
+func AddListToCart(ctx context.Context, id string) (*cart.Cart, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &cart.Cart{}, err
+	}
+
+	// add the items
+	if err := lists.AddToCart(ctx, claims.Subject.Id, id); err != nil {
+		return &cart.Cart{}, listError(err)
+	}
+
+	// get the cart
+	c, err := cart.Get(ctx, claims.Subject.Id)
+	if err != nil {
+		return &cart.Cart{}, err
+	}
+
+	return c, nil
+}
+
+// ListListAlerts - List the latest sale and restock alerts of the wishlists of the signed in user
+//
+//	@param ctx - context.Context
+//	@return alerts
+//	@return error
+//
+// encore:api auth method=GET path=/lists/alerts
+func ListListAlerts(ctx context.Context) (*lists.AlertsResponse, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &lists.AlertsResponse{}, err
+	}
+
+	// get the alerts
+	alerts, err := lists.Alerts(ctx, claims.Subject.Id)
+	if err != nil {
+		return &lists.AlertsResponse{}, err
+	}
+
+	return &lists.AlertsResponse{Alerts: alerts}, nil
+}
+
+// CheckListAlerts - Raise alerts for the wishlist items that went on sale or came back in stock
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/lists/check-alerts
+func CheckListAlerts(ctx context.Context) (*lists.CheckResponse, error) {
+	// check the wishlists
+	response, err := lists.CheckAlerts(ctx, time.Now())
+	if err != nil {
+		return &lists.CheckResponse{}, err
+	}
+
+	rlog.Info("products.CheckListAlerts", "items", response.Items, "alerts", response.Alerts, "delivered", response.Delivered)
+
+	return response, nil
+}
+
+// notify the owners of wishlists when a product is back in stock
+func init() {
+	lists.OnAlerts(notifyBackInStock)
+}
+
+// notifyBackInStock - sends a notification for every back in stock alert. An alert is notified once, so the
+// alerts given again after a failed hook are not sent twice.
+//
+//	@param ctx - context.Context
+//	@param alerts - []lists.Alert
+//	@return error
+func notifyBackInStock(ctx context.Context, alerts []lists.Alert) error {
+	ids := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		if alert.Kind == lists.AlertBackInStock {
+			ids = append(ids, alert.ProductId)
+		}
+	}
+	if len(ids) < 1 {
+		return nil
+	}
+
+	// get the names of the products
+	products, err := ps.GetMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byId := make(map[string]ps.Product, len(products))
+	for _, product := range products {
+		byId[product.Id] = product
+	}
+
+	for _, alert := range alerts {
+		product, ok := byId[alert.ProductId]
+		if alert.Kind != lists.AlertBackInStock || !ok {
+			continue
+		}
+		price := product.Price
+		if alert.NewPrice != nil {
+			price = *alert.NewPrice
+		}
+
+		if _, err := notifications.SendNotification(ctx, &store.SendRequest{
+			UserId:    alert.UserId,
+			Event:     store.EventBackInStock,
+			Reference: alert.Id,
+			Data:      map[string]string{"productName": product.Name, "price": price.Format(), "productId": product.Id},
+		}); err != nil && errs.Code(err) != errs.NotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listError - maps list store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func listError(err error) error {
+	switch {
+	case errors.Is(err, lists.ErrNotFound), errors.Is(err, lists.ErrItemNotFound), errors.Is(err, lists.ErrProductNotFound),
+		errors.Is(err, lists.ErrShareNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, lists.ErrAlreadyExists):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, lists.ErrForbidden):
+		return &errs.Error{Code: errs.PermissionDenied, Message: err.Error()}
+	case errors.Is(err, lists.ErrInvalidShare):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, lists.ErrTooManyItems):
+		return &errs.Error{Code: errs.ResourceExhausted, Message: err.Error()}
+	default:
+		return err
+	}
+}
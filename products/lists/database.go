@@ -0,0 +1,725 @@
+package lists
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/products/cart"
+	"encore.app/products/promo"
+	"encore.app/products/ps"
+)
+
+// the products database
+var listsDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// watched - a wishlist item with the owner of its list
+type watched struct {
+	Item
+	OwnerId string `db:"owner_id"`
+}
+
+// access - gets a list with the permission of a user on it.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @param id - string
+// @param need - the permission needed
+// @return list
+// @return error
+func access(ctx context.Context, db sqlx.ExtContext, userId, id, need string) (*List, error) {
+	list := &List{}
+
+	// query statement to be executed
+	q := `
+    SELECT l.*, CASE WHEN l.owner_id = :user_id THEN 'owner' ELSE s.permission END AS permission
+    FROM shopping_lists l
+    LEFT JOIN shopping_list_shares s ON s.list_id = l.id AND s.user_id = :user_id
+    WHERE l.id = :id AND (l.owner_id = :user_id OR s.user_id IS NOT NULL)
+  `
+
+	// execute query, a list that is not shared with the user is not found
+	if err := database.NamedStructQuery(ctx, db, q, map[string]interface{}{"id": id, "user_id": userId}, list); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("selecting list: %w", err)
+	}
+
+	// check the permission
+	if !Allows(list.Permission, need) {
+		return nil, fmt.Errorf("%w: needs the %v permission", ErrForbidden, need)
+	}
+
+	return list, nil
+}
+
+// checkName - checks no other list of the owner has a name.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param ownerId - string
+// @param id - the list being named, empty for a new list
+// @param name - string
+// @return error
+func checkName(ctx context.Context, db sqlx.ExtContext, ownerId, id, name string) error {
+	count, err := database.NamedCountQuery(ctx, db, `
+    SELECT COUNT(*) FROM shopping_lists WHERE owner_id = :owner_id AND name = :name AND CAST(id AS TEXT) <> :id
+  `, map[string]interface{}{"owner_id": ownerId, "id": id, "name": name})
+	if err != nil {
+		return fmt.Errorf("counting lists: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: a list is named %v", ErrAlreadyExists, name)
+	}
+
+	return nil
+}
+
+// ListAll - ListAll is a function that lists the lists a user owns or that are shared with them.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @return lists
+// @return error
+func ListAll(ctx context.Context, userId string) ([]List, error) {
+	lists := make([]List, 0)
+
+	// query statement to be executed
+	q := `
+    SELECT l.*, CASE WHEN l.owner_id = :user_id THEN 'owner' ELSE s.permission END AS permission
+    FROM shopping_lists l
+    LEFT JOIN shopping_list_shares s ON s.list_id = l.id AND s.user_id = :user_id
+    WHERE l.owner_id = :user_id OR s.user_id IS NOT NULL
+    ORDER BY l.created_at, l.id
+  `
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, listsDatabase(), q, map[string]interface{}{"user_id": userId}, &lists); err != nil {
+		return nil, fmt.Errorf("selecting lists: %w", err)
+	}
+
+	return lists, nil
+}
+
+// Get - Get is a function that gets a list with its items.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @return list
+// @return error
+func Get(ctx context.Context, userId, id string) (*ListResponse, error) {
+	list, err := access(ctx, listsDatabase(), userId, id, PermissionView)
+	if err != nil {
+		return nil, err
+	}
+	response := &ListResponse{List: list, Items: make([]ItemView, 0), Shares: make([]Share, 0)}
+
+	// query statement to be executed
+	q := `
+    SELECT i.*, p.name, p.price, p.stock_quantity
+    FROM shopping_list_items i
+    JOIN products p ON p.id = i.product_id
+    WHERE i.list_id = :id
+    ORDER BY i.created_at, i.product_id
+  `
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, listsDatabase(), q, map[string]interface{}{"id": id}, &response.Items); err != nil {
+		return nil, fmt.Errorf("selecting list items: %w", err)
+	}
+
+	// only the owner sees who the list is shared with
+	if list.Permission == PermissionOwner {
+		if err := database.NamedSliceQuery(ctx, listsDatabase(), "SELECT * FROM shopping_list_shares WHERE list_id = :id ORDER BY created_at, user_id", map[string]interface{}{
+			"id": id,
+		}, &response.Shares); err != nil {
+			return nil, fmt.Errorf("selecting list shares: %w", err)
+		}
+	}
+
+	return response, nil
+}
+
+// Create - Create is a function that creates a list.
+//
+// @param ctx - context.Context
+// @param userId - the owner
+// @param payload - *ListRequest
+// @return list
+// @return error
+func Create(ctx context.Context, userId string, payload *ListRequest) (*List, error) {
+	now := time.Now().UTC()
+	list := &List{Id: uuid.New().String(), OwnerId: userId, Name: payload.Name, Kind: payload.Kind, Permission: PermissionOwner, CreatedAt: now, UpdatedAt: now}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO shopping_lists (id, owner_id, name, kind, created_at, updated_at)
+    VALUES (:id, :owner_id, :name, :kind, :created_at, :updated_at)
+    ON CONFLICT (owner_id, name) DO NOTHING
+    RETURNING *, 'owner' AS permission
+  `
+
+	// execute query
+	if err := database.NamedStructQuery(ctx, listsDatabase(), q, list, list); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, fmt.Errorf("%w: a list is named %v", ErrAlreadyExists, payload.Name)
+		}
+		return nil, fmt.Errorf("inserting list: %w", err)
+	}
+
+	return list, nil
+}
+
+// Update - Update is a function that renames a list or changes its kind, only the owner can.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @param payload - *ListRequest
+// @return list
+// @return error
+func Update(ctx context.Context, userId, id string, payload *ListRequest) (*List, error) {
+	var list *List
+	err := database.Transaction(ctx, listsDatabase(), func(tx *sqlx.Tx) error {
+		var err error
+		if list, err = access(ctx, tx, userId, id, PermissionOwner); err != nil {
+			return err
+		}
+		if err := checkName(ctx, tx, list.OwnerId, id, payload.Name); err != nil {
+			return err
+		}
+
+		// query statement to be executed
+		q := `
+      UPDATE shopping_lists SET name = :name, kind = :kind, updated_at = :updated_at
+      WHERE id = :id
+      RETURNING *, 'owner' AS permission
+    `
+
+		// execute query
+		if err := database.NamedStructQuery(ctx, tx, q, map[string]interface{}{
+			"id":         id,
+			"name":       payload.Name,
+			"kind":       payload.Kind,
+			"updated_at": time.Now().UTC(),
+		}, list); err != nil {
+			return fmt.Errorf("updating list: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Delete - Delete is a function that deletes a list with its items, shares and alerts, only the owner can.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @return error
+func Delete(ctx context.Context, userId, id string) error {
+	return database.Transaction(ctx, listsDatabase(), func(tx *sqlx.Tx) error {
+		if _, err := access(ctx, tx, userId, id, PermissionOwner); err != nil {
+			return err
+		}
+		if err := database.NamedExecQuery(ctx, tx, "DELETE FROM shopping_lists WHERE id = :id", map[string]interface{}{"id": id}); err != nil {
+			return fmt.Errorf("deleting list: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteUser - DeleteUser is a function that removes the lists of a deleted user with their items, shares and alerts,
+// and the shares of other lists with the user. Items the user added to lists of others are kept.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param userId - string
+// @return the rows removed, by what they were
+// @return error
+func DeleteUser(ctx context.Context, db sqlx.ExtContext, userId string) (map[string]int, error) {
+	data := map[string]interface{}{"user_id": userId}
+
+	// the alerts go with the lists, count them first
+	alerts, err := database.NamedCountQuery(ctx, db, "SELECT COUNT(*) FROM shopping_list_alerts WHERE user_id = :user_id", data)
+	if err != nil {
+		return nil, fmt.Errorf("counting alerts: %w", err)
+	}
+
+	lists, err := database.NamedCountQuery(ctx, db, `
+    WITH removed AS (DELETE FROM shopping_lists WHERE owner_id = :user_id RETURNING id)
+    SELECT COUNT(*) FROM removed
+  `, data)
+	if err != nil {
+		return nil, fmt.Errorf("deleting lists: %w", err)
+	}
+
+	shares, err := database.NamedCountQuery(ctx, db, `
+    WITH removed AS (DELETE FROM shopping_list_shares WHERE user_id = :user_id RETURNING list_id)
+    SELECT COUNT(*) FROM removed
+  `, data)
+	if err != nil {
+		return nil, fmt.Errorf("deleting shares: %w", err)
+	}
+
+	return map[string]int{"lists": lists, "listShares": shares, "listAlerts": alerts}, nil
+}
+
+// SetItem - SetItem is a function that adds a product to a list or replaces its quantity and note.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @param productId - string
+// @param payload - *ItemRequest
+// @return item
+// @return error
+func SetItem(ctx context.Context, userId, id, productId string, payload *ItemRequest) (*Item, error) {
+	now := time.Now().UTC()
+	item := &Item{ListId: id, ProductId: productId, Quantity: payload.Quantity, Note: payload.Note, AddedBy: userId, CreatedAt: now, UpdatedAt: now}
+
+	err := database.Transaction(ctx, listsDatabase(), func(tx *sqlx.Tx) error {
+		if _, err := access(ctx, tx, userId, id, PermissionEdit); err != nil {
+			return err
+		}
+
+		// check if the product exists
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM products WHERE id = :product_id", item)
+		if err != nil {
+			return fmt.Errorf("counting products: %w", err)
+		}
+		if count < 1 {
+			return ErrProductNotFound
+		}
+
+		// query statement to be executed, the first user to add the product is kept
+		q := `
+      INSERT INTO shopping_list_items (list_id, product_id, quantity, note, added_by, created_at, updated_at)
+      VALUES (:list_id, :product_id, :quantity, :note, :added_by, :created_at, :updated_at)
+      ON CONFLICT (list_id, product_id) DO UPDATE SET
+        quantity = EXCLUDED.quantity, note = EXCLUDED.note, updated_at = EXCLUDED.updated_at
+      RETURNING *
+    `
+
+		// execute query
+		if err := database.NamedStructQuery(ctx, tx, q, item, item); err != nil {
+			return fmt.Errorf("setting list item: %w", err)
+		}
+
+		// check the size of the list
+		count, err = database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM shopping_list_items WHERE list_id = :list_id", item)
+		if err != nil {
+			return fmt.Errorf("counting list items: %w", err)
+		}
+		if count > MaxItems {
+			return fmt.Errorf("%w: a list holds up to %v products", ErrTooManyItems, MaxItems)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// RemoveItem - RemoveItem is a function that removes a product from a list.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @param productId - string
+// @return error
+func RemoveItem(ctx context.Context, userId, id, productId string) error {
+	return database.Transaction(ctx, listsDatabase(), func(tx *sqlx.Tx) error {
+		if _, err := access(ctx, tx, userId, id, PermissionEdit); err != nil {
+			return err
+		}
+
+		// execute query
+		item := &Item{}
+		if err := database.NamedStructQuery(ctx, tx, "DELETE FROM shopping_list_items WHERE list_id = :id AND product_id = :product_id RETURNING *", map[string]interface{}{
+			"id":         id,
+			"product_id": productId,
+		}, item); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return ErrItemNotFound
+			}
+			return fmt.Errorf("deleting list item: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// SetShare - SetShare is a function that shares a list with a user or changes their permission, only the owner can.
+//
+// @param ctx - context.Context
+// @param userId - the owner
+// @param id - string
+// @param shareWith - the user the list is shared with
+// @param payload - *ShareRequest
+// @return share
+// @return error
+func SetShare(ctx context.Context, userId, id, shareWith string, payload *ShareRequest) (*Share, error) {
+	share := &Share{ListId: id, UserId: shareWith, Permission: payload.Permission, CreatedAt: time.Now().UTC()}
+
+	err := database.Transaction(ctx, listsDatabase(), func(tx *sqlx.Tx) error {
+		list, err := access(ctx, tx, userId, id, PermissionOwner)
+		if err != nil {
+			return err
+		}
+		if shareWith == list.OwnerId {
+			return fmt.Errorf("%w: the owner cannot share a list with themselves", ErrInvalidShare)
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO shopping_list_shares (list_id, user_id, permission, created_at)
+      VALUES (:list_id, :user_id, :permission, :created_at)
+      ON CONFLICT (list_id, user_id) DO UPDATE SET permission = EXCLUDED.permission
+      RETURNING *
+    `
+
+		// execute query
+		if err := database.NamedStructQuery(ctx, tx, q, share, share); err != nil {
+			return fmt.Errorf("sharing list: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return share, nil
+}
+
+// RemoveShare - RemoveShare is a function that stops sharing a list with a user. The owner can remove anyone,
+// and a user can remove themselves.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @param sharedWith - the user the list is shared with
+// @return error
+func RemoveShare(ctx context.Context, userId, id, sharedWith string) error {
+	need := PermissionOwner
+	if userId == sharedWith {
+		need = PermissionView
+	}
+
+	return database.Transaction(ctx, listsDatabase(), func(tx *sqlx.Tx) error {
+		if _, err := access(ctx, tx, userId, id, need); err != nil {
+			return err
+		}
+
+		// execute query
+		share := &Share{}
+		if err := database.NamedStructQuery(ctx, tx, "DELETE FROM shopping_list_shares WHERE list_id = :id AND user_id = :user_id RETURNING *", map[string]interface{}{
+			"id":      id,
+			"user_id": sharedWith,
+		}, share); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return ErrShareNotFound
+			}
+			return fmt.Errorf("deleting list share: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// AddToCart - AddToCart is a function that adds every item of a list to the cart of a user, on top of what
+// is already in it. Anyone the list is shared with can.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @return error
+func AddToCart(ctx context.Context, userId, id string) error {
+	return database.Transaction(ctx, listsDatabase(), func(tx *sqlx.Tx) error {
+		if _, err := access(ctx, tx, userId, id, PermissionView); err != nil {
+			return err
+		}
+
+		// get the items
+		items := make([]cart.Item, 0)
+		if err := database.NamedSliceQuery(ctx, tx, "SELECT product_id, quantity FROM shopping_list_items WHERE list_id = :id ORDER BY created_at, product_id", map[string]interface{}{
+			"id": id,
+		}, &items); err != nil {
+			return fmt.Errorf("selecting list items: %w", err)
+		}
+
+		// add them to the cart
+		if err := cart.Add(ctx, tx, userId, items); err != nil {
+			if errors.Is(err, cart.ErrTooManyItems) {
+				return fmt.Errorf("%w: a cart holds up to %v products", ErrTooManyItems, cart.MaxItems)
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+// Alerts - Alerts is a function that lists the latest alerts of a user.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @return alerts
+// @return error
+func Alerts(ctx context.Context, userId string) ([]Alert, error) {
+	alerts := make([]Alert, 0)
+
+	// query statement to be executed
+	q := "SELECT * FROM shopping_list_alerts WHERE user_id = :user_id ORDER BY created_at DESC, id LIMIT :limit"
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, listsDatabase(), q, map[string]interface{}{"user_id": userId, "limit": MaxAlerts}, &alerts); err != nil {
+		return nil, fmt.Errorf("selecting alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// alertsBatch - the number of wishlist items compared in one transaction
+const alertsBatch = 500
+
+// CheckAlerts - CheckAlerts is a function that compares every wishlist item with the price and stock of its
+// product, raising alerts for the ones that went on sale or came back in stock. The price is the price
+// of a single unit after the running promotions. The alerts not yet delivered are then given to the hooks.
+// Items are compared in batches that lock them, an overlapping check skips the items locked by the other.
+//
+// @param ctx - context.Context
+// @param now - time.Time
+// @return response
+// @return error
+func CheckAlerts(ctx context.Context, now time.Time) (*CheckResponse, error) {
+	response := &CheckResponse{}
+
+	// get the running promotions once for every batch
+	promotions, err := promo.List(ctx, true, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// compare the items after the last item of the previous batch
+	data := map[string]interface{}{"kind": KindWishlist, "list_id": uuid.Nil.String(), "product_id": uuid.Nil.String(), "limit": alertsBatch}
+	for {
+		checked, raised := 0, 0
+		if err := database.Transaction(ctx, listsDatabase(), func(tx *sqlx.Tx) error {
+			items := make([]watched, 0)
+			if err := database.NamedSliceQuery(ctx, tx, `
+        SELECT i.*, l.owner_id
+        FROM shopping_list_items i
+        JOIN shopping_lists l ON l.id = i.list_id
+        WHERE l.kind = :kind AND (i.list_id, i.product_id) > (CAST(:list_id AS UUID), CAST(:product_id AS UUID))
+        ORDER BY i.list_id, i.product_id
+        LIMIT :limit
+        FOR UPDATE OF i SKIP LOCKED
+      `, data, &items); err != nil {
+				return fmt.Errorf("selecting wishlist items: %w", err)
+			}
+			if len(items) < 1 {
+				return nil
+			}
+			checked = len(items)
+			data["list_id"], data["product_id"] = items[len(items)-1].ListId, items[len(items)-1].ProductId
+
+			alerts, changed, err := compare(ctx, items, promotions, now)
+			if err != nil {
+				return err
+			}
+			raised = len(alerts)
+
+			// remember what was seen on the items that changed and raise the alerts together
+			for i := range changed {
+				if err := database.NamedExecQuery(ctx, tx, `
+          UPDATE shopping_list_items SET seen_price = :seen_price, seen_in_stock = :seen_in_stock
+          WHERE list_id = :list_id AND product_id = :product_id
+        `, changed[i]); err != nil {
+					return fmt.Errorf("updating wishlist item: %w", err)
+				}
+			}
+			if len(alerts) > 0 {
+				if err := database.NamedExecQuery(ctx, tx, `
+          INSERT INTO shopping_list_alerts (id, user_id, list_id, product_id, kind, old_price, new_price, created_at)
+          VALUES (:id, :user_id, :list_id, :product_id, :kind, :old_price, :new_price, :created_at)
+        `, alerts); err != nil {
+					return fmt.Errorf("inserting alerts: %w", err)
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if checked < 1 {
+			break
+		}
+		response.Items += checked
+		response.Alerts += raised
+	}
+
+	// deliver the alerts
+	if response.Delivered, err = deliver(ctx, now); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// compare - compares wishlist items with the price and stock of their products, returning the alerts to raise
+// and the items whose seen price or stock changed.
+//
+// @param ctx - context.Context
+// @param items - []watched
+// @param promotions - the running promotions
+// @param now - time.Time
+// @return alerts
+// @return changed items
+// @return error
+func compare(ctx context.Context, items []watched, promotions []promo.Promotion, now time.Time) ([]Alert, []Item, error) {
+	// price the products
+	prices, err := effectivePrices(ctx, items, promotions, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	alerts := make([]Alert, 0)
+	changed := make([]Item, 0)
+	for i := range items {
+		product, ok := prices[items[i].ProductId]
+		if !ok {
+			continue
+		}
+		inStock := product.StockQuantity > 0
+
+		for _, kind := range Detect(items[i].SeenPrice, items[i].SeenInStock, product.Price, inStock) {
+			alert := Alert{Id: uuid.New().String(), UserId: items[i].OwnerId, ListId: items[i].ListId, ProductId: items[i].ProductId, Kind: kind, CreatedAt: now.UTC()}
+			if kind == AlertOnSale {
+				price := product.Price
+				alert.OldPrice, alert.NewPrice = items[i].SeenPrice, &price
+			}
+			alerts = append(alerts, alert)
+		}
+
+		// only the items whose product changed since the last check are written
+		if items[i].SeenPrice != nil && *items[i].SeenPrice == product.Price && items[i].SeenInStock != nil && *items[i].SeenInStock == inStock {
+			continue
+		}
+		items[i].SeenPrice, items[i].SeenInStock = &product.Price, &inStock
+		changed = append(changed, items[i].Item)
+	}
+
+	return alerts, changed, nil
+}
+
+// effectivePrices - gets the products of wishlist items with the price of a single unit after the running promotions.
+//
+// @param ctx - context.Context
+// @param items - []watched
+// @param promotions - the running promotions
+// @param now - time.Time
+// @return products by id
+// @return error
+func effectivePrices(ctx context.Context, items []watched, promotions []promo.Promotion, now time.Time) (map[string]ps.Product, error) {
+	ids := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		if !seen[item.ProductId] {
+			seen[item.ProductId] = true
+			ids = append(ids, item.ProductId)
+		}
+	}
+	if len(ids) < 1 {
+		return map[string]ps.Product{}, nil
+	}
+
+	// get the products
+	products, err := ps.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// discount every product on its own
+	prices := make(map[string]ps.Product, len(products))
+	for _, product := range products {
+		if len(promotions) > 0 {
+			result, err := promo.Evaluate([]promo.Line{{Product: product, Quantity: 1}}, promotions, now)
+			if err != nil {
+				return nil, fmt.Errorf("pricing product %v: %w", product.Id, err)
+			}
+			if !result.Total.IsNegative() && result.Total.Currency == product.Price.Currency {
+				product.Price = result.Total
+			}
+		}
+		prices[product.Id] = product
+	}
+
+	return prices, nil
+}
+
+// deliver - gives the alerts not yet delivered to the hooks, and marks them delivered when every hook took them.
+// Without hooks the alerts are kept for the first hook registered.
+//
+// @param ctx - context.Context
+// @param now - time.Time
+// @return delivered
+// @return error
+func deliver(ctx context.Context, now time.Time) (int, error) {
+	hooks := registered()
+	if len(hooks) < 1 {
+		return 0, nil
+	}
+
+	// get the pending alerts
+	pending := make([]Alert, 0)
+	if err := database.NamedSliceQuery(ctx, listsDatabase(), "SELECT * FROM shopping_list_alerts WHERE delivered_at IS NULL ORDER BY created_at, id LIMIT 1000", map[string]interface{}{}, &pending); err != nil {
+		return 0, fmt.Errorf("selecting pending alerts: %w", err)
+	}
+	if len(pending) < 1 {
+		return 0, nil
+	}
+
+	// run the hooks, a failed hook gets the alerts again on the next check
+	for _, hook := range hooks {
+		if err := hook(ctx, pending); err != nil {
+			rlog.Warn("lists.deliver", "alerts", len(pending), "error", err)
+			return 0, nil
+		}
+	}
+
+	// mark the alerts delivered
+	ids := make([]string, 0, len(pending))
+	for _, alert := range pending {
+		ids = append(ids, alert.Id)
+	}
+	if err := database.NamedExecQuery(ctx, listsDatabase(), "UPDATE shopping_list_alerts SET delivered_at = :now WHERE id = ANY(CAST(:ids AS UUID[]))", map[string]interface{}{
+		"ids": ids,
+		"now": now.UTC(),
+	}); err != nil {
+		return 0, fmt.Errorf("marking alerts delivered: %w", err)
+	}
+
+	return len(pending), nil
+}
@@ -0,0 +1,42 @@
+package lists
+
+import (
+	"encore.app/pkg/money"
+)
+
+// ranks - the order of the permissions, a permission allows everything below it
+var ranks = map[string]int{PermissionView: 1, PermissionEdit: 2, PermissionOwner: 3}
+
+// Allows - Allows reports whether a permission on a list allows what needs another permission.
+//
+// @param have - the permission of the user
+// @param need - the permission needed
+// @return bool
+func Allows(have, need string) bool {
+	return ranks[have] > 0 && ranks[have] >= ranks[need]
+}
+
+// Detect - Detect compares the price and stock of a product with what was seen at the last check and
+// returns the kinds of alerts to raise. Nothing is raised the first time a product is seen, or when
+// its price moved to another currency.
+//
+// @param seenPrice - the price at the last check, nil when never checked
+// @param seenInStock - the stock at the last check, nil when never checked
+// @param price - the price now
+// @param inStock - whether the product can be bought now
+// @return kinds
+func Detect(seenPrice *money.Money, seenInStock *bool, price money.Money, inStock bool) []string {
+	kinds := make([]string, 0, 2)
+
+	// the price dropped
+	if seenPrice != nil && seenPrice.Currency == price.Currency && price.Amount < seenPrice.Amount {
+		kinds = append(kinds, AlertOnSale)
+	}
+
+	// the product came back
+	if seenInStock != nil && !*seenInStock && inStock {
+		kinds = append(kinds, AlertBackInStock)
+	}
+
+	return kinds
+}
@@ -0,0 +1,79 @@
+package lists
+
+import (
+	"testing"
+
+	"encore.app/pkg/money"
+	"encore.app/pkg/money/moneytest"
+)
+
+// stock - creates a seen stock.
+//
+//	@param inStock - bool
+//	@return *bool
+func stock(inStock bool) *bool {
+	return &inStock
+}
+
+// TestAllows - test what each permission allows
+//
+//	@param t - testing.T
+func TestAllows(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		have  string
+		need  string
+		allow bool
+	}{
+		{have: PermissionOwner, need: PermissionOwner, allow: true},
+		{have: PermissionOwner, need: PermissionView, allow: true},
+		{have: PermissionEdit, need: PermissionEdit, allow: true},
+		{have: PermissionEdit, need: PermissionOwner, allow: false},
+		{have: PermissionView, need: PermissionView, allow: true},
+		{have: PermissionView, need: PermissionEdit, allow: false},
+		{have: "", need: PermissionView, allow: false},
+	}
+
+	for _, item := range slice {
+		if got := Allows(item.have, item.need); got != item.allow {
+			t.Errorf("%q needing %q should allow %v, got %v", item.have, item.need, item.allow, got)
+		}
+	}
+}
+
+// TestDetect - test the alerts raised by price and stock changes
+//
+//	@param t - testing.T
+func TestDetect(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		name        string
+		seenPrice   *money.Money
+		seenInStock *bool
+		price       money.Money
+		inStock     bool
+		kinds       []string
+	}{
+		{name: "first check", price: *moneytest.USDRef(500), inStock: true},
+		{name: "unchanged", seenPrice: moneytest.USDRef(500), seenInStock: stock(true), price: *moneytest.USDRef(500), inStock: true},
+		{name: "price dropped", seenPrice: moneytest.USDRef(500), seenInStock: stock(true), price: *moneytest.USDRef(450), inStock: true, kinds: []string{AlertOnSale}},
+		{name: "price rose", seenPrice: moneytest.USDRef(500), seenInStock: stock(true), price: *moneytest.USDRef(550), inStock: true},
+		{name: "other currency", seenPrice: moneytest.USDRef(500), seenInStock: stock(true), price: money.Money{Amount: 1, Currency: "EUR"}, inStock: true},
+		{name: "back in stock", seenPrice: moneytest.USDRef(500), seenInStock: stock(false), price: *moneytest.USDRef(500), inStock: true, kinds: []string{AlertBackInStock}},
+		{name: "sold out", seenPrice: moneytest.USDRef(500), seenInStock: stock(true), price: *moneytest.USDRef(500), inStock: false},
+		{name: "back on sale", seenPrice: moneytest.USDRef(500), seenInStock: stock(false), price: *moneytest.USDRef(400), inStock: true, kinds: []string{AlertOnSale, AlertBackInStock}},
+	}
+
+	for _, item := range slice {
+		kinds := Detect(item.seenPrice, item.seenInStock, item.price, item.inStock)
+		if len(kinds) != len(item.kinds) {
+			t.Errorf("%v: should raise %v, got %v", item.name, item.kinds, kinds)
+			continue
+		}
+		for i := range kinds {
+			if kinds[i] != item.kinds[i] {
+				t.Errorf("%v: should raise %v, got %v", item.name, item.kinds, kinds)
+			}
+		}
+	}
+}
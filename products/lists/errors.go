@@ -0,0 +1,14 @@
+package lists
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("list not found")
+	ErrItemNotFound    = errors.New("list item not found")
+	ErrProductNotFound = errors.New("product not found")
+	ErrShareNotFound   = errors.New("list share not found")
+	ErrAlreadyExists   = errors.New("list already exists")
+	ErrForbidden       = errors.New("not allowed to change the list")
+	ErrInvalidShare    = errors.New("invalid share")
+	ErrTooManyItems    = errors.New("too many items in the list")
+)
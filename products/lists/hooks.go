@@ -0,0 +1,36 @@
+package lists
+
+import (
+	"context"
+	"sync"
+)
+
+// AlertHook - a function that delivers alerts, e.g. by sending a notification to the owner of the list.
+// An alert is delivered again by the next check until every hook returns no error, so hooks must
+// tolerate seeing an alert more than once.
+type AlertHook func(ctx context.Context, alerts []Alert) error
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []AlertHook
+)
+
+// OnAlerts - OnAlerts registers a hook that is given the alerts raised by every check.
+//
+// @param hook - AlertHook
+func OnAlerts(hook AlertHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	hooks = append(hooks, hook)
+}
+
+// registered - gets the registered hooks.
+//
+// @return []AlertHook
+func registered() []AlertHook {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+
+	return append([]AlertHook{}, hooks...)
+}
@@ -0,0 +1,110 @@
+package lists
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+const (
+	KindWishlist = "wishlist" // products wanted later, watched for sales and restocks
+	KindShopping = "shopping" // products to buy soon
+
+	PermissionOwner = "owner" // may rename, delete and share the list
+	PermissionEdit  = "edit"  // may change the items of the list
+	PermissionView  = "view"  // may see the list and add it to their cart
+
+	AlertOnSale      = "on_sale"       // the price of the product dropped
+	AlertBackInStock = "back_in_stock" // the product can be bought again
+
+	// MaxItems - the most products a list can hold
+	MaxItems = 500
+	// MaxAlerts - the most alerts listed for a user
+	MaxAlerts = 100
+)
+
+// List - a named list of products, with the access of the user reading it
+type List struct {
+	Id         string    `json:"id" db:"id"`
+	OwnerId    string    `json:"ownerId" db:"owner_id"`
+	Name       string    `json:"name" db:"name"`
+	Kind       string    `json:"kind" db:"kind"`
+	Permission string    `json:"permission" db:"permission"` // owner, edit or view
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type ListRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+	Kind string `json:"kind" validate:"required,oneof=wishlist shopping"`
+}
+
+type ListsResponse struct {
+	Lists []List `json:"data"`
+}
+
+type Item struct {
+	ListId      string       `json:"listId" db:"list_id"`
+	ProductId   string       `json:"productId" db:"product_id"`
+	Quantity    int          `json:"quantity" db:"quantity"`
+	Note        string       `json:"note" db:"note"`
+	SeenPrice   *money.Money `json:"seenPrice" db:"seen_price"`      // the price at the last alerts check
+	SeenInStock *bool        `json:"seenInStock" db:"seen_in_stock"` // the stock at the last alerts check
+	AddedBy     string       `json:"addedBy" db:"added_by"`
+	CreatedAt   time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updatedAt" db:"updated_at"`
+}
+
+type ItemRequest struct {
+	Quantity int    `json:"quantity" validate:"required,min=1,max=1000"`
+	Note     string `json:"note" validate:"omitempty,max=1000"`
+}
+
+// ItemView - an item of a list with its product
+type ItemView struct {
+	Item
+	Name          string      `json:"name" db:"name"`
+	Price         money.Money `json:"price" db:"price"`
+	StockQuantity int         `json:"stockQuantity" db:"stock_quantity"`
+}
+
+// Share - a user a list is shared with
+type Share struct {
+	ListId     string    `json:"listId" db:"list_id"`
+	UserId     string    `json:"userId" db:"user_id"`
+	Permission string    `json:"permission" db:"permission"` // edit or view
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+}
+
+type ShareRequest struct {
+	Permission string `json:"permission" validate:"required,oneof=view edit"`
+}
+
+type ListResponse struct {
+	List   *List      `json:"list"`
+	Items  []ItemView `json:"items"`
+	Shares []Share    `json:"shares"` // only listed for the owner
+}
+
+// Alert - a wishlist item that went on sale or came back in stock
+type Alert struct {
+	Id          string       `json:"id" db:"id"`
+	UserId      string       `json:"userId" db:"user_id"`
+	ListId      string       `json:"listId" db:"list_id"`
+	ProductId   string       `json:"productId" db:"product_id"`
+	Kind        string       `json:"kind" db:"kind"`
+	OldPrice    *money.Money `json:"oldPrice" db:"old_price"`
+	NewPrice    *money.Money `json:"newPrice" db:"new_price"`
+	CreatedAt   time.Time    `json:"createdAt" db:"created_at"`
+	DeliveredAt *time.Time   `json:"deliveredAt" db:"delivered_at"` // when every hook took the alert
+}
+
+type AlertsResponse struct {
+	Alerts []Alert `json:"data"`
+}
+
+type CheckResponse struct {
+	Items     int `json:"items"`     // the wishlist items checked
+	Alerts    int `json:"alerts"`    // the alerts raised
+	Delivered int `json:"delivered"` // the alerts taken by every hook
+}
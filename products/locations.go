@@ -0,0 +1,304 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/products/locations"
+)
+
+// =====================================================================================================================
+// LOCATIONS
+// =====================================================================================================================
+
+// ListLocations - List the active stores and warehouses by name
+//
+//	@param ctx - context.Context
+//	@param params - *locations.LocationsQuery
+//	@return locations
+//	@return error
+//
+// encore:api public method=GET path=/locations
+func ListLocations(ctx context.Context, params *locations.LocationsQuery) (*locations.LocationsResponse, error) {
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &locations.LocationsResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the locations
+	all, err := locations.List(ctx, params)
+	if err != nil {
+		return &locations.LocationsResponse{}, err
+	}
+
+	return &locations.LocationsResponse{Locations: all}, nil
+}
+
+// GetLocation - Get a store or warehouse with its opening hours
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return location
+//	@return error
+//
+// encore:api public method=GET path=/locations/:id
+func GetLocation(ctx context.Context, id string) (*locations.Location, error) {
+	// get the location
+	location, err := locations.Get(ctx, id)
+	if err != nil {
+		return &locations.Location{}, locationError(err)
+	}
+
+	return location, nil
+}
+
+// CreateLocation - Create a store or warehouse
+//
+//	@param ctx - context.Context
+//	@param payload - *locations.LocationRequest
+//	@return location
+//	@return error
+//
+// encore:api auth method=POST path=/locations
+func CreateLocation(ctx context.Context, payload *locations.LocationRequest) (*locations.Location, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &locations.Location{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &locations.Location{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the location
+	location, err := locations.Create(ctx, payload)
+	if err != nil {
+		return &locations.Location{}, locationError(err)
+	}
+
+	return location, nil
+}
+
+// UpdateLocation - Update a store or warehouse, deactivate it to stop keeping stock there
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *locations.LocationRequest
+//	@return location
+//	@return error
+//
+// encore:api auth method=PUT path=/locations/:id
+func UpdateLocation(ctx context.Context, id string, payload *locations.LocationRequest) (*locations.Location, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &locations.Location{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &locations.Location{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// update the location
+	location, err := locations.Update(ctx, id, payload)
+	if err != nil {
+		return &locations.Location{}, locationError(err)
+	}
+
+	return location, nil
+}
+
+// ListLocationStock - List the stock of the products at a location by name
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param params - *locations.StockQuery
+//	@return stock
+//	@return error
+//
+// encore:api auth method=GET path=/locations/:id/stock
+func ListLocationStock(ctx context.Context, id string, params *locations.StockQuery) (*locations.PaginatedStockResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &locations.PaginatedStockResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &locations.PaginatedStockResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the stock
+	response, err := locations.ListStock(ctx, id, params)
+	if err != nil {
+		return &locations.PaginatedStockResponse{}, locationError(err)
+	}
+
+	return response, nil
+}
+
+// GetAvailability - Get where a product can be collected and how many units each location has
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param params - *locations.AvailabilityQuery
+//	@return availability
+//	@return error
+//
+// encore:api public method=GET path=/products/:id/availability
+func GetAvailability(ctx context.Context, id string, params *locations.AvailabilityQuery) (*locations.AvailabilityResponse, error) {
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &locations.AvailabilityResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// look the product up
+	response, err := locations.Lookup(ctx, id, params)
+	if err != nil {
+		return &locations.AvailabilityResponse{}, locationError(err)
+	}
+
+	return response, nil
+}
+
+// =====================================================================================================================
+// TRANSFERS
+// =====================================================================================================================
+
+// ListTransfers - List stock transfers between locations, the newest first
+//
+//	@param ctx - context.Context
+//	@param params - *locations.TransfersQuery
+//	@return transfers
+//	@return error
+//
+// encore:api auth method=GET path=/transfers
+func ListTransfers(ctx context.Context, params *locations.TransfersQuery) (*locations.PaginatedTransfersResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &locations.PaginatedTransfersResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &locations.PaginatedTransfersResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the transfers
+	response, err := locations.ListTransfers(ctx, params)
+	if err != nil {
+		return &locations.PaginatedTransfersResponse{}, locationError(err)
+	}
+
+	return response, nil
+}
+
+// GetTransfer - Get a stock transfer with its lines
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return transfer
+//	@return error
+//
+// encore:api auth method=GET path=/transfers/:id
+func GetTransfer(ctx context.Context, id string) (*locations.TransferResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &locations.TransferResponse{}, err
+	}
+
+	// get the transfer
+	transfer, err := locations.GetTransfer(ctx, id)
+	if err != nil {
+		return &locations.TransferResponse{}, locationError(err)
+	}
+
+	return transfer, nil
+}
+
+// CreateTransfer - Draft a stock transfer from one location to another
+//
+//	@param ctx - context.Context
+//	@param payload - *locations.TransferRequest
+//	@return transfer
+//	@return error
+//
+// encore:api auth method=POST path=/transfers
+func CreateTransfer(ctx context.Context, payload *locations.TransferRequest) (*locations.TransferResponse, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &locations.TransferResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &locations.TransferResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// draft the transfer
+	transfer, err := locations.CreateTransfer(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &locations.TransferResponse{}, locationError(err)
+	}
+
+	return transfer, nil
+}
+
+// ActOnTransfer - Ship a draft transfer, receive a transfer in transit with the units that arrived, or cancel a
+// transfer before it arrives
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *locations.TransferActionRequest
+//	@return transfer
+//	@return error
+//
+// encore:api auth method=POST path=/transfers/:id/actions
+func ActOnTransfer(ctx context.Context, id string, payload *locations.TransferActionRequest) (*locations.TransferResponse, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &locations.TransferResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &locations.TransferResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// act on the transfer
+	transfer, err := locations.Act(ctx, claims.Subject.Id, id, payload, time.Now())
+	if err != nil {
+		return &locations.TransferResponse{}, locationError(err)
+	}
+
+	return transfer, nil
+}
+
+// locationError - maps location store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func locationError(err error) error {
+	switch {
+	case errors.Is(err, locations.ErrNotFound), errors.Is(err, locations.ErrProductNotFound),
+		errors.Is(err, locations.ErrTransferNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, locations.ErrAlreadyExists):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, locations.ErrInvalidHours), errors.Is(err, locations.ErrInvalidTransfer):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, locations.ErrInactive), errors.Is(err, locations.ErrDefaultLocation),
+		errors.Is(err, locations.ErrInvalidTransition):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	default:
+		return inventoryError(err)
+	}
+}
@@ -0,0 +1,689 @@
+package locations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/pagination"
+	"encore.app/products/inventory"
+)
+
+// the products database
+var locationsDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// =====================================================================================================================
+// LOCATIONS
+// =====================================================================================================================
+
+// get - gets a location, locking it when asked.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param id - string
+// @param lock - lock the location until the transaction ends
+// @return location
+// @return error
+func get(ctx context.Context, db sqlx.ExtContext, id string, lock bool) (*Location, error) {
+	q := "SELECT * FROM locations WHERE id = :id"
+	if lock {
+		q += " FOR UPDATE"
+	}
+
+	location := &Location{}
+	if err := database.NamedStructQuery(ctx, db, q, map[string]interface{}{"id": id}, location); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("selecting location: %w", err)
+	}
+
+	return location, nil
+}
+
+// List - List is a function that lists the active locations by name.
+//
+// @param ctx - context.Context
+// @param params - *LocationsQuery
+// @return locations
+// @return error
+func List(ctx context.Context, params *LocationsQuery) ([]Location, error) {
+	// query statement to be executed
+	q := `
+    SELECT * FROM locations
+    WHERE active AND (:kind = '' OR kind = :kind) AND (NOT :click_and_collect OR click_and_collect)
+    ORDER BY name, id
+  `
+
+	// execute query
+	locations := make([]Location, 0)
+	if err := database.NamedSliceQuery(ctx, locationsDatabase(), q, map[string]interface{}{
+		"kind":              params.Kind,
+		"click_and_collect": params.ClickAndCollect,
+	}, &locations); err != nil {
+		return nil, fmt.Errorf("selecting locations: %w", err)
+	}
+
+	return locations, nil
+}
+
+// Get - Get is a function that gets a location.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return location
+// @return error
+func Get(ctx context.Context, id string) (*Location, error) {
+	return get(ctx, locationsDatabase(), id, false)
+}
+
+// save - inserts or updates a location, moving the default to it when asked.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param location - *Location
+// @param q - the insert or update statement
+// @return error
+func save(ctx context.Context, tx *sqlx.Tx, location *Location, q string) error {
+	// check the code is free
+	count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM locations WHERE code = :code AND id <> :id", location)
+	if err != nil {
+		return fmt.Errorf("counting locations: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: a location has code %v", ErrAlreadyExists, location.Code)
+	}
+
+	// one default location at a time
+	if location.IsDefault {
+		if !location.Active {
+			return ErrDefaultLocation
+		}
+		if err := database.NamedExecQuery(ctx, tx, "UPDATE locations SET is_default = FALSE, updated_at = :updated_at WHERE is_default AND id <> :id", location); err != nil {
+			return fmt.Errorf("updating default location: %w", err)
+		}
+	}
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, tx, q, location); err != nil {
+		return fmt.Errorf("saving location: %w", err)
+	}
+
+	return nil
+}
+
+// Create - Create is a function that creates a location.
+//
+// @param ctx - context.Context
+// @param payload - *LocationRequest
+// @return location
+// @return error
+func Create(ctx context.Context, payload *LocationRequest) (*Location, error) {
+	hours, err := Normalize(payload.OpeningHours)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	location := &Location{
+		Id:              uuid.New().String(),
+		Code:            payload.Code,
+		Name:            payload.Name,
+		Kind:            payload.Kind,
+		Address:         payload.Address,
+		City:            payload.City,
+		PostalCode:      payload.PostalCode,
+		Country:         payload.Country,
+		Phone:           payload.Phone,
+		OpeningHours:    hours,
+		ClickAndCollect: payload.ClickAndCollect,
+		Active:          payload.Active == nil || *payload.Active,
+		IsDefault:       payload.IsDefault,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO locations (id, code, name, kind, address, city, postal_code, country, phone, opening_hours,
+                           click_and_collect, active, is_default, created_at, updated_at)
+    VALUES (:id, :code, :name, :kind, :address, :city, :postal_code, :country, :phone, :opening_hours,
+            :click_and_collect, :active, :is_default, :created_at, :updated_at)
+  `
+
+	if err := database.Transaction(ctx, locationsDatabase(), func(tx *sqlx.Tx) error {
+		return save(ctx, tx, location, q)
+	}); err != nil {
+		return nil, err
+	}
+
+	return location, nil
+}
+
+// Update - Update is a function that updates a location. The default location stays active and stays the default
+// until another location takes over.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param payload - *LocationRequest
+// @return location
+// @return error
+func Update(ctx context.Context, id string, payload *LocationRequest) (*Location, error) {
+	hours, err := Normalize(payload.OpeningHours)
+	if err != nil {
+		return nil, err
+	}
+
+	var location *Location
+	err = database.Transaction(ctx, locationsDatabase(), func(tx *sqlx.Tx) error {
+		var err error
+		if location, err = get(ctx, tx, id, true); err != nil {
+			return err
+		}
+		if location.IsDefault && !payload.IsDefault {
+			return fmt.Errorf("%w: make another location the default instead", ErrDefaultLocation)
+		}
+
+		location.Code = payload.Code
+		location.Name = payload.Name
+		location.Kind = payload.Kind
+		location.Address = payload.Address
+		location.City = payload.City
+		location.PostalCode = payload.PostalCode
+		location.Country = payload.Country
+		location.Phone = payload.Phone
+		location.OpeningHours = hours
+		location.ClickAndCollect = payload.ClickAndCollect
+		if payload.Active != nil {
+			location.Active = *payload.Active
+		}
+		location.IsDefault = payload.IsDefault
+		location.UpdatedAt = time.Now().UTC()
+
+		// query statement to be executed
+		q := `
+      UPDATE locations
+      SET code = :code, name = :name, kind = :kind, address = :address, city = :city, postal_code = :postal_code,
+          country = :country, phone = :phone, opening_hours = :opening_hours, click_and_collect = :click_and_collect,
+          active = :active, is_default = :is_default, updated_at = :updated_at
+      WHERE id = :id
+    `
+
+		return save(ctx, tx, location, q)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return location, nil
+}
+
+// stockSchema - the fields of the stock at a location allowed in list queries
+var stockSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "productId", Column: "product_id", Type: pagination.UUID, Sortable: true},
+		{Name: "name", Column: "name", Type: pagination.String, Sortable: true},
+		{Name: "quantity", Column: "quantity", Type: pagination.Number, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "product_id",
+	DefaultSort: "name",
+}
+
+// ListStock - ListStock is a function that lists the stock of the products at a location by name unless
+// sorted otherwise.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param params - *StockQuery
+// @return stock
+// @return error
+func ListStock(ctx context.Context, id string, params *StockQuery) (*PaginatedStockResponse, error) {
+	if _, err := get(ctx, locationsDatabase(), id, false); err != nil {
+		return nil, err
+	}
+
+	// parse the filter and sort
+	statement, err := stockSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	statement.Args["location_id"] = id
+	if params.InStock {
+		statement.AddCondition("quantity > 0", nil)
+	}
+
+	// execute query
+	stock := make([]Stock, 0)
+	page, err := database.NamedPageQuery(ctx, locationsDatabase(), `(
+    SELECT s.product_id, p.name, s.quantity, s.updated_at
+    FROM location_stock s
+    JOIN products p ON p.id = s.product_id
+    WHERE s.location_id = CAST(:location_id AS UUID)
+  ) AS stock`, statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &stock)
+	if err != nil {
+		return nil, fmt.Errorf("selecting location stock: %w", err)
+	}
+
+	return &PaginatedStockResponse{
+		Stock:           stock,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// Lookup - Lookup is a function that gets the stock of a product at the active locations customers
+// can collect at, with the units on their way there.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param params - *AvailabilityQuery
+// @return availability
+// @return error
+func Lookup(ctx context.Context, productId string, params *AvailabilityQuery) (*AvailabilityResponse, error) {
+	data := map[string]interface{}{"product_id": productId, "location_id": params.LocationId}
+
+	// check if the product exists
+	count, err := database.NamedCountQuery(ctx, locationsDatabase(), "SELECT COUNT(*) FROM products WHERE id = :product_id", data)
+	if err != nil {
+		return nil, fmt.Errorf("counting products: %w", err)
+	}
+	if count < 1 {
+		return nil, ErrProductNotFound
+	}
+
+	// query statement to be executed
+	q := `
+    SELECT l.id AS location_id, l.code, l.name, l.address, l.city, l.postal_code, l.opening_hours,
+           COALESCE(s.quantity, 0) AS quantity, COALESCE(t.incoming, 0) AS incoming
+    FROM locations l
+    LEFT JOIN location_stock s ON s.location_id = l.id AND s.product_id = :product_id
+    LEFT JOIN (
+      SELECT tr.to_location_id, SUM(tl.quantity) AS incoming
+      FROM transfers tr
+      JOIN transfer_lines tl ON tl.transfer_id = tr.id
+      WHERE tr.status = 'in_transit' AND tl.product_id = :product_id
+      GROUP BY tr.to_location_id
+    ) t ON t.to_location_id = l.id
+    WHERE l.active AND l.click_and_collect AND (:location_id = '' OR CAST(l.id AS TEXT) = :location_id)
+    ORDER BY l.name, l.id
+  `
+
+	// execute query
+	response := &AvailabilityResponse{ProductId: productId, Availability: make([]Availability, 0)}
+	if err := database.NamedSliceQuery(ctx, locationsDatabase(), q, data, &response.Availability); err != nil {
+		return nil, fmt.Errorf("selecting availability: %w", err)
+	}
+
+	wanted := params.Quantity
+	if wanted < 1 {
+		wanted = 1
+	}
+	for i := range response.Availability {
+		response.Availability[i].Available = response.Availability[i].Quantity >= wanted
+	}
+
+	return response, nil
+}
+
+// =====================================================================================================================
+// TRANSFERS
+// =====================================================================================================================
+
+// getTransfer - gets a transfer with its lines, locking the transfer when asked.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param id - string
+// @param lock - lock the transfer until the transaction ends
+// @return transfer
+// @return error
+func getTransfer(ctx context.Context, db sqlx.ExtContext, id string, lock bool) (*TransferResponse, error) {
+	q := "SELECT * FROM transfers WHERE id = :id"
+	if lock {
+		q += " FOR UPDATE"
+	}
+
+	data := map[string]interface{}{"id": id}
+	response := &TransferResponse{Transfer: &Transfer{}, Lines: make([]TransferLine, 0)}
+	if err := database.NamedStructQuery(ctx, db, q, data, response.Transfer); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("selecting transfer: %w", err)
+	}
+	if err := database.NamedSliceQuery(ctx, db, "SELECT * FROM transfer_lines WHERE transfer_id = :id ORDER BY product_id", data, &response.Lines); err != nil {
+		return nil, fmt.Errorf("selecting transfer lines: %w", err)
+	}
+
+	return response, nil
+}
+
+// CreateTransfer - CreateTransfer is a function that drafts a transfer between two active locations.
+//
+// @param ctx - context.Context
+// @param userId - the admin drafting the transfer
+// @param payload - *TransferRequest
+// @return transfer
+// @return error
+func CreateTransfer(ctx context.Context, userId string, payload *TransferRequest) (*TransferResponse, error) {
+	if payload.FromLocationId == payload.ToLocationId {
+		return nil, fmt.Errorf("%w: the locations must differ", ErrInvalidTransfer)
+	}
+
+	now := time.Now().UTC()
+	transfer := &Transfer{
+		Id:             uuid.New().String(),
+		FromLocationId: payload.FromLocationId,
+		ToLocationId:   payload.ToLocationId,
+		Status:         StatusDraft,
+		Notes:          payload.Notes,
+		CreatedBy:      userId,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	// check the lines
+	ids := make([]string, 0, len(payload.Lines))
+	seen := make(map[string]bool, len(payload.Lines))
+	lines := make([]TransferLine, 0, len(payload.Lines))
+	for _, line := range payload.Lines {
+		if seen[line.ProductId] {
+			return nil, fmt.Errorf("%w: product %v is on the transfer twice", ErrInvalidTransfer, line.ProductId)
+		}
+		seen[line.ProductId] = true
+		ids = append(ids, line.ProductId)
+		lines = append(lines, TransferLine{TransferId: transfer.Id, ProductId: line.ProductId, Quantity: line.Quantity})
+	}
+
+	var response *TransferResponse
+	err := database.Transaction(ctx, locationsDatabase(), func(tx *sqlx.Tx) error {
+		for _, id := range []string{transfer.FromLocationId, transfer.ToLocationId} {
+			location, err := get(ctx, tx, id, false)
+			if err != nil {
+				return err
+			}
+			if !location.Active {
+				return fmt.Errorf("%w[%v]", ErrInactive, location.Code)
+			}
+		}
+
+		// check the products exist
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM products WHERE id = ANY(CAST(:ids AS UUID[]))", map[string]interface{}{"ids": ids})
+		if err != nil {
+			return fmt.Errorf("counting products: %w", err)
+		}
+		if count < len(ids) {
+			return ErrProductNotFound
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO transfers (id, from_location_id, to_location_id, status, notes, created_by, created_at, updated_at)
+      VALUES (:id, :from_location_id, :to_location_id, :status, :notes, :created_by, :created_at, :updated_at)
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, transfer); err != nil {
+			return fmt.Errorf("inserting transfer: %w", err)
+		}
+		if err := database.NamedExecQuery(ctx, tx, "INSERT INTO transfer_lines (transfer_id, product_id, quantity) VALUES (:transfer_id, :product_id, :quantity)", lines); err != nil {
+			return fmt.Errorf("inserting transfer lines: %w", err)
+		}
+
+		response, err = getTransfer(ctx, tx, transfer.Id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetTransfer - GetTransfer is a function that gets a transfer with its lines.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return transfer
+// @return error
+func GetTransfer(ctx context.Context, id string) (*TransferResponse, error) {
+	return getTransfer(ctx, locationsDatabase(), id, false)
+}
+
+// transfersSchema - the fields of a transfer allowed in list queries
+var transfersSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "number", Column: "number", Type: pagination.Number, Sortable: true},
+		{Name: "fromLocationId", Column: "from_location_id", Type: pagination.UUID},
+		{Name: "toLocationId", Column: "to_location_id", Type: pagination.UUID},
+		{Name: "status", Column: "status", Type: pagination.String},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-createdAt",
+}
+
+// ListTransfers - ListTransfers is a function that lists transfers, the newest first unless sorted otherwise.
+//
+// @param ctx - context.Context
+// @param params - *TransfersQuery
+// @return transfers
+// @return error
+func ListTransfers(ctx context.Context, params *TransfersQuery) (*PaginatedTransfersResponse, error) {
+	// parse the filter and sort
+	statement, err := transfersSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	if len(params.LocationId) > 0 {
+		statement.AddCondition("(from_location_id = CAST(:location_id AS UUID) OR to_location_id = CAST(:location_id AS UUID))",
+			map[string]interface{}{"location_id": params.LocationId})
+	}
+	if len(params.Status) > 0 {
+		statement.AddCondition("status = :status", map[string]interface{}{"status": params.Status})
+	}
+
+	// execute query
+	transfers := make([]Transfer, 0)
+	page, err := database.NamedPageQuery(ctx, locationsDatabase(), "transfers", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &transfers)
+	if err != nil {
+		return nil, fmt.Errorf("selecting transfers: %w", err)
+	}
+
+	return &PaginatedTransfersResponse{
+		Transfers:       transfers,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// Act - Act is a function that ships, receives or cancels a transfer. Shipping takes the units out of the stock
+// of the origin, they stay in the stock of the product in transit until received into the stock of the destination.
+// Units that did not arrive are written off at the destination, and cancelling a transfer in transit puts its units
+// back at the origin.
+//
+// @param ctx - context.Context
+// @param userId - the admin acting on the transfer
+// @param id - string
+// @param payload - *TransferActionRequest
+// @param now - time.Time
+// @return transfer
+// @return error
+func Act(ctx context.Context, userId, id string, payload *TransferActionRequest, now time.Time) (*TransferResponse, error) {
+	if len(payload.Lines) > 0 && payload.Action != ActionReceive {
+		return nil, fmt.Errorf("%w: lines are only given on receiving", ErrInvalidTransfer)
+	}
+
+	var response *TransferResponse
+	err := database.Transaction(ctx, locationsDatabase(), func(tx *sqlx.Tx) error {
+		current, err := getTransfer(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		transfer := current.Transfer
+
+		status, err := Transition(transfer.Status, payload.Action)
+		if err != nil {
+			return err
+		}
+		shipped := transfer.Status == StatusInTransit
+		transfer.Status = status
+		transfer.UpdatedAt = now.UTC()
+
+		// move the stock out of the origin, into the destination or back into the origin
+		reference := fmt.Sprintf("TR-%06d", transfer.Number)
+		switch status {
+		case StatusInTransit:
+			transfer.ShippedAt = &transfer.UpdatedAt
+			for _, line := range current.Lines {
+				movement := &inventory.Movement{
+					ProductId:  line.ProductId,
+					LocationId: transfer.FromLocationId,
+					Quantity:   -line.Quantity,
+					Reason:     inventory.ReasonTransfer,
+					Reference:  reference,
+					CreatedBy:  &userId,
+					CreatedAt:  transfer.UpdatedAt,
+				}
+				if err := inventory.Move(ctx, tx, movement); err != nil {
+					return fmt.Errorf("shipping product %v: %w", line.ProductId, err)
+				}
+
+				// keep the movement, the lots shipped are the lots received
+				if err := database.NamedExecQuery(ctx, tx, `
+          UPDATE transfer_lines SET movement_id = :movement_id WHERE transfer_id = :transfer_id AND product_id = :product_id
+        `, map[string]interface{}{
+					"movement_id": movement.Id,
+					"transfer_id": transfer.Id,
+					"product_id":  line.ProductId,
+				}); err != nil {
+					return fmt.Errorf("updating transfer line: %w", err)
+				}
+			}
+		case StatusReceived:
+			transfer.ReceivedAt = &transfer.UpdatedAt
+			arrived, err := Arrived(current.Lines, payload.Lines)
+			if err != nil {
+				return err
+			}
+			for i := range current.Lines {
+				line := &current.Lines[i]
+				if err := unship(ctx, tx, userId, line, transfer.ToLocationId, reference, transfer.UpdatedAt); err != nil {
+					return fmt.Errorf("receiving product %v: %w", line.ProductId, err)
+				}
+
+				// write off the units lost on the way
+				if lost := line.Quantity - arrived[i]; lost > 0 {
+					if err := inventory.Move(ctx, tx, &inventory.Movement{
+						ProductId:  line.ProductId,
+						LocationId: transfer.ToLocationId,
+						Quantity:   -lost,
+						Reason:     inventory.ReasonAdjustment,
+						Reference:  reference,
+						Note:       "Lost in transit",
+						CreatedBy:  &userId,
+						CreatedAt:  transfer.UpdatedAt,
+					}); err != nil {
+						return fmt.Errorf("writing off product %v: %w", line.ProductId, err)
+					}
+				}
+
+				line.QuantityReceived = &arrived[i]
+				if err := database.NamedExecQuery(ctx, tx, `
+          UPDATE transfer_lines SET quantity_received = :quantity_received WHERE transfer_id = :transfer_id AND product_id = :product_id
+        `, line); err != nil {
+					return fmt.Errorf("updating transfer line: %w", err)
+				}
+			}
+		case StatusCancelled:
+			transfer.CancelledAt = &transfer.UpdatedAt
+			if shipped {
+				for i := range current.Lines {
+					line := &current.Lines[i]
+					if err := unship(ctx, tx, userId, line, transfer.FromLocationId, reference, transfer.UpdatedAt); err != nil {
+						return fmt.Errorf("returning product %v: %w", line.ProductId, err)
+					}
+				}
+			}
+		}
+
+		if err := database.NamedExecQuery(ctx, tx, `
+      UPDATE transfers
+      SET status = :status, shipped_at = :shipped_at, received_at = :received_at, cancelled_at = :cancelled_at,
+          updated_at = :updated_at
+      WHERE id = :id
+    `, transfer); err != nil {
+			return fmt.Errorf("updating transfer: %w", err)
+		}
+
+		response = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// unship - puts the units of a line shipped into the stock of a location, in the lots they were shipped from.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - the admin acting on the transfer
+// @param line - the line shipped
+// @param locationId - the destination, or the origin when the transfer is cancelled
+// @param reference - the number of the transfer
+// @param at - time.Time
+// @return error
+func unship(ctx context.Context, tx *sqlx.Tx, userId string, line *TransferLine, locationId, reference string, at time.Time) error {
+	var lots []inventory.Allocation
+	if line.MovementId != nil {
+		var err error
+		if lots, err = inventory.MovementLots(ctx, tx, *line.MovementId); err != nil {
+			return err
+		}
+	}
+
+	return inventory.Move(ctx, tx, &inventory.Movement{
+		ProductId:  line.ProductId,
+		LocationId: locationId,
+		Quantity:   line.Quantity,
+		Reason:     inventory.ReasonTransfer,
+		Reference:  reference,
+		Lots:       lots,
+		CreatedBy:  &userId,
+		CreatedAt:  at,
+	})
+}
@@ -0,0 +1,133 @@
+package locations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"encore.app/pkg/database"
+	"encore.app/products/inventory"
+	"encore.app/products/productstest"
+)
+
+// transfer - creates a product with 10 units at one of two new locations, and drafts a transfer of 6 units of it.
+//
+//	@param t - testing.T
+//	@param ctx - context.Context
+//	@param userId - string
+//	@return the transfer
+func transfer(t *testing.T, ctx context.Context, userId string) *TransferResponse {
+	productId, err := productstest.Create(ctx, locationsDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		code := uuid.New().String()[:8]
+		location, err := Create(ctx, &LocationRequest{Code: code, Name: "location " + code, Kind: KindStore})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, location.Id)
+	}
+	if _, err := inventory.Adjust(ctx, userId, productId, &inventory.AdjustRequest{LocationId: ids[0], Quantity: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := CreateTransfer(ctx, userId, &TransferRequest{
+		FromLocationId: ids[0],
+		ToLocationId:   ids[1],
+		Lines:          []TransferLineRequest{{ProductId: productId, Quantity: 6}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
+
+// levels - gets the stock of a product at the locations of a transfer, and the number of its stock changed events.
+//
+//	@param t - testing.T
+//	@param ctx - context.Context
+//	@param response - the transfer
+//	@return the stock of the product, in transit, at the origin and at the destination, and the events
+func levels(t *testing.T, ctx context.Context, response *TransferResponse) (int, int, int, int, int) {
+	productId := response.Lines[0].ProductId
+	levels, err := inventory.Levels(ctx, productId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := make(map[string]int, len(levels.Levels))
+	for _, level := range levels.Levels {
+		at[level.LocationId] = level.Quantity
+	}
+	events, err := database.NamedCountQuery(ctx, locationsDatabase(), "SELECT COUNT(*) FROM outbox WHERE payload->>'productId' = :product_id", map[string]interface{}{
+		"product_id": productId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return levels.Total, levels.InTransit, at[response.Transfer.FromLocationId], at[response.Transfer.ToLocationId], events
+}
+
+// TestShip - test shipping and receiving a transfer keeps the units in the stock of the product
+//
+//	@param t - testing.T
+func TestShip(t *testing.T) {
+	if !database.Available(locationsDatabase) {
+		t.Skip("the products database is not available, run with encore test")
+	}
+	ctx := context.Background()
+	userId := uuid.New().String()
+	response := transfer(t, ctx, userId)
+	id := response.Transfer.Id
+
+	// ship the units, only the adjustment putting them at the origin changed the stock
+	if _, err := Act(ctx, userId, id, &TransferActionRequest{Action: ActionShip}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if total, transit, from, to, events := levels(t, ctx, response); total != 10 || transit != 6 || from != 4 || to != 0 || events != 1 {
+		t.Errorf("expected 10 units with 6 in transit and 4 at the origin, got %v %v %v %v after %v events", total, transit, from, to, events)
+	}
+
+	// 5 units arrive, the one lost is written off
+	received, err := Act(ctx, userId, id, &TransferActionRequest{
+		Action: ActionReceive,
+		Lines:  []TransferReceiptLine{{ProductId: response.Lines[0].ProductId, Quantity: 5}},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := received.Lines[0].QuantityReceived; q == nil || *q != 5 {
+		t.Errorf("expected 5 units received, got %v", q)
+	}
+	if total, transit, from, to, events := levels(t, ctx, response); total != 9 || transit != 0 || from != 4 || to != 5 || events != 2 {
+		t.Errorf("expected 9 units with 4 at the origin and 5 at the destination, got %v %v %v %v after %v events", total, transit, from, to, events)
+	}
+}
+
+// TestCancelInTransit - test cancelling a transfer in transit puts its units back at the origin
+//
+//	@param t - testing.T
+func TestCancelInTransit(t *testing.T) {
+	if !database.Available(locationsDatabase) {
+		t.Skip("the products database is not available, run with encore test")
+	}
+	ctx := context.Background()
+	userId := uuid.New().String()
+	response := transfer(t, ctx, userId)
+	id := response.Transfer.Id
+
+	for _, action := range []string{ActionShip, ActionCancel} {
+		if _, err := Act(ctx, userId, id, &TransferActionRequest{Action: action}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total, transit, from, to, events := levels(t, ctx, response); total != 10 || transit != 0 || from != 10 || to != 0 || events != 1 {
+		t.Errorf("expected the 10 units back at the origin, got %v %v %v %v after %v events", total, transit, from, to, events)
+	}
+}
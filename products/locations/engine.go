@@ -0,0 +1,143 @@
+package locations
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"encore.app/pkg/lifecycle"
+)
+
+// OpeningHours - the hours a location is open every day of the week, kept as json
+type OpeningHours []Opening
+
+// Value - encodes the hours as json, an empty list when there are none.
+//
+//	@return driver.Value
+//	@return error
+func (h OpeningHours) Value() (driver.Value, error) {
+	if h == nil {
+		return "[]", nil
+	}
+
+	b, err := json.Marshal([]Opening(h))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan - decodes the json of the hours.
+//
+//	@param src - interface{}
+//	@return error
+func (h *OpeningHours) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*h = OpeningHours{}
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("scanning opening hours: unsupported type %T", src)
+	}
+
+	hours := make([]Opening, 0)
+	if err := json.Unmarshal(b, &hours); err != nil {
+		return fmt.Errorf("scanning opening hours: %w", err)
+	}
+	*h = hours
+
+	return nil
+}
+
+// Normalize - Normalize sorts the hours by day and time, and checks a location opens before it closes and is
+// not given overlapping hours on a day. Times are 24 hour, e.g. 09:00, so they compare as strings.
+//
+// @param hours - []Opening
+// @return the sorted hours
+// @return error
+func Normalize(hours []Opening) (OpeningHours, error) {
+	sorted := append(OpeningHours{}, hours...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Day != sorted[j].Day {
+			return sorted[i].Day < sorted[j].Day
+		}
+		return sorted[i].Opens < sorted[j].Opens
+	})
+
+	for i, opening := range sorted {
+		if opening.Day < 0 || opening.Day > 6 {
+			return nil, fmt.Errorf("%w: day %v is not a day of the week", ErrInvalidHours, opening.Day)
+		}
+		if opening.Opens >= opening.Closes {
+			return nil, fmt.Errorf("%w: opens at %v after closing at %v on day %v", ErrInvalidHours, opening.Opens, opening.Closes, opening.Day)
+		}
+		if i > 0 && sorted[i-1].Day == opening.Day && sorted[i-1].Closes > opening.Opens {
+			return nil, fmt.Errorf("%w: hours overlap on day %v", ErrInvalidHours, opening.Day)
+		}
+	}
+
+	return sorted, nil
+}
+
+// transferLifecycle - the status an action moves a transfer to, by the status it is in
+var transferLifecycle = &lifecycle.Machine{
+	Noun: "transfer",
+	Transitions: map[string]map[string]string{
+		StatusDraft:     {ActionShip: StatusInTransit, ActionCancel: StatusCancelled},
+		StatusInTransit: {ActionReceive: StatusReceived, ActionCancel: StatusCancelled},
+	},
+	Err: ErrInvalidTransition,
+}
+
+// Transition - Transition works out the status of a transfer after an action. A transfer is shipped from draft
+// and received once in transit. It is cancelled before it is shipped, or in transit when the units go back to
+// the origin.
+//
+// @param status - the status of the transfer
+// @param action - ship, receive or cancel
+// @return the new status
+// @return error
+func Transition(status, action string) (string, error) {
+	return transferLifecycle.Next(status, action)
+}
+
+// Arrived - Arrived works out the units of every line of a transfer that arrived. Lines short of units are given
+// with the units that did arrive, every unit shipped arrived of the other lines.
+//
+// @param lines - []TransferLine
+// @param receipts - the lines short of units
+// @return the units arrived, by line
+// @return error
+func Arrived(lines []TransferLine, receipts []TransferReceiptLine) ([]int, error) {
+	index := make(map[string]int, len(lines))
+	arrived := make([]int, len(lines))
+	for i, line := range lines {
+		index[line.ProductId] = i
+		arrived[i] = line.Quantity
+	}
+
+	seen := make(map[string]bool, len(receipts))
+	for _, receipt := range receipts {
+		i, ok := index[receipt.ProductId]
+		if !ok {
+			return nil, fmt.Errorf("%w: product %v is not on the transfer", ErrInvalidTransfer, receipt.ProductId)
+		}
+		if seen[receipt.ProductId] {
+			return nil, fmt.Errorf("%w: product %v is received twice", ErrInvalidTransfer, receipt.ProductId)
+		}
+		seen[receipt.ProductId] = true
+		if receipt.Quantity < 0 || receipt.Quantity > lines[i].Quantity {
+			return nil, fmt.Errorf("%w: %v of product %v were shipped", ErrInvalidTransfer, lines[i].Quantity, receipt.ProductId)
+		}
+		arrived[i] = receipt.Quantity
+	}
+
+	return arrived, nil
+}
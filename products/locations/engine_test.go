@@ -0,0 +1,82 @@
+package locations
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestNormalize - test opening hours are sorted and checked
+//
+//	@param t - testing.T
+func TestNormalize(t *testing.T) {
+	hours, err := Normalize([]Opening{
+		{Day: 2, Opens: "09:00", Closes: "17:00"},
+		{Day: 1, Opens: "14:00", Closes: "18:00"},
+		{Day: 1, Opens: "09:00", Closes: "12:30"},
+	})
+	if err != nil || len(hours) != 3 {
+		t.Fatalf("hours should be valid, got %v %v", hours, err)
+	}
+	if hours[0].Day != 1 || hours[0].Opens != "09:00" || hours[1].Opens != "14:00" || hours[2].Day != 2 {
+		t.Errorf("hours should be sorted by day and time, got %v", hours)
+	}
+
+	// create a slice
+	slice := [][]Opening{
+		{{Day: 7, Opens: "09:00", Closes: "17:00"}},
+		{{Day: 1, Opens: "17:00", Closes: "09:00"}},
+		{{Day: 1, Opens: "09:00", Closes: "13:00"}, {Day: 1, Opens: "12:00", Closes: "18:00"}},
+	}
+	for _, item := range slice {
+		if _, err := Normalize(item); !errors.Is(err, ErrInvalidHours) {
+			t.Errorf("%v should be invalid, got %v", item, err)
+		}
+	}
+}
+
+// TestOpeningHours - test opening hours are kept as json
+//
+//	@param t - testing.T
+func TestOpeningHours(t *testing.T) {
+	hours := OpeningHours{{Day: 1, Opens: "09:00", Closes: "17:30"}}
+	value, err := hours.Value()
+	if err != nil || value != `[{"day":1,"opens":"09:00","closes":"17:30"}]` {
+		t.Fatalf("hours should encode as json, got %v %v", value, err)
+	}
+
+	var scanned OpeningHours
+	if err := scanned.Scan([]byte(value.(string))); err != nil || len(scanned) != 1 || scanned[0] != hours[0] {
+		t.Errorf("hours should decode from json, got %v %v", scanned, err)
+	}
+	if value, _ := OpeningHours(nil).Value(); value != "[]" {
+		t.Errorf("no hours should encode as an empty list, got %v", value)
+	}
+	if err := scanned.Scan(42); err == nil {
+		t.Error("should not scan an integer")
+	}
+}
+
+// TestArrived - test the units arrived of every line of a transfer
+//
+//	@param t - testing.T
+func TestArrived(t *testing.T) {
+	lines := []TransferLine{{ProductId: "a", Quantity: 6}, {ProductId: "b", Quantity: 4}}
+
+	arrived, err := Arrived(lines, []TransferReceiptLine{{ProductId: "b", Quantity: 1}})
+	if err != nil || len(arrived) != 2 || arrived[0] != 6 || arrived[1] != 1 {
+		t.Errorf("expected 6 and 1 units to arrive, got %v %v", arrived, err)
+	}
+
+	// create a slice
+	slice := [][]TransferReceiptLine{
+		{{ProductId: "c", Quantity: 1}},
+		{{ProductId: "a", Quantity: 7}},
+		{{ProductId: "a", Quantity: -1}},
+		{{ProductId: "a", Quantity: 1}, {ProductId: "a", Quantity: 2}},
+	}
+	for _, item := range slice {
+		if _, err := Arrived(lines, item); !errors.Is(err, ErrInvalidTransfer) {
+			t.Errorf("%v should be invalid, got %v", item, err)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package locations
+
+import "errors"
+
+var (
+	ErrNotFound          = errors.New("location not found")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrTransferNotFound  = errors.New("transfer not found")
+	ErrAlreadyExists     = errors.New("location already exists")
+	ErrInactive          = errors.New("the location is not active")
+	ErrDefaultLocation   = errors.New("the default location must stay active")
+	ErrInvalidHours      = errors.New("invalid opening hours")
+	ErrInvalidTransfer   = errors.New("invalid transfer")
+	ErrInvalidTransition = errors.New("invalid transfer transition")
+)
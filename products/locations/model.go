@@ -0,0 +1,199 @@
+package locations
+
+import (
+	"time"
+)
+
+const (
+	KindStore     = "store"
+	KindWarehouse = "warehouse"
+
+	StatusDraft     = "draft"      // being prepared, lines can change
+	StatusInTransit = "in_transit" // shipped, out of the stock of the origin but still in the stock of the product
+	StatusReceived  = "received"   // in the stock of the destination
+	StatusCancelled = "cancelled"  // will not be shipped, or went back to the origin
+
+	ActionShip    = "ship"
+	ActionReceive = "receive"
+	ActionCancel  = "cancel"
+)
+
+// Opening - the hours a location is open on a day of the week
+type Opening struct {
+	Day    int    `json:"day" validate:"min=0,max=6"`                // 0 is sunday
+	Opens  string `json:"opens" validate:"required,datetime=15:04"`  // e.g. 09:00
+	Closes string `json:"closes" validate:"required,datetime=15:04"` // e.g. 17:30
+}
+
+// Location - a store or warehouse keeping stock
+type Location struct {
+	Id              string       `json:"id" db:"id"`
+	Code            string       `json:"code" db:"code"` // e.g. WH or LDN-01
+	Name            string       `json:"name" db:"name"`
+	Kind            string       `json:"kind" db:"kind"`
+	Address         string       `json:"address" db:"address"`
+	City            string       `json:"city" db:"city"`
+	PostalCode      string       `json:"postalCode" db:"postal_code"`
+	Country         string       `json:"country" db:"country"` // ISO 3166-1 alpha-2
+	Phone           string       `json:"phone" db:"phone"`
+	OpeningHours    OpeningHours `json:"openingHours" db:"opening_hours"`
+	ClickAndCollect bool         `json:"clickAndCollect" db:"click_and_collect"` // whether customers can collect orders there
+	Active          bool         `json:"active" db:"active"`
+	IsDefault       bool         `json:"isDefault" db:"is_default"` // where stock goes when no location is given
+	CreatedAt       time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt       time.Time    `json:"updatedAt" db:"updated_at"`
+}
+
+type LocationRequest struct {
+	Code            string    `json:"code" validate:"required,max=50"`
+	Name            string    `json:"name" validate:"required,max=255"`
+	Kind            string    `json:"kind" validate:"required,oneof=store warehouse"`
+	Address         string    `json:"address" validate:"max=2000"`
+	City            string    `json:"city" validate:"max=255"`
+	PostalCode      string    `json:"postalCode" validate:"max=20"`
+	Country         string    `json:"country" validate:"omitempty,len=2"`
+	Phone           string    `json:"phone" validate:"max=255"`
+	OpeningHours    []Opening `json:"openingHours" validate:"max=21,dive"`
+	ClickAndCollect bool      `json:"clickAndCollect"`
+	Active          *bool     `json:"active"`    // defaults to true
+	IsDefault       bool      `json:"isDefault"` // takes over from the default location
+}
+
+type LocationsQuery struct {
+	Kind            string `json:"kind" query:"kind" validate:"omitempty,oneof=store warehouse"`
+	ClickAndCollect bool   `json:"clickAndCollect" query:"clickAndCollect"` // only locations customers can collect at
+}
+
+type LocationsResponse struct {
+	Locations []Location `json:"data"`
+}
+
+// Stock - the stock of a product at a location
+type Stock struct {
+	ProductId string    `json:"productId" db:"product_id"`
+	Name      string    `json:"name" db:"name"`
+	Quantity  int       `json:"quantity" db:"quantity"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type StockQuery struct {
+	InStock bool   `json:"inStock" query:"inStock"`                        // only products with stock
+	Limit   int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page    int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter  string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. quantity>10
+	Sort    string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -quantity
+	Cursor  string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the products
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedStockResponse struct {
+	Stock           []Stock `json:"data"`
+	Total           int     `json:"total"`
+	TotalPages      int     `json:"totalPages"`
+	CurrentPage     int     `json:"currentPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	HasNextPage     bool    `json:"hasNextPage"`
+	PrevCursor      string  `json:"prevCursor"`
+	NextCursor      string  `json:"nextCursor"`
+}
+
+// Availability - whether a product can be collected at a location
+type Availability struct {
+	LocationId   string       `json:"locationId" db:"location_id"`
+	Code         string       `json:"code" db:"code"`
+	Name         string       `json:"name" db:"name"`
+	Address      string       `json:"address" db:"address"`
+	City         string       `json:"city" db:"city"`
+	PostalCode   string       `json:"postalCode" db:"postal_code"`
+	OpeningHours OpeningHours `json:"openingHours" db:"opening_hours"`
+	Quantity     int          `json:"quantity" db:"quantity"`
+	Incoming     int          `json:"incoming" db:"incoming"` // units on their way from another location
+	Available    bool         `json:"available" db:"-"`       // enough units to collect
+}
+
+type AvailabilityQuery struct {
+	LocationId string `json:"locationId" query:"locationId" validate:"omitempty,uuid"`
+	Quantity   int    `json:"quantity" query:"quantity" validate:"omitempty,min=0"` // the units wanted, 1 when empty
+}
+
+type AvailabilityResponse struct {
+	ProductId    string         `json:"productId"`
+	Availability []Availability `json:"data"`
+}
+
+// Transfer - stock sent from one location to another
+type Transfer struct {
+	Id             string     `json:"id" db:"id"`
+	Number         int64      `json:"number" db:"number"` // shown on the delivery note as TR-000042
+	FromLocationId string     `json:"fromLocationId" db:"from_location_id"`
+	ToLocationId   string     `json:"toLocationId" db:"to_location_id"`
+	Status         string     `json:"status" db:"status"`
+	Notes          string     `json:"notes" db:"notes"`
+	ShippedAt      *time.Time `json:"shippedAt" db:"shipped_at"`
+	ReceivedAt     *time.Time `json:"receivedAt" db:"received_at"`
+	CancelledAt    *time.Time `json:"cancelledAt" db:"cancelled_at"`
+	CreatedBy      string     `json:"createdBy" db:"created_by"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// TransferLine - units of a product on a transfer
+type TransferLine struct {
+	TransferId       string  `json:"transferId" db:"transfer_id"`
+	ProductId        string  `json:"productId" db:"product_id"`
+	Quantity         int     `json:"quantity" db:"quantity"`
+	QuantityReceived *int    `json:"quantityReceived" db:"quantity_received"` // the units that arrived, the rest were lost in transit
+	MovementId       *string `json:"-" db:"movement_id"`                      // the movement shipping the units, whose lots they arrive in
+}
+
+type TransferLineRequest struct {
+	ProductId string `json:"productId" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"required,min=1"`
+}
+
+type TransferRequest struct {
+	FromLocationId string                `json:"fromLocationId" validate:"required,uuid"`
+	ToLocationId   string                `json:"toLocationId" validate:"required,uuid,nefield=FromLocationId"`
+	Notes          string                `json:"notes" validate:"max=5000"`
+	Lines          []TransferLineRequest `json:"lines" validate:"required,min=1,max=500,dive"`
+}
+
+// TransferReceiptLine - the units of a line that arrived
+type TransferReceiptLine struct {
+	ProductId string `json:"productId" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"min=0"`
+}
+
+type TransferActionRequest struct {
+	Action string                `json:"action" validate:"required,oneof=ship receive cancel"`
+	Lines  []TransferReceiptLine `json:"lines" validate:"max=500,dive"` // on receiving, the lines short of units, every unit arrived of the others
+}
+
+type TransferResponse struct {
+	Transfer *Transfer      `json:"transfer"`
+	Lines    []TransferLine `json:"lines"`
+}
+
+type TransfersQuery struct {
+	LocationId string `json:"locationId" query:"locationId" validate:"omitempty,uuid"` // transfers from or to the location
+	Status     string `json:"status" query:"status" validate:"omitempty,oneof=draft in_transit received cancelled"`
+	Limit      int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page       int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter     string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. status=in_transit
+	Sort       string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -createdAt
+	Cursor     string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the transfers
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedTransfersResponse struct {
+	Transfers       []Transfer `json:"data"`
+	Total           int        `json:"total"`
+	TotalPages      int        `json:"totalPages"`
+	CurrentPage     int        `json:"currentPage"`
+	HasPreviousPage bool       `json:"hasPreviousPage"`
+	HasNextPage     bool       `json:"hasNextPage"`
+	PrevCursor      string     `json:"prevCursor"`
+	NextCursor      string     `json:"nextCursor"`
+}
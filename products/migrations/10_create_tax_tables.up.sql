@@ -0,0 +1,47 @@
+-- tax classes group the products that are taxed alike, e.g. groceries or alcohol
+CREATE TABLE tax_classes (
+  code            VARCHAR(50) NOT NULL PRIMARY KEY,
+  name            VARCHAR(255) NOT NULL,
+  description     TEXT NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- products without a class, directly or through their category, are in the standard class
+INSERT INTO tax_classes (code, name) VALUES ('standard', 'Standard');
+
+-- the classes are kept outside of the products and categories tables so that their queries are unaffected,
+-- the class of a product takes precedence over the class of its category
+CREATE TABLE product_tax_classes (
+  product_id      UUID NOT NULL PRIMARY KEY REFERENCES products (id) ON DELETE CASCADE,
+  class           VARCHAR(50) NOT NULL REFERENCES tax_classes (code) ON DELETE CASCADE
+);
+
+CREATE TABLE category_tax_classes (
+  category_id     UUID NOT NULL PRIMARY KEY REFERENCES categories (id) ON DELETE CASCADE,
+  class           VARCHAR(50) NOT NULL REFERENCES tax_classes (code) ON DELETE CASCADE
+);
+
+-- a tax jurisdiction decides whether prices include tax and where tax is rounded
+CREATE TABLE tax_jurisdictions (
+  code            VARCHAR(20) NOT NULL PRIMARY KEY,
+  name            VARCHAR(255) NOT NULL,
+  price_mode      VARCHAR(20) NOT NULL CHECK (price_mode IN ('exclusive', 'inclusive')),
+  rounding        VARCHAR(20) NOT NULL CHECK (rounding IN ('line', 'total')),
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the taxes of a class in a jurisdiction, a class taxed more than once has a rate for every tax
+CREATE TABLE tax_rates (
+  id              UUID NOT NULL PRIMARY KEY,
+  jurisdiction    VARCHAR(20) NOT NULL REFERENCES tax_jurisdictions (code) ON DELETE CASCADE,
+  class           VARCHAR(50) NOT NULL REFERENCES tax_classes (code) ON DELETE CASCADE,
+  name            VARCHAR(255) NOT NULL,
+  percent         NUMERIC(9, 6) NOT NULL CHECK (percent >= 0 AND percent <= 100),
+  effective_from  TIMESTAMP NOT NULL,
+  -- the rate stops applying at effective_to, it applies until replaced when empty
+  effective_to    TIMESTAMP CHECK (effective_to IS NULL OR effective_to > effective_from),
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX tax_rates_jurisdiction_idx ON tax_rates (jurisdiction, class, effective_from);
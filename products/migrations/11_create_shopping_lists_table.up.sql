@@ -0,0 +1,63 @@
+-- named lists of products kept by a user, wishlists are watched for sales and restocks
+CREATE TABLE shopping_lists (
+  id              UUID NOT NULL PRIMARY KEY,
+  owner_id        UUID NOT NULL,
+  name            VARCHAR(255) NOT NULL,
+  kind            VARCHAR(20) NOT NULL CHECK (kind IN ('wishlist', 'shopping')),
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (owner_id, name)
+);
+
+CREATE TABLE shopping_list_items (
+  list_id         UUID NOT NULL REFERENCES shopping_lists (id) ON DELETE CASCADE,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  quantity        INTEGER NOT NULL CHECK (quantity > 0),
+  note            TEXT NOT NULL DEFAULT '',
+  -- the price and stock last seen by the alerts check, empty until the first check
+  seen_price      monetary,
+  seen_in_stock   BOOLEAN,
+  added_by        UUID NOT NULL,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (list_id, product_id)
+);
+
+CREATE INDEX shopping_list_items_product_id_idx ON shopping_list_items (product_id);
+
+-- the users a list is shared with, to view or edit its items
+CREATE TABLE shopping_list_shares (
+  list_id         UUID NOT NULL REFERENCES shopping_lists (id) ON DELETE CASCADE,
+  user_id         UUID NOT NULL,
+  permission      VARCHAR(20) NOT NULL CHECK (permission IN ('view', 'edit')),
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (list_id, user_id)
+);
+
+CREATE INDEX shopping_list_shares_user_id_idx ON shopping_list_shares (user_id);
+
+-- a wishlist item that went on sale or came back in stock, kept until it is delivered to the owner
+CREATE TABLE shopping_list_alerts (
+  id              UUID NOT NULL PRIMARY KEY,
+  user_id         UUID NOT NULL,
+  list_id         UUID NOT NULL REFERENCES shopping_lists (id) ON DELETE CASCADE,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  kind            VARCHAR(20) NOT NULL CHECK (kind IN ('on_sale', 'back_in_stock')),
+  old_price       monetary,
+  new_price       monetary,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  delivered_at    TIMESTAMP
+);
+
+CREATE INDEX shopping_list_alerts_user_id_idx ON shopping_list_alerts (user_id, created_at DESC);
+CREATE INDEX shopping_list_alerts_pending_idx ON shopping_list_alerts (created_at) WHERE delivered_at IS NULL;
+
+-- the products a user is about to buy
+CREATE TABLE cart_items (
+  user_id         UUID NOT NULL,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  quantity        INTEGER NOT NULL CHECK (quantity > 0),
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, product_id)
+);
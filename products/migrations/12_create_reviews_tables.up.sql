@@ -0,0 +1,46 @@
+-- the rating of the approved reviews is kept on the product so product listings need no join
+ALTER TABLE products ADD COLUMN rating_average DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX products_rating_idx ON products (rating_average DESC, rating_count DESC);
+
+-- the products a user bought, recorded by the checkout, only buyers can review a product
+CREATE TABLE product_purchases (
+  user_id         UUID NOT NULL,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  -- what the product was bought with, e.g. an order id
+  reference       VARCHAR(255) NOT NULL,
+  purchased_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, product_id, reference)
+);
+
+CREATE TABLE product_reviews (
+  id              UUID NOT NULL PRIMARY KEY,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  user_id         UUID NOT NULL,
+  rating          SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  title           VARCHAR(255) NOT NULL,
+  body            TEXT NOT NULL DEFAULT '',
+  -- only approved reviews are listed and counted in the rating of the product
+  status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'flagged')),
+  reports         INTEGER NOT NULL DEFAULT 0 CHECK (reports >= 0),
+  moderated_by    UUID,
+  moderated_at    TIMESTAMP,
+  moderation_note TEXT NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (product_id, user_id)
+);
+
+CREATE INDEX product_reviews_listing_idx ON product_reviews (product_id, created_at DESC) WHERE status = 'approved';
+CREATE INDEX product_reviews_queue_idx ON product_reviews (status, created_at) WHERE status IN ('pending', 'flagged');
+
+-- abuse reports of reviews, a user reports a review once
+CREATE TABLE product_review_reports (
+  review_id       UUID NOT NULL REFERENCES product_reviews (id) ON DELETE CASCADE,
+  user_id         UUID NOT NULL,
+  reason          VARCHAR(20) NOT NULL CHECK (reason IN ('spam', 'offensive', 'off_topic', 'fake', 'other')),
+  comment         TEXT NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (review_id, user_id)
+);
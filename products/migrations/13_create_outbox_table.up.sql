@@ -0,0 +1,26 @@
+-- events saved with the changes they describe, published by the relay once the change is committed
+CREATE TABLE outbox (
+  -- the id of the event, subscribers dedupe on it
+  id              UUID NOT NULL PRIMARY KEY,
+  type            VARCHAR(100) NOT NULL,
+  version         INTEGER NOT NULL,
+  payload         JSONB NOT NULL,
+  occurred_at     TIMESTAMP NOT NULL,
+  attempts        INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  last_error      TEXT NOT NULL DEFAULT '',
+  sent_at         TIMESTAMP,
+  message_id      VARCHAR(255) NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (next_attempt_at, occurred_at) WHERE sent_at IS NULL;
+CREATE INDEX outbox_sent_at_idx ON outbox (sent_at) WHERE sent_at IS NOT NULL;
+
+-- the events handled by the subscriptions of the service, so an event delivered twice is handled once
+CREATE TABLE processed_events (
+  subscription    VARCHAR(100) NOT NULL,
+  event_id        UUID NOT NULL,
+  processed_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (subscription, event_id)
+);
@@ -0,0 +1,125 @@
+-- every change of the stock of a product after it was created
+CREATE TABLE stock_movements (
+  id              UUID NOT NULL PRIMARY KEY,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  -- negative when stock goes out
+  quantity        INTEGER NOT NULL CHECK (quantity <> 0),
+  quantity_after  INTEGER NOT NULL,
+  reason          VARCHAR(20) NOT NULL CHECK (reason IN ('receipt', 'sale', 'return', 'adjustment')),
+  -- e.g. the purchase order or the order
+  reference       VARCHAR(255) NOT NULL DEFAULT '',
+  -- what a unit cost when stock was bought
+  unit_cost       monetary,
+  note            TEXT NOT NULL DEFAULT '',
+  created_by      UUID,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX stock_movements_product_id_idx ON stock_movements (product_id, created_at DESC);
+
+CREATE TABLE suppliers (
+  id              UUID NOT NULL PRIMARY KEY,
+  name            VARCHAR(255) NOT NULL UNIQUE,
+  email           VARCHAR(255) NOT NULL DEFAULT '',
+  phone           VARCHAR(255) NOT NULL DEFAULT '',
+  address         TEXT NOT NULL DEFAULT '',
+  -- the days between sending an order and receiving it, unless a product says otherwise
+  lead_time_days  INTEGER NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0),
+  currency        CHAR(3) NOT NULL,
+  notes           TEXT NOT NULL DEFAULT '',
+  active          BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE supplier_contacts (
+  id              UUID NOT NULL PRIMARY KEY,
+  supplier_id     UUID NOT NULL REFERENCES suppliers (id) ON DELETE CASCADE,
+  name            VARCHAR(255) NOT NULL,
+  role            VARCHAR(255) NOT NULL DEFAULT '',
+  email           VARCHAR(255) NOT NULL DEFAULT '',
+  phone           VARCHAR(255) NOT NULL DEFAULT '',
+  is_primary      BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX supplier_contacts_supplier_id_idx ON supplier_contacts (supplier_id);
+-- a supplier has at most one primary contact
+CREATE UNIQUE INDEX supplier_contacts_primary_idx ON supplier_contacts (supplier_id) WHERE is_primary;
+
+-- the products a supplier sells us and what they cost
+CREATE TABLE supplier_products (
+  supplier_id     UUID NOT NULL REFERENCES suppliers (id) ON DELETE CASCADE,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  supplier_sku    VARCHAR(255) NOT NULL DEFAULT '',
+  cost_price      monetary NOT NULL,
+  -- overrides the lead time of the supplier when set
+  lead_time_days  INTEGER CHECK (lead_time_days >= 0),
+  min_order_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_order_quantity >= 1),
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (supplier_id, product_id)
+);
+
+CREATE INDEX supplier_products_product_id_idx ON supplier_products (product_id);
+
+CREATE TABLE purchase_orders (
+  id              UUID NOT NULL PRIMARY KEY,
+  -- the number shown to suppliers, e.g. PO-000042
+  number          BIGSERIAL NOT NULL UNIQUE,
+  supplier_id     UUID NOT NULL REFERENCES suppliers (id),
+  status          VARCHAR(20) NOT NULL DEFAULT 'draft'
+                  CHECK (status IN ('draft', 'sent', 'partially_received', 'received', 'cancelled')),
+  currency        CHAR(3) NOT NULL,
+  notes           TEXT NOT NULL DEFAULT '',
+  expected_at     TIMESTAMP,
+  sent_at         TIMESTAMP,
+  received_at     TIMESTAMP,
+  cancelled_at    TIMESTAMP,
+  created_by      UUID NOT NULL,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX purchase_orders_supplier_id_idx ON purchase_orders (supplier_id, created_at DESC);
+CREATE INDEX purchase_orders_status_idx ON purchase_orders (status, created_at DESC);
+
+CREATE TABLE purchase_order_lines (
+  id                UUID NOT NULL PRIMARY KEY,
+  purchase_order_id UUID NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+  product_id        UUID NOT NULL REFERENCES products (id),
+  quantity_ordered  INTEGER NOT NULL CHECK (quantity_ordered > 0),
+  quantity_received INTEGER NOT NULL DEFAULT 0 CHECK (quantity_received >= 0),
+  -- the cost agreed when the order was made
+  unit_cost         monetary NOT NULL,
+  created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (purchase_order_id, product_id)
+);
+
+-- every delivery taken in for a line, with what the units actually cost
+CREATE TABLE purchase_order_receipts (
+  id                UUID NOT NULL PRIMARY KEY,
+  purchase_order_id UUID NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+  line_id           UUID NOT NULL REFERENCES purchase_order_lines (id) ON DELETE CASCADE,
+  product_id        UUID NOT NULL REFERENCES products (id),
+  quantity          INTEGER NOT NULL CHECK (quantity > 0),
+  unit_cost         monetary NOT NULL,
+  movement_id       UUID NOT NULL REFERENCES stock_movements (id),
+  received_by       UUID NOT NULL,
+  received_at       TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX purchase_order_receipts_order_idx ON purchase_order_receipts (purchase_order_id, received_at);
+
+-- the moving average cost of the stock on hand of a product, in each currency it was bought in
+CREATE TABLE product_costs (
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  currency        CHAR(3) NOT NULL,
+  average_cost    monetary NOT NULL,
+  last_cost       monetary NOT NULL,
+  quantity        BIGINT NOT NULL DEFAULT 0,
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (product_id, currency)
+);
@@ -0,0 +1,24 @@
+-- when to reorder a product and how much stock to order up to
+CREATE TABLE reorder_settings (
+  product_id      UUID NOT NULL PRIMARY KEY REFERENCES products (id) ON DELETE CASCADE,
+  -- reorder once stock and units on order fall to this level
+  reorder_point   INTEGER NOT NULL CHECK (reorder_point >= 0),
+  -- the stock to order up to
+  target_stock    INTEGER NOT NULL CHECK (target_stock >= reorder_point),
+  -- order from this supplier instead of the quickest one
+  supplier_id     UUID REFERENCES suppliers (id) ON DELETE SET NULL,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the units of a product sold a day over the last weeks, computed by a scheduled job
+CREATE TABLE sales_velocities (
+  product_id      UUID NOT NULL PRIMARY KEY REFERENCES products (id) ON DELETE CASCADE,
+  -- sales less returns over the window
+  units_sold      INTEGER NOT NULL,
+  days            INTEGER NOT NULL CHECK (days > 0),
+  units_per_day   DOUBLE PRECISION NOT NULL,
+  computed_at     TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX stock_movements_reason_idx ON stock_movements (reason, created_at);
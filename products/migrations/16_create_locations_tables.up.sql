@@ -0,0 +1,85 @@
+-- the stores and warehouses stock is kept at
+CREATE TABLE locations (
+  id                 UUID NOT NULL PRIMARY KEY,
+  code               VARCHAR(50) NOT NULL UNIQUE,
+  name               VARCHAR(255) NOT NULL,
+  kind               VARCHAR(20) NOT NULL CHECK (kind IN ('store', 'warehouse')),
+  address            TEXT NOT NULL DEFAULT '',
+  city               VARCHAR(255) NOT NULL DEFAULT '',
+  postal_code        VARCHAR(20) NOT NULL DEFAULT '',
+  country            CHAR(2) NOT NULL DEFAULT '',
+  phone              VARCHAR(255) NOT NULL DEFAULT '',
+  -- the hours of every day of the week it is open, e.g. [{"day": 1, "opens": "09:00", "closes": "17:30"}]
+  opening_hours      JSONB NOT NULL DEFAULT '[]',
+  -- whether customers can collect orders there
+  click_and_collect  BOOLEAN NOT NULL DEFAULT FALSE,
+  active             BOOLEAN NOT NULL DEFAULT TRUE,
+  -- where stock goes when no location is given
+  is_default         BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at         TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at         TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- there is one default location
+CREATE UNIQUE INDEX locations_default_idx ON locations (is_default) WHERE is_default;
+
+-- the stock of a product at a location, the stock of the product is their sum
+CREATE TABLE location_stock (
+  location_id     UUID NOT NULL REFERENCES locations (id),
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  quantity        INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (location_id, product_id)
+);
+
+CREATE INDEX location_stock_product_id_idx ON location_stock (product_id);
+
+-- the stock so far is kept at a central warehouse
+INSERT INTO locations (id, code, name, kind, is_default)
+VALUES ('00000000-0000-0000-0000-000000000001', 'WH', 'Central warehouse', 'warehouse', TRUE);
+
+INSERT INTO location_stock (location_id, product_id, quantity)
+SELECT '00000000-0000-0000-0000-000000000001', id, stock_quantity FROM products WHERE stock_quantity > 0;
+
+ALTER TABLE stock_movements ADD COLUMN location_id UUID REFERENCES locations (id);
+UPDATE stock_movements SET location_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE stock_movements ALTER COLUMN location_id SET NOT NULL;
+
+ALTER TABLE stock_movements DROP CONSTRAINT stock_movements_reason_check;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_reason_check
+  CHECK (reason IN ('receipt', 'sale', 'return', 'adjustment', 'transfer'));
+
+CREATE INDEX stock_movements_location_id_idx ON stock_movements (location_id, created_at DESC);
+
+-- where the units of a purchase order were received
+ALTER TABLE purchase_order_receipts ADD COLUMN location_id UUID REFERENCES locations (id);
+UPDATE purchase_order_receipts SET location_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE purchase_order_receipts ALTER COLUMN location_id SET NOT NULL;
+
+-- stock sent from one location to another
+CREATE TABLE transfers (
+  id                UUID NOT NULL PRIMARY KEY,
+  -- the number shown on the delivery note, e.g. TR-000042
+  number            BIGSERIAL NOT NULL UNIQUE,
+  from_location_id  UUID NOT NULL REFERENCES locations (id),
+  to_location_id    UUID NOT NULL REFERENCES locations (id),
+  status            VARCHAR(20) NOT NULL DEFAULT 'draft'
+                    CHECK (status IN ('draft', 'in_transit', 'received', 'cancelled')),
+  notes             TEXT NOT NULL DEFAULT '',
+  shipped_at        TIMESTAMP,
+  received_at       TIMESTAMP,
+  cancelled_at      TIMESTAMP,
+  created_by        UUID NOT NULL,
+  created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+  CHECK (from_location_id <> to_location_id)
+);
+
+CREATE INDEX transfers_status_idx ON transfers (status, created_at DESC);
+
+CREATE TABLE transfer_lines (
+  transfer_id     UUID NOT NULL REFERENCES transfers (id) ON DELETE CASCADE,
+  product_id      UUID NOT NULL REFERENCES products (id),
+  quantity        INTEGER NOT NULL CHECK (quantity > 0),
+  PRIMARY KEY (transfer_id, product_id)
+);
@@ -0,0 +1,39 @@
+-- the stock of a product at a location by lot and expiry date, the stock at the location is their sum. Stock
+-- received without a lot number is kept in a lot numbered ''
+CREATE TABLE stock_lots (
+  id              UUID NOT NULL PRIMARY KEY,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  location_id     UUID NOT NULL REFERENCES locations (id),
+  lot_number      VARCHAR(100) NOT NULL DEFAULT '',
+  expires_at      DATE,
+  quantity        INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX stock_lots_key_idx ON stock_lots (product_id, location_id, lot_number, COALESCE(expires_at, 'infinity'::DATE));
+CREATE INDEX stock_lots_expires_at_idx ON stock_lots (expires_at) WHERE quantity > 0;
+CREATE INDEX stock_lots_lot_number_idx ON stock_lots (lot_number) WHERE lot_number <> '';
+
+-- the lots a stock movement took stock from or put it in
+CREATE TABLE stock_movement_lots (
+  movement_id     UUID NOT NULL REFERENCES stock_movements (id) ON DELETE CASCADE,
+  lot_id          UUID NOT NULL REFERENCES stock_lots (id) ON DELETE CASCADE,
+  -- negative when stock goes out
+  quantity        INTEGER NOT NULL CHECK (quantity <> 0),
+  PRIMARY KEY (movement_id, lot_id)
+);
+
+CREATE INDEX stock_movement_lots_lot_id_idx ON stock_movement_lots (lot_id);
+
+-- the stock so far has no lot
+INSERT INTO stock_lots (id, product_id, location_id, quantity, created_at, updated_at)
+SELECT gen_random_uuid(), product_id, location_id, quantity, updated_at, updated_at FROM location_stock WHERE quantity > 0;
+
+ALTER TABLE purchase_order_receipts ADD COLUMN lot_number VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE purchase_order_receipts ADD COLUMN expires_at DATE;
+
+-- the movement shipping a line, its lots are received at the destination
+ALTER TABLE transfer_lines ADD COLUMN movement_id UUID REFERENCES stock_movements (id);
+
+CREATE INDEX stock_movements_reference_idx ON stock_movements (reference) WHERE reference <> '';
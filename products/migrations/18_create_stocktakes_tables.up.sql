@@ -0,0 +1,50 @@
+-- the barcodes printed on a product, kept outside of the products table so that product queries are unaffected
+CREATE TABLE product_barcodes (
+  barcode         VARCHAR(64) NOT NULL PRIMARY KEY,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX product_barcodes_product_id_idx ON product_barcodes (product_id);
+
+-- a physical count of the stock at a location, of one category or of every product
+CREATE TABLE stocktakes (
+  id              UUID NOT NULL PRIMARY KEY,
+  -- the number shown on count sheets, e.g. ST-000042
+  number          BIGSERIAL NOT NULL UNIQUE,
+  location_id     UUID NOT NULL REFERENCES locations (id),
+  category_id     UUID REFERENCES categories (id),
+  status          VARCHAR(20) NOT NULL DEFAULT 'counting'
+                  CHECK (status IN ('counting', 'submitted', 'posted', 'cancelled')),
+  notes           TEXT NOT NULL DEFAULT '',
+  -- why a manager sent the counts back
+  review_note     TEXT NOT NULL DEFAULT '',
+  submitted_by    UUID,
+  submitted_at    TIMESTAMP,
+  approved_by     UUID,
+  posted_at       TIMESTAMP,
+  cancelled_at    TIMESTAMP,
+  created_by      UUID NOT NULL,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX stocktakes_location_id_idx ON stocktakes (location_id, created_at DESC);
+CREATE INDEX stocktakes_status_idx ON stocktakes (status, created_at DESC);
+
+-- a product to count, with the stock the system had when the count started
+CREATE TABLE stocktake_lines (
+  stocktake_id    UUID NOT NULL REFERENCES stocktakes (id) ON DELETE CASCADE,
+  product_id      UUID NOT NULL REFERENCES products (id),
+  expected        INTEGER NOT NULL,
+  -- none until the product is counted
+  counted         INTEGER CHECK (counted >= 0),
+  reason          VARCHAR(20) NOT NULL DEFAULT ''
+                  CHECK (reason IN ('', 'miscount', 'damaged', 'expired', 'theft', 'found', 'other')),
+  note            TEXT NOT NULL DEFAULT '',
+  counted_by      UUID,
+  counted_at      TIMESTAMP,
+  -- the adjustment posted for the variance
+  movement_id     UUID REFERENCES stock_movements (id),
+  PRIMARY KEY (stocktake_id, product_id)
+);
@@ -0,0 +1,10 @@
+-- keep the first of the changes already scheduled to start at the same time
+UPDATE product_prices p SET status = 'cancelled', updated_at = NOW()
+WHERE p.status = 'scheduled' AND EXISTS (
+  SELECT 1 FROM product_prices o
+  WHERE o.product_id = p.product_id AND o.effective_from = p.effective_from AND o.status = 'scheduled'
+    AND (o.created_at, o.id) < (p.created_at, p.id)
+);
+
+-- a product cannot have two price changes scheduled to start at the same time
+CREATE UNIQUE INDEX product_prices_scheduled_from_idx ON product_prices (product_id, effective_from) WHERE status = 'scheduled';
@@ -0,0 +1,12 @@
+CREATE TABLE products (
+  id              UUID NOT NULL PRIMARY KEY,
+  name            VARCHAR(1000) NOT NULL UNIQUE,
+  brand           VARCHAR(255) NOT NULL DEFAULT '',
+  description     TEXT NOT NULL DEFAULT '',
+  price           NUMERIC(12, 2) NOT NULL DEFAULT 0,
+  category_id     UUID NOT NULL,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX products_category_id_idx ON products (category_id);
@@ -0,0 +1,4 @@
+-- the average cost of a product is kept in the catalog currency only, converting the units bought in other
+-- currencies, since the stock on hand weighting it is counted across every currency. The averages kept in other
+-- currencies were weighted by the units of every currency, they are dropped and the next receipt starts over.
+DELETE FROM product_costs WHERE currency <> 'USD';
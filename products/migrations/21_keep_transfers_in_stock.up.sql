@@ -0,0 +1,15 @@
+-- units in transit between locations stay in the stock of the product, shipping used to take them out of it
+UPDATE products p
+SET stock_quantity = p.stock_quantity + t.quantity
+FROM (
+  SELECT l.product_id, SUM(l.quantity) AS quantity
+  FROM transfer_lines l
+  JOIN transfers tr ON tr.id = l.transfer_id
+  WHERE tr.status = 'in_transit'
+  GROUP BY l.product_id
+) t
+WHERE p.id = t.product_id;
+
+-- the units of a line that arrived, the rest were lost in transit
+ALTER TABLE transfer_lines ADD COLUMN quantity_received INTEGER CHECK (quantity_received BETWEEN 0 AND quantity);
+UPDATE transfer_lines l SET quantity_received = l.quantity FROM transfers t WHERE t.id = l.transfer_id AND t.status = 'received';
@@ -0,0 +1,8 @@
+-- why an adjustment was made, e.g. the reason a stocktake counted a product differently from the system stock
+ALTER TABLE stock_movements ADD COLUMN reason_code VARCHAR(20) NOT NULL DEFAULT ''
+  CHECK (reason_code IN ('', 'miscount', 'damaged', 'expired', 'theft', 'found', 'other'));
+
+-- the adjustments posted by stocktakes so far kept the reason in their note
+UPDATE stock_movements m SET reason_code = l.reason, note = l.note FROM stocktake_lines l WHERE l.movement_id = m.id;
+
+CREATE INDEX stock_movements_reason_code_idx ON stock_movements (reason_code) WHERE reason_code <> '';
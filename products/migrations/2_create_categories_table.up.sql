@@ -0,0 +1,11 @@
+CREATE TABLE categories (
+  id              UUID NOT NULL PRIMARY KEY,
+  name            VARCHAR(255) NOT NULL UNIQUE,
+  description     TEXT NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- a category cannot be removed while products still reference it
+ALTER TABLE products ADD CONSTRAINT products_category_id_fkey
+  FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE RESTRICT;
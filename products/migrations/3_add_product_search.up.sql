@@ -0,0 +1,54 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products ADD COLUMN stock_quantity INTEGER NOT NULL DEFAULT 0;
+
+-- search documents are kept outside of the products table so that product queries are unaffected
+CREATE TABLE product_search_documents (
+  product_id      UUID NOT NULL PRIMARY KEY REFERENCES products (id) ON DELETE CASCADE,
+  document        TSVECTOR NOT NULL
+);
+
+CREATE INDEX product_search_documents_document_idx ON product_search_documents USING GIN (document);
+CREATE INDEX products_name_trgm_idx ON products USING GIN (name gin_trgm_ops);
+CREATE INDEX products_brand_idx ON products (brand);
+CREATE INDEX products_price_idx ON products (price);
+
+-- rebuild the search document of a product from its name, brand, category name and description
+CREATE FUNCTION refresh_product_search_document(pid UUID) RETURNS VOID AS $$
+  INSERT INTO product_search_documents (product_id, document)
+  SELECT
+    p.id,
+    setweight(to_tsvector('simple', p.name), 'A') ||
+    setweight(to_tsvector('simple', p.brand), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(c.name, '')), 'B') ||
+    setweight(to_tsvector('simple', p.description), 'C')
+  FROM products p
+  LEFT JOIN categories c ON c.id = p.category_id
+  WHERE p.id = pid
+  ON CONFLICT (product_id) DO UPDATE SET document = EXCLUDED.document;
+$$ LANGUAGE SQL;
+
+CREATE FUNCTION products_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM refresh_product_search_document(NEW.id);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_search_refresh
+  AFTER INSERT OR UPDATE OF name, brand, description, category_id ON products
+  FOR EACH ROW EXECUTE FUNCTION products_search_trigger();
+
+CREATE FUNCTION categories_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM refresh_product_search_document(p.id) FROM products p WHERE p.category_id = NEW.id;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER categories_search_refresh
+  AFTER UPDATE OF name ON categories
+  FOR EACH ROW EXECUTE FUNCTION categories_search_trigger();
+
+-- index the existing products
+SELECT refresh_product_search_document(id) FROM products;
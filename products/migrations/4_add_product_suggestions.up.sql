@@ -0,0 +1,12 @@
+-- popularity is tracked per product and rolled up for brands and categories
+CREATE TABLE product_popularity (
+  product_id      UUID NOT NULL PRIMARY KEY REFERENCES products (id) ON DELETE CASCADE,
+  score           BIGINT NOT NULL DEFAULT 0,
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX products_name_prefix_idx ON products (LOWER(name) text_pattern_ops);
+CREATE INDEX products_brand_prefix_idx ON products (LOWER(brand) text_pattern_ops);
+CREATE INDEX products_brand_trgm_idx ON products USING GIN (brand gin_trgm_ops);
+CREATE INDEX categories_name_prefix_idx ON categories (LOWER(name) text_pattern_ops);
+CREATE INDEX categories_name_trgm_idx ON categories USING GIN (name gin_trgm_ops);
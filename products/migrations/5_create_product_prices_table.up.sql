@@ -0,0 +1,24 @@
+-- every price a product has had or is scheduled to have
+-- status is one of [scheduled, active, superseded, cancelled]
+CREATE TABLE product_prices (
+  id              UUID NOT NULL PRIMARY KEY,
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  price           NUMERIC(12, 2) NOT NULL,
+  effective_from  TIMESTAMP NOT NULL,
+  effective_to    TIMESTAMP,
+  status          VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+  -- the admin who made the change, empty for prices recorded by the system
+  created_by      VARCHAR(255) NOT NULL DEFAULT '',
+  note            TEXT NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX product_prices_product_id_idx ON product_prices (product_id, effective_from);
+CREATE INDEX product_prices_scheduled_idx ON product_prices (effective_from) WHERE status = 'scheduled';
+-- a product has at most one active price
+CREATE UNIQUE INDEX product_prices_active_idx ON product_prices (product_id) WHERE status = 'active';
+
+-- record the current price of the existing products
+INSERT INTO product_prices (id, product_id, price, effective_from, status)
+SELECT gen_random_uuid(), id, price, created_at, 'active' FROM products;
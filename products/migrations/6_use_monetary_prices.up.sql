@@ -0,0 +1,17 @@
+-- an exact amount of money in the minor units of an ISO 4217 currency
+CREATE TYPE monetary AS (
+  amount          BIGINT,
+  currency        CHAR(3)
+);
+
+-- existing prices were stored in major units of the default currency
+DROP INDEX products_price_idx;
+
+ALTER TABLE products
+  ALTER COLUMN price DROP DEFAULT,
+  ALTER COLUMN price TYPE monetary USING ROW(ROUND(price * 100)::BIGINT, 'USD')::monetary;
+
+ALTER TABLE product_prices
+  ALTER COLUMN price TYPE monetary USING ROW(ROUND(price * 100)::BIGINT, 'USD')::monetary;
+
+CREATE INDEX products_price_idx ON products (((price).amount));
@@ -0,0 +1,25 @@
+-- every exchange rate set by an admin, the latest started rate of a pair is current
+-- rate is the number of quote currency units in one base currency unit
+CREATE TABLE exchange_rates (
+  id              UUID NOT NULL PRIMARY KEY,
+  base            CHAR(3) NOT NULL,
+  quote           CHAR(3) NOT NULL,
+  rate            NUMERIC(24, 12) NOT NULL CHECK (rate > 0),
+  effective_from  TIMESTAMP NOT NULL,
+  created_by      VARCHAR(255) NOT NULL DEFAULT '',
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  CHECK (base <> quote)
+);
+
+CREATE INDEX exchange_rates_pair_idx ON exchange_rates (base, quote, effective_from DESC);
+
+-- prices set for a product in a currency other than its own
+CREATE TABLE product_currency_prices (
+  product_id      UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  currency        CHAR(3) NOT NULL,
+  price           monetary NOT NULL,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (product_id, currency),
+  CHECK ((price).currency = currency)
+);
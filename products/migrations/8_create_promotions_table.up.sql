@@ -0,0 +1,27 @@
+-- promotions discount baskets, see the promo package for how they are applied
+-- type is one of [percentage, fixed, buy_x_get_y, bundle]
+CREATE TABLE promotions (
+  id              UUID NOT NULL PRIMARY KEY,
+  name            VARCHAR(255) NOT NULL,
+  description     TEXT NOT NULL DEFAULT '',
+  type            VARCHAR(20) NOT NULL,
+  -- basis points, 1250 is 12.5%
+  percentage      BIGINT NOT NULL DEFAULT 0 CHECK (percentage BETWEEN 0 AND 10000),
+  amount          monetary,
+  buy_quantity    INTEGER NOT NULL DEFAULT 0,
+  get_quantity    INTEGER NOT NULL DEFAULT 0,
+  bundle_quantity INTEGER NOT NULL DEFAULT 0,
+  min_spend       monetary,
+  -- every product is included when both lists are empty
+  product_ids     UUID[] NOT NULL DEFAULT '{}',
+  category_ids    UUID[] NOT NULL DEFAULT '{}',
+  priority        INTEGER NOT NULL DEFAULT 0,
+  stackable       BOOLEAN NOT NULL DEFAULT FALSE,
+  active          BOOLEAN NOT NULL DEFAULT TRUE,
+  starts_at       TIMESTAMP,
+  ends_at         TIMESTAMP,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX promotions_running_idx ON promotions (starts_at, ends_at) WHERE active;
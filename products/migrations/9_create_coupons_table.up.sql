@@ -0,0 +1,38 @@
+-- promotions only applied with a coupon are left out of the running promotions
+ALTER TABLE promotions ADD COLUMN coupon_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- coupons apply a promotion to the basket of whoever holds the code
+CREATE TABLE coupons (
+  id                UUID NOT NULL PRIMARY KEY,
+  code              VARCHAR(64) NOT NULL UNIQUE,
+  promotion_id      UUID NOT NULL REFERENCES promotions (id) ON DELETE CASCADE,
+  -- the coupons generated together share a batch
+  batch             VARCHAR(255) NOT NULL DEFAULT '',
+  -- usage limits, 0 is unlimited
+  max_uses          INTEGER NOT NULL DEFAULT 0 CHECK (max_uses >= 0),
+  max_uses_per_user INTEGER NOT NULL DEFAULT 0 CHECK (max_uses_per_user >= 0),
+  uses              INTEGER NOT NULL DEFAULT 0 CHECK (max_uses = 0 OR uses <= max_uses),
+  expires_at        TIMESTAMP,
+  -- the items the coupon can be used for, the items of the promotion when both lists are empty
+  product_ids       UUID[] NOT NULL DEFAULT '{}',
+  category_ids      UUID[] NOT NULL DEFAULT '{}',
+  active            BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at        TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX coupons_batch_idx ON coupons (batch) WHERE batch <> '';
+
+-- every use of a coupon
+CREATE TABLE coupon_redemptions (
+  id                UUID NOT NULL PRIMARY KEY,
+  coupon_id         UUID NOT NULL REFERENCES coupons (id) ON DELETE CASCADE,
+  user_id           VARCHAR(255) NOT NULL,
+  -- what the coupon was used for, e.g. an order id, a coupon is used once per reference
+  reference         VARCHAR(255) NOT NULL,
+  discount          monetary NOT NULL,
+  created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (coupon_id, reference)
+);
+
+CREATE INDEX coupon_redemptions_user_idx ON coupon_redemptions (coupon_id, user_id);
@@ -0,0 +1,363 @@
+package pl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/events"
+	"encore.app/pkg/money"
+	"encore.app/pkg/outbox"
+)
+
+// the products database
+var pricesDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// FindOneByField - get price by field
+//
+//	@param ctx - context.Context
+//	@param field - string
+//	@param ops - string
+//	@param value - interface{}
+//	@return price
+//	@return error
+func FindOneByField(ctx context.Context, field, ops string, value interface{}) (Price, error) {
+	// set the data fields for the query
+	data := map[string]interface{}{
+		field: value,
+	}
+
+	// query statement to be executed
+	q := "SELECT * FROM product_prices WHERE %v %v :%v LIMIT 1"
+	// format query parameters
+	q = fmt.Sprintf(q, field, ops, field)
+
+	// declare price
+	var price Price
+	// execute query
+	if err := database.NamedStructQuery(ctx, pricesDatabase(), q, data, &price); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return Price{}, ErrNotFound
+		}
+		return Price{}, fmt.Errorf("selecting prices by ID[%v]: %w", value, err)
+	}
+
+	return price, nil
+}
+
+// Record - Record is a function that records the first price of a new product.
+// It is run with the transaction creating the product.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param productId - string
+// @param price - money.Money
+// @param createdBy - string
+// @return error
+func Record(ctx context.Context, db sqlx.ExtContext, productId string, price money.Money, createdBy string) error {
+	now := time.Now().UTC()
+
+	// query statement to be executed
+	q := `
+    INSERT INTO product_prices (id, product_id, price, effective_from, status, created_by, created_at, updated_at)
+    VALUES (:id, :product_id, :price, :effective_from, :status, :created_by, :created_at, :updated_at)
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, db, q, Price{
+		Id:            uuid.New().String(),
+		ProductId:     productId,
+		Price:         price,
+		EffectiveFrom: now,
+		Status:        StatusActive,
+		CreatedBy:     createdBy,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}); err != nil {
+		return fmt.Errorf("recording price: %w", err)
+	}
+
+	return nil
+}
+
+// Change - Change is a function that changes the price of a product.
+// Changes without an effective time, or with one in the past, are applied immediately;
+// later changes are scheduled and applied by ApplyScheduled.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param createdBy - string
+// @param payload - *PriceChangeRequest
+// @return price
+// @return error
+func Change(ctx context.Context, productId, createdBy string, payload *PriceChangeRequest) (*Price, error) {
+	// check the price, kept in the currency of the catalog like the price of the product
+	if err := payload.Price.Validate(); err != nil || payload.Price.Amount <= 0 || payload.Price.Currency != money.DefaultCurrency {
+		return nil, ErrInvalidPrice
+	}
+
+	// check if the product exists
+	count, err := database.NamedCountQuery(ctx, pricesDatabase(), "SELECT COUNT(*) FROM products WHERE id = :id", map[string]interface{}{
+		"id": productId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("selecting product: %w", err)
+	}
+	if count < 1 {
+		return nil, ErrProductNotFound
+	}
+
+	// create the price
+	now := time.Now().UTC()
+	price := Price{
+		Id:            uuid.New().String(),
+		ProductId:     productId,
+		Price:         payload.Price,
+		EffectiveFrom: now,
+		Status:        StatusScheduled,
+		CreatedBy:     createdBy,
+		Note:          payload.Note,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	// schedule changes that start later
+	scheduled := payload.EffectiveFrom != nil && payload.EffectiveFrom.After(now)
+	if scheduled {
+		price.EffectiveFrom = payload.EffectiveFrom.UTC()
+
+		// a product cannot have two changes scheduled to start at the same time
+		count, err := database.NamedCountQuery(ctx, pricesDatabase(), `
+      SELECT COUNT(*) FROM product_prices
+      WHERE product_id = :product_id AND status = :status AND effective_from = :effective_from
+    `, price)
+		if err != nil {
+			return nil, fmt.Errorf("selecting scheduled prices: %w", err)
+		}
+		if count > 0 {
+			return nil, ErrAlreadyExists
+		}
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO product_prices (id, product_id, price, effective_from, status, created_by, note, created_at, updated_at)
+    VALUES (:id, :product_id, :price, :effective_from, :status, :created_by, :note, :created_at, :updated_at)
+  `
+
+	// insert the price and activate it when it starts now
+	if err := database.Transaction(ctx, pricesDatabase(), func(tx *sqlx.Tx) error {
+		if err := database.NamedExecQuery(ctx, tx, q, price); err != nil {
+			// a concurrent change was scheduled at the same time
+			if database.IsUniqueViolation(err) {
+				return ErrAlreadyExists
+			}
+			return fmt.Errorf("inserting price: %w", err)
+		}
+
+		if scheduled {
+			return nil
+		}
+
+		_, err := activate(ctx, tx, &price)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
+
+// activate - makes a price the active price of its product, closing the previous price.
+// A price that starts before the active price, e.g. a scheduled price the cron had not applied yet when
+// a later change was made immediately, is superseded instead so it never replaces a newer price.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param price - *Price
+// @return whether the price was activated
+// @return error
+func activate(ctx context.Context, tx *sqlx.Tx, price *Price) (bool, error) {
+	now := time.Now().UTC()
+
+	// keep the price being replaced
+	var product struct {
+		Price money.Money `db:"price"`
+	}
+	if err := database.NamedStructQuery(ctx, tx, "SELECT price FROM products WHERE id = :product_id FOR UPDATE", price, &product); err != nil {
+		return false, fmt.Errorf("selecting product price: %w", err)
+	}
+
+	// supersede the price when the active price started after it
+	var active Price
+	err := database.NamedStructQuery(ctx, tx, "SELECT * FROM product_prices WHERE product_id = :product_id AND status = :status", map[string]interface{}{
+		"product_id": price.ProductId,
+		"status":     StatusActive,
+	}, &active)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return false, fmt.Errorf("selecting active price: %w", err)
+	}
+	if err == nil && active.EffectiveFrom.After(price.EffectiveFrom) {
+		price.Status = StatusSuperseded
+		price.EffectiveTo = &active.EffectiveFrom
+		price.UpdatedAt = now
+		if err := database.NamedExecQuery(ctx, tx, `
+      UPDATE product_prices SET status = :status, effective_to = :effective_to, updated_at = :updated_at WHERE id = :id
+    `, price); err != nil {
+			return false, fmt.Errorf("superseding price: %w", err)
+		}
+		return false, nil
+	}
+
+	// close the active price
+	if err := database.NamedExecQuery(ctx, tx, `
+    UPDATE product_prices
+    SET status = :superseded, effective_to = :effective_from, updated_at = :updated_at
+    WHERE product_id = :product_id AND status = :active
+  `, map[string]interface{}{
+		"superseded":     StatusSuperseded,
+		"active":         StatusActive,
+		"effective_from": price.EffectiveFrom,
+		"updated_at":     now,
+		"product_id":     price.ProductId,
+	}); err != nil {
+		return false, fmt.Errorf("closing active price: %w", err)
+	}
+
+	// activate the price
+	price.Status = StatusActive
+	price.UpdatedAt = now
+	if err := database.NamedExecQuery(ctx, tx, "UPDATE product_prices SET status = :status, updated_at = :updated_at WHERE id = :id", price); err != nil {
+		return false, fmt.Errorf("activating price: %w", err)
+	}
+
+	// update the price of the product
+	if err := database.NamedExecQuery(ctx, tx, "UPDATE products SET price = :price, updated_at = :updated_at WHERE id = :product_id", price); err != nil {
+		return false, fmt.Errorf("updating product price: %w", err)
+	}
+
+	// let other services know
+	event := &events.PriceChanged{Meta: events.NewMeta(events.TypePriceChanged, now), ProductId: price.ProductId, PriceId: price.Id, OldPrice: product.Price, NewPrice: price.Price}
+	if err := outbox.Add(ctx, tx, event.Meta, event); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ApplyScheduled - ApplyScheduled is a function that activates the scheduled prices that have started.
+// Prices are applied in the order they start, so the latest started price of a product ends up active,
+// and prices starting before the active price are superseded without being applied.
+//
+// @param ctx - context.Context
+// @param now - time.Time
+// @return number of prices applied
+// @return error
+func ApplyScheduled(ctx context.Context, now time.Time) (int, error) {
+	applied := 0
+
+	if err := database.Transaction(ctx, pricesDatabase(), func(tx *sqlx.Tx) error {
+		// lock the started prices so concurrent runs skip them
+		var prices []Price
+		q := `
+      SELECT * FROM product_prices
+      WHERE status = :status AND effective_from <= :now
+      ORDER BY effective_from, created_at
+      LIMIT 500
+      FOR UPDATE SKIP LOCKED
+    `
+		if err := database.NamedSliceQuery(ctx, tx, q, map[string]interface{}{
+			"status": StatusScheduled,
+			"now":    now.UTC(),
+		}, &prices); err != nil {
+			return fmt.Errorf("selecting scheduled prices: %w", err)
+		}
+
+		// activate the prices in order
+		for i := range prices {
+			activated, err := activate(ctx, tx, &prices[i])
+			if err != nil {
+				return err
+			}
+			if activated {
+				applied++
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return applied, nil
+}
+
+// Cancel - Cancel is a function that cancels a scheduled price change.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param id - string
+// @return error
+func Cancel(ctx context.Context, productId, id string) error {
+	// check if the price exists
+	price, err := FindOneByField(ctx, "id", "=", id)
+	if err != nil {
+		return err
+	}
+	if price.ProductId != productId {
+		return ErrNotFound
+	}
+
+	// only scheduled prices can be cancelled
+	if price.Status != StatusScheduled {
+		return ErrNotScheduled
+	}
+
+	// cancel the price, unless it was applied in the meantime
+	q := "UPDATE product_prices SET status = :cancelled, updated_at = NOW() WHERE id = :id AND status = :scheduled"
+	cancelled, err := database.NamedExecRowsQuery(ctx, pricesDatabase(), q, map[string]interface{}{
+		"cancelled": StatusCancelled,
+		"scheduled": StatusScheduled,
+		"id":        price.Id,
+	})
+	if err != nil {
+		return fmt.Errorf("cancelling price: %w", err)
+	}
+	if cancelled < 1 {
+		return ErrNotScheduled
+	}
+
+	return nil
+}
+
+// Timeline - Timeline is a function that gets every price of a product in the order they start.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @return prices
+// @return error
+func Timeline(ctx context.Context, productId string) ([]Price, error) {
+	prices := make([]Price, 0)
+
+	// query statement to be executed
+	q := `
+    SELECT * FROM product_prices
+    WHERE product_id = :product_id
+    ORDER BY effective_from, created_at
+  `
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, pricesDatabase(), q, map[string]interface{}{"product_id": productId}, &prices); err != nil {
+		return nil, fmt.Errorf("selecting prices: %w", err)
+	}
+
+	return prices, nil
+}
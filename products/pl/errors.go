@@ -0,0 +1,11 @@
+package pl
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("price not found")
+	ErrProductNotFound = errors.New("product not found")
+	ErrNotScheduled    = errors.New("price change is not scheduled")
+	ErrAlreadyExists   = errors.New("a price change is already scheduled at that time")
+	ErrInvalidPrice    = errors.New("price must be greater than zero and in the catalog currency")
+)
@@ -0,0 +1,42 @@
+package pl
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+const (
+	StatusScheduled  = "scheduled"
+	StatusActive     = "active"
+	StatusSuperseded = "superseded"
+	StatusCancelled  = "cancelled"
+)
+
+type Price struct {
+	Id            string      `json:"id" db:"id"`
+	ProductId     string      `json:"productId" db:"product_id"`
+	Price         money.Money `json:"price" db:"price"`
+	EffectiveFrom time.Time   `json:"effectiveFrom" db:"effective_from"`
+	EffectiveTo   *time.Time  `json:"effectiveTo" db:"effective_to"`
+	Status        string      `json:"status" db:"status"`
+	CreatedBy     string      `json:"createdBy" db:"created_by"`
+	Note          string      `json:"note" db:"note"`
+	CreatedAt     time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+type PriceChangeRequest struct {
+	Price         money.Money `json:"price"`                              // in the catalog currency
+	EffectiveFrom *time.Time  `json:"effectiveFrom" validate:"omitempty"` // applied immediately when empty or in the past
+	Note          string      `json:"note" validate:"omitempty,max=500"`
+}
+
+type PriceTimelineResponse struct {
+	ProductId string  `json:"productId"`
+	Prices    []Price `json:"data"`
+}
+
+type ApplyScheduledResponse struct {
+	Applied int `json:"applied"`
+}
@@ -0,0 +1,142 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/products/pl"
+)
+
+// =====================================================================================================================
+// PRICES
+// =====================================================================================================================
+
+// apply the scheduled price changes that have started
+var _ = cron.NewJob("apply-scheduled-prices", cron.JobConfig{
+	Title:    "Apply scheduled price changes",
+	Every:    5 * cron.Minute,
+	Endpoint: ApplyScheduledPrices,
+})
+
+// ChangePrice - Change the price of a product now or at a later time
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *pl.PriceChangeRequest
+//	@return price
+//	@return error
+//
+// encore:api auth method=POST path=/products/:id/prices
+func ChangePrice(ctx context.Context, id string, payload *pl.PriceChangeRequest) (*pl.Price, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &pl.Price{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &pl.Price{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// change the price
+	price, err := pl.Change(ctx, id, claims.Subject.Id, payload)
+	if err != nil {
+		return &pl.Price{}, priceError(err)
+	}
+
+	return price, nil
+}
+
+// GetPriceTimeline - Get every price of a product, including scheduled changes
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return prices
+//	@return error
+//
+// encore:api auth method=GET path=/products/:id/prices
+func GetPriceTimeline(ctx context.Context, id string) (*pl.PriceTimelineResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &pl.PriceTimelineResponse{}, err
+	}
+
+	// get the prices
+	prices, err := pl.Timeline(ctx, id)
+	if err != nil {
+		return &pl.PriceTimelineResponse{}, priceError(err)
+	}
+
+	return &pl.PriceTimelineResponse{
+		ProductId: id,
+		Prices:    prices,
+	}, nil
+}
+
+// CancelPriceChange - Cancel a scheduled price change
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param priceId - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/products/:id/prices/:priceId
+func CancelPriceChange(ctx context.Context, id, priceId string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// cancel the price change
+	if err := pl.Cancel(ctx, id, priceId); err != nil {
+		return priceError(err)
+	}
+
+	return nil
+}
+
+// ApplyScheduledPrices - Apply the scheduled price changes that have started
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/prices/apply-scheduled
+func ApplyScheduledPrices(ctx context.Context) (*pl.ApplyScheduledResponse, error) {
+	// apply the prices
+	applied, err := pl.ApplyScheduled(ctx, time.Now())
+	if err != nil {
+		return &pl.ApplyScheduledResponse{}, err
+	}
+
+	rlog.Info("products.ApplyScheduledPrices", "applied", applied)
+
+	return &pl.ApplyScheduledResponse{Applied: applied}, nil
+}
+
+// priceError - maps price store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func priceError(err error) error {
+	switch {
+	case errors.Is(err, pl.ErrNotFound), errors.Is(err, pl.ErrProductNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, pl.ErrAlreadyExists):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, pl.ErrInvalidPrice):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, pl.ErrNotScheduled):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	default:
+		return err
+	}
+}
@@ -2,22 +2,60 @@ package products
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"github.com/go-playground/validator/v10"
 
 	"encore.app/pkg/middleware"
+	"encore.app/pkg/money"
+	"encore.app/products/fx"
 	"encore.app/products/ps"
 )
 
+// CreateProduct - Create a product
+//
+//	@param ctx - context.Context
+//	@param payload - *ps.ProductRequest
+//	@return product
+//	@return error
+//
+// encore:api auth method=POST path=/products
+func CreateProduct(ctx context.Context, payload *ps.ProductRequest) (*ps.Product, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &ps.Product{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &ps.Product{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the product
+	product, err := ps.Create(ctx, payload)
+	if err != nil {
+		if errors.Is(err, ps.ErrInvalidPrice) {
+			return &ps.Product{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+		}
+		return &ps.Product{}, err
+	}
+
+	return &product, nil
+}
+
 // Get - Get a product
 //
 //	@param ctx - context.Context
 //	@param id
+//	@param params - *fx.CurrencyQuery
 //	@return product
 //	@return error
 //
 // encore:api auth method=GET path=/products/:id
-func Get(ctx context.Context, id string) (*ps.Product, error) {
+func Get(ctx context.Context, id string, params *fx.CurrencyQuery) (*ps.Product, error) {
 	// check for claims
 	claims, err := middleware.GetVerifiedClaims(ctx, "")
 	if err != nil {
@@ -33,8 +71,131 @@ func Get(ctx context.Context, id string) (*ps.Product, error) {
 		}
 	}
 
-	// get the product from
+	return getProduct(ctx, id, params)
+}
+
+// View - Get a product for a shopper, recording the view so popular products are suggested first
+//
+//	@param ctx - context.Context
+//	@param id
+//	@param params - *fx.CurrencyQuery
+//	@return product
+//	@return error
+//
+// encore:api public method=GET path=/products/view/:id
+func View(ctx context.Context, id string, params *fx.CurrencyQuery) (*ps.Product, error) {
+	product, err := getProduct(ctx, id, params)
+	if err != nil {
+		return product, err
+	}
+
+	// record the view
+	if err := ps.RecordView(ctx, product.Id); err != nil {
+		rlog.Error("products.View", "err", err)
+	}
+
+	return product, nil
+}
+
+// getProduct - gets a product priced in the currency asked for
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param params - *fx.CurrencyQuery
+//	@return product
+//	@return error
+func getProduct(ctx context.Context, id string, params *fx.CurrencyQuery) (*ps.Product, error) {
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &ps.Product{}, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	// get the product from the store
+	product, err := ps.FindOneByField(ctx, "id", "=", id)
+	if err != nil {
+		if errors.Is(err, ps.ErrNotFound) {
+			return &ps.Product{}, &errs.Error{Code: errs.NotFound, Message: err.Error()}
+		}
+		return &ps.Product{}, err
+	}
+
+	// price the product in the currency asked for
+	if err := fx.Localize(ctx, params.Currency, map[string]*money.Money{product.Id: &product.Price}); err != nil {
+		return &ps.Product{}, currencyError(err)
+	}
+
+	// return product
+	return &product, nil
+}
+
+// Search - Search products
+//
+//	@param ctx - context.Context
+//	@param params - *ps.SearchQuery
+//	@return products
+//	@return error
+//
+// encore:api public method=GET path=/products/search
+func Search(ctx context.Context, params *ps.SearchQuery) (*ps.PaginatedSearchResponse, error) {
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &ps.PaginatedSearchResponse{}, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	// check the price range
+	if _, _, err := params.PriceRange(); err != nil {
+		return &ps.PaginatedSearchResponse{}, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	// search products
+	products, err := ps.Search(ctx, params)
+	if err != nil {
+		return &ps.PaginatedSearchResponse{}, fmt.Errorf("searching products: %w", err)
+	}
+
+	// price the products in the currency asked for
+	prices := make(map[string]*money.Money, len(products.Products))
+	for i := range products.Products {
+		prices[products.Products[i].Id] = &products.Products[i].Price
+	}
+	if err := fx.Localize(ctx, params.Currency, prices); err != nil {
+		return &ps.PaginatedSearchResponse{}, currencyError(err)
+	}
+
+	return products, nil
+}
+
+// Suggest - Suggest product names, brands and categories for a search box
+//
+//	@param ctx - context.Context
+//	@param params - *ps.SuggestQuery
+//	@return suggestions
+//	@return error
+//
+// encore:api public method=GET path=/products/suggest
+func Suggest(ctx context.Context, params *ps.SuggestQuery) (*ps.SuggestResponse, error) {
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &ps.SuggestResponse{}, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	// get suggestions
+	suggestions, err := ps.Suggest(ctx, params)
+	if err != nil {
+		return &ps.SuggestResponse{}, fmt.Errorf("suggesting products: %w", err)
+	}
 
-	// return user
-	return &ps.Product{}, nil
+	return suggestions, nil
 }
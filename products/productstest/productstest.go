@@ -0,0 +1,40 @@
+// Package productstest holds the catalog rows shared by the database tests of the products subsystems.
+package productstest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+)
+
+// Create - Create is a function that inserts a product out of stock, in a category of its own, for a test to
+// move stock of.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @return product id
+// @return error
+func Create(ctx context.Context, db sqlx.ExtContext) (string, error) {
+	row := map[string]interface{}{
+		"category_id": uuid.New().String(),
+		"product_id":  uuid.New().String(),
+	}
+
+	// query statements to be executed
+	if err := database.NamedExecQuery(ctx, db, `
+    INSERT INTO categories (id, name) VALUES (:category_id, 'category ' || :category_id)
+  `, row); err != nil {
+		return "", fmt.Errorf("inserting category: %w", err)
+	}
+	if err := database.NamedExecQuery(ctx, db, `
+    INSERT INTO products (id, name, price, category_id) VALUES (:product_id, 'product ' || :product_id, ROW(1000, 'USD'), :category_id)
+  `, row); err != nil {
+		return "", fmt.Errorf("inserting product: %w", err)
+	}
+
+	return row["product_id"].(string), nil
+}
@@ -0,0 +1,266 @@
+package promo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/products/ps"
+)
+
+// the products database
+var promotionsDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// Get - Get is a function that gets a promotion.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return promotion
+// @return error
+func Get(ctx context.Context, id string) (*Promotion, error) {
+	var promotion Promotion
+
+	// execute query
+	if err := database.NamedStructQuery(ctx, promotionsDatabase(), "SELECT * FROM promotions WHERE id = :id", map[string]interface{}{
+		"id": id,
+	}, &promotion); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("selecting promotion by ID[%v]: %w", id, err)
+	}
+
+	return &promotion, nil
+}
+
+// List - List is a function that gets the promotions, the ones running at a time when running is set.
+// Running promotions do not include the promotions only applied with a coupon.
+//
+// @param ctx - context.Context
+// @param running - bool
+// @param at - time.Time
+// @return promotions
+// @return error
+func List(ctx context.Context, running bool, at time.Time) ([]Promotion, error) {
+	promotions := make([]Promotion, 0)
+
+	// query statement to be executed
+	q := "SELECT * FROM promotions ORDER BY priority DESC, id"
+	if running {
+		q = `
+      SELECT * FROM promotions
+      WHERE active AND NOT coupon_only AND (starts_at IS NULL OR starts_at <= :at) AND (ends_at IS NULL OR ends_at > :at)
+      ORDER BY priority DESC, id
+    `
+	}
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, promotionsDatabase(), q, map[string]interface{}{"at": at.UTC()}, &promotions); err != nil {
+		return nil, fmt.Errorf("selecting promotions: %w", err)
+	}
+
+	return promotions, nil
+}
+
+// Create - Create is a function that creates a promotion.
+//
+// @param ctx - context.Context
+// @param payload - *PromotionRequest
+// @return promotion
+// @return error
+func Create(ctx context.Context, payload *PromotionRequest) (*Promotion, error) {
+	now := time.Now().UTC()
+	promotion := fromRequest(payload)
+	promotion.Id = uuid.New().String()
+	promotion.CreatedAt = now
+	promotion.UpdatedAt = now
+
+	// check the settings of the promotion
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO promotions (
+      id, name, description, type, percentage, amount, buy_quantity, get_quantity, bundle_quantity, min_spend,
+      product_ids, category_ids, priority, stackable, coupon_only, active, starts_at, ends_at, created_at, updated_at
+    )
+    VALUES (
+      :id, :name, :description, :type, :percentage, :amount, :buy_quantity, :get_quantity, :bundle_quantity, :min_spend,
+      :product_ids, :category_ids, :priority, :stackable, :coupon_only, :active, :starts_at, :ends_at, :created_at, :updated_at
+    )
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, promotionsDatabase(), q, promotion); err != nil {
+		return nil, fmt.Errorf("inserting promotion: %w", err)
+	}
+
+	return &promotion, nil
+}
+
+// Update - Update is a function that replaces the settings of a promotion.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param payload - *PromotionRequest
+// @return promotion
+// @return error
+func Update(ctx context.Context, id string, payload *PromotionRequest) (*Promotion, error) {
+	// check if the promotion exists
+	current, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion := fromRequest(payload)
+	promotion.Id = current.Id
+	promotion.CreatedAt = current.CreatedAt
+	promotion.UpdatedAt = time.Now().UTC()
+
+	// check the settings of the promotion
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+
+	// query statement to be executed
+	q := `
+    UPDATE promotions SET
+      name = :name, description = :description, type = :type, percentage = :percentage, amount = :amount,
+      buy_quantity = :buy_quantity, get_quantity = :get_quantity, bundle_quantity = :bundle_quantity,
+      min_spend = :min_spend, product_ids = :product_ids, category_ids = :category_ids, priority = :priority,
+      stackable = :stackable, coupon_only = :coupon_only, active = :active, starts_at = :starts_at,
+      ends_at = :ends_at, updated_at = :updated_at
+    WHERE id = :id
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, promotionsDatabase(), q, promotion); err != nil {
+		return nil, fmt.Errorf("updating promotion: %w", err)
+	}
+
+	return &promotion, nil
+}
+
+// Delete - Delete is a function that deletes a promotion.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return error
+func Delete(ctx context.Context, id string) error {
+	// check if the promotion exists
+	if _, err := Get(ctx, id); err != nil {
+		return err
+	}
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, promotionsDatabase(), "DELETE FROM promotions WHERE id = :id", map[string]interface{}{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("deleting promotion: %w", err)
+	}
+
+	return nil
+}
+
+// EvaluateBasket - EvaluateBasket is a function that applies the running promotions to a basket of products.
+//
+// @param ctx - context.Context
+// @param payload - *EvaluateRequest
+// @param now - time.Time
+// @return result
+// @return error
+func EvaluateBasket(ctx context.Context, payload *EvaluateRequest, now time.Time) (*Result, error) {
+	// get the products of the basket
+	lines, err := Lines(ctx, payload.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	// get the running promotions
+	promotions, err := List(ctx, true, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return Evaluate(lines, promotions, now)
+}
+
+// Lines - Lines is a function that gets the products of a basket.
+//
+// @param ctx - context.Context
+// @param basket - []BasketLine
+// @return lines
+// @return error
+func Lines(ctx context.Context, basket []BasketLine) ([]Line, error) {
+	// get the products
+	ids := make([]string, 0, len(basket))
+	for _, line := range basket {
+		ids = append(ids, line.ProductId)
+	}
+	products, err := ps.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[string]ps.Product, len(products))
+	for _, product := range products {
+		byId[product.Id] = product
+	}
+
+	// create the lines
+	lines := make([]Line, 0, len(basket))
+	for _, line := range basket {
+		product, ok := byId[line.ProductId]
+		if !ok {
+			return nil, fmt.Errorf("%w: product %v not found", ErrInvalidBasket, line.ProductId)
+		}
+		lines = append(lines, Line{Product: product, Quantity: line.Quantity})
+	}
+
+	return lines, nil
+}
+
+// fromRequest - creates a promotion from its settings.
+//
+// @param payload - *PromotionRequest
+// @return Promotion
+func fromRequest(payload *PromotionRequest) Promotion {
+	promotion := Promotion{
+		Name:           payload.Name,
+		Description:    payload.Description,
+		Type:           payload.Type,
+		Percentage:     payload.Percentage,
+		Amount:         payload.Amount,
+		BuyQuantity:    payload.BuyQuantity,
+		GetQuantity:    payload.GetQuantity,
+		BundleQuantity: payload.BundleQuantity,
+		MinSpend:       payload.MinSpend,
+		ProductIds:     payload.ProductIds,
+		CategoryIds:    payload.CategoryIds,
+		Priority:       payload.Priority,
+		Stackable:      payload.Stackable,
+		CouponOnly:     payload.CouponOnly,
+		Active:         payload.Active,
+		StartsAt:       payload.StartsAt,
+		EndsAt:         payload.EndsAt,
+	}
+
+	// the lists are never null
+	if promotion.ProductIds == nil {
+		promotion.ProductIds = []string{}
+	}
+	if promotion.CategoryIds == nil {
+		promotion.CategoryIds = []string{}
+	}
+
+	return promotion
+}
@@ -0,0 +1,460 @@
+package promo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"encore.app/pkg/money"
+	"encore.app/pkg/slice"
+)
+
+// lineState - a line of the basket and what is left to discount on it
+type lineState struct {
+	line      Line
+	remaining int64 // the line total left after the promotions applied so far, in minor units
+}
+
+// Validate - Validate checks the settings of the promotion needed by its type.
+//
+// @return error
+func (p *Promotion) Validate() error {
+	fail := func(reason string) error {
+		return fmt.Errorf("%w: %v", ErrInvalidPromotion, reason)
+	}
+
+	// check the amounts
+	if p.Amount != nil {
+		if err := p.Amount.Validate(); err != nil || p.Amount.Amount <= 0 {
+			return fail("amount must be greater than zero and in a supported currency")
+		}
+	}
+	if p.MinSpend != nil {
+		if err := p.MinSpend.Validate(); err != nil || p.MinSpend.Amount <= 0 {
+			return fail("minSpend must be greater than zero and in a supported currency")
+		}
+	}
+	if p.Amount != nil && p.MinSpend != nil && p.Amount.Currency != p.MinSpend.Currency {
+		return fail("amount and minSpend must be in the same currency")
+	}
+	if p.Percentage < 0 || p.Percentage > 10000 {
+		return fail("percentage must be between 0 and 10000 basis points")
+	}
+
+	// check the settings of the type
+	switch p.Type {
+	case TypePercentage:
+		if p.Percentage < 1 {
+			return fail("percentage promotions need a percentage")
+		}
+	case TypeFixed:
+		if p.Amount == nil {
+			return fail("fixed promotions need an amount")
+		}
+	case TypeBuyXGetY:
+		if p.BuyQuantity < 1 || p.GetQuantity < 1 {
+			return fail("buy_x_get_y promotions need a buyQuantity and a getQuantity")
+		}
+		if p.Percentage < 1 {
+			return fail("buy_x_get_y promotions need the percentage off the items got, 10000 for free items")
+		}
+	case TypeBundle:
+		if p.BundleQuantity < 2 {
+			return fail("bundle promotions need a bundleQuantity of at least 2")
+		}
+		if p.Amount == nil {
+			return fail("bundle promotions need the price of a bundle as the amount")
+		}
+	default:
+		return fail(fmt.Sprintf("unknown type %q", p.Type))
+	}
+
+	// check the validity window
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fail("endsAt must be after startsAt")
+	}
+
+	return nil
+}
+
+// Evaluate - Evaluate applies the promotions to a basket and explains every promotion applied or skipped.
+// Promotions are applied in order of priority, highest first, and each one discounts what is left of
+// the lines after the ones before it. A promotion that is not stackable is only applied to a basket
+// without discounts, and no promotion is applied after it.
+// Evaluate does not change the lines or the promotions.
+//
+// @param lines - []Line
+// @param promotions - []Promotion
+// @param now - time.Time
+// @return result
+// @return error
+func Evaluate(lines []Line, promotions []Promotion, now time.Time) (*Result, error) {
+	// the basket is priced in the currency of its products
+	currency := money.DefaultCurrency
+	if len(lines) > 0 {
+		currency = lines[0].Product.Price.Currency
+	}
+
+	// total the lines
+	subtotal := money.Zero(currency)
+	states := make([]lineState, 0, len(lines))
+	for _, line := range lines {
+		if line.Quantity < 1 {
+			return nil, fmt.Errorf("%w: the quantity of product %v must be at least 1", ErrInvalidBasket, line.Product.Id)
+		}
+		if line.Product.Price.Currency != currency {
+			return nil, fmt.Errorf("%w: products are priced in %v and %v", ErrInvalidBasket, currency, line.Product.Price.Currency)
+		}
+
+		total, err := line.Product.Price.Mul(int64(line.Quantity))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBasket, err)
+		}
+		if subtotal, err = subtotal.Add(total); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBasket, err)
+		}
+		states = append(states, lineState{line: line, remaining: total.Amount})
+	}
+
+	// order the promotions by priority, keeping the order of equal priorities stable
+	ordered := make([]Promotion, len(promotions))
+	copy(ordered, promotions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Priority != ordered[j].Priority {
+			return ordered[i].Priority > ordered[j].Priority
+		}
+		return ordered[i].Id < ordered[j].Id
+	})
+
+	result := &Result{
+		Subtotal: subtotal,
+		Applied:  make([]AppliedPromotion, 0),
+		Skipped:  make([]SkippedPromotion, 0),
+	}
+
+	// the name of the applied promotion that cannot be combined
+	exclusive := ""
+
+	for _, p := range ordered {
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, SkippedPromotion{PromotionId: p.Id, Name: p.Name, Reason: reason})
+		}
+
+		// check the promotion applies to the basket
+		if reason := p.ineligible(now, subtotal); len(reason) > 0 {
+			skip(reason)
+			continue
+		}
+		if len(exclusive) > 0 {
+			skip(fmt.Sprintf("cannot be combined with %v", exclusive))
+			continue
+		}
+		if !p.Stackable && len(result.Applied) > 0 {
+			skip("cannot be combined with the promotions already applied")
+			continue
+		}
+		matching := p.matching(states)
+		if len(matching) < 1 {
+			skip("no items in the basket are included in the promotion")
+			continue
+		}
+
+		// work out the discount of every line
+		discounts, explanation, reason, err := p.discounts(states, matching, currency)
+		if err != nil {
+			return nil, fmt.Errorf("applying promotion %v: %w", p.Id, err)
+		}
+		if len(reason) > 0 {
+			skip(reason)
+			continue
+		}
+
+		// discount the lines, never below zero
+		applied := AppliedPromotion{
+			PromotionId: p.Id,
+			Name:        p.Name,
+			Discount:    money.Zero(currency),
+			Explanation: explanation,
+			Lines:       make([]LineDiscount, 0, len(matching)),
+		}
+		for i, discount := range discounts {
+			if discount > states[i].remaining {
+				discount = states[i].remaining
+			}
+			if discount <= 0 {
+				continue
+			}
+			states[i].remaining -= discount
+			applied.Discount.Amount += discount
+			applied.Lines = append(applied.Lines, LineDiscount{
+				ProductId: states[i].line.Product.Id,
+				Discount:  money.Money{Amount: discount, Currency: currency},
+			})
+		}
+		if applied.Discount.IsZero() {
+			skip("the items are already fully discounted")
+			continue
+		}
+
+		result.Applied = append(result.Applied, applied)
+		if !p.Stackable {
+			exclusive = p.Name
+		}
+	}
+
+	// total the discounts
+	result.Discount = money.Zero(currency)
+	for _, applied := range result.Applied {
+		result.Discount.Amount += applied.Discount.Amount
+	}
+	result.Total = money.Money{Amount: subtotal.Amount - result.Discount.Amount, Currency: currency}
+
+	return result, nil
+}
+
+// ineligible - returns why the promotion cannot be applied to a basket, or an empty string when it can.
+//
+// @param now - time.Time
+// @param subtotal - money.Money
+// @return string
+func (p *Promotion) ineligible(now time.Time, subtotal money.Money) string {
+	switch {
+	case !p.Active:
+		return "the promotion is not active"
+	case p.StartsAt != nil && now.Before(*p.StartsAt):
+		return fmt.Sprintf("the promotion starts at %v", p.StartsAt.UTC().Format(time.RFC3339))
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return fmt.Sprintf("the promotion ended at %v", p.EndsAt.UTC().Format(time.RFC3339))
+	case p.Amount != nil && p.Amount.Currency != subtotal.Currency:
+		return fmt.Sprintf("the promotion is priced in %v and the basket in %v", p.Amount.Currency, subtotal.Currency)
+	case p.MinSpend != nil && p.MinSpend.Currency != subtotal.Currency:
+		return fmt.Sprintf("the promotion is priced in %v and the basket in %v", p.MinSpend.Currency, subtotal.Currency)
+	case p.MinSpend != nil && subtotal.Amount < p.MinSpend.Amount:
+		return fmt.Sprintf("spend at least %v, the basket subtotal is %v", p.MinSpend.Format(), subtotal.Format())
+	default:
+		return ""
+	}
+}
+
+// matching - returns the indexes of the lines included in the promotion.
+//
+// @param states - []lineState
+// @return []int
+func (p *Promotion) matching(states []lineState) []int {
+	matching := make([]int, 0, len(states))
+	everything := len(p.ProductIds) < 1 && len(p.CategoryIds) < 1
+
+	for i, state := range states {
+		if everything || slice.Contains(p.ProductIds, state.line.Product.Id) || slice.Contains(p.CategoryIds, state.line.Product.CategoryId) {
+			matching = append(matching, i)
+		}
+	}
+
+	return matching
+}
+
+// discounts - returns the discount of every line, in minor units, and the explanation of the promotion.
+// A reason is returned instead when the matching lines do not qualify for a discount.
+//
+// @param states - []lineState
+// @param matching - []int
+// @param currency - string
+// @return discounts
+// @return explanation
+// @return reason
+// @return error
+func (p *Promotion) discounts(states []lineState, matching []int, currency string) ([]int64, string, string, error) {
+	discounts := make([]int64, len(states))
+
+	// count the matching items
+	units := 0
+	for _, i := range matching {
+		units += states[i].line.Quantity
+	}
+
+	var explanation string
+
+	switch p.Type {
+	case TypePercentage:
+		// take the percentage off what is left of every line
+		for _, i := range matching {
+			discount, err := money.Money{Amount: states[i].remaining, Currency: currency}.Percentage(p.Percentage, money.HalfUp)
+			if err != nil {
+				return nil, "", "", err
+			}
+			discounts[i] = discount.Amount
+		}
+		explanation = fmt.Sprintf("%v off %v", formatBasisPoints(p.Percentage), items(units))
+
+	case TypeFixed:
+		// take the amount off the matching lines, split by what is left of them
+		ratios := make([]int64, len(matching))
+		var left int64
+		for j, i := range matching {
+			ratios[j] = states[i].remaining
+			left += states[i].remaining
+		}
+		if left <= 0 {
+			return nil, "", "the items are already fully discounted", nil
+		}
+		off := p.Amount.Amount
+		if off > left {
+			off = left
+		}
+		parts, err := money.Money{Amount: off, Currency: currency}.Allocate(ratios...)
+		if err != nil {
+			return nil, "", "", err
+		}
+		for j, i := range matching {
+			discounts[i] = parts[j].Amount
+		}
+		explanation = fmt.Sprintf("%v off %v", p.Amount.Format(), items(units))
+
+	case TypeBuyXGetY:
+		// every group of buy + get items gives the cheapest items at a discount
+		discounted := units / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+		if discounted < 1 {
+			return nil, "", fmt.Sprintf("buy %v get %v needs %v, the basket has %v", p.BuyQuantity, p.GetQuantity, items(p.BuyQuantity+p.GetQuantity), items(units)), nil
+		}
+
+		for _, take := range takeUnits(states, matching, discounted, true) {
+			total, err := states[take.index].line.Product.Price.Mul(int64(take.units))
+			if err != nil {
+				return nil, "", "", err
+			}
+			discount, err := total.Percentage(p.Percentage, money.HalfUp)
+			if err != nil {
+				return nil, "", "", err
+			}
+			discounts[take.index] = discount.Amount
+		}
+
+		offer := "free"
+		if p.Percentage < 10000 {
+			offer = fmt.Sprintf("at %v off", formatBasisPoints(p.Percentage))
+		}
+		explanation = fmt.Sprintf("buy %v get %v %v, %v discounted", p.BuyQuantity, p.GetQuantity, offer, items(discounted))
+
+	case TypeBundle:
+		// bundle the most expensive items
+		bundles := units / p.BundleQuantity
+		if bundles < 1 {
+			return nil, "", fmt.Sprintf("a bundle needs %v, the basket has %v", items(p.BundleQuantity), items(units)), nil
+		}
+
+		takes := takeUnits(states, matching, bundles*p.BundleQuantity, false)
+		ratios := make([]int64, len(takes))
+		var value int64
+		for j, take := range takes {
+			total, err := states[take.index].line.Product.Price.Mul(int64(take.units))
+			if err != nil {
+				return nil, "", "", err
+			}
+			ratios[j] = total.Amount
+			value += total.Amount
+		}
+
+		// the saving is the difference to the price of the bundles
+		price, err := p.Amount.Mul(int64(bundles))
+		if err != nil {
+			return nil, "", "", err
+		}
+		if value <= price.Amount {
+			return nil, "", "the bundle price is not lower than the price of the items", nil
+		}
+		parts, err := money.Money{Amount: value - price.Amount, Currency: currency}.Allocate(ratios...)
+		if err != nil {
+			return nil, "", "", err
+		}
+		for j, take := range takes {
+			discounts[take.index] = parts[j].Amount
+		}
+
+		explanation = fmt.Sprintf("%v of %v for %v", plural(bundles, "bundle"), items(p.BundleQuantity), p.Amount.Format())
+
+	default:
+		return nil, "", fmt.Sprintf("unknown type %q", p.Type), nil
+	}
+
+	// mention the minimum spend
+	if p.MinSpend != nil {
+		explanation += fmt.Sprintf(" on baskets of %v or more", p.MinSpend.Format())
+	}
+
+	return discounts, explanation, "", nil
+}
+
+// take - a number of items taken from a line
+type take struct {
+	index int
+	units int
+}
+
+// takeUnits - takes a number of items from the matching lines, cheapest or most expensive first.
+//
+// @param states - []lineState
+// @param matching - []int
+// @param count - int
+// @param cheapest - bool
+// @return []take
+func takeUnits(states []lineState, matching []int, count int, cheapest bool) []take {
+	// order the lines by unit price
+	ordered := make([]int, len(matching))
+	copy(ordered, matching)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := states[ordered[i]].line.Product.Price.Amount, states[ordered[j]].line.Product.Price.Amount
+		if cheapest {
+			return a < b
+		}
+		return a > b
+	})
+
+	takes := make([]take, 0, len(ordered))
+	for _, i := range ordered {
+		if count < 1 {
+			break
+		}
+		units := states[i].line.Quantity
+		if units > count {
+			units = count
+		}
+		takes = append(takes, take{index: i, units: units})
+		count -= units
+	}
+
+	return takes
+}
+
+// formatBasisPoints - formats basis points as a percentage, e.g. 12.5%.
+//
+// @param basisPoints - int64
+// @return string
+func formatBasisPoints(basisPoints int64) string {
+	if basisPoints%100 == 0 {
+		return fmt.Sprintf("%v%%", basisPoints/100)
+	}
+
+	return strings.TrimRight(fmt.Sprintf("%v.%02d", basisPoints/100, basisPoints%100), "0") + "%"
+}
+
+// items - formats a number of items, e.g. 1 item or 3 items.
+//
+// @param n - int
+// @return string
+func items(n int) string {
+	return plural(n, "item")
+}
+
+// plural - formats a number of things.
+//
+// @param n - int
+// @param noun - string
+// @return string
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%v %v", n, noun)
+	}
+
+	return fmt.Sprintf("%v %vs", n, noun)
+}
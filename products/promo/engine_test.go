@@ -0,0 +1,371 @@
+package promo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/pkg/money"
+	"encore.app/pkg/money/moneytest"
+	"encore.app/products/ps"
+)
+
+// the time the promotions are evaluated at
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// the categories of the test products
+const (
+	fruit  = "fruit"
+	drinks = "drinks"
+)
+
+// line - creates a basket line of a product priced in dollars.
+//
+//	@param id - string
+//	@param category - string
+//	@param cents - int64
+//	@param quantity - int
+//	@return Line
+func line(id, category string, cents int64, quantity int) Line {
+	return Line{
+		Product:  ps.Product{Id: id, Name: id, CategoryId: category, Price: moneytest.USD(cents)},
+		Quantity: quantity,
+	}
+}
+
+// at - returns a time relative to now.
+//
+//	@param d - time.Duration
+//	@return *time.Time
+func at(d time.Duration) *time.Time {
+	t := now.Add(d)
+	return &t
+}
+
+// TestEvaluate - test the discounts of every type of promotion
+//
+//	@param t - testing.T
+func TestEvaluate(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		name        string
+		lines       []Line
+		promotion   Promotion
+		discounts   map[string]int64
+		explanation string
+		reason      string
+	}{
+		{
+			name:        "percentage off every item",
+			lines:       []Line{line("apple", fruit, 1999, 2), line("juice", drinks, 350, 1)},
+			promotion:   Promotion{Type: TypePercentage, Percentage: 1000},
+			discounts:   map[string]int64{"apple": 400, "juice": 35},
+			explanation: "10% off 3 items",
+		},
+		{
+			name:        "percentage off a category",
+			lines:       []Line{line("apple", fruit, 1000, 1), line("juice", drinks, 350, 1)},
+			promotion:   Promotion{Type: TypePercentage, Percentage: 1250, CategoryIds: []string{drinks}},
+			discounts:   map[string]int64{"juice": 44},
+			explanation: "12.5% off 1 item",
+		},
+		{
+			name:        "fixed amount split across the items",
+			lines:       []Line{line("apple", fruit, 300, 1), line("pear", fruit, 100, 1), line("juice", drinks, 350, 1)},
+			promotion:   Promotion{Type: TypeFixed, Amount: moneytest.USDRef(200), CategoryIds: []string{fruit}},
+			discounts:   map[string]int64{"apple": 150, "pear": 50},
+			explanation: "$2.00 off 2 items",
+		},
+		{
+			name:        "fixed amount capped at the price of the items",
+			lines:       []Line{line("juice", drinks, 350, 1)},
+			promotion:   Promotion{Type: TypeFixed, Amount: moneytest.USDRef(500), ProductIds: []string{"juice"}},
+			discounts:   map[string]int64{"juice": 350},
+			explanation: "$5.00 off 1 item",
+		},
+		{
+			name:        "buy two get the cheapest free",
+			lines:       []Line{line("apple", fruit, 1000, 2), line("pear", fruit, 400, 1)},
+			promotion:   Promotion{Type: TypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Percentage: 10000},
+			discounts:   map[string]int64{"pear": 400},
+			explanation: "buy 2 get 1 free, 1 item discounted",
+		},
+		{
+			name:        "buy one get one at half price",
+			lines:       []Line{line("apple", fruit, 1000, 3), line("pear", fruit, 400, 2)},
+			promotion:   Promotion{Type: TypeBuyXGetY, BuyQuantity: 1, GetQuantity: 1, Percentage: 5000},
+			discounts:   map[string]int64{"pear": 400, "apple": 0},
+			explanation: "buy 1 get 1 at 50% off, 2 items discounted",
+		},
+		{
+			name:      "buy two get one without enough items",
+			lines:     []Line{line("apple", fruit, 1000, 2)},
+			promotion: Promotion{Type: TypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Percentage: 10000},
+			reason:    "buy 2 get 1 needs 3 items, the basket has 2 items",
+		},
+		{
+			name:        "bundle of the most expensive items",
+			lines:       []Line{line("apple", fruit, 400, 3), line("pear", fruit, 500, 1)},
+			promotion:   Promotion{Type: TypeBundle, BundleQuantity: 3, Amount: moneytest.USDRef(1000)},
+			discounts:   map[string]int64{"pear": 116, "apple": 184},
+			explanation: "1 bundle of 3 items for $10.00",
+		},
+		{
+			name:      "bundle that does not save",
+			lines:     []Line{line("apple", fruit, 300, 2)},
+			promotion: Promotion{Type: TypeBundle, BundleQuantity: 2, Amount: moneytest.USDRef(600)},
+			reason:    "the bundle price is not lower than the price of the items",
+		},
+		{
+			name:        "minimum spend reached",
+			lines:       []Line{line("apple", fruit, 2500, 2)},
+			promotion:   Promotion{Type: TypeFixed, Amount: moneytest.USDRef(500), MinSpend: moneytest.USDRef(5000)},
+			discounts:   map[string]int64{"apple": 500},
+			explanation: "$5.00 off 2 items on baskets of $50.00 or more",
+		},
+		{
+			name:      "minimum spend not reached",
+			lines:     []Line{line("apple", fruit, 2499, 2)},
+			promotion: Promotion{Type: TypeFixed, Amount: moneytest.USDRef(500), MinSpend: moneytest.USDRef(5000)},
+			reason:    "spend at least $50.00, the basket subtotal is $49.98",
+		},
+		{
+			name:      "no matching items",
+			lines:     []Line{line("apple", fruit, 1000, 1)},
+			promotion: Promotion{Type: TypePercentage, Percentage: 1000, CategoryIds: []string{drinks}},
+			reason:    "no items in the basket are included in the promotion",
+		},
+		{
+			name:      "not started",
+			lines:     []Line{line("apple", fruit, 1000, 1)},
+			promotion: Promotion{Type: TypePercentage, Percentage: 1000, StartsAt: at(time.Hour)},
+			reason:    "the promotion starts at 2024-06-01T13:00:00Z",
+		},
+		{
+			name:      "ended",
+			lines:     []Line{line("apple", fruit, 1000, 1)},
+			promotion: Promotion{Type: TypePercentage, Percentage: 1000, StartsAt: at(-2 * time.Hour), EndsAt: at(0)},
+			reason:    "the promotion ended at 2024-06-01T12:00:00Z",
+		},
+		{
+			name:      "priced in another currency",
+			lines:     []Line{line("apple", fruit, 1000, 1)},
+			promotion: Promotion{Type: TypeFixed, Amount: &money.Money{Amount: 100, Currency: "EUR"}},
+			reason:    "the promotion is priced in EUR and the basket in USD",
+		},
+	}
+
+	// check the discounts of every promotion
+	for _, item := range slice {
+		item.promotion.Id = "promotion"
+		item.promotion.Name = item.name
+		item.promotion.Active = true
+
+		result, err := Evaluate(item.lines, []Promotion{item.promotion}, now)
+		if err != nil {
+			t.Errorf("%v: should evaluate, got %v", item.name, err)
+			continue
+		}
+
+		// skipped promotions explain why
+		if len(item.reason) > 0 {
+			if len(result.Applied) > 0 || len(result.Skipped) != 1 || result.Skipped[0].Reason != item.reason {
+				t.Errorf("%v: should be skipped with %q, got %+v", item.name, item.reason, result)
+			}
+			if result.Total != result.Subtotal {
+				t.Errorf("%v: total should be the subtotal, got %v and %v", item.name, result.Total, result.Subtotal)
+			}
+			continue
+		}
+
+		if len(result.Applied) != 1 {
+			t.Errorf("%v: should be applied, got %+v", item.name, result)
+			continue
+		}
+		applied := result.Applied[0]
+		if applied.Explanation != item.explanation {
+			t.Errorf("%v: should be explained as %q, got %q", item.name, item.explanation, applied.Explanation)
+		}
+
+		// compare the discount of every line
+		var total int64
+		discounts := map[string]int64{}
+		for _, line := range applied.Lines {
+			discounts[line.ProductId] = line.Discount.Amount
+			total += line.Discount.Amount
+		}
+		for id, discount := range item.discounts {
+			if discounts[id] != discount {
+				t.Errorf("%v: %v should be discounted by %v, got %v", item.name, id, discount, discounts[id])
+			}
+		}
+		if applied.Discount.Amount != total || result.Discount.Amount != total || result.Total.Amount != result.Subtotal.Amount-total {
+			t.Errorf("%v: totals should add up, got %+v", item.name, result)
+		}
+	}
+}
+
+// TestEvaluateStacking - test the order and combination of promotions
+//
+//	@param t - testing.T
+func TestEvaluateStacking(t *testing.T) {
+	lines := []Line{line("apple", fruit, 1000, 1)}
+	tenPercent := func(id string, priority int, stackable bool) Promotion {
+		return Promotion{Id: id, Name: id, Type: TypePercentage, Percentage: 1000, Priority: priority, Stackable: stackable, Active: true}
+	}
+
+	// create a slice
+	slice := []struct {
+		name       string
+		promotions []Promotion
+		applied    []string
+		skipped    map[string]string
+		total      int64
+	}{
+		{
+			name:       "stackable promotions compound",
+			promotions: []Promotion{tenPercent("a", 1, true), tenPercent("b", 2, true)},
+			applied:    []string{"b", "a"},
+			total:      810,
+		},
+		{
+			name:       "an exclusive promotion stops the others",
+			promotions: []Promotion{tenPercent("a", 1, true), tenPercent("b", 2, false)},
+			applied:    []string{"b"},
+			skipped:    map[string]string{"a": "cannot be combined with b"},
+			total:      900,
+		},
+		{
+			name:       "an exclusive promotion needs a basket without discounts",
+			promotions: []Promotion{tenPercent("a", 2, true), tenPercent("b", 1, false)},
+			applied:    []string{"a"},
+			skipped:    map[string]string{"b": "cannot be combined with the promotions already applied"},
+			total:      900,
+		},
+		{
+			name:       "equal priorities are ordered by id",
+			promotions: []Promotion{tenPercent("b", 1, false), tenPercent("a", 1, false)},
+			applied:    []string{"a"},
+			skipped:    map[string]string{"b": "cannot be combined with a"},
+			total:      900,
+		},
+		{
+			name: "an inactive exclusive promotion does not stop the others",
+			promotions: []Promotion{
+				{Id: "a", Name: "a", Type: TypePercentage, Percentage: 1000, Priority: 2},
+				tenPercent("b", 1, true),
+			},
+			applied: []string{"b"},
+			skipped: map[string]string{"a": "the promotion is not active"},
+			total:   900,
+		},
+		{
+			name: "fully discounted items",
+			promotions: []Promotion{
+				{Id: "a", Name: "a", Type: TypeFixed, Amount: moneytest.USDRef(1000), Priority: 2, Stackable: true, Active: true},
+				tenPercent("b", 1, true),
+			},
+			applied: []string{"a"},
+			skipped: map[string]string{"b": "the items are already fully discounted"},
+			total:   0,
+		},
+	}
+
+	// check the promotions applied to the basket
+	for _, item := range slice {
+		result, err := Evaluate(lines, item.promotions, now)
+		if err != nil {
+			t.Errorf("%v: should evaluate, got %v", item.name, err)
+			continue
+		}
+
+		applied := make([]string, 0, len(result.Applied))
+		for _, p := range result.Applied {
+			applied = append(applied, p.PromotionId)
+		}
+		if len(applied) != len(item.applied) {
+			t.Errorf("%v: should apply %v, got %v", item.name, item.applied, applied)
+			continue
+		}
+		for i := range applied {
+			if applied[i] != item.applied[i] {
+				t.Errorf("%v: should apply %v, got %v", item.name, item.applied, applied)
+				break
+			}
+		}
+
+		for _, p := range result.Skipped {
+			if item.skipped[p.PromotionId] != p.Reason {
+				t.Errorf("%v: %v should be skipped with %q, got %q", item.name, p.PromotionId, item.skipped[p.PromotionId], p.Reason)
+			}
+		}
+		if result.Total.Amount != item.total {
+			t.Errorf("%v: total should be %v, got %v", item.name, item.total, result.Total.Amount)
+		}
+	}
+}
+
+// TestEvaluateErrors - test invalid baskets are rejected
+//
+//	@param t - testing.T
+func TestEvaluateErrors(t *testing.T) {
+	euro := line("juice", drinks, 350, 1)
+	euro.Product.Price.Currency = "EUR"
+
+	// create a slice
+	slice := [][]Line{
+		{line("apple", fruit, 1000, 0)},
+		{line("apple", fruit, 1000, 1), euro},
+	}
+
+	// check the error of every basket
+	for _, lines := range slice {
+		if _, err := Evaluate(lines, nil, now); !errors.Is(err, ErrInvalidBasket) {
+			t.Errorf("basket %+v should fail with %v, got %v", lines, ErrInvalidBasket, err)
+		}
+	}
+
+	// an empty basket has nothing to discount
+	result, err := Evaluate(nil, []Promotion{{Id: "a", Type: TypePercentage, Percentage: 1000, Active: true}}, now)
+	if err != nil || !result.Total.IsZero() || len(result.Skipped) != 1 {
+		t.Errorf("an empty basket should not be discounted, got %+v %v", result, err)
+	}
+}
+
+// TestValidate - test the settings of every type of promotion are checked
+//
+//	@param t - testing.T
+func TestValidate(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		promotion Promotion
+		valid     bool
+	}{
+		{promotion: Promotion{Type: TypePercentage, Percentage: 1000}, valid: true},
+		{promotion: Promotion{Type: TypePercentage}},
+		{promotion: Promotion{Type: TypePercentage, Percentage: 10001}},
+		{promotion: Promotion{Type: TypeFixed, Amount: moneytest.USDRef(500)}, valid: true},
+		{promotion: Promotion{Type: TypeFixed}},
+		{promotion: Promotion{Type: TypeFixed, Amount: &money.Money{Amount: 500, Currency: "ABC"}}},
+		{promotion: Promotion{Type: TypeFixed, Amount: moneytest.USDRef(500), MinSpend: &money.Money{Amount: 500, Currency: "EUR"}}},
+		{promotion: Promotion{Type: TypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Percentage: 10000}, valid: true},
+		{promotion: Promotion{Type: TypeBuyXGetY, BuyQuantity: 2, Percentage: 10000}},
+		{promotion: Promotion{Type: TypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1}},
+		{promotion: Promotion{Type: TypeBundle, BundleQuantity: 3, Amount: moneytest.USDRef(1000)}, valid: true},
+		{promotion: Promotion{Type: TypeBundle, BundleQuantity: 1, Amount: moneytest.USDRef(1000)}},
+		{promotion: Promotion{Type: TypeBundle, BundleQuantity: 3}},
+		{promotion: Promotion{Type: TypePercentage, Percentage: 1000, StartsAt: at(time.Hour), EndsAt: at(0)}},
+		{promotion: Promotion{Type: "free_shipping"}},
+	}
+
+	// check every promotion
+	for _, item := range slice {
+		err := item.promotion.Validate()
+		if item.valid && err != nil {
+			t.Errorf("promotion %+v should be valid, got %v", item.promotion, err)
+		}
+		if !item.valid && !errors.Is(err, ErrInvalidPromotion) {
+			t.Errorf("promotion %+v should be invalid, got %v", item.promotion, err)
+		}
+	}
+}
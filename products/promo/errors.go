@@ -0,0 +1,9 @@
+package promo
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("promotion not found")
+	ErrInvalidPromotion = errors.New("invalid promotion")
+	ErrInvalidBasket    = errors.New("invalid basket")
+)
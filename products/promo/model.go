@@ -0,0 +1,112 @@
+package promo
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+	"encore.app/products/ps"
+)
+
+const (
+	TypePercentage = "percentage"  // a percentage off the matching items
+	TypeFixed      = "fixed"       // an amount off the matching items
+	TypeBuyXGetY   = "buy_x_get_y" // buy a number of matching items and get more at a discount
+	TypeBundle     = "bundle"      // a number of matching items for a fixed price
+)
+
+type Promotion struct {
+	Id             string       `json:"id" db:"id"`
+	Name           string       `json:"name" db:"name"`
+	Description    string       `json:"description" db:"description"`
+	Type           string       `json:"type" db:"type"`
+	Percentage     int64        `json:"percentage" db:"percentage"`          // in basis points, 1250 is 12.5%, also the discount on the free items of buy_x_get_y
+	Amount         *money.Money `json:"amount" db:"amount"`                  // the amount off of fixed, the price of a bundle
+	BuyQuantity    int          `json:"buyQuantity" db:"buy_quantity"`       // the items to buy for buy_x_get_y
+	GetQuantity    int          `json:"getQuantity" db:"get_quantity"`       // the discounted items of buy_x_get_y
+	BundleQuantity int          `json:"bundleQuantity" db:"bundle_quantity"` // the items in a bundle
+	MinSpend       *money.Money `json:"minSpend" db:"min_spend"`             // the lowest basket subtotal the promotion applies to
+	ProductIds     []string     `json:"productIds" db:"product_ids"`         // the matching products, every product when both lists are empty
+	CategoryIds    []string     `json:"categoryIds" db:"category_ids"`       // the matching categories
+	Priority       int          `json:"priority" db:"priority"`              // promotions with a higher priority are applied first
+	Stackable      bool         `json:"stackable" db:"stackable"`            // whether the promotion combines with others
+	CouponOnly     bool         `json:"couponOnly" db:"coupon_only"`         // whether the promotion is only applied with a coupon
+	Active         bool         `json:"active" db:"active"`
+	StartsAt       *time.Time   `json:"startsAt" db:"starts_at"`
+	EndsAt         *time.Time   `json:"endsAt" db:"ends_at"`
+	CreatedAt      time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updatedAt" db:"updated_at"`
+}
+
+type PromotionRequest struct {
+	Name           string       `json:"name" validate:"required,max=255"`
+	Description    string       `json:"description" validate:"omitempty"`
+	Type           string       `json:"type" validate:"required,oneof=percentage fixed buy_x_get_y bundle"`
+	Percentage     int64        `json:"percentage" validate:"omitempty,min=0,max=10000"`
+	Amount         *money.Money `json:"amount" validate:"omitempty"`
+	BuyQuantity    int          `json:"buyQuantity" validate:"omitempty,min=0"`
+	GetQuantity    int          `json:"getQuantity" validate:"omitempty,min=0"`
+	BundleQuantity int          `json:"bundleQuantity" validate:"omitempty,min=0"`
+	MinSpend       *money.Money `json:"minSpend" validate:"omitempty"`
+	ProductIds     []string     `json:"productIds" validate:"omitempty,dive,uuid"`
+	CategoryIds    []string     `json:"categoryIds" validate:"omitempty,dive,uuid"`
+	Priority       int          `json:"priority"`
+	Stackable      bool         `json:"stackable"`
+	CouponOnly     bool         `json:"couponOnly"`
+	Active         bool         `json:"active"`
+	StartsAt       *time.Time   `json:"startsAt" validate:"omitempty"`
+	EndsAt         *time.Time   `json:"endsAt" validate:"omitempty"`
+}
+
+type PromotionsQuery struct {
+	Running bool `json:"running" query:"running"` // only the promotions running now
+}
+
+type PromotionsResponse struct {
+	Promotions []Promotion `json:"data"`
+}
+
+// Line - a product in a basket
+type Line struct {
+	Product  ps.Product `json:"product"`
+	Quantity int        `json:"quantity"`
+}
+
+// Result - the discounts applied to a basket
+type Result struct {
+	Subtotal money.Money        `json:"subtotal"`
+	Discount money.Money        `json:"discount"`
+	Total    money.Money        `json:"total"`
+	Applied  []AppliedPromotion `json:"applied"`
+	Skipped  []SkippedPromotion `json:"skipped"`
+}
+
+// AppliedPromotion - a promotion applied to a basket and the discount it gave each line
+type AppliedPromotion struct {
+	PromotionId string         `json:"promotionId"`
+	Name        string         `json:"name"`
+	Discount    money.Money    `json:"discount"`
+	Explanation string         `json:"explanation"`
+	Lines       []LineDiscount `json:"lines"`
+}
+
+// LineDiscount - the discount given to a line
+type LineDiscount struct {
+	ProductId string      `json:"productId"`
+	Discount  money.Money `json:"discount"`
+}
+
+// SkippedPromotion - a promotion not applied to a basket and why
+type SkippedPromotion struct {
+	PromotionId string `json:"promotionId"`
+	Name        string `json:"name"`
+	Reason      string `json:"reason"`
+}
+
+type BasketLine struct {
+	ProductId string `json:"productId" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"required,min=1"`
+}
+
+type EvaluateRequest struct {
+	Lines []BasketLine `json:"lines" validate:"required,min=1,max=200,dive"`
+}
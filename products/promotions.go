@@ -0,0 +1,179 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/products/promo"
+)
+
+// =====================================================================================================================
+// PROMOTIONS
+// =====================================================================================================================
+
+// CreatePromotion - Create a promotion
+//
+//	@param ctx - context.Context
+//	@param payload - *promo.PromotionRequest
+//	@return promotion
+//	@return error
+//
+// encore:api auth method=POST path=/promotions
+func CreatePromotion(ctx context.Context, payload *promo.PromotionRequest) (*promo.Promotion, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &promo.Promotion{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &promo.Promotion{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the promotion
+	promotion, err := promo.Create(ctx, payload)
+	if err != nil {
+		return &promo.Promotion{}, promotionError(err)
+	}
+
+	return promotion, nil
+}
+
+// ListPromotions - List the promotions
+//
+//	@param ctx - context.Context
+//	@param params - *promo.PromotionsQuery
+//	@return promotions
+//	@return error
+//
+// encore:api auth method=GET path=/promotions
+func ListPromotions(ctx context.Context, params *promo.PromotionsQuery) (*promo.PromotionsResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &promo.PromotionsResponse{}, err
+	}
+
+	// get the promotions
+	promotions, err := promo.List(ctx, params.Running, time.Now())
+	if err != nil {
+		return &promo.PromotionsResponse{}, err
+	}
+
+	return &promo.PromotionsResponse{Promotions: promotions}, nil
+}
+
+// GetPromotion - Get a promotion
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return promotion
+//	@return error
+//
+// encore:api auth method=GET path=/promotions/:id
+func GetPromotion(ctx context.Context, id string) (*promo.Promotion, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &promo.Promotion{}, err
+	}
+
+	// get the promotion
+	promotion, err := promo.Get(ctx, id)
+	if err != nil {
+		return &promo.Promotion{}, promotionError(err)
+	}
+
+	return promotion, nil
+}
+
+// UpdatePromotion - Replace the settings of a promotion
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *promo.PromotionRequest
+//	@return promotion
+//	@return error
+//
+// encore:api auth method=PUT path=/promotions/:id
+func UpdatePromotion(ctx context.Context, id string, payload *promo.PromotionRequest) (*promo.Promotion, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &promo.Promotion{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &promo.Promotion{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// update the promotion
+	promotion, err := promo.Update(ctx, id, payload)
+	if err != nil {
+		return &promo.Promotion{}, promotionError(err)
+	}
+
+	return promotion, nil
+}
+
+// DeletePromotion - Delete a promotion
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/promotions/:id
+func DeletePromotion(ctx context.Context, id string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// delete the promotion
+	if err := promo.Delete(ctx, id); err != nil {
+		return promotionError(err)
+	}
+
+	return nil
+}
+
+// EvaluatePromotions - Apply the running promotions to a basket and explain the discounts
+//
+//	@param ctx - context.Context
+//	@param payload - *promo.EvaluateRequest
+//	@return result
+//	@return error
+//
+// encore:api public method=POST path=/promotions/evaluate
+func EvaluatePromotions(ctx context.Context, payload *promo.EvaluateRequest) (*promo.Result, error) {
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &promo.Result{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// apply the promotions
+	result, err := promo.EvaluateBasket(ctx, payload, time.Now())
+	if err != nil {
+		return &promo.Result{}, promotionError(err)
+	}
+
+	return result, nil
+}
+
+// promotionError - maps promotion store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func promotionError(err error) error {
+	switch {
+	case errors.Is(err, promo.ErrNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, promo.ErrInvalidPromotion), errors.Is(err, promo.ErrInvalidBasket):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	default:
+		return err
+	}
+}
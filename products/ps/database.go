@@ -11,10 +11,17 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"encore.app/pkg/database"
+	"encore.app/pkg/events"
+	"encore.app/pkg/money"
+	"encore.app/pkg/outbox"
+	"encore.app/products/inventory"
+	"encore.app/products/pl"
 )
 
-// get the service name
-var productsDatabase = sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+// the products database
+var productsDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
 
 // FindOneByField - get product by field
 //
@@ -38,7 +45,7 @@ func FindOneByField(ctx context.Context, field, ops string, value interface{}) (
 	// declare product
 	var product Product
 	// execute query
-	if err := database.NamedStructQuery(ctx, productsDatabase, q, data, &product); err != nil {
+	if err := database.NamedStructQuery(ctx, productsDatabase(), q, data, &product); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
 			return Product{}, ErrNotFound
 		}
@@ -55,15 +62,22 @@ func FindOneByField(ctx context.Context, field, ops string, value interface{}) (
 // @return product
 // @return error
 func Create(ctx context.Context, payload *ProductRequest) (Product, error) {
+	// check the price, kept in the currency of the catalog so prices can be filtered and sorted together
+	if err := payload.Price.Validate(); err != nil || payload.Price.Amount <= 0 || payload.Price.Currency != money.DefaultCurrency {
+		return Product{}, ErrInvalidPrice
+	}
+
 	// create a new product
 	product := Product{
-		Id:          uuid.New().String(),
-		Name:        payload.Name,
-		Description: payload.Description,
-		Price:       payload.Price,
-		CategoryId:  payload.CategoryId,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Id:            uuid.New().String(),
+		Name:          payload.Name,
+		Brand:         payload.Brand,
+		Description:   payload.Description,
+		Price:         payload.Price,
+		CategoryId:    payload.CategoryId,
+		StockQuantity: payload.StockQuantity,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 
 	// insert product into database
@@ -72,13 +86,36 @@ func Create(ctx context.Context, payload *ProductRequest) (Product, error) {
 	}
 
 	query := `
-    INSERT INTO products (id, name, description, price, category_id, created_at, updated_at)
-    VALUES (:id, :name, :description, :price, :category_id, :created_at, :updated_at)
+    INSERT INTO products (id, name, brand, description, price, category_id, stock_quantity, created_at, updated_at)
+    VALUES (:id, :name, :brand, :description, :price, :category_id, :stock_quantity, :created_at, :updated_at)
 `
 
-	// execute query
-	if err := database.NamedExecQuery(ctx, productsDatabase, query, product); err != nil {
-		return Product{}, fmt.Errorf("inserting product: %w", err)
+	// insert the product and record its first price
+	if err := database.Transaction(ctx, productsDatabase(), func(tx *sqlx.Tx) error {
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, query, product); err != nil {
+			return fmt.Errorf("inserting product: %w", err)
+		}
+		if err := pl.Record(ctx, tx, product.Id, product.Price, ""); err != nil {
+			return err
+		}
+		if err := inventory.Open(ctx, tx, product.Id, product.StockQuantity, product.CreatedAt); err != nil {
+			return err
+		}
+
+		// let other services know
+		event := &events.ProductCreated{
+			Meta:          events.NewMeta(events.TypeProductCreated, product.CreatedAt),
+			ProductId:     product.Id,
+			Name:          product.Name,
+			Brand:         product.Brand,
+			CategoryId:    product.CategoryId,
+			Price:         product.Price,
+			StockQuantity: product.StockQuantity,
+		}
+		return outbox.Add(ctx, tx, event.Meta, event)
+	}); err != nil {
+		return Product{}, err
 	}
 
 	// query data from database
@@ -89,3 +126,34 @@ func Create(ctx context.Context, payload *ProductRequest) (Product, error) {
 
 	return p, nil
 }
+
+// GetMany - GetMany is a function that gets many products.
+//
+// @param ctx - context.Context
+// @param ids - []string
+// @return products
+// @return error
+func GetMany(ctx context.Context, ids []string) ([]Product, error) {
+	products := make([]Product, 0, len(ids))
+
+	// query statement to be executed
+	q := "SELECT * FROM products WHERE id = ANY(CAST(:ids AS UUID[]))"
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, productsDatabase(), q, map[string]interface{}{"ids": ids}, &products); err != nil {
+		return nil, fmt.Errorf("selecting products: %w", err)
+	}
+
+	return products, nil
+}
+
+// RelayEvents - RelayEvents is a function that publishes the events saved in the outbox of the products.
+//
+// @param ctx - context.Context
+// @param publish - outbox.Publisher
+// @param now - time.Time
+// @return response
+// @return error
+func RelayEvents(ctx context.Context, publish outbox.Publisher, now time.Time) (*outbox.RelayResponse, error) {
+	return outbox.Relay(ctx, productsDatabase(), publish, now)
+}
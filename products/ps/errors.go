@@ -3,5 +3,7 @@ package ps
 import "errors"
 
 var (
-	ErrNotFound = errors.New("product not found")
+	ErrNotFound     = errors.New("product not found")
+	ErrInvalidPrice = errors.New("price must be greater than zero and in the catalog currency")
+	ErrInvalidRange = errors.New("invalid price range")
 )
@@ -1,22 +1,88 @@
 package ps
 
-import "time"
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
 
 type Product struct {
-	Id          string    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Brand       string    `json:"brand" db:"brand"`
-	Description string    `json:"description" db:"description"`
-	Price       float64   `json:"price" db:"price"`
-	CategoryId  string    `json:"categoryId" db:"category_id"`
-	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
+	Id            string      `json:"id" db:"id"`
+	Name          string      `json:"name" db:"name"`
+	Brand         string      `json:"brand" db:"brand"`
+	Description   string      `json:"description" db:"description"`
+	Price         money.Money `json:"price" db:"price"`
+	CategoryId    string      `json:"categoryId" db:"category_id"`
+	StockQuantity int         `json:"stockQuantity" db:"stock_quantity"`
+	RatingAverage float64     `json:"ratingAverage" db:"rating_average"` // the average rating of the approved reviews
+	RatingCount   int         `json:"ratingCount" db:"rating_count"`     // the number of approved reviews
+	CreatedAt     time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updatedAt" db:"updated_at"`
 }
 
 type ProductRequest struct {
-	Name          string  `json:"name" validate:"required"`
-	Description   string  `json:"description"  validate:"required"`
-	Price         float64 `json:"price" validate:"required,min=0"`
-	CategoryId    string  `json:"categoryId"  validate:"omitempty"`
-	StockQuantity int     `json:"stockQuantity" validate:"required,min=0"`
+	Name          string      `json:"name" validate:"required"`
+	Brand         string      `json:"brand" validate:"omitempty"`
+	Description   string      `json:"description"  validate:"required"`
+	Price         money.Money `json:"price"` // in the catalog currency, other currencies are set as currency prices
+	CategoryId    string      `json:"categoryId"  validate:"omitempty"`
+	StockQuantity int         `json:"stockQuantity" validate:"required,min=0"`
+}
+
+type SearchQuery struct {
+	Q        string `json:"q" query:"q" validate:"omitempty,max=200"`                                                     // the search terms
+	Category string `json:"category" query:"category" validate:"omitempty,uuid"`                                          // the category id
+	Brand    string `json:"brand" query:"brand" validate:"omitempty"`                                                     // the brand
+	MinPrice string `json:"minPrice" query:"minPrice" validate:"omitempty,numeric"`                                       // the lowest price in the default currency, e.g. 2.50
+	MaxPrice string `json:"maxPrice" query:"maxPrice" validate:"omitempty,numeric"`                                       // the highest price in the default currency
+	Currency string `json:"currency" query:"currency" validate:"omitempty,len=3"`                                         // the currency of the prices in the results
+	InStock  bool   `json:"inStock" query:"inStock"`                                                                      // only products with stock
+	Sort     string `json:"sort" query:"sort" validate:"omitempty,oneof=relevance price -price name -name newest rating"` // how the results are ordered
+	Limit    int    `json:"limit" query:"limit" validate:"omitempty,min=0"`                                               // the number of items
+	Page     int    `json:"page" query:"page" validate:"omitempty,min=0"`                                                 // the page
+}
+
+type SearchResult struct {
+	Product
+	CategoryName string  `json:"categoryName" db:"category_name"`
+	Rank         float64 `json:"rank" db:"rank"`
+}
+
+type Facet struct {
+	Value string `json:"value" db:"value"`
+	Label string `json:"label" db:"label"`
+	Count int    `json:"count" db:"count"`
+}
+
+type SearchFacets struct {
+	Brands     []Facet `json:"brands"`
+	Categories []Facet `json:"categories"`
+}
+
+type PaginatedSearchResponse struct {
+	Products        []SearchResult `json:"data"`
+	Facets          SearchFacets   `json:"facets"`
+	Total           int            `json:"total" db:"total"`
+	TotalPages      int            `json:"totalPages" db:"total_pages"`
+	CurrentPage     int            `json:"currentPage" db:"current_page"`
+	HasPreviousPage bool           `json:"hasPreviousPage" db:"hasPreviousPage"`
+	HasNextPage     bool           `json:"hasNextPage" db:"hasNextPage"`
+}
+
+type SuggestQuery struct {
+	Q     string `json:"q" query:"q" validate:"omitempty,max=100"`              // the text typed so far
+	Limit int    `json:"limit" query:"limit" validate:"omitempty,min=0,max=20"` // the number of suggestions per group
+}
+
+type Suggestion struct {
+	Id         string `json:"id" db:"id"`
+	Text       string `json:"text" db:"text"`
+	Popularity int64  `json:"popularity" db:"popularity"`
+}
+
+type SuggestResponse struct {
+	Query      string       `json:"query"`
+	Products   []Suggestion `json:"products"`
+	Brands     []Suggestion `json:"brands"`
+	Categories []Suggestion `json:"categories"`
 }
@@ -0,0 +1,201 @@
+package ps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/money"
+	"encore.app/pkg/pagination"
+	"encore.app/pkg/search"
+)
+
+// searchOrders - maps the sort values accepted by Search to their order clauses
+var searchOrders = map[string]string{
+	"relevance": "rank DESC, p.name ASC",
+	"price":     "(p.price).amount ASC, p.name ASC",
+	"-price":    "(p.price).amount DESC, p.name ASC",
+	"name":      "p.name ASC",
+	"-name":     "p.name DESC",
+	"newest":    "p.created_at DESC",
+	"rating":    "p.rating_average DESC, p.rating_count DESC, p.name ASC",
+}
+
+// Search - Search is a function that searches products with full-text search, filters and facets.
+// Every term of the query is matched as a prefix and names close to the query are matched
+// with trigram similarity so small typos still return results.
+//
+// @param ctx - context.Context
+// @param params - *SearchQuery
+// @return results
+// @return error
+func Search(ctx context.Context, params *SearchQuery) (*PaginatedSearchResponse, error) {
+	results := make([]SearchResult, 0)
+
+	// conditions and data for the query
+	var conditions []string
+	data := map[string]interface{}{}
+
+	// match the search terms
+	rank := "0"
+	if tsquery := search.PrefixQuery(params.Q); len(tsquery) > 0 {
+		conditions = append(conditions, "(d.document @@ to_tsquery('simple', :tsquery) OR :q <% p.name)")
+		rank = "ts_rank(d.document, to_tsquery('simple', :tsquery)) + word_similarity(:q, p.name)"
+		data["tsquery"] = tsquery
+		data["q"] = strings.ToLower(strings.TrimSpace(params.Q))
+	}
+
+	// filter by category
+	if len(params.Category) > 0 {
+		conditions = append(conditions, "p.category_id = :category_id")
+		data["category_id"] = params.Category
+	}
+
+	// filter by brand
+	if brand := strings.TrimSpace(params.Brand); len(brand) > 0 {
+		conditions = append(conditions, "LOWER(p.brand) = LOWER(:brand)")
+		data["brand"] = brand
+	}
+
+	// filter by price range
+	minPrice, maxPrice, err := params.PriceRange()
+	if err != nil {
+		return nil, err
+	}
+	if minPrice != nil {
+		conditions = append(conditions, "(p.price).amount >= :min_price")
+		data["min_price"] = minPrice.Amount
+	}
+	if maxPrice != nil {
+		conditions = append(conditions, "(p.price).amount <= :max_price")
+		data["max_price"] = maxPrice.Amount
+	}
+
+	// filter by stock
+	if params.InStock {
+		conditions = append(conditions, "p.stock_quantity > 0")
+	}
+
+	// create the where clause
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// resolve the order of the results
+	sort := params.Sort
+	if len(sort) < 1 {
+		sort = "relevance"
+	}
+	order, ok := searchOrders[sort]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort value[%v]", params.Sort)
+	}
+
+	// tables the search runs over
+	const from = `
+    FROM products p
+    LEFT JOIN product_search_documents d ON d.product_id = p.id
+    LEFT JOIN categories c ON c.id = p.category_id
+  `
+
+	// get count of products
+	count, err := database.NamedCountQuery(ctx, productsDatabase(), fmt.Sprintf("SELECT COUNT(*) %v %v", from, where), data)
+	if err != nil {
+		return nil, fmt.Errorf("getting count of products: %w", err)
+	}
+
+	// set limit to 20 if it is less than 1 or greater than 100
+	if params.Limit < 1 || params.Limit > 100 {
+		params.Limit = 20
+	}
+
+	// initialize pagination
+	paging := pagination.New(params.Page, params.Limit, count)
+
+	// query to search, order and paginate products
+	query := fmt.Sprintf(`
+    SELECT p.*, COALESCE(c.name, '') AS category_name, %v AS rank
+    %v %v
+    ORDER BY %v, p.id
+    LIMIT :limit OFFSET :offset
+  `, rank, from, where, order)
+	// data to be passed to the query
+	data["limit"] = paging.PerPage()
+	data["offset"] = paging.Offset()
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, productsDatabase(), query, data, &results); err != nil {
+		return nil, fmt.Errorf("searching products: %w", err)
+	}
+
+	// count the matching products per brand
+	brands := make([]Facet, 0)
+	brandsQuery := fmt.Sprintf(`
+    SELECT p.brand AS value, p.brand AS label, COUNT(*) AS count
+    %v %v
+    GROUP BY p.brand
+    ORDER BY count DESC, value
+  `, from, where)
+	if err := database.NamedSliceQuery(ctx, productsDatabase(), brandsQuery, data, &brands); err != nil {
+		return nil, fmt.Errorf("getting brand facets: %w", err)
+	}
+
+	// count the matching products per category
+	categories := make([]Facet, 0)
+	categoriesQuery := fmt.Sprintf(`
+    SELECT CAST(p.category_id AS TEXT) AS value, COALESCE(c.name, '') AS label, COUNT(*) AS count
+    %v %v
+    GROUP BY p.category_id, c.name
+    ORDER BY count DESC, label
+  `, from, where)
+	if err := database.NamedSliceQuery(ctx, productsDatabase(), categoriesQuery, data, &categories); err != nil {
+		return nil, fmt.Errorf("getting category facets: %w", err)
+	}
+
+	return &PaginatedSearchResponse{
+		Products: results,
+		Facets: SearchFacets{
+			Brands:     brands,
+			Categories: categories,
+		},
+		TotalPages:      paging.Pages(),
+		Total:           paging.Total(),
+		CurrentPage:     paging.Page(),
+		HasPreviousPage: paging.HasPrevious(),
+		HasNextPage:     paging.HasNext(),
+	}, nil
+}
+
+// PriceRange - PriceRange parses the price range of the query in the default currency.
+// Bounds that are not given are nil.
+//
+// @return minPrice
+// @return maxPrice
+// @return error
+func (params *SearchQuery) PriceRange() (*money.Money, *money.Money, error) {
+	var minPrice, maxPrice *money.Money
+
+	if len(params.MinPrice) > 0 {
+		price, err := money.Parse(params.MinPrice, money.DefaultCurrency)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: minPrice: %v", ErrInvalidRange, err)
+		}
+		minPrice = &price
+	}
+	if len(params.MaxPrice) > 0 {
+		price, err := money.Parse(params.MaxPrice, money.DefaultCurrency)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: maxPrice: %v", ErrInvalidRange, err)
+		}
+		maxPrice = &price
+	}
+
+	// the range cannot be empty
+	if minPrice != nil && maxPrice != nil && minPrice.Amount > maxPrice.Amount {
+		return nil, nil, fmt.Errorf("%w: minPrice cannot be greater than maxPrice", ErrInvalidRange)
+	}
+
+	return minPrice, maxPrice, nil
+}
@@ -0,0 +1,127 @@
+package ps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"encore.app/pkg/cache"
+	"encore.app/pkg/database"
+	"encore.app/pkg/search"
+)
+
+// suggestionsCache - keeps recent suggestions in memory so repeated keystrokes skip the database
+var suggestionsCache = cache.New[string, *SuggestResponse](time.Minute, 5000)
+
+// MinSuggestLength - the shortest query that returns suggestions
+const MinSuggestLength = 2
+
+// Suggest - Suggest is a function that returns product names, brands and categories matching what has been typed.
+// Values starting with the query are ranked first, followed by popularity and trigram similarity.
+//
+// @param ctx - context.Context
+// @param params - *SuggestQuery
+// @return suggestions
+// @return error
+func Suggest(ctx context.Context, params *SuggestQuery) (*SuggestResponse, error) {
+	q := strings.ToLower(strings.Join(strings.Fields(params.Q), " "))
+
+	// set limit to 5 if it is less than 1
+	if params.Limit < 1 {
+		params.Limit = 5
+	}
+
+	response := &SuggestResponse{
+		Query:      q,
+		Products:   make([]Suggestion, 0),
+		Brands:     make([]Suggestion, 0),
+		Categories: make([]Suggestion, 0),
+	}
+
+	// return no suggestions for short queries
+	if len([]rune(q)) < MinSuggestLength {
+		return response, nil
+	}
+
+	// check the cache
+	key := fmt.Sprintf("%v:%v", params.Limit, q)
+	if cached, ok := suggestionsCache.Get(key); ok {
+		return cached, nil
+	}
+
+	// data to be passed to the queries
+	data := map[string]interface{}{
+		"q":      q,
+		"prefix": search.LikePrefix(q),
+		"limit":  params.Limit,
+	}
+
+	// query matching product names
+	productsQuery := `
+    SELECT CAST(p.id AS TEXT) AS id, p.name AS text, COALESCE(pp.score, 0) AS popularity
+    FROM products p
+    LEFT JOIN product_popularity pp ON pp.product_id = p.id
+    WHERE LOWER(p.name) LIKE :prefix OR :q <% p.name
+    ORDER BY LOWER(p.name) LIKE :prefix DESC, popularity DESC, word_similarity(:q, p.name) DESC, p.name
+    LIMIT :limit
+  `
+	if err := database.NamedSliceQuery(ctx, productsDatabase(), productsQuery, data, &response.Products); err != nil {
+		return nil, fmt.Errorf("suggesting products: %w", err)
+	}
+
+	// query matching brands
+	brandsQuery := `
+    SELECT p.brand AS id, p.brand AS text, CAST(COALESCE(SUM(pp.score), 0) AS BIGINT) AS popularity
+    FROM products p
+    LEFT JOIN product_popularity pp ON pp.product_id = p.id
+    WHERE p.brand <> '' AND (LOWER(p.brand) LIKE :prefix OR :q <% p.brand)
+    GROUP BY p.brand
+    ORDER BY LOWER(p.brand) LIKE :prefix DESC, popularity DESC, COUNT(*) DESC, p.brand
+    LIMIT :limit
+  `
+	if err := database.NamedSliceQuery(ctx, productsDatabase(), brandsQuery, data, &response.Brands); err != nil {
+		return nil, fmt.Errorf("suggesting brands: %w", err)
+	}
+
+	// query matching categories
+	categoriesQuery := `
+    SELECT CAST(c.id AS TEXT) AS id, c.name AS text, CAST(COALESCE(SUM(pp.score), 0) AS BIGINT) AS popularity
+    FROM categories c
+    LEFT JOIN products p ON p.category_id = c.id
+    LEFT JOIN product_popularity pp ON pp.product_id = p.id
+    WHERE LOWER(c.name) LIKE :prefix OR :q <% c.name
+    GROUP BY c.id, c.name
+    ORDER BY LOWER(c.name) LIKE :prefix DESC, popularity DESC, COUNT(p.id) DESC, c.name
+    LIMIT :limit
+  `
+	if err := database.NamedSliceQuery(ctx, productsDatabase(), categoriesQuery, data, &response.Categories); err != nil {
+		return nil, fmt.Errorf("suggesting categories: %w", err)
+	}
+
+	// cache the suggestions
+	suggestionsCache.Set(key, response)
+
+	return response, nil
+}
+
+// RecordView - RecordView is a function that increases the popularity of a product.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return error
+func RecordView(ctx context.Context, id string) error {
+	// query statement to be executed
+	q := `
+    INSERT INTO product_popularity (product_id, score, updated_at)
+    VALUES (:id, 1, NOW())
+    ON CONFLICT (product_id) DO UPDATE SET score = product_popularity.score + 1, updated_at = NOW()
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, productsDatabase(), q, map[string]interface{}{"id": id}); err != nil {
+		return fmt.Errorf("recording product view: %w", err)
+	}
+
+	return nil
+}
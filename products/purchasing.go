@@ -0,0 +1,630 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/products/fx"
+	"encore.app/products/purchasing"
+)
+
+// =====================================================================================================================
+// SUPPLIERS
+// =====================================================================================================================
+
+// ListSuppliers - List the suppliers by name
+//
+//	@param ctx - context.Context
+//	@return suppliers
+//	@return error
+//
+// encore:api auth method=GET path=/suppliers
+func ListSuppliers(ctx context.Context) (*purchasing.SuppliersResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.SuppliersResponse{}, err
+	}
+
+	// get the suppliers
+	suppliers, err := purchasing.ListSuppliers(ctx)
+	if err != nil {
+		return &purchasing.SuppliersResponse{}, err
+	}
+
+	return &purchasing.SuppliersResponse{Suppliers: suppliers}, nil
+}
+
+// GetSupplier - Get a supplier with its contacts and the products it supplies
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return supplier
+//	@return error
+//
+// encore:api auth method=GET path=/suppliers/:id
+func GetSupplier(ctx context.Context, id string) (*purchasing.SupplierResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.SupplierResponse{}, err
+	}
+
+	// get the supplier
+	supplier, err := purchasing.GetSupplier(ctx, id)
+	if err != nil {
+		return &purchasing.SupplierResponse{}, purchasingError(err)
+	}
+
+	return supplier, nil
+}
+
+// CreateSupplier - Create a supplier
+//
+//	@param ctx - context.Context
+//	@param payload - *purchasing.SupplierRequest
+//	@return supplier
+//	@return error
+//
+// encore:api auth method=POST path=/suppliers
+func CreateSupplier(ctx context.Context, payload *purchasing.SupplierRequest) (*purchasing.Supplier, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.Supplier{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.Supplier{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the supplier
+	supplier, err := purchasing.CreateSupplier(ctx, payload)
+	if err != nil {
+		return &purchasing.Supplier{}, purchasingError(err)
+	}
+
+	return supplier, nil
+}
+
+// UpdateSupplier - Update a supplier, deactivate it to stop ordering from it
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *purchasing.SupplierRequest
+//	@return supplier
+//	@return error
+//
+// encore:api auth method=PUT path=/suppliers/:id
+func UpdateSupplier(ctx context.Context, id string, payload *purchasing.SupplierRequest) (*purchasing.Supplier, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.Supplier{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.Supplier{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// update the supplier
+	supplier, err := purchasing.UpdateSupplier(ctx, id, payload)
+	if err != nil {
+		return &purchasing.Supplier{}, purchasingError(err)
+	}
+
+	return supplier, nil
+}
+
+// DeleteSupplier - Delete a supplier without purchase orders
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/suppliers/:id
+func DeleteSupplier(ctx context.Context, id string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// delete the supplier
+	if err := purchasing.DeleteSupplier(ctx, id); err != nil {
+		return purchasingError(err)
+	}
+
+	return nil
+}
+
+// AddSupplierContact - Add a contact to a supplier
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *purchasing.ContactRequest
+//	@return contact
+//	@return error
+//
+// encore:api auth method=POST path=/suppliers/:id/contacts
+func AddSupplierContact(ctx context.Context, id string, payload *purchasing.ContactRequest) (*purchasing.Contact, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.Contact{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.Contact{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// add the contact
+	contact, err := purchasing.AddContact(ctx, id, payload)
+	if err != nil {
+		return &purchasing.Contact{}, purchasingError(err)
+	}
+
+	return contact, nil
+}
+
+// RemoveSupplierContact - Remove a contact from a supplier
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param contactId - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/suppliers/:id/contacts/:contactId
+func RemoveSupplierContact(ctx context.Context, id, contactId string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// remove the contact
+	if err := purchasing.RemoveContact(ctx, id, contactId); err != nil {
+		return purchasingError(err)
+	}
+
+	return nil
+}
+
+// SetSupplierProduct - Add a product a supplier sells us or change what it costs
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param productId - string
+//	@param payload - *purchasing.SupplierProductRequest
+//	@return supplier product
+//	@return error
+//
+// encore:api auth method=PUT path=/suppliers/:id/products/:productId
+func SetSupplierProduct(ctx context.Context, id, productId string, payload *purchasing.SupplierProductRequest) (*purchasing.SupplierProduct, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.SupplierProduct{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.SupplierProduct{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// set the product
+	product, err := purchasing.SetSupplierProduct(ctx, id, productId, payload)
+	if err != nil {
+		return &purchasing.SupplierProduct{}, purchasingError(err)
+	}
+
+	return product, nil
+}
+
+// RemoveSupplierProduct - Remove a product from those a supplier sells us
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param productId - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/suppliers/:id/products/:productId
+func RemoveSupplierProduct(ctx context.Context, id, productId string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// remove the product
+	if err := purchasing.RemoveSupplierProduct(ctx, id, productId); err != nil {
+		return purchasingError(err)
+	}
+
+	return nil
+}
+
+// =====================================================================================================================
+// PURCHASE ORDERS
+// =====================================================================================================================
+
+// ListPurchaseOrders - List purchase orders, the newest first
+//
+//	@param ctx - context.Context
+//	@param params - *purchasing.OrdersQuery
+//	@return orders
+//	@return error
+//
+// encore:api auth method=GET path=/purchase-orders
+func ListPurchaseOrders(ctx context.Context, params *purchasing.OrdersQuery) (*purchasing.PaginatedOrdersResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.PaginatedOrdersResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &purchasing.PaginatedOrdersResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the orders
+	response, err := purchasing.ListOrders(ctx, params)
+	if err != nil {
+		return &purchasing.PaginatedOrdersResponse{}, purchasingError(err)
+	}
+
+	return response, nil
+}
+
+// GetPurchaseOrder - Get a purchase order with its lines and receipts
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return order
+//	@return error
+//
+// encore:api auth method=GET path=/purchase-orders/:id
+func GetPurchaseOrder(ctx context.Context, id string) (*purchasing.OrderResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.OrderResponse{}, err
+	}
+
+	// get the order
+	order, err := purchasing.GetOrder(ctx, id)
+	if err != nil {
+		return &purchasing.OrderResponse{}, purchasingError(err)
+	}
+
+	return order, nil
+}
+
+// CreatePurchaseOrder - Draft a purchase order for a supplier
+//
+//	@param ctx - context.Context
+//	@param payload - *purchasing.OrderRequest
+//	@return order
+//	@return error
+//
+// encore:api auth method=POST path=/purchase-orders
+func CreatePurchaseOrder(ctx context.Context, payload *purchasing.OrderRequest) (*purchasing.OrderResponse, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &purchasing.OrderResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.OrderResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// draft the order
+	order, err := purchasing.CreateOrder(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &purchasing.OrderResponse{}, purchasingError(err)
+	}
+
+	return order, nil
+}
+
+// UpdatePurchaseOrder - Change a draft purchase order, its lines are replaced
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *purchasing.OrderRequest
+//	@return order
+//	@return error
+//
+// encore:api auth method=PUT path=/purchase-orders/:id
+func UpdatePurchaseOrder(ctx context.Context, id string, payload *purchasing.OrderRequest) (*purchasing.OrderResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.OrderResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.OrderResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// update the order
+	order, err := purchasing.UpdateOrder(ctx, id, payload)
+	if err != nil {
+		return &purchasing.OrderResponse{}, purchasingError(err)
+	}
+
+	return order, nil
+}
+
+// ActOnPurchaseOrder - Send a draft purchase order to the supplier, or cancel it before anything arrives
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *purchasing.ActionRequest
+//	@return order
+//	@return error
+//
+// encore:api auth method=POST path=/purchase-orders/:id/actions
+func ActOnPurchaseOrder(ctx context.Context, id string, payload *purchasing.ActionRequest) (*purchasing.OrderResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.OrderResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.OrderResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// act on the order
+	order, err := purchasing.Act(ctx, id, payload.Action, time.Now())
+	if err != nil {
+		return &purchasing.OrderResponse{}, purchasingError(err)
+	}
+
+	return order, nil
+}
+
+// ReceivePurchaseOrder - Take a delivery for a sent purchase order into stock, with what the units actually cost
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *purchasing.ReceiveRequest
+//	@return order
+//	@return error
+//
+// encore:api auth method=POST path=/purchase-orders/:id/receive
+func ReceivePurchaseOrder(ctx context.Context, id string, payload *purchasing.ReceiveRequest) (*purchasing.OrderResponse, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &purchasing.OrderResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.OrderResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// receive the delivery
+	order, err := purchasing.Receive(ctx, claims.Subject.Id, id, payload)
+	if err != nil {
+		return &purchasing.OrderResponse{}, purchasingError(err)
+	}
+
+	return order, nil
+}
+
+// ListMargins - List products with their price against the average cost of the stock on hand
+//
+//	@param ctx - context.Context
+//	@param params - *purchasing.MarginsQuery
+//	@return margins
+//	@return error
+//
+// encore:api auth method=GET path=/purchasing/margins
+func ListMargins(ctx context.Context, params *purchasing.MarginsQuery) (*purchasing.PaginatedMarginsResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.PaginatedMarginsResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &purchasing.PaginatedMarginsResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the margins
+	response, err := purchasing.Margins(ctx, params)
+	if err != nil {
+		return &purchasing.PaginatedMarginsResponse{}, purchasingError(err)
+	}
+
+	return response, nil
+}
+
+// =====================================================================================================================
+// REORDERING
+// =====================================================================================================================
+
+// work out how fast products sell every night
+var _ = cron.NewJob("compute-sales-velocity", cron.JobConfig{
+	Title:    "Compute the sales velocity of products",
+	Every:    24 * cron.Hour,
+	Endpoint: ComputeSalesVelocity,
+})
+
+// GetReorderSetting - Get when a product is reordered
+//
+//	@param ctx - context.Context
+//	@param productId - string
+//	@return setting
+//	@return error
+//
+// encore:api auth method=GET path=/inventory/:productId/reorder
+func GetReorderSetting(ctx context.Context, productId string) (*purchasing.ReorderSetting, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.ReorderSetting{}, err
+	}
+
+	// get the setting
+	setting, err := purchasing.GetReorderSetting(ctx, productId)
+	if err != nil {
+		return &purchasing.ReorderSetting{}, purchasingError(err)
+	}
+
+	return setting, nil
+}
+
+// SetReorderSetting - Set the reorder point and target stock of a product, and optionally its preferred supplier
+//
+//	@param ctx - context.Context
+//	@param productId - string
+//	@param payload - *purchasing.ReorderSettingRequest
+//	@return setting
+//	@return error
+//
+// encore:api auth method=PUT path=/inventory/:productId/reorder
+func SetReorderSetting(ctx context.Context, productId string, payload *purchasing.ReorderSettingRequest) (*purchasing.ReorderSetting, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.ReorderSetting{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.ReorderSetting{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// set the setting
+	setting, err := purchasing.SetReorderSetting(ctx, productId, payload)
+	if err != nil {
+		return &purchasing.ReorderSetting{}, purchasingError(err)
+	}
+
+	return setting, nil
+}
+
+// RemoveReorderSetting - Stop suggesting to reorder a product
+//
+//	@param ctx - context.Context
+//	@param productId - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/inventory/:productId/reorder
+func RemoveReorderSetting(ctx context.Context, productId string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// remove the setting
+	if err := purchasing.RemoveReorderSetting(ctx, productId); err != nil {
+		return purchasingError(err)
+	}
+
+	return nil
+}
+
+// ListReorderSuggestions - List the units to order per supplier to get products back to their target stock
+//
+//	@param ctx - context.Context
+//	@param params - *purchasing.SuggestionsQuery
+//	@return suggestions
+//	@return error
+//
+// encore:api auth method=GET path=/purchasing/reorder
+func ListReorderSuggestions(ctx context.Context, params *purchasing.SuggestionsQuery) (*purchasing.SuggestionsResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &purchasing.SuggestionsResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &purchasing.SuggestionsResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the suggestions
+	response, err := purchasing.Suggest(ctx, params)
+	if err != nil {
+		return &purchasing.SuggestionsResponse{}, err
+	}
+
+	return response, nil
+}
+
+// DraftReorders - Draft purchase orders from the reorder suggestions, for one supplier or every supplier
+//
+//	@param ctx - context.Context
+//	@param payload - *purchasing.DraftRequest
+//	@return orders
+//	@return error
+//
+// encore:api auth method=POST path=/purchasing/reorder/draft
+func DraftReorders(ctx context.Context, payload *purchasing.DraftRequest) (*purchasing.DraftResponse, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &purchasing.DraftResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &purchasing.DraftResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// draft the orders
+	response, err := purchasing.DraftReorders(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &purchasing.DraftResponse{}, purchasingError(err)
+	}
+
+	return response, nil
+}
+
+// ComputeSalesVelocity - Work out the units of every product sold a day over the last weeks
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/purchasing/velocity
+func ComputeSalesVelocity(ctx context.Context) (*purchasing.VelocityResponse, error) {
+	// compute the velocities
+	products, err := purchasing.ComputeVelocity(ctx, time.Now())
+	if err != nil {
+		return &purchasing.VelocityResponse{}, err
+	}
+
+	rlog.Info("products.ComputeSalesVelocity", "products", products)
+
+	return &purchasing.VelocityResponse{Products: products}, nil
+}
+
+// purchasingError - maps purchasing store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func purchasingError(err error) error {
+	switch {
+	case errors.Is(err, purchasing.ErrSupplierNotFound), errors.Is(err, purchasing.ErrContactNotFound),
+		errors.Is(err, purchasing.ErrProductNotFound), errors.Is(err, purchasing.ErrSupplierProductNotFound),
+		errors.Is(err, purchasing.ErrOrderNotFound), errors.Is(err, purchasing.ErrReorderSettingNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, purchasing.ErrAlreadyExists):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, purchasing.ErrInvalidOrder), errors.Is(err, purchasing.ErrInvalidReceipt),
+		errors.Is(err, purchasing.ErrInvalidCurrency), errors.Is(err, purchasing.ErrCurrencyMismatch):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, purchasing.ErrSupplierInUse), errors.Is(err, purchasing.ErrInactiveSupplier),
+		errors.Is(err, purchasing.ErrInvalidTransition), errors.Is(err, purchasing.ErrNotEditable),
+		errors.Is(err, purchasing.ErrOverReceipt), errors.Is(err, fx.ErrNoRate):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	default:
+		return inventoryError(err)
+	}
+}
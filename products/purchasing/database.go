@@ -0,0 +1,1367 @@
+package purchasing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/money"
+	"encore.app/pkg/pagination"
+	"encore.app/products/fx"
+	"encore.app/products/inventory"
+)
+
+// the products database
+var purchasingDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// =====================================================================================================================
+// SUPPLIERS
+// =====================================================================================================================
+
+// getSupplier - gets a supplier, locking it when asked.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param id - string
+// @param lock - lock the supplier until the transaction ends
+// @return supplier
+// @return error
+func getSupplier(ctx context.Context, db sqlx.ExtContext, id string, lock bool) (*Supplier, error) {
+	q := "SELECT * FROM suppliers WHERE id = :id"
+	if lock {
+		q += " FOR UPDATE"
+	}
+
+	supplier := &Supplier{}
+	if err := database.NamedStructQuery(ctx, db, q, map[string]interface{}{"id": id}, supplier); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		return nil, fmt.Errorf("selecting supplier: %w", err)
+	}
+
+	return supplier, nil
+}
+
+// ListSuppliers - ListSuppliers is a function that lists the suppliers by name.
+//
+// @param ctx - context.Context
+// @return suppliers
+// @return error
+func ListSuppliers(ctx context.Context) ([]Supplier, error) {
+	suppliers := make([]Supplier, 0)
+	if err := database.NamedSliceQuery(ctx, purchasingDatabase(), "SELECT * FROM suppliers ORDER BY name", map[string]interface{}{}, &suppliers); err != nil {
+		return nil, fmt.Errorf("selecting suppliers: %w", err)
+	}
+
+	return suppliers, nil
+}
+
+// GetSupplier - GetSupplier is a function that gets a supplier with its contacts and the products it supplies.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return supplier
+// @return error
+func GetSupplier(ctx context.Context, id string) (*SupplierResponse, error) {
+	supplier, err := getSupplier(ctx, purchasingDatabase(), id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SupplierResponse{Supplier: supplier, Contacts: make([]Contact, 0), Products: make([]SupplierProduct, 0)}
+	data := map[string]interface{}{"id": id}
+	if err := database.NamedSliceQuery(ctx, purchasingDatabase(), "SELECT * FROM supplier_contacts WHERE supplier_id = :id ORDER BY is_primary DESC, name, id", data, &response.Contacts); err != nil {
+		return nil, fmt.Errorf("selecting supplier contacts: %w", err)
+	}
+	if err := database.NamedSliceQuery(ctx, purchasingDatabase(), "SELECT * FROM supplier_products WHERE supplier_id = :id ORDER BY created_at, product_id", data, &response.Products); err != nil {
+		return nil, fmt.Errorf("selecting supplier products: %w", err)
+	}
+
+	return response, nil
+}
+
+// CreateSupplier - CreateSupplier is a function that creates a supplier.
+//
+// @param ctx - context.Context
+// @param payload - *SupplierRequest
+// @return supplier
+// @return error
+func CreateSupplier(ctx context.Context, payload *SupplierRequest) (*Supplier, error) {
+	if !money.IsCurrency(payload.Currency) {
+		return nil, fmt.Errorf("%w[%v]", ErrInvalidCurrency, payload.Currency)
+	}
+
+	now := time.Now().UTC()
+	supplier := &Supplier{
+		Id:           uuid.New().String(),
+		Name:         payload.Name,
+		Email:        payload.Email,
+		Phone:        payload.Phone,
+		Address:      payload.Address,
+		LeadTimeDays: payload.LeadTimeDays,
+		Currency:     payload.Currency,
+		Notes:        payload.Notes,
+		Active:       payload.Active == nil || *payload.Active,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO suppliers (id, name, email, phone, address, lead_time_days, currency, notes, active, created_at, updated_at)
+    VALUES (:id, :name, :email, :phone, :address, :lead_time_days, :currency, :notes, :active, :created_at, :updated_at)
+    ON CONFLICT (name) DO NOTHING
+    RETURNING *
+  `
+
+	// execute query
+	if err := database.NamedStructQuery(ctx, purchasingDatabase(), q, supplier, supplier); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, fmt.Errorf("%w: a supplier is named %v", ErrAlreadyExists, payload.Name)
+		}
+		return nil, fmt.Errorf("inserting supplier: %w", err)
+	}
+
+	return supplier, nil
+}
+
+// UpdateSupplier - UpdateSupplier is a function that updates a supplier. The currency cannot change once the
+// supplier has purchase orders or costs in it.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param payload - *SupplierRequest
+// @return supplier
+// @return error
+func UpdateSupplier(ctx context.Context, id string, payload *SupplierRequest) (*Supplier, error) {
+	if !money.IsCurrency(payload.Currency) {
+		return nil, fmt.Errorf("%w[%v]", ErrInvalidCurrency, payload.Currency)
+	}
+
+	var supplier *Supplier
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		var err error
+		if supplier, err = getSupplier(ctx, tx, id, true); err != nil {
+			return err
+		}
+
+		// keep the currency of orders and costs
+		data := map[string]interface{}{"id": id}
+		if payload.Currency != supplier.Currency {
+			count, err := database.NamedCountQuery(ctx, tx, `
+        SELECT (SELECT COUNT(*) FROM purchase_orders WHERE supplier_id = :id) +
+               (SELECT COUNT(*) FROM supplier_products WHERE supplier_id = :id)
+      `, data)
+			if err != nil {
+				return fmt.Errorf("counting supplier orders and products: %w", err)
+			}
+			if count > 0 {
+				return fmt.Errorf("%w: the supplier has orders or products in %v", ErrCurrencyMismatch, supplier.Currency)
+			}
+		}
+
+		// check the name is free
+		data["name"] = payload.Name
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM suppliers WHERE name = :name AND id <> :id", data)
+		if err != nil {
+			return fmt.Errorf("counting suppliers: %w", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: a supplier is named %v", ErrAlreadyExists, payload.Name)
+		}
+
+		supplier.Name = payload.Name
+		supplier.Email = payload.Email
+		supplier.Phone = payload.Phone
+		supplier.Address = payload.Address
+		supplier.LeadTimeDays = payload.LeadTimeDays
+		supplier.Currency = payload.Currency
+		supplier.Notes = payload.Notes
+		if payload.Active != nil {
+			supplier.Active = *payload.Active
+		}
+		supplier.UpdatedAt = time.Now().UTC()
+
+		// query statement to be executed
+		q := `
+      UPDATE suppliers
+      SET name = :name, email = :email, phone = :phone, address = :address, lead_time_days = :lead_time_days,
+          currency = :currency, notes = :notes, active = :active, updated_at = :updated_at
+      WHERE id = :id
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, supplier); err != nil {
+			return fmt.Errorf("updating supplier: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return supplier, nil
+}
+
+// DeleteSupplier - DeleteSupplier is a function that deletes a supplier with its contacts and products. A supplier
+// with purchase orders is kept for their history, deactivate it instead.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return error
+func DeleteSupplier(ctx context.Context, id string) error {
+	return database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		if _, err := getSupplier(ctx, tx, id, true); err != nil {
+			return err
+		}
+
+		data := map[string]interface{}{"id": id}
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM purchase_orders WHERE supplier_id = :id", data)
+		if err != nil {
+			return fmt.Errorf("counting purchase orders: %w", err)
+		}
+		if count > 0 {
+			return ErrSupplierInUse
+		}
+
+		if err := database.NamedExecQuery(ctx, tx, "DELETE FROM suppliers WHERE id = :id", data); err != nil {
+			return fmt.Errorf("deleting supplier: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// AddContact - AddContact is a function that adds a contact to a supplier. A new primary contact takes over from
+// the one before.
+//
+// @param ctx - context.Context
+// @param supplierId - string
+// @param payload - *ContactRequest
+// @return contact
+// @return error
+func AddContact(ctx context.Context, supplierId string, payload *ContactRequest) (*Contact, error) {
+	now := time.Now().UTC()
+	contact := &Contact{
+		Id:         uuid.New().String(),
+		SupplierId: supplierId,
+		Name:       payload.Name,
+		Role:       payload.Role,
+		Email:      payload.Email,
+		Phone:      payload.Phone,
+		IsPrimary:  payload.IsPrimary,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		if _, err := getSupplier(ctx, tx, supplierId, true); err != nil {
+			return err
+		}
+
+		// one primary contact at a time
+		if contact.IsPrimary {
+			if err := database.NamedExecQuery(ctx, tx, `
+        UPDATE supplier_contacts SET is_primary = FALSE, updated_at = :updated_at
+        WHERE supplier_id = :supplier_id AND is_primary
+      `, contact); err != nil {
+				return fmt.Errorf("updating primary contact: %w", err)
+			}
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO supplier_contacts (id, supplier_id, name, role, email, phone, is_primary, created_at, updated_at)
+      VALUES (:id, :supplier_id, :name, :role, :email, :phone, :is_primary, :created_at, :updated_at)
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, contact); err != nil {
+			return fmt.Errorf("inserting supplier contact: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+// RemoveContact - RemoveContact is a function that removes a contact from a supplier.
+//
+// @param ctx - context.Context
+// @param supplierId - string
+// @param contactId - string
+// @return error
+func RemoveContact(ctx context.Context, supplierId, contactId string) error {
+	count, err := database.NamedCountQuery(ctx, purchasingDatabase(), `
+    WITH removed AS (DELETE FROM supplier_contacts WHERE id = :id AND supplier_id = :supplier_id RETURNING id)
+    SELECT COUNT(*) FROM removed
+  `, map[string]interface{}{"id": contactId, "supplier_id": supplierId})
+	if err != nil {
+		return fmt.Errorf("deleting supplier contact: %w", err)
+	}
+	if count < 1 {
+		return ErrContactNotFound
+	}
+
+	return nil
+}
+
+// SetSupplierProduct - SetSupplierProduct is a function that adds a product to those a supplier sells us, or
+// changes what it costs. The cost is in the currency of the supplier.
+//
+// @param ctx - context.Context
+// @param supplierId - string
+// @param productId - string
+// @param payload - *SupplierProductRequest
+// @return supplier product
+// @return error
+func SetSupplierProduct(ctx context.Context, supplierId, productId string, payload *SupplierProductRequest) (*SupplierProduct, error) {
+	if err := payload.CostPrice.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOrder, err)
+	}
+	if payload.CostPrice.IsNegative() {
+		return nil, fmt.Errorf("%w: the cost price cannot be negative", ErrInvalidOrder)
+	}
+
+	now := time.Now().UTC()
+	product := &SupplierProduct{
+		SupplierId:       supplierId,
+		ProductId:        productId,
+		SupplierSku:      payload.SupplierSku,
+		CostPrice:        payload.CostPrice,
+		LeadTimeDays:     payload.LeadTimeDays,
+		MinOrderQuantity: payload.MinOrderQuantity,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if product.MinOrderQuantity < 1 {
+		product.MinOrderQuantity = 1
+	}
+
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		supplier, err := getSupplier(ctx, tx, supplierId, false)
+		if err != nil {
+			return err
+		}
+		if product.CostPrice.Currency != supplier.Currency {
+			return fmt.Errorf("%w: costs of the supplier are in %v", ErrCurrencyMismatch, supplier.Currency)
+		}
+
+		// check if the product exists
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM products WHERE id = :product_id", product)
+		if err != nil {
+			return fmt.Errorf("counting products: %w", err)
+		}
+		if count < 1 {
+			return ErrProductNotFound
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO supplier_products (supplier_id, product_id, supplier_sku, cost_price, lead_time_days, min_order_quantity, created_at, updated_at)
+      VALUES (:supplier_id, :product_id, :supplier_sku, :cost_price, :lead_time_days, :min_order_quantity, :created_at, :updated_at)
+      ON CONFLICT (supplier_id, product_id) DO UPDATE
+      SET supplier_sku = EXCLUDED.supplier_sku, cost_price = EXCLUDED.cost_price, lead_time_days = EXCLUDED.lead_time_days,
+          min_order_quantity = EXCLUDED.min_order_quantity, updated_at = EXCLUDED.updated_at
+      RETURNING *
+    `
+
+		// execute query
+		if err := database.NamedStructQuery(ctx, tx, q, product, product); err != nil {
+			return fmt.Errorf("upserting supplier product: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// RemoveSupplierProduct - RemoveSupplierProduct is a function that removes a product from those a supplier sells us.
+// Orders already made keep their lines.
+//
+// @param ctx - context.Context
+// @param supplierId - string
+// @param productId - string
+// @return error
+func RemoveSupplierProduct(ctx context.Context, supplierId, productId string) error {
+	count, err := database.NamedCountQuery(ctx, purchasingDatabase(), `
+    WITH removed AS (DELETE FROM supplier_products WHERE supplier_id = :supplier_id AND product_id = :product_id RETURNING product_id)
+    SELECT COUNT(*) FROM removed
+  `, map[string]interface{}{"supplier_id": supplierId, "product_id": productId})
+	if err != nil {
+		return fmt.Errorf("deleting supplier product: %w", err)
+	}
+	if count < 1 {
+		return ErrSupplierProductNotFound
+	}
+
+	return nil
+}
+
+// =====================================================================================================================
+// PURCHASE ORDERS
+// =====================================================================================================================
+
+// getOrder - gets a purchase order with its lines and receipts, locking the order when asked.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param id - string
+// @param lock - lock the order until the transaction ends
+// @return order
+// @return error
+func getOrder(ctx context.Context, db sqlx.ExtContext, id string, lock bool) (*OrderResponse, error) {
+	q := "SELECT * FROM purchase_orders WHERE id = :id"
+	if lock {
+		q += " FOR UPDATE"
+	}
+
+	data := map[string]interface{}{"id": id}
+	response := &OrderResponse{Order: &PurchaseOrder{}, Lines: make([]Line, 0), Receipts: make([]Receipt, 0)}
+	if err := database.NamedStructQuery(ctx, db, q, data, response.Order); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("selecting purchase order: %w", err)
+	}
+	if err := database.NamedSliceQuery(ctx, db, "SELECT * FROM purchase_order_lines WHERE purchase_order_id = :id ORDER BY created_at, id", data, &response.Lines); err != nil {
+		return nil, fmt.Errorf("selecting purchase order lines: %w", err)
+	}
+	if err := database.NamedSliceQuery(ctx, db, "SELECT * FROM purchase_order_receipts WHERE purchase_order_id = :id ORDER BY received_at, id", data, &response.Receipts); err != nil {
+		return nil, fmt.Errorf("selecting purchase order receipts: %w", err)
+	}
+
+	total, err := Total(response.Order.Currency, response.Lines)
+	if err != nil {
+		return nil, fmt.Errorf("totalling purchase order: %w", err)
+	}
+	response.Total = total
+
+	return response, nil
+}
+
+// setLines - replaces the lines of a draft purchase order. A line without a cost takes the cost price of the
+// supplier, which must then supply the product.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param order - *PurchaseOrder
+// @param requests - []LineRequest
+// @param now - time.Time
+// @return error
+func setLines(ctx context.Context, tx *sqlx.Tx, order *PurchaseOrder, requests []LineRequest, now time.Time) error {
+	ids := make([]string, 0, len(requests))
+	seen := make(map[string]bool, len(requests))
+	for _, r := range requests {
+		if seen[r.ProductId] {
+			return fmt.Errorf("%w: product %v is on the order twice", ErrInvalidOrder, r.ProductId)
+		}
+		seen[r.ProductId] = true
+		ids = append(ids, r.ProductId)
+	}
+
+	// check the products exist and get what the supplier charges for them
+	data := map[string]interface{}{"supplier_id": order.SupplierId, "ids": ids}
+	count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM products WHERE id = ANY(CAST(:ids AS UUID[]))", data)
+	if err != nil {
+		return fmt.Errorf("counting products: %w", err)
+	}
+	if count < len(ids) {
+		return ErrProductNotFound
+	}
+	supplied := make([]SupplierProduct, 0)
+	if err := database.NamedSliceQuery(ctx, tx, `
+    SELECT * FROM supplier_products WHERE supplier_id = :supplier_id AND product_id = ANY(CAST(:ids AS UUID[]))
+  `, data, &supplied); err != nil {
+		return fmt.Errorf("selecting supplier products: %w", err)
+	}
+	prices := make(map[string]SupplierProduct, len(supplied))
+	for _, p := range supplied {
+		prices[p.ProductId] = p
+	}
+
+	lines := make([]Line, 0, len(requests))
+	for _, r := range requests {
+		line := Line{Id: uuid.New().String(), PurchaseOrderId: order.Id, ProductId: r.ProductId, QuantityOrdered: r.Quantity, CreatedAt: now, UpdatedAt: now}
+
+		price, ok := prices[r.ProductId]
+		switch {
+		case r.UnitCost != nil:
+			line.UnitCost = *r.UnitCost
+		case ok:
+			line.UnitCost = price.CostPrice
+		default:
+			return fmt.Errorf("%w[%v]: give the unit cost", ErrSupplierProductNotFound, r.ProductId)
+		}
+		if err := line.UnitCost.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidOrder, err)
+		}
+		if line.UnitCost.IsNegative() {
+			return fmt.Errorf("%w: the unit cost cannot be negative", ErrInvalidOrder)
+		}
+		if line.UnitCost.Currency != order.Currency {
+			return fmt.Errorf("%w: the order is in %v", ErrCurrencyMismatch, order.Currency)
+		}
+		if ok && line.QuantityOrdered < price.MinOrderQuantity {
+			return fmt.Errorf("%w: the supplier sells product %v by %v at least", ErrInvalidOrder, r.ProductId, price.MinOrderQuantity)
+		}
+		lines = append(lines, line)
+	}
+
+	// replace the lines
+	if err := database.NamedExecQuery(ctx, tx, "DELETE FROM purchase_order_lines WHERE purchase_order_id = :id", order); err != nil {
+		return fmt.Errorf("deleting purchase order lines: %w", err)
+	}
+	if err := database.NamedExecQuery(ctx, tx, `
+    INSERT INTO purchase_order_lines (id, purchase_order_id, product_id, quantity_ordered, quantity_received, unit_cost, created_at, updated_at)
+    VALUES (:id, :purchase_order_id, :product_id, :quantity_ordered, :quantity_received, :unit_cost, :created_at, :updated_at)
+  `, lines); err != nil {
+		return fmt.Errorf("inserting purchase order lines: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOrder - CreateOrder is a function that drafts a purchase order in the currency of an active supplier.
+//
+// @param ctx - context.Context
+// @param userId - the admin drafting the order
+// @param payload - *OrderRequest
+// @return order
+// @return error
+func CreateOrder(ctx context.Context, userId string, payload *OrderRequest) (*OrderResponse, error) {
+	var response *OrderResponse
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		var err error
+		response, err = createOrder(ctx, tx, userId, payload)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// createOrder - drafts a purchase order within a transaction.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - the admin drafting the order
+// @param payload - *OrderRequest
+// @return order
+// @return error
+func createOrder(ctx context.Context, tx *sqlx.Tx, userId string, payload *OrderRequest) (*OrderResponse, error) {
+	supplier, err := getSupplier(ctx, tx, payload.SupplierId, false)
+	if err != nil {
+		return nil, err
+	}
+	if !supplier.Active {
+		return nil, ErrInactiveSupplier
+	}
+
+	now := time.Now().UTC()
+	order := &PurchaseOrder{
+		Id:         uuid.New().String(),
+		SupplierId: supplier.Id,
+		Status:     StatusDraft,
+		Currency:   supplier.Currency,
+		Notes:      payload.Notes,
+		ExpectedAt: payload.ExpectedAt,
+		CreatedBy:  userId,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO purchase_orders (id, supplier_id, status, currency, notes, expected_at, created_by, created_at, updated_at)
+    VALUES (:id, :supplier_id, :status, :currency, :notes, :expected_at, :created_by, :created_at, :updated_at)
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, tx, q, order); err != nil {
+		return nil, fmt.Errorf("inserting purchase order: %w", err)
+	}
+	if err := setLines(ctx, tx, order, payload.Lines, now); err != nil {
+		return nil, err
+	}
+
+	return getOrder(ctx, tx, order.Id, false)
+}
+
+// UpdateOrder - UpdateOrder is a function that changes the notes, expected date and lines of a draft purchase order.
+// The supplier cannot change, draft a new order instead.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param payload - *OrderRequest
+// @return order
+// @return error
+func UpdateOrder(ctx context.Context, id string, payload *OrderRequest) (*OrderResponse, error) {
+	var response *OrderResponse
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		current, err := getOrder(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		order := current.Order
+		if order.Status != StatusDraft {
+			return ErrNotEditable
+		}
+		if payload.SupplierId != order.SupplierId {
+			return fmt.Errorf("%w: the supplier of an order cannot change", ErrInvalidOrder)
+		}
+
+		now := time.Now().UTC()
+		order.Notes = payload.Notes
+		order.ExpectedAt = payload.ExpectedAt
+		order.UpdatedAt = now
+		if err := database.NamedExecQuery(ctx, tx, `
+      UPDATE purchase_orders SET notes = :notes, expected_at = :expected_at, updated_at = :updated_at WHERE id = :id
+    `, order); err != nil {
+			return fmt.Errorf("updating purchase order: %w", err)
+		}
+		if err := setLines(ctx, tx, order, payload.Lines, now); err != nil {
+			return err
+		}
+
+		response, err = getOrder(ctx, tx, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetOrder - GetOrder is a function that gets a purchase order with its lines and receipts.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return order
+// @return error
+func GetOrder(ctx context.Context, id string) (*OrderResponse, error) {
+	return getOrder(ctx, purchasingDatabase(), id, false)
+}
+
+// ordersSchema - the fields of a purchase order allowed in list queries
+var ordersSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "number", Column: "number", Type: pagination.Number, Sortable: true},
+		{Name: "supplierId", Column: "supplier_id", Type: pagination.UUID},
+		{Name: "status", Column: "status", Type: pagination.String, Sortable: true},
+		{Name: "currency", Column: "currency", Type: pagination.String},
+		{Name: "expectedAt", Column: "expected_at", Type: pagination.Time},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-createdAt",
+}
+
+// ListOrders - ListOrders is a function that lists purchase orders, the newest first unless sorted otherwise.
+//
+// @param ctx - context.Context
+// @param params - *OrdersQuery
+// @return orders
+// @return error
+func ListOrders(ctx context.Context, params *OrdersQuery) (*PaginatedOrdersResponse, error) {
+	// parse the filter and sort
+	statement, err := ordersSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	if len(params.SupplierId) > 0 {
+		statement.AddCondition("supplier_id = CAST(:supplier_id AS UUID)", map[string]interface{}{"supplier_id": params.SupplierId})
+	}
+	if len(params.Status) > 0 {
+		statement.AddCondition("status = :status", map[string]interface{}{"status": params.Status})
+	}
+
+	// execute query
+	orders := make([]PurchaseOrder, 0)
+	page, err := database.NamedPageQuery(ctx, purchasingDatabase(), "purchase_orders", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &orders)
+	if err != nil {
+		return nil, fmt.Errorf("selecting purchase orders: %w", err)
+	}
+
+	return &PaginatedOrdersResponse{
+		Orders:          orders,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// Act - Act is a function that sends or cancels a purchase order. A sent order without an expected date is
+// expected after the lead time of the supplier.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param action - send or cancel
+// @param now - time.Time
+// @return order
+// @return error
+func Act(ctx context.Context, id, action string, now time.Time) (*OrderResponse, error) {
+	var response *OrderResponse
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		current, err := getOrder(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		order := current.Order
+
+		status, err := Transition(order.Status, action)
+		if err != nil {
+			return err
+		}
+		order.Status = status
+		order.UpdatedAt = now.UTC()
+
+		switch status {
+		case StatusSent:
+			supplier, err := getSupplier(ctx, tx, order.SupplierId, false)
+			if err != nil {
+				return err
+			}
+			if !supplier.Active {
+				return ErrInactiveSupplier
+			}
+			order.SentAt = &order.UpdatedAt
+			if order.ExpectedAt == nil {
+				expected := order.UpdatedAt.AddDate(0, 0, supplier.LeadTimeDays)
+				order.ExpectedAt = &expected
+			}
+		case StatusCancelled:
+			order.CancelledAt = &order.UpdatedAt
+		}
+
+		if err := database.NamedExecQuery(ctx, tx, `
+      UPDATE purchase_orders
+      SET status = :status, expected_at = :expected_at, sent_at = :sent_at, cancelled_at = :cancelled_at, updated_at = :updated_at
+      WHERE id = :id
+    `, order); err != nil {
+			return fmt.Errorf("updating purchase order: %w", err)
+		}
+
+		response, err = getOrder(ctx, tx, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Receive - Receive is a function that takes a delivery for a sent purchase order into stock at a location. Every
+// product received moves stock in, is kept as a receipt with what a unit actually cost, and updates the average cost
+// of the product. The order is received once every unit arrived.
+//
+// @param ctx - context.Context
+// @param userId - the admin taking the delivery
+// @param id - string
+// @param payload - *ReceiveRequest
+// @return order
+// @return error
+func Receive(ctx context.Context, userId, id string, payload *ReceiveRequest) (*OrderResponse, error) {
+	var response *OrderResponse
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		current, err := getOrder(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		order := current.Order
+		if order.Status != StatusSent && order.Status != StatusPartiallyReceived {
+			return fmt.Errorf("%w: cannot receive a %v order", ErrInvalidTransition, order.Status)
+		}
+
+		matched, err := Match(current.Lines, payload.Lines)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		reference := fmt.Sprintf("PO-%06d", order.Number)
+		for i, r := range payload.Lines {
+			line := &current.Lines[matched[i]]
+
+			// what a unit actually cost
+			cost := line.UnitCost
+			if r.UnitCost != nil {
+				cost = *r.UnitCost
+			}
+			if err := cost.Validate(); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidReceipt, err)
+			}
+			if cost.IsNegative() {
+				return fmt.Errorf("%w: the unit cost cannot be negative", ErrInvalidReceipt)
+			}
+			if cost.Currency != order.Currency {
+				return fmt.Errorf("%w: the order is in %v", ErrCurrencyMismatch, order.Currency)
+			}
+
+			// move the stock in
+			movement := &inventory.Movement{
+				ProductId:  line.ProductId,
+				LocationId: payload.LocationId,
+				Quantity:   r.Quantity,
+				Reason:     inventory.ReasonReceipt,
+				Reference:  reference,
+				UnitCost:   &cost,
+				LotNumber:  r.LotNumber,
+				ExpiresAt:  r.ExpiresAt,
+				CreatedBy:  &userId,
+				CreatedAt:  now,
+			}
+			if err := inventory.Move(ctx, tx, movement); err != nil {
+				return err
+			}
+
+			// keep the receipt
+			line.QuantityReceived += r.Quantity
+			line.UpdatedAt = now
+			receipt := &Receipt{
+				Id:              uuid.New().String(),
+				PurchaseOrderId: order.Id,
+				LineId:          line.Id,
+				ProductId:       line.ProductId,
+				LocationId:      movement.LocationId,
+				Quantity:        r.Quantity,
+				UnitCost:        cost,
+				LotNumber:       r.LotNumber,
+				ExpiresAt:       inventory.Day(r.ExpiresAt),
+				MovementId:      movement.Id,
+				ReceivedBy:      userId,
+				ReceivedAt:      now,
+			}
+			if err := database.NamedExecQuery(ctx, tx, `
+        INSERT INTO purchase_order_receipts (id, purchase_order_id, line_id, product_id, location_id, quantity, unit_cost, lot_number, expires_at, movement_id, received_by, received_at)
+        VALUES (:id, :purchase_order_id, :line_id, :product_id, :location_id, :quantity, :unit_cost, :lot_number, :expires_at, :movement_id, :received_by, :received_at)
+      `, receipt); err != nil {
+				return fmt.Errorf("inserting purchase order receipt: %w", err)
+			}
+			if err := database.NamedExecQuery(ctx, tx, `
+        UPDATE purchase_order_lines SET quantity_received = :quantity_received, updated_at = :updated_at WHERE id = :id
+      `, line); err != nil {
+				return fmt.Errorf("updating purchase order line: %w", err)
+			}
+
+			if err := addCost(ctx, tx, line.ProductId, cost, int64(r.Quantity), now); err != nil {
+				return err
+			}
+		}
+
+		// move the order on
+		order.Status = Progress(current.Lines)
+		order.UpdatedAt = now
+		if order.Status == StatusReceived {
+			order.ReceivedAt = &now
+		}
+		if err := database.NamedExecQuery(ctx, tx, `
+      UPDATE purchase_orders SET status = :status, received_at = :received_at, updated_at = :updated_at WHERE id = :id
+    `, order); err != nil {
+			return fmt.Errorf("updating purchase order: %w", err)
+		}
+
+		response, err = getOrder(ctx, tx, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// addCost - folds units received into the average cost of a product, weighting the average so far by the stock on
+// hand before the receipt so that units sold no longer count. The stock on hand is counted across every supplier,
+// so there is a single cost basis per product kept in the catalog currency, and units bought in other currencies
+// are converted with the exchange rates of the day they were received.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param productId - string
+// @param cost - what a unit cost, in the currency of the order
+// @param quantity - the units received, already moved into stock
+// @param now - time.Time
+// @return error
+func addCost(ctx context.Context, tx *sqlx.Tx, productId string, cost money.Money, quantity int64, now time.Time) error {
+	basis, err := fx.Convert(ctx, cost, money.DefaultCurrency, now)
+	if err != nil {
+		return err
+	}
+
+	current := &Cost{ProductId: productId, Currency: basis.Currency}
+	err = database.NamedStructQuery(ctx, tx, `
+    SELECT * FROM product_costs WHERE product_id = :product_id AND currency = :currency FOR UPDATE
+  `, current, current)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return fmt.Errorf("selecting product cost: %w", err)
+	}
+	costed := err == nil
+
+	// the stock on hand before the receipt
+	var product struct {
+		StockQuantity int64 `db:"stock_quantity"`
+	}
+	if err := database.NamedStructQuery(ctx, tx, "SELECT stock_quantity FROM products WHERE id = :id", map[string]interface{}{
+		"id": productId,
+	}, &product); err != nil {
+		return fmt.Errorf("selecting product stock: %w", err)
+	}
+	// stock without a known cost does not weigh the average
+	onHand := product.StockQuantity - quantity
+	if onHand < 0 || !costed {
+		onHand = 0
+	}
+
+	average, err := AverageCost(current.AverageCost, onHand, basis, quantity)
+	if err != nil {
+		return err
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO product_costs (product_id, currency, average_cost, last_cost, quantity, updated_at)
+    VALUES (:product_id, :currency, :average_cost, :last_cost, :quantity, :updated_at)
+    ON CONFLICT (product_id, currency) DO UPDATE
+    SET average_cost = EXCLUDED.average_cost, last_cost = EXCLUDED.last_cost, quantity = EXCLUDED.quantity,
+        updated_at = EXCLUDED.updated_at
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, tx, q, &Cost{
+		ProductId:   productId,
+		Currency:    basis.Currency,
+		AverageCost: average,
+		LastCost:    basis,
+		Quantity:    onHand + quantity,
+		UpdatedAt:   now,
+	}); err != nil {
+		return fmt.Errorf("upserting product cost: %w", err)
+	}
+
+	return nil
+}
+
+// =====================================================================================================================
+// MARGINS
+// =====================================================================================================================
+
+// marginsSchema - the fields of a margin allowed in list queries
+var marginsSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "productId", Column: "product_id", Type: pagination.UUID, Sortable: true},
+		{Name: "categoryId", Column: "category_id", Type: pagination.UUID},
+		{Name: "name", Column: "name", Type: pagination.String, Sortable: true},
+	},
+	Key:         "product_id",
+	DefaultSort: "name",
+}
+
+// Margins - Margins is a function that lists products by name, unless sorted otherwise, with their price against
+// the average cost of the stock on hand in the currency of the price.
+//
+// @param ctx - context.Context
+// @param params - *MarginsQuery
+// @return margins
+// @return error
+func Margins(ctx context.Context, params *MarginsQuery) (*PaginatedMarginsResponse, error) {
+	// parse the filter and sort
+	statement, err := marginsSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	if len(params.CategoryId) > 0 {
+		statement.AddCondition("category_id = CAST(:category_id AS UUID)", map[string]interface{}{"category_id": params.CategoryId})
+	}
+
+	// execute query
+	margins := make([]Margin, 0)
+	page, err := database.NamedPageQuery(ctx, purchasingDatabase(), `(
+    SELECT p.id AS product_id, p.category_id, p.name, p.price, c.average_cost
+    FROM products p
+    LEFT JOIN product_costs c ON c.product_id = p.id AND c.currency = (p.price).currency
+  ) AS margins`, statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &margins)
+	if err != nil {
+		return nil, fmt.Errorf("selecting margins: %w", err)
+	}
+
+	for i := range margins {
+		if margins[i].AverageCost == nil {
+			continue
+		}
+		margin, bps, err := MarginOf(margins[i].Price, *margins[i].AverageCost)
+		if err != nil {
+			return nil, fmt.Errorf("working out margin: %w", err)
+		}
+		margins[i].Margin, margins[i].MarginBps = &margin, &bps
+	}
+
+	return &PaginatedMarginsResponse{
+		Margins:         margins,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// =====================================================================================================================
+// REORDERING
+// =====================================================================================================================
+
+// GetReorderSetting - GetReorderSetting is a function that gets when a product is reordered.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @return setting
+// @return error
+func GetReorderSetting(ctx context.Context, productId string) (*ReorderSetting, error) {
+	setting := &ReorderSetting{}
+	if err := database.NamedStructQuery(ctx, purchasingDatabase(), "SELECT * FROM reorder_settings WHERE product_id = :product_id", map[string]interface{}{
+		"product_id": productId,
+	}, setting); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrReorderSettingNotFound
+		}
+		return nil, fmt.Errorf("selecting reorder setting: %w", err)
+	}
+
+	return setting, nil
+}
+
+// SetReorderSetting - SetReorderSetting is a function that sets when a product is reordered and how much stock it
+// is ordered up to. A preferred supplier must sell the product.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param payload - *ReorderSettingRequest
+// @return setting
+// @return error
+func SetReorderSetting(ctx context.Context, productId string, payload *ReorderSettingRequest) (*ReorderSetting, error) {
+	now := time.Now().UTC()
+	setting := &ReorderSetting{
+		ProductId:    productId,
+		ReorderPoint: payload.ReorderPoint,
+		TargetStock:  payload.TargetStock,
+		SupplierId:   payload.SupplierId,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		// check if the product exists
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM products WHERE id = :product_id", setting)
+		if err != nil {
+			return fmt.Errorf("counting products: %w", err)
+		}
+		if count < 1 {
+			return ErrProductNotFound
+		}
+
+		// check the supplier sells it
+		if setting.SupplierId != nil {
+			if _, err := getSupplier(ctx, tx, *setting.SupplierId, false); err != nil {
+				return err
+			}
+			count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM supplier_products WHERE supplier_id = :supplier_id AND product_id = :product_id", setting)
+			if err != nil {
+				return fmt.Errorf("counting supplier products: %w", err)
+			}
+			if count < 1 {
+				return ErrSupplierProductNotFound
+			}
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO reorder_settings (product_id, reorder_point, target_stock, supplier_id, created_at, updated_at)
+      VALUES (:product_id, :reorder_point, :target_stock, :supplier_id, :created_at, :updated_at)
+      ON CONFLICT (product_id) DO UPDATE
+      SET reorder_point = EXCLUDED.reorder_point, target_stock = EXCLUDED.target_stock, supplier_id = EXCLUDED.supplier_id,
+          updated_at = EXCLUDED.updated_at
+      RETURNING *
+    `
+
+		// execute query
+		if err := database.NamedStructQuery(ctx, tx, q, setting, setting); err != nil {
+			return fmt.Errorf("upserting reorder setting: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return setting, nil
+}
+
+// RemoveReorderSetting - RemoveReorderSetting is a function that stops suggesting to reorder a product.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @return error
+func RemoveReorderSetting(ctx context.Context, productId string) error {
+	count, err := database.NamedCountQuery(ctx, purchasingDatabase(), `
+    WITH removed AS (DELETE FROM reorder_settings WHERE product_id = :product_id RETURNING product_id)
+    SELECT COUNT(*) FROM removed
+  `, map[string]interface{}{"product_id": productId})
+	if err != nil {
+		return fmt.Errorf("deleting reorder setting: %w", err)
+	}
+	if count < 1 {
+		return ErrReorderSettingNotFound
+	}
+
+	return nil
+}
+
+// ComputeVelocity - ComputeVelocity is a function that works out the units of every product sold a day from the
+// sale and return stock movements of the last VelocityDays days.
+//
+// @param ctx - context.Context
+// @param now - time.Time
+// @return the products a velocity was computed for
+// @return error
+func ComputeVelocity(ctx context.Context, now time.Time) (int, error) {
+	now = now.UTC()
+
+	// sales take stock out and returns put it back, so the units sold are the opposite of their sum
+	velocities := make([]Velocity, 0)
+	if err := database.NamedSliceQuery(ctx, purchasingDatabase(), `
+    SELECT p.id AS product_id, COALESCE(-SUM(m.quantity), 0) AS units_sold
+    FROM products p
+    LEFT JOIN stock_movements m ON m.product_id = p.id AND m.reason IN ('sale', 'return') AND m.created_at >= :since
+    GROUP BY p.id
+    ORDER BY p.id
+  `, map[string]interface{}{"since": now.AddDate(0, 0, -VelocityDays)}, &velocities); err != nil {
+		return 0, fmt.Errorf("selecting units sold: %w", err)
+	}
+
+	for i := range velocities {
+		velocities[i].Days = VelocityDays
+		velocities[i].UnitsPerDay = UnitsPerDay(velocities[i].UnitsSold, VelocityDays)
+		velocities[i].ComputedAt = now
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO sales_velocities (product_id, units_sold, days, units_per_day, computed_at)
+    VALUES (:product_id, :units_sold, :days, :units_per_day, :computed_at)
+    ON CONFLICT (product_id) DO UPDATE
+    SET units_sold = EXCLUDED.units_sold, days = EXCLUDED.days, units_per_day = EXCLUDED.units_per_day,
+        computed_at = EXCLUDED.computed_at
+  `
+
+	// execute query in batches, keeping under the limit of parameters of a statement
+	for start := 0; start < len(velocities); start += VelocityBatchSize {
+		end := start + VelocityBatchSize
+		if end > len(velocities) {
+			end = len(velocities)
+		}
+		if err := database.NamedExecQuery(ctx, purchasingDatabase(), q, velocities[start:end]); err != nil {
+			return 0, fmt.Errorf("upserting sales velocities: %w", err)
+		}
+	}
+
+	return len(velocities), nil
+}
+
+// Suggest - Suggest is a function that works out what to order for the products with a reorder setting, grouped
+// by supplier. A product is ordered from its preferred supplier, or else from the active supplier with the shortest
+// lead time and then the lowest cost.
+//
+// @param ctx - context.Context
+// @param params - *SuggestionsQuery
+// @return suggestions
+// @return error
+func Suggest(ctx context.Context, params *SuggestionsQuery) (*SuggestionsResponse, error) {
+	return suggest(ctx, purchasingDatabase(), params)
+}
+
+// suggest - works out what to order, reading the stock and the units on order through db.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param params - *SuggestionsQuery
+// @return suggestions
+// @return error
+func suggest(ctx context.Context, db sqlx.ExtContext, params *SuggestionsQuery) (*SuggestionsResponse, error) {
+	// query statement to be executed
+	q := `
+    SELECT p.id AS product_id, p.name, p.stock_quantity, r.reorder_point, r.target_stock,
+           COALESCE(v.units_per_day, 0) AS units_per_day, COALESCE(o.on_order, 0) AS on_order,
+           s.supplier_id, COALESCE(s.lead_time_days, 0) AS lead_time_days,
+           COALESCE(s.min_order_quantity, 1) AS min_order_quantity, s.cost_price
+    FROM reorder_settings r
+    JOIN products p ON p.id = r.product_id
+    LEFT JOIN sales_velocities v ON v.product_id = r.product_id
+    LEFT JOIN (
+      SELECT l.product_id, SUM(l.quantity_ordered - l.quantity_received) AS on_order
+      FROM purchase_order_lines l
+      JOIN purchase_orders po ON po.id = l.purchase_order_id
+      WHERE po.status IN ('draft', 'sent', 'partially_received')
+      GROUP BY l.product_id
+    ) o ON o.product_id = r.product_id
+    LEFT JOIN LATERAL (
+      SELECT sp.supplier_id, COALESCE(sp.lead_time_days, su.lead_time_days) AS lead_time_days,
+             sp.min_order_quantity, sp.cost_price
+      FROM supplier_products sp
+      JOIN suppliers su ON su.id = sp.supplier_id
+      WHERE sp.product_id = r.product_id AND su.active AND (r.supplier_id IS NULL OR sp.supplier_id = r.supplier_id)
+      ORDER BY COALESCE(sp.lead_time_days, su.lead_time_days), (sp.cost_price).amount, su.name
+      LIMIT 1
+    ) s ON TRUE
+    WHERE (:supplier_id = '' OR CAST(s.supplier_id AS TEXT) = :supplier_id)
+    ORDER BY p.name, p.id
+  `
+
+	// execute query
+	candidates := make([]Suggestion, 0)
+	if err := database.NamedSliceQuery(ctx, db, q, map[string]interface{}{"supplier_id": params.SupplierId}, &candidates); err != nil {
+		return nil, fmt.Errorf("selecting reorder candidates: %w", err)
+	}
+
+	// keep the products to reorder, grouped by supplier
+	response := &SuggestionsResponse{Suppliers: make([]SupplierSuggestions, 0), Unassigned: make([]Suggestion, 0)}
+	bySupplier := make(map[string][]Suggestion)
+	ids := make([]string, 0)
+	for _, candidate := range candidates {
+		if candidate.Quantity = Reorder(&candidate); candidate.Quantity < 1 {
+			continue
+		}
+		if candidate.SupplierId == nil {
+			response.Unassigned = append(response.Unassigned, candidate)
+			continue
+		}
+		if _, ok := bySupplier[*candidate.SupplierId]; !ok {
+			ids = append(ids, *candidate.SupplierId)
+		}
+		bySupplier[*candidate.SupplierId] = append(bySupplier[*candidate.SupplierId], candidate)
+	}
+	if len(ids) < 1 {
+		return response, nil
+	}
+
+	// get the suppliers by name
+	suppliers := make([]Supplier, 0, len(ids))
+	if err := database.NamedSliceQuery(ctx, db, "SELECT * FROM suppliers WHERE id = ANY(CAST(:ids AS UUID[])) ORDER BY name", map[string]interface{}{
+		"ids": ids,
+	}, &suppliers); err != nil {
+		return nil, fmt.Errorf("selecting suppliers: %w", err)
+	}
+
+	for i := range suppliers {
+		group := SupplierSuggestions{Supplier: &suppliers[i], Suggestions: bySupplier[suppliers[i].Id], Total: money.Zero(suppliers[i].Currency)}
+		for _, suggestion := range group.Suggestions {
+			cost, err := suggestion.UnitCost.Mul(int64(suggestion.Quantity))
+			if err != nil {
+				return nil, fmt.Errorf("totalling suggestions: %w", err)
+			}
+			if group.Total, err = group.Total.Add(cost); err != nil {
+				return nil, fmt.Errorf("totalling suggestions: %w", err)
+			}
+		}
+		response.Suppliers = append(response.Suppliers, group)
+	}
+
+	return response, nil
+}
+
+// DraftReorders - DraftReorders is a function that drafts a purchase order for every supplier with products to
+// reorder. The units on the drafts count as on order, so drafting again does not order them twice. Drafting is
+// serialized and the drafts are created together, or not at all.
+//
+// @param ctx - context.Context
+// @param userId - the admin drafting the orders
+// @param payload - *DraftRequest
+// @return orders
+// @return error
+func DraftReorders(ctx context.Context, userId string, payload *DraftRequest) (*DraftResponse, error) {
+	var response *DraftResponse
+	err := database.Transaction(ctx, purchasingDatabase(), func(tx *sqlx.Tx) error {
+		// wait for any other drafting, so that its drafts count as on order
+		if err := database.NamedExecQuery(ctx, tx, "SELECT pg_advisory_xact_lock(hashtext('purchasing:reorders'))", map[string]interface{}{}); err != nil {
+			return fmt.Errorf("locking reorders: %w", err)
+		}
+
+		suggestions, err := suggest(ctx, tx, &SuggestionsQuery{SupplierId: payload.SupplierId})
+		if err != nil {
+			return err
+		}
+
+		response = &DraftResponse{Orders: make([]OrderResponse, 0, len(suggestions.Suppliers))}
+		for _, group := range suggestions.Suppliers {
+			lines := make([]LineRequest, 0, len(group.Suggestions))
+			for _, suggestion := range group.Suggestions {
+				lines = append(lines, LineRequest{ProductId: suggestion.ProductId, Quantity: suggestion.Quantity})
+			}
+
+			// split the lines over orders of at most MaxLines
+			for start := 0; start < len(lines); start += MaxLines {
+				end := start + MaxLines
+				if end > len(lines) {
+					end = len(lines)
+				}
+
+				order, err := createOrder(ctx, tx, userId, &OrderRequest{
+					SupplierId: group.Supplier.Id,
+					Notes:      "Drafted from reorder suggestions",
+					Lines:      lines[start:end],
+				})
+				if err != nil {
+					return fmt.Errorf("drafting order for supplier %v: %w", group.Supplier.Name, err)
+				}
+				response.Orders = append(response.Orders, *order)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,117 @@
+package purchasing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/money"
+	"encore.app/products/fx"
+	"encore.app/products/productstest"
+)
+
+// TestAddCost - test the average cost of a product bought from suppliers in two currencies
+//
+// @param t - testing.T
+func TestAddCost(t *testing.T) {
+	if !database.Available(purchasingDatabase) {
+		t.Skip("the products database is not available, run with encore test")
+	}
+	ctx := context.Background()
+	userId := uuid.New().String()
+
+	// a euro is worth 1.10 dollars
+	if _, err := fx.SetRate(ctx, userId, &fx.RateRequest{Base: "EUR", Quote: "USD", Rate: "1.10"}); err != nil {
+		t.Fatal(err)
+	}
+	productId, err := productstest.Create(ctx, purchasingDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// receive 10 units at $5.00, then 10 units at €10.00
+	for _, cost := range []money.Money{{Amount: 500, Currency: "USD"}, {Amount: 1000, Currency: "EUR"}} {
+		supplier, err := CreateSupplier(ctx, &SupplierRequest{Name: "supplier " + uuid.New().String(), Currency: cost.Currency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		order, err := CreateOrder(ctx, userId, &OrderRequest{
+			SupplierId: supplier.Id,
+			Lines:      []LineRequest{{ProductId: productId, Quantity: 10, UnitCost: &cost}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Act(ctx, order.Order.Id, ActionSend, order.Order.CreatedAt); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Receive(ctx, userId, order.Order.Id, &ReceiveRequest{
+			Lines: []ReceiveLine{{ProductId: productId, Quantity: 10}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// one cost basis in dollars, the euro units converted at $11.00
+	var costs []Cost
+	if err := database.NamedSliceQuery(ctx, purchasingDatabase(), "SELECT * FROM product_costs WHERE product_id = :product_id", map[string]interface{}{
+		"product_id": productId,
+	}, &costs); err != nil {
+		t.Fatal(err)
+	}
+	if len(costs) != 1 {
+		t.Fatalf("expected one cost, got %v", costs)
+	}
+	want := money.Money{Amount: 800, Currency: "USD"}
+	if costs[0].AverageCost != want || costs[0].Quantity != 20 {
+		t.Errorf("expected an average of %v over 20 units, got %v over %v", want, costs[0].AverageCost, costs[0].Quantity)
+	}
+}
+
+// TestDraftReorders - test drafting reorders twice at once orders the products once
+//
+// @param t - testing.T
+func TestDraftReorders(t *testing.T) {
+	if !database.Available(purchasingDatabase) {
+		t.Skip("the products database is not available, run with encore test")
+	}
+	ctx := context.Background()
+
+	productId, err := productstest.Create(ctx, purchasingDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	supplier, err := CreateSupplier(ctx, &SupplierRequest{Name: "supplier " + uuid.New().String(), Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetSupplierProduct(ctx, supplier.Id, productId, &SupplierProductRequest{CostPrice: money.Money{Amount: 500, Currency: "USD"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetReorderSetting(ctx, productId, &ReorderSettingRequest{ReorderPoint: 5, TargetStock: 10, SupplierId: &supplier.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	// draft twice at once
+	results := make(chan *DraftResponse, 2)
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			response, err := DraftReorders(ctx, uuid.New().String(), &DraftRequest{SupplierId: supplier.Id})
+			results <- response
+			errs <- err
+		}()
+	}
+	orders := 0
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		orders += len((<-results).Orders)
+	}
+	if orders != 1 {
+		t.Errorf("expected one order drafted, got %v", orders)
+	}
+}
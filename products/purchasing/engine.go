@@ -0,0 +1,212 @@
+package purchasing
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"encore.app/pkg/lifecycle"
+	"encore.app/pkg/money"
+)
+
+// orderLifecycle - the status an action moves a purchase order to, by the status it is in
+var orderLifecycle = &lifecycle.Machine{
+	Noun: "order",
+	Transitions: map[string]map[string]string{
+		StatusDraft: {ActionSend: StatusSent, ActionCancel: StatusCancelled},
+		StatusSent:  {ActionCancel: StatusCancelled},
+	},
+	Err: ErrInvalidTransition,
+}
+
+// Transition - Transition works out the status of a purchase order after an action. Orders are sent from draft
+// and cancelled before any unit arrives; receiving moves them on by itself.
+//
+// @param status - the status of the order
+// @param action - send or cancel
+// @return the new status
+// @return error
+func Transition(status, action string) (string, error) {
+	return orderLifecycle.Next(status, action)
+}
+
+// Progress - Progress works out the status of a sent purchase order from the units received of its lines.
+//
+// @param lines - []Line
+// @return status
+func Progress(lines []Line) string {
+	received, complete := false, true
+	for _, line := range lines {
+		if line.QuantityReceived > 0 {
+			received = true
+		}
+		if line.QuantityReceived < line.QuantityOrdered {
+			complete = false
+		}
+	}
+
+	switch {
+	case received && complete:
+		return StatusReceived
+	case received:
+		return StatusPartiallyReceived
+	default:
+		return StatusSent
+	}
+}
+
+// Remaining - Remaining gets the units of a line still to arrive.
+//
+// @param line - *Line
+// @return int
+func Remaining(line *Line) int {
+	if line.QuantityReceived >= line.QuantityOrdered {
+		return 0
+	}
+
+	return line.QuantityOrdered - line.QuantityReceived
+}
+
+// AverageCost - AverageCost works out the moving average cost after receiving units, weighting the average so far
+// by the units still on hand. The result is rounded half to even.
+//
+// @param average - the average so far
+// @param quantity - the units on hand before the receipt
+// @param cost - what a unit received cost
+// @param received - the units received
+// @return the new average
+// @return error
+func AverageCost(average money.Money, quantity int64, cost money.Money, received int64) (money.Money, error) {
+	if received < 1 || quantity < 0 {
+		return money.Money{}, fmt.Errorf("%w: quantities must be positive", ErrInvalidReceipt)
+	}
+	if quantity == 0 {
+		return cost, nil
+	}
+	if average.Currency != cost.Currency {
+		return money.Money{}, ErrCurrencyMismatch
+	}
+
+	// (average × quantity + cost × received) / (quantity + received), with big integers so nothing overflows
+	total := new(big.Int).Mul(big.NewInt(average.Amount), big.NewInt(quantity))
+	total.Add(total, new(big.Int).Mul(big.NewInt(cost.Amount), big.NewInt(received)))
+	amount := money.Round(total, big.NewInt(quantity+received), money.HalfEven)
+	if !amount.IsInt64() {
+		return money.Money{}, money.ErrOverflow
+	}
+
+	return money.Money{Amount: amount.Int64(), Currency: cost.Currency}, nil
+}
+
+// MarginOf - MarginOf works out what a product earns over its cost, and that margin in basis points of its price.
+//
+// @param price - what the product sells for
+// @param cost - what a unit cost
+// @return margin
+// @return basis points of the price, 0 when the price is zero
+// @return error
+func MarginOf(price, cost money.Money) (money.Money, int64, error) {
+	margin, err := price.Sub(cost)
+	if err != nil {
+		return money.Money{}, 0, err
+	}
+	if price.Amount == 0 {
+		return margin, 0, nil
+	}
+
+	bps := money.Round(new(big.Int).Mul(big.NewInt(margin.Amount), big.NewInt(10000)), big.NewInt(price.Amount), money.HalfEven)
+
+	return margin, bps.Int64(), nil
+}
+
+// Total - Total works out the cost of the units ordered on the lines of a purchase order.
+//
+// @param currency - the currency of the order
+// @param lines - []Line
+// @return total
+// @return error
+func Total(currency string, lines []Line) (money.Money, error) {
+	total := money.Zero(currency)
+	for _, line := range lines {
+		cost, err := line.UnitCost.Mul(int64(line.QuantityOrdered))
+		if err != nil {
+			return money.Money{}, err
+		}
+		if total, err = total.Add(cost); err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	return total, nil
+}
+
+// Match - Match finds the line of the order each received product belongs to. A product is received at most once
+// per delivery and never beyond the units still to arrive.
+//
+// @param lines - the lines of the order
+// @param received - the products received
+// @return the index of the line of every product received
+// @return error
+func Match(lines []Line, received []ReceiveLine) ([]int, error) {
+	index := make(map[string]int, len(lines))
+	for i, line := range lines {
+		index[line.ProductId] = i
+	}
+
+	matched := make([]int, len(received))
+	seen := make(map[string]bool, len(received))
+	for i, r := range received {
+		j, ok := index[r.ProductId]
+		if !ok {
+			return nil, fmt.Errorf("%w: product %v is not on the order", ErrInvalidReceipt, r.ProductId)
+		}
+		if seen[r.ProductId] {
+			return nil, fmt.Errorf("%w: product %v is received twice", ErrInvalidReceipt, r.ProductId)
+		}
+		seen[r.ProductId] = true
+
+		if remaining := Remaining(&lines[j]); r.Quantity > remaining {
+			return nil, fmt.Errorf("%w: %v of product %v still to arrive", ErrOverReceipt, remaining, r.ProductId)
+		}
+		matched[i] = j
+	}
+
+	return matched, nil
+}
+
+// UnitsPerDay - UnitsPerDay works out the units of a product sold a day. More returns than sales count as none sold.
+//
+// @param sold - sales less returns over the days
+// @param days - int
+// @return units a day
+func UnitsPerDay(sold, days int) float64 {
+	if sold < 1 || days < 1 {
+		return 0
+	}
+
+	return float64(sold) / float64(days)
+}
+
+// Reorder - Reorder works out the units of a product to order. A product is reordered once its stock and the units
+// on order fall to its reorder point, or to what sells while waiting for the supplier when that is more. It is then
+// ordered up to its target stock plus what sells while waiting, and at least the minimum the supplier sells.
+//
+// @param suggestion - *Suggestion
+// @return the units to order, 0 when the product is not reordered
+func Reorder(suggestion *Suggestion) int {
+	demand := int(math.Ceil(suggestion.UnitsPerDay * float64(suggestion.LeadTimeDays)))
+	available := suggestion.Stock + suggestion.OnOrder
+	if available > suggestion.ReorderPoint && available > demand {
+		return 0
+	}
+
+	quantity := suggestion.TargetStock + demand - available
+	if quantity < 1 {
+		return 0
+	}
+	if quantity < suggestion.MinOrderQuantity {
+		quantity = suggestion.MinOrderQuantity
+	}
+
+	return quantity
+}
@@ -0,0 +1,165 @@
+package purchasing
+
+import (
+	"errors"
+	"testing"
+
+	"encore.app/pkg/money"
+)
+
+// TestProgress - test the status of an order from the units received
+//
+//	@param t - testing.T
+func TestProgress(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		received []int
+		status   string
+	}{
+		{received: []int{0, 0}, status: StatusSent},
+		{received: []int{4, 0}, status: StatusPartiallyReceived},
+		{received: []int{10, 3}, status: StatusPartiallyReceived},
+		{received: []int{10, 5}, status: StatusReceived},
+	}
+
+	for _, item := range slice {
+		lines := []Line{
+			{ProductId: "a", QuantityOrdered: 10, QuantityReceived: item.received[0]},
+			{ProductId: "b", QuantityOrdered: 5, QuantityReceived: item.received[1]},
+		}
+		if status := Progress(lines); status != item.status {
+			t.Errorf("%v received should make the order %v, got %v", item.received, item.status, status)
+		}
+	}
+}
+
+// TestAverageCost - test the moving average cost
+//
+//	@param t - testing.T
+func TestAverageCost(t *testing.T) {
+	usd := func(amount int64) money.Money { return money.Money{Amount: amount, Currency: "USD"} }
+
+	// the first units set the average
+	if average, err := AverageCost(money.Money{}, 0, usd(250), 4); err != nil || average != usd(250) {
+		t.Errorf("first units should set the average to 250, got %v %v", average, err)
+	}
+
+	// 10 at 1.00 and 30 at 2.00 average 1.75
+	if average, err := AverageCost(usd(100), 10, usd(200), 30); err != nil || average != usd(175) {
+		t.Errorf("average should be 175, got %v %v", average, err)
+	}
+
+	// 1 at 0.01 and 1 at 0.02 average 0.015, rounded half to even
+	if average, err := AverageCost(usd(1), 1, usd(2), 1); err != nil || average != usd(2) {
+		t.Errorf("average should round to 2, got %v %v", average, err)
+	}
+
+	if _, err := AverageCost(usd(100), 10, money.Money{Amount: 100, Currency: "EUR"}, 1); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("currencies should match, got %v", err)
+	}
+	if _, err := AverageCost(usd(100), 10, usd(100), 0); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("nothing received should fail, got %v", err)
+	}
+}
+
+// TestMarginOf - test the margin of a product over its cost
+//
+//	@param t - testing.T
+func TestMarginOf(t *testing.T) {
+	usd := func(amount int64) money.Money { return money.Money{Amount: amount, Currency: "USD"} }
+
+	margin, bps, err := MarginOf(usd(400), usd(300))
+	if err != nil || margin != usd(100) || bps != 2500 {
+		t.Errorf("margin should be 100 at 2500 bps, got %v %v %v", margin, bps, err)
+	}
+
+	margin, bps, err = MarginOf(usd(300), usd(400))
+	if err != nil || margin != usd(-100) || bps != -3333 {
+		t.Errorf("margin should be -100 at -3333 bps, got %v %v %v", margin, bps, err)
+	}
+
+	if _, bps, err = MarginOf(usd(0), usd(100)); err != nil || bps != 0 {
+		t.Errorf("a free product should have 0 bps, got %v %v", bps, err)
+	}
+}
+
+// TestMatch - test received products are matched to the lines of the order
+//
+//	@param t - testing.T
+func TestMatch(t *testing.T) {
+	lines := []Line{
+		{ProductId: "a", QuantityOrdered: 10, QuantityReceived: 4},
+		{ProductId: "b", QuantityOrdered: 5},
+	}
+
+	matched, err := Match(lines, []ReceiveLine{{ProductId: "b", Quantity: 5}, {ProductId: "a", Quantity: 6}})
+	if err != nil || len(matched) != 2 || matched[0] != 1 || matched[1] != 0 {
+		t.Errorf("products should match their lines, got %v %v", matched, err)
+	}
+
+	if _, err := Match(lines, []ReceiveLine{{ProductId: "a", Quantity: 7}}); !errors.Is(err, ErrOverReceipt) {
+		t.Errorf("more than remaining should fail, got %v", err)
+	}
+	if _, err := Match(lines, []ReceiveLine{{ProductId: "c", Quantity: 1}}); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("a product off the order should fail, got %v", err)
+	}
+	if _, err := Match(lines, []ReceiveLine{{ProductId: "b", Quantity: 1}, {ProductId: "b", Quantity: 1}}); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("a product received twice should fail, got %v", err)
+	}
+}
+
+// TestTotal - test the cost of an order
+//
+//	@param t - testing.T
+func TestTotal(t *testing.T) {
+	lines := []Line{
+		{QuantityOrdered: 3, UnitCost: money.Money{Amount: 150, Currency: "USD"}},
+		{QuantityOrdered: 2, UnitCost: money.Money{Amount: 25, Currency: "USD"}},
+	}
+	if total, err := Total("USD", lines); err != nil || total.Amount != 500 {
+		t.Errorf("total should be 500, got %v %v", total, err)
+	}
+	if total, err := Total("USD", nil); err != nil || total.Amount != 0 || total.Currency != "USD" {
+		t.Errorf("an empty order should cost nothing, got %v %v", total, err)
+	}
+}
+
+// TestReorder - test the units suggested to reorder
+//
+//	@param t - testing.T
+func TestReorder(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		name       string
+		suggestion Suggestion
+		quantity   int
+	}{
+		{name: "above the reorder point", suggestion: Suggestion{Stock: 11, ReorderPoint: 10, TargetStock: 50, MinOrderQuantity: 1}},
+		{name: "at the reorder point", suggestion: Suggestion{Stock: 10, ReorderPoint: 10, TargetStock: 50, MinOrderQuantity: 1}, quantity: 40},
+		{name: "on order counts", suggestion: Suggestion{Stock: 5, OnOrder: 20, ReorderPoint: 10, TargetStock: 50, MinOrderQuantity: 1}},
+		{name: "sales while waiting", suggestion: Suggestion{Stock: 20, ReorderPoint: 10, TargetStock: 50, UnitsPerDay: 2.5, LeadTimeDays: 10, MinOrderQuantity: 1}, quantity: 55},
+		{name: "minimum order", suggestion: Suggestion{Stock: 9, ReorderPoint: 10, TargetStock: 12, MinOrderQuantity: 24}, quantity: 24},
+		{name: "nothing wanted", suggestion: Suggestion{MinOrderQuantity: 6}},
+	}
+
+	for _, item := range slice {
+		if quantity := Reorder(&item.suggestion); quantity != item.quantity {
+			t.Errorf("%v should order %v, got %v", item.name, item.quantity, quantity)
+		}
+	}
+}
+
+// TestUnitsPerDay - test the sales velocity
+//
+//	@param t - testing.T
+func TestUnitsPerDay(t *testing.T) {
+	if v := UnitsPerDay(56, 28); v != 2 {
+		t.Errorf("56 over 28 days should be 2 a day, got %v", v)
+	}
+	if v := UnitsPerDay(-3, 28); v != 0 {
+		t.Errorf("more returns than sales should be 0 a day, got %v", v)
+	}
+	if v := UnitsPerDay(10, 0); v != 0 {
+		t.Errorf("no days should be 0 a day, got %v", v)
+	}
+}
@@ -0,0 +1,22 @@
+package purchasing
+
+import "errors"
+
+var (
+	ErrSupplierNotFound        = errors.New("supplier not found")
+	ErrContactNotFound         = errors.New("supplier contact not found")
+	ErrProductNotFound         = errors.New("product not found")
+	ErrSupplierProductNotFound = errors.New("the supplier does not supply the product")
+	ErrOrderNotFound           = errors.New("purchase order not found")
+	ErrReorderSettingNotFound  = errors.New("the product has no reorder setting")
+	ErrAlreadyExists           = errors.New("supplier already exists")
+	ErrSupplierInUse           = errors.New("the supplier has purchase orders")
+	ErrInactiveSupplier        = errors.New("the supplier is not active")
+	ErrInvalidTransition       = errors.New("invalid purchase order transition")
+	ErrNotEditable             = errors.New("only draft purchase orders can be changed")
+	ErrInvalidOrder            = errors.New("invalid purchase order")
+	ErrInvalidCurrency         = errors.New("unknown currency")
+	ErrInvalidReceipt          = errors.New("invalid purchase order receipt")
+	ErrOverReceipt             = errors.New("more units received than ordered")
+	ErrCurrencyMismatch        = errors.New("the cost is not in the currency of the supplier")
+)
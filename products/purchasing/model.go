@@ -0,0 +1,319 @@
+package purchasing
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+const (
+	StatusDraft             = "draft"              // being prepared, lines can change
+	StatusSent              = "sent"               // sent to the supplier
+	StatusPartiallyReceived = "partially_received" // some units arrived
+	StatusReceived          = "received"           // every unit arrived
+	StatusCancelled         = "cancelled"          // will not be received
+
+	ActionSend   = "send"
+	ActionCancel = "cancel"
+
+	// MaxLines - the most products on a purchase order
+	MaxLines = 500
+
+	// VelocityDays - the days of sales the velocity is worked out over
+	VelocityDays = 28
+	// VelocityBatchSize - the velocities saved by a statement
+	VelocityBatchSize = 1000
+)
+
+// Supplier - a company we buy stock from
+type Supplier struct {
+	Id           string    `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Email        string    `json:"email" db:"email"`
+	Phone        string    `json:"phone" db:"phone"`
+	Address      string    `json:"address" db:"address"`
+	LeadTimeDays int       `json:"leadTimeDays" db:"lead_time_days"` // the days between sending an order and receiving it
+	Currency     string    `json:"currency" db:"currency"`           // the currency of its prices and orders
+	Notes        string    `json:"notes" db:"notes"`
+	Active       bool      `json:"active" db:"active"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type SupplierRequest struct {
+	Name         string `json:"name" validate:"required,max=255"`
+	Email        string `json:"email" validate:"omitempty,email,max=255"`
+	Phone        string `json:"phone" validate:"max=255"`
+	Address      string `json:"address" validate:"max=2000"`
+	LeadTimeDays int    `json:"leadTimeDays" validate:"min=0,max=365"`
+	Currency     string `json:"currency" validate:"required,len=3"`
+	Notes        string `json:"notes" validate:"max=5000"`
+	Active       *bool  `json:"active"` // defaults to true
+}
+
+type SuppliersResponse struct {
+	Suppliers []Supplier `json:"data"`
+}
+
+// Contact - a person to talk to at a supplier
+type Contact struct {
+	Id         string    `json:"id" db:"id"`
+	SupplierId string    `json:"supplierId" db:"supplier_id"`
+	Name       string    `json:"name" db:"name"`
+	Role       string    `json:"role" db:"role"` // e.g. account manager
+	Email      string    `json:"email" db:"email"`
+	Phone      string    `json:"phone" db:"phone"`
+	IsPrimary  bool      `json:"isPrimary" db:"is_primary"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type ContactRequest struct {
+	Name      string `json:"name" validate:"required,max=255"`
+	Role      string `json:"role" validate:"max=255"`
+	Email     string `json:"email" validate:"omitempty,email,max=255"`
+	Phone     string `json:"phone" validate:"max=255"`
+	IsPrimary bool   `json:"isPrimary"`
+}
+
+// SupplierProduct - a product a supplier sells us and what it costs
+type SupplierProduct struct {
+	SupplierId       string      `json:"supplierId" db:"supplier_id"`
+	ProductId        string      `json:"productId" db:"product_id"`
+	SupplierSku      string      `json:"supplierSku" db:"supplier_sku"`
+	CostPrice        money.Money `json:"costPrice" db:"cost_price"`
+	LeadTimeDays     *int        `json:"leadTimeDays" db:"lead_time_days"` // overrides the lead time of the supplier
+	MinOrderQuantity int         `json:"minOrderQuantity" db:"min_order_quantity"`
+	CreatedAt        time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+type SupplierProductRequest struct {
+	SupplierSku      string      `json:"supplierSku" validate:"max=255"`
+	CostPrice        money.Money `json:"costPrice"`
+	LeadTimeDays     *int        `json:"leadTimeDays" validate:"omitempty,min=0,max=365"`
+	MinOrderQuantity int         `json:"minOrderQuantity" validate:"omitempty,min=1"` // defaults to 1
+}
+
+type SupplierResponse struct {
+	Supplier *Supplier         `json:"supplier"`
+	Contacts []Contact         `json:"contacts"`
+	Products []SupplierProduct `json:"products"`
+}
+
+// PurchaseOrder - stock ordered from a supplier
+type PurchaseOrder struct {
+	Id          string     `json:"id" db:"id"`
+	Number      int64      `json:"number" db:"number"` // shown to the supplier as PO-000042
+	SupplierId  string     `json:"supplierId" db:"supplier_id"`
+	Status      string     `json:"status" db:"status"`
+	Currency    string     `json:"currency" db:"currency"`
+	Notes       string     `json:"notes" db:"notes"`
+	ExpectedAt  *time.Time `json:"expectedAt" db:"expected_at"`
+	SentAt      *time.Time `json:"sentAt" db:"sent_at"`
+	ReceivedAt  *time.Time `json:"receivedAt" db:"received_at"`
+	CancelledAt *time.Time `json:"cancelledAt" db:"cancelled_at"`
+	CreatedBy   string     `json:"createdBy" db:"created_by"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// Line - a product on a purchase order
+type Line struct {
+	Id               string      `json:"id" db:"id"`
+	PurchaseOrderId  string      `json:"purchaseOrderId" db:"purchase_order_id"`
+	ProductId        string      `json:"productId" db:"product_id"`
+	QuantityOrdered  int         `json:"quantityOrdered" db:"quantity_ordered"`
+	QuantityReceived int         `json:"quantityReceived" db:"quantity_received"`
+	UnitCost         money.Money `json:"unitCost" db:"unit_cost"` // the cost agreed when the order was made
+	CreatedAt        time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+type LineRequest struct {
+	ProductId string       `json:"productId" validate:"required,uuid"`
+	Quantity  int          `json:"quantity" validate:"required,min=1"`
+	UnitCost  *money.Money `json:"unitCost"` // defaults to the cost price of the supplier
+}
+
+type OrderRequest struct {
+	SupplierId string        `json:"supplierId" validate:"required,uuid"`
+	Notes      string        `json:"notes" validate:"max=5000"`
+	ExpectedAt *time.Time    `json:"expectedAt"` // defaults to the lead time of the supplier once sent
+	Lines      []LineRequest `json:"lines" validate:"required,min=1,max=500,dive"`
+}
+
+type ActionRequest struct {
+	Action string `json:"action" validate:"required,oneof=send cancel"`
+}
+
+type ReceiveLine struct {
+	ProductId string       `json:"productId" validate:"required,uuid"`
+	Quantity  int          `json:"quantity" validate:"required,min=1"`
+	UnitCost  *money.Money `json:"unitCost"`                     // what a unit actually cost, defaults to the cost on the order
+	LotNumber string       `json:"lotNumber" validate:"max=100"` // the batch printed on the delivery, if any
+	ExpiresAt *time.Time   `json:"expiresAt"`                    // the best-before or use-by date of perishable units
+}
+
+type ReceiveRequest struct {
+	LocationId string        `json:"locationId" validate:"omitempty,uuid"` // where the delivery arrived, the default location when empty
+	Lines      []ReceiveLine `json:"lines" validate:"required,min=1,max=500,dive"`
+}
+
+// Receipt - units of a line taken into stock
+type Receipt struct {
+	Id              string      `json:"id" db:"id"`
+	PurchaseOrderId string      `json:"purchaseOrderId" db:"purchase_order_id"`
+	LineId          string      `json:"lineId" db:"line_id"`
+	ProductId       string      `json:"productId" db:"product_id"`
+	LocationId      string      `json:"locationId" db:"location_id"`
+	Quantity        int         `json:"quantity" db:"quantity"`
+	UnitCost        money.Money `json:"unitCost" db:"unit_cost"` // what a unit actually cost
+	LotNumber       string      `json:"lotNumber" db:"lot_number"`
+	ExpiresAt       *time.Time  `json:"expiresAt" db:"expires_at"`
+	MovementId      string      `json:"movementId" db:"movement_id"`
+	ReceivedBy      string      `json:"receivedBy" db:"received_by"`
+	ReceivedAt      time.Time   `json:"receivedAt" db:"received_at"`
+}
+
+type OrderResponse struct {
+	Order    *PurchaseOrder `json:"order"`
+	Lines    []Line         `json:"lines"`
+	Receipts []Receipt      `json:"receipts"`
+	Total    money.Money    `json:"total"` // the cost of the units ordered
+}
+
+type OrdersQuery struct {
+	SupplierId string `json:"supplierId" query:"supplierId" validate:"omitempty,uuid"`
+	Status     string `json:"status" query:"status" validate:"omitempty,oneof=draft sent partially_received received cancelled"`
+	Limit      int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page       int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter     string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. expectedAt<2024-06-01
+	Sort       string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -createdAt
+	Cursor     string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the orders
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedOrdersResponse struct {
+	Orders          []PurchaseOrder `json:"data"`
+	Total           int             `json:"total"`
+	TotalPages      int             `json:"totalPages"`
+	CurrentPage     int             `json:"currentPage"`
+	HasPreviousPage bool            `json:"hasPreviousPage"`
+	HasNextPage     bool            `json:"hasNextPage"`
+	PrevCursor      string          `json:"prevCursor"`
+	NextCursor      string          `json:"nextCursor"`
+}
+
+// Cost - the moving average cost of the stock of a product, in the catalog currency
+type Cost struct {
+	ProductId   string      `json:"productId" db:"product_id"`
+	Currency    string      `json:"currency" db:"currency"`
+	AverageCost money.Money `json:"averageCost" db:"average_cost"`
+	LastCost    money.Money `json:"lastCost" db:"last_cost"`
+	Quantity    int64       `json:"quantity" db:"quantity"` // the units on hand the average was last taken over
+	UpdatedAt   time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+// Margin - what a product sells for against what it cost
+type Margin struct {
+	ProductId   string       `json:"productId" db:"product_id"`
+	CategoryId  string       `json:"categoryId" db:"category_id"`
+	Name        string       `json:"name" db:"name"`
+	Price       money.Money  `json:"price" db:"price"`
+	AverageCost *money.Money `json:"averageCost" db:"average_cost"` // none until units are received in the currency of the price
+	Margin      *money.Money `json:"margin" db:"-"`                 // the price less the average cost
+	MarginBps   *int64       `json:"marginBps" db:"-"`              // the margin in basis points of the price
+}
+
+type MarginsQuery struct {
+	CategoryId string `json:"categoryId" query:"categoryId" validate:"omitempty,uuid"`
+	Limit      int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page       int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter     string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. name~milk
+	Sort       string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. name
+	Cursor     string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the products
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedMarginsResponse struct {
+	Margins         []Margin `json:"data"`
+	Total           int      `json:"total"`
+	TotalPages      int      `json:"totalPages"`
+	CurrentPage     int      `json:"currentPage"`
+	HasPreviousPage bool     `json:"hasPreviousPage"`
+	HasNextPage     bool     `json:"hasNextPage"`
+	PrevCursor      string   `json:"prevCursor"`
+	NextCursor      string   `json:"nextCursor"`
+}
+
+// ReorderSetting - when to reorder a product and how much stock to order up to
+type ReorderSetting struct {
+	ProductId    string    `json:"productId" db:"product_id"`
+	ReorderPoint int       `json:"reorderPoint" db:"reorder_point"` // reorder once stock and units on order fall to it
+	TargetStock  int       `json:"targetStock" db:"target_stock"`   // the stock to order up to
+	SupplierId   *string   `json:"supplierId" db:"supplier_id"`     // the supplier to order from, the quickest when empty
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type ReorderSettingRequest struct {
+	ReorderPoint int     `json:"reorderPoint" validate:"min=0"`
+	TargetStock  int     `json:"targetStock" validate:"gtefield=ReorderPoint"`
+	SupplierId   *string `json:"supplierId" validate:"omitempty,uuid"`
+}
+
+// Velocity - the units of a product sold a day over the last weeks
+type Velocity struct {
+	ProductId   string    `json:"productId" db:"product_id"`
+	UnitsSold   int       `json:"unitsSold" db:"units_sold"` // sales less returns
+	Days        int       `json:"days" db:"days"`
+	UnitsPerDay float64   `json:"unitsPerDay" db:"units_per_day"`
+	ComputedAt  time.Time `json:"computedAt" db:"computed_at"`
+}
+
+type VelocityResponse struct {
+	Products int `json:"products"` // the products a velocity was computed for
+}
+
+// Suggestion - units of a product to order to get back to its target stock
+type Suggestion struct {
+	ProductId        string       `json:"productId" db:"product_id"`
+	Name             string       `json:"name" db:"name"`
+	SupplierId       *string      `json:"supplierId" db:"supplier_id"` // none when no active supplier sells the product
+	Stock            int          `json:"stock" db:"stock_quantity"`
+	OnOrder          int          `json:"onOrder" db:"on_order"` // units on draft and sent orders still to arrive
+	ReorderPoint     int          `json:"reorderPoint" db:"reorder_point"`
+	TargetStock      int          `json:"targetStock" db:"target_stock"`
+	UnitsPerDay      float64      `json:"unitsPerDay" db:"units_per_day"`
+	LeadTimeDays     int          `json:"leadTimeDays" db:"lead_time_days"`
+	MinOrderQuantity int          `json:"minOrderQuantity" db:"min_order_quantity"`
+	UnitCost         *money.Money `json:"unitCost" db:"cost_price"`
+	Quantity         int          `json:"quantity" db:"-"` // the units to order
+}
+
+// SupplierSuggestions - what to order from a supplier
+type SupplierSuggestions struct {
+	Supplier    *Supplier    `json:"supplier"`
+	Suggestions []Suggestion `json:"suggestions"`
+	Total       money.Money  `json:"total"` // the cost of the units to order
+}
+
+type SuggestionsQuery struct {
+	SupplierId string `json:"supplierId" query:"supplierId" validate:"omitempty,uuid"`
+}
+
+type SuggestionsResponse struct {
+	Suppliers  []SupplierSuggestions `json:"data"`
+	Unassigned []Suggestion          `json:"unassigned"` // products to reorder that no active supplier sells
+}
+
+type DraftRequest struct {
+	SupplierId string `json:"supplierId" validate:"omitempty,uuid"` // every supplier when empty
+}
+
+type DraftResponse struct {
+	Orders []OrderResponse `json:"data"`
+}
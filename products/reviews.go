@@ -0,0 +1,252 @@
+package products
+
+import (
+	"context"
+	"errors"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/pkg/pagination"
+	"encore.app/products/reviews"
+)
+
+// =====================================================================================================================
+// REVIEWS
+// =====================================================================================================================
+
+// CreateReview - Review a product bought by the signed in user, the review is listed once a moderator approves it
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *reviews.ReviewRequest
+//	@return review
+//	@return error
+//
+// encore:api auth method=POST path=/products/:id/reviews
+func CreateReview(ctx context.Context, id string, payload *reviews.ReviewRequest) (*reviews.Review, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &reviews.Review{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &reviews.Review{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the review
+	review, err := reviews.Create(ctx, claims.Subject.Id, id, payload)
+	if err != nil {
+		return &reviews.Review{}, reviewError(err)
+	}
+
+	return review, nil
+}
+
+// ListReviews - List the approved reviews of a product with its rating summary
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param params - *reviews.ReviewsQuery
+//	@return reviews
+//	@return error
+//
+// encore:api public method=GET path=/products/:id/reviews
+func ListReviews(ctx context.Context, id string, params *reviews.ReviewsQuery) (*reviews.PaginatedReviewsResponse, error) {
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &reviews.PaginatedReviewsResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the reviews
+	response, err := reviews.ListApproved(ctx, id, params)
+	if err != nil {
+		return &reviews.PaginatedReviewsResponse{}, reviewError(err)
+	}
+
+	return response, nil
+}
+
+// UpdateReview - Replace the rating and text of a review of the signed in user, it waits for a moderator again
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *reviews.ReviewRequest
+//	@return review
+//	@return error
+//
+// encore:api auth method=PUT path=/reviews/:id
+func UpdateReview(ctx context.Context, id string, payload *reviews.ReviewRequest) (*reviews.Review, error) {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return &reviews.Review{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &reviews.Review{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// update the review
+	review, err := reviews.Update(ctx, claims.Subject.Id, id, payload)
+	if err != nil {
+		return &reviews.Review{}, reviewError(err)
+	}
+
+	return review, nil
+}
+
+// DeleteReview - Delete a review, the author and moderators can
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/reviews/:id
+func DeleteReview(ctx context.Context, id string) error {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// delete the review
+	moderator := claims.HasRole(middleware.RoleSuperAdmin, middleware.RoleAdmin, middleware.RoleModerator)
+	if err := reviews.Delete(ctx, claims.Subject.Id, id, moderator); err != nil {
+		return reviewError(err)
+	}
+
+	return nil
+}
+
+// ReportReview - Report a review for abuse, enough reports take it down until a moderator looks at it
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *reviews.ReportRequest
+//	@return error
+//
+// encore:api auth method=POST path=/reviews/:id/report
+func ReportReview(ctx context.Context, id string, payload *reviews.ReportRequest) error {
+	// check for claims
+	claims, err := middleware.GetVerifiedClaims(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// report the review
+	if err := reviews.Report(ctx, claims.Subject.Id, id, payload); err != nil {
+		return reviewError(err)
+	}
+
+	return nil
+}
+
+// ListModerationQueue - List the reviews waiting for a moderator, the most reported first
+//
+//	@param ctx - context.Context
+//	@param params - *reviews.QueueQuery
+//	@return reviews
+//	@return error
+//
+// encore:api auth method=GET path=/reviews/moderation
+func ListModerationQueue(ctx context.Context, params *reviews.QueueQuery) (*reviews.PaginatedQueueResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin, middleware.RoleModerator); err != nil {
+		return &reviews.PaginatedQueueResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &reviews.PaginatedQueueResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the queue
+	response, err := reviews.Queue(ctx, params)
+	if err != nil {
+		return &reviews.PaginatedQueueResponse{}, reviewError(err)
+	}
+
+	return response, nil
+}
+
+// ModerateReview - Approve, reject or flag a review
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *reviews.ModerateRequest
+//	@return review
+//	@return error
+//
+// encore:api auth method=POST path=/reviews/:id/moderate
+func ModerateReview(ctx context.Context, id string, payload *reviews.ModerateRequest) (*reviews.Review, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin, middleware.RoleModerator)
+	if err != nil {
+		return &reviews.Review{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &reviews.Review{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// moderate the review
+	review, err := reviews.Moderate(ctx, claims.Subject.Id, id, payload)
+	if err != nil {
+		return &reviews.Review{}, reviewError(err)
+	}
+
+	return review, nil
+}
+
+// RecordPurchases - Record the products bought by a user so they can review them, called by the checkout
+//
+//	@param ctx - context.Context
+//	@param payload - *reviews.PurchaseRequest
+//	@return error
+//
+// encore:api private method=POST path=/reviews/purchases
+func RecordPurchases(ctx context.Context, payload *reviews.PurchaseRequest) error {
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// record the purchases
+	if err := reviews.RecordPurchases(ctx, payload); err != nil {
+		return reviewError(err)
+	}
+
+	return nil
+}
+
+// reviewError - maps review store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func reviewError(err error) error {
+	switch {
+	case errors.Is(err, reviews.ErrNotFound), errors.Is(err, reviews.ErrProductNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, reviews.ErrAlreadyExists), errors.Is(err, reviews.ErrAlreadyReported):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, reviews.ErrNotPurchased), errors.Is(err, reviews.ErrForbidden):
+		return &errs.Error{Code: errs.PermissionDenied, Message: err.Error()}
+	case pagination.IsQueryError(err):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, reviews.ErrInvalidTransition):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,563 @@
+package reviews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/pagination"
+)
+
+// the products database
+var reviewsDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// lock - gets a review and locks it until the end of the transaction.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param id - string
+// @return review
+// @return error
+func lock(ctx context.Context, tx *sqlx.Tx, id string) (*Review, error) {
+	review := &Review{}
+	if err := database.NamedStructQuery(ctx, tx, "SELECT * FROM product_reviews WHERE id = :id FOR UPDATE", map[string]interface{}{
+		"id": id,
+	}, review); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("selecting review: %w", err)
+	}
+
+	return review, nil
+}
+
+// refresh - works out the rating of a product again from its approved reviews.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param productId - string
+// @return error
+func refresh(ctx context.Context, tx *sqlx.Tx, productId string) error {
+	// query statement to be executed
+	q := `
+    UPDATE products SET rating_count = s.count, rating_average = s.average
+    FROM (
+      SELECT COUNT(*) AS count, COALESCE(CAST(ROUND(AVG(rating), 2) AS DOUBLE PRECISION), 0) AS average
+      FROM product_reviews
+      WHERE product_id = :product_id AND status = 'approved'
+    ) s
+    WHERE id = :product_id
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, tx, q, map[string]interface{}{"product_id": productId}); err != nil {
+		return fmt.Errorf("updating product rating: %w", err)
+	}
+
+	return nil
+}
+
+// RecordPurchases - RecordPurchases is a function that records the products bought by a user, so they can review them.
+// Recording the same purchase again changes nothing.
+//
+// @param ctx - context.Context
+// @param payload - *PurchaseRequest
+// @return error
+func RecordPurchases(ctx context.Context, payload *PurchaseRequest) error {
+	now := time.Now().UTC()
+	seen := make(map[string]bool, len(payload.ProductIds))
+	purchases := make([]Purchase, 0, len(payload.ProductIds))
+	for _, productId := range payload.ProductIds {
+		if !seen[productId] {
+			seen[productId] = true
+			purchases = append(purchases, Purchase{UserId: payload.UserId, ProductId: productId, Reference: payload.Reference, PurchasedAt: now})
+		}
+	}
+
+	return database.Transaction(ctx, reviewsDatabase(), func(tx *sqlx.Tx) error {
+		// check if the products exist
+		ids := make([]string, 0, len(purchases))
+		for _, purchase := range purchases {
+			ids = append(ids, purchase.ProductId)
+		}
+		count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM products WHERE id = ANY(CAST(:ids AS UUID[]))", map[string]interface{}{"ids": ids})
+		if err != nil {
+			return fmt.Errorf("counting products: %w", err)
+		}
+		if count < len(ids) {
+			return ErrProductNotFound
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO product_purchases (user_id, product_id, reference, purchased_at)
+      VALUES (:user_id, :product_id, :reference, :purchased_at)
+      ON CONFLICT (user_id, product_id, reference) DO NOTHING
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, purchases); err != nil {
+			return fmt.Errorf("inserting purchases: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Create - Create is a function that creates the review of a product, it waits for a moderator before it is listed.
+//
+// @param ctx - context.Context
+// @param userId - the author
+// @param productId - string
+// @param payload - *ReviewRequest
+// @return review
+// @return error
+func Create(ctx context.Context, userId, productId string, payload *ReviewRequest) (*Review, error) {
+	now := time.Now().UTC()
+	review := &Review{
+		Id:        uuid.New().String(),
+		ProductId: productId,
+		UserId:    userId,
+		Rating:    payload.Rating,
+		Title:     payload.Title,
+		Body:      payload.Body,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// check if the product exists
+	count, err := database.NamedCountQuery(ctx, reviewsDatabase(), "SELECT COUNT(*) FROM products WHERE id = :product_id", review)
+	if err != nil {
+		return nil, fmt.Errorf("counting products: %w", err)
+	}
+	if count < 1 {
+		return nil, ErrProductNotFound
+	}
+
+	// check if the user bought the product
+	count, err = database.NamedCountQuery(ctx, reviewsDatabase(), "SELECT COUNT(*) FROM product_purchases WHERE user_id = :user_id AND product_id = :product_id", review)
+	if err != nil {
+		return nil, fmt.Errorf("counting purchases: %w", err)
+	}
+	if count < 1 {
+		return nil, ErrNotPurchased
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO product_reviews (id, product_id, user_id, rating, title, body, status, created_at, updated_at)
+    VALUES (:id, :product_id, :user_id, :rating, :title, :body, :status, :created_at, :updated_at)
+    ON CONFLICT (product_id, user_id) DO NOTHING
+    RETURNING *
+  `
+
+	// execute query, a user reviews a product once
+	if err := database.NamedStructQuery(ctx, reviewsDatabase(), q, review, review); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, fmt.Errorf("inserting review: %w", err)
+	}
+
+	return review, nil
+}
+
+// Update - Update is a function that replaces the rating and text of a review, only the author can.
+// The review waits for a moderator again.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @param payload - *ReviewRequest
+// @return review
+// @return error
+func Update(ctx context.Context, userId, id string, payload *ReviewRequest) (*Review, error) {
+	var review *Review
+	err := database.Transaction(ctx, reviewsDatabase(), func(tx *sqlx.Tx) error {
+		var err error
+		if review, err = lock(ctx, tx, id); err != nil {
+			return err
+		}
+		if review.UserId != userId {
+			return fmt.Errorf("%w: only the author can change it", ErrForbidden)
+		}
+		wasApproved := review.Status == StatusApproved
+
+		// query statement to be executed
+		q := `
+      UPDATE product_reviews SET
+        rating = :rating, title = :title, body = :body, status = :status,
+        moderated_by = NULL, moderated_at = NULL, moderation_note = '', updated_at = :updated_at
+      WHERE id = :id
+      RETURNING *
+    `
+
+		// execute query
+		if err := database.NamedStructQuery(ctx, tx, q, map[string]interface{}{
+			"id":         id,
+			"rating":     payload.Rating,
+			"title":      payload.Title,
+			"body":       payload.Body,
+			"status":     StatusPending,
+			"updated_at": time.Now().UTC(),
+		}, review); err != nil {
+			return fmt.Errorf("updating review: %w", err)
+		}
+
+		// the review is no longer counted
+		if wasApproved {
+			return refresh(ctx, tx, review.ProductId)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// Delete - Delete is a function that deletes a review, the author and moderators can.
+//
+// @param ctx - context.Context
+// @param userId - string
+// @param id - string
+// @param moderator - whether the user is a moderator
+// @return error
+func Delete(ctx context.Context, userId, id string, moderator bool) error {
+	return database.Transaction(ctx, reviewsDatabase(), func(tx *sqlx.Tx) error {
+		review, err := lock(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if review.UserId != userId && !moderator {
+			return fmt.Errorf("%w: only the author or a moderator can delete it", ErrForbidden)
+		}
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, "DELETE FROM product_reviews WHERE id = :id", review); err != nil {
+			return fmt.Errorf("deleting review: %w", err)
+		}
+
+		if review.Status == StatusApproved {
+			return refresh(ctx, tx, review.ProductId)
+		}
+
+		return nil
+	})
+}
+
+// DeleteUser - DeleteUser is a function that removes the reviews, reports and purchases of a deleted user, and
+// works out the rating of the products they had reviewed again.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - string
+// @return the rows removed, by what they were
+// @return error
+func DeleteUser(ctx context.Context, tx *sqlx.Tx, userId string) (map[string]int, error) {
+	data := map[string]interface{}{"user_id": userId}
+
+	// remove the reviews, keeping the products whose rating counted them
+	removed := make([]Review, 0)
+	if err := database.NamedSliceQuery(ctx, tx, "DELETE FROM product_reviews WHERE user_id = :user_id RETURNING *", data, &removed); err != nil {
+		return nil, fmt.Errorf("deleting reviews: %w", err)
+	}
+	for _, review := range removed {
+		if review.Status == StatusApproved {
+			if err := refresh(ctx, tx, review.ProductId); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// remove the reports of the user from the reviews of others
+	reports, err := database.NamedCountQuery(ctx, tx, `
+    WITH removed AS (DELETE FROM product_review_reports WHERE user_id = :user_id RETURNING review_id),
+    updated AS (
+      UPDATE product_reviews r SET reports = GREATEST(r.reports - 1, 0)
+      FROM removed WHERE r.id = removed.review_id
+      RETURNING r.id
+    )
+    SELECT COUNT(*) FROM removed
+  `, data)
+	if err != nil {
+		return nil, fmt.Errorf("deleting review reports: %w", err)
+	}
+
+	purchases, err := database.NamedCountQuery(ctx, tx, `
+    WITH removed AS (DELETE FROM product_purchases WHERE user_id = :user_id RETURNING product_id)
+    SELECT COUNT(*) FROM removed
+  `, data)
+	if err != nil {
+		return nil, fmt.Errorf("deleting purchases: %w", err)
+	}
+
+	return map[string]int{"reviews": len(removed), "reviewReports": reports, "purchases": purchases}, nil
+}
+
+// Moderate - Moderate is a function that approves, rejects or flags a review.
+//
+// @param ctx - context.Context
+// @param moderatorId - string
+// @param id - string
+// @param payload - *ModerateRequest
+// @return review
+// @return error
+func Moderate(ctx context.Context, moderatorId, id string, payload *ModerateRequest) (*Review, error) {
+	var review *Review
+	err := database.Transaction(ctx, reviewsDatabase(), func(tx *sqlx.Tx) error {
+		var err error
+		if review, err = lock(ctx, tx, id); err != nil {
+			return err
+		}
+		status, err := Transition(review.Status, payload.Action)
+		if err != nil {
+			return err
+		}
+		counted := review.Status == StatusApproved || status == StatusApproved
+
+		// query statement to be executed, approving a review clears its reports
+		q := `
+      UPDATE product_reviews SET
+        status = :status, moderated_by = :moderated_by, moderated_at = :now, moderation_note = :note,
+        reports = CASE WHEN :status = 'approved' THEN 0 ELSE reports END, updated_at = :now
+      WHERE id = :id
+      RETURNING *
+    `
+
+		// execute query
+		if err := database.NamedStructQuery(ctx, tx, q, map[string]interface{}{
+			"id":           id,
+			"status":       status,
+			"moderated_by": moderatorId,
+			"note":         payload.Note,
+			"now":          time.Now().UTC(),
+		}, review); err != nil {
+			return fmt.Errorf("moderating review: %w", err)
+		}
+
+		// the reports are cleared with the review
+		if status == StatusApproved {
+			if err := database.NamedExecQuery(ctx, tx, "DELETE FROM product_review_reports WHERE review_id = :id", review); err != nil {
+				return fmt.Errorf("deleting review reports: %w", err)
+			}
+		}
+
+		if counted {
+			return refresh(ctx, tx, review.ProductId)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// Report - Report is a function that reports a listed review for abuse. Enough reports take the review
+// down until a moderator looks at it.
+//
+// @param ctx - context.Context
+// @param userId - the user reporting
+// @param id - string
+// @param payload - *ReportRequest
+// @return error
+func Report(ctx context.Context, userId, id string, payload *ReportRequest) error {
+	return database.Transaction(ctx, reviewsDatabase(), func(tx *sqlx.Tx) error {
+		review, err := lock(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		// only listed reviews can be seen and reported
+		if review.Status != StatusApproved {
+			return ErrNotFound
+		}
+		if review.UserId == userId {
+			return fmt.Errorf("%w: the author cannot report their review", ErrForbidden)
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO product_review_reports (review_id, user_id, reason, comment, created_at)
+      VALUES (:review_id, :user_id, :reason, :comment, :created_at)
+      ON CONFLICT (review_id, user_id) DO NOTHING
+      RETURNING review_id
+    `
+
+		// execute query, a user reports a review once
+		var report struct {
+			ReviewId string `db:"review_id"`
+		}
+		if err := database.NamedStructQuery(ctx, tx, q, map[string]interface{}{
+			"review_id":  id,
+			"user_id":    userId,
+			"reason":     payload.Reason,
+			"comment":    payload.Comment,
+			"created_at": time.Now().UTC(),
+		}, &report); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return ErrAlreadyReported
+			}
+			return fmt.Errorf("inserting review report: %w", err)
+		}
+
+		// count the report
+		review.Reports++
+		review.Status = Reported(review.Status, review.Reports)
+		if err := database.NamedExecQuery(ctx, tx, "UPDATE product_reviews SET reports = :reports, status = :status WHERE id = :id", review); err != nil {
+			return fmt.Errorf("updating review: %w", err)
+		}
+
+		// the review is no longer counted
+		if review.Status != StatusApproved {
+			return refresh(ctx, tx, review.ProductId)
+		}
+
+		return nil
+	})
+}
+
+// reviewsSchema - the fields of a review allowed in list queries
+var reviewsSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "productId", Column: "product_id", Type: pagination.UUID},
+		{Name: "userId", Column: "user_id", Type: pagination.UUID},
+		{Name: "rating", Column: "rating", Type: pagination.Number, Sortable: true},
+		{Name: "status", Column: "status", Type: pagination.String},
+		{Name: "reports", Column: "reports", Type: pagination.Number, Sortable: true},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-createdAt",
+}
+
+// queueSchema - the fields of a review allowed in moderation queue queries, the most reported first
+var queueSchema = &pagination.Schema{
+	Fields:      reviewsSchema.Fields,
+	Key:         "id",
+	DefaultSort: "-reports,createdAt",
+}
+
+// ListApproved - ListApproved is a function that lists the approved reviews of a product, newest first unless sorted
+// otherwise, with its rating summary.
+//
+// @param ctx - context.Context
+// @param productId - string
+// @param params - *ReviewsQuery
+// @return reviews
+// @return error
+func ListApproved(ctx context.Context, productId string, params *ReviewsQuery) (*PaginatedReviewsResponse, error) {
+	data := map[string]interface{}{"product_id": productId}
+
+	// check if the product exists
+	count, err := database.NamedCountQuery(ctx, reviewsDatabase(), "SELECT COUNT(*) FROM products WHERE id = :product_id", data)
+	if err != nil {
+		return nil, fmt.Errorf("counting products: %w", err)
+	}
+	if count < 1 {
+		return nil, ErrProductNotFound
+	}
+
+	// count the reviews per rating
+	buckets := make([]Bucket, 0, 5)
+	if err := database.NamedSliceQuery(ctx, reviewsDatabase(), `
+    SELECT rating, COUNT(*) AS count FROM product_reviews
+    WHERE product_id = :product_id AND status = 'approved'
+    GROUP BY rating
+  `, data, &buckets); err != nil {
+		return nil, fmt.Errorf("counting reviews: %w", err)
+	}
+	summary := Summarize(buckets)
+
+	// parse the filter and sort
+	statement, err := reviewsSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	statement.AddCondition("product_id = CAST(:product_id AS UUID) AND status = 'approved'", data)
+
+	// execute query
+	reviews := make([]Review, 0)
+	page, err := database.NamedPageQuery(ctx, reviewsDatabase(), "product_reviews", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &reviews)
+	if err != nil {
+		return nil, fmt.Errorf("selecting reviews: %w", err)
+	}
+
+	return &PaginatedReviewsResponse{
+		Summary:         summary,
+		Reviews:         reviews,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// Queue - Queue is a function that lists the reviews waiting for a moderator, the most reported first unless sorted
+// otherwise.
+//
+// @param ctx - context.Context
+// @param params - *QueueQuery
+// @return reviews
+// @return error
+func Queue(ctx context.Context, params *QueueQuery) (*PaginatedQueueResponse, error) {
+	statuses := []string{StatusPending, StatusFlagged}
+	if len(params.Status) > 0 {
+		statuses = []string{params.Status}
+	}
+
+	// parse the filter and sort
+	statement, err := queueSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	statement.AddCondition("status = ANY(CAST(:statuses AS TEXT[]))", map[string]interface{}{"statuses": statuses})
+
+	// execute query
+	reviews := make([]Review, 0)
+	page, err := database.NamedPageQuery(ctx, reviewsDatabase(), "product_reviews", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &reviews)
+	if err != nil {
+		return nil, fmt.Errorf("selecting reviews: %w", err)
+	}
+
+	return &PaginatedQueueResponse{
+		Reviews:         reviews,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
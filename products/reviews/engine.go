@@ -0,0 +1,68 @@
+package reviews
+
+import (
+	"math"
+
+	"encore.app/pkg/lifecycle"
+)
+
+// moderation - the status a moderation action moves a review to, by the status it is in
+var moderation = &lifecycle.Machine{
+	Noun: "review",
+	Transitions: map[string]map[string]string{
+		StatusPending:  {ActionApprove: StatusApproved, ActionReject: StatusRejected, ActionFlag: StatusFlagged},
+		StatusFlagged:  {ActionApprove: StatusApproved, ActionReject: StatusRejected},
+		StatusApproved: {ActionReject: StatusRejected, ActionFlag: StatusFlagged},
+		StatusRejected: {ActionApprove: StatusApproved},
+	},
+	Err: ErrInvalidTransition,
+}
+
+// Transition - Transition returns the status a moderation action moves a review to.
+//
+// @param status - the status of the review
+// @param action - approve, reject or flag
+// @return status
+// @return error
+func Transition(status, action string) (string, error) {
+	return moderation.Next(status, action)
+}
+
+// Reported - Reported returns the status of a review once it has a number of reports. An approved review
+// with enough reports is flagged for a moderator, moderators decide on every other review.
+//
+// @param status - the status of the review
+// @param reports - the reports of the review
+// @return status
+func Reported(status string, reports int) string {
+	if status == StatusApproved && reports >= ReportThreshold {
+		return StatusFlagged
+	}
+
+	return status
+}
+
+// Summarize - Summarize works out the rating summary of a product from the number of reviews per rating.
+//
+// @param buckets - []Bucket
+// @return summary
+func Summarize(buckets []Bucket) *Summary {
+	counts := make(map[int]int, 5)
+	for _, bucket := range buckets {
+		counts[bucket.Rating] += bucket.Count
+	}
+
+	// count every rating, highest first
+	summary := &Summary{Distribution: make([]Bucket, 0, 5)}
+	total := 0
+	for rating := 5; rating >= 1; rating-- {
+		summary.Distribution = append(summary.Distribution, Bucket{Rating: rating, Count: counts[rating]})
+		summary.Count += counts[rating]
+		total += rating * counts[rating]
+	}
+	if summary.Count > 0 {
+		summary.Average = math.Round(float64(total)*100/float64(summary.Count)) / 100
+	}
+
+	return summary
+}
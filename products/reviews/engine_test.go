@@ -0,0 +1,35 @@
+package reviews
+
+import "testing"
+
+// TestReported - test reports flag approved reviews
+//
+//	@param t - testing.T
+func TestReported(t *testing.T) {
+	if status := Reported(StatusApproved, ReportThreshold-1); status != StatusApproved {
+		t.Errorf("should stay approved under the threshold, got %v", status)
+	}
+	if status := Reported(StatusApproved, ReportThreshold); status != StatusFlagged {
+		t.Errorf("should be flagged at the threshold, got %v", status)
+	}
+	if status := Reported(StatusRejected, ReportThreshold); status != StatusRejected {
+		t.Errorf("rejected reviews should stay rejected, got %v", status)
+	}
+}
+
+// TestSummarize - test the rating summary of a product
+//
+//	@param t - testing.T
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]Bucket{{Rating: 5, Count: 2}, {Rating: 4, Count: 1}})
+	if summary.Count != 3 || summary.Average != 4.67 {
+		t.Errorf("should average 4.67 over 3 reviews, got %v over %v", summary.Average, summary.Count)
+	}
+	if len(summary.Distribution) != 5 || summary.Distribution[0].Count != 2 || summary.Distribution[4].Count != 0 {
+		t.Errorf("should count every rating, got %+v", summary.Distribution)
+	}
+
+	if empty := Summarize(nil); empty.Count != 0 || empty.Average != 0 || len(empty.Distribution) != 5 {
+		t.Errorf("no reviews should average 0, got %+v", empty)
+	}
+}
@@ -0,0 +1,13 @@
+package reviews
+
+import "errors"
+
+var (
+	ErrNotFound          = errors.New("review not found")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrAlreadyExists     = errors.New("review already exists")
+	ErrAlreadyReported   = errors.New("review already reported")
+	ErrNotPurchased      = errors.New("only buyers of the product can review it")
+	ErrForbidden         = errors.New("not allowed to change the review")
+	ErrInvalidTransition = errors.New("invalid moderation")
+)
@@ -0,0 +1,122 @@
+package reviews
+
+import (
+	"time"
+)
+
+const (
+	StatusPending  = "pending"  // waiting for a moderator
+	StatusApproved = "approved" // listed and counted in the rating of the product
+	StatusRejected = "rejected" // hidden
+	StatusFlagged  = "flagged"  // hidden until a moderator looks at it again
+
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionFlag    = "flag"
+
+	// ReportThreshold - the reports that take an approved review down for a moderator to look at
+	ReportThreshold = 3
+)
+
+type Review struct {
+	Id             string     `json:"id" db:"id"`
+	ProductId      string     `json:"productId" db:"product_id"`
+	UserId         string     `json:"userId" db:"user_id"`
+	Rating         int        `json:"rating" db:"rating"`
+	Title          string     `json:"title" db:"title"`
+	Body           string     `json:"body" db:"body"`
+	Status         string     `json:"status" db:"status"`
+	Reports        int        `json:"reports" db:"reports"` // the abuse reports of other users
+	ModeratedBy    *string    `json:"moderatedBy" db:"moderated_by"`
+	ModeratedAt    *time.Time `json:"moderatedAt" db:"moderated_at"`
+	ModerationNote string     `json:"moderationNote" db:"moderation_note"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Title  string `json:"title" validate:"required,max=255"`
+	Body   string `json:"body" validate:"omitempty,max=10000"`
+}
+
+type ModerateRequest struct {
+	Action string `json:"action" validate:"required,oneof=approve reject flag"`
+	Note   string `json:"note" validate:"omitempty,max=1000"`
+}
+
+type ReportRequest struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam offensive off_topic fake other"`
+	Comment string `json:"comment" validate:"omitempty,max=1000"`
+}
+
+type PurchaseRequest struct {
+	UserId     string   `json:"userId" validate:"required,uuid"`
+	ProductIds []string `json:"productIds" validate:"required,min=1,max=200,dive,uuid"`
+	Reference  string   `json:"reference" validate:"required,max=255"` // e.g. the order id
+}
+
+// Purchase - a product bought by a user
+type Purchase struct {
+	UserId      string    `json:"userId" db:"user_id"`
+	ProductId   string    `json:"productId" db:"product_id"`
+	Reference   string    `json:"reference" db:"reference"`
+	PurchasedAt time.Time `json:"purchasedAt" db:"purchased_at"`
+}
+
+// Bucket - the number of approved reviews with a rating
+type Bucket struct {
+	Rating int `json:"rating" db:"rating"`
+	Count  int `json:"count" db:"count"`
+}
+
+// Summary - the ratings of the approved reviews of a product
+type Summary struct {
+	Average      float64  `json:"average"` // rounded to 2 decimals
+	Count        int      `json:"count"`
+	Distribution []Bucket `json:"distribution"` // every rating from 5 to 1
+}
+
+type ReviewsQuery struct {
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page   int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. rating>=4
+	Sort   string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -rating
+	Cursor string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the reviews
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedReviewsResponse struct {
+	Summary         *Summary `json:"summary"`
+	Reviews         []Review `json:"data"`
+	Total           int      `json:"total"`
+	TotalPages      int      `json:"totalPages"`
+	CurrentPage     int      `json:"currentPage"`
+	HasPreviousPage bool     `json:"hasPreviousPage"`
+	HasNextPage     bool     `json:"hasNextPage"`
+	PrevCursor      string   `json:"prevCursor"`
+	NextCursor      string   `json:"nextCursor"`
+}
+
+type QueueQuery struct {
+	Status string `json:"status" query:"status" validate:"omitempty,oneof=pending flagged"` // both when empty
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=0"`                   // the number of items
+	Page   int    `json:"page" query:"page" validate:"omitempty,min=0"`                     // the page
+	Filter string `json:"filter" query:"filter" validate:"omitempty"`                       // e.g. rating<=2
+	Sort   string `json:"sort" query:"sort" validate:"omitempty"`                           // e.g. -reports,createdAt
+	Cursor string `json:"cursor" query:"cursor" validate:"omitempty"`                       // the position to continue from, instead of the page
+	// SkipTotal - skips counting the reviews
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedQueueResponse struct {
+	Reviews         []Review `json:"data"`
+	Total           int      `json:"total"`
+	TotalPages      int      `json:"totalPages"`
+	CurrentPage     int      `json:"currentPage"`
+	HasPreviousPage bool     `json:"hasPreviousPage"`
+	HasNextPage     bool     `json:"hasNextPage"`
+	PrevCursor      string   `json:"prevCursor"`
+	NextCursor      string   `json:"nextCursor"`
+}
@@ -0,0 +1,216 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/products/stocktakes"
+)
+
+// =====================================================================================================================
+// STOCKTAKES
+// =====================================================================================================================
+
+// ListStocktakes - List stocktakes, the newest first
+//
+//	@param ctx - context.Context
+//	@param params - *stocktakes.StocktakesQuery
+//	@return stocktakes
+//	@return error
+//
+// encore:api auth method=GET path=/stocktakes
+func ListStocktakes(ctx context.Context, params *stocktakes.StocktakesQuery) (*stocktakes.PaginatedStocktakesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &stocktakes.PaginatedStocktakesResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &stocktakes.PaginatedStocktakesResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the stocktakes
+	response, err := stocktakes.List(ctx, params)
+	if err != nil {
+		return &stocktakes.PaginatedStocktakesResponse{}, stocktakeError(err)
+	}
+
+	return response, nil
+}
+
+// GetStocktake - Get a stocktake with its lines and their variances
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return stocktake
+//	@return error
+//
+// encore:api auth method=GET path=/stocktakes/:id
+func GetStocktake(ctx context.Context, id string) (*stocktakes.StocktakeResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &stocktakes.StocktakeResponse{}, err
+	}
+
+	// get the stocktake
+	stocktake, err := stocktakes.Get(ctx, id)
+	if err != nil {
+		return &stocktakes.StocktakeResponse{}, stocktakeError(err)
+	}
+
+	return stocktake, nil
+}
+
+// CreateStocktake - Start counting the stock at a location, of one category or of every product stocked there
+//
+//	@param ctx - context.Context
+//	@param payload - *stocktakes.StocktakeRequest
+//	@return stocktake
+//	@return error
+//
+// encore:api auth method=POST path=/stocktakes
+func CreateStocktake(ctx context.Context, payload *stocktakes.StocktakeRequest) (*stocktakes.StocktakeResponse, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &stocktakes.StocktakeResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &stocktakes.StocktakeResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// start the stocktake
+	stocktake, err := stocktakes.Create(ctx, claims.Subject.Id, payload)
+	if err != nil {
+		return &stocktakes.StocktakeResponse{}, stocktakeError(err)
+	}
+
+	return stocktake, nil
+}
+
+// RecordCounts - Record units counted by product or by barcode, replacing or adding to those counted so far
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *stocktakes.CountRequest
+//	@return stocktake
+//	@return error
+//
+// encore:api auth method=POST path=/stocktakes/:id/counts
+func RecordCounts(ctx context.Context, id string, payload *stocktakes.CountRequest) (*stocktakes.StocktakeResponse, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &stocktakes.StocktakeResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &stocktakes.StocktakeResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// record the counts
+	stocktake, err := stocktakes.Record(ctx, claims.Subject.Id, id, payload, time.Now().UTC())
+	if err != nil {
+		return &stocktakes.StocktakeResponse{}, stocktakeError(err)
+	}
+
+	return stocktake, nil
+}
+
+// ExplainVariance - Give the reason a product was counted differently from the system stock
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param productId - string
+//	@param payload - *stocktakes.ExplainRequest
+//	@return stocktake
+//	@return error
+//
+// encore:api auth method=PUT path=/stocktakes/:id/lines/:productId
+func ExplainVariance(ctx context.Context, id, productId string, payload *stocktakes.ExplainRequest) (*stocktakes.StocktakeResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &stocktakes.StocktakeResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &stocktakes.StocktakeResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// explain the variance
+	stocktake, err := stocktakes.Explain(ctx, id, productId, payload)
+	if err != nil {
+		return &stocktakes.StocktakeResponse{}, stocktakeError(err)
+	}
+
+	return stocktake, nil
+}
+
+// ActOnStocktake - Submit the counts of a stocktake, approve them to post the variances to the stock, send them
+// back to be counted again, or cancel the stocktake. Only a super admin approves or sends counts back.
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *stocktakes.ActionRequest
+//	@return stocktake
+//	@return error
+//
+// encore:api auth method=POST path=/stocktakes/:id/actions
+func ActOnStocktake(ctx context.Context, id string, payload *stocktakes.ActionRequest) (*stocktakes.StocktakeResponse, error) {
+	// check for the roles
+	claims, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin)
+	if err != nil {
+		return &stocktakes.StocktakeResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &stocktakes.StocktakeResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// only a manager reviews the counts
+	if payload.Action == stocktakes.ActionApprove || payload.Action == stocktakes.ActionReject {
+		if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin); err != nil {
+			return &stocktakes.StocktakeResponse{}, err
+		}
+	}
+
+	// act on the stocktake
+	stocktake, err := stocktakes.Act(ctx, claims.Subject.Id, id, payload, time.Now())
+	if err != nil {
+		return &stocktakes.StocktakeResponse{}, stocktakeError(err)
+	}
+
+	return stocktake, nil
+}
+
+// stocktakeError - maps stocktake store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func stocktakeError(err error) error {
+	switch {
+	case errors.Is(err, stocktakes.ErrNotFound), errors.Is(err, stocktakes.ErrLineNotFound),
+		errors.Is(err, stocktakes.ErrLocationNotFound), errors.Is(err, stocktakes.ErrCategoryNotFound),
+		errors.Is(err, stocktakes.ErrProductNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, stocktakes.ErrInvalidCount):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, stocktakes.ErrInactive), errors.Is(err, stocktakes.ErrOverlapping),
+		errors.Is(err, stocktakes.ErrNotCounting), errors.Is(err, stocktakes.ErrUnexplained),
+		errors.Is(err, stocktakes.ErrInvalidTransition):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	default:
+		return inventoryError(err)
+	}
+}
@@ -0,0 +1,532 @@
+package stocktakes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/pagination"
+	"encore.app/products/inventory"
+)
+
+// the products database
+var stocktakesDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// get - gets a stocktake with its lines and their variances, locking the stocktake when asked.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param id - string
+// @param lock - lock the stocktake until the transaction ends
+// @return stocktake
+// @return error
+func get(ctx context.Context, db sqlx.ExtContext, id string, lock bool) (*StocktakeResponse, error) {
+	q := "SELECT * FROM stocktakes WHERE id = :id"
+	if lock {
+		q += " FOR UPDATE"
+	}
+
+	data := map[string]interface{}{"id": id}
+	response := &StocktakeResponse{Stocktake: &Stocktake{}, Lines: make([]Line, 0)}
+	if err := database.NamedStructQuery(ctx, db, q, data, response.Stocktake); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("selecting stocktake: %w", err)
+	}
+	if err := database.NamedSliceQuery(ctx, db, `
+    SELECT sl.*, p.name
+    FROM stocktake_lines sl
+    JOIN products p ON p.id = sl.product_id
+    WHERE sl.stocktake_id = :id
+    ORDER BY p.name, sl.product_id
+  `, data, &response.Lines); err != nil {
+		return nil, fmt.Errorf("selecting stocktake lines: %w", err)
+	}
+	response.Summary = Reconcile(response.Lines)
+
+	return response, nil
+}
+
+// Create - Create is a function that starts counting the stock at an active location, of one category or of every
+// product stocked there. The lines keep the system stock at the start, the variances are worked out against it.
+// Products being counted in another open stocktake at the location cannot be counted again.
+//
+// @param ctx - context.Context
+// @param userId - the admin starting the count
+// @param payload - *StocktakeRequest
+// @return stocktake
+// @return error
+func Create(ctx context.Context, userId string, payload *StocktakeRequest) (*StocktakeResponse, error) {
+	now := time.Now().UTC()
+	stocktake := &Stocktake{
+		Id:         uuid.New().String(),
+		LocationId: payload.LocationId,
+		Status:     StatusCounting,
+		Notes:      payload.Notes,
+		CreatedBy:  userId,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if len(payload.CategoryId) > 0 {
+		stocktake.CategoryId = &payload.CategoryId
+	}
+
+	var response *StocktakeResponse
+	err := database.Transaction(ctx, stocktakesDatabase(), func(tx *sqlx.Tx) error {
+		// check the location
+		location := struct {
+			Active bool `db:"active"`
+		}{}
+		if err := database.NamedStructQuery(ctx, tx, "SELECT active FROM locations WHERE id = :location_id", stocktake, &location); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return fmt.Errorf("%w[%v]", ErrLocationNotFound, stocktake.LocationId)
+			}
+			return fmt.Errorf("selecting location: %w", err)
+		}
+		if !location.Active {
+			return fmt.Errorf("%w[%v]", ErrInactive, stocktake.LocationId)
+		}
+
+		// check the category
+		if stocktake.CategoryId != nil {
+			count, err := database.NamedCountQuery(ctx, tx, "SELECT COUNT(*) FROM categories WHERE id = :category_id", stocktake)
+			if err != nil {
+				return fmt.Errorf("counting categories: %w", err)
+			}
+			if count < 1 {
+				return fmt.Errorf("%w[%v]", ErrCategoryNotFound, *stocktake.CategoryId)
+			}
+		}
+
+		// serialize the stocktakes of the location, and check none counts the same products
+		data := map[string]interface{}{"location_id": stocktake.LocationId, "category_id": payload.CategoryId}
+		if err := database.NamedExecQuery(ctx, tx, "SELECT pg_advisory_xact_lock(hashtext('stocktake:' || :location_id))", data); err != nil {
+			return fmt.Errorf("locking location: %w", err)
+		}
+		count, err := database.NamedCountQuery(ctx, tx, `
+      SELECT COUNT(*) FROM stocktakes
+      WHERE location_id = :location_id AND status IN ('counting', 'submitted')
+        AND (:category_id = '' OR category_id IS NULL OR CAST(category_id AS TEXT) = :category_id)
+    `, data)
+		if err != nil {
+			return fmt.Errorf("counting open stocktakes: %w", err)
+		}
+		if count > 0 {
+			return ErrOverlapping
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO stocktakes (id, location_id, category_id, status, notes, created_by, created_at, updated_at)
+      VALUES (:id, :location_id, :category_id, :status, :notes, :created_by, :created_at, :updated_at)
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, stocktake); err != nil {
+			return fmt.Errorf("inserting stocktake: %w", err)
+		}
+
+		// keep the system stock of the products stocked at the location
+		data["id"] = stocktake.Id
+		if err := database.NamedExecQuery(ctx, tx, `
+      INSERT INTO stocktake_lines (stocktake_id, product_id, expected)
+      SELECT :id, s.product_id, s.quantity
+      FROM location_stock s
+      JOIN products p ON p.id = s.product_id
+      WHERE s.location_id = :location_id AND (:category_id = '' OR CAST(p.category_id AS TEXT) = :category_id)
+    `, data); err != nil {
+			return fmt.Errorf("inserting stocktake lines: %w", err)
+		}
+
+		response, err = get(ctx, tx, stocktake.Id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Get - Get is a function that gets a stocktake with its lines and their variances.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return stocktake
+// @return error
+func Get(ctx context.Context, id string) (*StocktakeResponse, error) {
+	return get(ctx, stocktakesDatabase(), id, false)
+}
+
+// stocktakesSchema - the fields of a stocktake allowed in list queries
+var stocktakesSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "number", Column: "number", Type: pagination.Number, Sortable: true},
+		{Name: "locationId", Column: "location_id", Type: pagination.UUID},
+		{Name: "categoryId", Column: "category_id", Type: pagination.UUID},
+		{Name: "status", Column: "status", Type: pagination.String, Sortable: true},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-createdAt",
+}
+
+// List - List is a function that lists stocktakes, the newest first unless sorted otherwise.
+//
+// @param ctx - context.Context
+// @param params - *StocktakesQuery
+// @return stocktakes
+// @return error
+func List(ctx context.Context, params *StocktakesQuery) (*PaginatedStocktakesResponse, error) {
+	// parse the filter and sort
+	statement, err := stocktakesSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	if len(params.LocationId) > 0 {
+		statement.AddCondition("location_id = CAST(:location_id AS UUID)", map[string]interface{}{"location_id": params.LocationId})
+	}
+	if len(params.Status) > 0 {
+		statement.AddCondition("status = :status", map[string]interface{}{"status": params.Status})
+	}
+
+	// execute query
+	stocktakes := make([]Stocktake, 0)
+	page, err := database.NamedPageQuery(ctx, stocktakesDatabase(), "stocktakes", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &stocktakes)
+	if err != nil {
+		return nil, fmt.Errorf("selecting stocktakes: %w", err)
+	}
+
+	return &PaginatedStocktakesResponse{
+		Stocktakes:      stocktakes,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// Record - Record is a function that records units counted, by product or by barcode. The system stock of every
+// product counted is taken again, so the variance is against the stock when it was counted. A product found that is
+// not on the stocktake is added to it.
+//
+// @param ctx - context.Context
+// @param userId - the admin counting
+// @param id - string
+// @param payload - *CountRequest
+// @param now - time.Time
+// @return stocktake
+// @return error
+func Record(ctx context.Context, userId, id string, payload *CountRequest, now time.Time) (*StocktakeResponse, error) {
+	var response *StocktakeResponse
+	err := database.Transaction(ctx, stocktakesDatabase(), func(tx *sqlx.Tx) error {
+		current, err := get(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		stocktake := current.Stocktake
+		if stocktake.Status != StatusCounting {
+			return fmt.Errorf("%w: the stocktake is %v", ErrNotCounting, stocktake.Status)
+		}
+
+		// find the products of the barcodes scanned
+		codes := make([]string, 0)
+		for _, count := range payload.Counts {
+			if len(count.ProductId) < 1 {
+				codes = append(codes, count.Barcode)
+			}
+		}
+		scanned, err := inventory.Scan(ctx, tx, codes)
+		if err != nil {
+			return err
+		}
+
+		lines := make(map[string]*Line, len(current.Lines))
+		for i := range current.Lines {
+			lines[current.Lines[i].ProductId] = &current.Lines[i]
+		}
+
+		// add the products not on the stocktake
+		missing := make([]string, 0)
+		for i, count := range payload.Counts {
+			if len(count.ProductId) < 1 {
+				payload.Counts[i].ProductId = scanned[count.Barcode]
+			}
+			if _, ok := lines[payload.Counts[i].ProductId]; !ok {
+				missing = append(missing, payload.Counts[i].ProductId)
+				lines[payload.Counts[i].ProductId] = nil
+			}
+		}
+		if len(missing) > 0 {
+			found := make([]Line, 0, len(missing))
+			if err := database.NamedSliceQuery(ctx, tx, `
+        SELECT CAST(:id AS UUID) AS stocktake_id, p.id AS product_id, p.name, COALESCE(s.quantity, 0) AS expected
+        FROM products p
+        LEFT JOIN location_stock s ON s.product_id = p.id AND s.location_id = :location_id
+        WHERE p.id = ANY(CAST(:ids AS UUID[]))
+          AND (CAST(:category_id AS TEXT) IS NULL OR CAST(p.category_id AS TEXT) = :category_id)
+      `, map[string]interface{}{
+				"id":          stocktake.Id,
+				"location_id": stocktake.LocationId,
+				"category_id": stocktake.CategoryId,
+				"ids":         missing,
+			}, &found); err != nil {
+				return fmt.Errorf("selecting products: %w", err)
+			}
+			if len(found) < len(missing) {
+				return fmt.Errorf("%w in the category counted", ErrProductNotFound)
+			}
+			for i := range found {
+				lines[found[i].ProductId] = &found[i]
+			}
+		}
+
+		// count
+		counted := make([]Line, 0, len(payload.Counts))
+		seen := make(map[string]bool, len(payload.Counts))
+		order := make([]string, 0, len(payload.Counts))
+		for _, count := range payload.Counts {
+			line := lines[count.ProductId]
+			if err := Tally(line, count.Mode, count.Quantity); err != nil {
+				return err
+			}
+			line.CountedBy, line.CountedAt = &userId, &now
+			if !seen[line.ProductId] {
+				seen[line.ProductId] = true
+				order = append(order, line.ProductId)
+			}
+		}
+
+		// take the system stock as it is when counted, so stock moved since the stocktake started counts once
+		stock := make([]struct {
+			ProductId string `db:"product_id"`
+			Quantity  int    `db:"quantity"`
+		}, 0, len(order))
+		if err := database.NamedSliceQuery(ctx, tx, `
+      SELECT product_id, quantity FROM location_stock
+      WHERE location_id = :location_id AND product_id = ANY(CAST(:ids AS UUID[]))
+    `, map[string]interface{}{
+			"location_id": stocktake.LocationId,
+			"ids":         order,
+		}, &stock); err != nil {
+			return fmt.Errorf("selecting location stock: %w", err)
+		}
+		for _, productId := range order {
+			lines[productId].Expected = 0
+		}
+		for _, level := range stock {
+			lines[level.ProductId].Expected = level.Quantity
+		}
+		for _, productId := range order {
+			counted = append(counted, *lines[productId])
+		}
+
+		// query statement to be executed
+		q := `
+      INSERT INTO stocktake_lines (stocktake_id, product_id, expected, counted, counted_by, counted_at)
+      VALUES (:stocktake_id, :product_id, :expected, :counted, :counted_by, :counted_at)
+      ON CONFLICT (stocktake_id, product_id) DO UPDATE
+      SET expected = EXCLUDED.expected, counted = EXCLUDED.counted, counted_by = EXCLUDED.counted_by,
+          counted_at = EXCLUDED.counted_at
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, counted); err != nil {
+			return fmt.Errorf("upserting stocktake lines: %w", err)
+		}
+		if err := database.NamedExecQuery(ctx, tx, "UPDATE stocktakes SET updated_at = :updated_at WHERE id = :id", map[string]interface{}{
+			"id":         stocktake.Id,
+			"updated_at": now,
+		}); err != nil {
+			return fmt.Errorf("updating stocktake: %w", err)
+		}
+
+		response, err = get(ctx, tx, stocktake.Id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Explain - Explain is a function that gives the reason a product was counted differently from the system stock.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param productId - string
+// @param payload - *ExplainRequest
+// @return stocktake
+// @return error
+func Explain(ctx context.Context, id, productId string, payload *ExplainRequest) (*StocktakeResponse, error) {
+	var response *StocktakeResponse
+	err := database.Transaction(ctx, stocktakesDatabase(), func(tx *sqlx.Tx) error {
+		current, err := get(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		if current.Stocktake.Status != StatusCounting {
+			return fmt.Errorf("%w: the stocktake is %v", ErrNotCounting, current.Stocktake.Status)
+		}
+
+		count, err := database.NamedCountQuery(ctx, tx, `
+      WITH updated AS (
+        UPDATE stocktake_lines SET reason = :reason, note = :note
+        WHERE stocktake_id = :id AND product_id = :product_id
+        RETURNING product_id
+      )
+      SELECT COUNT(*) FROM updated
+    `, map[string]interface{}{
+			"id":         id,
+			"product_id": productId,
+			"reason":     payload.Reason,
+			"note":       payload.Note,
+		})
+		if err != nil {
+			return fmt.Errorf("updating stocktake line: %w", err)
+		}
+		if count < 1 {
+			return fmt.Errorf("%w[%v]", ErrLineNotFound, productId)
+		}
+
+		response, err = get(ctx, tx, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Act - Act is a function that submits, approves, rejects or cancels a stocktake. Approving posts the variance of
+// every counted line as an adjustment at the location. The variance is against the stock when the product was
+// counted, so stock sold or received before or after the count is kept.
+//
+// @param ctx - context.Context
+// @param userId - the admin acting on the stocktake
+// @param id - string
+// @param payload - *ActionRequest
+// @param now - time.Time
+// @return stocktake
+// @return error
+func Act(ctx context.Context, userId, id string, payload *ActionRequest, now time.Time) (*StocktakeResponse, error) {
+	var response *StocktakeResponse
+	err := database.Transaction(ctx, stocktakesDatabase(), func(tx *sqlx.Tx) error {
+		current, err := get(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		stocktake := current.Stocktake
+
+		status, err := Transition(stocktake.Status, payload.Action)
+		if err != nil {
+			return err
+		}
+		stocktake.Status = status
+		stocktake.UpdatedAt = now.UTC()
+
+		switch payload.Action {
+		case ActionSubmit:
+			if current.Summary.Counted < 1 {
+				return fmt.Errorf("%w: nothing is counted", ErrInvalidCount)
+			}
+			if current.Summary.Unexplained > 0 {
+				return fmt.Errorf("%w: %v without one", ErrUnexplained, current.Summary.Unexplained)
+			}
+			stocktake.SubmittedBy, stocktake.SubmittedAt = &userId, &stocktake.UpdatedAt
+			stocktake.ReviewNote = ""
+		case ActionApprove:
+			stocktake.ApprovedBy, stocktake.PostedAt = &userId, &stocktake.UpdatedAt
+			if err := post(ctx, tx, userId, stocktake, current.Lines); err != nil {
+				return err
+			}
+		case ActionReject:
+			stocktake.ReviewNote = payload.Note
+		case ActionCancel:
+			stocktake.CancelledAt = &stocktake.UpdatedAt
+		}
+
+		if err := database.NamedExecQuery(ctx, tx, `
+      UPDATE stocktakes
+      SET status = :status, review_note = :review_note, submitted_by = :submitted_by, submitted_at = :submitted_at,
+          approved_by = :approved_by, posted_at = :posted_at, cancelled_at = :cancelled_at, updated_at = :updated_at
+      WHERE id = :id
+    `, stocktake); err != nil {
+			return fmt.Errorf("updating stocktake: %w", err)
+		}
+
+		response, err = get(ctx, tx, stocktake.Id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// post - adjusts the stock at the location of a stocktake by the variances counted, each adjustment with the reason
+// of its variance.
+//
+// @param ctx - context.Context
+// @param tx - *sqlx.Tx
+// @param userId - the manager approving the counts
+// @param stocktake - *Stocktake
+// @param lines - the lines with their variances
+// @return error
+func post(ctx context.Context, tx *sqlx.Tx, userId string, stocktake *Stocktake, lines []Line) error {
+	reference := fmt.Sprintf("ST-%06d", stocktake.Number)
+	for _, line := range lines {
+		if line.Variance == nil || *line.Variance == 0 {
+			continue
+		}
+
+		movement := &inventory.Movement{
+			ProductId:  line.ProductId,
+			LocationId: stocktake.LocationId,
+			Quantity:   *line.Variance,
+			Reason:     inventory.ReasonAdjustment,
+			ReasonCode: line.Reason,
+			Reference:  reference,
+			Note:       line.Note,
+			CreatedBy:  &userId,
+			CreatedAt:  stocktake.UpdatedAt,
+		}
+		if err := inventory.Move(ctx, tx, movement); err != nil {
+			return fmt.Errorf("posting product %v: %w", line.ProductId, err)
+		}
+
+		if err := database.NamedExecQuery(ctx, tx, `
+      UPDATE stocktake_lines SET movement_id = :movement_id WHERE stocktake_id = :stocktake_id AND product_id = :product_id
+    `, map[string]interface{}{
+			"movement_id":  movement.Id,
+			"stocktake_id": stocktake.Id,
+			"product_id":   line.ProductId,
+		}); err != nil {
+			return fmt.Errorf("updating stocktake line: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package stocktakes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"encore.app/pkg/database"
+	"encore.app/products/inventory"
+	"encore.app/products/locations"
+	"encore.app/products/productstest"
+)
+
+// TestPost - test stock moved before and after a product is counted is kept when the stocktake is posted
+//
+//	@param t - testing.T
+func TestPost(t *testing.T) {
+	if !database.Available(stocktakesDatabase) {
+		t.Skip("the products database is not available, run with encore test")
+	}
+	ctx := context.Background()
+	userId := uuid.New().String()
+
+	productId, err := productstest.Create(ctx, stocktakesDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := uuid.New().String()[:8]
+	location, err := locations.Create(ctx, &locations.LocationRequest{Code: code, Name: "location " + code, Kind: locations.KindStore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adjust := func(quantity int) {
+		if _, err := inventory.Adjust(ctx, userId, productId, &inventory.AdjustRequest{LocationId: location.Id, Quantity: quantity}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 10 units when the stocktake starts, 20 received before the product is counted
+	adjust(10)
+	stocktake, err := Create(ctx, userId, &StocktakeRequest{LocationId: location.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := stocktake.Stocktake.Id
+	adjust(20)
+
+	// 28 counted, 2 of them damaged
+	counted, err := Record(ctx, userId, id, &CountRequest{Counts: []Count{{ProductId: productId, Quantity: 28}}}, time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := counted.Lines[0]; line.Expected != 30 || line.Variance == nil || *line.Variance != -2 {
+		t.Fatalf("expected a variance of -2 against 30 units, got %v against %v", line.Variance, line.Expected)
+	}
+	if _, err := Explain(ctx, id, productId, &ExplainRequest{Reason: ReasonDamaged, Note: "crushed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 sold after the count
+	adjust(-5)
+
+	for _, action := range []string{ActionSubmit, ActionApprove} {
+		if _, err := Act(ctx, userId, id, &ActionRequest{Action: action}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	levels, err := inventory.Levels(ctx, productId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levels.Total != 23 {
+		t.Errorf("expected 23 units, got %v", levels.Total)
+	}
+	movements, err := inventory.ListMovements(ctx, productId, &inventory.MovementsQuery{Filter: "reasonCode=damaged"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movements.Movements) != 1 || movements.Movements[0].Quantity != -2 || movements.Movements[0].Note != "crushed" {
+		t.Errorf("expected one adjustment of -2 for damage, got %v", movements.Movements)
+	}
+}
@@ -0,0 +1,88 @@
+package stocktakes
+
+import (
+	"fmt"
+
+	"encore.app/pkg/lifecycle"
+)
+
+// stocktakeLifecycle - the status an action moves a stocktake to, by the status it is in
+var stocktakeLifecycle = &lifecycle.Machine{
+	Noun: "stocktake",
+	Transitions: map[string]map[string]string{
+		StatusCounting:  {ActionSubmit: StatusSubmitted, ActionCancel: StatusCancelled},
+		StatusSubmitted: {ActionApprove: StatusPosted, ActionReject: StatusCounting, ActionCancel: StatusCancelled},
+	},
+	Err: ErrInvalidTransition,
+}
+
+// Transition - Transition works out the status of a stocktake after an action. Counts are submitted to a manager,
+// who approves them or sends them back to be counted again, and a stocktake is cancelled until it is posted.
+//
+// @param status - the status of the stocktake
+// @param action - submit, approve, reject or cancel
+// @return the new status
+// @return error
+func Transition(status, action string) (string, error) {
+	return stocktakeLifecycle.Next(status, action)
+}
+
+// Tally - Tally records units counted on a line, replacing or adding to those counted so far.
+//
+// @param line - *Line
+// @param mode - set or add, set when empty
+// @param quantity - the units counted
+// @return error
+func Tally(line *Line, mode string, quantity int) error {
+	if quantity < 0 {
+		return fmt.Errorf("%w: the units counted cannot be negative", ErrInvalidCount)
+	}
+
+	counted := quantity
+	switch mode {
+	case ModeSet, "":
+	case ModeAdd:
+		if line.Counted != nil {
+			counted += *line.Counted
+		}
+	default:
+		return fmt.Errorf("%w: unknown mode %v", ErrInvalidCount, mode)
+	}
+	line.Counted = &counted
+
+	return nil
+}
+
+// Reconcile - Reconcile works out the variance of every counted line against the system stock, and sums them up.
+//
+// @param lines - []Line
+// @return the summary
+func Reconcile(lines []Line) Summary {
+	summary := Summary{Lines: len(lines)}
+	for i := range lines {
+		line := &lines[i]
+		line.Variance = nil
+		if line.Counted == nil {
+			summary.Uncounted++
+			continue
+		}
+		summary.Counted++
+
+		variance := *line.Counted - line.Expected
+		line.Variance = &variance
+		if variance == 0 {
+			continue
+		}
+		summary.Variances++
+		if len(line.Reason) < 1 {
+			summary.Unexplained++
+		}
+		if variance > 0 {
+			summary.UnitsOver += variance
+		} else {
+			summary.UnitsShort -= variance
+		}
+	}
+
+	return summary
+}
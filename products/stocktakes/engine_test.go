@@ -0,0 +1,69 @@
+package stocktakes
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestTally - test counts replace or add to the units counted
+//
+//	@param t - testing.T
+func TestTally(t *testing.T) {
+	line := &Line{Expected: 10}
+
+	// scans add to nothing counted yet
+	for i := 0; i < 3; i++ {
+		if err := Tally(line, ModeAdd, 1); err != nil {
+			t.Fatalf("a scan should be counted, got %v", err)
+		}
+	}
+	if line.Counted == nil || *line.Counted != 3 {
+		t.Errorf("3 scans should count 3 units, got %v", line.Counted)
+	}
+
+	// a count replaces the scans
+	if err := Tally(line, "", 8); err != nil || *line.Counted != 8 {
+		t.Errorf("the count should be 8, got %v %v", *line.Counted, err)
+	}
+	if err := Tally(line, ModeSet, 0); err != nil || *line.Counted != 0 {
+		t.Errorf("the count should be 0, got %v %v", *line.Counted, err)
+	}
+
+	// invalid counts
+	if err := Tally(line, ModeSet, -1); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("a negative count should be invalid, got %v", err)
+	}
+	if err := Tally(line, "multiply", 2); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("an unknown mode should be invalid, got %v", err)
+	}
+}
+
+// TestReconcile - test variances are worked out against the system stock
+//
+//	@param t - testing.T
+func TestReconcile(t *testing.T) {
+	count := func(n int) *int { return &n }
+	lines := []Line{
+		{ProductId: "exact", Expected: 5, Counted: count(5)},
+		{ProductId: "over", Expected: 2, Counted: count(6), Reason: ReasonFound},
+		{ProductId: "short", Expected: 10, Counted: count(7)},
+		{ProductId: "uncounted", Expected: 4},
+	}
+
+	summary := Reconcile(lines)
+	want := Summary{Lines: 4, Counted: 3, Uncounted: 1, Variances: 2, Unexplained: 1, UnitsOver: 4, UnitsShort: 3}
+	if summary != want {
+		t.Errorf("summary should be %+v, got %+v", want, summary)
+	}
+
+	variances := map[string]*int{"exact": count(0), "over": count(4), "short": count(-3), "uncounted": nil}
+	for _, line := range lines {
+		expected := variances[line.ProductId]
+		switch {
+		case expected == nil && line.Variance != nil:
+			t.Errorf("%v should have no variance, got %v", line.ProductId, *line.Variance)
+		case expected != nil && (line.Variance == nil || *line.Variance != *expected):
+			t.Errorf("%v should have a variance of %v, got %v", line.ProductId, *expected, line.Variance)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package stocktakes
+
+import "errors"
+
+var (
+	ErrNotFound          = errors.New("stocktake not found")
+	ErrLineNotFound      = errors.New("the product is not on the stocktake")
+	ErrLocationNotFound  = errors.New("location not found")
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrInactive          = errors.New("the location is not active")
+	ErrOverlapping       = errors.New("the products are already being counted at the location")
+	ErrNotCounting       = errors.New("the stocktake is not being counted")
+	ErrInvalidCount      = errors.New("invalid count")
+	ErrUnexplained       = errors.New("every variance needs a reason")
+	ErrInvalidTransition = errors.New("invalid stocktake transition")
+)
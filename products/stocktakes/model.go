@@ -0,0 +1,129 @@
+package stocktakes
+
+import (
+	"time"
+)
+
+const (
+	StatusCounting  = "counting"  // products are being counted
+	StatusSubmitted = "submitted" // counted, waiting for a manager
+	StatusPosted    = "posted"    // approved, the variances are adjustments in the stock ledger
+	StatusCancelled = "cancelled" // will not be posted
+
+	ActionSubmit  = "submit"
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionCancel  = "cancel"
+
+	ModeSet = "set" // the count replaces the units counted so far
+	ModeAdd = "add" // the count adds to the units counted so far, e.g. one per scan
+
+	ReasonMiscount = "miscount" // the system stock was wrong, e.g. a delivery booked twice
+	ReasonDamaged  = "damaged"
+	ReasonExpired  = "expired"
+	ReasonTheft    = "theft"
+	ReasonFound    = "found" // units turned up that were thought lost
+	ReasonOther    = "other"
+)
+
+// Stocktake - a physical count of the stock at a location, of one category or of every product
+type Stocktake struct {
+	Id          string     `json:"id" db:"id"`
+	Number      int64      `json:"number" db:"number"` // shown on count sheets as ST-000042
+	LocationId  string     `json:"locationId" db:"location_id"`
+	CategoryId  *string    `json:"categoryId" db:"category_id"` // every product stocked at the location when none
+	Status      string     `json:"status" db:"status"`
+	Notes       string     `json:"notes" db:"notes"`
+	ReviewNote  string     `json:"reviewNote" db:"review_note"` // why a manager sent the counts back
+	SubmittedBy *string    `json:"submittedBy" db:"submitted_by"`
+	SubmittedAt *time.Time `json:"submittedAt" db:"submitted_at"`
+	ApprovedBy  *string    `json:"approvedBy" db:"approved_by"`
+	PostedAt    *time.Time `json:"postedAt" db:"posted_at"`
+	CancelledAt *time.Time `json:"cancelledAt" db:"cancelled_at"`
+	CreatedBy   string     `json:"createdBy" db:"created_by"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// Line - a product to count, with the stock the system had when it was counted
+type Line struct {
+	StocktakeId string     `json:"stocktakeId" db:"stocktake_id"`
+	ProductId   string     `json:"productId" db:"product_id"`
+	Name        string     `json:"name" db:"name"`
+	Expected    int        `json:"expected" db:"expected"` // the system stock at the location, taken again when counted
+	Counted     *int       `json:"counted" db:"counted"`   // none until the product is counted
+	Variance    *int       `json:"variance" db:"-"`        // counted less expected, none until counted
+	Reason      string     `json:"reason" db:"reason"`     // why the count differs
+	Note        string     `json:"note" db:"note"`
+	CountedBy   *string    `json:"countedBy" db:"counted_by"`
+	CountedAt   *time.Time `json:"countedAt" db:"counted_at"`
+	MovementId  *string    `json:"movementId" db:"movement_id"` // the adjustment posted for the variance
+}
+
+// Summary - the counts and variances of a stocktake
+type Summary struct {
+	Lines       int `json:"lines"`
+	Counted     int `json:"counted"`
+	Uncounted   int `json:"uncounted"`   // left as they are when posted
+	Variances   int `json:"variances"`   // lines counted differently from the system stock
+	Unexplained int `json:"unexplained"` // variances without a reason, the stocktake cannot be submitted with any
+	UnitsOver   int `json:"unitsOver"`   // units found beyond the system stock
+	UnitsShort  int `json:"unitsShort"`  // units missing from the system stock
+}
+
+type StocktakeRequest struct {
+	LocationId string `json:"locationId" validate:"required,uuid"`
+	CategoryId string `json:"categoryId" validate:"omitempty,uuid"` // every product stocked at the location when empty
+	Notes      string `json:"notes" validate:"max=5000"`
+}
+
+// Count - units of a product counted, found by its id or by a barcode read by a scanner
+type Count struct {
+	ProductId string `json:"productId" validate:"required_without=Barcode,omitempty,uuid"`
+	Barcode   string `json:"barcode" validate:"required_without=ProductId,max=64"`
+	Quantity  int    `json:"quantity" validate:"min=0"`
+	Mode      string `json:"mode" validate:"omitempty,oneof=set add"` // set when empty
+}
+
+type CountRequest struct {
+	Counts []Count `json:"counts" validate:"required,min=1,max=500,dive"`
+}
+
+type ExplainRequest struct {
+	Reason string `json:"reason" validate:"required,oneof=miscount damaged expired theft found other"`
+	Note   string `json:"note" validate:"max=1000"`
+}
+
+type ActionRequest struct {
+	Action string `json:"action" validate:"required,oneof=submit approve reject cancel"`
+	Note   string `json:"note" validate:"max=1000"` // why the counts are sent back when rejecting
+}
+
+type StocktakeResponse struct {
+	Stocktake *Stocktake `json:"stocktake"`
+	Summary   Summary    `json:"summary"`
+	Lines     []Line     `json:"lines"`
+}
+
+type StocktakesQuery struct {
+	LocationId string `json:"locationId" query:"locationId" validate:"omitempty,uuid"`
+	Status     string `json:"status" query:"status" validate:"omitempty,oneof=counting submitted posted cancelled"`
+	Limit      int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page       int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter     string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. number>=40
+	Sort       string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -createdAt
+	Cursor     string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the stocktakes
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedStocktakesResponse struct {
+	Stocktakes      []Stocktake `json:"data"`
+	Total           int         `json:"total"`
+	TotalPages      int         `json:"totalPages"`
+	CurrentPage     int         `json:"currentPage"`
+	HasPreviousPage bool        `json:"hasPreviousPage"`
+	HasNextPage     bool        `json:"hasNextPage"`
+	PrevCursor      string      `json:"prevCursor"`
+	NextCursor      string      `json:"nextCursor"`
+}
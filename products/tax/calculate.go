@@ -0,0 +1,345 @@
+package tax
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+// component - a tax in effect, as a fraction of the net price
+type component struct {
+	name    string
+	percent string
+	rate    *big.Rat
+}
+
+// key - identifies the same tax across lines
+//
+// @return string
+func (c component) key() string {
+	return c.name + "|" + c.percent
+}
+
+// Calculate - Calculate works out the tax of the lines in a jurisdiction with the rates in effect at a time.
+// Tax is rounded half up, on every line or once on the total depending on the rounding of the jurisdiction.
+// When the total is rounded it is spread over the lines by the largest remainder, so the lines always add
+// up to the total.
+//
+// @param jurisdiction - Jurisdiction
+// @param rates - the rates of the jurisdiction
+// @param lines - []Line
+// @param at - time.Time
+// @return breakdown
+// @return error
+func Calculate(jurisdiction Jurisdiction, rates []Rate, lines []Line, at time.Time) (*Breakdown, error) {
+	if jurisdiction.PriceMode != PriceModeExclusive && jurisdiction.PriceMode != PriceModeInclusive {
+		return nil, fmt.Errorf("%w: unknown price mode %q", ErrInvalidRate, jurisdiction.PriceMode)
+	}
+	if jurisdiction.Rounding != RoundingLine && jurisdiction.Rounding != RoundingTotal {
+		return nil, fmt.Errorf("%w: unknown rounding %q", ErrInvalidRate, jurisdiction.Rounding)
+	}
+
+	// get the taxes of every class in effect
+	classes, err := effective(jurisdiction.Code, rates, at)
+	if err != nil {
+		return nil, err
+	}
+
+	// the lines are taxed in the currency of their amounts
+	currency := money.DefaultCurrency
+	if len(lines) > 0 {
+		currency = lines[0].Amount.Currency
+	}
+
+	// work out the exact tax of every tax of every line
+	exact := make([][]*big.Rat, len(lines))
+	components := make([][]component, len(lines))
+	for i, line := range lines {
+		if line.Amount.Currency != currency {
+			return nil, fmt.Errorf("%w: amounts are in %v and %v", ErrInvalidLine, currency, line.Amount.Currency)
+		}
+		if line.Amount.IsNegative() {
+			return nil, fmt.Errorf("%w: the amount of line %v is negative", ErrInvalidLine, line.Id)
+		}
+
+		class := classOf(line)
+		taxes, ok := classes[class]
+		if !ok {
+			return nil, fmt.Errorf("%w: %v in %v", ErrNoRate, class, jurisdiction.Code)
+		}
+		components[i] = taxes
+
+		// the combined rate of the taxes
+		total := new(big.Rat)
+		for _, tax := range taxes {
+			total.Add(total, tax.rate)
+		}
+
+		// the share of the amount that is tax
+		amount := new(big.Rat).SetInt64(line.Amount.Amount)
+		share := new(big.Rat).Set(total)
+		if jurisdiction.PriceMode == PriceModeInclusive {
+			share.Quo(total, new(big.Rat).Add(big.NewRat(1, 1), total))
+		}
+
+		exact[i] = make([]*big.Rat, len(taxes))
+		for j, tax := range taxes {
+			// amount * share * rate / total is the part of the tax from this rate
+			part := new(big.Rat).Mul(amount, share)
+			if total.Sign() > 0 {
+				part.Mul(part, tax.rate).Quo(part, total)
+			}
+			exact[i][j] = part
+		}
+	}
+
+	// round the tax
+	rounded := make([][]int64, len(lines))
+	if jurisdiction.Rounding == RoundingLine {
+		for i := range lines {
+			rounded[i] = distribute(round(sum(exact[i])), exact[i])
+		}
+	} else {
+		for i := range lines {
+			rounded[i] = make([]int64, len(exact[i]))
+		}
+
+		// round every tax once over all the lines and spread it back
+		for _, key := range keys(components) {
+			var parts []*big.Rat
+			var at [][2]int
+			for i := range lines {
+				for j, c := range components[i] {
+					if c.key() == key {
+						parts = append(parts, exact[i][j])
+						at = append(at, [2]int{i, j})
+					}
+				}
+			}
+			for k, amount := range distribute(round(sum(parts)), parts) {
+				rounded[at[k][0]][at[k][1]] = amount
+			}
+		}
+	}
+
+	// create the breakdown
+	breakdown := &Breakdown{
+		Jurisdiction: jurisdiction.Code,
+		PriceMode:    jurisdiction.PriceMode,
+		Rounding:     jurisdiction.Rounding,
+		Lines:        make([]LineTax, 0, len(lines)),
+		Net:          money.Zero(currency),
+		Tax:          money.Zero(currency),
+		Gross:        money.Zero(currency),
+		Components:   make([]ComponentTax, 0),
+	}
+	totals := make(map[string]int64)
+	for i, line := range lines {
+		lineTax := LineTax{
+			Id:         line.Id,
+			ProductId:  line.ProductId,
+			Class:      classOf(line),
+			Quantity:   line.Quantity,
+			Tax:        money.Zero(currency),
+			Components: make([]ComponentTax, 0, len(components[i])),
+		}
+		for j, c := range components[i] {
+			lineTax.Tax.Amount += rounded[i][j]
+			lineTax.Components = append(lineTax.Components, ComponentTax{
+				Name:    c.name,
+				Percent: c.percent,
+				Tax:     money.Money{Amount: rounded[i][j], Currency: currency},
+			})
+			totals[c.key()] += rounded[i][j]
+		}
+
+		// the tax is added to or taken out of the amount
+		if jurisdiction.PriceMode == PriceModeInclusive {
+			lineTax.Gross = line.Amount
+			lineTax.Net = money.Money{Amount: line.Amount.Amount - lineTax.Tax.Amount, Currency: currency}
+		} else {
+			lineTax.Net = line.Amount
+			lineTax.Gross = money.Money{Amount: line.Amount.Amount + lineTax.Tax.Amount, Currency: currency}
+		}
+
+		breakdown.Net.Amount += lineTax.Net.Amount
+		breakdown.Tax.Amount += lineTax.Tax.Amount
+		breakdown.Gross.Amount += lineTax.Gross.Amount
+		breakdown.Lines = append(breakdown.Lines, lineTax)
+	}
+
+	// total every tax
+	for _, key := range keys(components) {
+		parts := strings.SplitN(key, "|", 2)
+		breakdown.Components = append(breakdown.Components, ComponentTax{
+			Name:    parts[0],
+			Percent: parts[1],
+			Tax:     money.Money{Amount: totals[key], Currency: currency},
+		})
+	}
+
+	return breakdown, nil
+}
+
+// effective - returns the taxes of every class in a jurisdiction in effect at a time, ordered by name.
+//
+// @param jurisdiction - string
+// @param rates - []Rate
+// @param at - time.Time
+// @return map[string][]component
+// @return error
+func effective(jurisdiction string, rates []Rate, at time.Time) (map[string][]component, error) {
+	classes := make(map[string][]component)
+
+	for _, rate := range rates {
+		if rate.Jurisdiction != jurisdiction || at.Before(rate.EffectiveFrom) || (rate.EffectiveTo != nil && !at.Before(*rate.EffectiveTo)) {
+			continue
+		}
+
+		percent, err := ParsePercent(rate.Percent)
+		if err != nil {
+			return nil, fmt.Errorf("rate %v: %w", rate.Id, err)
+		}
+		classes[rate.Class] = append(classes[rate.Class], component{
+			name:    rate.Name,
+			percent: FormatPercent(percent),
+			rate:    new(big.Rat).Quo(percent, big.NewRat(100, 1)),
+		})
+	}
+
+	for _, taxes := range classes {
+		sort.SliceStable(taxes, func(i, j int) bool {
+			return taxes[i].name < taxes[j].name
+		})
+	}
+
+	return classes, nil
+}
+
+// ParsePercent - ParsePercent parses a tax rate in percent from a decimal string, e.g. "12.5".
+//
+// @param s - string
+// @return *big.Rat
+// @return error
+func ParsePercent(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 1 || strings.ContainsAny(s, "eE/") {
+		return nil, fmt.Errorf("%w: %q is not a percentage", ErrInvalidRate, s)
+	}
+	percent, ok := new(big.Rat).SetString(s)
+	if !ok || percent.Sign() < 0 || percent.Cmp(big.NewRat(100, 1)) > 0 {
+		return nil, fmt.Errorf("%w: %q is not a percentage between 0 and 100", ErrInvalidRate, s)
+	}
+
+	return percent, nil
+}
+
+// FormatPercent - FormatPercent formats a percentage without trailing zeros, e.g. 12.5.
+//
+// @param percent - *big.Rat
+// @return string
+func FormatPercent(percent *big.Rat) string {
+	s := percent.FloatString(6)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// classOf - returns the class of a line.
+//
+// @param line - Line
+// @return string
+func classOf(line Line) string {
+	if len(line.Class) < 1 {
+		return DefaultClass
+	}
+
+	return line.Class
+}
+
+// keys - returns the taxes of the lines in the order they first appear.
+//
+// @param components - [][]component
+// @return []string
+func keys(components [][]component) []string {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+
+	for _, taxes := range components {
+		for _, c := range taxes {
+			if !seen[c.key()] {
+				seen[c.key()] = true
+				keys = append(keys, c.key())
+			}
+		}
+	}
+
+	return keys
+}
+
+// sum - returns the sum of the amounts.
+//
+// @param amounts - []*big.Rat
+// @return *big.Rat
+func sum(amounts []*big.Rat) *big.Rat {
+	total := new(big.Rat)
+	for _, amount := range amounts {
+		total.Add(total, amount)
+	}
+
+	return total
+}
+
+// round - rounds an amount of minor units half up.
+//
+// @param amount - *big.Rat
+// @return int64
+func round(amount *big.Rat) int64 {
+	return money.Round(amount.Num(), amount.Denom(), money.HalfUp).Int64()
+}
+
+// distribute - splits a rounded total over exact parts by the largest remainder, so the rounded parts
+// add up to the total. Ties go to the first parts.
+//
+// @param total - int64
+// @param parts - []*big.Rat
+// @return []int64
+func distribute(total int64, parts []*big.Rat) []int64 {
+	amounts := make([]int64, len(parts))
+
+	exact := sum(parts)
+	if exact.Sign() == 0 {
+		return amounts
+	}
+
+	// give every part its share rounded down
+	remainders := make([]*big.Rat, len(parts))
+	left := total
+	for i, part := range parts {
+		share := new(big.Rat).Mul(part, new(big.Rat).SetInt64(total))
+		share.Quo(share, exact)
+
+		floor := money.Round(share.Num(), share.Denom(), money.Floor)
+		amounts[i] = floor.Int64()
+		remainders[i] = share.Sub(share, new(big.Rat).SetInt(floor))
+		left -= amounts[i]
+	}
+
+	// hand out what is left to the largest remainders
+	order := make([]int, len(parts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]].Cmp(remainders[order[j]]) > 0
+	})
+	for k := 0; left > 0 && k < len(order); k++ {
+		amounts[order[k]]++
+		left--
+	}
+
+	return amounts
+}
@@ -0,0 +1,166 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+// update - rewrites the golden files with the current output, run with go test ./products/tax -update
+var update = flag.Bool("update", false, "update the golden files")
+
+// input - a calculation read from testdata
+type input struct {
+	Jurisdiction Jurisdiction `json:"jurisdiction"`
+	Rates        []Rate       `json:"rates"`
+	Lines        []Line       `json:"lines"`
+	At           time.Time    `json:"at"`
+}
+
+// TestCalculateGolden - test the breakdowns of the calculations in testdata against their golden files
+//
+//	@param t - testing.T
+func TestCalculateGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.input.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 1 {
+		t.Fatal("there should be calculations in testdata")
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".input.json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var in input
+			if err := json.Unmarshal(data, &in); err != nil {
+				t.Fatal(err)
+			}
+
+			breakdown, err := Calculate(in.Jurisdiction, in.Rates, in.Lines, in.At)
+			if err != nil {
+				t.Fatalf("should calculate, got %v", err)
+			}
+			checkTotals(t, breakdown)
+
+			got, err := json.MarshalIndent(breakdown, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("the breakdown does not match %v, got\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// checkTotals - checks the lines and taxes of a breakdown add up.
+//
+//	@param t - testing.T
+//	@param breakdown - *Breakdown
+func checkTotals(t *testing.T, breakdown *Breakdown) {
+	t.Helper()
+
+	var net, tax, gross, components int64
+	for _, line := range breakdown.Lines {
+		var lineTax int64
+		for _, c := range line.Components {
+			lineTax += c.Tax.Amount
+		}
+		if lineTax != line.Tax.Amount || line.Net.Amount+line.Tax.Amount != line.Gross.Amount {
+			t.Errorf("line %v does not add up: %+v", line.Id, line)
+		}
+		net += line.Net.Amount
+		tax += line.Tax.Amount
+		gross += line.Gross.Amount
+	}
+	for _, c := range breakdown.Components {
+		components += c.Tax.Amount
+	}
+
+	if net != breakdown.Net.Amount || tax != breakdown.Tax.Amount || gross != breakdown.Gross.Amount || components != tax {
+		t.Errorf("the totals do not add up: %+v", breakdown)
+	}
+}
+
+// TestCalculateErrors - test the calculations that cannot be made
+//
+//	@param t - testing.T
+func TestCalculateErrors(t *testing.T) {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	jurisdiction := Jurisdiction{Code: "GH", PriceMode: PriceModeExclusive, Rounding: RoundingLine}
+	rates := []Rate{
+		{Id: "vat", Jurisdiction: "GH", Class: DefaultClass, Name: "VAT", Percent: "15", EffectiveFrom: at.AddDate(-1, 0, 0)},
+		{Id: "old", Jurisdiction: "GH", Class: "groceries", Name: "VAT", Percent: "5", EffectiveFrom: at.AddDate(-2, 0, 0), EffectiveTo: &at},
+	}
+	ghs := money.Money{Amount: 1000, Currency: "GHS"}
+
+	// create a slice
+	slice := []struct {
+		name         string
+		jurisdiction Jurisdiction
+		rates        []Rate
+		lines        []Line
+		err          error
+	}{
+		{name: "no rate for the class", jurisdiction: jurisdiction, rates: rates, lines: []Line{{Class: "alcohol", Amount: ghs}}, err: ErrNoRate},
+		{name: "rate no longer in effect", jurisdiction: jurisdiction, rates: rates, lines: []Line{{Class: "groceries", Amount: ghs}}, err: ErrNoRate},
+		{name: "mixed currencies", jurisdiction: jurisdiction, rates: rates, lines: []Line{{Amount: ghs}, {Amount: money.Money{Amount: 1000, Currency: "USD"}}}, err: ErrInvalidLine},
+		{name: "negative amount", jurisdiction: jurisdiction, rates: rates, lines: []Line{{Amount: money.Money{Amount: -1, Currency: "GHS"}}}, err: ErrInvalidLine},
+		{name: "invalid percent", jurisdiction: jurisdiction, rates: []Rate{{Jurisdiction: "GH", Class: DefaultClass, Percent: "150"}}, lines: []Line{{Amount: ghs}}, err: ErrInvalidRate},
+		{name: "unknown price mode", jurisdiction: Jurisdiction{Code: "GH", Rounding: RoundingLine}, rates: rates, err: ErrInvalidRate},
+	}
+
+	// check every calculation
+	for _, item := range slice {
+		if _, err := Calculate(item.jurisdiction, item.rates, item.lines, at); !errors.Is(err, item.err) {
+			t.Errorf("%v: should fail with %v, got %v", item.name, item.err, err)
+		}
+	}
+}
+
+// TestPercent - test parsing and formatting percentages
+//
+//	@param t - testing.T
+func TestPercent(t *testing.T) {
+	for s, want := range map[string]string{"12.5": "12.5", "15.000": "15", "0": "0", " 7.25 ": "7.25", "100": "100"} {
+		percent, err := ParsePercent(s)
+		if err != nil {
+			t.Errorf("%q should parse, got %v", s, err)
+			continue
+		}
+		if got := FormatPercent(percent); got != want {
+			t.Errorf("%q should format as %q, got %q", s, want, got)
+		}
+	}
+
+	for _, s := range []string{"", "-1", "100.01", "1e2", "1/2", "ten"} {
+		if _, err := ParsePercent(s); !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("%q should not parse, got %v", s, err)
+		}
+	}
+}
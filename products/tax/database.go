@@ -0,0 +1,403 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/money"
+)
+
+// the products database
+var taxDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("products").Stdlib(), "postgres")
+})
+
+// CreateClass - CreateClass is a function that creates a tax class.
+//
+// @param ctx - context.Context
+// @param payload - *ClassRequest
+// @return class
+// @return error
+func CreateClass(ctx context.Context, payload *ClassRequest) (*Class, error) {
+	class := Class{
+		Code:        strings.TrimSpace(payload.Code),
+		Name:        payload.Name,
+		Description: payload.Description,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO tax_classes (code, name, description, created_at)
+    VALUES (:code, :name, :description, :created_at)
+    ON CONFLICT (code) DO NOTHING
+    RETURNING code
+  `
+
+	// execute query
+	var created struct {
+		Code string `db:"code"`
+	}
+	if err := database.NamedStructQuery(ctx, taxDatabase(), q, class, &created); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, fmt.Errorf("inserting tax class: %w", err)
+	}
+
+	return &class, nil
+}
+
+// ListClasses - ListClasses is a function that gets the tax classes.
+//
+// @param ctx - context.Context
+// @return classes
+// @return error
+func ListClasses(ctx context.Context) ([]Class, error) {
+	classes := make([]Class, 0)
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, taxDatabase(), "SELECT * FROM tax_classes ORDER BY code", map[string]interface{}{}, &classes); err != nil {
+		return nil, fmt.Errorf("selecting tax classes: %w", err)
+	}
+
+	return classes, nil
+}
+
+// checkClass - checks a tax class exists.
+//
+// @param ctx - context.Context
+// @param code - string
+// @return error
+func checkClass(ctx context.Context, code string) error {
+	count, err := database.NamedCountQuery(ctx, taxDatabase(), "SELECT COUNT(*) FROM tax_classes WHERE code = :code", map[string]interface{}{
+		"code": code,
+	})
+	if err != nil {
+		return fmt.Errorf("counting tax classes: %w", err)
+	}
+	if count < 1 {
+		return fmt.Errorf("%w: %v", ErrClassNotFound, code)
+	}
+
+	return nil
+}
+
+// assign - sets or removes the class of a product or category.
+//
+// @param ctx - context.Context
+// @param table - the table of the classes
+// @param column - the column of the product or category in the table
+// @param owner - the table of the products or categories
+// @param id - string
+// @param class - the class, removed when empty
+// @param missing - the error when the thing does not exist
+// @return error
+func assign(ctx context.Context, table, column, owner, id, class string, missing error) error {
+	// check if the product or category exists
+	count, err := database.NamedCountQuery(ctx, taxDatabase(), fmt.Sprintf("SELECT COUNT(*) FROM %v WHERE id = :id", owner), map[string]interface{}{
+		"id": id,
+	})
+	if err != nil {
+		return fmt.Errorf("counting %v: %w", owner, err)
+	}
+	if count < 1 {
+		return missing
+	}
+
+	// remove the class
+	data := map[string]interface{}{"id": id, "class": class}
+	if len(class) < 1 {
+		if err := database.NamedExecQuery(ctx, taxDatabase(), fmt.Sprintf("DELETE FROM %v WHERE %v = :id", table, column), data); err != nil {
+			return fmt.Errorf("deleting tax class: %w", err)
+		}
+		return nil
+	}
+
+	// check if the class exists
+	if err := checkClass(ctx, class); err != nil {
+		return err
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO %[1]v (%[2]v, class) VALUES (:id, :class)
+    ON CONFLICT (%[2]v) DO UPDATE SET class = EXCLUDED.class
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, taxDatabase(), fmt.Sprintf(q, table, column), data); err != nil {
+		return fmt.Errorf("setting tax class: %w", err)
+	}
+
+	return nil
+}
+
+// AssignProduct - AssignProduct is a function that sets the tax class of a product, the class of its
+// category applies when it is removed.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param class - string
+// @return error
+func AssignProduct(ctx context.Context, id, class string) error {
+	return assign(ctx, "product_tax_classes", "product_id", "products", id, strings.TrimSpace(class), ErrProductNotFound)
+}
+
+// AssignCategory - AssignCategory is a function that sets the tax class of the products of a category.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param class - string
+// @return error
+func AssignCategory(ctx context.Context, id, class string) error {
+	return assign(ctx, "category_tax_classes", "category_id", "categories", id, strings.TrimSpace(class), ErrCategoryNotFound)
+}
+
+// GetJurisdiction - GetJurisdiction is a function that gets a tax jurisdiction.
+//
+// @param ctx - context.Context
+// @param code - string
+// @return jurisdiction
+// @return error
+func GetJurisdiction(ctx context.Context, code string) (*Jurisdiction, error) {
+	var jurisdiction Jurisdiction
+
+	// execute query
+	if err := database.NamedStructQuery(ctx, taxDatabase(), "SELECT * FROM tax_jurisdictions WHERE code = :code", map[string]interface{}{
+		"code": strings.ToUpper(strings.TrimSpace(code)),
+	}, &jurisdiction); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrJurisdictionNotFound
+		}
+		return nil, fmt.Errorf("selecting tax jurisdiction by code[%v]: %w", code, err)
+	}
+
+	return &jurisdiction, nil
+}
+
+// SaveJurisdiction - SaveJurisdiction is a function that creates a tax jurisdiction or replaces its settings.
+//
+// @param ctx - context.Context
+// @param payload - *JurisdictionRequest
+// @return jurisdiction
+// @return error
+func SaveJurisdiction(ctx context.Context, payload *JurisdictionRequest) (*Jurisdiction, error) {
+	now := time.Now().UTC()
+	jurisdiction := Jurisdiction{
+		Code:      strings.ToUpper(strings.TrimSpace(payload.Code)),
+		Name:      payload.Name,
+		PriceMode: payload.PriceMode,
+		Rounding:  payload.Rounding,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO tax_jurisdictions (code, name, price_mode, rounding, created_at, updated_at)
+    VALUES (:code, :name, :price_mode, :rounding, :created_at, :updated_at)
+    ON CONFLICT (code) DO UPDATE SET
+      name = EXCLUDED.name, price_mode = EXCLUDED.price_mode, rounding = EXCLUDED.rounding, updated_at = EXCLUDED.updated_at
+    RETURNING *
+  `
+
+	// execute query
+	var saved Jurisdiction
+	if err := database.NamedStructQuery(ctx, taxDatabase(), q, jurisdiction, &saved); err != nil {
+		return nil, fmt.Errorf("saving tax jurisdiction: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// ListJurisdictions - ListJurisdictions is a function that gets the tax jurisdictions.
+//
+// @param ctx - context.Context
+// @return jurisdictions
+// @return error
+func ListJurisdictions(ctx context.Context) ([]Jurisdiction, error) {
+	jurisdictions := make([]Jurisdiction, 0)
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, taxDatabase(), "SELECT * FROM tax_jurisdictions ORDER BY code", map[string]interface{}{}, &jurisdictions); err != nil {
+		return nil, fmt.Errorf("selecting tax jurisdictions: %w", err)
+	}
+
+	return jurisdictions, nil
+}
+
+// AddRate - AddRate is a function that adds a tax rate to a jurisdiction.
+// Rates are never overwritten, a new rate is added with the date it takes effect and the old one ends then.
+//
+// @param ctx - context.Context
+// @param payload - *RateRequest
+// @return rate
+// @return error
+func AddRate(ctx context.Context, payload *RateRequest) (*Rate, error) {
+	now := time.Now().UTC()
+
+	// check the rate
+	percent, err := ParsePercent(payload.Percent)
+	if err != nil {
+		return nil, err
+	}
+	rate := Rate{
+		Id:            uuid.New().String(),
+		Jurisdiction:  strings.ToUpper(strings.TrimSpace(payload.Jurisdiction)),
+		Class:         strings.TrimSpace(payload.Class),
+		Name:          payload.Name,
+		Percent:       FormatPercent(percent),
+		EffectiveFrom: now,
+		CreatedAt:     now,
+	}
+	if payload.EffectiveFrom != nil {
+		rate.EffectiveFrom = payload.EffectiveFrom.UTC()
+	}
+	if payload.EffectiveTo != nil {
+		to := payload.EffectiveTo.UTC()
+		if !to.After(rate.EffectiveFrom) {
+			return nil, fmt.Errorf("%w: the rate must end after it takes effect", ErrInvalidRate)
+		}
+		rate.EffectiveTo = &to
+	}
+
+	// check the jurisdiction and the class exist
+	if _, err := GetJurisdiction(ctx, rate.Jurisdiction); err != nil {
+		return nil, err
+	}
+	if err := checkClass(ctx, rate.Class); err != nil {
+		return nil, err
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO tax_rates (id, jurisdiction, class, name, percent, effective_from, effective_to, created_at)
+    VALUES (:id, :jurisdiction, :class, :name, :percent, :effective_from, :effective_to, :created_at)
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, taxDatabase(), q, rate); err != nil {
+		return nil, fmt.Errorf("inserting tax rate: %w", err)
+	}
+
+	return &rate, nil
+}
+
+// Rates - Rates is a function that gets every rate of a jurisdiction, latest first.
+//
+// @param ctx - context.Context
+// @param jurisdiction - string
+// @return rates
+// @return error
+func Rates(ctx context.Context, jurisdiction string) ([]Rate, error) {
+	rates := make([]Rate, 0)
+
+	// query statement to be executed
+	q := `
+    SELECT * FROM tax_rates WHERE jurisdiction = :jurisdiction
+    ORDER BY class, name, effective_from DESC
+  `
+
+	// execute query
+	if err := database.NamedSliceQuery(ctx, taxDatabase(), q, map[string]interface{}{
+		"jurisdiction": strings.ToUpper(strings.TrimSpace(jurisdiction)),
+	}, &rates); err != nil {
+		return nil, fmt.Errorf("selecting tax rates: %w", err)
+	}
+
+	// drop the trailing zeros of the stored percentages
+	for i := range rates {
+		if percent, err := ParsePercent(rates[i].Percent); err == nil {
+			rates[i].Percent = FormatPercent(percent)
+		}
+	}
+
+	return rates, nil
+}
+
+// Quote - Quote is a function that works out the tax of a cart or order in a jurisdiction.
+// The class of a product is its own class, the class of its category or the default class.
+//
+// @param ctx - context.Context
+// @param payload - *CalculateRequest
+// @param at - time.Time
+// @return breakdown
+// @return error
+func Quote(ctx context.Context, payload *CalculateRequest, at time.Time) (*Breakdown, error) {
+	// get the jurisdiction and its rates
+	jurisdiction, err := GetJurisdiction(ctx, payload.Jurisdiction)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := Rates(ctx, jurisdiction.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	// get the prices and classes of the products
+	ids := make([]string, 0, len(payload.Lines))
+	for _, line := range payload.Lines {
+		ids = append(ids, line.ProductId)
+	}
+
+	// query statement to be executed
+	q := `
+    SELECT p.id, p.price, COALESCE(ptc.class, ctc.class, :class) AS class
+    FROM products p
+    LEFT JOIN product_tax_classes ptc ON ptc.product_id = p.id
+    LEFT JOIN category_tax_classes ctc ON ctc.category_id = p.category_id
+    WHERE p.id = ANY(CAST(:ids AS UUID[]))
+  `
+
+	// execute query
+	var products []struct {
+		Id    string      `db:"id"`
+		Price money.Money `db:"price"`
+		Class string      `db:"class"`
+	}
+	if err := database.NamedSliceQuery(ctx, taxDatabase(), q, map[string]interface{}{"ids": ids, "class": DefaultClass}, &products); err != nil {
+		return nil, fmt.Errorf("selecting product tax classes: %w", err)
+	}
+	byId := make(map[string]int, len(products))
+	for i, product := range products {
+		byId[product.Id] = i
+	}
+
+	// create the lines
+	lines := make([]Line, 0, len(payload.Lines))
+	for i, item := range payload.Lines {
+		k, ok := byId[item.ProductId]
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrProductNotFound, item.ProductId)
+		}
+		product := products[k]
+
+		// the amount is the product price times the quantity unless given
+		amount := product.Price
+		if item.Amount != nil {
+			amount = *item.Amount
+		} else if amount, err = product.Price.Mul(int64(item.Quantity)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidLine, err)
+		}
+
+		lines = append(lines, Line{
+			Id:        strconv.Itoa(i + 1),
+			ProductId: product.Id,
+			Class:     product.Class,
+			Quantity:  item.Quantity,
+			Amount:    amount,
+		})
+	}
+
+	return Calculate(*jurisdiction, rates, lines, at)
+}
@@ -0,0 +1,15 @@
+package tax
+
+import "errors"
+
+var (
+	ErrNotFound             = errors.New("tax rate not found")
+	ErrClassNotFound        = errors.New("tax class not found")
+	ErrJurisdictionNotFound = errors.New("tax jurisdiction not found")
+	ErrProductNotFound      = errors.New("product not found")
+	ErrCategoryNotFound     = errors.New("category not found")
+	ErrAlreadyExists        = errors.New("tax class already exists")
+	ErrNoRate               = errors.New("no tax rate for the class")
+	ErrInvalidRate          = errors.New("invalid tax rate")
+	ErrInvalidLine          = errors.New("invalid line")
+)
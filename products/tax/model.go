@@ -0,0 +1,139 @@
+package tax
+
+import (
+	"time"
+
+	"encore.app/pkg/money"
+)
+
+const (
+	// DefaultClass - the class of products without a class, directly or through their category
+	DefaultClass = "standard"
+
+	PriceModeExclusive = "exclusive" // prices do not include tax, tax is added on top
+	PriceModeInclusive = "inclusive" // prices include tax, tax is taken out of them
+
+	RoundingLine  = "line"  // tax is rounded on every line
+	RoundingTotal = "total" // tax is rounded once on the total and spread over the lines
+)
+
+type Class struct {
+	Code        string    `json:"code" db:"code"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
+
+type ClassRequest struct {
+	Code        string `json:"code" validate:"required,max=50,lowercase"` // e.g. groceries
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description" validate:"omitempty"`
+}
+
+type ClassesResponse struct {
+	Classes []Class `json:"data"`
+}
+
+type AssignClassRequest struct {
+	Class string `json:"class" validate:"omitempty,max=50"` // removes the class when empty
+}
+
+type Jurisdiction struct {
+	Code      string    `json:"code" db:"code"` // e.g. GH or US-CA
+	Name      string    `json:"name" db:"name"`
+	PriceMode string    `json:"priceMode" db:"price_mode"`
+	Rounding  string    `json:"rounding" db:"rounding"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type JurisdictionRequest struct {
+	Code      string `json:"code" validate:"required,max=20"`
+	Name      string `json:"name" validate:"required,max=255"`
+	PriceMode string `json:"priceMode" validate:"required,oneof=exclusive inclusive"`
+	Rounding  string `json:"rounding" validate:"required,oneof=line total"`
+}
+
+type JurisdictionsResponse struct {
+	Jurisdictions []Jurisdiction `json:"data"`
+}
+
+// Rate - a tax of a class in a jurisdiction, classes taxed more than once have a rate for every tax
+type Rate struct {
+	Id            string     `json:"id" db:"id"`
+	Jurisdiction  string     `json:"jurisdiction" db:"jurisdiction"`
+	Class         string     `json:"class" db:"class"`
+	Name          string     `json:"name" db:"name"`       // e.g. VAT or county tax
+	Percent       string     `json:"percent" db:"percent"` // e.g. 12.5
+	EffectiveFrom time.Time  `json:"effectiveFrom" db:"effective_from"`
+	EffectiveTo   *time.Time `json:"effectiveTo" db:"effective_to"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type RateRequest struct {
+	Jurisdiction  string     `json:"jurisdiction" validate:"required,max=20"`
+	Class         string     `json:"class" validate:"required,max=50"`
+	Name          string     `json:"name" validate:"required,max=255"`
+	Percent       string     `json:"percent" validate:"required,numeric"`
+	EffectiveFrom *time.Time `json:"effectiveFrom" validate:"omitempty"` // starts immediately when empty
+	EffectiveTo   *time.Time `json:"effectiveTo" validate:"omitempty"`
+}
+
+type RatesQuery struct {
+	Jurisdiction string `json:"jurisdiction" query:"jurisdiction" validate:"required,max=20"`
+}
+
+type RatesResponse struct {
+	Rates []Rate `json:"data"`
+}
+
+// Line - an amount to tax
+type Line struct {
+	Id        string      `json:"id"`
+	ProductId string      `json:"productId"`
+	Class     string      `json:"class"`
+	Quantity  int         `json:"quantity"`
+	Amount    money.Money `json:"amount"` // the price of the line, with or without tax depending on the price mode
+}
+
+// Breakdown - the tax of every line and of the whole
+type Breakdown struct {
+	Jurisdiction string         `json:"jurisdiction"`
+	PriceMode    string         `json:"priceMode"`
+	Rounding     string         `json:"rounding"`
+	Lines        []LineTax      `json:"lines"`
+	Net          money.Money    `json:"net"`
+	Tax          money.Money    `json:"tax"`
+	Gross        money.Money    `json:"gross"`
+	Components   []ComponentTax `json:"components"` // the total of every tax
+}
+
+// LineTax - the tax of a line
+type LineTax struct {
+	Id         string         `json:"id"`
+	ProductId  string         `json:"productId"`
+	Class      string         `json:"class"`
+	Quantity   int            `json:"quantity"`
+	Net        money.Money    `json:"net"`
+	Tax        money.Money    `json:"tax"`
+	Gross      money.Money    `json:"gross"`
+	Components []ComponentTax `json:"components"`
+}
+
+// ComponentTax - the amount of one tax
+type ComponentTax struct {
+	Name    string      `json:"name"`
+	Percent string      `json:"percent"`
+	Tax     money.Money `json:"tax"`
+}
+
+type CalculateLine struct {
+	ProductId string       `json:"productId" validate:"required,uuid"`
+	Quantity  int          `json:"quantity" validate:"required,min=1"`
+	Amount    *money.Money `json:"amount" validate:"omitempty"` // the line price after discounts, the product price times the quantity when empty
+}
+
+type CalculateRequest struct {
+	Jurisdiction string          `json:"jurisdiction" validate:"required,max=20"`
+	Lines        []CalculateLine `json:"lines" validate:"required,min=1,max=200,dive"`
+}
@@ -0,0 +1,265 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/products/tax"
+)
+
+// =====================================================================================================================
+// TAXES
+// =====================================================================================================================
+
+// CreateTaxClass - Create a tax class, e.g. groceries or alcohol
+//
+//	@param ctx - context.Context
+//	@param payload - *tax.ClassRequest
+//	@return class
+//	@return error
+//
+// encore:api auth method=POST path=/tax/classes
+func CreateTaxClass(ctx context.Context, payload *tax.ClassRequest) (*tax.Class, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &tax.Class{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &tax.Class{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the class
+	class, err := tax.CreateClass(ctx, payload)
+	if err != nil {
+		return &tax.Class{}, taxError(err)
+	}
+
+	return class, nil
+}
+
+// ListTaxClasses - List the tax classes
+//
+//	@param ctx - context.Context
+//	@return classes
+//	@return error
+//
+// encore:api auth method=GET path=/tax/classes
+func ListTaxClasses(ctx context.Context) (*tax.ClassesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &tax.ClassesResponse{}, err
+	}
+
+	// get the classes
+	classes, err := tax.ListClasses(ctx)
+	if err != nil {
+		return &tax.ClassesResponse{}, err
+	}
+
+	return &tax.ClassesResponse{Classes: classes}, nil
+}
+
+// SetProductTaxClass - Set the tax class of a product, the class of its category applies when it is empty
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *tax.AssignClassRequest
+//	@return error
+//
+// encore:api auth method=PUT path=/products/:id/tax-class
+func SetProductTaxClass(ctx context.Context, id string, payload *tax.AssignClassRequest) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// set the class
+	if err := tax.AssignProduct(ctx, id, payload.Class); err != nil {
+		return taxError(err)
+	}
+
+	return nil
+}
+
+// SetCategoryTaxClass - Set the tax class of the products of a category, the standard class applies when it is empty
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *tax.AssignClassRequest
+//	@return error
+//
+// encore:api auth method=PUT path=/categories/:id/tax-class
+func SetCategoryTaxClass(ctx context.Context, id string, payload *tax.AssignClassRequest) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// set the class
+	if err := tax.AssignCategory(ctx, id, payload.Class); err != nil {
+		return taxError(err)
+	}
+
+	return nil
+}
+
+// SaveTaxJurisdiction - Create a tax jurisdiction or replace its price mode and rounding
+//
+//	@param ctx - context.Context
+//	@param payload - *tax.JurisdictionRequest
+//	@return jurisdiction
+//	@return error
+//
+// encore:api auth method=POST path=/tax/jurisdictions
+func SaveTaxJurisdiction(ctx context.Context, payload *tax.JurisdictionRequest) (*tax.Jurisdiction, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &tax.Jurisdiction{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &tax.Jurisdiction{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// save the jurisdiction
+	jurisdiction, err := tax.SaveJurisdiction(ctx, payload)
+	if err != nil {
+		return &tax.Jurisdiction{}, taxError(err)
+	}
+
+	return jurisdiction, nil
+}
+
+// ListTaxJurisdictions - List the tax jurisdictions
+//
+//	@param ctx - context.Context
+//	@return jurisdictions
+//	@return error
+//
+// encore:api public method=GET path=/tax/jurisdictions
+func ListTaxJurisdictions(ctx context.Context) (*tax.JurisdictionsResponse, error) {
+	// get the jurisdictions
+	jurisdictions, err := tax.ListJurisdictions(ctx)
+	if err != nil {
+		return &tax.JurisdictionsResponse{}, err
+	}
+
+	return &tax.JurisdictionsResponse{Jurisdictions: jurisdictions}, nil
+}
+
+// AddTaxRate - Add a tax rate of a class in a jurisdiction, taking effect now or at a later time
+//
+//	@param ctx - context.Context
+//	@param payload - *tax.RateRequest
+//	@return rate
+//	@return error
+//
+// encore:api auth method=POST path=/tax/rates
+func AddTaxRate(ctx context.Context, payload *tax.RateRequest) (*tax.Rate, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &tax.Rate{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &tax.Rate{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// add the rate
+	rate, err := tax.AddRate(ctx, payload)
+	if err != nil {
+		return &tax.Rate{}, taxError(err)
+	}
+
+	return rate, nil
+}
+
+// ListTaxRates - List every tax rate of a jurisdiction, past and scheduled included
+//
+//	@param ctx - context.Context
+//	@param params - *tax.RatesQuery
+//	@return rates
+//	@return error
+//
+// encore:api auth method=GET path=/tax/rates
+func ListTaxRates(ctx context.Context, params *tax.RatesQuery) (*tax.RatesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &tax.RatesResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &tax.RatesResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// get the rates
+	rates, err := tax.Rates(ctx, params.Jurisdiction)
+	if err != nil {
+		return &tax.RatesResponse{}, err
+	}
+
+	return &tax.RatesResponse{Rates: rates}, nil
+}
+
+// CalculateTax - Work out the tax of every line of a cart or order and of the whole
+//
+//	@param ctx - context.Context
+//	@param payload - *tax.CalculateRequest
+//	@return breakdown
+//	@return error
+//
+// encore:api public method=POST path=/tax/calculate
+func CalculateTax(ctx context.Context, payload *tax.CalculateRequest) (*tax.Breakdown, error) {
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &tax.Breakdown{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// work out the tax
+	breakdown, err := tax.Quote(ctx, payload, time.Now())
+	if err != nil {
+		return &tax.Breakdown{}, taxError(err)
+	}
+
+	return breakdown, nil
+}
+
+// taxError - maps tax store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func taxError(err error) error {
+	switch {
+	case errors.Is(err, tax.ErrNotFound), errors.Is(err, tax.ErrClassNotFound), errors.Is(err, tax.ErrJurisdictionNotFound),
+		errors.Is(err, tax.ErrProductNotFound), errors.Is(err, tax.ErrCategoryNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, tax.ErrAlreadyExists):
+		return &errs.Error{Code: errs.AlreadyExists, Message: err.Error()}
+	case errors.Is(err, tax.ErrInvalidRate), errors.Is(err, tax.ErrInvalidLine):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	case errors.Is(err, tax.ErrNoRate):
+		return &errs.Error{Code: errs.FailedPrecondition, Message: err.Error()}
+	default:
+		return err
+	}
+}
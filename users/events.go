@@ -0,0 +1,69 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"encore.dev/cron"
+	"encore.dev/pubsub"
+	"encore.dev/rlog"
+
+	"encore.app/pkg/events"
+	"encore.app/pkg/events/topics"
+	"encore.app/pkg/outbox"
+	"encore.app/users/store"
+)
+
+// =====================================================================================================================
+// EVENTS
+// =====================================================================================================================
+
+// publish the events saved with the changes of the users
+var _ = cron.NewJob("relay-user-events", cron.JobConfig{
+	Title:    "Publish the events of the users",
+	Every:    1 * cron.Minute,
+	Endpoint: RelayUserEvents,
+})
+
+// RelayUserEvents - Publish the events saved in the outbox of the users
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/users/events/relay
+func RelayUserEvents(ctx context.Context) (*outbox.RelayResponse, error) {
+	// publish the events
+	response, err := store.RelayEvents(ctx, topics.Publish, time.Now())
+	if err != nil {
+		return &outbox.RelayResponse{}, err
+	}
+
+	rlog.Info("users.RelayUserEvents", "sent", response.Sent, "failed", response.Failed, "purged", response.Purged)
+
+	return response, nil
+}
+
+// record the cleanups the services report for deleted users
+var _ = pubsub.NewSubscription(topics.CleanupCompleted, "users-cleanup-completed", pubsub.SubscriptionConfig[*events.CleanupCompleted]{
+	Handler: CompleteUserCleanup,
+})
+
+// CompleteUserCleanup - Record that a service removed what it kept for a deleted user
+//
+//	@param ctx - context.Context
+//	@param event - *events.CleanupCompleted
+//	@return error
+func CompleteUserCleanup(ctx context.Context, event *events.CleanupCompleted) error {
+	if !events.Accept(event.Meta, 1) {
+		return nil
+	}
+
+	if err := store.CompleteCleanup(ctx, "users-cleanup-completed", event); err != nil {
+		return err
+	}
+
+	rlog.Info("users.CompleteUserCleanup", "id", event.Meta.Id, "user", event.UserId, "service", event.Service)
+
+	return nil
+}
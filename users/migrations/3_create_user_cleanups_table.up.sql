@@ -0,0 +1,17 @@
+-- the users deleted, the services keeping data for them remove it once the deletion event reaches them
+CREATE TABLE user_deletions (
+  user_id         UUID NOT NULL PRIMARY KEY,
+  event_id        UUID NOT NULL,
+  deleted_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the cleanups each service reported for a deleted user
+CREATE TABLE user_cleanups (
+  user_id         UUID NOT NULL REFERENCES user_deletions (user_id) ON DELETE CASCADE,
+  service         VARCHAR(100) NOT NULL,
+  event_id        UUID NOT NULL,
+  -- the rows removed, by what they were
+  removed         JSONB NOT NULL DEFAULT '{}',
+  completed_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, service)
+);
@@ -0,0 +1,15 @@
+-- the services expected to clean up after a deletion, recorded when the user is deleted, so that services added
+-- later are not waited for on older deletions
+ALTER TABLE user_deletions ADD COLUMN services TEXT[] NOT NULL DEFAULT '{}';
+
+-- the earlier deletions expected the first services, and any service that has reported since
+UPDATE user_deletions d SET services = ARRAY(
+  SELECT DISTINCT s FROM (
+    SELECT unnest(ARRAY['products', 'customers']) AS s
+    UNION
+    SELECT c.service FROM user_cleanups c WHERE c.user_id = d.user_id
+  ) expected ORDER BY s
+);
+
+-- a user deleted before deletions were recorded has no UserDeleted event to point to
+ALTER TABLE user_deletions ALTER COLUMN event_id DROP NOT NULL;
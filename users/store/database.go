@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -12,8 +13,11 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
+	"encore.app/pkg/condition"
 	"encore.app/pkg/database"
+	"encore.app/pkg/events"
 	"encore.app/pkg/middleware"
+	"encore.app/pkg/outbox"
 	"encore.app/pkg/pagination"
 	"encore.app/pkg/slice"
 )
@@ -102,8 +106,15 @@ func Create(ctx context.Context, payload *SignupPayload) (*User, error) {
 	// ON CONFLICT (email) DO NOTHING
 	//   ON CONFLICT (username) DO NOTHING
 
-	// insert user into database
-	if err := database.NamedExecQuery(ctx, usersDatabase, query, user); err != nil {
+	// insert user into database and let other services know
+	if err := database.Transaction(ctx, usersDatabase, func(tx *sqlx.Tx) error {
+		if err := database.NamedExecQuery(ctx, tx, query, user); err != nil {
+			return err
+		}
+
+		event := &events.UserCreated{Meta: events.NewMeta(events.TypeUserCreated, user.CreatedAt), UserId: user.Id, Email: user.Email, Phone: user.Phone, Username: user.Username, Roles: user.Roles}
+		return outbox.Add(ctx, tx, event.Meta, event)
+	}); err != nil {
 		return &User{}, err
 	}
 
@@ -232,16 +243,36 @@ func UpdateRole(ctx context.Context, id string) error {
 		roles = append(roles, middleware.RoleAdmin)
 	}
 
-	// update user in database
-	if err := database.NamedExecQuery(ctx, usersDatabase, "UPDATE users SET roles = :roles, updated_at = :updated_at WHERE id = :id", map[string]interface{}{
-		"roles":      roles,
-		"updated_at": time.Now(),
-		"id":         user.Id,
-	}); err != nil {
-		return err
-	}
+	// update user in database and let other services know
+	return database.Transaction(ctx, usersDatabase, func(tx *sqlx.Tx) error {
+		if err := database.NamedExecQuery(ctx, tx, "UPDATE users SET roles = :roles, updated_at = :updated_at WHERE id = :id", map[string]interface{}{
+			"roles":      roles,
+			"updated_at": time.Now(),
+			"id":         user.Id,
+		}); err != nil {
+			return err
+		}
 
-	return nil
+		event := &events.RoleChanged{Meta: events.NewMeta(events.TypeRoleChanged, time.Now()), UserId: user.Id, Roles: roles, PreviousRoles: user.Roles}
+		return outbox.Add(ctx, tx, event.Meta, event)
+	})
+}
+
+// usersSchema - the fields of a user allowed in list queries
+var usersSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "name", Column: "name", Type: pagination.String, Sortable: true},
+		{Name: "username", Column: "username", Type: pagination.String, Sortable: true},
+		{Name: "email", Column: "email", Type: pagination.String, Sortable: true},
+		{Name: "phone", Column: "phone", Type: pagination.String},
+		{Name: "roles", Column: "roles", Type: pagination.Opaque},
+		{Name: "avatar", Column: "avatar", Type: pagination.Opaque},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-createdAt",
 }
 
 // GetAll - GetAll is a function that gets all users.
@@ -252,45 +283,53 @@ func UpdateRole(ctx context.Context, id string) error {
 func GetAll(ctx context.Context, pag *pagination.Options) (*PaginatedUsersResponse, error) {
 	var users []User
 
-	// create query
-	countQuery := `SELECT COUNT(*) FROM users`
-
-	// get total count of users
-	// get count of categories
-	count, err := database.NamedCountQuery(ctx, usersDatabase, countQuery, map[string]interface{}{})
+	// parse the filter, sort and field selection
+	statement, err := usersSchema.Parse(pag.Query())
 	if err != nil {
-		return nil, fmt.Errorf("getting count of users: %w", err)
+		return nil, err
+	}
+
+	// get total count of users unless it is skipped
+	count := 0
+	if !pag.SkipTotal {
+		count, err = database.NamedCountQuery(ctx, usersDatabase, fmt.Sprintf("SELECT COUNT(*) FROM users %v", statement.Where()), statement.Args)
+		if err != nil {
+			return nil, fmt.Errorf("getting count of users: %w", err)
+		}
 	}
 
-	// set limit to 20 if it is less than 0 or greater than count
-	if pag.Limit < 1 || pag.Limit > count {
+	// set limit to 50 if it is less than 1 or greater than the maximum
+	if pag.Limit < 1 || pag.Limit > pagination.MaxLimit {
 		pag.Limit = 50
 	}
 
-	// calculate for pagination
-	// var paginate pagination.Paginate
 	// initialize pagination
 	paging := pagination.New(pag.Page, pag.Limit, count)
 
-	// if page is greater than total pages, set page to total pages
-	if pag.Page > paging.Pages() {
-		paging.SetPage(paging.Pages())
+	// continue from the cursor or from the offset of the page
+	if len(pag.Cursor) > 0 {
+		if err := statement.ApplyCursor(pag.Cursor); err != nil {
+			return nil, err
+		}
+	} else {
+		statement.Offset = pagination.Offset(pag.Page, pag.Limit, condition.Ternary(pag.SkipTotal, 0, paging.Pages()))
 	}
 
-	// query to set offset and limit
-	const query = `SELECT * FROM users LIMIT :limit OFFSET :offset`
+	// query to set filter, order, offset and limit, fetching one more user to find the next page
+	query := fmt.Sprintf("SELECT %v FROM users %v %v LIMIT :limit OFFSET :offset", statement.Select(), statement.Where(), statement.OrderBy())
 	// data to be passed to the query
-	p := struct {
-		Limit  int `db:"limit" json:"limit" validate:"omitempty" url:"limit"`
-		Offset int `db:"offset" json:"offset" validate:"omitempty" url:"offset"`
-	}{
-		Limit:  paging.PerPage(),
-		Offset: paging.Offset(),
-	}
+	statement.Args["limit"] = pag.Limit + 1
+	statement.Args["offset"] = statement.Offset
 
 	// execute query
-	if err := database.NamedSliceQuery(ctx, usersDatabase, query, p, &users); err != nil {
-		return nil, fmt.Errorf("getting categories: %w", err)
+	if err := database.NamedSliceQuery(ctx, usersDatabase, query, statement.Args, &users); err != nil {
+		return nil, fmt.Errorf("getting users: %w", err)
+	}
+
+	// create the cursors of the previous and next pages
+	window, err := statement.Window(&users, pag.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("getting users: %w", err)
 	}
 
 	// create users for response
@@ -310,13 +349,21 @@ func GetAll(ctx context.Context, pag *pagination.Options) (*PaginatedUsersRespon
 		})
 	}
 
+	// leave out the fields not selected
+	data, err := statement.Project(usersResponse)
+	if err != nil {
+		return nil, fmt.Errorf("getting users: %w", err)
+	}
+
 	return &PaginatedUsersResponse{
-		TotalPages:      paging.Pages(),
-		Total:           paging.Total(),
-		CurrentPage:     paging.Page(),
-		HasPreviousPage: paging.HasPrevious(),
-		HasNextPage:     paging.HasNext(),
-		Users:           usersResponse,
+		TotalPages:      condition.Ternary(pag.SkipTotal, 0, paging.Pages()),
+		Total:           count,
+		CurrentPage:     condition.Ternary(len(pag.Cursor) > 0, 0, statement.Offset/pag.Limit+1),
+		HasPreviousPage: window.HasPrevious,
+		HasNextPage:     window.HasNext,
+		PrevCursor:      window.PrevCursor,
+		NextCursor:      window.NextCursor,
+		Users:           data,
 	}, nil
 }
 
@@ -337,13 +384,138 @@ func Delete(ctx context.Context, id string) error {
 		return fmt.Errorf("cannot delete super admin")
 	}
 
-	// delete user from database
-	if err := database.NamedExecQuery(ctx, usersDatabase, "DELETE FROM users WHERE id = :id", map[string]interface{}{
-		"id": user.Id,
-	}); err != nil {
-		return err
+	// delete user from database and let other services remove what they keep for the user
+	return database.Transaction(ctx, usersDatabase, func(tx *sqlx.Tx) error {
+		if err := database.NamedExecQuery(ctx, tx, "DELETE FROM users WHERE id = :id", map[string]interface{}{
+			"id": user.Id,
+		}); err != nil {
+			return err
+		}
+
+		// record the deletion with the services expected to clean up, so their cleanups can be followed
+		event := &events.UserDeleted{Meta: events.NewMeta(events.TypeUserDeleted, time.Now()), UserId: user.Id}
+		if err := database.NamedExecQuery(ctx, tx, `
+      INSERT INTO user_deletions (user_id, event_id, services, deleted_at) VALUES (:user_id, :event_id, :services, :deleted_at)
+    `, map[string]interface{}{"user_id": user.Id, "event_id": event.Meta.Id, "services": CleanupServices, "deleted_at": event.Meta.OccurredAt}); err != nil {
+			return fmt.Errorf("inserting user deletion: %w", err)
+		}
+
+		return outbox.Add(ctx, tx, event.Meta, event)
+	})
+}
+
+// CompleteCleanup - CompleteCleanup is a function that records the cleanup a service reported for a deleted user.
+// A cleanup reported again changes nothing.
+//
+//	@param ctx - context.Context
+//	@param subscription - the name of the subscription handling the event
+//	@param event - *events.CleanupCompleted
+//	@return error
+func CompleteCleanup(ctx context.Context, subscription string, event *events.CleanupCompleted) error {
+	removed, err := json.Marshal(event.Removed)
+	if err != nil {
+		return fmt.Errorf("encoding removed rows: %w", err)
 	}
 
-	// delete user from database
-	return nil
+	return database.Transaction(ctx, usersDatabase, func(tx *sqlx.Tx) error {
+		claimed, err := outbox.Claim(ctx, tx, subscription, event.Meta.Id)
+		if err != nil || !claimed {
+			return err
+		}
+
+		// query statement to be executed, a user deleted before deletions were recorded gets recorded now,
+		// without the UserDeleted event it never had and expecting the services of today
+		q := `
+      WITH deletion AS (
+        INSERT INTO user_deletions (user_id, services, deleted_at) VALUES (:user_id, :services, :completed_at)
+        ON CONFLICT (user_id) DO NOTHING
+      )
+      INSERT INTO user_cleanups (user_id, service, event_id, removed, completed_at)
+      VALUES (:user_id, :service, :event_id, :removed, :completed_at)
+      ON CONFLICT (user_id, service) DO NOTHING
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, map[string]interface{}{
+			"user_id":      event.UserId,
+			"services":     CleanupServices,
+			"service":      event.Service,
+			"event_id":     event.Meta.Id,
+			"removed":      removed,
+			"completed_at": event.Meta.OccurredAt,
+		}); err != nil {
+			return fmt.Errorf("inserting user cleanup: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetCleanupStatus - GetCleanupStatus is a function that gets whether every service removed what it kept for a
+// deleted user.
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return status
+//	@return error
+func GetCleanupStatus(ctx context.Context, id string) (*CleanupStatus, error) {
+	data := map[string]interface{}{"user_id": id}
+
+	// get the deletion
+	var deletion struct {
+		UserId    string    `db:"user_id"`
+		EventId   *string   `db:"event_id"`
+		Services  []string  `db:"services"`
+		DeletedAt time.Time `db:"deleted_at"`
+	}
+	if err := database.NamedStructQuery(ctx, usersDatabase, "SELECT * FROM user_deletions WHERE user_id = :user_id", data, &deletion); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrDeletionNotFound
+		}
+		return nil, fmt.Errorf("selecting user deletion: %w", err)
+	}
+
+	// get the cleanups reported
+	rows := make([]struct {
+		Service     string    `db:"service"`
+		Removed     []byte    `db:"removed"`
+		CompletedAt time.Time `db:"completed_at"`
+	}, 0)
+	if err := database.NamedSliceQuery(ctx, usersDatabase, `
+    SELECT service, removed, completed_at FROM user_cleanups WHERE user_id = :user_id
+  `, data, &rows); err != nil {
+		return nil, fmt.Errorf("selecting user cleanups: %w", err)
+	}
+
+	// list every service expected when the user was deleted, completed or not
+	status := &CleanupStatus{UserId: deletion.UserId, DeletedAt: deletion.DeletedAt, Completed: true, Services: make([]Cleanup, 0, len(deletion.Services))}
+	for _, service := range deletion.Services {
+		cleanup := Cleanup{Service: service, Removed: map[string]int{}}
+		for _, row := range rows {
+			if row.Service != service {
+				continue
+			}
+			if err := json.Unmarshal(row.Removed, &cleanup.Removed); err != nil {
+				return nil, fmt.Errorf("decoding removed rows: %w", err)
+			}
+			completedAt := row.CompletedAt
+			cleanup.Completed = true
+			cleanup.CompletedAt = &completedAt
+		}
+		status.Completed = status.Completed && cleanup.Completed
+		status.Services = append(status.Services, cleanup)
+	}
+
+	return status, nil
+}
+
+// RelayEvents - RelayEvents is a function that publishes the events saved in the outbox of the users.
+//
+//	@param ctx - context.Context
+//	@param publish - outbox.Publisher
+//	@param now - time.Time
+//	@return response
+//	@return error
+func RelayEvents(ctx context.Context, publish outbox.Publisher, now time.Time) (*outbox.RelayResponse, error) {
+	return outbox.Relay(ctx, usersDatabase, publish, now)
 }
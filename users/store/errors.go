@@ -3,5 +3,6 @@ package store
 import "errors"
 
 var (
-	ErrNotFound = errors.New("user not found")
+	ErrNotFound         = errors.New("user not found")
+	ErrDeletionNotFound = errors.New("user deletion not found")
 )
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -50,22 +51,20 @@ type UserResponse struct {
 }
 
 type PaginatedUsersResponse struct {
-	Users           []UserResponse `json:"data"`
-	Total           int            `json:"total" db:"total"`
-	TotalPages      int            `json:"totalPages" db:"totalPages"`
-	CurrentPage     int            `json:"currentPage" db:"currentPage"`
-	HasPreviousPage bool           `json:"hasPreviousPage" db:"hasPreviousPage"`
-	HasNextPage     bool           `json:"hasNextPage" db:"hasNextPage"`
+	Users           []json.RawMessage `json:"data"` // users with the fields selected
+	Total           int               `json:"total" db:"total"`
+	TotalPages      int               `json:"totalPages" db:"totalPages"`
+	CurrentPage     int               `json:"currentPage" db:"currentPage"`
+	HasPreviousPage bool              `json:"hasPreviousPage" db:"hasPreviousPage"`
+	HasNextPage     bool              `json:"hasNextPage" db:"hasNextPage"`
+	PrevCursor      string            `json:"prevCursor" db:"prevCursor"`
+	NextCursor      string            `json:"nextCursor" db:"nextCursor"`
 }
 
 type UserUpdateResponse struct {
 	Message string `json:"message"`
 }
 
-type DeleteUserEvent struct {
-	Id string `json:"id" db:"id"`
-}
-
 type LoginPayload struct {
 	Email    string `json:"email" validate:"required,email"` // required
 	Password string `json:"password" validate:"required"`    // required
@@ -77,3 +76,22 @@ type Response struct {
 	Token   string        `json:"token"`
 	Payload *UserResponse `json:"payload"`
 }
+
+// CleanupServices - the services keeping data for a user, each removes it once the user is deleted and reports back.
+// They are recorded on every deletion, so a service added here is only expected on the deletions that follow.
+var CleanupServices = []string{"products", "customers", "notifications"}
+
+// Cleanup - what a service removed for a deleted user
+type Cleanup struct {
+	Service     string         `json:"service"`
+	Completed   bool           `json:"completed"`
+	Removed     map[string]int `json:"removed"` // the rows removed, by what they were
+	CompletedAt *time.Time     `json:"completedAt"`
+}
+
+type CleanupStatus struct {
+	UserId    string    `json:"userId"`
+	DeletedAt time.Time `json:"deletedAt"`
+	Completed bool      `json:"completed"` // every service removed what it kept for the user
+	Services  []Cleanup `json:"services"`
+}
@@ -10,8 +10,10 @@ import (
 
 	"encore.dev/beta/auth"
 	"encore.dev/beta/errs"
+	"encore.dev/rlog"
 	"github.com/go-playground/validator/v10"
 
+	"encore.app/customers"
 	"encore.app/pkg/middleware"
 	"encore.app/pkg/pagination"
 	"encore.app/users/store"
@@ -111,14 +113,23 @@ func Login(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Get the date of birth from the customer profile, the token is still issued without it
+	var dateOfBirth string
+	if profile, err := customers.GetDateOfBirth(req.Context(), user.Id); err != nil {
+		rlog.Warn("users.Login", "dateOfBirth", err)
+	} else {
+		dateOfBirth = profile.DateOfBirth
+	}
+
 	// Generate tokens
 	token, err := middleware.GetToken(&middleware.User{
-		Id:       user.Id,
-		Name:     user.Name,
-		Username: user.Username,
-		Email:    user.Email,
-		Phone:    user.Phone,
-		Roles:    user.Roles,
+		Id:          user.Id,
+		Name:        user.Name,
+		Username:    user.Username,
+		Email:       user.Email,
+		DateOfBirth: dateOfBirth,
+		Phone:       user.Phone,
+		Roles:       user.Roles,
 	})
 	if err != nil {
 		writeJSONErrorResponse(w, "authentication failed: unable to generate token", http.StatusInternalServerError)
@@ -221,6 +232,9 @@ func QueryAll(ctx context.Context, options *pagination.Options) (*store.Paginate
 	// query users
 	users, err := store.GetAll(ctx, options)
 	if err != nil {
+		if pagination.IsQueryError(err) {
+			return &store.PaginatedUsersResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+		}
 		return &store.PaginatedUsersResponse{}, fmt.Errorf("querying users: %w", err)
 	}
 
@@ -296,6 +310,32 @@ func Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// GetCleanupStatus - Get whether every service removed what it kept for a deleted user
+//
+//	@param ctx - context.Context
+//	@param id
+//	@return status
+//	@return error
+//
+// encore:api auth method=GET path=/users/:id/cleanup
+func GetCleanupStatus(ctx context.Context, id string) (*store.CleanupStatus, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.CleanupStatus{}, err
+	}
+
+	// get the status
+	status, err := store.GetCleanupStatus(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrDeletionNotFound) {
+			return &store.CleanupStatus{}, &errs.Error{Code: errs.NotFound, Message: err.Error()}
+		}
+		return &store.CleanupStatus{}, err
+	}
+
+	return status, nil
+}
+
 // Update - Updates a user
 //
 //	@param ctx - context.Context
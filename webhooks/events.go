@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"encore.dev/cron"
+	"encore.dev/pubsub"
+	"encore.dev/rlog"
+
+	"encore.app/pkg/events"
+	"encore.app/pkg/events/topics"
+	"encore.app/webhooks/store"
+)
+
+// =====================================================================================================================
+// EVENTS
+// =====================================================================================================================
+
+// queue a delivery of the events partners subscribe to
+var _ = pubsub.NewSubscription(topics.OrderPlaced, "webhooks-order-placed", pubsub.SubscriptionConfig[*events.OrderPlaced]{
+	Handler: QueueOrderPlaced,
+})
+var _ = pubsub.NewSubscription(topics.StockChanged, "webhooks-stock-changed", pubsub.SubscriptionConfig[*events.StockChanged]{
+	Handler: QueueStockChanged,
+})
+var _ = pubsub.NewSubscription(topics.PriceChanged, "webhooks-price-changed", pubsub.SubscriptionConfig[*events.PriceChanged]{
+	Handler: QueuePriceChanged,
+})
+
+// QueueOrderPlaced - Queue the deliveries of a placed order
+//
+//	@param ctx - context.Context
+//	@param event - *events.OrderPlaced
+//	@return error
+func QueueOrderPlaced(ctx context.Context, event *events.OrderPlaced) error {
+	return enqueue(ctx, event.Meta, event)
+}
+
+// QueueStockChanged - Queue the deliveries of a stock change
+//
+//	@param ctx - context.Context
+//	@param event - *events.StockChanged
+//	@return error
+func QueueStockChanged(ctx context.Context, event *events.StockChanged) error {
+	return enqueue(ctx, event.Meta, event)
+}
+
+// QueuePriceChanged - Queue the deliveries of a price change
+//
+//	@param ctx - context.Context
+//	@param event - *events.PriceChanged
+//	@return error
+func QueuePriceChanged(ctx context.Context, event *events.PriceChanged) error {
+	return enqueue(ctx, event.Meta, event)
+}
+
+// enqueue - creates the deliveries of an event for the subscriptions to it.
+//
+//	@param ctx - context.Context
+//	@param meta - the metadata of the event
+//	@param event - the event, sent as the body
+//	@return error
+func enqueue(ctx context.Context, meta events.Meta, event interface{}) error {
+	if !events.Accept(meta, 1) {
+		return nil
+	}
+
+	count, err := store.Enqueue(ctx, meta.Type, meta.Id, event)
+	if err != nil {
+		return err
+	}
+
+	rlog.Info("webhooks.enqueue", "id", meta.Id, "type", meta.Type, "deliveries", count)
+
+	return nil
+}
+
+// send the deliveries due
+var _ = cron.NewJob("dispatch-webhooks", cron.JobConfig{
+	Title:    "Send the webhook deliveries due",
+	Every:    1 * cron.Minute,
+	Endpoint: DispatchWebhooks,
+})
+
+// DispatchWebhooks - Send the webhook deliveries due, trying failed ones again later until they are dead
+//
+//	@param ctx - context.Context
+//	@return response
+//	@return error
+//
+// encore:api private method=POST path=/webhooks/dispatch
+func DispatchWebhooks(ctx context.Context) (*store.DispatchResponse, error) {
+	// send the deliveries
+	response, err := store.Dispatch(ctx, client, time.Now())
+	if err != nil {
+		return &store.DispatchResponse{}, err
+	}
+
+	rlog.Info("webhooks.DispatchWebhooks", "delivered", response.Delivered, "failed", response.Failed, "dead", response.Dead, "deferred", response.Deferred)
+
+	return response, nil
+}
@@ -0,0 +1,48 @@
+-- the endpoints of partners receiving events, managed by admins
+CREATE TABLE webhook_subscriptions (
+  id              UUID NOT NULL PRIMARY KEY,
+  url             VARCHAR(2000) NOT NULL,
+  description     VARCHAR(1000) NOT NULL DEFAULT '',
+  -- the events sent, e.g. {order.placed,product.stock_changed}
+  event_types     TEXT[] NOT NULL DEFAULT '{}',
+  -- signs every delivery, never returned after the subscription is created
+  secret          VARCHAR(255) NOT NULL,
+  active          BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- an event to send to a subscription, tried until it is delivered or dead
+CREATE TABLE webhook_deliveries (
+  id              UUID NOT NULL PRIMARY KEY,
+  subscription_id UUID NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+  event_id        UUID NOT NULL,
+  event_type      VARCHAR(100) NOT NULL,
+  payload         JSONB NOT NULL,
+  status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+  attempts        INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  last_status     INTEGER NOT NULL DEFAULT 0,
+  last_error      TEXT NOT NULL DEFAULT '',
+  delivered_at    TIMESTAMP,
+  created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+  -- an event delivered twice by pub/sub is sent once
+  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, created_at DESC);
+
+-- every request made for a delivery
+CREATE TABLE webhook_attempts (
+  id              UUID NOT NULL PRIMARY KEY,
+  delivery_id     UUID NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+  attempt         INTEGER NOT NULL,
+  status          INTEGER NOT NULL DEFAULT 0,
+  error           TEXT NOT NULL DEFAULT '',
+  duration_ms     BIGINT NOT NULL DEFAULT 0,
+  attempted_at    TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhook_attempts_delivery_idx ON webhook_attempts (delivery_id, attempt);
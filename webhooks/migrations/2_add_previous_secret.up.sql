@@ -0,0 +1,5 @@
+-- a rotated secret keeps signing the deliveries next to the new one until it expires,
+-- so receivers can switch to the new secret without rejecting deliveries
+ALTER TABLE webhook_subscriptions
+  ADD COLUMN previous_secret VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN previous_secret_expires_at TIMESTAMP;
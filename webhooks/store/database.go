@@ -0,0 +1,567 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"encore.app/pkg/database"
+	"encore.app/pkg/pagination"
+	"encore.app/pkg/retry"
+	"encore.app/pkg/slice"
+)
+
+// the webhooks database
+var webhooksDatabase = database.Lazy(func() *sqlx.DB {
+	return sqlx.NewDb(sqldb.Named("webhooks").Stdlib(), "postgres")
+})
+
+// lease - how long a dispatch keeps the deliveries it picked before another dispatch can pick them
+const lease = 5 * time.Minute
+
+// getSubscription - gets a subscription.
+//
+// @param ctx - context.Context
+// @param db - sqlx.ExtContext
+// @param id - string
+// @return subscription
+// @return error
+func getSubscription(ctx context.Context, db sqlx.ExtContext, id string) (*Subscription, error) {
+	subscription := &Subscription{}
+	if err := database.NamedStructQuery(ctx, db, "SELECT * FROM webhook_subscriptions WHERE id = :id", map[string]interface{}{
+		"id": id,
+	}, subscription); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("selecting webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// checkEventTypes - checks partners can subscribe to every event type.
+//
+// @param eventTypes - []string
+// @return error
+func checkEventTypes(eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		if !slice.Contains(EventTypes, eventType) {
+			return fmt.Errorf("%w[%v]: partners can subscribe to %v", ErrInvalidEvent, eventType, EventTypes)
+		}
+	}
+
+	return nil
+}
+
+// newSecret - generates the secret of a subscription.
+//
+// @return secret
+// @return error
+func newSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("generating secret: %w", err)
+	}
+
+	return "whsec_" + hex.EncodeToString(key), nil
+}
+
+// ListSubscriptions - ListSubscriptions is a function that lists the subscriptions, the newest first.
+//
+// @param ctx - context.Context
+// @return subscriptions
+// @return error
+func ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	subscriptions := make([]Subscription, 0)
+	if err := database.NamedSliceQuery(ctx, webhooksDatabase(), "SELECT * FROM webhook_subscriptions ORDER BY created_at DESC, id", map[string]interface{}{}, &subscriptions); err != nil {
+		return nil, fmt.Errorf("selecting webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// GetSubscription - GetSubscription is a function that gets a subscription.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return subscription
+// @return error
+func GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	return getSubscription(ctx, webhooksDatabase(), id)
+}
+
+// CreateSubscription - CreateSubscription is a function that creates a subscription, generating its secret
+// when none is given.
+//
+// @param ctx - context.Context
+// @param payload - *SubscriptionRequest
+// @return subscription with its secret
+// @return error
+func CreateSubscription(ctx context.Context, payload *SubscriptionRequest) (*SecretResponse, error) {
+	if err := checkEventTypes(payload.EventTypes); err != nil {
+		return nil, err
+	}
+	if err := CheckUrl(payload.Url); err != nil {
+		return nil, err
+	}
+
+	secret := payload.Secret
+	if len(secret) < 1 {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	subscription := &Subscription{
+		Id:          uuid.New().String(),
+		Url:         payload.Url,
+		Description: payload.Description,
+		EventTypes:  slice.Unique(payload.EventTypes),
+		Secret:      secret,
+		Active:      payload.Active == nil || *payload.Active,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO webhook_subscriptions (id, url, description, event_types, secret, active, created_at, updated_at)
+    VALUES (:id, :url, :description, :event_types, :secret, :active, :created_at, :updated_at)
+  `
+
+	// execute query
+	if err := database.NamedExecQuery(ctx, webhooksDatabase(), q, subscription); err != nil {
+		return nil, fmt.Errorf("inserting webhook subscription: %w", err)
+	}
+
+	return &SecretResponse{Subscription: subscription, Secret: secret}, nil
+}
+
+// UpdateSubscription - UpdateSubscription is a function that replaces the url, events and state of a subscription.
+// The secret is kept unless a new one is given, the replaced secret then keeps signing for the RotationGrace.
+//
+// @param ctx - context.Context
+// @param id - string
+// @param payload - *SubscriptionRequest
+// @return subscription
+// @return error
+func UpdateSubscription(ctx context.Context, id string, payload *SubscriptionRequest) (*Subscription, error) {
+	if err := checkEventTypes(payload.EventTypes); err != nil {
+		return nil, err
+	}
+	if err := CheckUrl(payload.Url); err != nil {
+		return nil, err
+	}
+
+	var subscription *Subscription
+	err := database.Transaction(ctx, webhooksDatabase(), func(tx *sqlx.Tx) error {
+		var err error
+		if subscription, err = getSubscription(ctx, tx, id); err != nil {
+			return err
+		}
+
+		subscription.Url = payload.Url
+		subscription.Description = payload.Description
+		subscription.EventTypes = slice.Unique(payload.EventTypes)
+		subscription.Active = payload.Active == nil || *payload.Active
+		subscription.UpdatedAt = time.Now().UTC()
+
+		// a new secret rotates the secret, the old one keeps signing for the grace period
+		if len(payload.Secret) > 0 && payload.Secret != subscription.Secret {
+			expiresAt := subscription.UpdatedAt.Add(RotationGrace)
+			subscription.PreviousSecret, subscription.PreviousSecretExpiresAt = subscription.Secret, &expiresAt
+			subscription.Secret = payload.Secret
+		}
+
+		// query statement to be executed
+		q := `
+      UPDATE webhook_subscriptions SET url = :url, description = :description, event_types = :event_types,
+        secret = :secret, previous_secret = :previous_secret, previous_secret_expires_at = :previous_secret_expires_at,
+        active = :active, updated_at = :updated_at
+      WHERE id = :id
+    `
+
+		// execute query
+		if err := database.NamedExecQuery(ctx, tx, q, subscription); err != nil {
+			return fmt.Errorf("updating webhook subscription: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// DeleteSubscription - DeleteSubscription is a function that deletes a subscription with its deliveries.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return error
+func DeleteSubscription(ctx context.Context, id string) error {
+	return database.Transaction(ctx, webhooksDatabase(), func(tx *sqlx.Tx) error {
+		if _, err := getSubscription(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := database.NamedExecQuery(ctx, tx, "DELETE FROM webhook_subscriptions WHERE id = :id", map[string]interface{}{"id": id}); err != nil {
+			return fmt.Errorf("deleting webhook subscription: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Enqueue - Enqueue is a function that creates a delivery of an event for every active subscription to its type.
+// An event enqueued again is not delivered twice.
+//
+// @param ctx - context.Context
+// @param eventType - string
+// @param eventId - string
+// @param event - the event, sent as the body
+// @return the deliveries created
+// @return error
+func Enqueue(ctx context.Context, eventType, eventId string, event interface{}) (int, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("encoding event: %w", err)
+	}
+
+	// get the subscriptions to the event
+	subscriptions := make([]Subscription, 0)
+	if err := database.NamedSliceQuery(ctx, webhooksDatabase(), `
+    SELECT * FROM webhook_subscriptions WHERE active AND :event_type = ANY(event_types)
+  `, map[string]interface{}{"event_type": eventType}, &subscriptions); err != nil {
+		return 0, fmt.Errorf("selecting webhook subscriptions: %w", err)
+	}
+	if len(subscriptions) < 1 {
+		return 0, nil
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]Delivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, Delivery{
+			Id:             uuid.New().String(),
+			SubscriptionId: subscription.Id,
+			EventId:        eventId,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         StatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+
+	// query statement to be executed
+	q := `
+    INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at, updated_at)
+    VALUES (:id, :subscription_id, :event_id, :event_type, :payload, :status, :next_attempt_at, :created_at, :updated_at)
+    ON CONFLICT (subscription_id, event_id) DO NOTHING
+    RETURNING id
+  `
+
+	// execute query, counting the deliveries not made already for an event delivered twice
+	inserted := make([]Delivery, 0, len(deliveries))
+	if err := database.NamedSliceQuery(ctx, webhooksDatabase(), q, deliveries, &inserted); err != nil {
+		return 0, fmt.Errorf("inserting webhook deliveries: %w", err)
+	}
+
+	return len(inserted), nil
+}
+
+// attempt - sends a delivery once and records the attempt, the delivery is dead once it used every attempt.
+//
+// @param ctx - context.Context
+// @param client - *http.Client
+// @param subscription - *Subscription
+// @param delivery - *Delivery
+// @return error
+func attempt(ctx context.Context, client *http.Client, subscription *Subscription, delivery *Delivery) error {
+	now := time.Now()
+	record := Attempt{Id: uuid.New().String(), DeliveryId: delivery.Id, Attempt: delivery.Attempts + 1, AttemptedAt: now.UTC()}
+
+	// send the delivery, unless the subscription was paused since it was created
+	var err error
+	if subscription.Active {
+		record.Status, err = Send(ctx, client, subscription.Url, subscription.Secrets(now), delivery)
+		record.DurationMs = time.Since(now).Milliseconds()
+	} else {
+		err = errors.New("subscription is not active")
+	}
+
+	delivery.Attempts = record.Attempt
+	delivery.LastStatus = record.Status
+	delivery.UpdatedAt = now.UTC()
+	switch {
+	case err == nil:
+		deliveredAt := now.UTC()
+		delivery.Status = StatusDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &deliveredAt
+	case delivery.Attempts >= MaxAttempts || !subscription.Active:
+		record.Error = err.Error()
+		delivery.Status = StatusDead
+		delivery.LastError = record.Error
+	default:
+		record.Error = err.Error()
+		delivery.Status = StatusPending
+		delivery.LastError = record.Error
+		delivery.NextAttemptAt = now.UTC().Add(retry.Backoff(RetryWait, MaxRetryWait, delivery.Attempts))
+	}
+	if err != nil {
+		rlog.Warn("store.attempt", "delivery", delivery.Id, "subscription", subscription.Id, "attempts", delivery.Attempts, "error", err)
+	}
+
+	// record the attempt
+	return database.Transaction(ctx, webhooksDatabase(), func(tx *sqlx.Tx) error {
+		if err := database.NamedExecQuery(ctx, tx, `
+      INSERT INTO webhook_attempts (id, delivery_id, attempt, status, error, duration_ms, attempted_at)
+      VALUES (:id, :delivery_id, :attempt, :status, :error, :duration_ms, :attempted_at)
+    `, &record); err != nil {
+			return fmt.Errorf("inserting webhook attempt: %w", err)
+		}
+
+		if err := database.NamedExecQuery(ctx, tx, `
+      UPDATE webhook_deliveries SET status = :status, attempts = :attempts, next_attempt_at = :next_attempt_at,
+        last_status = :last_status, last_error = :last_error, delivered_at = :delivered_at, updated_at = :updated_at
+      WHERE id = :id
+    `, delivery); err != nil {
+			return fmt.Errorf("updating webhook delivery: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Dispatch - Dispatch is a function that sends the deliveries due, the oldest first. The deliveries are leased
+// before they are sent, so concurrent dispatches skip them and a dispatch that stops sends them again later.
+// A dispatch stops sending before its lease runs out and releases the deliveries left to the next dispatch.
+//
+// @param ctx - context.Context
+// @param client - *http.Client
+// @param now - time.Time
+// @return response
+// @return error
+func Dispatch(ctx context.Context, client *http.Client, now time.Time) (*DispatchResponse, error) {
+	response := &DispatchResponse{}
+
+	// lease the deliveries due
+	deliveries := make([]Delivery, 0)
+	if err := database.NamedSliceQuery(ctx, webhooksDatabase(), `
+    UPDATE webhook_deliveries SET next_attempt_at = :lease
+    WHERE id IN (
+      SELECT id FROM webhook_deliveries
+      WHERE status = 'pending' AND next_attempt_at <= :now
+      ORDER BY next_attempt_at, created_at
+      LIMIT :limit
+      FOR UPDATE SKIP LOCKED
+    )
+    RETURNING *
+  `, map[string]interface{}{"now": now.UTC(), "lease": now.UTC().Add(lease), "limit": BatchSize}, &deliveries); err != nil {
+		return nil, fmt.Errorf("leasing webhook deliveries: %w", err)
+	}
+	if len(deliveries) < 1 {
+		return response, nil
+	}
+
+	// get their subscriptions
+	ids := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.SubscriptionId)
+	}
+	subscriptions := make([]Subscription, 0)
+	if err := database.NamedSliceQuery(ctx, webhooksDatabase(), `
+    SELECT * FROM webhook_subscriptions WHERE id = ANY(CAST(:ids AS UUID[]))
+  `, map[string]interface{}{"ids": slice.Unique(ids)}, &subscriptions); err != nil {
+		return nil, fmt.Errorf("selecting webhook subscriptions: %w", err)
+	}
+	byId := make(map[string]*Subscription, len(subscriptions))
+	for i := range subscriptions {
+		byId[subscriptions[i].Id] = &subscriptions[i]
+	}
+
+	// send them while the lease holds, with time for the last one to answer
+	deadline := time.Now().Add(lease - 2*Timeout)
+	for i := range deliveries {
+		if time.Now().After(deadline) {
+			left := make([]string, 0, len(deliveries)-i)
+			for _, delivery := range deliveries[i:] {
+				left = append(left, delivery.Id)
+			}
+			if err := database.NamedExecQuery(ctx, webhooksDatabase(), `
+        UPDATE webhook_deliveries SET next_attempt_at = :now WHERE id = ANY(CAST(:ids AS UUID[])) AND status = 'pending'
+      `, map[string]interface{}{"now": now.UTC(), "ids": left}); err != nil {
+				return nil, fmt.Errorf("releasing webhook deliveries: %w", err)
+			}
+			response.Deferred = len(left)
+			break
+		}
+
+		delivery := &deliveries[i]
+		subscription, ok := byId[delivery.SubscriptionId]
+		if !ok {
+			continue // deleted with its deliveries since they were leased
+		}
+		if err := attempt(ctx, client, subscription, delivery); err != nil {
+			return nil, err
+		}
+
+		switch delivery.Status {
+		case StatusDelivered:
+			response.Delivered++
+		case StatusDead:
+			response.Dead++
+		default:
+			response.Failed++
+		}
+	}
+
+	return response, nil
+}
+
+// Redeliver - Redeliver is a function that sends a delivery again now, whatever its status. A delivery that fails
+// is tried again with every attempt, like a new one.
+//
+// @param ctx - context.Context
+// @param client - *http.Client
+// @param id - string
+// @param now - time.Time
+// @return delivery
+// @return error
+func Redeliver(ctx context.Context, client *http.Client, id string, now time.Time) (*Delivery, error) {
+	delivery := &Delivery{}
+	var subscription *Subscription
+
+	// reset the delivery, leasing it so a dispatch does not send it as well
+	err := database.Transaction(ctx, webhooksDatabase(), func(tx *sqlx.Tx) error {
+		if err := database.NamedStructQuery(ctx, tx, `
+      UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = :lease, updated_at = :now
+      WHERE id = :id
+      RETURNING *
+    `, map[string]interface{}{"id": id, "now": now.UTC(), "lease": now.UTC().Add(lease)}, delivery); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return ErrDeliveryNotFound
+			}
+			return fmt.Errorf("resetting webhook delivery: %w", err)
+		}
+
+		var err error
+		subscription, err = getSubscription(ctx, tx, delivery.SubscriptionId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := attempt(ctx, client, subscription, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// deliveriesSchema - the fields of a delivery allowed in list queries
+var deliveriesSchema = &pagination.Schema{
+	Fields: []pagination.Field{
+		{Name: "id", Column: "id", Type: pagination.UUID, Sortable: true},
+		{Name: "eventId", Column: "event_id", Type: pagination.UUID},
+		{Name: "eventType", Column: "event_type", Type: pagination.String},
+		{Name: "status", Column: "status", Type: pagination.String},
+		{Name: "attempts", Column: "attempts", Type: pagination.Number, Sortable: true},
+		{Name: "lastStatus", Column: "last_status", Type: pagination.Number},
+		{Name: "createdAt", Column: "created_at", Type: pagination.Time, Sortable: true},
+		{Name: "updatedAt", Column: "updated_at", Type: pagination.Time, Sortable: true},
+	},
+	Key:         "id",
+	DefaultSort: "-createdAt",
+}
+
+// ListDeliveries - ListDeliveries is a function that lists the deliveries of a subscription, the newest first unless
+// sorted otherwise.
+//
+// @param ctx - context.Context
+// @param subscriptionId - string
+// @param params - *DeliveriesQuery
+// @return deliveries
+// @return error
+func ListDeliveries(ctx context.Context, subscriptionId string, params *DeliveriesQuery) (*PaginatedDeliveriesResponse, error) {
+	if _, err := getSubscription(ctx, webhooksDatabase(), subscriptionId); err != nil {
+		return nil, err
+	}
+
+	// parse the filter and sort
+	statement, err := deliveriesSchema.Parse(pagination.Query{Filter: params.Filter, Sort: params.Sort})
+	if err != nil {
+		return nil, err
+	}
+	statement.AddCondition("subscription_id = CAST(:subscription_id AS UUID)", map[string]interface{}{"subscription_id": subscriptionId})
+	if len(params.Status) > 0 {
+		statement.AddCondition("status = :status", map[string]interface{}{"status": params.Status})
+	}
+
+	// execute query
+	deliveries := make([]Delivery, 0)
+	page, err := database.NamedPageQuery(ctx, webhooksDatabase(), "webhook_deliveries", statement, &pagination.Options{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		Cursor:    params.Cursor,
+		SkipTotal: params.SkipTotal,
+	}, &deliveries)
+	if err != nil {
+		return nil, fmt.Errorf("selecting webhook deliveries: %w", err)
+	}
+
+	return &PaginatedDeliveriesResponse{
+		Deliveries:      deliveries,
+		Total:           page.Total,
+		TotalPages:      page.TotalPages,
+		CurrentPage:     page.CurrentPage,
+		HasPreviousPage: page.HasPreviousPage,
+		HasNextPage:     page.HasNextPage,
+		PrevCursor:      page.PrevCursor,
+		NextCursor:      page.NextCursor,
+	}, nil
+}
+
+// GetDelivery - GetDelivery is a function that gets a delivery with its body and the log of its attempts.
+//
+// @param ctx - context.Context
+// @param id - string
+// @return delivery
+// @return error
+func GetDelivery(ctx context.Context, id string) (*DeliveryResponse, error) {
+	data := map[string]interface{}{"id": id}
+
+	delivery := &Delivery{}
+	if err := database.NamedStructQuery(ctx, webhooksDatabase(), "SELECT * FROM webhook_deliveries WHERE id = :id", data, delivery); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("selecting webhook delivery: %w", err)
+	}
+
+	attempts := make([]Attempt, 0)
+	if err := database.NamedSliceQuery(ctx, webhooksDatabase(), `
+    SELECT * FROM webhook_attempts WHERE delivery_id = :id ORDER BY attempted_at, attempt
+  `, data, &attempts); err != nil {
+		return nil, fmt.Errorf("selecting webhook attempts: %w", err)
+	}
+
+	return &DeliveryResponse{Delivery: delivery, Payload: string(delivery.Payload), Attempts: attempts}, nil
+}
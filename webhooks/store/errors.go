@@ -0,0 +1,11 @@
+package store
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidEvent     = errors.New("unknown event type")
+	ErrInvalidUrl       = errors.New("webhook url not allowed")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"time"
+
+	"encore.app/pkg/events"
+)
+
+const (
+	StatusPending   = "pending"   // waiting for its next attempt
+	StatusDelivered = "delivered" // the receiver answered with a 2xx status
+	StatusDead      = "dead"      // every attempt failed, only sent again when redelivered
+
+	// MaxAttempts - the attempts made before a delivery is dead
+	MaxAttempts = 10
+	// RetryWait - the wait after the first failed attempt, doubling after every attempt up to MaxRetryWait
+	RetryWait    = 30 * time.Second
+	MaxRetryWait = 6 * time.Hour
+	// BatchSize - the most deliveries sent by a dispatch
+	BatchSize = 100
+	// Timeout - how long a receiver has to answer
+	Timeout = 10 * time.Second
+	// Tolerance - how old a signed timestamp can be when verified, so a captured delivery cannot be replayed later
+	Tolerance = 5 * time.Minute
+	// RotationGrace - how long the previous secret keeps signing the deliveries after the secret is rotated
+	RotationGrace = 24 * time.Hour
+
+	// the headers of a delivery
+	HeaderId        = "Webhook-Id"        // the id of the event, receivers can ignore an id they have seen
+	HeaderEvent     = "Webhook-Event"     // e.g. order.placed
+	HeaderTimestamp = "Webhook-Timestamp" // unix seconds, part of the signature
+	HeaderSignature = "Webhook-Signature" // v1=hex(hmac-sha256(secret, timestamp + "." + body)), one per secret signing
+)
+
+// EventTypes - the events partners can subscribe to
+var EventTypes = []string{events.TypeOrderPlaced, events.TypeStockChanged, events.TypePriceChanged}
+
+// Subscription - an endpoint of a partner receiving events
+type Subscription struct {
+	Id          string    `json:"id" db:"id"`
+	Url         string    `json:"url" db:"url"`
+	Description string    `json:"description" db:"description"`
+	EventTypes  []string  `json:"eventTypes" db:"event_types"`
+	Secret      string    `json:"-" db:"secret"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
+	// the secret replaced by the last rotation, signing next to the secret until it expires
+	PreviousSecret          string     `json:"-" db:"previous_secret"`
+	PreviousSecretExpiresAt *time.Time `json:"previousSecretExpiresAt" db:"previous_secret_expires_at"`
+}
+
+type SubscriptionRequest struct {
+	Url         string   `json:"url" validate:"required,url,max=2000"`
+	Description string   `json:"description" validate:"max=1000"`
+	EventTypes  []string `json:"eventTypes" validate:"required,min=1,dive,required"`
+	Active      *bool    `json:"active"`                                     // defaults to true
+	Secret      string   `json:"secret" validate:"omitempty,min=16,max=255"` // generated when empty, a new secret rotates it
+}
+
+// SecretResponse - the subscription with its secret, only returned when it is created or the secret is rotated
+type SecretResponse struct {
+	Subscription *Subscription `json:"subscription"`
+	Secret       string        `json:"secret"`
+}
+
+type SubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"data"`
+}
+
+// Delivery - an event to send to a subscription
+type Delivery struct {
+	Id             string     `json:"id" db:"id"`
+	SubscriptionId string     `json:"subscriptionId" db:"subscription_id"`
+	EventId        string     `json:"eventId" db:"event_id"`
+	EventType      string     `json:"eventType" db:"event_type"`
+	Payload        []byte     `json:"-" db:"payload"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastStatus     int        `json:"lastStatus" db:"last_status"` // the status code of the last answer, 0 when none came
+	LastError      string     `json:"lastError" db:"last_error"`
+	DeliveredAt    *time.Time `json:"deliveredAt" db:"delivered_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// Attempt - a request made for a delivery
+type Attempt struct {
+	Id          string    `json:"id" db:"id"`
+	DeliveryId  string    `json:"deliveryId" db:"delivery_id"`
+	Attempt     int       `json:"attempt" db:"attempt"`
+	Status      int       `json:"status" db:"status"` // 0 when no answer came
+	Error       string    `json:"error" db:"error"`
+	DurationMs  int64     `json:"durationMs" db:"duration_ms"`
+	AttemptedAt time.Time `json:"attemptedAt" db:"attempted_at"`
+}
+
+type DeliveryResponse struct {
+	Delivery *Delivery `json:"delivery"`
+	Payload  string    `json:"payload"` // the body sent
+	Attempts []Attempt `json:"attempts"`
+}
+
+type DeliveriesQuery struct {
+	Status string `json:"status" query:"status" validate:"omitempty,oneof=pending delivered dead"`
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=0"` // the number of items
+	Page   int    `json:"page" query:"page" validate:"omitempty,min=0"`   // the page
+	Filter string `json:"filter" query:"filter" validate:"omitempty"`     // e.g. eventType=stock.changed
+	Sort   string `json:"sort" query:"sort" validate:"omitempty"`         // e.g. -createdAt
+	Cursor string `json:"cursor" query:"cursor" validate:"omitempty"`     // the position to continue from, instead of the page
+	// SkipTotal - skips counting the deliveries
+	SkipTotal bool `json:"skipTotal" query:"skipTotal"`
+}
+
+type PaginatedDeliveriesResponse struct {
+	Deliveries      []Delivery `json:"data"`
+	Total           int        `json:"total"`
+	TotalPages      int        `json:"totalPages"`
+	CurrentPage     int        `json:"currentPage"`
+	HasPreviousPage bool       `json:"hasPreviousPage"`
+	HasNextPage     bool       `json:"hasNextPage"`
+	PrevCursor      string     `json:"prevCursor"`
+	NextCursor      string     `json:"nextCursor"`
+}
+
+type DispatchResponse struct {
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	Dead      int `json:"dead"`
+	Deferred  int `json:"deferred"` // left for the next dispatch once the lease ran short
+}
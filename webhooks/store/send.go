@@ -0,0 +1,54 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Send - Send posts a delivery to the url of its subscription, signed with the secrets in use when it is sent.
+// A receiver answering with a status other than 2xx fails the delivery.
+//
+// @param ctx - context.Context
+// @param client - *http.Client
+// @param url - the url of the subscription
+// @param secrets - the secrets signing the delivery
+// @param delivery - *Delivery
+// @return the status code answered, 0 when no answer came
+// @return error
+func Send(ctx context.Context, client *http.Client, url string, secrets []string, delivery *Delivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	// build the request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Supermark-Webhooks/1.0")
+	req.Header.Set(HeaderId, delivery.EventId)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, SignAll(secrets, timestamp, delivery.Payload))
+
+	// send it
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// keep the start of the answer to help the partner find the problem
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return res.StatusCode, fmt.Errorf("receiver answered %v: %v", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	return res.StatusCode, nil
+}
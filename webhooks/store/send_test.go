@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSend - test a delivery reaches a local receiver signed, and fails when the receiver does not accept it
+//
+//	@param t - testing.T
+func TestSend(t *testing.T) {
+	delivery := &Delivery{EventId: "0b6f6c1e-8f1d-4a57-9a5e-3c8f2f6b1d2a", EventType: "order.placed", Payload: []byte(`{"orderId":"1"}`)}
+
+	// create a slice
+	slice := []struct {
+		name   string
+		answer int
+		status int
+		fails  bool
+	}{
+		{name: "accepted", answer: http.StatusOK, status: http.StatusOK},
+		{name: "accepted without content", answer: http.StatusNoContent, status: http.StatusNoContent},
+		{name: "rejected", answer: http.StatusBadRequest, status: http.StatusBadRequest, fails: true},
+		{name: "receiver failing", answer: http.StatusServiceUnavailable, status: http.StatusServiceUnavailable, fails: true},
+	}
+
+	for _, item := range slice {
+		// the receiver checks the delivery the way a partner does
+		var verified error
+		var event, id string
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			verified = Verify("secret", r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Now())
+			event = r.Header.Get(HeaderEvent)
+			id = r.Header.Get(HeaderId)
+			w.WriteHeader(item.answer)
+		}))
+
+		status, err := Send(context.Background(), receiver.Client(), receiver.URL, []string{"secret"}, delivery)
+		receiver.Close()
+
+		if status != item.status {
+			t.Errorf("%v: status should be %v, got %v", item.name, item.status, status)
+		}
+		if item.fails != (err != nil) {
+			t.Errorf("%v: should fail %v, got %v", item.name, item.fails, err)
+		}
+		if verified != nil {
+			t.Errorf("%v: receiver should verify the signature, got %v", item.name, verified)
+		}
+		if event != delivery.EventType || id != delivery.EventId {
+			t.Errorf("%v: receiver should get the event headers, got %v and %v", item.name, event, id)
+		}
+	}
+}
+
+// TestSendUnreachable - test a receiver that does not answer fails the delivery without a status
+//
+//	@param t - testing.T
+func TestSendUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer receiver.Close()
+
+	client := receiver.Client()
+	client.Timeout = 50 * time.Millisecond
+
+	status, err := Send(context.Background(), client, receiver.URL, []string{"secret"}, &Delivery{Payload: []byte(`{}`)})
+	if status != 0 || err == nil {
+		t.Errorf("delivery should fail without a status, got %v and %v", status, err)
+	}
+}
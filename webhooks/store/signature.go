@@ -0,0 +1,85 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sign - Sign signs the body of a delivery sent at a time, so a receiver knowing the secret can check it came
+// from us unchanged.
+//
+// @param secret - the secret of the subscription
+// @param timestamp - when the delivery is sent, in unix seconds
+// @param body - the body sent
+// @return the value of the signature header
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignAll - SignAll signs the body of a delivery with every secret in use, separated by commas the way Verify
+// reads them.
+//
+// @param secrets - the secrets of the subscription
+// @param timestamp - when the delivery is sent, in unix seconds
+// @param body - the body sent
+// @return the value of the signature header
+func SignAll(secrets []string, timestamp int64, body []byte) string {
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		signatures = append(signatures, Sign(secret, timestamp, body))
+	}
+
+	return strings.Join(signatures, ", ")
+}
+
+// Secrets - Secrets returns the secrets signing the deliveries of a subscription at a time, the secret and,
+// until it expires, the secret it was rotated from.
+//
+// @param now - time.Time
+// @return secrets
+func (s *Subscription) Secrets(now time.Time) []string {
+	secrets := []string{s.Secret}
+	if len(s.PreviousSecret) > 0 && s.PreviousSecretExpiresAt != nil && now.Before(*s.PreviousSecretExpiresAt) {
+		secrets = append(secrets, s.PreviousSecret)
+	}
+
+	return secrets
+}
+
+// Verify - Verify checks the signature of a delivery the way a receiver does. It is given the timestamp and
+// signature headers, and rejects timestamps further from now than the tolerance.
+//
+// @param secret - the secret of the subscription
+// @param timestamp - the timestamp header
+// @param signature - the signature header, a comma separated list while a rotated secret still signs
+// @param body - the body received
+// @param now - time.Time
+// @return error
+func Verify(secret, timestamp, signature string, body []byte, now time.Time) error {
+	at, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(at, 0)); age > Tolerance || age < -Tolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidSignature)
+	}
+
+	expected := []byte(Sign(secret, at, body))
+	for _, candidate := range strings.Split(signature, ",") {
+		if hmac.Equal([]byte(strings.TrimSpace(candidate)), expected) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: signature does not match", ErrInvalidSignature)
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestVerify - test a receiver checking the signature of a delivery
+//
+//	@param t - testing.T
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"meta":{"id":"1","type":"order.placed"}}`)
+	signature := Sign("secret", now.Unix(), body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	// create a slice
+	slice := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		valid     bool
+	}{
+		{name: "signed", secret: "secret", timestamp: timestamp, signature: signature, body: body, now: now, valid: true},
+		{name: "within the tolerance", secret: "secret", timestamp: timestamp, signature: signature, body: body, now: now.Add(Tolerance), valid: true},
+		{name: "rotated", secret: "secret", timestamp: timestamp, signature: "v1=00, " + signature, body: body, now: now, valid: true},
+		{name: "other secret", secret: "other", timestamp: timestamp, signature: signature, body: body, now: now},
+		{name: "changed body", secret: "secret", timestamp: timestamp, signature: signature, body: []byte(`{}`), now: now},
+		{name: "changed timestamp", secret: "secret", timestamp: strconv.FormatInt(now.Unix()+1, 10), signature: signature, body: body, now: now},
+		{name: "replayed", secret: "secret", timestamp: timestamp, signature: signature, body: body, now: now.Add(Tolerance + time.Second)},
+		{name: "no timestamp", secret: "secret", timestamp: "", signature: signature, body: body, now: now},
+	}
+
+	for _, item := range slice {
+		err := Verify(item.secret, item.timestamp, item.signature, item.body, item.now)
+		if item.valid && err != nil {
+			t.Errorf("%v: should be valid, got %v", item.name, err)
+		}
+		if !item.valid && !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%v: should be invalid, got %v", item.name, err)
+		}
+	}
+}
+
+// TestSign - test the signature matches the one a receiver computes with any hmac-sha256 implementation
+//
+//	@param t - testing.T
+func TestSign(t *testing.T) {
+	// echo -n "1700000000.{}" | openssl dgst -sha256 -hmac secret
+	want := "v1=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := Sign("secret", 1700000000, []byte("{}")); got != want {
+		t.Errorf("signature should be %v, got %v", want, got)
+	}
+}
+
+// TestSecrets - test a rotated secret keeps signing until its grace period ends, so receivers with either secret
+// verify the deliveries
+//
+//	@param t - testing.T
+func TestSecrets(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	expiresAt := now.Add(RotationGrace)
+	subscription := &Subscription{Secret: "new", PreviousSecret: "old", PreviousSecretExpiresAt: &expiresAt}
+	body := []byte(`{}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	// during the grace period both secrets sign
+	signature := SignAll(subscription.Secrets(now), now.Unix(), body)
+	for _, secret := range []string{"new", "old"} {
+		if err := Verify(secret, timestamp, signature, body, now); err != nil {
+			t.Errorf("a receiver with the %v secret should verify, got %v", secret, err)
+		}
+	}
+
+	// once it ends only the new secret signs
+	if secrets := subscription.Secrets(expiresAt); len(secrets) != 1 || secrets[0] != "new" {
+		t.Errorf("only the new secret should sign, got %v", secrets)
+	}
+	if secrets := (&Subscription{Secret: "only"}).Secrets(now); len(secrets) != 1 {
+		t.Errorf("a secret never rotated should sign alone, got %v", secrets)
+	}
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// reserved - the ranges outside of the public internet that the net package does not name, e.g. carrier-grade NAT
+var reserved = func() []*net.IPNet {
+	ranges := make([]*net.IPNet, 0)
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b::/96"} {
+		_, block, _ := net.ParseCIDR(cidr)
+		ranges = append(ranges, block)
+	}
+	return ranges
+}()
+
+// Public - Public returns whether an address is on the public internet. Receivers on private, loopback or
+// link-local addresses, such as the metadata endpoint of the cloud, are refused.
+//
+// @param ip - net.IP
+// @return bool
+func Public(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, block := range reserved {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckUrl - CheckUrl checks deliveries can be sent to the url of a subscription, an https url on a public host.
+// The host is checked again when a delivery connects, as a name can resolve to another address later.
+//
+// @param raw - the url of the subscription
+// @return error
+func CheckUrl(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || target.Scheme != "https" || len(target.Hostname()) < 1 {
+		return fmt.Errorf("%w[%v]: only https urls are allowed", ErrInvalidUrl, raw)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(target.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") || strings.HasSuffix(host, ".local") {
+		return fmt.Errorf("%w[%v]: the host is not public", ErrInvalidUrl, raw)
+	}
+	if ip := net.ParseIP(host); ip != nil && !Public(ip) {
+		return fmt.Errorf("%w[%v]: the address is not public", ErrInvalidUrl, raw)
+	}
+
+	return nil
+}
+
+// NewClient - NewClient creates the client sending the deliveries. It only connects to public addresses, checked
+// once the host is resolved, goes to the receivers directly rather than through a proxy, and does not follow
+// redirects, so a receiver answering with one fails the attempt.
+//
+// @return *http.Client
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !Public(ip) {
+				return fmt.Errorf("%w: %v is not a public address", ErrInvalidUrl, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
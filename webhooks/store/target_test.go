@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCheckUrl - test only https urls on public hosts can be subscribed
+//
+//	@param t - testing.T
+func TestCheckUrl(t *testing.T) {
+	// create a slice
+	slice := []struct {
+		url   string
+		valid bool
+	}{
+		{url: "https://partner.example.com/hooks", valid: true},
+		{url: "https://93.184.216.34/hooks", valid: true},
+		{url: "http://partner.example.com/hooks"},
+		{url: "ftp://partner.example.com/hooks"},
+		{url: "https:///hooks"},
+		{url: "https://localhost/hooks"},
+		{url: "https://api.localhost./hooks"},
+		{url: "https://metadata.google.internal/computeMetadata/v1/"},
+		{url: "https://127.0.0.1/hooks"},
+		{url: "https://10.0.0.8/hooks"},
+		{url: "https://192.168.1.1/hooks"},
+		{url: "https://169.254.169.254/latest/meta-data/"},
+		{url: "https://100.64.0.1/hooks"},
+		{url: "https://0.0.0.0/hooks"},
+		{url: "https://[::1]/hooks"},
+		{url: "https://[fe80::1]/hooks"},
+		{url: "https://[fd00::1]/hooks"},
+		{url: "https://[::ffff:127.0.0.1]/hooks"},
+	}
+
+	for _, item := range slice {
+		err := CheckUrl(item.url)
+		if item.valid && err != nil {
+			t.Errorf("%v: should be allowed, got %v", item.url, err)
+		}
+		if !item.valid && !errors.Is(err, ErrInvalidUrl) {
+			t.Errorf("%v: should be refused, got %v", item.url, err)
+		}
+	}
+}
+
+// TestNewClient - test the client refuses private addresses when it connects and does not follow redirects
+//
+//	@param t - testing.T
+func TestNewClient(t *testing.T) {
+	reached := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer receiver.Close()
+
+	// the test receiver listens on a loopback address
+	status, err := Send(context.Background(), NewClient(), receiver.URL, []string{"secret"}, &Delivery{Payload: []byte(`{}`)})
+	if !errors.Is(err, ErrInvalidUrl) || status != 0 || reached {
+		t.Errorf("should not connect to a loopback address, got %v and %v", status, err)
+	}
+
+	// a redirect is answered back instead of followed
+	req, _ := http.NewRequest(http.MethodPost, "https://partner.example.com/hooks", nil)
+	if err := NewClient().CheckRedirect(req, []*http.Request{req}); !errors.Is(err, http.ErrUseLastResponse) {
+		t.Errorf("should not follow redirects, got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/go-playground/validator/v10"
+
+	"encore.app/pkg/middleware"
+	"encore.app/pkg/pagination"
+	"encore.app/webhooks/store"
+)
+
+// the client sending the deliveries, a receiver that does not answer in time fails the attempt
+var client = store.NewClient()
+
+// =====================================================================================================================
+// SUBSCRIPTIONS
+// =====================================================================================================================
+
+// ListWebhooks - List the webhook subscriptions of the partners
+//
+//	@param ctx - context.Context
+//	@return subscriptions
+//	@return error
+//
+// encore:api auth method=GET path=/webhooks
+func ListWebhooks(ctx context.Context) (*store.SubscriptionsResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.SubscriptionsResponse{}, err
+	}
+
+	// list the subscriptions
+	subscriptions, err := store.ListSubscriptions(ctx)
+	if err != nil {
+		return &store.SubscriptionsResponse{}, err
+	}
+
+	return &store.SubscriptionsResponse{Subscriptions: subscriptions}, nil
+}
+
+// CreateWebhook - Subscribe the url of a partner to events, the secret signing the deliveries is only returned now
+//
+//	@param ctx - context.Context
+//	@param payload - *store.SubscriptionRequest
+//	@return subscription with its secret
+//	@return error
+//
+// encore:api auth method=POST path=/webhooks
+func CreateWebhook(ctx context.Context, payload *store.SubscriptionRequest) (*store.SecretResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.SecretResponse{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &store.SecretResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// create the subscription
+	response, err := store.CreateSubscription(ctx, payload)
+	if err != nil {
+		return &store.SecretResponse{}, webhookError(err)
+	}
+
+	return response, nil
+}
+
+// GetWebhook - Get a webhook subscription
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return subscription
+//	@return error
+//
+// encore:api auth method=GET path=/webhooks/:id
+func GetWebhook(ctx context.Context, id string) (*store.Subscription, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.Subscription{}, err
+	}
+
+	// get the subscription
+	subscription, err := store.GetSubscription(ctx, id)
+	if err != nil {
+		return &store.Subscription{}, webhookError(err)
+	}
+
+	return subscription, nil
+}
+
+// UpdateWebhook - Replace the url, events and state of a webhook subscription, a new secret rotates it
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param payload - *store.SubscriptionRequest
+//	@return subscription
+//	@return error
+//
+// encore:api auth method=PUT path=/webhooks/:id
+func UpdateWebhook(ctx context.Context, id string, payload *store.SubscriptionRequest) (*store.Subscription, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.Subscription{}, err
+	}
+
+	// validate payload
+	if err := validator.New().Struct(payload); err != nil {
+		return &store.Subscription{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// update the subscription
+	subscription, err := store.UpdateSubscription(ctx, id, payload)
+	if err != nil {
+		return &store.Subscription{}, webhookError(err)
+	}
+
+	return subscription, nil
+}
+
+// DeleteWebhook - Delete a webhook subscription with its deliveries
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return error
+//
+// encore:api auth method=DELETE path=/webhooks/:id
+func DeleteWebhook(ctx context.Context, id string) error {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return err
+	}
+
+	// delete the subscription
+	if err := store.DeleteSubscription(ctx, id); err != nil {
+		return webhookError(err)
+	}
+
+	return nil
+}
+
+// =====================================================================================================================
+// DELIVERIES
+// =====================================================================================================================
+
+// ListWebhookDeliveries - List the deliveries of a webhook subscription, the newest first
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@param params - *store.DeliveriesQuery
+//	@return deliveries
+//	@return error
+//
+// encore:api auth method=GET path=/webhooks/:id/deliveries
+func ListWebhookDeliveries(ctx context.Context, id string, params *store.DeliveriesQuery) (*store.PaginatedDeliveriesResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.PaginatedDeliveriesResponse{}, err
+	}
+
+	// validate params
+	if err := validator.New().Struct(params); err != nil {
+		return &store.PaginatedDeliveriesResponse{}, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	// list the deliveries
+	deliveries, err := store.ListDeliveries(ctx, id, params)
+	if err != nil {
+		return &store.PaginatedDeliveriesResponse{}, webhookError(err)
+	}
+
+	return deliveries, nil
+}
+
+// GetWebhookDelivery - Get a delivery with the body sent and the log of its attempts
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return delivery
+//	@return error
+//
+// encore:api auth method=GET path=/webhooks/deliveries/:id
+func GetWebhookDelivery(ctx context.Context, id string) (*store.DeliveryResponse, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.DeliveryResponse{}, err
+	}
+
+	// get the delivery
+	delivery, err := store.GetDelivery(ctx, id)
+	if err != nil {
+		return &store.DeliveryResponse{}, webhookError(err)
+	}
+
+	return delivery, nil
+}
+
+// RedeliverWebhook - Send a delivery again now, a dead delivery gets every attempt again when this one fails
+//
+//	@param ctx - context.Context
+//	@param id - string
+//	@return delivery
+//	@return error
+//
+// encore:api auth method=POST path=/webhooks/deliveries/:id/redeliver
+func RedeliverWebhook(ctx context.Context, id string) (*store.Delivery, error) {
+	// check for the roles
+	if _, err := middleware.RequireRole(ctx, middleware.RoleSuperAdmin, middleware.RoleAdmin); err != nil {
+		return &store.Delivery{}, err
+	}
+
+	// send the delivery
+	delivery, err := store.Redeliver(ctx, client, id, time.Now())
+	if err != nil {
+		return &store.Delivery{}, webhookError(err)
+	}
+
+	return delivery, nil
+}
+
+// webhookError - maps webhook store errors to API errors.
+//
+//	@param err - error
+//	@return error
+func webhookError(err error) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrDeliveryNotFound):
+		return &errs.Error{Code: errs.NotFound, Message: err.Error()}
+	case errors.Is(err, store.ErrInvalidEvent), errors.Is(err, store.ErrInvalidUrl), pagination.IsQueryError(err):
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	default:
+		return err
+	}
+}